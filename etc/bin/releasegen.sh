@@ -16,6 +16,7 @@ goos() {
 goarch() {
   case "${1}" in
     x86_64) echo amd64 ;;
+    arm64|aarch64) echo arm64 ;;
     *) return 1 ;;
   esac
 }
@@ -23,8 +24,17 @@ goarch() {
 BASE_DIR="release"
 rm -rf "${BASE_DIR}"
 
+# these are the values of uname -m, which is arm64 on Darwin and aarch64 on Linux
+arches() {
+  case "${1}" in
+    Darwin) echo x86_64 arm64 ;;
+    Linux) echo x86_64 aarch64 ;;
+    *) return 1 ;;
+  esac
+}
+
 for os in Darwin Linux; do
-  for arch in x86_64; do
+  for arch in $(arches "${os}"); do
     dir="${BASE_DIR}/${os}/${arch}/prototool"
     tar_context_dir="$(dirname "${dir}")"
     tar_dir="prototool"
@@ -0,0 +1,108 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package format exposes the Prototool formatter as a library, so that editors,
+// bots, and services can format Protobuf source in-process without shelling out
+// to the prototool binary.
+//
+// This package is meant to be stable. The formatting output may change between
+// releases, but the API in this package will only change in a
+// backwards-compatible manner.
+package format
+
+import (
+	"bytes"
+	"errors"
+
+	intformat "github.com/uber/prototool/internal/format"
+	"github.com/uber/prototool/internal/text"
+)
+
+// DefaultFilename is the filename used for failures and for deriving
+// java_outer_classname if WithFilename is not used.
+const DefaultFilename = "input.proto"
+
+// Option is an option for Format.
+type Option func(*options)
+
+// WithFilename returns an Option that uses the given filename.
+//
+// The filename is used in error messages, and to derive the value of
+// java_outer_classname if WithRewrite is used.
+//
+// The default is to use DefaultFilename.
+func WithFilename(filename string) Option {
+	return func(options *options) {
+		options.filename = filename
+	}
+}
+
+// WithRewrite returns an Option that updates the file options go_package,
+// java_multiple_files, java_outer_classname, and java_package to match the
+// package per the guidelines of the style guide.
+//
+// This matches the default behavior of prototool format. The default for
+// this function is to not rewrite, which matches prototool format --no-rewrite.
+func WithRewrite() Option {
+	return func(options *options) {
+		options.rewrite = true
+	}
+}
+
+// Format formats the given Protobuf source.
+//
+// The source is expected to be compilable. If the source cannot be parsed
+// or formatted, an error is returned.
+func Format(src []byte, opts ...Option) ([]byte, error) {
+	options := &options{
+		filename: DefaultFilename,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	transformerOptions := []intformat.TransformerOption{}
+	if options.rewrite {
+		transformerOptions = append(transformerOptions, intformat.TransformerWithRewrite())
+	}
+	data, failures, err := intformat.NewTransformer(transformerOptions...).Transform(options.filename, src)
+	if err != nil {
+		return nil, err
+	}
+	if len(failures) > 0 {
+		return nil, failuresToError(failures)
+	}
+	return data, nil
+}
+
+type options struct {
+	filename string
+	rewrite  bool
+}
+
+func failuresToError(failures []*text.Failure) error {
+	buffer := bytes.NewBuffer(nil)
+	for i, failure := range failures {
+		if i > 0 {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(failure.String())
+	}
+	return errors.New(buffer.String())
+}
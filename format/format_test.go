@@ -0,0 +1,113 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	data, err := Format([]byte(`syntax="proto3";
+package foo;
+message Bar {
+int64 one=1;
+}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, `syntax = "proto3";
+
+package foo;
+
+message Bar {
+  int64 one = 1;
+}
+`, string(data))
+}
+
+func TestFormatRewrite(t *testing.T) {
+	data, err := Format([]byte(`syntax = "proto3";
+
+package foo.bar;
+`), WithFilename("foo_bar.proto"), WithRewrite())
+	require.NoError(t, err)
+	assert.Equal(t, `syntax = "proto3";
+
+package foo.bar;
+
+option go_package = "barpb";
+option java_multiple_files = true;
+option java_outer_classname = "FooBarProto";
+option java_package = "com.foo.bar";
+`, string(data))
+}
+
+func TestFormatEdition(t *testing.T) {
+	data, err := Format([]byte(`edition="2023";
+package foo;
+option features.field_presence=IMPLICIT;
+message Bar {
+int64 one=1 [features.field_presence=EXPLICIT];
+repeated int64 two=2;
+}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, `edition = "2023";
+
+package foo;
+
+option features.field_presence = IMPLICIT;
+
+message Bar {
+  int64 one = 1 [
+    features.field_presence = EXPLICIT
+  ];
+  repeated int64 two = 2;
+}
+`, string(data))
+}
+
+func TestFormatProto3Optional(t *testing.T) {
+	data, err := Format([]byte(`syntax="proto3";
+package foo;
+message Bar {
+optional int64 one=1;
+repeated int64 two=2;
+}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, `syntax = "proto3";
+
+package foo;
+
+message Bar {
+  optional int64 one = 1;
+  repeated int64 two = 2;
+}
+`, string(data))
+}
+
+func TestFormatError(t *testing.T) {
+	_, err := Format([]byte(`syntax = "proto3"; message {`))
+	assert.Error(t, err)
+}
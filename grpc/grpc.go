@@ -0,0 +1,232 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package grpc exposes the Prototool gRPC client as a library, so that other Go
+// tools and services can call gRPC methods described by FileDescriptorSets
+// in-process with dynamic messages, without shelling out to the prototool binary
+// or generating code for the services.
+//
+// This package is meant to be stable. The API in this package will only change
+// in a backwards-compatible manner.
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	intgrpc "github.com/uber/prototool/internal/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// DefaultCallTimeout is the default call timeout.
+	DefaultCallTimeout = intgrpc.DefaultCallTimeout
+	// DefaultConnectTimeout is the default connect timeout.
+	DefaultConnectTimeout = intgrpc.DefaultConnectTimeout
+)
+
+// InvokeRequest is a request to call a gRPC method.
+type InvokeRequest struct {
+	// The FileDescriptorSets that contain the service of the method and the
+	// types it uses, as produced by protoc --descriptor_set_out with
+	// --include_imports.
+	FileDescriptorSets []*descriptor.FileDescriptorSet
+	// The address to connect to, in the form host:port.
+	Address string
+	// The method to call, in the form package.Service/Method.
+	Method string
+	// The request messages, which must be of the input type of the method,
+	// either generated messages or dynamic messages created from the input
+	// type of the MethodDescriptor.
+	//
+	// If the method is not client streaming, there must be at most one
+	// request, and an empty request is sent if there is none.
+	Requests []proto.Message
+	// The headers to send with the call, in addition to the headers of the
+	// Client and the outgoing metadata of the context.
+	Headers map[string]string
+}
+
+// InvokeResponse is the response of a call to a gRPC method.
+type InvokeResponse struct {
+	// The response messages, of the output type of the method.
+	Responses []*dynamic.Message
+	// The headers and trailers sent by the server.
+	Headers  metadata.MD
+	Trailers metadata.MD
+}
+
+// TLSConfig is the TLS configuration of connections.
+type TLSConfig struct {
+	// The path to the PEM file of the certificate authorities to verify
+	// the server with. If empty, the system certificate authorities are used.
+	CACertPath string
+	// The paths to the PEM files of the client certificate and key, if any.
+	CertPath string
+	KeyPath  string
+	// Do not verify the server certificate.
+	InsecureSkipVerify bool
+	// The name to verify the server certificate with.
+	// If empty, the host of the address is used.
+	ServerName string
+}
+
+// Client calls gRPC methods.
+type Client interface {
+	// Invoke calls the method of the request and returns the responses.
+	//
+	// A new connection is made for each call. The call has the earlier of
+	// the deadline of the context and the call timeout of the Client. If the
+	// call fails, the error is the gRPC status error, so that the code can
+	// be retrieved with status.Code.
+	Invoke(ctx context.Context, request InvokeRequest) (InvokeResponse, error)
+	// MethodDescriptor returns the descriptor of the method in the
+	// FileDescriptorSets, in the form package.Service/Method.
+	//
+	// Requests can be created with dynamic.NewMessage and the input type
+	// of the MethodDescriptor.
+	MethodDescriptor(fileDescriptorSets []*descriptor.FileDescriptorSet, method string) (*desc.MethodDescriptor, error)
+}
+
+// ClientOption is an option for a new Client.
+type ClientOption func(*client)
+
+// ClientWithCallTimeout returns a ClientOption that has the given call timeout.
+//
+// The default is to use DefaultCallTimeout.
+func ClientWithCallTimeout(callTimeout time.Duration) ClientOption {
+	return func(client *client) {
+		client.handlerOptions = append(client.handlerOptions, intgrpc.HandlerWithCallTimeout(callTimeout))
+	}
+}
+
+// ClientWithConnectTimeout returns a ClientOption that has the given connect timeout.
+//
+// The default is to use DefaultConnectTimeout.
+func ClientWithConnectTimeout(connectTimeout time.Duration) ClientOption {
+	return func(client *client) {
+		client.handlerOptions = append(client.handlerOptions, intgrpc.HandlerWithConnectTimeout(connectTimeout))
+	}
+}
+
+// ClientWithTLS returns a ClientOption that connects with TLS.
+//
+// The default is to connect without TLS.
+func ClientWithTLS(tlsConfig TLSConfig) ClientOption {
+	return func(client *client) {
+		client.handlerOptions = append(client.handlerOptions, intgrpc.HandlerWithTLS(intgrpc.TLSConfig(tlsConfig)))
+	}
+}
+
+// ClientWithAuthToken returns a ClientOption that attaches the given token
+// to each call as the header "authorization: Bearer token".
+func ClientWithAuthToken(authToken string) ClientOption {
+	return func(client *client) {
+		client.handlerOptions = append(client.handlerOptions, intgrpc.HandlerWithAuthToken(authToken))
+	}
+}
+
+// ClientWithAuthority returns a ClientOption that uses the given value for
+// the :authority pseudo-header.
+//
+// The default is to use the address.
+func ClientWithAuthority(authority string) ClientOption {
+	return func(client *client) {
+		client.handlerOptions = append(client.handlerOptions, intgrpc.HandlerWithAuthority(authority))
+	}
+}
+
+// ClientWithUserAgent returns a ClientOption that uses the given value as the
+// user-agent. This is prepended to the gRPC library user-agent.
+func ClientWithUserAgent(userAgent string) ClientOption {
+	return func(client *client) {
+		client.handlerOptions = append(client.handlerOptions, intgrpc.HandlerWithUserAgent(userAgent))
+	}
+}
+
+// ClientWithHeader returns a ClientOption that adds the given key/value
+// header to each call.
+func ClientWithHeader(key string, value string) ClientOption {
+	return func(client *client) {
+		client.handlerOptions = append(client.handlerOptions, intgrpc.HandlerWithHeader(key, value))
+	}
+}
+
+// ClientWithCompression returns a ClientOption that compresses requests
+// with the given compressor. The only supported compressor is "gzip".
+//
+// The default is to not compress requests.
+func ClientWithCompression(compression string) ClientOption {
+	return func(client *client) {
+		client.handlerOptions = append(client.handlerOptions, intgrpc.HandlerWithCompression(compression))
+	}
+}
+
+// ClientWithMaxRecvMsgSize returns a ClientOption that sets the maximum
+// size in bytes of a response message.
+//
+// The default is to use the gRPC default of 4MB.
+func ClientWithMaxRecvMsgSize(maxRecvMsgSize int) ClientOption {
+	return func(client *client) {
+		client.handlerOptions = append(client.handlerOptions, intgrpc.HandlerWithMaxRecvMsgSize(maxRecvMsgSize))
+	}
+}
+
+// NewClient returns a new Client.
+func NewClient(options ...ClientOption) Client {
+	return newClient(options...)
+}
+
+type client struct {
+	handlerOptions []intgrpc.HandlerOption
+	handler        intgrpc.Handler
+}
+
+func newClient(options ...ClientOption) *client {
+	client := &client{}
+	for _, option := range options {
+		option(client)
+	}
+	client.handler = intgrpc.NewHandler(client.handlerOptions...)
+	return client
+}
+
+func (c *client) Invoke(ctx context.Context, request InvokeRequest) (InvokeResponse, error) {
+	for key, value := range request.Headers {
+		ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+	}
+	result, err := c.handler.InvokeMessages(ctx, request.FileDescriptorSets, request.Address, request.Method, request.Requests)
+	if err != nil {
+		return InvokeResponse{}, err
+	}
+	return InvokeResponse{
+		Responses: result.Responses,
+		Headers:   result.Headers,
+		Trailers:  result.Trailers,
+	}, nil
+}
+
+func (c *client) MethodDescriptor(fileDescriptorSets []*descriptor.FileDescriptorSet, method string) (*desc.MethodDescriptor, error) {
+	return c.handler.GetMethodDescriptor(fileDescriptorSets, method)
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/mock"
+)
+
+func TestInvoke(t *testing.T) {
+	fileDescriptorSets := []*descriptor.FileDescriptorSet{
+		{
+			File: []*descriptor.FileDescriptorProto{
+				{
+					Name:    proto.String("invoketest/echo.proto"),
+					Package: proto.String("invoketest"),
+					Syntax:  proto.String("proto3"),
+					MessageType: []*descriptor.DescriptorProto{
+						{
+							Name: proto.String("Value"),
+							Field: []*descriptor.FieldDescriptorProto{
+								{
+									Name:     proto.String("value"),
+									JsonName: proto.String("value"),
+									Number:   proto.Int32(1),
+									Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+									Type:     descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+								},
+							},
+						},
+					},
+					Service: []*descriptor.ServiceDescriptorProto{
+						{
+							Name: proto.String("EchoAPI"),
+							Method: []*descriptor.MethodDescriptorProto{
+								{
+									Name:       proto.String("Echo"),
+									InputType:  proto.String(".invoketest.Value"),
+									OutputType: proto.String(".invoketest.Value"),
+								},
+								{
+									Name:            proto.String("EchoMany"),
+									InputType:       proto.String(".invoketest.Value"),
+									OutputType:      proto.String(".invoketest.Value"),
+									ServerStreaming: proto.Bool(true),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	server := mock.NewServer(mock.ServerWithFixtureData([]byte(`invoketest.EchoAPI/Echo:
+  value: pong
+invoketest.EchoAPI/EchoMany:
+  - value: one
+  - value: two
+`)))
+	go func() { _ = server.Serve(fileDescriptorSets, listener) }()
+	address := listener.Addr().String()
+
+	client := NewClient()
+	methodDescriptor, err := client.MethodDescriptor(fileDescriptorSets, "invoketest.EchoAPI/Echo")
+	require.NoError(t, err)
+	request := dynamic.NewMessage(methodDescriptor.GetInputType())
+	require.NoError(t, request.TrySetFieldByName("value", "ping"))
+
+	response, err := client.Invoke(
+		context.Background(),
+		InvokeRequest{
+			FileDescriptorSets: fileDescriptorSets,
+			Address:            address,
+			Method:             "invoketest.EchoAPI/Echo",
+			Requests:           []proto.Message{request},
+			Headers:            map[string]string{"x-test": "test"},
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, response.Responses, 1)
+	assert.Equal(t, "pong", response.Responses[0].GetFieldByName("value"))
+
+	response, err = client.Invoke(
+		context.Background(),
+		InvokeRequest{
+			FileDescriptorSets: fileDescriptorSets,
+			Address:            address,
+			Method:             "invoketest.EchoAPI/EchoMany",
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, response.Responses, 2)
+	assert.Equal(t, "one", response.Responses[0].GetFieldByName("value"))
+	assert.Equal(t, "two", response.Responses[1].GetFieldByName("value"))
+
+	_, err = client.Invoke(
+		context.Background(),
+		InvokeRequest{
+			FileDescriptorSets: fileDescriptorSets,
+			Address:            address,
+			Method:             "invoketest.EchoAPI/Echo",
+			Requests:           []proto.Message{request, request},
+		},
+	)
+	assert.Error(t, err)
+
+	_, err = client.MethodDescriptor(fileDescriptorSets, "invoketest.EchoAPI/Unknown")
+	assert.Error(t, err)
+}
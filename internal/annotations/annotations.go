@@ -0,0 +1,94 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package annotations extracts the usages of options from Protobuf files,
+// such as custom options that mark fields as containing personal data.
+package annotations
+
+import (
+	"github.com/uber/prototool/internal/file"
+	"go.uber.org/zap"
+)
+
+const (
+	// ElementTypeFile is the element type of file options.
+	ElementTypeFile = "file"
+	// ElementTypeMessage is the element type of message options.
+	ElementTypeMessage = "message"
+	// ElementTypeField is the element type of field options.
+	ElementTypeField = "field"
+	// ElementTypeOneof is the element type of oneof options.
+	ElementTypeOneof = "oneof"
+	// ElementTypeEnum is the element type of enum options.
+	ElementTypeEnum = "enum"
+	// ElementTypeEnumValue is the element type of enum value options.
+	ElementTypeEnumValue = "enum_value"
+	// ElementTypeService is the element type of service options.
+	ElementTypeService = "service"
+	// ElementTypeRPC is the element type of rpc options.
+	ElementTypeRPC = "rpc"
+)
+
+// Annotation is a usage of an option.
+type Annotation struct {
+	// The display path of the file.
+	Filename string `json:"filename"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	// The type of the element that the option is set on, such as field.
+	ElementType string `json:"element_type"`
+	// The fully-qualified name of the element that the option is set on,
+	// such as foo.v1.User.email. Empty for options of files without a package.
+	Element string `json:"element"`
+	// The name of the option as written, such as (foo.v1.sensitive).
+	Option string `json:"option"`
+	// The value of the option as written, such as true or "internal".
+	Value string `json:"value"`
+}
+
+// Extractor extracts the usages of options from Protobuf files.
+type Extractor interface {
+	// Extract returns the usages of the options with the given names,
+	// sorted by filename and position.
+	//
+	// Names are matched without parentheses, and also match the fields
+	// of an option, so foo.v1.rules matches (foo.v1.rules).max_len.
+	// Options that are written relative to the package of a file are
+	// resolved against the package. If no names are given, all custom
+	// options are returned.
+	Extract(protoFiles []*file.ProtoFile, optionNames []string) ([]*Annotation, error)
+}
+
+// ExtractorOption is an option for a new Extractor.
+type ExtractorOption func(*extractor)
+
+// ExtractorWithLogger returns an ExtractorOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func ExtractorWithLogger(logger *zap.Logger) ExtractorOption {
+	return func(extractor *extractor) {
+		extractor.logger = logger
+	}
+}
+
+// NewExtractor returns a new Extractor.
+func NewExtractor(options ...ExtractorOption) Extractor {
+	return newExtractor(options...)
+}
@@ -0,0 +1,228 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package annotations
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/editions"
+	"github.com/uber/prototool/internal/file"
+	"go.uber.org/zap"
+)
+
+type extractor struct {
+	logger *zap.Logger
+}
+
+func newExtractor(options ...ExtractorOption) *extractor {
+	extractor := &extractor{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(extractor)
+	}
+	return extractor
+}
+
+func (e *extractor) Extract(protoFiles []*file.ProtoFile, optionNames []string) ([]*Annotation, error) {
+	state := &extractState{}
+	for _, optionName := range optionNames {
+		state.optionNames = append(state.optionNames, normalizeOptionName(optionName))
+	}
+	for _, protoFile := range protoFiles {
+		descriptor, err := parse(protoFile.Path, protoFile.DisplayPath)
+		if err != nil {
+			return nil, err
+		}
+		state.extractFile(descriptor, protoFile.DisplayPath)
+	}
+	annotations := state.annotations
+	sort.SliceStable(annotations, func(i int, j int) bool {
+		if annotations[i].Filename != annotations[j].Filename {
+			return annotations[i].Filename < annotations[j].Filename
+		}
+		if annotations[i].Line != annotations[j].Line {
+			return annotations[i].Line < annotations[j].Line
+		}
+		return annotations[i].Column < annotations[j].Column
+	})
+	e.logger.Debug("extracted annotations", zap.Int("files", len(protoFiles)), zap.Int("annotations", len(annotations)))
+	return annotations, nil
+}
+
+type extractState struct {
+	optionNames []string
+	annotations []*Annotation
+	// the values below are for the file being extracted
+	filename string
+	pkg      string
+}
+
+func (s *extractState) extractFile(descriptor *proto.Proto, displayPath string) {
+	s.filename = displayPath
+	s.pkg = ""
+	for _, element := range descriptor.Elements {
+		if pkg, ok := element.(*proto.Package); ok {
+			s.pkg = pkg.Name
+		}
+	}
+	s.extractElements(descriptor.Elements, s.pkg, ElementTypeFile, s.pkg)
+}
+
+// extractElements extracts the options in elements, which are the elements
+// of the element with the given type and name, and recurses into the
+// nested elements. prefix is the prefix of the names of nested elements.
+func (s *extractState) extractElements(elements []proto.Visitee, prefix string, elementType string, elementName string) {
+	for _, element := range elements {
+		switch element := element.(type) {
+		case *proto.Option:
+			s.extractOptions([]*proto.Option{element}, elementType, elementName)
+		case *proto.Message:
+			// extensions are in the scope that the extend block is in
+			if element.IsExtend {
+				s.extractElements(element.Elements, prefix, elementType, elementName)
+				continue
+			}
+			name := joinName(prefix, element.Name)
+			s.extractElements(element.Elements, name, ElementTypeMessage, name)
+		case *proto.Group:
+			name := joinName(prefix, element.Name)
+			s.extractElements(element.Elements, name, ElementTypeMessage, name)
+		case *proto.NormalField:
+			s.extractOptions(element.Options, ElementTypeField, joinName(prefix, element.Name))
+		case *proto.MapField:
+			s.extractOptions(element.Options, ElementTypeField, joinName(prefix, element.Name))
+		case *proto.OneOfField:
+			s.extractOptions(element.Options, ElementTypeField, joinName(prefix, element.Name))
+		case *proto.Oneof:
+			// the fields of a oneof are in the scope of the message
+			s.extractElements(element.Elements, prefix, ElementTypeOneof, joinName(prefix, element.Name))
+		case *proto.Enum:
+			name := joinName(prefix, element.Name)
+			s.extractElements(element.Elements, name, ElementTypeEnum, name)
+		case *proto.EnumField:
+			s.extractElements(element.Elements, prefix, ElementTypeEnumValue, joinName(prefix, element.Name))
+		case *proto.Service:
+			name := joinName(prefix, element.Name)
+			s.extractElements(element.Elements, name, ElementTypeService, name)
+		case *proto.RPC:
+			s.extractElements(element.Elements, prefix, ElementTypeRPC, joinName(prefix, element.Name))
+		}
+	}
+}
+
+func (s *extractState) extractOptions(options []*proto.Option, elementType string, elementName string) {
+	for _, option := range options {
+		if !s.matches(option.Name) {
+			continue
+		}
+		s.annotations = append(s.annotations, &Annotation{
+			Filename:    s.filename,
+			Line:        option.Position.Line,
+			Column:      option.Position.Column,
+			ElementType: elementType,
+			Element:     elementName,
+			Option:      option.Name,
+			Value:       getLiteralString(&option.Constant),
+		})
+	}
+}
+
+func (s *extractState) matches(optionName string) bool {
+	if len(s.optionNames) == 0 {
+		return strings.HasPrefix(optionName, "(")
+	}
+	for _, candidate := range s.getCandidateNames(optionName) {
+		for _, name := range s.optionNames {
+			if candidate == name || strings.HasPrefix(candidate, name+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getCandidateNames returns the fully-qualified names that the option
+// name can refer to, starting with the innermost package scope.
+func (s *extractState) getCandidateNames(optionName string) []string {
+	if strings.HasPrefix(optionName, "(.") || !strings.HasPrefix(optionName, "(") || s.pkg == "" {
+		return []string{normalizeOptionName(optionName)}
+	}
+	name := normalizeOptionName(optionName)
+	var candidates []string
+	pkgParts := strings.Split(s.pkg, ".")
+	for i := len(pkgParts); i > 0; i-- {
+		candidates = append(candidates, strings.Join(pkgParts[:i], ".")+"."+name)
+	}
+	return append(candidates, name)
+}
+
+// normalizeOptionName removes the parentheses and leading dot of the
+// option name, so (.foo.rules).max_len becomes foo.rules.max_len.
+func normalizeOptionName(optionName string) string {
+	optionName = strings.Replace(optionName, "(", "", -1)
+	optionName = strings.Replace(optionName, ")", "", -1)
+	return strings.TrimPrefix(optionName, ".")
+}
+
+// getLiteralString returns the literal as written, with aggregate values
+// in the text format.
+func getLiteralString(literal *proto.Literal) string {
+	if len(literal.OrderedMap) > 0 {
+		values := make([]string, 0, len(literal.OrderedMap))
+		for _, namedLiteral := range literal.OrderedMap {
+			values = append(values, namedLiteral.Name+": "+getLiteralString(namedLiteral.Literal))
+		}
+		return "{" + strings.Join(values, ", ") + "}"
+	}
+	if len(literal.Array) > 0 {
+		values := make([]string, 0, len(literal.Array))
+		for _, element := range literal.Array {
+			values = append(values, getLiteralString(element))
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	}
+	return literal.SourceRepresentation()
+}
+
+func joinName(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func parse(path string, displayPath string) (*proto.Proto, error) {
+	osFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	parser, err := editions.NewParser(osFile)
+	_ = osFile.Close()
+	if err != nil {
+		return nil, err
+	}
+	parser.Filename(displayPath)
+	return parser.Parse()
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package annotations
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/file"
+)
+
+const testFileContent = `syntax = "proto3";
+
+package foo.v1;
+
+option go_package = "foov1";
+option (foo.v1.owner) = "identity";
+
+message User {
+  option (visibility) = "internal";
+  string email = 1 [(foo.v1.sensitive) = true, deprecated = true];
+  map<string, string> labels = 2 [(foo.v1.rules).max_len = 10];
+  oneof contact {
+    string phone = 3 [(sensitive) = true];
+  }
+  message Address {
+    string street = 1 [(v1.sensitive) = true];
+  }
+}
+
+enum Role {
+  ROLE_INVALID = 0;
+  ROLE_ADMIN = 1 [(visibility) = "internal"];
+}
+
+service UserAPI {
+  rpc GetUser(User) returns (User) {
+    option (foo.v1.rules) = { max_len: 5, tags: ["a", "b"] };
+  }
+}
+`
+
+func TestExtract(t *testing.T) {
+	protoFiles := writeTestFile(t)
+	defer func() { _ = os.RemoveAll(filepath.Dir(protoFiles[0].Path)) }()
+
+	annotations, err := NewExtractor().Extract(protoFiles, []string{"foo.v1.sensitive", "(foo.v1.rules)"})
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*Annotation{
+			{
+				Filename:    "foo.proto",
+				Line:        10,
+				Column:      20,
+				ElementType: ElementTypeField,
+				Element:     "foo.v1.User.email",
+				Option:      "(foo.v1.sensitive)",
+				Value:       "true",
+			},
+			{
+				Filename:    "foo.proto",
+				Line:        11,
+				Column:      34,
+				ElementType: ElementTypeField,
+				Element:     "foo.v1.User.labels",
+				Option:      "(foo.v1.rules).max_len",
+				Value:       "10",
+			},
+			{
+				Filename:    "foo.proto",
+				Line:        13,
+				Column:      22,
+				ElementType: ElementTypeField,
+				Element:     "foo.v1.User.phone",
+				Option:      "(sensitive)",
+				Value:       "true",
+			},
+			{
+				Filename:    "foo.proto",
+				Line:        16,
+				Column:      23,
+				ElementType: ElementTypeField,
+				Element:     "foo.v1.User.Address.street",
+				Option:      "(v1.sensitive)",
+				Value:       "true",
+			},
+			{
+				Filename:    "foo.proto",
+				Line:        27,
+				Column:      5,
+				ElementType: ElementTypeRPC,
+				Element:     "foo.v1.UserAPI.GetUser",
+				Option:      "(foo.v1.rules)",
+				Value:       `{max_len: 5, tags: ["a", "b"]}`,
+			},
+		},
+		annotations,
+	)
+}
+
+func TestExtractAllCustomOptions(t *testing.T) {
+	protoFiles := writeTestFile(t)
+	defer func() { _ = os.RemoveAll(filepath.Dir(protoFiles[0].Path)) }()
+
+	annotations, err := NewExtractor().Extract(protoFiles, nil)
+	require.NoError(t, err)
+	var elements []string
+	for _, annotation := range annotations {
+		elements = append(elements, annotation.ElementType+" "+annotation.Element+" "+annotation.Option)
+	}
+	assert.Equal(
+		t,
+		[]string{
+			"file foo.v1 (foo.v1.owner)",
+			"message foo.v1.User (visibility)",
+			"field foo.v1.User.email (foo.v1.sensitive)",
+			"field foo.v1.User.labels (foo.v1.rules).max_len",
+			"field foo.v1.User.phone (sensitive)",
+			"field foo.v1.User.Address.street (v1.sensitive)",
+			"enum_value foo.v1.Role.ROLE_ADMIN (visibility)",
+			"rpc foo.v1.UserAPI.GetUser (foo.v1.rules)",
+		},
+		elements,
+	)
+}
+
+func writeTestFile(t *testing.T) []*file.ProtoFile {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	path := filepath.Join(tmpDirPath, "foo.proto")
+	require.NoError(t, ioutil.WriteFile(path, []byte(testFileContent), 0644))
+	return []*file.ProtoFile{
+		{
+			Path:        path,
+			DisplayPath: "foo.proto",
+		},
+	}
+}
@@ -0,0 +1,397 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bazel
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/editions"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/strs"
+	"github.com/uber/prototool/internal/wkt"
+	"go.uber.org/zap"
+)
+
+const (
+	wktProtoRepository = "@com_google_protobuf//"
+	wktGoRepository    = "@io_bazel_rules_go//proto/wkt:"
+	wktGogoLabel       = "@com_github_gogo_protobuf//types:go_default_library"
+
+	goProtoCompiler   = "@io_bazel_rules_go//proto:go_proto"
+	goGRPCCompiler    = "@io_bazel_rules_go//proto:go_grpc"
+	gogoProtoCompiler = "@io_bazel_rules_go//proto:gogo_proto"
+	gogoGRPCCompiler  = "@io_bazel_rules_go//proto:gogo_grpc"
+)
+
+var workspaceFilenames = []string{
+	"WORKSPACE",
+	"WORKSPACE.bazel",
+}
+
+type generator struct {
+	logger *zap.Logger
+}
+
+func newGenerator(options ...GeneratorOption) *generator {
+	generator := &generator{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(generator)
+	}
+	return generator
+}
+
+func (g *generator) Generate(protoSet *file.ProtoSet) ([]*BuildFile, error) {
+	configDirPath := protoSet.Config.DirPath
+	if configDirPath == "" {
+		configDirPath = protoSet.WorkDirPath
+	}
+	workspaceDirPath := getWorkspaceDirPath(configDirPath)
+	g.logger.Debug("using workspace", zap.String("workspaceDirPath", workspaceDirPath))
+
+	goPlugin, hasGoPlugin := getGoPlugin(protoSet.Config.Gen)
+	dirPathToPackage := make(map[string]*buildPackage, len(protoSet.DirPathToFiles))
+	// the import path of every file relative to the config directory or
+	// the root the file is in, which is always passed to protoc with -I,
+	// to the directory the file is in
+	importPathToDirPath := make(map[string]string)
+	for dirPath, protoFiles := range protoSet.DirPathToFiles {
+		buildPackage, err := newBuildPackage(workspaceDirPath, file.RootDirPath(protoSet, dirPath), dirPath, protoFiles)
+		if err != nil {
+			return nil, err
+		}
+		buildPackage.GRPC = hasGoPlugin && buildPackage.HasServices && hasGRPCFlag(goPlugin)
+		dirPathToPackage[dirPath] = buildPackage
+		for _, protoFile := range protoFiles {
+			importPath, err := file.ImportPath(protoSet, protoFile.Path)
+			if err != nil {
+				return nil, err
+			}
+			importPathToDirPath[importPath] = dirPath
+		}
+	}
+
+	buildFiles := make([]*BuildFile, 0, len(dirPathToPackage))
+	for dirPath, buildPackage := range dirPathToPackage {
+		var protoDeps []string
+		var goDeps []string
+		for _, importPath := range buildPackage.Imports {
+			if _, ok := wkt.Filenames[importPath]; ok {
+				protoDeps = append(protoDeps, getWKTProtoLabel(importPath))
+				if hasGoPlugin {
+					goDeps = append(goDeps, getWKTGoLabel(goPlugin, importPath))
+				}
+				continue
+			}
+			importDirPath, ok := importPathToDirPath[importPath]
+			if !ok {
+				// this is most likely a file on one of the protoc_includes paths,
+				// which we have no way of mapping to a Bazel label
+				g.logger.Warn("could not resolve import to a Bazel label", zap.String("dirPath", dirPath), zap.String("import", importPath))
+				continue
+			}
+			if importDirPath == dirPath {
+				continue
+			}
+			importPackage := dirPathToPackage[importDirPath]
+			protoDeps = append(protoDeps, importPackage.Label(buildPackage, importPackage.ProtoName()))
+			if hasGoPlugin {
+				goDeps = append(goDeps, importPackage.Label(buildPackage, importPackage.GoName()))
+			}
+		}
+		buffer := bytes.NewBuffer(nil)
+		writeHeader(buffer, buildPackage, hasGoPlugin)
+		writeProtoLibrary(buffer, buildPackage, strs.DedupeSort(protoDeps, nil))
+		if hasGoPlugin {
+			goImportPath, err := getGoImportPath(protoSet.Config, goPlugin, file.RootDirPath(protoSet, dirPath), dirPath)
+			if err != nil {
+				return nil, err
+			}
+			if buildPackage.GRPC {
+				writeGoGRPCLibrary(buffer, buildPackage, goPlugin, goImportPath, strs.DedupeSort(goDeps, nil))
+			} else {
+				writeGoProtoLibrary(buffer, buildPackage, goPlugin, goImportPath, strs.DedupeSort(goDeps, nil))
+			}
+		}
+		buildFiles = append(buildFiles, &BuildFile{
+			Path: filepath.Join(dirPath, DefaultBuildFilename),
+			Data: buffer.Bytes(),
+		})
+	}
+	sort.Slice(buildFiles, func(i int, j int) bool { return buildFiles[i].Path < buildFiles[j].Path })
+	return buildFiles, nil
+}
+
+type buildPackage struct {
+	// The Bazel package path relative to the workspace, "" for the root package.
+	PackagePath string
+	// The value for strip_import_prefix, "" if not needed.
+	StripImportPrefix string
+	// The base name used for rules.
+	BaseName string
+	// The basenames of the .proto files in this package, sorted.
+	Srcs []string
+	// The imports of all files in this package, deduped and sorted.
+	Imports []string
+	// Whether any file in this package declares a service.
+	HasServices bool
+	// Whether a go_grpc_library rule is written for this package
+	// instead of a go_proto_library rule.
+	GRPC bool
+}
+
+func newBuildPackage(workspaceDirPath string, rootDirPath string, dirPath string, protoFiles []*file.ProtoFile) (*buildPackage, error) {
+	packagePath, err := filepath.Rel(workspaceDirPath, dirPath)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(packagePath, "..") {
+		return nil, fmt.Errorf("directory %s is outside of the Bazel workspace %s", dirPath, workspaceDirPath)
+	}
+	packagePath = filepath.ToSlash(packagePath)
+	if packagePath == "." {
+		packagePath = ""
+	}
+	stripImportPrefix := ""
+	if rootDirPath != workspaceDirPath {
+		rel, err := filepath.Rel(workspaceDirPath, rootDirPath)
+		if err != nil {
+			return nil, err
+		}
+		stripImportPrefix = "/" + filepath.ToSlash(rel)
+	}
+	buildPackage := &buildPackage{
+		PackagePath:       packagePath,
+		StripImportPrefix: stripImportPrefix,
+		BaseName:          getBaseName(dirPath),
+	}
+	var imports []string
+	for _, protoFile := range protoFiles {
+		buildPackage.Srcs = append(buildPackage.Srcs, filepath.Base(protoFile.Path))
+		descriptor, err := parse(protoFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, element := range descriptor.Elements {
+			switch e := element.(type) {
+			case *proto.Import:
+				imports = append(imports, e.Filename)
+			case *proto.Service:
+				buildPackage.HasServices = true
+			}
+		}
+	}
+	sort.Strings(buildPackage.Srcs)
+	buildPackage.Imports = strs.DedupeSort(imports, nil)
+	return buildPackage, nil
+}
+
+func (b *buildPackage) ProtoName() string {
+	return b.BaseName + "_proto"
+}
+
+// GoName returns the name of the Go rule, which is the go_grpc_library
+// rule if the package has one.
+func (b *buildPackage) GoName() string {
+	if b.GRPC {
+		return b.BaseName + "_go_grpc"
+	}
+	return b.BaseName + "_go_proto"
+}
+
+// Label returns the label for the rule name in this package as referenced from the given package.
+func (b *buildPackage) Label(from *buildPackage, name string) string {
+	if from.PackagePath == b.PackagePath {
+		return ":" + name
+	}
+	return "//" + b.PackagePath + ":" + name
+}
+
+func writeHeader(buffer *bytes.Buffer, buildPackage *buildPackage, hasGoPlugin bool) {
+	buffer.WriteString("# Code generated by prototool bazel gen. DO NOT EDIT.\n")
+	if hasGoPlugin {
+		rule := "go_proto_library"
+		if buildPackage.GRPC {
+			rule = "go_grpc_library"
+		}
+		_, _ = fmt.Fprintf(buffer, "\nload(\"@io_bazel_rules_go//proto:def.bzl\", %q)\n", rule)
+	}
+}
+
+func writeProtoLibrary(buffer *bytes.Buffer, buildPackage *buildPackage, deps []string) {
+	buffer.WriteString("\nproto_library(\n")
+	writeStringAttr(buffer, "name", buildPackage.ProtoName())
+	writeListAttr(buffer, "srcs", buildPackage.Srcs)
+	if buildPackage.StripImportPrefix != "" {
+		writeStringAttr(buffer, "strip_import_prefix", buildPackage.StripImportPrefix)
+	}
+	writeListAttr(buffer, "visibility", []string{"//visibility:public"})
+	writeListAttr(buffer, "deps", deps)
+	buffer.WriteString(")\n")
+}
+
+func writeGoProtoLibrary(buffer *bytes.Buffer, buildPackage *buildPackage, goPlugin settings.GenPlugin, goImportPath string, deps []string) {
+	buffer.WriteString("\ngo_proto_library(\n")
+	writeGoLibraryAttrs(buffer, buildPackage, goPlugin, goImportPath, deps)
+	buffer.WriteString(")\n")
+}
+
+// the go_grpc_library rule has the same attributes as go_proto_library, and
+// Bazel builds the messages and the gRPC stubs of the package as one library
+func writeGoGRPCLibrary(buffer *bytes.Buffer, buildPackage *buildPackage, goPlugin settings.GenPlugin, goImportPath string, deps []string) {
+	buffer.WriteString("\ngo_grpc_library(\n")
+	writeGoLibraryAttrs(buffer, buildPackage, goPlugin, goImportPath, deps)
+	buffer.WriteString(")\n")
+}
+
+func writeGoLibraryAttrs(buffer *bytes.Buffer, buildPackage *buildPackage, goPlugin settings.GenPlugin, goImportPath string, deps []string) {
+	writeStringAttr(buffer, "name", buildPackage.GoName())
+	writeListAttr(buffer, "compilers", []string{getGoCompiler(goPlugin, buildPackage.GRPC)})
+	writeStringAttr(buffer, "importpath", goImportPath)
+	writeStringAttr(buffer, "proto", ":"+buildPackage.ProtoName())
+	writeListAttr(buffer, "visibility", []string{"//visibility:public"})
+	writeListAttr(buffer, "deps", deps)
+}
+
+func writeStringAttr(buffer *bytes.Buffer, key string, value string) {
+	_, _ = fmt.Fprintf(buffer, "    %s = %q,\n", key, value)
+}
+
+func writeListAttr(buffer *bytes.Buffer, key string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	if len(values) == 1 {
+		_, _ = fmt.Fprintf(buffer, "    %s = [%q],\n", key, values[0])
+		return
+	}
+	_, _ = fmt.Fprintf(buffer, "    %s = [\n", key)
+	for _, value := range values {
+		_, _ = fmt.Fprintf(buffer, "        %q,\n", value)
+	}
+	buffer.WriteString("    ],\n")
+}
+
+// getGoPlugin returns the first go or gogo plugin, if any.
+//
+// Plugins are sorted by name in settings, so this is deterministic.
+func getGoPlugin(genConfig settings.GenConfig) (settings.GenPlugin, bool) {
+	for _, genPlugin := range genConfig.Plugins {
+		if genPlugin.Type.IsGo() || genPlugin.Type.IsGogo() {
+			return genPlugin, true
+		}
+	}
+	return settings.GenPlugin{}, false
+}
+
+// this mirrors the Mfile=package modifiers computed in internal/protoc
+func getGoImportPath(config settings.Config, goPlugin settings.GenPlugin, rootDirPath string, dirPath string) (string, error) {
+	rel, err := filepath.Rel(rootDirPath, dirPath)
+	if err != nil {
+		return "", err
+	}
+	return path.Clean(path.Join(config.Gen.GoPluginOptions.ImportPath, filepath.ToSlash(goPlugin.OutputPath.RelPath), filepath.ToSlash(rel))), nil
+}
+
+func hasGRPCFlag(goPlugin settings.GenPlugin) bool {
+	return strings.Contains(goPlugin.Flags, "plugins=grpc")
+}
+
+func getGoCompiler(goPlugin settings.GenPlugin, withGRPC bool) string {
+	if goPlugin.Type.IsGogo() {
+		if withGRPC {
+			return gogoGRPCCompiler
+		}
+		return gogoProtoCompiler
+	}
+	if withGRPC {
+		return goGRPCCompiler
+	}
+	return goProtoCompiler
+}
+
+// google/protobuf/compiler/plugin.proto -> @com_google_protobuf//:compiler_plugin_proto
+func getWKTProtoLabel(importPath string) string {
+	return wktProtoRepository + ":" + getWKTName(importPath) + "_proto"
+}
+
+func getWKTGoLabel(goPlugin settings.GenPlugin, importPath string) string {
+	if goPlugin.Type.IsGogo() {
+		return wktGogoLabel
+	}
+	return wktGoRepository + getWKTName(importPath) + "_go_proto"
+}
+
+func getWKTName(importPath string) string {
+	name := strings.TrimSuffix(strings.TrimPrefix(importPath, "google/protobuf/"), ".proto")
+	return strings.Replace(name, "/", "_", -1)
+}
+
+func getBaseName(dirPath string) string {
+	base := filepath.Base(dirPath)
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, base)
+}
+
+// getWorkspaceDirPath goes up from the given directory until it finds a
+// WORKSPACE file. If none is found, the given directory is returned.
+func getWorkspaceDirPath(dirPath string) string {
+	current := dirPath
+	for {
+		for _, workspaceFilename := range workspaceFilenames {
+			if _, err := os.Stat(filepath.Join(current, workspaceFilename)); err == nil {
+				return current
+			}
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dirPath
+		}
+		current = parent
+	}
+}
+
+func parse(protoFile *file.ProtoFile) (*proto.Proto, error) {
+	file, err := os.Open(protoFile.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	parser, err := editions.NewParser(file)
+	if err != nil {
+		return nil, err
+	}
+	parser.Filename(protoFile.DisplayPath)
+	return parser.Parse()
+}
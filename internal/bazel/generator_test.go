@@ -0,0 +1,64 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package bazel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/file"
+)
+
+func TestGenerateSinglePackage(t *testing.T) {
+	assertGolden(t, "testdata/single", "foo/BUILD.bazel")
+}
+
+func TestGenerateMultiplePackages(t *testing.T) {
+	assertGolden(t, "testdata/multi", "bar/BUILD.bazel", "foo/BUILD.bazel")
+}
+
+func TestGenerateServices(t *testing.T) {
+	assertGolden(t, "testdata/grpc", "bar/BUILD.bazel", "baz/BUILD.bazel", "foo/BUILD.bazel")
+}
+
+func assertGolden(t *testing.T, dirPath string, expectedRelPaths ...string) {
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	absDirPath, err := filepath.Abs(dirPath)
+	require.NoError(t, err)
+	protoSet, err := file.NewProtoSetProvider().GetForDir(cwd, absDirPath)
+	require.NoError(t, err)
+	buildFiles, err := newGenerator().Generate(protoSet)
+	require.NoError(t, err)
+	require.Len(t, buildFiles, len(expectedRelPaths))
+	for i, buildFile := range buildFiles {
+		relPath, err := filepath.Rel(absDirPath, buildFile.Path)
+		require.NoError(t, err)
+		assert.Equal(t, expectedRelPaths[i], filepath.ToSlash(relPath))
+		golden, err := ioutil.ReadFile(buildFile.Path + ".golden")
+		require.NoError(t, err)
+		assert.Equal(t, string(golden), string(buildFile.Data), buildFile.Path)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package breaking checks for breaking changes between the previous
+// and current versions of Protobuf files, and diffs their schemas.
+package breaking
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/uber/prototool/internal/text"
+	"go.uber.org/zap"
+)
+
+// ExceptionDateLayout is the layout of the expiry date of an Exception.
+const ExceptionDateLayout = "2006-01-02"
+
+// File is a file to check.
+type File struct {
+	// The filename to use for failures.
+	Filename string
+	// The data of the previous version of the file.
+	// This will be nil if the file is new.
+	PreviousData []byte
+	// The data of the current version of the file.
+	CurrentData []byte
+}
+
+// Exception is a one-time exception for an intentional breaking change.
+type Exception struct {
+	// The ID of the check, for example FIELDS_RESERVED_ON_DELETE.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+	// The fully-qualified type, for example foo.v1.Bar, that the exception
+	// applies to, including the types nested within it.
+	// If empty, the exception applies to all types.
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// Why the breaking change is intentional.
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	// The last day the exception applies, in the form of ExceptionDateLayout.
+	Expires string `json:"expires,omitempty" yaml:"expires,omitempty"`
+}
+
+// ParseExceptions parses the YAML or JSON data of an exceptions file, which
+// has the key "exceptions" with a list of Exceptions.
+//
+// Every Exception must have an ID, a reason, and an expiry date.
+func ParseExceptions(data []byte) ([]*Exception, error) {
+	var exceptionsFile struct {
+		Exceptions []*Exception `json:"exceptions,omitempty" yaml:"exceptions,omitempty"`
+	}
+	if err := yaml.Unmarshal(data, &exceptionsFile); err != nil {
+		return nil, err
+	}
+	for i, exception := range exceptionsFile.Exceptions {
+		if exception.ID == "" {
+			return nil, fmt.Errorf("exception %d has no id", i)
+		}
+		if exception.Reason == "" {
+			return nil, fmt.Errorf("exception %d for %s has no reason", i, exception.ID)
+		}
+		if _, err := time.Parse(ExceptionDateLayout, exception.Expires); err != nil {
+			return nil, fmt.Errorf("exception %d for %s must have an expiry date of the form YYYY-MM-DD but had %q", i, exception.ID, exception.Expires)
+		}
+	}
+	return exceptionsFile.Exceptions, nil
+}
+
+// Checker checks for breaking changes.
+type Checker interface {
+	// Check checks the current version of each file against the previous version.
+	//
+	// New files are not checked.
+	Check(files ...*File) ([]*text.Failure, error)
+}
+
+// CheckerOption is an option for a new Checker.
+type CheckerOption func(*checker)
+
+// CheckerWithLogger returns a CheckerOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func CheckerWithLogger(logger *zap.Logger) CheckerOption {
+	return func(checker *checker) {
+		checker.logger = logger
+	}
+}
+
+// CheckerWithIgnoreIDs returns a CheckerOption that does not run the checks
+// with the given IDs.
+func CheckerWithIgnoreIDs(ids ...string) CheckerOption {
+	return func(checker *checker) {
+		checker.ignoreIDs = append(checker.ignoreIDs, ids...)
+	}
+}
+
+// CheckerWithIgnoreTypes returns a CheckerOption that does not check the given
+// fully-qualified types, for example foo.v1.Bar, or the types nested within them.
+func CheckerWithIgnoreTypes(types ...string) CheckerOption {
+	return func(checker *checker) {
+		checker.ignoreTypes = append(checker.ignoreTypes, types...)
+	}
+}
+
+// CheckerWithExceptions returns a CheckerOption that allows the breaking changes
+// matching the given Exceptions until they expire.
+//
+// Failures that only match expired Exceptions say when the Exception expired.
+func CheckerWithExceptions(exceptions ...*Exception) CheckerOption {
+	return func(checker *checker) {
+		checker.exceptions = append(checker.exceptions, exceptions...)
+	}
+}
+
+// NewChecker returns a new Checker.
+func NewChecker(options ...CheckerOption) Checker {
+	return newChecker(options...)
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package breaking
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// changelogSections are the sections of each package in a changelog,
+// in order, with the kinds of changes in each section.
+var changelogSections = []struct {
+	title string
+	kinds []string
+}{
+	{"Added", []string{"added"}},
+	{"Changed", []string{"renumbered", "type changed"}},
+	{"Deprecated", []string{"deprecated"}},
+	{"Removed", []string{"removed"}},
+}
+
+// Commit is a commit and the changes it made.
+type Commit struct {
+	Hash    string
+	Subject string
+	Changes []*Change
+}
+
+// WriteChangelog writes a Markdown changelog of the changes made by the
+// commits, which are in chronological order.
+//
+// The changelog has a section per package, sorted by package, with the
+// changes split into Added, Changed, Deprecated, and Removed. Each change
+// refers to the commit that made it.
+func WriteChangelog(writer io.Writer, commits ...*Commit) error {
+	type entry struct {
+		commit *Commit
+		change *Change
+	}
+	pkgToEntries := make(map[string][]*entry)
+	for _, commit := range commits {
+		for _, change := range commit.Changes {
+			pkgToEntries[change.Package] = append(pkgToEntries[change.Package], &entry{commit: commit, change: change})
+		}
+	}
+	pkgs := make([]string, 0, len(pkgToEntries))
+	for pkg := range pkgToEntries {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	buffer := bytes.NewBuffer(nil)
+	for i, pkg := range pkgs {
+		if i > 0 {
+			buffer.WriteString("\n")
+		}
+		if pkg == "" {
+			buffer.WriteString("## (no package)\n")
+		} else {
+			fmt.Fprintf(buffer, "## %s\n", pkg)
+		}
+		for _, section := range changelogSections {
+			var lines []string
+			for _, entry := range pkgToEntries[pkg] {
+				for _, kind := range section.kinds {
+					if entry.change.Kind == kind {
+						lines = append(lines, fmt.Sprintf("- %s (%s %s)", describeChange(entry.change), shortHash(entry.commit.Hash), entry.commit.Subject))
+					}
+				}
+			}
+			if len(lines) == 0 {
+				continue
+			}
+			fmt.Fprintf(buffer, "\n### %s\n\n", section.title)
+			for _, line := range lines {
+				buffer.WriteString(line)
+				buffer.WriteString("\n")
+			}
+		}
+	}
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+// describeChange describes the change without the kind for added, removed,
+// and deprecated changes, as the section of the changelog says the kind.
+func describeChange(change *Change) string {
+	switch change.Kind {
+	case "renumbered", "type changed":
+		return fmt.Sprintf("%s `%s` %s from `%s` to `%s`", change.Element, change.Name, change.Kind, change.Previous, change.Current)
+	default:
+		return fmt.Sprintf("%s `%s`", change.Element, change.Name)
+	}
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package breaking
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteChangelog(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(
+		t,
+		WriteChangelog(
+			buffer,
+			&Commit{
+				Hash:    "0123456789abcdef",
+				Subject: "Add Bar",
+				Changes: []*Change{
+					{Kind: "added", Element: "message", Name: "foo.v1.Bar", Package: "foo.v1"},
+					{Kind: "removed", Element: "field", Name: "foo.v1.Foo.one", Package: "foo.v1"},
+				},
+			},
+			&Commit{
+				Hash:    "fedcba9876543210",
+				Subject: "Deprecate Bar",
+				Changes: []*Change{
+					{Kind: "added", Element: "package", Name: "bar.v1", Package: "bar.v1"},
+					{Kind: "deprecated", Element: "message", Name: "foo.v1.Bar", Package: "foo.v1"},
+					{Kind: "renumbered", Element: "field", Name: "foo.v1.Foo.two", Package: "foo.v1", Previous: "2", Current: "3"},
+				},
+			},
+		),
+	)
+	assert.Equal(t, "## bar.v1\n"+
+		"\n"+
+		"### Added\n"+
+		"\n"+
+		"- package `bar.v1` (fedcba9 Deprecate Bar)\n"+
+		"\n"+
+		"## foo.v1\n"+
+		"\n"+
+		"### Added\n"+
+		"\n"+
+		"- message `foo.v1.Bar` (0123456 Add Bar)\n"+
+		"\n"+
+		"### Changed\n"+
+		"\n"+
+		"- field `foo.v1.Foo.two` renumbered from `2` to `3` (fedcba9 Deprecate Bar)\n"+
+		"\n"+
+		"### Deprecated\n"+
+		"\n"+
+		"- message `foo.v1.Bar` (fedcba9 Deprecate Bar)\n"+
+		"\n"+
+		"### Removed\n"+
+		"\n"+
+		"- field `foo.v1.Foo.one` (0123456 Add Bar)\n",
+		buffer.String(),
+	)
+}
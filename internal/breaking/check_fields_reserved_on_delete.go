@@ -0,0 +1,206 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package breaking
+
+import (
+	"sort"
+	"strings"
+	"text/scanner"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+// checkFieldsReservedOnDelete verifies that message fields and enum values
+// that were deleted have their numbers and names reserved, so that they
+// cannot be accidentally reused.
+func checkFieldsReservedOnDelete(add func(string, *text.Failure), previous *proto.Proto, current *proto.Proto) {
+	pkg := getPackage(current)
+	previousContainers := getContainers(previous)
+	currentContainers := getContainers(current)
+	names := make([]string, 0, len(previousContainers))
+	for name := range previousContainers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		previousContainer := previousContainers[name]
+		currentContainer, ok := currentContainers[name]
+		// deleting an entire message or enum is a different breaking change
+		if !ok || currentContainer.kind != previousContainer.kind {
+			continue
+		}
+		for _, field := range previousContainer.fields {
+			if currentContainer.hasNumber(field.number) {
+				continue
+			}
+			var unreserved []string
+			if !currentContainer.isReservedNumber(field.number) {
+				unreserved = append(unreserved, "number")
+			}
+			if _, ok := currentContainer.reservedNames[field.name]; !ok {
+				unreserved = append(unreserved, "name")
+			}
+			if len(unreserved) > 0 {
+				add(qualify(pkg, currentContainer.name), text.NewFailuref(
+					currentContainer.position,
+					"",
+					"%s %q with number %d was deleted from %s %q without reserving its %s.",
+					capitalize(currentContainer.fieldKind()),
+					field.name,
+					field.number,
+					currentContainer.kind,
+					currentContainer.name,
+					strings.Join(unreserved, " and "),
+				))
+			}
+		}
+	}
+}
+
+// capitalize returns the string with its first letter in upper case.
+//
+// This differs from strings.Title, which capitalizes every word.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+type containerField struct {
+	name   string
+	number int
+}
+
+// container is a message or enum.
+type container struct {
+	// message or enum
+	kind           string
+	name           string
+	position       scanner.Position
+	fields         []*containerField
+	reservedRanges []proto.Range
+	reservedNames  map[string]struct{}
+}
+
+func newContainer(kind string, name string, position scanner.Position) *container {
+	return &container{
+		kind:          kind,
+		name:          name,
+		position:      position,
+		reservedNames: make(map[string]struct{}),
+	}
+}
+
+func (c *container) fieldKind() string {
+	if c.kind == "enum" {
+		return "enum value"
+	}
+	return "field"
+}
+
+func (c *container) addField(name string, number int) {
+	c.fields = append(c.fields, &containerField{name: name, number: number})
+}
+
+func (c *container) addReserved(reserved *proto.Reserved) {
+	c.reservedRanges = append(c.reservedRanges, reserved.Ranges...)
+	for _, fieldName := range reserved.FieldNames {
+		c.reservedNames[fieldName] = struct{}{}
+	}
+}
+
+func (c *container) hasNumber(number int) bool {
+	for _, field := range c.fields {
+		if field.number == number {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *container) isReservedNumber(number int) bool {
+	for _, reservedRange := range c.reservedRanges {
+		if number == reservedRange.From {
+			return true
+		}
+		if number > reservedRange.From && (reservedRange.Max || number <= reservedRange.To) {
+			return true
+		}
+	}
+	return false
+}
+
+// getContainers returns the messages and enums in the descriptor keyed
+// by their nested names, for example Foo.Bar.
+func getContainers(descriptor *proto.Proto) map[string]*container {
+	containers := make(map[string]*container)
+	for _, element := range descriptor.Elements {
+		switch t := element.(type) {
+		case *proto.Message:
+			addMessageContainers(containers, "", t)
+		case *proto.Enum:
+			addEnumContainer(containers, "", t)
+		}
+	}
+	return containers
+}
+
+func addMessageContainers(containers map[string]*container, prefix string, message *proto.Message) {
+	c := newContainer("message", prefix+message.Name, message.Position)
+	containers[c.name] = c
+	for _, element := range message.Elements {
+		switch t := element.(type) {
+		case *proto.NormalField:
+			c.addField(t.Name, t.Sequence)
+		case *proto.MapField:
+			c.addField(t.Name, t.Sequence)
+		case *proto.Group:
+			c.addField(t.Name, t.Sequence)
+		case *proto.Oneof:
+			for _, oneofElement := range t.Elements {
+				if oneOfField, ok := oneofElement.(*proto.OneOfField); ok {
+					c.addField(oneOfField.Name, oneOfField.Sequence)
+				}
+			}
+		case *proto.Reserved:
+			c.addReserved(t)
+		case *proto.Message:
+			addMessageContainers(containers, c.name+".", t)
+		case *proto.Enum:
+			addEnumContainer(containers, c.name+".", t)
+		}
+	}
+}
+
+func addEnumContainer(containers map[string]*container, prefix string, enum *proto.Enum) {
+	c := newContainer("enum", prefix+enum.Name, enum.Position)
+	containers[c.name] = c
+	for _, element := range enum.Elements {
+		switch t := element.(type) {
+		case *proto.EnumField:
+			c.addField(t.Name, t.Integer)
+		case *proto.Reserved:
+			c.addReserved(t)
+		}
+	}
+}
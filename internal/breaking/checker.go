@@ -0,0 +1,186 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package breaking
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/editions"
+	"github.com/uber/prototool/internal/text"
+	"go.uber.org/zap"
+)
+
+// checkFunc adds failures for breaking changes from previous to current,
+// along with the fully-qualified type that each breaking change is in.
+type checkFunc func(add func(string, *text.Failure), previous *proto.Proto, current *proto.Proto)
+
+type check struct {
+	ID string
+	f  checkFunc
+}
+
+var allChecks = []*check{
+	{
+		ID: "FIELDS_RESERVED_ON_DELETE",
+		f:  checkFieldsReservedOnDelete,
+	},
+}
+
+type checker struct {
+	logger      *zap.Logger
+	ignoreIDs   []string
+	ignoreTypes []string
+	exceptions  []*Exception
+	now         func() time.Time
+}
+
+func newChecker(options ...CheckerOption) *checker {
+	checker := &checker{
+		logger: zap.NewNop(),
+		now:    time.Now,
+	}
+	for _, option := range options {
+		option(checker)
+	}
+	return checker
+}
+
+func (c *checker) Check(files ...*File) ([]*text.Failure, error) {
+	checks, err := c.getChecks()
+	if err != nil {
+		return nil, err
+	}
+	var failures []*text.Failure
+	for _, file := range files {
+		if file.PreviousData == nil {
+			c.logger.Debug("skipping new file", zap.String("filename", file.Filename))
+			continue
+		}
+		previous, err := parse(file.Filename, file.PreviousData)
+		if err != nil {
+			return nil, err
+		}
+		current, err := parse(file.Filename, file.CurrentData)
+		if err != nil {
+			return nil, err
+		}
+		for _, check := range checks {
+			check.f(
+				func(typeName string, failure *text.Failure) {
+					failure.ID = check.ID
+					if c.isAllowed(typeName, failure) {
+						return
+					}
+					failures = append(failures, failure)
+				},
+				previous,
+				current,
+			)
+		}
+	}
+	return failures, nil
+}
+
+func (c *checker) getChecks() ([]*check, error) {
+	idToCheck := make(map[string]*check, len(allChecks))
+	for _, check := range allChecks {
+		idToCheck[check.ID] = check
+	}
+	ignoreIDs := make(map[string]struct{}, len(c.ignoreIDs))
+	for _, id := range c.ignoreIDs {
+		if _, ok := idToCheck[id]; !ok {
+			return nil, fmt.Errorf("unknown breaking check id: %s", id)
+		}
+		ignoreIDs[id] = struct{}{}
+	}
+	for _, exception := range c.exceptions {
+		if _, ok := idToCheck[exception.ID]; !ok {
+			return nil, fmt.Errorf("unknown breaking check id in exception: %s", exception.ID)
+		}
+	}
+	checks := make([]*check, 0, len(allChecks))
+	for _, check := range allChecks {
+		if _, ok := ignoreIDs[check.ID]; !ok {
+			checks = append(checks, check)
+		}
+	}
+	return checks, nil
+}
+
+// isAllowed returns true if the failure for the type is ignored or has an
+// unexpired exception. If the failure only has expired exceptions, the
+// message of the failure is updated to say so.
+func (c *checker) isAllowed(typeName string, failure *text.Failure) bool {
+	for _, ignoreType := range c.ignoreTypes {
+		if typeIn(typeName, ignoreType) {
+			return true
+		}
+	}
+	today := c.now().UTC().Format(ExceptionDateLayout)
+	for _, exception := range c.exceptions {
+		if exception.ID != failure.ID || (exception.Type != "" && !typeIn(typeName, exception.Type)) {
+			continue
+		}
+		// dates in this layout sort lexicographically
+		if today <= exception.Expires {
+			return true
+		}
+		failure.Message = fmt.Sprintf("%s The exception for this change expired on %s.", failure.Message, exception.Expires)
+	}
+	return false
+}
+
+// typeIn returns true if the type is the parent type or nested within it.
+func typeIn(typeName string, parentTypeName string) bool {
+	return typeName == parentTypeName || strings.HasPrefix(typeName, parentTypeName+".")
+}
+
+func parse(filename string, data []byte) (*proto.Proto, error) {
+	parser, err := editions.NewParser(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	parser.Filename(filename)
+	return parser.Parse()
+}
+
+// getPackage returns the package of the descriptor, or an empty string
+// if there is no package.
+func getPackage(descriptor *proto.Proto) string {
+	for _, element := range descriptor.Elements {
+		if pkg, ok := element.(*proto.Package); ok {
+			return pkg.Name
+		}
+	}
+	return ""
+}
+
+// qualify returns the fully-qualified name of the type in the package.
+func qualify(pkg string, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
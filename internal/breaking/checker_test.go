@@ -0,0 +1,201 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package breaking
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/text"
+)
+
+func TestCheckFieldsReservedOnDelete(t *testing.T) {
+	previous := `syntax = "proto3";
+
+message Foo {
+  int64 one = 1;
+  int64 two = 2;
+  int64 three = 3;
+  message Bar {
+    int64 one = 1;
+  }
+}
+
+enum Hello {
+  HELLO_INVALID = 0;
+  HELLO_ONE = 1;
+}
+`
+	current := `syntax = "proto3";
+
+message Foo {
+  reserved 2;
+  reserved "two", "three";
+  int64 one = 1;
+  message Bar {}
+}
+
+enum Hello {
+  HELLO_INVALID = 0;
+}
+`
+	failures, err := NewChecker().Check(
+		&File{
+			Filename:     "foo.proto",
+			PreviousData: []byte(previous),
+			CurrentData:  []byte(current),
+		},
+		&File{
+			Filename:    "new.proto",
+			CurrentData: []byte(`syntax = "proto3";`),
+		},
+	)
+	require.NoError(t, err)
+	var messages []string
+	for _, failure := range failures {
+		assert.Equal(t, "FIELDS_RESERVED_ON_DELETE", failure.ID)
+		messages = append(messages, failure.Message)
+	}
+	sort.Strings(messages)
+	assert.Equal(
+		t,
+		[]string{
+			`Enum value "HELLO_ONE" with number 1 was deleted from enum "Hello" without reserving its number and name.`,
+			`Field "one" with number 1 was deleted from message "Foo.Bar" without reserving its number and name.`,
+			`Field "three" with number 3 was deleted from message "Foo" without reserving its number.`,
+		},
+		messages,
+	)
+}
+
+func TestCheckAllowlist(t *testing.T) {
+	file := &File{
+		Filename: "foo.proto",
+		PreviousData: []byte(`syntax = "proto3";
+
+package foo.v1;
+
+message Foo {
+  int64 one = 1;
+  message Bar {
+    int64 one = 1;
+  }
+}
+
+message Baz {
+  int64 one = 1;
+}
+`),
+		CurrentData: []byte(`syntax = "proto3";
+
+package foo.v1;
+
+message Foo {
+  message Bar {}
+}
+
+message Baz {}
+`),
+	}
+	now := func() time.Time { return time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC) }
+	check := func(t *testing.T, expectedTypes []string, options ...CheckerOption) []*text.Failure {
+		checker := newChecker(options...)
+		checker.now = now
+		failures, err := checker.Check(file)
+		require.NoError(t, err)
+		var messages []string
+		for _, failure := range failures {
+			messages = append(messages, failure.Message)
+		}
+		require.Len(t, messages, len(expectedTypes), strings.Join(messages, "\n"))
+		for i, expectedType := range expectedTypes {
+			assert.Contains(t, messages[i], expectedType)
+		}
+		return failures
+	}
+
+	check(t, []string{`"Baz"`, `"Foo"`, `"Foo.Bar"`})
+	check(t, nil, CheckerWithIgnoreIDs("FIELDS_RESERVED_ON_DELETE"))
+	check(t, []string{`"Baz"`}, CheckerWithIgnoreTypes("foo.v1.Foo"))
+	check(t, []string{`"Baz"`, `"Foo"`}, CheckerWithIgnoreTypes("foo.v1.Foo.Bar"))
+	check(
+		t,
+		[]string{`"Baz"`},
+		CheckerWithExceptions(
+			&Exception{ID: "FIELDS_RESERVED_ON_DELETE", Type: "foo.v1.Foo", Reason: "test", Expires: "2020-06-15"},
+		),
+	)
+	failures := check(
+		t,
+		[]string{`"Baz"`, `"Foo"`, `"Foo.Bar"`},
+		CheckerWithExceptions(
+			&Exception{ID: "FIELDS_RESERVED_ON_DELETE", Type: "foo.v1.Foo", Reason: "test", Expires: "2020-06-14"},
+		),
+	)
+	assert.Contains(t, failures[1].Message, "The exception for this change expired on 2020-06-14.")
+	check(
+		t,
+		nil,
+		CheckerWithExceptions(
+			&Exception{ID: "FIELDS_RESERVED_ON_DELETE", Reason: "test", Expires: "2021-01-01"},
+		),
+	)
+
+	_, err := NewChecker(CheckerWithIgnoreIDs("UNKNOWN")).Check(file)
+	assert.Error(t, err)
+}
+
+func TestParseExceptions(t *testing.T) {
+	exceptions, err := ParseExceptions([]byte(`exceptions:
+  - id: FIELDS_RESERVED_ON_DELETE
+    type: foo.v1.Foo
+    reason: Removing a field that was never used.
+    expires: 2020-06-15
+`))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*Exception{
+			{
+				ID:      "FIELDS_RESERVED_ON_DELETE",
+				Type:    "foo.v1.Foo",
+				Reason:  "Removing a field that was never used.",
+				Expires: "2020-06-15",
+			},
+		},
+		exceptions,
+	)
+	_, err = ParseExceptions([]byte(`exceptions:
+  - id: FIELDS_RESERVED_ON_DELETE
+    expires: 2020-06-15
+`))
+	assert.Error(t, err)
+	_, err = ParseExceptions([]byte(`exceptions:
+  - id: FIELDS_RESERVED_ON_DELETE
+    reason: foo
+    expires: tomorrow
+`))
+	assert.Error(t, err)
+}
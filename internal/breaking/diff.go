@@ -0,0 +1,339 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package breaking
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emicklei/proto"
+)
+
+// Change is a change to a package, message, field, enum, enum value,
+// service, or RPC between the previous and current versions of files.
+type Change struct {
+	// The kind of change, which is added, removed, renumbered, type changed,
+	// or deprecated.
+	Kind string
+	// The element that changed, which is package, message, field, enum,
+	// enum value, service, or rpc.
+	Element string
+	// The fully-qualified name of the element, for example foo.v1.Bar.baz.
+	// Enum values are qualified by their enum, for example foo.v1.Hello.HELLO_WORLD.
+	Name string
+	// The package of the element.
+	Package string
+	// The previous number or type, if the element was renumbered or its
+	// type changed.
+	Previous string
+	// The current number or type, if the element was renumbered or its
+	// type changed.
+	Current string
+}
+
+// String returns a human-readable representation of the change, such as
+// "~ field foo.v1.Bar.baz renumbered from 1 to 2".
+func (c *Change) String() string {
+	switch c.Kind {
+	case "added":
+		return fmt.Sprintf("+ %s %s", c.Element, c.Name)
+	case "removed":
+		return fmt.Sprintf("- %s %s", c.Element, c.Name)
+	case "deprecated":
+		return fmt.Sprintf("~ %s %s deprecated", c.Element, c.Name)
+	default:
+		return fmt.Sprintf("~ %s %s %s from %s to %s", c.Element, c.Name, c.Kind, c.Previous, c.Current)
+	}
+}
+
+// Diff returns the changes from the previous versions of the files to
+// the current versions, sorted by the names of the changed elements, with
+// packages first.
+//
+// Elements are matched by their fully-qualified names across all files, so
+// moving a message between files is not a change. The elements within an
+// added or removed element are not reported separately. Nil data is treated
+// as an empty file.
+func Diff(files ...*File) ([]*Change, error) {
+	previous := newSchema()
+	current := newSchema()
+	for _, file := range files {
+		if err := previous.add(file.Filename, file.PreviousData); err != nil {
+			return nil, err
+		}
+		if err := current.add(file.Filename, file.CurrentData); err != nil {
+			return nil, err
+		}
+	}
+	var changes []*Change
+	for _, pkg := range sortedKeys(previous.packages) {
+		if _, ok := current.packages[pkg]; !ok {
+			changes = append(changes, &Change{Kind: "removed", Element: "package", Name: pkg, Package: pkg})
+		}
+	}
+	for _, pkg := range sortedKeys(current.packages) {
+		if _, ok := previous.packages[pkg]; !ok {
+			changes = append(changes, &Change{Kind: "added", Element: "package", Name: pkg, Package: pkg})
+		}
+	}
+	names := make(map[string]struct{}, len(previous.elements)+len(current.elements))
+	for name := range previous.elements {
+		names[name] = struct{}{}
+	}
+	for name := range current.elements {
+		names[name] = struct{}{}
+	}
+	for _, name := range sortedKeys(names) {
+		previousElement := previous.get(name, "")
+		currentElement := current.get(name, "")
+		if previousElement != nil && currentElement != nil && previousElement.kind == currentElement.kind {
+			if previousElement.number != currentElement.number {
+				changes = append(changes, &Change{Kind: "renumbered", Element: currentElement.kind, Name: name, Package: currentElement.pkg, Previous: previousElement.number, Current: currentElement.number})
+			}
+			if previousElement.typeName != currentElement.typeName {
+				changes = append(changes, &Change{Kind: "type changed", Element: currentElement.kind, Name: name, Package: currentElement.pkg, Previous: previousElement.typeName, Current: currentElement.typeName})
+			}
+			if !previousElement.deprecated && currentElement.deprecated {
+				changes = append(changes, &Change{Kind: "deprecated", Element: currentElement.kind, Name: name, Package: currentElement.pkg})
+			}
+			continue
+		}
+		// a message that became an enum, for example, is removed and added
+		if previousElement != nil && current.get(previousElement.parent, previous.kindOf(previousElement.parent)) != nil {
+			changes = append(changes, &Change{Kind: "removed", Element: previousElement.kind, Name: name, Package: previousElement.pkg})
+		}
+		if currentElement != nil && previous.get(currentElement.parent, current.kindOf(currentElement.parent)) != nil {
+			changes = append(changes, &Change{Kind: "added", Element: currentElement.kind, Name: name, Package: currentElement.pkg})
+		}
+	}
+	return changes, nil
+}
+
+type schemaElement struct {
+	// message, field, enum, enum value, service, or rpc
+	kind string
+	// the fully-qualified name of the element this element is in,
+	// or empty if it is at the top level
+	parent string
+	pkg    string
+	// set for fields and enum values
+	number string
+	// set for fields and rpcs
+	typeName   string
+	deprecated bool
+}
+
+// schema is the elements of a set of files.
+type schema struct {
+	packages map[string]struct{}
+	elements map[string]*schemaElement
+}
+
+func newSchema() *schema {
+	return &schema{
+		packages: make(map[string]struct{}),
+		elements: make(map[string]*schemaElement),
+	}
+}
+
+// get returns the element with the name and kind, or any kind if kind is
+// empty. The empty name is the top level, which always exists.
+func (s *schema) get(name string, kind string) *schemaElement {
+	if name == "" {
+		return &schemaElement{}
+	}
+	element, ok := s.elements[name]
+	if !ok || (kind != "" && element.kind != kind) {
+		return nil
+	}
+	return element
+}
+
+func (s *schema) kindOf(name string) string {
+	if element, ok := s.elements[name]; ok {
+		return element.kind
+	}
+	return ""
+}
+
+func (s *schema) add(filename string, data []byte) error {
+	if data == nil {
+		return nil
+	}
+	descriptor, err := parse(filename, data)
+	if err != nil {
+		return err
+	}
+	pkg := getPackage(descriptor)
+	if pkg != "" {
+		s.packages[pkg] = struct{}{}
+	}
+	for _, element := range descriptor.Elements {
+		switch t := element.(type) {
+		case *proto.Message:
+			s.addMessage(pkg, "", qualify(pkg, t.Name), t)
+		case *proto.Enum:
+			s.addEnum(pkg, "", qualify(pkg, t.Name), t)
+		case *proto.Service:
+			s.addService(pkg, qualify(pkg, t.Name), t)
+		}
+	}
+	return nil
+}
+
+func (s *schema) addMessage(pkg string, parent string, name string, message *proto.Message) {
+	// extensions are not elements of their own
+	if message.IsExtend {
+		return
+	}
+	s.elements[name] = &schemaElement{
+		kind:       "message",
+		parent:     parent,
+		pkg:        pkg,
+		deprecated: isDeprecated(getOptions(message.Elements)),
+	}
+	for _, element := range message.Elements {
+		switch t := element.(type) {
+		case *proto.NormalField:
+			s.addField(pkg, name, t.Field, getLabel(t.Repeated, t.Optional, t.Required)+trimDot(t.Type))
+		case *proto.MapField:
+			s.addField(pkg, name, t.Field, fmt.Sprintf("map<%s, %s>", t.KeyType, trimDot(t.Type)))
+		case *proto.Group:
+			s.addField(pkg, name, &proto.Field{Name: t.Name, Sequence: t.Sequence}, getLabel(t.Repeated, t.Optional, t.Required)+"group")
+		case *proto.Oneof:
+			for _, oneofElement := range t.Elements {
+				if oneOfField, ok := oneofElement.(*proto.OneOfField); ok {
+					s.addField(pkg, name, oneOfField.Field, trimDot(oneOfField.Type))
+				}
+			}
+		case *proto.Message:
+			s.addMessage(pkg, name, name+"."+t.Name, t)
+		case *proto.Enum:
+			s.addEnum(pkg, name, name+"."+t.Name, t)
+		}
+	}
+}
+
+func (s *schema) addField(pkg string, parent string, field *proto.Field, typeName string) {
+	s.elements[parent+"."+field.Name] = &schemaElement{
+		kind:       "field",
+		parent:     parent,
+		pkg:        pkg,
+		number:     strconv.Itoa(field.Sequence),
+		typeName:   typeName,
+		deprecated: isDeprecated(field.Options),
+	}
+}
+
+func (s *schema) addEnum(pkg string, parent string, name string, enum *proto.Enum) {
+	s.elements[name] = &schemaElement{
+		kind:       "enum",
+		parent:     parent,
+		pkg:        pkg,
+		deprecated: isDeprecated(getOptions(enum.Elements)),
+	}
+	for _, element := range enum.Elements {
+		if enumField, ok := element.(*proto.EnumField); ok {
+			s.elements[name+"."+enumField.Name] = &schemaElement{
+				kind:       "enum value",
+				parent:     name,
+				pkg:        pkg,
+				number:     strconv.Itoa(enumField.Integer),
+				deprecated: isDeprecated(getOptions(enumField.Elements)),
+			}
+		}
+	}
+}
+
+func (s *schema) addService(pkg string, name string, service *proto.Service) {
+	s.elements[name] = &schemaElement{
+		kind:       "service",
+		pkg:        pkg,
+		deprecated: isDeprecated(getOptions(service.Elements)),
+	}
+	for _, element := range service.Elements {
+		if rpc, ok := element.(*proto.RPC); ok {
+			s.elements[name+"."+rpc.Name] = &schemaElement{
+				kind:       "rpc",
+				parent:     name,
+				pkg:        pkg,
+				typeName:   fmt.Sprintf("(%s) returns (%s)", getStreamType(rpc.StreamsRequest, rpc.RequestType), getStreamType(rpc.StreamsReturns, rpc.ReturnsType)),
+				deprecated: isDeprecated(append(rpc.Options, getOptions(rpc.Elements)...)),
+			}
+		}
+	}
+}
+
+func getOptions(elements []proto.Visitee) []*proto.Option {
+	var options []*proto.Option
+	for _, element := range elements {
+		if option, ok := element.(*proto.Option); ok {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
+func isDeprecated(options []*proto.Option) bool {
+	for _, option := range options {
+		if option.Name == "deprecated" && option.Constant.Source == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+func getLabel(repeated bool, optional bool, required bool) string {
+	switch {
+	case repeated:
+		return "repeated "
+	case optional:
+		return "optional "
+	case required:
+		return "required "
+	default:
+		return ""
+	}
+}
+
+func getStreamType(stream bool, typeName string) string {
+	if stream {
+		return "stream " + trimDot(typeName)
+	}
+	return trimDot(typeName)
+}
+
+// trimDot removes the leading dot of fully-qualified type names, so that
+// .foo.Bar and foo.Bar are the same type.
+func trimDot(typeName string) string {
+	return strings.TrimPrefix(typeName, ".")
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
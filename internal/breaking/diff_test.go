@@ -0,0 +1,139 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package breaking
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	previous := `syntax = "proto3";
+
+package foo.v1;
+
+message Foo {
+  int64 one = 1;
+  int64 two = 2;
+  string three = 3;
+  message Bar {
+    int64 one = 1;
+  }
+  message Baz {
+    int64 one = 1;
+  }
+}
+
+enum Hello {
+  HELLO_INVALID = 0;
+  HELLO_ONE = 1;
+}
+
+service FooService {
+  rpc Get(Foo) returns (Foo);
+  rpc Delete(Foo) returns (Foo);
+}
+`
+	current := `syntax = "proto3";
+
+package foo.v1;
+
+message Foo {
+  int64 one = 1 [deprecated = true];
+  int64 two = 4;
+  bytes three = 3;
+  repeated string four = 5;
+  message Bar {
+    int64 one = 1;
+    map<string, Foo> three = 2;
+  }
+  enum Baz {
+    BAZ_INVALID = 0;
+  }
+}
+
+enum Hello {
+  HELLO_INVALID = 0;
+  HELLO_ONE = 2;
+}
+
+service FooService {
+  rpc Get(Foo) returns (stream Foo) {
+    option deprecated = true;
+  }
+}
+`
+	moved := `syntax = "proto3";
+
+package foo.v1;
+
+message Moved {}
+`
+	changes, err := Diff(
+		&File{
+			Filename:     "foo.proto",
+			PreviousData: []byte(previous),
+			CurrentData:  []byte(current),
+		},
+		&File{
+			Filename:     "a.proto",
+			PreviousData: []byte(moved),
+		},
+		&File{
+			Filename:    "b.proto",
+			CurrentData: []byte(moved),
+		},
+		&File{
+			Filename:    "bar.proto",
+			CurrentData: []byte("syntax = \"proto3\";\n\npackage bar.v1;\n\nmessage Bar {\n  int64 one = 1;\n}\n"),
+		},
+	)
+	require.NoError(t, err)
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, change.String())
+		if change.Element != "package" {
+			assert.True(t, strings.HasPrefix(change.Name, change.Package+"."), change.Name)
+		}
+	}
+	assert.Equal(
+		t,
+		[]string{
+			"+ package bar.v1",
+			"+ message bar.v1.Bar",
+			"+ field foo.v1.Foo.Bar.three",
+			"- message foo.v1.Foo.Baz",
+			"+ enum foo.v1.Foo.Baz",
+			"+ field foo.v1.Foo.four",
+			"~ field foo.v1.Foo.one deprecated",
+			"~ field foo.v1.Foo.three type changed from string to bytes",
+			"~ field foo.v1.Foo.two renumbered from 2 to 4",
+			"- rpc foo.v1.FooService.Delete",
+			"~ rpc foo.v1.FooService.Get type changed from (Foo) returns (Foo) to (Foo) returns (stream Foo)",
+			"~ rpc foo.v1.FooService.Get deprecated",
+			"~ enum value foo.v1.Hello.HELLO_ONE renumbered from 1 to 2",
+		},
+		lines,
+	)
+}
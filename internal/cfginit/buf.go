@@ -0,0 +1,623 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cfginit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/uber/prototool/internal/lint"
+	"github.com/uber/prototool/internal/protostrs"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/strs"
+)
+
+var (
+	// bufCategoryToRules is the map from buf lint category to the buf
+	// lint rules in the category.
+	bufCategoryToRules = map[string][]string{
+		"MINIMAL": {
+			"DIRECTORY_SAME_PACKAGE",
+			"PACKAGE_DEFINED",
+			"PACKAGE_DIRECTORY_MATCH",
+			"PACKAGE_SAME_DIRECTORY",
+		},
+		"BASIC": {
+			"ENUM_FIRST_VALUE_ZERO",
+			"ENUM_NO_ALLOW_ALIAS",
+			"ENUM_PASCAL_CASE",
+			"ENUM_VALUE_UPPER_SNAKE_CASE",
+			"FIELD_LOWER_SNAKE_CASE",
+			"IMPORT_NO_PUBLIC",
+			"IMPORT_NO_WEAK",
+			"IMPORT_USED",
+			"MESSAGE_PASCAL_CASE",
+			"ONEOF_LOWER_SNAKE_CASE",
+			"PACKAGE_LOWER_SNAKE_CASE",
+			"PACKAGE_SAME_CSHARP_NAMESPACE",
+			"PACKAGE_SAME_GO_PACKAGE",
+			"PACKAGE_SAME_JAVA_MULTIPLE_FILES",
+			"PACKAGE_SAME_JAVA_PACKAGE",
+			"PACKAGE_SAME_PHP_NAMESPACE",
+			"PACKAGE_SAME_RUBY_PACKAGE",
+			"PACKAGE_SAME_SWIFT_PREFIX",
+			"RPC_PASCAL_CASE",
+			"SERVICE_PASCAL_CASE",
+		},
+		"DEFAULT": {
+			"ENUM_VALUE_PREFIX",
+			"ENUM_ZERO_VALUE_SUFFIX",
+			"FILE_LOWER_SNAKE_CASE",
+			"PACKAGE_VERSION_SUFFIX",
+			"RPC_REQUEST_RESPONSE_UNIQUE",
+			"RPC_REQUEST_STANDARD_NAME",
+			"RPC_RESPONSE_STANDARD_NAME",
+			"SERVICE_SUFFIX",
+		},
+		"COMMENTS": {
+			"COMMENT_ENUM",
+			"COMMENT_ENUM_VALUE",
+			"COMMENT_FIELD",
+			"COMMENT_MESSAGE",
+			"COMMENT_ONEOF",
+			"COMMENT_RPC",
+			"COMMENT_SERVICE",
+		},
+		"UNARY_RPC": {
+			"RPC_NO_CLIENT_STREAMING",
+			"RPC_NO_SERVER_STREAMING",
+		},
+	}
+
+	// bufCategoryToIncludedCategories is the map from buf lint category
+	// to the smaller categories that it includes.
+	bufCategoryToIncludedCategories = map[string][]string{
+		"BASIC":   {"MINIMAL"},
+		"DEFAULT": {"MINIMAL", "BASIC"},
+	}
+
+	// bufRuleToLintIDs is the map from buf lint rule to the prototool
+	// lint IDs that check the same thing. Rules that are not in this
+	// map do not have an equivalent.
+	bufRuleToLintIDs = map[string][]string{
+		"COMMENT_ENUM":                     {"ENUMS_HAVE_COMMENTS"},
+		"COMMENT_MESSAGE":                  {"MESSAGES_HAVE_COMMENTS"},
+		"COMMENT_RPC":                      {"RPCS_HAVE_COMMENTS"},
+		"COMMENT_SERVICE":                  {"SERVICES_HAVE_COMMENTS"},
+		"ENUM_NO_ALLOW_ALIAS":              {"ENUMS_NO_ALLOW_ALIAS"},
+		"ENUM_PASCAL_CASE":                 {"ENUM_NAMES_CAMEL_CASE", "ENUM_NAMES_CAPITALIZED"},
+		"ENUM_VALUE_PREFIX":                {"ENUM_FIELD_PREFIXES"},
+		"ENUM_VALUE_UPPER_SNAKE_CASE":      {"ENUM_FIELD_NAMES_UPPER_SNAKE_CASE"},
+		"ENUM_ZERO_VALUE_SUFFIX":           {"ENUM_ZERO_VALUES_INVALID"},
+		"FIELD_LOWER_SNAKE_CASE":           {"MESSAGE_FIELD_NAMES_LOWER_SNAKE_CASE"},
+		"MESSAGE_PASCAL_CASE":              {"MESSAGE_NAMES_CAMEL_CASE", "MESSAGE_NAMES_CAPITALIZED"},
+		"ONEOF_LOWER_SNAKE_CASE":           {"ONEOF_NAMES_LOWER_SNAKE_CASE"},
+		"PACKAGE_DEFINED":                  {"PACKAGE_IS_DECLARED"},
+		"PACKAGE_LOWER_SNAKE_CASE":         {"PACKAGE_LOWER_SNAKE_CASE"},
+		"PACKAGE_SAME_DIRECTORY":           {"PACKAGES_SAME_IN_DIR"},
+		"PACKAGE_SAME_GO_PACKAGE":          {"FILE_OPTIONS_GO_PACKAGE_SAME_IN_DIR"},
+		"PACKAGE_SAME_JAVA_MULTIPLE_FILES": {"FILE_OPTIONS_JAVA_MULTIPLE_FILES_SAME_IN_DIR"},
+		"PACKAGE_SAME_JAVA_PACKAGE":        {"FILE_OPTIONS_JAVA_PACKAGE_SAME_IN_DIR"},
+		"RPC_PASCAL_CASE":                  {"RPC_NAMES_CAMEL_CASE", "RPC_NAMES_CAPITALIZED"},
+		"RPC_REQUEST_RESPONSE_UNIQUE":      {"REQUEST_RESPONSE_TYPES_UNIQUE"},
+		"RPC_REQUEST_STANDARD_NAME":        {"REQUEST_RESPONSE_NAMES_MATCH_RPC"},
+		"RPC_RESPONSE_STANDARD_NAME":       {"REQUEST_RESPONSE_NAMES_MATCH_RPC"},
+		"SERVICE_PASCAL_CASE":              {"SERVICE_NAMES_CAMEL_CASE", "SERVICE_NAMES_CAPITALIZED"},
+		"SERVICE_SUFFIX":                   {"SERVICE_NAMES_HAVE_SUFFIX"},
+	}
+)
+
+// bufYAML is the subset of a buf.yaml file that can be converted.
+type bufYAML struct {
+	Version  string          `json:"version,omitempty"`
+	Build    *bufYAMLBuild   `json:"build,omitempty"`
+	Deps     []string        `json:"deps,omitempty"`
+	Lint     *bufYAMLLint    `json:"lint,omitempty"`
+	Breaking json.RawMessage `json:"breaking,omitempty"`
+}
+
+type bufYAMLBuild struct {
+	Roots    []string `json:"roots,omitempty"`
+	Excludes []string `json:"excludes,omitempty"`
+}
+
+type bufYAMLLint struct {
+	Use                 []string            `json:"use,omitempty"`
+	Except              []string            `json:"except,omitempty"`
+	Ignore              []string            `json:"ignore,omitempty"`
+	IgnoreOnly          map[string][]string `json:"ignore_only,omitempty"`
+	EnumZeroValueSuffix string              `json:"enum_zero_value_suffix,omitempty"`
+	ServiceSuffix       string              `json:"service_suffix,omitempty"`
+	AllowCommentIgnores bool                `json:"allow_comment_ignores,omitempty"`
+}
+
+// bufGenYAML is the subset of a buf.gen.yaml file that can be converted.
+type bufGenYAML struct {
+	Version string `json:"version,omitempty"`
+	Managed *struct {
+		Enabled bool `json:"enabled,omitempty"`
+	} `json:"managed,omitempty"`
+	Plugins []bufGenYAMLPlugin `json:"plugins,omitempty"`
+}
+
+type bufGenYAMLPlugin struct {
+	Plugin   string     `json:"plugin,omitempty"`
+	Name     string     `json:"name,omitempty"`
+	Remote   string     `json:"remote,omitempty"`
+	Out      string     `json:"out,omitempty"`
+	Opt      bufStrings `json:"opt,omitempty"`
+	Path     bufStrings `json:"path,omitempty"`
+	Strategy string     `json:"strategy,omitempty"`
+}
+
+// bufStrings is a value in a buf configuration file that can be either
+// a string or a list of strings.
+type bufStrings []string
+
+// MarshalJSON marshals a single value as a string.
+func (b bufStrings) MarshalJSON() ([]byte, error) {
+	if len(b) == 1 {
+		return json.Marshal(b[0])
+	}
+	return json.Marshal([]string(b))
+}
+
+// UnmarshalJSON unmarshals either a string or a list of strings.
+func (b *bufStrings) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = bufStrings{s}
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(data, &l); err != nil {
+		return err
+	}
+	*b = l
+	return nil
+}
+
+// GenerateFromBuf generates the data for a prototool.yaml file that is
+// equivalent to the given buf.yaml and buf.gen.yaml data, either of
+// which can be empty, for the v1beta1 and v1 configuration versions.
+//
+// The buf lint rules in use are mapped to the prototool lint IDs that
+// check the same thing, and each buf.gen.yaml plugin becomes a plugin
+// with the same name, output, options, and path. Settings that have no
+// prototool equivalent are listed in a comment at the top of the file.
+func GenerateFromBuf(protocVersion string, bufYAMLData []byte, bufGenYAMLData []byte) ([]byte, error) {
+	if len(bufYAMLData) == 0 && len(bufGenYAMLData) == 0 {
+		return nil, errors.New("no buf.yaml or buf.gen.yaml found")
+	}
+	bufYAML := &bufYAML{
+		Build: &bufYAMLBuild{},
+		Lint:  &bufYAMLLint{},
+	}
+	if err := yaml.Unmarshal(bufYAMLData, bufYAML); err != nil {
+		return nil, fmt.Errorf("could not parse buf.yaml: %v", err)
+	}
+	bufGenYAML := &bufGenYAML{}
+	if err := yaml.Unmarshal(bufGenYAMLData, bufGenYAML); err != nil {
+		return nil, fmt.Errorf("could not parse buf.gen.yaml: %v", err)
+	}
+	externalConfig := &externalConfig{
+		ProtocVersion: protocVersion,
+		Excludes:      bufYAML.Build.Excludes,
+	}
+	var notConverted []string
+	for _, root := range bufYAML.Build.Roots {
+		if root = path.Clean(root); root != "." {
+			externalConfig.ProtocRoots = append(externalConfig.ProtocRoots, protocRootExternalConfig{Path: root})
+		}
+	}
+	for _, dep := range bufYAML.Deps {
+		if strings.HasPrefix(dep, "buf.build/googleapis/googleapis") {
+			externalConfig.GoogleapisVersion = "master"
+		} else {
+			notConverted = append(notConverted, "deps: "+dep)
+		}
+	}
+	if len(bufYAMLData) > 0 {
+		lintExternalConfig, lintNotConverted, err := getBufLintExternalConfig(bufYAML)
+		if err != nil {
+			return nil, err
+		}
+		externalConfig.Lint = lintExternalConfig
+		notConverted = append(notConverted, lintNotConverted...)
+	}
+	if len(bufYAML.Breaking) > 0 {
+		notConverted = append(notConverted, "breaking, use prototool break check instead")
+	}
+	if bufGenYAML.Managed != nil && bufGenYAML.Managed.Enabled {
+		notConverted = append(notConverted, "managed, use prototool format to set file options instead")
+	}
+	var plugins []*genPluginExternalConfig
+	for _, bufPlugin := range bufGenYAML.Plugins {
+		name := bufPlugin.Plugin
+		if name == "" {
+			name = bufPlugin.Name
+		}
+		if name == "" {
+			name = bufPlugin.Remote
+		}
+		if strings.Contains(name, "/") {
+			notConverted = append(notConverted, "remote plugin "+name)
+			name = getBufRemotePluginLocalName(name)
+		}
+		if name == "" {
+			return nil, errors.New("buf.gen.yaml plugin has no name")
+		}
+		plugin := &genPluginExternalConfig{
+			Name:   name,
+			Flags:  strings.Join(bufPlugin.Opt, ","),
+			Output: bufPlugin.Out,
+		}
+		switch len(bufPlugin.Path) {
+		case 0:
+		case 1:
+			plugin.Path = bufPlugin.Path[0]
+		default:
+			notConverted = append(notConverted, "path "+strings.Join(bufPlugin.Path, " ")+" of plugin "+name)
+		}
+		plugins = append(plugins, plugin)
+	}
+	if len(plugins) > 0 {
+		externalConfig.Gen = &genExternalConfig{
+			Plugins: plugins,
+		}
+	}
+	headerLines := []string{"Generated by prototool init from buf configuration."}
+	if len(notConverted) > 0 {
+		headerLines = append(headerLines, "The following settings have no equivalent and were not converted:")
+		for _, s := range notConverted {
+			headerLines = append(headerLines, "  "+s)
+		}
+	}
+	return marshalExternalConfig(externalConfig, headerLines)
+}
+
+func getBufLintExternalConfig(bufYAML *bufYAML) (*lintExternalConfig, []string, error) {
+	var notConverted []string
+	use := bufYAML.Lint.Use
+	if len(use) == 0 {
+		use = []string{"DEFAULT"}
+	}
+	rules := make(map[string]struct{})
+	for _, categoryOrRule := range use {
+		categoryOrRule = strings.ToUpper(categoryOrRule)
+		bufRules, ok := bufCategoryToRules[categoryOrRule]
+		if !ok {
+			rules[categoryOrRule] = struct{}{}
+			continue
+		}
+		for _, bufRule := range bufRules {
+			rules[bufRule] = struct{}{}
+		}
+		for _, includedCategory := range bufCategoryToIncludedCategories[categoryOrRule] {
+			for _, bufRule := range bufCategoryToRules[includedCategory] {
+				rules[bufRule] = struct{}{}
+			}
+		}
+	}
+	for _, categoryOrRule := range bufYAML.Lint.Except {
+		categoryOrRule = strings.ToUpper(categoryOrRule)
+		if bufRules, ok := bufCategoryToRules[categoryOrRule]; ok {
+			for _, bufRule := range bufRules {
+				delete(rules, bufRule)
+			}
+		} else {
+			delete(rules, categoryOrRule)
+		}
+	}
+	ids := make(map[string]struct{})
+	for rule := range rules {
+		lintIDs, ok := bufRuleToLintIDs[rule]
+		if !ok {
+			notConverted = append(notConverted, "lint rule "+rule)
+			continue
+		}
+		for _, lintID := range lintIDs {
+			ids[lintID] = struct{}{}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil, errors.New("no buf lint rules in use have an equivalent prototool lint ID")
+	}
+	lintExternalConfig := &lintExternalConfig{}
+	for lintID := range ids {
+		lintExternalConfig.IDs = append(lintExternalConfig.IDs, lintID)
+	}
+	sort.Strings(lintExternalConfig.IDs)
+	for rule, paths := range bufYAML.Lint.IgnoreOnly {
+		for _, lintID := range bufRuleToLintIDs[strings.ToUpper(rule)] {
+			if _, ok := ids[lintID]; ok {
+				notConverted = append(notConverted, addIgnoreIDToFiles(lintExternalConfig, lintID, paths)...)
+			}
+		}
+	}
+	for _, lintID := range lintExternalConfig.IDs {
+		notConverted = append(notConverted, addIgnoreIDToFiles(lintExternalConfig, lintID, bufYAML.Lint.Ignore)...)
+	}
+	if _, ok := ids["ENUM_ZERO_VALUES_INVALID"]; ok {
+		// buf defaults to _UNSPECIFIED while prototool defaults to _INVALID
+		zeroValueSuffix := strings.TrimPrefix(bufYAML.Lint.EnumZeroValueSuffix, "_")
+		if zeroValueSuffix == "" {
+			zeroValueSuffix = "UNSPECIFIED"
+		}
+		lintExternalConfig.Enums = &struct {
+			ZeroValueSuffix string `json:"zero_value_suffix,omitempty"`
+		}{
+			ZeroValueSuffix: zeroValueSuffix,
+		}
+	}
+	if _, ok := ids["SERVICE_NAMES_HAVE_SUFFIX"]; ok && bufYAML.Lint.ServiceSuffix != "" {
+		lintExternalConfig.Naming = &struct {
+			ServiceSuffixes []string `json:"service_suffixes,omitempty"`
+		}{
+			ServiceSuffixes: []string{bufYAML.Lint.ServiceSuffix},
+		}
+	}
+	if bufYAML.Lint.AllowCommentIgnores {
+		notConverted = append(notConverted, "lint allow_comment_ignores")
+	}
+	return lintExternalConfig, strs.DedupeSort(notConverted, nil), nil
+}
+
+// addIgnoreIDToFiles adds the paths that are files to the files to ignore
+// for the lint ID, and returns the paths that are directories, which
+// prototool cannot ignore.
+func addIgnoreIDToFiles(lintExternalConfig *lintExternalConfig, lintID string, paths []string) []string {
+	var notConverted []string
+	for _, p := range paths {
+		if path.Ext(p) != ".proto" {
+			notConverted = append(notConverted, "lint ignore of directory "+p)
+			continue
+		}
+		if lintExternalConfig.IgnoreIDToFiles == nil {
+			lintExternalConfig.IgnoreIDToFiles = make(map[string][]string)
+		}
+		lintExternalConfig.IgnoreIDToFiles[lintID] = appendIfMissing(lintExternalConfig.IgnoreIDToFiles[lintID], path.Clean(p))
+	}
+	return notConverted
+}
+
+// getBufRemotePluginLocalName returns the name of the local plugin for the
+// remote plugin, such as go for buf.build/protocolbuffers/go:v1.28.1 and
+// go-grpc for buf.build/grpc/go, which is protoc-gen-go-grpc.
+func getBufRemotePluginLocalName(remotePlugin string) string {
+	remotePlugin = strings.SplitN(remotePlugin, ":", 2)[0]
+	name := path.Base(remotePlugin)
+	if path.Base(path.Dir(remotePlugin)) == "grpc" {
+		if name == "go" {
+			return "go-grpc"
+		}
+		return "grpc-" + name
+	}
+	return name
+}
+
+// GenerateBuf generates the data for buf.yaml and buf.gen.yaml files that
+// are equivalent to the given config, for the v1 configuration version.
+//
+// The lint IDs in use are mapped to the buf lint rules that check the
+// same thing, and each gen plugin becomes a buf.gen.yaml plugin with the
+// same name, output, options, and path. The buf.gen.yaml data is nil if
+// there are no gen plugins. Settings that have no buf equivalent are
+// listed in a comment at the top of the files.
+func GenerateBuf(config settings.Config) ([]byte, []byte, error) {
+	linters, err := lint.GetLinters(config.Lint)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make(map[string]struct{}, len(linters))
+	for _, linter := range linters {
+		ids[linter.ID()] = struct{}{}
+	}
+	bufYAML := &bufYAML{
+		Version: "v1",
+		Lint:    &bufYAMLLint{},
+	}
+	var notConverted []string
+	for _, excludePrefix := range config.ExcludePrefixes {
+		relExcludePrefix, err := filepath.Rel(config.DirPath, excludePrefix)
+		if err != nil {
+			return nil, nil, err
+		}
+		if bufYAML.Build == nil {
+			bufYAML.Build = &bufYAMLBuild{}
+		}
+		bufYAML.Build.Excludes = append(bufYAML.Build.Excludes, filepath.ToSlash(relExcludePrefix))
+	}
+	if len(config.Compile.Roots) > 0 {
+		notConverted = append(notConverted, "protoc_roots, use the directories of a buf.work.yaml file instead")
+	}
+	if len(config.Lint.Overrides) > 0 {
+		notConverted = append(notConverted, "lint overrides, use ignore_only in buf.yaml for the excluded linters instead")
+	}
+	if config.Compile.GoogleapisVersion != "" {
+		bufYAML.Deps = append(bufYAML.Deps, "buf.build/googleapis/googleapis")
+	}
+
+	enumNaming := lint.GetEnumNaming(config.Lint)
+	serviceNameSuffixes := config.Lint.ServiceNameSuffixes
+	if len(serviceNameSuffixes) == 0 {
+		serviceNameSuffixes = []string{"API", "Service"}
+	}
+	// the IDs that are checked by a buf lint rule
+	convertedIDs := make(map[string]struct{})
+	for rule, lintIDs := range bufRuleToLintIDs {
+		switch {
+		case !containsAll(ids, lintIDs):
+			continue
+		case rule == "ENUM_VALUE_PREFIX" && enumNaming.ValuePrefix != protostrs.DefaultEnumValuePrefix:
+			continue
+		case rule == "SERVICE_SUFFIX" && len(serviceNameSuffixes) > 1:
+			continue
+		}
+		bufYAML.Lint.Use = append(bufYAML.Lint.Use, rule)
+		for _, lintID := range lintIDs {
+			convertedIDs[lintID] = struct{}{}
+		}
+		if filePaths := config.Lint.IgnoreIDToFilePaths[lintIDs[0]]; len(filePaths) > 0 {
+			if bufYAML.Lint.IgnoreOnly == nil {
+				bufYAML.Lint.IgnoreOnly = make(map[string][]string)
+			}
+			for _, filePath := range filePaths {
+				relFilePath, err := filepath.Rel(config.DirPath, filePath)
+				if err != nil {
+					return nil, nil, err
+				}
+				bufYAML.Lint.IgnoreOnly[rule] = append(bufYAML.Lint.IgnoreOnly[rule], filepath.ToSlash(relFilePath))
+			}
+		}
+	}
+	if len(bufYAML.Lint.Use) == 0 {
+		return nil, nil, errors.New("no lint IDs in use have an equivalent buf lint rule")
+	}
+	sort.Strings(bufYAML.Lint.Use)
+	moveBufIgnoreOnlyToIgnore(bufYAML.Lint)
+	if _, ok := convertedIDs["ENUM_ZERO_VALUES_INVALID"]; ok {
+		bufYAML.Lint.EnumZeroValueSuffix = "_" + enumNaming.ZeroValueSuffix
+	}
+	if _, ok := convertedIDs["SERVICE_NAMES_HAVE_SUFFIX"]; ok {
+		bufYAML.Lint.ServiceSuffix = serviceNameSuffixes[0]
+	}
+	for id := range ids {
+		if _, ok := convertedIDs[id]; !ok {
+			notConverted = append(notConverted, "lint ID "+id)
+		}
+	}
+	sort.Strings(notConverted)
+	bufYAMLData, err := marshalBufYAML(bufYAML, notConverted)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(config.Gen.Plugins) == 0 {
+		return bufYAMLData, nil, nil
+	}
+
+	bufGenYAML := &bufGenYAML{
+		Version: "v1",
+	}
+	notConverted = nil
+	for _, genPlugin := range config.Gen.Plugins {
+		bufPlugin := bufGenYAMLPlugin{
+			Plugin: genPlugin.Name,
+			Out:    filepath.ToSlash(genPlugin.OutputPath.RelPath),
+		}
+		if genPlugin.Flags != "" {
+			bufPlugin.Opt = bufStrings{genPlugin.Flags}
+		}
+		if genPlugin.Path != "" {
+			pluginPath := genPlugin.Path
+			if filepath.IsAbs(pluginPath) {
+				if pluginPath, err = filepath.Rel(config.DirPath, pluginPath); err != nil {
+					return nil, nil, err
+				}
+			}
+			bufPlugin.Path = bufStrings{filepath.ToSlash(pluginPath)}
+		}
+		if genPlugin.Type.IsGo() || genPlugin.Type.IsGogo() {
+			notConverted = append(notConverted, "the Mfile=package modifiers of plugin "+genPlugin.Name+", use managed mode instead")
+		}
+		if genPlugin.Preset != "" {
+			notConverted = append(notConverted, "the preset of plugin "+genPlugin.Name+", install protoc-gen-"+genPlugin.Name+" instead")
+		}
+		if len(genPlugin.ProtocArgs) > 0 {
+			notConverted = append(notConverted, "the protoc_args of plugin "+genPlugin.Name)
+		}
+		bufGenYAML.Plugins = append(bufGenYAML.Plugins, bufPlugin)
+	}
+	bufGenYAMLData, err := marshalBufYAML(bufGenYAML, notConverted)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bufYAMLData, bufGenYAMLData, nil
+}
+
+// marshalBufYAML marshals the buf configuration to YAML after a comment
+// that lists the settings that were not converted.
+func marshalBufYAML(v interface{}, notConverted []string) ([]byte, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	headerLines := []string{"Generated by prototool config export from prototool.yaml."}
+	if len(notConverted) > 0 {
+		headerLines = append(headerLines, "The following settings have no equivalent and were not converted:")
+		for _, s := range notConverted {
+			headerLines = append(headerLines, "  "+s)
+		}
+	}
+	var header string
+	for _, headerLine := range headerLines {
+		header += "# " + headerLine + "\n"
+	}
+	return append([]byte(header), data...), nil
+}
+
+// moveBufIgnoreOnlyToIgnore moves the paths that are ignored for every
+// rule in use from ignore_only to ignore.
+func moveBufIgnoreOnlyToIgnore(bufYAMLLint *bufYAMLLint) {
+	pathToCount := make(map[string]int)
+	for _, paths := range bufYAMLLint.IgnoreOnly {
+		for _, p := range paths {
+			pathToCount[p]++
+		}
+	}
+	for p, count := range pathToCount {
+		if count == len(bufYAMLLint.Use) {
+			bufYAMLLint.Ignore = append(bufYAMLLint.Ignore, p)
+		}
+	}
+	if len(bufYAMLLint.Ignore) == 0 {
+		return
+	}
+	sort.Strings(bufYAMLLint.Ignore)
+	for rule, paths := range bufYAMLLint.IgnoreOnly {
+		var remainingPaths []string
+		for _, p := range paths {
+			if pathToCount[p] != len(bufYAMLLint.Use) {
+				remainingPaths = append(remainingPaths, p)
+			}
+		}
+		if len(remainingPaths) == 0 {
+			delete(bufYAMLLint.IgnoreOnly, rule)
+		} else {
+			bufYAMLLint.IgnoreOnly[rule] = remainingPaths
+		}
+	}
+}
+
+func containsAll(m map[string]struct{}, keys []string) bool {
+	for _, key := range keys {
+		if _, ok := m[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
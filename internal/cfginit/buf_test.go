@@ -0,0 +1,240 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cfginit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/settings"
+)
+
+func TestGenerateFromBuf(t *testing.T) {
+	t.Parallel()
+	data, err := GenerateFromBuf(
+		"3.5.1",
+		[]byte(`version: v1
+deps:
+  - buf.build/googleapis/googleapis
+build:
+  excludes:
+    - third_party
+lint:
+  use:
+    - BASIC
+    - SERVICE_SUFFIX
+  except:
+    - MINIMAL
+    - PACKAGE_SAME_GO_PACKAGE
+  ignore_only:
+    SERVICE_SUFFIX:
+      - foo/v1/foo.proto
+      - bar
+  service_suffix: API
+breaking:
+  use:
+    - FILE
+`),
+		[]byte(`version: v1
+plugins:
+  - plugin: go
+    out: gen/go
+    opt: paths=source_relative
+  - plugin: buf.build/grpc/go:v1.3.0
+    out: gen/go
+    opt:
+      - paths=source_relative
+      - require_unimplemented_servers=false
+  - name: java
+    out: gen/java
+    path: bin/protoc-gen-java
+`),
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`# Generated by prototool init from buf configuration.
+# The following settings have no equivalent and were not converted:
+#   lint ignore of directory bar
+#   lint rule ENUM_FIRST_VALUE_ZERO
+#   lint rule IMPORT_NO_PUBLIC
+#   lint rule IMPORT_NO_WEAK
+#   lint rule IMPORT_USED
+#   lint rule PACKAGE_SAME_CSHARP_NAMESPACE
+#   lint rule PACKAGE_SAME_PHP_NAMESPACE
+#   lint rule PACKAGE_SAME_RUBY_PACKAGE
+#   lint rule PACKAGE_SAME_SWIFT_PREFIX
+#   breaking, use prototool break check instead
+#   remote plugin buf.build/grpc/go:v1.3.0
+excludes:
+- third_party
+gen:
+  plugins:
+  - flags: paths=source_relative
+    name: go
+    output: gen/go
+  - flags: paths=source_relative,require_unimplemented_servers=false
+    name: go-grpc
+    output: gen/go
+  - name: java
+    output: gen/java
+    path: bin/protoc-gen-java
+googleapis_version: master
+lint:
+  ids:
+  - ENUMS_NO_ALLOW_ALIAS
+  - ENUM_FIELD_NAMES_UPPER_SNAKE_CASE
+  - ENUM_NAMES_CAMEL_CASE
+  - ENUM_NAMES_CAPITALIZED
+  - FILE_OPTIONS_JAVA_MULTIPLE_FILES_SAME_IN_DIR
+  - FILE_OPTIONS_JAVA_PACKAGE_SAME_IN_DIR
+  - MESSAGE_FIELD_NAMES_LOWER_SNAKE_CASE
+  - MESSAGE_NAMES_CAMEL_CASE
+  - MESSAGE_NAMES_CAPITALIZED
+  - ONEOF_NAMES_LOWER_SNAKE_CASE
+  - PACKAGE_LOWER_SNAKE_CASE
+  - RPC_NAMES_CAMEL_CASE
+  - RPC_NAMES_CAPITALIZED
+  - SERVICE_NAMES_CAMEL_CASE
+  - SERVICE_NAMES_CAPITALIZED
+  - SERVICE_NAMES_HAVE_SUFFIX
+  ignore_id_to_files:
+    SERVICE_NAMES_HAVE_SUFFIX:
+    - foo/v1/foo.proto
+  naming:
+    service_suffixes:
+    - API
+protoc_version: 3.5.1
+`,
+		string(data),
+	)
+
+	data, err = GenerateFromBuf("3.5.1", []byte(`version: v1`), nil)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "  - ENUM_ZERO_VALUES_INVALID\n")
+	assert.Contains(t, string(data), "    zero_value_suffix: UNSPECIFIED\n")
+
+	_, err = GenerateFromBuf("3.5.1", nil, nil)
+	assert.EqualError(t, err, "no buf.yaml or buf.gen.yaml found")
+	_, err = GenerateFromBuf("3.5.1", []byte(`lint:
+  use:
+    - UNARY_RPC
+`), nil)
+	assert.EqualError(t, err, "no buf lint rules in use have an equivalent prototool lint ID")
+}
+
+func TestGenerateBuf(t *testing.T) {
+	t.Parallel()
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, settings.DefaultConfigFilename), []byte(`excludes:
+  - third_party
+googleapis_version: master
+lint:
+  ids:
+    - ENUM_ZERO_VALUES_INVALID
+    - FILE_OPTIONS_REQUIRE_GO_PACKAGE
+    - MESSAGE_NAMES_CAMEL_CASE
+    - MESSAGE_NAMES_CAPITALIZED
+    - SERVICE_NAMES_HAVE_SUFFIX
+  ignore_id_to_files:
+    MESSAGE_NAMES_CAMEL_CASE:
+      - foo/foo.proto
+  enums:
+    zero_value_suffix: UNSPECIFIED
+  naming:
+    service_suffixes:
+      - API
+gen:
+  go_options:
+    import_path: github.com/foo/bar
+  plugins:
+    - name: go
+      type: go
+      flags: plugins=grpc
+      output: gen/go
+    - name: java
+      path: bin/protoc-gen-java
+      output: gen/java
+`), 0644))
+	config, err := settings.NewConfigProvider().GetForDir(tmpDir)
+	require.NoError(t, err)
+	bufYAMLData, bufGenYAMLData, err := GenerateBuf(config)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`# Generated by prototool config export from prototool.yaml.
+# The following settings have no equivalent and were not converted:
+#   lint ID FILE_OPTIONS_REQUIRE_GO_PACKAGE
+build:
+  excludes:
+  - third_party
+  - vendor
+deps:
+- buf.build/googleapis/googleapis
+lint:
+  enum_zero_value_suffix: _UNSPECIFIED
+  ignore_only:
+    MESSAGE_PASCAL_CASE:
+    - foo/foo.proto
+  service_suffix: API
+  use:
+  - ENUM_ZERO_VALUE_SUFFIX
+  - MESSAGE_PASCAL_CASE
+  - SERVICE_SUFFIX
+version: v1
+`,
+		string(bufYAMLData),
+	)
+	assert.Equal(
+		t,
+		`# Generated by prototool config export from prototool.yaml.
+# The following settings have no equivalent and were not converted:
+#   the Mfile=package modifiers of plugin go, use managed mode instead
+plugins:
+- opt: plugins=grpc
+  out: gen/go
+  plugin: go
+- out: gen/java
+  path: bin/protoc-gen-java
+  plugin: java
+version: v1
+`,
+		string(bufGenYAMLData),
+	)
+
+	// the buf configuration converts back to the same lint IDs
+	data, err := GenerateFromBuf("3.5.1", bufYAMLData, bufGenYAMLData)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `  ids:
+  - ENUM_ZERO_VALUES_INVALID
+  - MESSAGE_NAMES_CAMEL_CASE
+  - MESSAGE_NAMES_CAPITALIZED
+  - SERVICE_NAMES_HAVE_SUFFIX
+`)
+}
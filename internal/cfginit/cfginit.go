@@ -21,6 +21,9 @@
 // Package cfginit contains the template for prototool.yaml files, as well
 // as a function to generate a prototool.yaml file given a specific protoc
 // version, with or without commenting out the remainder of the options.
+//
+// It also converts protoc command lines and buf configuration files to
+// prototool.yaml files, and prototool.yaml files to buf configuration files.
 package cfginit
 
 import (
@@ -31,8 +34,36 @@ import (
 var tmpl = template.Must(template.New("tmpl").Parse(`# The Protobuf version to use from https://github.com/google/protobuf/releases.
 # By default use {{.ProtocVersion}}.
 # You probably want to set this to make your builds completely reproducible.
+# Set this to auto to use the minimum version that supports the features used by
+# your files, such as editions, or the default version if that is newer.
 protoc_version: {{.ProtocVersion}}
 
+# The protoc binary to use instead of downloading protoc, either a path relative
+# to this file or a name to look up in PATH. If set, protoc_version is ignored.
+{{.V}}protoc:
+  {{.V}}bin_path: /usr/bin/protoc
+  # The path to include for the well-known types.
+  # By default, the include directory next to the bin directory of bin_path is used.
+  {{.V}}wkt_path: /usr/include
+  # Extra flags to pass as-is to every protoc invocation, for protoc
+  # features that prototool does not otherwise expose.
+  # Flags that prototool manages, such as -I, -o, --plugin, and --*_out, are not allowed.
+  {{.V}}extra_args:
+  {{.V}}  - --experimental_editions
+  # The maximum time each protoc invocation, including its plugins, can run
+  # before it is killed. Plugins can override this with their own timeout.
+  # By default there is no timeout.
+  {{.V}}timeout: 5m
+  # The maximum number of bytes protoc and plugins can write to stderr before
+  # protoc is killed. By default there is no maximum.
+  {{.V}}max_output_bytes: 1048576
+  # Run protoc and plugins in a temporary working directory with all
+  # environment variables cleared except PATH and the ones in sandbox_env,
+  # so that plugins cannot read credentials from the environment.
+  {{.V}}sandbox: true
+  {{.V}}sandbox_env:
+  {{.V}}  - GOPATH
+
 # Paths to exclude when using directory mode.
 # These are prefixes, not regexes, so path/to/a will ignore anything beginning with
 # $(dirname some/dir/prototool.yaml)/path/to/a including for example $(dirname some/dir/prototool.yaml)/path/to/ab.
@@ -50,14 +81,81 @@ protoc_version: {{.ProtocVersion}}
 {{.V}}protoc_includes:
 {{.V}}  - ../../vendor/github.com/grpc-ecosystem/grpc-gateway/third_party/googleapis
 
+# Proto roots for repositories that keep Protobuf files under several
+# top-level directories. Files import each other relative to the root they
+# are in, and can import files in any other root. Each root can have
+# additional paths to include with -I to protoc for only the files in it.
+# Files that are not in a root are compiled relative to the config directory.
+{{.V}}protoc_roots:
+{{.V}}  - path: api
+{{.V}}  - path: third_party/proto
+{{.V}}    includes:
+{{.V}}      - ../../vendor/github.com/gogo/protobuf
+
+# Other local repository checkouts whose Protobuf files are compiled and
+# linted together with the files in this directory, so that imports across
+# repositories are resolved. Each path is relative to this file, and each
+# root is a proto root as in protoc_roots relative to the repository. The
+# default root is the repository itself. This config is used for the files
+# in all repositories, and their own config files are ignored.
+{{.V}}workspace:
+  {{.V}}repos:
+    {{.V}}- path: ../payments-api
+      {{.V}}roots:
+        {{.V}}- proto
+    {{.V}}- path: ../users-api
+
 # Include the Well-Known Types when compiling with protoc.
 # For example, this allows you to do import "google/protobuf/timestamp.proto" in your Protobuf files.
 {{.V}}protoc_include_wkt: true
 
+# The protoc-gen-validate version to download and use.
+# This adds validate/validate.proto to the include path, and the plugin
+# named validate will use the downloaded protoc-gen-validate binary.
+{{.V}}protoc_gen_validate_version: 1.0.2
+
+# The github.com/gogo/protobuf version to take gogoproto/gogo.proto from.
+# This adds gogoproto/gogo.proto to the include path, and go and gogo
+# plugins map it to github.com/gogo/protobuf/gogoproto.
+# Defaults to 1.3.2 if a plugin uses a gogo preset.
+{{.V}}gogo_protobuf_version: 1.3.2
+
+# The github.com/grpc-ecosystem/grpc-gateway version to download
+# protoc-gen-grpc-gateway and protoc-gen-openapiv2 for.
+# This adds google/api/annotations.proto, google/api/http.proto, and the
+# protoc-gen-openapiv2 options to the include path.
+# Defaults to 2.16.0 if a plugin uses the grpc-gateway or openapiv2 preset.
+{{.V}}grpc_gateway_version: 2.16.0
+
+# The github.com/protocolbuffers/protobuf-javascript version to download
+# protoc-gen-js for.
+# Defaults to 3.21.2 if a plugin uses the js preset.
+{{.V}}protobuf_javascript_version: 3.21.2
+
+# The github.com/googleapis/googleapis commit or branch to take the google/...
+# Protobuf files from. This adds them and the Well-Known Types to the include
+# path, so you can do import "google/type/date.proto" without a vendored copy.
+# Set this to a commit to make your builds completely reproducible.
+{{.V}}googleapis_version: master
+
 # If not set, compile will fail if there are unused imports.
-# Setting this will ignore unused imports.
+# Setting this will print unused imports as warnings instead.
 {{.V}}allow_unused_imports: true
 
+# Treat protoc warnings as errors, failing compile if there are any.
+# Otherwise warnings are printed but do not fail compile.
+{{.V}}warnings_as_errors: true
+
+# The languages that code is generated for, which can be csharp, go, java,
+# and objc. If set, only the file options for these languages are required
+# by lint with FILE_OPTIONS_REQUIRED_FOR_LANGUAGES, set by format without
+# --no-rewrite, and set by create. The FILE_OPTIONS lint checks for go_package
+# and the java options are only run if go and java are set.
+{{.V}}languages:
+{{.V}}  - go
+{{.V}}  - java
+{{.V}}  - objc
+
 # Create directives.
 {{.V}}create:
   # Map from relative directory to base package.
@@ -69,6 +167,26 @@ protoc_version: {{.ProtocVersion}}
     # This means that a file created "idl/code.uber/a/b/c.proto" will have package "uber.a.b".
     {{.V}}idl/code.uber: uber
 
+  # Layouts to match the directory of a created file against, in order.
+  # The first matching layout takes precedence over dir_to_base_package.
+  # In the path, <name> matches any part of a single directory name, and
+  # the package and options can use the matched values.
+  {{.V}}layouts:
+    # This means that a file created "idl/payments/ledger/v2/ledger.proto" will have
+    # package "payments.ledger.v2" and go_package "github.com/example/gen/go/payments/ledger/v2;ledgerpb".
+    {{.V}}- path: idl/<team>/<name>/v<version>
+    {{.V}}  package: <team>.<name>.v<version>
+    {{.V}}  options:
+    {{.V}}    go_package: github.com/example/gen/go/<team>/<name>/v<version>;<name>pb
+
+  # The header to add to created files as comments. Lines that do not start
+  # with // are commented. <author> and <author_email> are user.name and
+  # user.email from git config, <date> is YYYY-MM-DD, <ticket> is the value
+  # of --ticket, and <year>, <package>, <file>, and the variables of the
+  # matching layout can also be used.
+  {{.V}}header: |
+  {{.V}}  Created by <author> on <date> for <ticket>.
+
 # Lint directives.
 {{.V}}lint:
   # Linter * files to ignore.
@@ -79,6 +197,26 @@ protoc_version: {{.ProtocVersion}}
 {{.V}}    SYNTAX_PROTO3:
 {{.V}}      - path/to/foo.proto
 
+  # Linters to use or not use for the files matching a path, in addition to
+  # the linters above. Paths are globs relative to this file matched as for
+  # owners below. If more than one path matches a file, they are applied in
+  # order, so the last one that includes or excludes a linter is used.
+{{.V}}  overrides:
+{{.V}}    - path: internal/
+{{.V}}      exclude_ids:
+{{.V}}        - MESSAGES_HAVE_COMMENTS
+{{.V}}    - path: api/**
+{{.V}}      include_ids:
+{{.V}}        - AIP_PAGINATION_FIELDS
+
+  # The severity of the failures of a linter, either error, warning, or info.
+  # Warnings and info failures are printed but do not fail lint, unless the
+  # number of warnings is greater than the --max-warnings flag.
+  # By default, all failures are errors.
+{{.V}}  id_to_severity:
+{{.V}}    ENUM_ZERO_VALUES_INVALID: warning
+{{.V}}    MESSAGES_HAVE_COMMENTS: info
+
   # When specifying linters, you can only specify ids, or any combination of
   # group,include_ids,exclude_ids, but not ids and any of those three.
   # Run prototool list-all-linters to see all available linters.
@@ -102,6 +240,268 @@ protoc_version: {{.ProtocVersion}}
 {{.V}}  exclude_ids:
 {{.V}}    - ENUM_NAMES_CAMEL_CASE
 
+  # How enum values are named, for the linters ENUM_FIELD_PREFIXES and
+  # ENUM_ZERO_VALUES_INVALID and for prototool migrate enums.
+{{.V}}  enums:
+    # The prefix of enum values, either nested for [NESTED_MESSAGE_NAME_]ENUM_NAME_,
+    # enum for ENUM_NAME_, or none for no prefix.
+    # The default is enum for the aip lint group, and nested otherwise.
+{{.V}}    value_prefix: enum
+
+    # The suffix of enum zero values, which are prefixed with ENUM_NAME_ if
+    # value_prefix is none.
+    # The default is UNSPECIFIED for the aip lint group, and INVALID otherwise.
+{{.V}}    zero_value_suffix: UNSPECIFIED
+
+  # How services, RPCs, and request and response types are named, for the
+  # linters SERVICE_NAMES_HAVE_SUFFIX, RPC_NAMES_HAVE_PREFIX, and
+  # REQUEST_RESPONSE_NAMES_MATCH_RPC, which are not in the default lint group.
+{{.V}}  naming:
+    # The suffixes that service names must end with one of.
+    # The default is API and Service.
+{{.V}}    service_suffixes:
+{{.V}}      - Service
+
+    # The prefixes that RPC names must start with one of.
+    # The default is Create, Delete, Get, List, and Update.
+{{.V}}    rpc_prefixes:
+{{.V}}      - Get
+{{.V}}      - List
+{{.V}}      - Search
+
+    # The templates for request and response type names, with the fields .Service
+    # for the service name and .RPC for the RPC name.
+    # The defaults are {{"{{"}}.RPC{{"}}"}}Request and {{"{{"}}.RPC{{"}}"}}Response.
+{{.V}}    request_template: "{{"{{"}}.RPC{{"}}"}}Request"
+{{.V}}    response_template: "{{"{{"}}.Service{{"}}"}}{{"{{"}}.RPC{{"}}"}}Response"
+
+  # Field number policy, used by FIELD_NUMBERS_LOW_FOR_HOT_FIELDS and
+  # MESSAGE_FIELDS_MAX_COUNT.
+{{.V}}  fields:
+    # The map of fully-qualified message name to the fields of the message
+    # that must use field numbers 1 to 15, which are encoded in one byte.
+    # No other fields of these messages may use field numbers 1 to 15.
+{{.V}}    hot_fields:
+{{.V}}      foo.v1.Event:
+{{.V}}        - id
+{{.V}}        - timestamp
+
+    # The maximum number of fields a message can have.
+    # The default is 100.
+{{.V}}    max_per_message: 50
+
+  # Package policy, used by PACKAGE_HAS_VERSION_SUFFIX.
+{{.V}}  packages:
+    # Do not allow alpha and beta package versions such as v1beta1.
+{{.V}}    stable_versions_only: true
+
+  # Custom options policy, used by CUSTOM_OPTIONS_NUMBERS_IN_RANGE.
+{{.V}}  options:
+    # The field numbers or ranges of field numbers that custom options,
+    # which are extensions of the messages in google/protobuf/descriptor.proto,
+    # may use, such as the range registered for your company.
+    # The default is 50000-99999.
+{{.V}}    number_ranges:
+{{.V}}      - 50000-50999
+
+  # Sensitive data policy, used by SENSITIVE_FIELDS_ANNOTATED and
+  # LOG_EXEMPT_TYPES_NOT_IN_LOG_PACKAGES.
+{{.V}}  sensitive:
+    # The fully-qualified name of the custom option that sensitive fields
+    # must set. Setting one of the fields of the option is also allowed.
+{{.V}}    option: foo.v1.sensitive
+    # Globs of lower-case field names that are sensitive.
+    # The default is *email*, *password*, and *ssn*.
+{{.V}}    field_patterns:
+{{.V}}      - "*email*"
+{{.V}}      - "*password*"
+{{.V}}      - "*ssn*"
+{{.V}}      - "*phone*"
+    # Fully-qualified types or package wildcards that must never be logged,
+    # and so cannot be used by fields and RPCs in log_packages.
+{{.V}}    log_exempt_types:
+{{.V}}      - foo.v1.CardDetails
+{{.V}}      - foo.secret.*
+    # Packages or package wildcards whose messages are logged.
+{{.V}}    log_packages:
+{{.V}}      - foo.log.*
+
+  # Forbidden imports and types, used by IMPORTS_AND_TYPES_NOT_FORBIDDEN.
+{{.V}}  forbidden:
+    # Files or directory prefixes ending in / that cannot be imported.
+    # The allow list contains file path patterns that may import them.
+{{.V}}    imports:
+{{.V}}      - path: gogo/protobuf/gogo.proto
+{{.V}}      - path: foo/internal/
+{{.V}}        allow:
+{{.V}}          - foo/v1/
+    # Fully-qualified types or package wildcards that cannot be used by
+    # fields and RPCs. The allow list works the same as for imports.
+{{.V}}    types:
+{{.V}}      - name: google.protobuf.Any
+{{.V}}      - name: foo.internal.*
+{{.V}}        allow:
+{{.V}}          - foo/v1/
+
+# JSON output directives for binary-to-json, binary-to-yaml, and grpc.
+# These can be overridden with flags.
+{{.V}}json:
+  # Output fields with default values.
+{{.V}}  emit_defaults: true
+
+  # Use the original proto field names instead of lowerCamelCase names.
+{{.V}}  orig_name: true
+
+  # Output enum values as integers instead of names.
+{{.V}}  enums_as_ints: true
+
+  # The number of spaces to indent with. Set to a negative value for no indentation.
+  # By default, conversions are not indented and grpc output is indented with two spaces.
+{{.V}}  indent: 2
+
+# Module directives, for publishing and depending on versioned FileDescriptorSets
+# stored in an HTTP artifact store with prototool module push and prototool module fetch.
+{{.V}}modules:
+  # The base URL of the artifact store.
+{{.V}}  registry_url: https://artifacts.example.com/protobuf
+
+  # The name to push the files in this directory as.
+{{.V}}  name: acme/users
+
+  # The modules to depend on. Run prototool module fetch to fetch these, after which
+  # the files in these modules can be imported.
+{{.V}}  deps:
+{{.V}}    - name: acme/common
+{{.V}}      version: v1.0.0
+
+# Documentation directives.
+{{.V}}doc:
+  # The path to write the documentation for all files to when running gen.
+  # Must be relative.
+  {{.V}}output: doc/api.md
+
+  # The format of the documentation, either markdown or html.
+  # The default is markdown.
+  {{.V}}format: markdown
+
+# Breaking change check directives.
+{{.V}}break:
+  # The IDs of the breaking change checks to not run.
+  {{.V}}ignore_ids:
+    {{.V}}- FIELDS_RESERVED_ON_DELETE
+
+  # The fully-qualified types to not check, including the types nested within them.
+  {{.V}}ignore_types:
+    {{.V}}- foo.v1.Bar
+
+  # The path to a file of one-time exceptions for intentional breaking changes.
+  # Each exception has an id, an optional type, a reason, and an expiry date of
+  # the form YYYY-MM-DD, after which the exception no longer applies.
+  # Must be relative.
+  {{.V}}exceptions_file: break_exceptions.yaml
+
+# The teams that own files, included in failures as the owner field.
+# Paths are globs relative to this file matched as in CODEOWNERS files, where a
+# path with no slash matches in any directory, a path ending in a slash matches
+# everything in the directory, and ** matches any number of directories.
+# If more than one path matches a file, the last one is used.
+{{.V}}owners:
+  {{.V}}- path: "*.proto"
+    {{.V}}team: api-platform
+  {{.V}}- path: payments/
+    {{.V}}team: payments
+
+# The webhook that compile, lint, and break check failures are posted to
+# when --notify is passed. Environment variables in the url and the header
+# values are expanded, and the header values can be references to secrets
+# as described for grpc below.
+{{.V}}webhook:
+  {{.V}}url: https://hooks.example.com/prototool
+  # The template for the request body, executed with the fields command,
+  # error_count, warning_count, and failures as Command, ErrorCount,
+  # WarningCount, and Failures. The json function encodes a value as JSON.
+  # The default is to post the failures as JSON.
+  {{.V}}template: '{"text": {{"{{"}}json (printf "prototool %s found %d errors" .Command .ErrorCount){{"}}"}}}'
+  {{.V}}headers:
+    {{.V}}Authorization: Bearer ${WEBHOOK_TOKEN}
+
+# Named endpoints that grpc connects to with --target NAME instead of
+# --address. Environment variables in the address, the header values, and
+# the auth token are expanded. Headers passed with --header take precedence.
+#
+# These values can also be references to secrets, so that secrets are not
+# stored in this file: env://NAME is the value of an environment variable,
+# file://path is the contents of a file relative to this file, and
+# cmd://command is the output of a command, which is split on whitespace
+# and not run in a shell.
+{{.V}}grpc:
+  {{.V}}targets:
+    {{.V}}staging-users:
+      {{.V}}address: users.staging.example.com:443
+      {{.V}}headers:
+        {{.V}}x-api-key: env://STAGING_API_KEY
+      # The token to attach to each call as the header
+      # "authorization: Bearer token", unless --auth-token or
+      # --auth-token-file is passed.
+      {{.V}}auth_token: cmd://vault read -field=token secret/staging-users
+      # Connect with TLS. The paths are relative to this file.
+      {{.V}}tls:
+        {{.V}}enabled: true
+        # The certificate authorities to verify the server with.
+        # The default is to use the system certificate authorities.
+        {{.V}}ca_cert: certs/ca.pem
+        # The client certificate and key, if the server requires them.
+        {{.V}}cert: certs/client.pem
+        {{.V}}key: certs/client-key.pem
+        # The name to verify the server certificate with instead of the
+        # host of the address.
+        {{.V}}server_name: users.example.com
+
+# Vet directives.
+{{.V}}vet:
+  # For each package pattern, the package patterns that matching packages may
+  # not import, directly or transitively. Patterns are either a package, or a
+  # package followed by .* which matches the package and all packages within it.
+  {{.V}}disallowed_imports:
+    {{.V}}foo.v1:
+      {{.V}}- internal.*
+    {{.V}}foo.*:
+      {{.V}}- bar.v1
+
+# Format directives.
+{{.V}}format:
+  # The indentation to use, either 2, 4, or tab.
+  # The default is 2.
+  {{.V}}indent: 2
+
+  # Align the = signs of consecutive fields and enum values.
+  {{.V}}align_fields: true
+
+  # Align the opening brackets of the options of consecutive fields and enum values.
+  {{.V}}align_options: true
+
+  # The number of blank lines between top-level elements, between 1 and 3.
+  # The default is 1.
+  {{.V}}top_level_blank_lines: 1
+
+  # Order imports with regular imports first, then public imports, then weak
+  # imports, and order file options with built-in options before custom options.
+  # The FILE_HEADER_CANONICAL_ORDER lint check verifies this order.
+  # The default is to sort imports by filename and file options by name.
+  {{.V}}canonical_order: true
+
+  # Templates to compute the values of file options from when formatting without
+  # --no-rewrite, overriding the defaults. The keys can be csharp_namespace,
+  # go_package, java_multiple_files, java_outer_classname, java_package, and
+  # objc_class_prefix. The templates can use .Package (foo.bar.v1),
+  # .PackageLast (v1), .PackagePath (foo/bar/v1), .PackageUpperCamelCase
+  # (Foo.Bar.V1), .PackageInitials (FBV), and .FileUpperCamelCase (FooBar for
+  # foo_bar.proto). csharp_namespace is only
+  # set if it has a template. The FILE_OPTIONS_EQUAL lint checks only accept
+  # the default values, so exclude the checks for options you override.
+  {{.V}}file_option_templates:
+    {{.V}}csharp_namespace: "{{"{{"}}.PackageUpperCamelCase{{"}}"}}"
+
 # Code generation directives.
 {{.V}}gen:
   # Options that will apply to all plugins of type go, gogo, gogrpc, gogogrpc.
@@ -129,6 +529,30 @@ protoc_version: {{.ProtocVersion}}
 {{.V}}  plugin_overrides:
 {{.V}}    grpc-gpp: /usr/local/bin/grpc_cpp_plugin
 
+  # The directories to look for protoc-gen-NAME in, in order, instead of PATH,
+  # for plugins that do not have a path and are not built into protoc.
+  # Paths can be relative to this file.
+{{.V}}  plugin_dirs:
+{{.V}}    - ../../bin
+
+  # Generate Go client interfaces and stub implementations of them for the
+  # services, for use as test doubles, in the directory structure of the
+  # .proto files under this directory relative to this file. The generated
+  # code references the package of the first go plugin.
+{{.V}}  go_stubs:
+{{.V}}    output: ../../gen/stubs
+
+  # Add a header comment to the generated files with the tool versions and the
+  # path and SHA-256 hash of the .proto file each was generated from.
+{{.V}}  provenance:
+{{.V}}    enabled: true
+    # Text to put at the top of the header, such as a copyright notice.
+{{.V}}    license: Copyright (c) 2024 Foo Inc. All rights reserved.
+    # The timestamp to put in the header. The default is none so that the
+    # generated files are reproducible. Can also be now, or source_date_epoch
+    # for the time in the SOURCE_DATE_EPOCH environment variable.
+{{.V}}    timestamp: none
+
   # The list of plugins.
 {{.V}}  plugins:
       # The plugin name. This will go to protoc with --name_out, so it either needs
@@ -154,8 +578,33 @@ protoc_version: {{.ProtocVersion}}
       # This needs to be a relative path.
 {{.V}}      output: ../../.gen/proto/go
 
+      # Extra flags to pass as-is to protoc only when generating with this
+      # plugin, after the protoc extra_args.
+{{.V}}      protoc_args:
+{{.V}}        - --gogo_opt=paths=source_relative
+
+      # The maximum time protoc can run when generating with this plugin,
+      # overriding the protoc timeout.
+{{.V}}      timeout: 10m
+
+{{.V}}    - name: gogofaster
+      # The preset, if any. Valid presets are gogofast, gogofaster, gogoslick,
+      # grpc-gateway, openapiv2, js, ts. Presets set the type, default the name
+      # to the preset, and always map the Well-Known Types to their packages.
+      # The gogo presets set gogo_protobuf_version to 1.3.2 if not set.
+      # The grpc-gateway and openapiv2 presets use the downloaded plugins,
+      # and set grpc_gateway_version to 2.16.0 if not set.
+      # The js preset uses the downloaded protoc-gen-js, and sets
+      # protobuf_javascript_version to 3.21.2 if not set.
+      # The ts preset uses protoc-gen-ts from the closest node_modules/.bin.
+{{.V}}      preset: gogofaster
+{{.V}}      output: ../../.gen/proto/gogofaster
+
 {{.V}}    - name: yarpc-go
 {{.V}}      type: gogo
+      # The path to the plugin, either absolute or relative to this file.
+      # By default, the plugin is looked for in plugin_dirs if set, otherwise in PATH.
+{{.V}}      path: ../../bin/protoc-gen-yarpc-go
 {{.V}}      output: ../../.gen/proto/go
 
 {{.V}}    - name: grpc-gateway
@@ -0,0 +1,435 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cfginit
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+var makefileAssignmentRegexp = regexp.MustCompile(`^(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)\s*(\?=|:=|::=|\+=|=)\s*(.*)$`)
+
+// externalConfig is the subset of the prototool.yaml settings that init
+// generates from the configuration of other tools.
+type externalConfig struct {
+	Excludes          []string                   `json:"excludes,omitempty"`
+	ProtocVersion     string                     `json:"protoc_version,omitempty"`
+	ProtocIncludes    []string                   `json:"protoc_includes,omitempty"`
+	ProtocIncludeWKT  bool                       `json:"protoc_include_wkt,omitempty"`
+	GoogleapisVersion string                     `json:"googleapis_version,omitempty"`
+	Protoc            *protocExternalConfig      `json:"protoc,omitempty"`
+	ProtocRoots       []protocRootExternalConfig `json:"protoc_roots,omitempty"`
+	Lint              *lintExternalConfig        `json:"lint,omitempty"`
+	Gen               *genExternalConfig         `json:"gen,omitempty"`
+}
+
+type protocExternalConfig struct {
+	ExtraArgs []string `json:"extra_args,omitempty"`
+}
+
+type protocRootExternalConfig struct {
+	Path string `json:"path,omitempty"`
+}
+
+type lintExternalConfig struct {
+	IDs             []string            `json:"ids,omitempty"`
+	IgnoreIDToFiles map[string][]string `json:"ignore_id_to_files,omitempty"`
+	Enums           *struct {
+		ZeroValueSuffix string `json:"zero_value_suffix,omitempty"`
+	} `json:"enums,omitempty"`
+	Naming *struct {
+		ServiceSuffixes []string `json:"service_suffixes,omitempty"`
+	} `json:"naming,omitempty"`
+}
+
+type genExternalConfig struct {
+	Plugins []*genPluginExternalConfig `json:"plugins,omitempty"`
+}
+
+type genPluginExternalConfig struct {
+	Name   string `json:"name,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Flags  string `json:"flags,omitempty"`
+	Output string `json:"output,omitempty"`
+}
+
+// GenerateFromProtoc generates the data for a prototool.yaml file that
+// compiles and generates like the given protoc command lines, which are
+// assumed to be run from the directory of the prototool.yaml file.
+//
+// Command lines can contain several commands separated by &&, ||, or ;,
+// and commands that do not run protoc are ignored. Include paths that
+// contain the input files become protoc_roots, other include paths
+// become protoc_includes, and each --NAME_out becomes a plugin named
+// NAME. Input files are otherwise ignored, as prototool finds them.
+// Flags that prototool manages, such as -o and --include_imports, are
+// dropped, and other flags become protoc extra_args.
+func GenerateFromProtoc(protocVersion string, commandLines ...string) ([]byte, error) {
+	var protocCommands [][]string
+	for _, commandLine := range commandLines {
+		tokens, err := splitCommandLine(commandLine)
+		if err != nil {
+			return nil, err
+		}
+		for _, command := range splitCommands(tokens) {
+			if len(command) > 0 && isProtocCommand(command[0]) {
+				protocCommands = append(protocCommands, command[1:])
+			}
+		}
+	}
+	if len(protocCommands) == 0 {
+		return nil, errors.New("no protoc command found")
+	}
+	externalConfig := &externalConfig{
+		ProtocVersion: protocVersion,
+	}
+	var includes []string
+	var inputs []string
+	var extraArgs []string
+	var plugins []*genPluginExternalConfig
+	nameToPlugin := make(map[string]*genPluginExternalConfig)
+	nameToPath := make(map[string]string)
+	nameToOpts := make(map[string][]string)
+	for _, args := range protocCommands {
+		for i := 0; i < len(args); i++ {
+			arg := args[i]
+			if !strings.HasPrefix(arg, "-") {
+				inputs = append(inputs, arg)
+				continue
+			}
+			name, value, hasValue := arg, "", false
+			switch {
+			case strings.HasPrefix(arg, "--"):
+				if j := strings.Index(arg, "="); j >= 0 {
+					name, value, hasValue = arg[:j], arg[j+1:], true
+				}
+			case len(arg) > 2:
+				// -Ipath and -opath
+				name, value, hasValue = arg[:2], arg[2:], true
+			}
+			if !hasValue && protocFlagTakesValue(name) {
+				if i+1 == len(args) {
+					return nil, fmt.Errorf("%s requires a value", name)
+				}
+				i++
+				value = args[i]
+			}
+			switch {
+			case name == "-I", name == "--proto_path":
+				includes = append(includes, filepath.SplitList(value)...)
+			case name == "-o", name == "--descriptor_set_out", name == "--include_imports", name == "--include_source_info", name == "--error_format":
+				// managed by prototool
+			case name == "--plugin":
+				pluginName, pluginPath := "", value
+				if j := strings.Index(value, "="); j >= 0 {
+					pluginName, pluginPath = value[:j], value[j+1:]
+				} else {
+					pluginName = strings.TrimSuffix(path.Base(filepath.ToSlash(value)), ".exe")
+				}
+				nameToPath[strings.TrimPrefix(pluginName, "protoc-gen-")] = pluginPath
+			case strings.HasSuffix(name, "_out"):
+				pluginName := strings.TrimSuffix(strings.TrimPrefix(name, "--"), "_out")
+				opts, output := splitProtocOutValue(value)
+				plugin, ok := nameToPlugin[pluginName]
+				if !ok {
+					plugin = &genPluginExternalConfig{
+						Name: pluginName,
+					}
+					nameToPlugin[pluginName] = plugin
+					plugins = append(plugins, plugin)
+				}
+				plugin.Output = output
+				if opts != "" {
+					nameToOpts[pluginName] = append(nameToOpts[pluginName], opts)
+				}
+			case strings.HasSuffix(name, "_opt"):
+				pluginName := strings.TrimSuffix(strings.TrimPrefix(name, "--"), "_opt")
+				nameToOpts[pluginName] = append(nameToOpts[pluginName], value)
+			default:
+				extraArgs = appendIfMissing(extraArgs, arg)
+			}
+		}
+	}
+	for _, include := range includes {
+		include = strings.TrimSuffix(filepath.ToSlash(include), "/")
+		switch {
+		case include == "" || path.Clean(include) == ".":
+			// the config directory is always included
+		case filepath.IsAbs(include) && path.Base(include) == "include":
+			// the include directory of a system protoc, which contains
+			// the Well-Known Types
+			externalConfig.ProtocIncludeWKT = true
+		case !filepath.IsAbs(include) && !strings.HasPrefix(path.Clean(include), "..") && containsInput(path.Clean(include), inputs):
+			if !containsProtocRoot(externalConfig.ProtocRoots, path.Clean(include)) {
+				externalConfig.ProtocRoots = append(externalConfig.ProtocRoots, protocRootExternalConfig{Path: path.Clean(include)})
+			}
+		default:
+			externalConfig.ProtocIncludes = appendIfMissing(externalConfig.ProtocIncludes, include)
+		}
+	}
+	if len(extraArgs) > 0 {
+		externalConfig.Protoc = &protocExternalConfig{
+			ExtraArgs: extraArgs,
+		}
+	}
+	if len(plugins) > 0 {
+		for _, plugin := range plugins {
+			plugin.Path = nameToPath[plugin.Name]
+			plugin.Flags = strings.Join(nameToOpts[plugin.Name], ",")
+		}
+		externalConfig.Gen = &genExternalConfig{
+			Plugins: plugins,
+		}
+	}
+	headerLines := []string{"Generated by prototool init from:"}
+	for _, args := range protocCommands {
+		headerLines = append(headerLines, "  protoc "+strings.Join(args, " "))
+	}
+	return marshalExternalConfig(externalConfig, headerLines)
+}
+
+// marshalExternalConfig marshals the externalConfig to YAML after the
+// given lines as comments.
+func marshalExternalConfig(externalConfig *externalConfig, headerLines []string) ([]byte, error) {
+	data, err := yaml.Marshal(externalConfig)
+	if err != nil {
+		return nil, err
+	}
+	buffer := bytes.NewBuffer(nil)
+	for _, headerLine := range headerLines {
+		buffer.WriteString("# " + headerLine + "\n")
+	}
+	buffer.Write(data)
+	return buffer.Bytes(), nil
+}
+
+// ProtocCommandLinesFromMakefile returns the recipe lines of the given
+// Makefile data that may run protoc, with simple variable references
+// expanded, for use with GenerateFromProtoc.
+//
+// Only variables assigned with =, :=, ::=, ?=, and += are expanded, and
+// conditionals, functions, and included Makefiles are not evaluated.
+func ProtocCommandLinesFromMakefile(data []byte) ([]string, error) {
+	variables := make(map[string]string)
+	var recipeLines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	var line string
+	for scanner.Scan() {
+		// join lines continued with a backslash
+		text := scanner.Text()
+		if line != "" {
+			text = strings.TrimLeft(text, " \t")
+		}
+		if strings.HasSuffix(text, "\\") {
+			line += strings.TrimRight(strings.TrimSuffix(text, "\\"), " \t") + " "
+			continue
+		}
+		line += text
+		if strings.HasPrefix(line, "\t") {
+			recipeLine := strings.TrimLeft(strings.TrimSpace(line), "@-+")
+			if strings.Contains(recipeLine, "protoc") || strings.Contains(recipeLine, "PROTOC") {
+				recipeLines = append(recipeLines, recipeLine)
+			}
+		} else if matches := makefileAssignmentRegexp.FindStringSubmatch(strings.TrimSpace(line)); matches != nil {
+			name, operator, value := matches[1], matches[2], strings.TrimSpace(matches[3])
+			switch operator {
+			case "?=":
+				if _, ok := variables[name]; !ok {
+					variables[name] = value
+				}
+			case "+=":
+				variables[name] = strings.TrimSpace(variables[name] + " " + value)
+			default:
+				variables[name] = value
+			}
+		}
+		line = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for i, recipeLine := range recipeLines {
+		recipeLines[i] = expandMakefileVariables(recipeLine, variables, 0)
+	}
+	return recipeLines, nil
+}
+
+func expandMakefileVariables(s string, variables map[string]string, depth int) string {
+	// guard against variables that reference themselves
+	if depth > 10 {
+		return s
+	}
+	for name, value := range variables {
+		expandedValue := expandMakefileVariables(value, variables, depth+1)
+		s = strings.Replace(s, "$("+name+")", expandedValue, -1)
+		s = strings.Replace(s, "${"+name+"}", expandedValue, -1)
+	}
+	return s
+}
+
+// splitCommandLine splits the command line into tokens like a shell,
+// keeping $(...) and `...` substitutions as part of a single token.
+func splitCommandLine(commandLine string) ([]string, error) {
+	var tokens []string
+	token := bytes.NewBuffer(nil)
+	inToken := false
+	var quote rune
+	parens := 0
+	escaped := false
+	for _, c := range commandLine {
+		switch {
+		case escaped:
+			// a backslash before a newline continues the line
+			if c != '\n' {
+				token.WriteRune(c)
+				inToken = true
+			}
+			escaped = false
+		case c == '\\' && quote == 0 && parens == 0:
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				if c == '`' {
+					token.WriteRune(c)
+				}
+				quote = 0
+			} else {
+				token.WriteRune(c)
+			}
+		case parens > 0:
+			token.WriteRune(c)
+			if c == '(' {
+				parens++
+			} else if c == ')' {
+				parens--
+			}
+		case c == '\'' || c == '"' || c == '`':
+			if c == '`' {
+				token.WriteRune(c)
+			}
+			quote = c
+			inToken = true
+		case c == '(' && strings.HasSuffix(token.String(), "$"):
+			token.WriteRune(c)
+			parens++
+		case c == ' ' || c == '\t' || c == '\n':
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		case c == ';':
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+			tokens = append(tokens, ";")
+		default:
+			token.WriteRune(c)
+			inToken = true
+		}
+	}
+	if quote != 0 || parens > 0 {
+		return nil, fmt.Errorf("unterminated quote or substitution in %q", commandLine)
+	}
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
+
+// splitCommands splits the tokens into commands at &&, ||, |, and ;.
+func splitCommands(tokens []string) [][]string {
+	var commands [][]string
+	var command []string
+	for _, token := range tokens {
+		switch token {
+		case "&&", "||", "|", ";":
+			commands = append(commands, command)
+			command = nil
+		default:
+			command = append(command, token)
+		}
+	}
+	return append(commands, command)
+}
+
+func isProtocCommand(token string) bool {
+	name := strings.ToLower(path.Base(filepath.ToSlash(token)))
+	name = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(name, "$("), "${"), ")")
+	name = strings.TrimSuffix(name, "}")
+	return name == "protoc" || name == "protoc.exe"
+}
+
+func protocFlagTakesValue(name string) bool {
+	switch name {
+	case "-I", "--proto_path", "-o", "--descriptor_set_out", "--plugin", "--error_format":
+		return true
+	}
+	return strings.HasPrefix(name, "--") && (strings.HasSuffix(name, "_out") || strings.HasSuffix(name, "_opt"))
+}
+
+// splitProtocOutValue splits the value of a --NAME_out flag into the
+// plugin options and the output directory.
+func splitProtocOutValue(value string) (string, string) {
+	i := strings.Index(value, ":")
+	// a Windows drive letter is not a separator
+	if i < 0 || (i == 1 && len(value) > 2 && (value[2] == '\\' || value[2] == '/')) {
+		return "", value
+	}
+	return value[:i], value[i+1:]
+}
+
+func containsInput(dirPath string, inputs []string) bool {
+	for _, input := range inputs {
+		if strings.HasPrefix(path.Clean(filepath.ToSlash(input)), dirPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func containsProtocRoot(protocRoots []protocRootExternalConfig, dirPath string) bool {
+	for _, protocRoot := range protocRoots {
+		if protocRoot.Path == dirPath {
+			return true
+		}
+	}
+	return false
+}
+
+func appendIfMissing(s []string, value string) []string {
+	for _, e := range s {
+		if e == value {
+			return s
+		}
+	}
+	return append(s, value)
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cfginit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateFromProtoc(t *testing.T) {
+	t.Parallel()
+	data, err := GenerateFromProtoc(
+		"3.5.1",
+		`mkdir -p gen && protoc -I proto -I /usr/local/include -I ../third_party \
+		--go_out=plugins=grpc:gen/go --go_opt=paths=source_relative \
+		--plugin=protoc-gen-go=bin/protoc-gen-go --java_out gen/java \
+		--experimental_allow_proto3_optional --include_imports -o /dev/null \
+		$(find proto -name '*.proto') proto/foo/v1/foo.proto`,
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`# Generated by prototool init from:
+#   protoc -I proto -I /usr/local/include -I ../third_party --go_out=plugins=grpc:gen/go --go_opt=paths=source_relative --plugin=protoc-gen-go=bin/protoc-gen-go --java_out gen/java --experimental_allow_proto3_optional --include_imports -o /dev/null $(find proto -name '*.proto') proto/foo/v1/foo.proto
+gen:
+  plugins:
+  - flags: plugins=grpc,paths=source_relative
+    name: go
+    output: gen/go
+    path: bin/protoc-gen-go
+  - name: java
+    output: gen/java
+protoc:
+  extra_args:
+  - --experimental_allow_proto3_optional
+protoc_include_wkt: true
+protoc_includes:
+- ../third_party
+protoc_roots:
+- path: proto
+protoc_version: 3.5.1
+`,
+		string(data),
+	)
+
+	_, err = GenerateFromProtoc("3.5.1", "make all")
+	assert.EqualError(t, err, "no protoc command found")
+	_, err = GenerateFromProtoc("3.5.1", "protoc --go_out")
+	assert.EqualError(t, err, "--go_out requires a value")
+	_, err = GenerateFromProtoc("3.5.1", "protoc 'foo.proto")
+	assert.Error(t, err)
+}
+
+func TestProtocCommandLinesFromMakefile(t *testing.T) {
+	t.Parallel()
+	commandLines, err := ProtocCommandLinesFromMakefile([]byte(`PROTOC ?= protoc
+PROTOC ?= /usr/bin/protoc
+FLAGS = -I proto \
+	-I vendor
+FLAGS += $(EXTRA_FLAGS)
+EXTRA_FLAGS := --fatal_warnings
+
+gen:
+	@mkdir -p gen
+	$(PROTOC) $(FLAGS) --cpp_out=gen $(PROTO_FILES)
+	-${PROTOC} $(FLAGS) --python_out=gen proto/a.proto
+`))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			"protoc -I proto -I vendor --fatal_warnings --cpp_out=gen $(PROTO_FILES)",
+			"protoc -I proto -I vendor --fatal_warnings --python_out=gen proto/a.proto",
+		},
+		commandLines,
+	)
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package checkstyle provides functionality to print failures as
+// Checkstyle XML reports.
+//
+// The Checkstyle report format is understood by many CI tools,
+// such as the Jenkins Warnings Next Generation plugin and reviewdog.
+package checkstyle
+
+import (
+	"encoding/xml"
+	"io"
+
+	"github.com/uber/prototool/internal/text"
+)
+
+const (
+	// Version is the Checkstyle version written to reports.
+	Version = "8.0"
+	// DefaultSource is the source used when a failure has no ID.
+	DefaultSource = "PROTOTOOL"
+)
+
+// Report is a Checkstyle report.
+type Report struct {
+	XMLName xml.Name `xml:"checkstyle"`
+	Version string   `xml:"version,attr"`
+	Files   []*File  `xml:"file"`
+}
+
+// File is a file within a Checkstyle report.
+type File struct {
+	Name   string   `xml:"name,attr"`
+	Errors []*Error `xml:"error"`
+}
+
+// Error is an error within a Checkstyle file.
+type Error struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// TextFailuresToReport converts the text.Failures to a Report.
+//
+// Files are in the order they first appear in the failures, so the
+// failures should be sorted first.
+func TextFailuresToReport(textFailures ...*text.Failure) *Report {
+	report := &Report{
+		Version: Version,
+	}
+	nameToFile := make(map[string]*File)
+	for _, textFailure := range textFailures {
+		jsonFailure := textFailure.JSONFailure()
+		file, ok := nameToFile[jsonFailure.Filename]
+		if !ok {
+			file = &File{
+				Name: jsonFailure.Filename,
+			}
+			nameToFile[jsonFailure.Filename] = file
+			report.Files = append(report.Files, file)
+		}
+		source := jsonFailure.ID
+		if source == "" {
+			source = DefaultSource
+		}
+		file.Errors = append(file.Errors, &Error{
+			Line:     jsonFailure.Line,
+			Column:   jsonFailure.Column,
+			Severity: jsonFailure.Severity,
+			Message:  jsonFailure.Message,
+			Source:   source,
+		})
+	}
+	return report
+}
+
+// WriteReport writes the text.Failures as a Checkstyle report.
+func WriteReport(writer io.Writer, textFailures ...*text.Failure) error {
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(TextFailuresToReport(textFailures...)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, "\n")
+	return err
+}
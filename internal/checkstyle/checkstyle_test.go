@@ -0,0 +1,74 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package checkstyle
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/prototool/internal/text"
+)
+
+func TestWriteReport(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(
+		t,
+		WriteReport(
+			buffer,
+			&text.Failure{
+				Filename: "a.proto",
+				Line:     2,
+				Column:   3,
+				ID:       "FOO",
+				Message:  `Foo is a "foo".`,
+			},
+			&text.Failure{
+				Filename: "a.proto",
+				Line:     4,
+				Message:  "Bar is a bar.",
+				Severity: text.SeverityWarning,
+			},
+			&text.Failure{
+				Filename: "b.proto",
+				Line:     1,
+				Column:   1,
+				ID:       "BAZ",
+				Message:  "Baz is a baz.",
+			},
+		),
+	)
+	assert.Equal(
+		t,
+		`<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="8.0">
+  <file name="a.proto">
+    <error line="2" column="3" severity="error" message="Foo is a &#34;foo&#34;." source="FOO"></error>
+    <error line="4" column="1" severity="warning" message="Bar is a bar." source="PROTOTOOL"></error>
+  </file>
+  <file name="b.proto">
+    <error line="1" column="1" severity="error" message="Baz is a baz." source="BAZ"></error>
+  </file>
+</checkstyle>
+`,
+		buffer.String(),
+	)
+}
@@ -29,17 +29,30 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"runtime"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
+	"github.com/uber/prototool/internal/envelope"
 	"github.com/uber/prototool/internal/exec"
+	"github.com/uber/prototool/internal/exectrace"
+	"github.com/uber/prototool/internal/metrics"
+	"github.com/uber/prototool/internal/progress"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/timing"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 // when generating man pages, the current date is used
@@ -97,27 +110,158 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 
 	allCmd := &cobra.Command{
 		Use:   "all dirOrProtoFiles...",
-		Short: "Compile, then format and overwrite, then re-compile and generate, then lint, stopping if any step fails.",
+		Short: "Format and overwrite, then compile and generate, and lint.",
 		Run: func(cmd *cobra.Command, args []string) {
 			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
 				return runner.All(args, flags.disableFormat, flags.disableLint, !flags.noRewrite)
 			})
 		},
 	}
+	flags.bindCompileExitCode(allCmd.PersistentFlags())
 	flags.bindDirMode(allCmd.PersistentFlags())
 	flags.bindDisableFormat(allCmd.PersistentFlags())
 	flags.bindDisableLint(allCmd.PersistentFlags())
+	flags.bindFailureFormat(allCmd.PersistentFlags())
+	flags.bindFormatExitCode(allCmd.PersistentFlags())
+	flags.bindLintExitCode(allCmd.PersistentFlags())
+	flags.bindMaxWarnings(allCmd.PersistentFlags())
+	flags.bindNoCache(allCmd.PersistentFlags())
 	flags.bindNoRewrite(allCmd.PersistentFlags())
+	flags.bindNotify(allCmd.PersistentFlags())
+	flags.bindJobs(allCmd.PersistentFlags())
+	flags.bindWarningsAsErrors(allCmd.PersistentFlags())
+
+	annotationsCmd := &cobra.Command{
+		Use:   "annotations",
+		Short: "Annotation commands.",
+	}
+
+	annotationsListCmd := &cobra.Command{
+		Use:   "list dirOrProtoFiles...",
+		Short: "List the usages of custom options, such as options that mark fields as sensitive.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.AnnotationsList(args, flags.optionNames, flags.annotationsFormat)
+			})
+		},
+	}
+	flags.bindAnnotationsOutputFormat(annotationsListCmd.PersistentFlags())
+	flags.bindDirMode(annotationsListCmd.PersistentFlags())
+	flags.bindOptionNames(annotationsListCmd.PersistentFlags())
+	annotationsCmd.AddCommand(annotationsListCmd)
+
+	bazelCmd := &cobra.Command{
+		Use:   "bazel",
+		Short: "Bazel integration commands.",
+	}
+
+	bazelGenCmd := &cobra.Command{
+		Use:   "gen dirOrProtoFiles...",
+		Short: "Generate Bazel BUILD files with proto_library and go_proto_library rules matching the config and import graph.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.BazelGen(args, flags.dryRun) })
+		},
+	}
+	flags.bindDirMode(bazelGenCmd.PersistentFlags())
+	bazelCmd.AddCommand(bazelGenCmd)
+
+	breakCmd := &cobra.Command{
+		Use:   "break",
+		Short: "Breaking change commands.",
+	}
+
+	breakCheckCmd := &cobra.Command{
+		Use:   "check dirOrProtoFiles...",
+		Short: "Check for breaking changes against the given git ref.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.BreakCheck(args, flags.gitRef) })
+		},
+	}
+	flags.bindDirMode(breakCheckCmd.PersistentFlags())
+	flags.bindFailureFormat(breakCheckCmd.PersistentFlags())
+	flags.bindGitRef(breakCheckCmd.PersistentFlags())
+	flags.bindNotify(breakCheckCmd.PersistentFlags())
+	breakCmd.AddCommand(breakCheckCmd)
+
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Config file commands.",
+	}
+
+	configExportCmd := &cobra.Command{
+		Use:   "export [dirPath]",
+		Short: "Export the config file for the current or given directory to the configuration files of another tool.",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.ConfigExport(args, flags.exportFormat, flags.dryRun)
+			})
+		},
+	}
+	flags.bindExportFormat(configExportCmd.PersistentFlags())
+	configCmd.AddCommand(configExportCmd)
+
+	configIncludesCmd := &cobra.Command{
+		Use:   "includes dirOrProtoFiles...",
+		Short: "Print the include paths passed to protoc, or with --explain, the include path that satisfies each import.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.ConfigIncludes(args, flags.explain)
+			})
+		},
+	}
+	flags.bindExplain(configIncludesCmd.PersistentFlags())
+	configCmd.AddCommand(configIncludesCmd)
 
 	binaryToJSONCmd := &cobra.Command{
 		Use:   "binary-to-json dirOrProtoFiles... messagePath data",
 		Short: "Convert the data from json to binary for the message path and data.",
-		Args:  cobra.MinimumNArgs(3),
+		Args:  cobra.MinimumNArgs(2),
 		Run: func(cmd *cobra.Command, args []string) {
-			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.BinaryToJSON(args) })
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.BinaryToJSON(args, flags.delimited) })
 		},
 	}
+	flags.bindDelimited(binaryToJSONCmd.PersistentFlags())
+	flags.bindDescriptorSet(binaryToJSONCmd.PersistentFlags())
 	flags.bindDirMode(binaryToJSONCmd.PersistentFlags())
+	flags.bindJSON(binaryToJSONCmd.PersistentFlags())
+
+	binaryToTextCmd := &cobra.Command{
+		Use:   "binary-to-text dirOrProtoFiles... messagePath data",
+		Short: "Convert the data from binary to text format for the message path and data.",
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.BinaryToText(args) })
+		},
+	}
+	flags.bindDescriptorSet(binaryToTextCmd.PersistentFlags())
+	flags.bindDirMode(binaryToTextCmd.PersistentFlags())
+
+	binaryToYAMLCmd := &cobra.Command{
+		Use:   "binary-to-yaml dirOrProtoFiles... messagePath data",
+		Short: "Convert the data from binary to yaml for the message path and data.",
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.BinaryToYAML(args) })
+		},
+	}
+	flags.bindDescriptorSet(binaryToYAMLCmd.PersistentFlags())
+	flags.bindDirMode(binaryToYAMLCmd.PersistentFlags())
+	flags.bindJSON(binaryToYAMLCmd.PersistentFlags())
+
+	changelogCmd := &cobra.Command{
+		Use:   "changelog [dirPath]",
+		Short: "Print a changelog of the API additions, changes, deprecations, and removals per package made by the commits since a git ref.",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			dirPath := ""
+			if len(args) == 1 {
+				dirPath = args[0]
+			}
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Changelog(dirPath, flags.since) })
+		},
+	}
+	flags.bindSince(changelogCmd.PersistentFlags())
 
 	cleanCmd := &cobra.Command{
 		Use:   "clean",
@@ -132,10 +276,31 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 		Use:   "compile dirOrProtoFiles...",
 		Short: "Compile with protoc to check for failures.",
 		Run: func(cmd *cobra.Command, args []string) {
-			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Compile(args, flags.dryRun) })
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.Compile(args, flags.dryRun, flags.parserOnly, flags.protocVersions)
+			})
 		},
 	}
+	flags.bindCompileExitCode(compileCmd.PersistentFlags())
 	flags.bindDirMode(compileCmd.PersistentFlags())
+	flags.bindFailureFormat(compileCmd.PersistentFlags())
+	flags.bindJobs(compileCmd.PersistentFlags())
+	flags.bindNotify(compileCmd.PersistentFlags())
+	flags.bindParserOnly(compileCmd.PersistentFlags())
+	flags.bindProtocVersions(compileCmd.PersistentFlags())
+	flags.bindWarningsAsErrors(compileCmd.PersistentFlags())
+
+	completionCmd := &cobra.Command{
+		Use:       "completion bash|zsh|fish",
+		Short:     "Generate a shell completion file for the given shell.",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"bash", "fish", "zsh"},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := genCompletion(cmd.Root(), args[0], stdout); err != nil {
+				*exitCodeAddr = printAndGetErrorExitCode(err, stdout)
+			}
+		},
+	}
 
 	createCmd := &cobra.Command{
 		Use:   "create files...",
@@ -143,11 +308,35 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 		Args:  cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
-				return runner.Create(args, flags.pkg)
+				return runner.Create(args, flags.pkg, flags.edition, flags.packageVersion, flags.ticket)
 			})
 		},
 	}
-	flags.bindPackage(createCmd.PersistentFlags())
+	// not persistent flags so that they are not inherited by create version
+	flags.bindEdition(createCmd.Flags())
+	flags.bindPackage(createCmd.Flags())
+	flags.bindPackageVersion(createCmd.Flags())
+	flags.bindTicket(createCmd.Flags())
+
+	createVersionCmd := &cobra.Command{
+		Use:   "version dirPath version",
+		Short: "Copy the Protobuf files in a version directory such as foo/v1 to a new version directory, rewriting packages, imports, and file options.",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.CreateVersion(args[0], args[1]) })
+		},
+	}
+	createCmd.AddCommand(createVersionCmd)
+
+	decompileCmd := &cobra.Command{
+		Use:   "decompile descriptorSetFile",
+		Short: "Reconstruct formatted Protobuf files from a FileDescriptorSet. Be sure to set the required flag out.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Decompile(args[0], flags.outDirPath) })
+		},
+	}
+	flags.bindOutDirPath(decompileCmd.PersistentFlags())
 
 	descriptorProtoCmd := &cobra.Command{
 		Use:   "descriptor-proto dirOrProtoFiles... messagePath",
@@ -159,6 +348,19 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 	}
 	flags.bindDirMode(descriptorProtoCmd.PersistentFlags())
 
+	diffCmd := &cobra.Command{
+		Use:   "diff from to [dirPath]",
+		Short: "Print the added, removed, renumbered, deprecated, and type-changed packages, messages, fields, enums, and RPCs between two git refs or directories.",
+		Args:  cobra.RangeArgs(2, 3),
+		Run: func(cmd *cobra.Command, args []string) {
+			dirPath := ""
+			if len(args) == 3 {
+				dirPath = args[2]
+			}
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Diff(args[0], args[1], dirPath) })
+		},
+	}
+
 	downloadCmd := &cobra.Command{
 		Use:   "download",
 		Short: "Download the protobuf artifacts to a cache.",
@@ -191,51 +393,205 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 		Short: "Format a proto file and compile with protoc to check for failures.",
 		Run: func(cmd *cobra.Command, args []string) {
 			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
-				return runner.Format(args, flags.overwrite, flags.diffMode, flags.lintMode, !flags.noRewrite)
+				return runner.Format(args, flags.overwrite, flags.diffMode, flags.lintMode, flags.stat, !flags.noRewrite, flags.checkRoundTrip)
 			})
 		},
 	}
+	flags.bindCheckRoundTrip(formatCmd.PersistentFlags())
+	flags.bindCompileExitCode(formatCmd.PersistentFlags())
 	flags.bindDiffMode(formatCmd.PersistentFlags())
+	flags.bindFailureFormat(formatCmd.PersistentFlags())
+	flags.bindFormatExitCode(formatCmd.PersistentFlags())
 	flags.bindLintMode(formatCmd.PersistentFlags())
 	flags.bindOverwrite(formatCmd.PersistentFlags())
 	flags.bindNoRewrite(formatCmd.PersistentFlags())
+	flags.bindStat(formatCmd.PersistentFlags())
 
 	genCmd := &cobra.Command{
 		Use:   "gen dirOrProtoFiles...",
 		Short: "Generate with protoc.",
 		Run: func(cmd *cobra.Command, args []string) {
-			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Gen(args, flags.dryRun) })
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.Gen(args, flags.dryRun, flags.printPlan, flags.outputArchive)
+			})
 		},
 	}
+	flags.bindCompileExitCode(genCmd.PersistentFlags())
 	flags.bindDirMode(genCmd.PersistentFlags())
+	flags.bindFailureFormat(genCmd.PersistentFlags())
+	flags.bindJobs(genCmd.PersistentFlags())
+	flags.bindOutputArchive(genCmd.PersistentFlags())
+	flags.bindPrintPlan(genCmd.PersistentFlags())
+	flags.bindWarningsAsErrors(genCmd.PersistentFlags())
+
+	generateDataCmd := &cobra.Command{
+		Use:   "generate-data dirOrProtoFiles... messagePath",
+		Short: "Generate random messages that are valid for the message path.",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.GenerateData(args, flags.count, flags.outputFormat, flags.seed)
+			})
+		},
+	}
+	flags.bindCount(generateDataCmd.PersistentFlags())
+	flags.bindDescriptorSet(generateDataCmd.PersistentFlags())
+	flags.bindDirMode(generateDataCmd.PersistentFlags())
+	flags.bindJSON(generateDataCmd.PersistentFlags())
+	flags.bindOutputFormat(generateDataCmd.PersistentFlags())
+	flags.bindSeed(generateDataCmd.PersistentFlags())
+
+	githookCmd := &cobra.Command{
+		Use:   "githook",
+		Short: "Git hook commands.",
+	}
+
+	githookInstallCmd := &cobra.Command{
+		Use:   "install [dirPath]",
+		Short: "Install a git hook that lints and checks the formatting of changed Protobuf files in the current or given repository.",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.GithookInstall(args, flags.hookType, flags.framework, flags.overwrite)
+			})
+		},
+	}
+	flags.bindFramework(githookInstallCmd.PersistentFlags())
+	flags.bindGithookOverwrite(githookInstallCmd.PersistentFlags())
+	flags.bindHookType(githookInstallCmd.PersistentFlags())
+	githookCmd.AddCommand(githookInstallCmd)
+
+	// used for shell completion of the method flag for grpc
+	grpcMethodsCmd := &cobra.Command{
+		Use:    "grpc-methods dirOrProtoFiles...",
+		Short:  "Print the gRPC methods in the form package.Service/Method.",
+		Hidden: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.GRPCMethods(args) })
+		},
+	}
+	flags.bindDescriptorSet(grpcMethodsCmd.PersistentFlags())
+	flags.bindDirMode(grpcMethodsCmd.PersistentFlags())
 
 	grpcCmd := &cobra.Command{
 		Use:   "grpc dirOrProtoFiles...",
-		Short: "Call a gRPC endpoint. Be sure to set required flags address, method, and either data or stdin.",
+		Short: "Call a gRPC endpoint. Be sure to set required flags address or target, method, and either data or stdin.",
 		Run: func(cmd *cobra.Command, args []string) {
 			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
-				return runner.GRPC(args, flags.headers, flags.address, flags.method, flags.data, flags.callTimeout, flags.connectTimeout, flags.keepaliveTime, flags.stdin)
+				return runner.GRPC(args, exec.GRPCOptions{
+					Address:          flags.address,
+					Target:           flags.target,
+					Method:           flags.method,
+					Data:             flags.data,
+					Stdin:            flags.stdin,
+					Interactive:      flags.interactive,
+					List:             flags.list,
+					Headers:          flags.headers,
+					HeaderEnvPrefix:  flags.headerEnvPrefix,
+					Authority:        flags.authority,
+					UserAgent:        flags.userAgent,
+					AuthToken:        flags.authToken,
+					AuthTokenFile:    flags.authTokenFile,
+					Compress:         flags.compress,
+					CallTimeout:      flags.callTimeout,
+					ConnectTimeout:   flags.connectTimeout,
+					KeepaliveTime:    flags.keepaliveTime,
+					Deadline:         flags.deadline,
+					CancelAfter:      flags.cancelAfter,
+					CancelAfterBytes: flags.cancelAfterBytes,
+					WaitForReady:     flags.waitForReady,
+					MaxRecvMsgSize:   flags.maxRecvMsgSize,
+					MaxSendMsgSize:   flags.maxSendMsgSize,
+					MaxAttempts:      flags.maxAttempts,
+					RetryBackoff:     flags.retryBackoff,
+					RetryCodes:       flags.retryCodes,
+					Output:           flags.output,
+					OutputFormat:     flags.outputFormat,
+					PrintMetadata:    flags.printMetadata,
+					Fields:           flags.fields,
+					ExpectJSON:       flags.expectJSON,
+					ExpectCode:       flags.expectCode,
+					ExpectFields:     flags.expectFields,
+					Record:           flags.record,
+				})
 			})
 		},
 	}
 	flags.bindAddress(grpcCmd.PersistentFlags())
+	flags.bindAuthority(grpcCmd.PersistentFlags())
+	flags.bindAuthToken(grpcCmd.PersistentFlags())
+	flags.bindAuthTokenFile(grpcCmd.PersistentFlags())
 	flags.bindCallTimeout(grpcCmd.PersistentFlags())
+	flags.bindCancelAfter(grpcCmd.PersistentFlags())
+	flags.bindCancelAfterBytes(grpcCmd.PersistentFlags())
+	flags.bindCompress(grpcCmd.PersistentFlags())
 	flags.bindConnectTimeout(grpcCmd.PersistentFlags())
 	flags.bindData(grpcCmd.PersistentFlags())
+	flags.bindDeadline(grpcCmd.PersistentFlags())
+	flags.bindDescriptorSet(grpcCmd.PersistentFlags())
 	flags.bindDirMode(grpcCmd.PersistentFlags())
+	flags.bindExpectCode(grpcCmd.PersistentFlags())
+	flags.bindExpectFields(grpcCmd.PersistentFlags())
+	flags.bindExpectJSON(grpcCmd.PersistentFlags())
+	flags.bindFields(grpcCmd.PersistentFlags())
+	flags.bindGRPCOutput(grpcCmd.PersistentFlags())
+	flags.bindGRPCOutputFormat(grpcCmd.PersistentFlags())
+	flags.bindJSON(grpcCmd.PersistentFlags())
 	flags.bindHeaders(grpcCmd.PersistentFlags())
+	flags.bindHeaderEnvPrefix(grpcCmd.PersistentFlags())
+	flags.bindInteractive(grpcCmd.PersistentFlags())
 	flags.bindKeepaliveTime(grpcCmd.PersistentFlags())
+	flags.bindList(grpcCmd.PersistentFlags())
+	flags.bindMaxAttempts(grpcCmd.PersistentFlags())
+	flags.bindMaxRecvMsgSize(grpcCmd.PersistentFlags())
+	flags.bindMaxSendMsgSize(grpcCmd.PersistentFlags())
 	flags.bindMethod(grpcCmd.PersistentFlags())
+	flags.bindPrintMetadata(grpcCmd.PersistentFlags())
+	flags.bindRecord(grpcCmd.PersistentFlags())
+	flags.bindRetryBackoff(grpcCmd.PersistentFlags())
+	flags.bindRetryCodes(grpcCmd.PersistentFlags())
 	flags.bindStdin(grpcCmd.PersistentFlags())
+	flags.bindTarget(grpcCmd.PersistentFlags())
+	flags.bindUserAgent(grpcCmd.PersistentFlags())
+	flags.bindWaitForReady(grpcCmd.PersistentFlags())
+
+	grpcProxyCmd := &cobra.Command{
+		Use:   "proxy dirOrProtoFiles...",
+		Short: "Forward gRPC calls to address or target, printing the requests and responses as JSON. Be sure to set the required flag listen.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.GRPCProxy(args, exec.GRPCProxyOptions{
+					Address:         flags.address,
+					Target:          flags.target,
+					Listen:          flags.listen,
+					Headers:         flags.headers,
+					HeaderEnvPrefix: flags.headerEnvPrefix,
+					Authority:       flags.authority,
+					UserAgent:       flags.userAgent,
+					AuthToken:       flags.authToken,
+					AuthTokenFile:   flags.authTokenFile,
+					ConnectTimeout:  flags.connectTimeout,
+					Record:          flags.record,
+				})
+			})
+		},
+	}
+	flags.bindListen(grpcProxyCmd.PersistentFlags())
+	grpcCmd.AddCommand(grpcProxyCmd)
 
 	initCmd := &cobra.Command{
 		Use:   "init [dirPath]",
 		Short: "Generate an initial config file in the current or given directory.",
 		Args:  cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Init(args, flags.uncomment) })
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.Init(args, flags.uncomment, flags.fromProtoc, flags.fromMakefile, flags.fromBuf)
+			})
 		},
 	}
+	flags.bindFromBuf(initCmd.PersistentFlags())
+	flags.bindFromMakefile(initCmd.PersistentFlags())
+	flags.bindFromProtoc(initCmd.PersistentFlags())
 	flags.bindUncomment(initCmd.PersistentFlags())
 
 	jsonToBinaryCmd := &cobra.Command{
@@ -243,19 +599,61 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 		Short: "Convert the data from json to binary for the message path and data.",
 		Args:  cobra.MinimumNArgs(2),
 		Run: func(cmd *cobra.Command, args []string) {
-			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.JSONToBinary(args) })
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.JSONToBinary(args, flags.delimited) })
 		},
 	}
+	flags.bindDelimited(jsonToBinaryCmd.PersistentFlags())
+	flags.bindDescriptorSet(jsonToBinaryCmd.PersistentFlags())
 	flags.bindDirMode(jsonToBinaryCmd.PersistentFlags())
 
+	jsonToTextCmd := &cobra.Command{
+		Use:   "json-to-text dirOrProtoFiles... messagePath data",
+		Short: "Convert the data from json to text format for the message path and data.",
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.JSONToText(args) })
+		},
+	}
+	flags.bindDescriptorSet(jsonToTextCmd.PersistentFlags())
+	flags.bindDirMode(jsonToTextCmd.PersistentFlags())
+
 	lintCmd := &cobra.Command{
 		Use:   "lint dirOrProtoFiles...",
 		Short: "Lint proto files and compile with protoc to check for failures.",
 		Run: func(cmd *cobra.Command, args []string) {
-			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Lint(args) })
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Lint(args, flags.summary) })
 		},
 	}
+	flags.bindCompileExitCode(lintCmd.PersistentFlags())
 	flags.bindDirMode(lintCmd.PersistentFlags())
+	flags.bindFailureFormat(lintCmd.PersistentFlags())
+	flags.bindLintExitCode(lintCmd.PersistentFlags())
+	flags.bindMaxWarnings(lintCmd.PersistentFlags())
+	flags.bindNoCache(lintCmd.PersistentFlags())
+	flags.bindNotify(lintCmd.PersistentFlags())
+	flags.bindSummary(lintCmd.PersistentFlags())
+	flags.bindJobs(lintCmd.PersistentFlags())
+	flags.bindWarningsAsErrors(lintCmd.PersistentFlags())
+
+	lintExplainCmd := &cobra.Command{
+		Use:   "explain lintID",
+		Short: "Explain a lint rule with its rationale, examples, and the groups that contain it.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.LintExplain(args[0]) })
+		},
+	}
+	lintCmd.AddCommand(lintExplainCmd)
+
+	lintListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List all linters with their groups, how their failures are fixed, and the config keys that configure them. With --json, the list is printed as JSON.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, exec.Runner.LintList)
+		},
+	}
+	lintCmd.AddCommand(lintListCmd)
 
 	listAllLintersCmd := &cobra.Command{
 		Use:   "list-all-linters",
@@ -285,14 +683,145 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 	}
 
 	listLintGroupCmd := &cobra.Command{
-		Use:   "list-lint-group group",
-		Short: "List the linters in the given lint group.",
-		Args:  cobra.ExactArgs(1),
+		Use:       "list-lint-group group",
+		Short:     "List the linters in the given lint group.",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: getLintGroups(),
 		Run: func(cmd *cobra.Command, args []string) {
 			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.ListLintGroup(args[0]) })
 		},
 	}
 
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migration commands.",
+	}
+
+	migrateEditionsCmd := &cobra.Command{
+		Use:   "editions dirOrProtoFiles...",
+		Short: "Migrate proto3 files to the equivalent edition, 2023 by default, and format them.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.MigrateEditions(args, flags.edition, flags.overwrite, flags.diffMode, flags.lintMode)
+			})
+		},
+	}
+	flags.bindDiffMode(migrateEditionsCmd.PersistentFlags())
+	flags.bindEdition(migrateEditionsCmd.PersistentFlags())
+	flags.bindFailureFormat(migrateEditionsCmd.PersistentFlags())
+	flags.bindLintMode(migrateEditionsCmd.PersistentFlags())
+	flags.bindOverwrite(migrateEditionsCmd.PersistentFlags())
+	migrateCmd.AddCommand(migrateEditionsCmd)
+
+	migrateProto3Cmd := &cobra.Command{
+		Use:   "proto3 dirOrProtoFiles...",
+		Short: "Migrate proto2 files to proto3 where possible, and format them.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.MigrateProto3(args, flags.overwrite, flags.diffMode, flags.lintMode)
+			})
+		},
+	}
+	flags.bindDiffMode(migrateProto3Cmd.PersistentFlags())
+	flags.bindFailureFormat(migrateProto3Cmd.PersistentFlags())
+	flags.bindLintMode(migrateProto3Cmd.PersistentFlags())
+	flags.bindOverwrite(migrateProto3Cmd.PersistentFlags())
+	migrateCmd.AddCommand(migrateProto3Cmd)
+
+	migrateEnumsCmd := &cobra.Command{
+		Use:   "enums dirOrProtoFiles...",
+		Short: "Rename enum values to have the prefix and zero value suffix expected by lint, and format them.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.MigrateEnums(args, flags.overwrite, flags.diffMode, flags.lintMode)
+			})
+		},
+	}
+	flags.bindDiffMode(migrateEnumsCmd.PersistentFlags())
+	flags.bindFailureFormat(migrateEnumsCmd.PersistentFlags())
+	flags.bindLintMode(migrateEnumsCmd.PersistentFlags())
+	flags.bindOverwrite(migrateEnumsCmd.PersistentFlags())
+	migrateCmd.AddCommand(migrateEnumsCmd)
+
+	moduleCmd := &cobra.Command{
+		Use:   "module",
+		Short: "Module registry commands.",
+	}
+
+	moduleFetchCmd := &cobra.Command{
+		Use:   "fetch [dirPath]",
+		Short: "Fetch the module deps in the config file for the current or given directory.",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.ModuleFetch(args, flags.url) })
+		},
+	}
+	flags.bindModuleRegistryURL(moduleFetchCmd.PersistentFlags())
+	moduleCmd.AddCommand(moduleFetchCmd)
+
+	modulePushCmd := &cobra.Command{
+		Use:   "push dirOrProtoFiles...",
+		Short: "Push the compiled files as a versioned module. Be sure to set the required flag version.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.ModulePush(args, flags.url, flags.name, flags.version)
+			})
+		},
+	}
+	flags.bindDirMode(modulePushCmd.PersistentFlags())
+	flags.bindModuleName(modulePushCmd.PersistentFlags())
+	flags.bindModuleRegistryURL(modulePushCmd.PersistentFlags())
+	flags.bindModuleVersion(modulePushCmd.PersistentFlags())
+	moduleCmd.AddCommand(modulePushCmd)
+
+	registryCmd := &cobra.Command{
+		Use:   "registry",
+		Short: "Confluent Schema Registry commands.",
+	}
+
+	registryPullCmd := &cobra.Command{
+		Use:   "pull",
+		Short: "Print the schema registered under a subject. Be sure to set the required flags url and subject.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.RegistryPull(flags.url, flags.subject, flags.schemaVersion, flags.basicAuth)
+			})
+		},
+	}
+	flags.bindBasicAuth(registryPullCmd.PersistentFlags())
+	flags.bindSchemaRegistryURL(registryPullCmd.PersistentFlags())
+	flags.bindSchemaVersion(registryPullCmd.PersistentFlags())
+	flags.bindSubject(registryPullCmd.PersistentFlags())
+	registryCmd.AddCommand(registryPullCmd)
+
+	registryPushCmd := &cobra.Command{
+		Use:   "push protoFile",
+		Short: "Register a file and its imports after checking compatibility with the registered versions. Be sure to set the required flags url and subject.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.RegistryPush(args, flags.url, flags.subject, flags.basicAuth)
+			})
+		},
+	}
+	flags.bindBasicAuth(registryPushCmd.PersistentFlags())
+	flags.bindSchemaRegistryURL(registryPushCmd.PersistentFlags())
+	flags.bindSubject(registryPushCmd.PersistentFlags())
+	registryCmd.AddCommand(registryPushCmd)
+
+	serveCmd := &cobra.Command{
+		Use:   "serve dirOrProtoFiles...",
+		Short: "Serve a mock gRPC server for all services with server reflection enabled. Be sure to set the required flag address.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Serve(args, flags.address, flags.fixtures) })
+		},
+	}
+	flags.bindDescriptorSet(serveCmd.PersistentFlags())
+	flags.bindDirMode(serveCmd.PersistentFlags())
+	flags.bindFixtures(serveCmd.PersistentFlags())
+	flags.bindServeAddress(serveCmd.PersistentFlags())
+
 	serviceDescriptorProtoCmd := &cobra.Command{
 		Use:   "service-descriptor-proto dirOrProtoFiles... servicePath",
 		Short: "Get the service descriptor proto for the service path.",
@@ -303,6 +832,55 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 	}
 	flags.bindDirMode(serviceDescriptorProtoCmd.PersistentFlags())
 
+	testCmd := &cobra.Command{
+		Use:   "test dirOrProtoFiles... scenarioFile",
+		Short: "Run the gRPC calls in the scenario file with assertions on their results, capturing variables from responses.",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.Test(args, flags.headers, flags.address, flags.callTimeout, flags.connectTimeout)
+			})
+		},
+	}
+	flags.bindScenarioAddress(testCmd.PersistentFlags())
+	flags.bindCallTimeout(testCmd.PersistentFlags())
+	flags.bindConnectTimeout(testCmd.PersistentFlags())
+	flags.bindDescriptorSet(testCmd.PersistentFlags())
+	flags.bindDirMode(testCmd.PersistentFlags())
+	flags.bindHeaders(testCmd.PersistentFlags())
+
+	textToBinaryCmd := &cobra.Command{
+		Use:   "text-to-binary dirOrProtoFiles... messagePath data",
+		Short: "Convert the data from text format to binary for the message path and data.",
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.TextToBinary(args) })
+		},
+	}
+	flags.bindDescriptorSet(textToBinaryCmd.PersistentFlags())
+	flags.bindDirMode(textToBinaryCmd.PersistentFlags())
+
+	yamlToBinaryCmd := &cobra.Command{
+		Use:   "yaml-to-binary dirOrProtoFiles... messagePath data",
+		Short: "Convert the data from yaml to binary for the message path and data.",
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.YAMLToBinary(args) })
+		},
+	}
+	flags.bindDescriptorSet(yamlToBinaryCmd.PersistentFlags())
+	flags.bindDirMode(yamlToBinaryCmd.PersistentFlags())
+
+	vetCmd := &cobra.Command{
+		Use:   "vet dirOrProtoFiles...",
+		Short: "Compile with protoc and perform semantic checks that protoc does not perform.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Vet(args) })
+		},
+	}
+	flags.bindDirMode(vetCmd.PersistentFlags())
+	flags.bindFailureFormat(vetCmd.PersistentFlags())
+
 	versionCmd := &cobra.Command{
 		Use:   "version",
 		Short: "Print the version.",
@@ -312,36 +890,77 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 		},
 	}
 
-	rootCmd := &cobra.Command{Use: "prototool"}
+	rootCmd := &cobra.Command{
+		Use:                    "prototool",
+		BashCompletionFunction: bashCompletionFunction,
+		// record the command for metrics
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			flags.command = strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+		},
+	}
 	rootCmd.AddCommand(allCmd)
+	rootCmd.AddCommand(annotationsCmd)
+	rootCmd.AddCommand(bazelCmd)
 	rootCmd.AddCommand(binaryToJSONCmd)
+	rootCmd.AddCommand(binaryToTextCmd)
+	rootCmd.AddCommand(binaryToYAMLCmd)
+	rootCmd.AddCommand(breakCmd)
+	rootCmd.AddCommand(changelogCmd)
 	rootCmd.AddCommand(cleanCmd)
 	rootCmd.AddCommand(compileCmd)
+	rootCmd.AddCommand(completionCmd)
+	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(createCmd)
+	rootCmd.AddCommand(decompileCmd)
 	rootCmd.AddCommand(descriptorProtoCmd)
+	rootCmd.AddCommand(diffCmd)
 	rootCmd.AddCommand(downloadCmd)
 	rootCmd.AddCommand(fieldDescriptorProtoCmd)
 	rootCmd.AddCommand(filesCmd)
 	rootCmd.AddCommand(formatCmd)
 	rootCmd.AddCommand(genCmd)
+	rootCmd.AddCommand(generateDataCmd)
+	rootCmd.AddCommand(githookCmd)
 	rootCmd.AddCommand(grpcCmd)
+	rootCmd.AddCommand(grpcMethodsCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(jsonToBinaryCmd)
+	rootCmd.AddCommand(jsonToTextCmd)
 	rootCmd.AddCommand(lintCmd)
 	rootCmd.AddCommand(listAllLintersCmd)
 	rootCmd.AddCommand(listAllLintGroupsCmd)
 	rootCmd.AddCommand(listLintersCmd)
 	rootCmd.AddCommand(listLintGroupCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(moduleCmd)
+	rootCmd.AddCommand(registryCmd)
+	rootCmd.AddCommand(serveCmd)
 	rootCmd.AddCommand(serviceDescriptorProtoCmd)
+	rootCmd.AddCommand(testCmd)
+	rootCmd.AddCommand(textToBinaryCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(vetCmd)
+	rootCmd.AddCommand(yamlToBinaryCmd)
 
 	// flags bound to rootCmd are global flags
 	flags.bindCachePath(rootCmd.PersistentFlags())
 	flags.bindDebug(rootCmd.PersistentFlags())
 	flags.bindDryRun(rootCmd.PersistentFlags())
 	flags.bindHarbormaster(rootCmd.PersistentFlags())
+	flags.bindJSONOutput(rootCmd.PersistentFlags())
+	flags.bindLogFile(rootCmd.PersistentFlags())
+	flags.bindLogFormat(rootCmd.PersistentFlags())
+	flags.bindMetricsURL(rootCmd.PersistentFlags())
+	flags.bindOutputPreset(rootCmd.PersistentFlags())
 	flags.bindPrintFields(rootCmd.PersistentFlags())
+	flags.bindProtocBinPath(rootCmd.PersistentFlags())
 	flags.bindProtocURL(rootCmd.PersistentFlags())
+	flags.bindProtocWKTPath(rootCmd.PersistentFlags())
+	flags.bindQuiet(rootCmd.PersistentFlags())
+	flags.bindRemoteExecutionURL(rootCmd.PersistentFlags())
+	flags.bindTemplate(rootCmd.PersistentFlags())
+	flags.bindTiming(rootCmd.PersistentFlags())
+	flags.bindTraceExec(rootCmd.PersistentFlags())
 
 	rootCmd.SetArgs(args)
 	rootCmd.SetOutput(stdout)
@@ -351,32 +970,95 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 
 func checkOS() error {
 	switch runtime.GOOS {
-	case "darwin", "linux":
+	case "darwin", "linux", "windows":
 		return nil
 	default:
-		return fmt.Errorf("%s is not a supported operating system, if you want to go through the code and change all the strings.HasPrefix and \"/\" stuff to os.PathSeparator and filepath calls, you're more than welcome to", runtime.GOOS)
+		return fmt.Errorf("%s is not a supported operating system", runtime.GOOS)
 	}
 }
 
 func checkCmd(exitCodeAddr *int, stdin io.Reader, stdout io.Writer, stderr io.Writer, flags *flags, f func(exec.Runner) error) {
-	runner, err := getRunner(stdin, stdout, stderr, flags)
-	if err != nil {
-		*exitCodeAddr = printAndGetErrorExitCode(err, stdout)
+	if !flags.jsonOutput {
+		if err := runCmd(stdin, stdout, stderr, flags, nil, f); err != nil {
+			*exitCodeAddr = printAndGetErrorExitCode(err, stdout)
+		}
 		return
 	}
-	if err := f(runner); err != nil {
+	// the output of the command is wrapped in the envelope
+	envelopeBuilder := envelope.NewBuilder()
+	output := bytes.NewBuffer(nil)
+	err := runCmd(stdin, output, stderr, flags, envelopeBuilder, f)
+	*exitCodeAddr = getErrorExitCode(err)
+	if err := printEnvelope(envelopeBuilder, flags.command, *exitCodeAddr, err, output.Bytes(), stdout); err != nil {
 		*exitCodeAddr = printAndGetErrorExitCode(err, stdout)
 	}
 }
 
-func getRunner(stdin io.Reader, stdout io.Writer, stderr io.Writer, flags *flags) (exec.Runner, error) {
-	logger, err := getLogger(stderr, flags.debug)
+// runCmd runs the command, returning the error of the command.
+//
+// The envelope.Builder is nil unless --json is set.
+func runCmd(stdin io.Reader, stdout io.Writer, stderr io.Writer, flags *flags, envelopeBuilder envelope.Builder, f func(exec.Runner) error) (retErr error) {
+	var timer timing.Timer
+	if flags.timing {
+		timer = timing.NewTimer()
+	}
+	var execTracer exectrace.Tracer
+	if flags.traceExec {
+		execTracer = exectrace.NewTracer(stderr)
+	}
+	logger, closeLogger, err := getLogger(stderr, flags.debug, flags.quiet, flags.logFormat, flags.logFile)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer closeLogger()
+	var recorder metrics.Recorder
+	if flags.metricsURL != "" {
+		recorder, err = metrics.NewRecorder(flags.metricsURL, metrics.RecorderWithLogger(logger))
+		if err != nil {
+			return fmt.Errorf("invalid --metrics-url: %v", err)
+		}
 	}
+	runner, err := getRunner(stdin, stdout, stderr, logger, flags, timer, execTracer, recorder, envelopeBuilder)
+	if err != nil {
+		return err
+	}
+	retErr = f(runner)
+	if timer != nil {
+		printTiming(timer, stderr)
+	}
+	if recorder != nil {
+		// metrics that cannot be emitted do not change the result of the command
+		if err := recorder.Emit(flags.command, getErrorExitCode(retErr)); err != nil {
+			logger.Warn("failed to emit metrics", zap.Error(err))
+		}
+	}
+	return retErr
+}
+
+// printEnvelope prints the envelope of the command as JSON.
+func printEnvelope(envelopeBuilder envelope.Builder, command string, exitCode int, cmdErr error, output []byte, stdout io.Writer) error {
+	result, err := envelopeBuilder.Build(command, exitCode, cmdErr, output)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, string(data))
+	return err
+}
+
+func getRunner(stdin io.Reader, stdout io.Writer, stderr io.Writer, logger *zap.Logger, flags *flags, timer timing.Timer, execTracer exectrace.Tracer, recorder metrics.Recorder, envelopeBuilder envelope.Builder) (exec.Runner, error) {
 	runnerOptions := []exec.RunnerOption{
 		exec.RunnerWithLogger(logger),
 	}
+	if !flags.quiet {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithProgress(newProgressReporter(stderr)),
+		)
+	}
 	if flags.cachePath != "" {
 		runnerOptions = append(
 			runnerOptions,
@@ -389,24 +1071,168 @@ func getRunner(stdin io.Reader, stdout io.Writer, stderr io.Writer, flags *flags
 			exec.RunnerWithDirMode(),
 		)
 	}
+	if flags.compileExitCode < 0 || flags.compileExitCode > 255 {
+		return nil, fmt.Errorf("--compile-exit-code must be between 0 and 255: %d", flags.compileExitCode)
+	}
+	if flags.compileExitCode != 0 {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithCompileExitCode(flags.compileExitCode),
+		)
+	}
+	if flags.formatExitCode < 0 || flags.formatExitCode > 255 {
+		return nil, fmt.Errorf("--format-exit-code must be between 0 and 255: %d", flags.formatExitCode)
+	}
+	if flags.formatExitCode != 0 {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithFormatExitCode(flags.formatExitCode),
+		)
+	}
+	if flags.lintExitCode < 0 || flags.lintExitCode > 255 {
+		return nil, fmt.Errorf("--lint-exit-code must be between 0 and 255: %d", flags.lintExitCode)
+	}
+	if flags.lintExitCode != 0 {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithLintExitCode(flags.lintExitCode),
+		)
+	}
+	if flags.descriptorSet != "" {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithDescriptorSetPath(flags.descriptorSet),
+		)
+	}
+	if flags.emitDefaults || flags.origName || flags.enumsAsInts || flags.indent != 0 {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithJSONConfig(settings.JSONConfig{
+				EmitDefaults: flags.emitDefaults,
+				OrigName:     flags.origName,
+				EnumsAsInts:  flags.enumsAsInts,
+				Indent:       flags.indent,
+			}),
+		)
+	}
 	if flags.harbormaster {
 		runnerOptions = append(
 			runnerOptions,
 			exec.RunnerWithHarbormaster(),
 		)
 	}
+	if envelopeBuilder != nil {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithEnvelope(envelopeBuilder),
+		)
+	}
+	if flags.maxWarnings >= 0 {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithMaxWarnings(flags.maxWarnings),
+		)
+	}
+	if flags.noCache {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithNoLintCache(),
+		)
+	}
+	if flags.notify {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithNotify(),
+		)
+	}
+	if flags.failureFormat != "" {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithOutputFormat(flags.failureFormat),
+		)
+	}
+	if flags.outputPreset != "" {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithOutputPreset(flags.outputPreset),
+		)
+	}
 	if flags.printFields != "" {
 		runnerOptions = append(
 			runnerOptions,
 			exec.RunnerWithPrintFields(flags.printFields),
 		)
 	}
+	if flags.template != "" {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithFailureTemplate(flags.template),
+		)
+	}
 	if flags.protocURL != "" {
 		runnerOptions = append(
 			runnerOptions,
 			exec.RunnerWithProtocURL(flags.protocURL),
 		)
 	}
+	if flags.protocBinPath != "" {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithProtocBinPath(flags.protocBinPath),
+		)
+	}
+	if flags.protocWKTPath != "" {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithProtocWKTPath(flags.protocWKTPath),
+		)
+	}
+	if flags.remoteExecutionURL != "" {
+		remoteExecutionURL, err := url.Parse(flags.remoteExecutionURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --remote-execution-url: %v", err)
+		}
+		if remoteExecutionURL.Scheme != "http" && remoteExecutionURL.Scheme != "https" {
+			return nil, fmt.Errorf("--remote-execution-url must be an http or https URL: %s", flags.remoteExecutionURL)
+		}
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithRemoteExecutionURL(flags.remoteExecutionURL),
+		)
+	}
+	if flags.jobs < 0 {
+		return nil, fmt.Errorf("--jobs must be positive: %d", flags.jobs)
+	}
+	if flags.jobs > 0 {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithJobs(flags.jobs),
+		)
+	}
+	if flags.warningsAsErrors {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithWarningsAsErrors(),
+		)
+	}
+	if timer != nil {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithTiming(timer),
+		)
+	}
+	if execTracer != nil {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithExecTracer(execTracer),
+		)
+	}
+	if recorder != nil {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithMetrics(recorder),
+		)
+	}
 	workDirPath, err := os.Getwd()
 	if err != nil {
 		return nil, err
@@ -414,26 +1240,89 @@ func getRunner(stdin io.Reader, stdout io.Writer, stderr io.Writer, flags *flags
 	return exec.NewRunner(workDirPath, stdin, stdout, runnerOptions...), nil
 }
 
-func getLogger(stderr io.Writer, debug bool) (*zap.Logger, error) {
+// getLogger returns a logger that writes to stderr, or appends to logFile
+// if set, along with a function to sync and close the logger.
+//
+// The json logFormat always includes debug logs, so that CI runs
+// can be debugged after the fact. If quiet is set, only errors are
+// written to stderr.
+func getLogger(stderr io.Writer, debug bool, quiet bool, logFormat string, logFile string) (*zap.Logger, func(), error) {
+	if debug && quiet {
+		return nil, nil, errors.New("--debug and --quiet cannot be used together")
+	}
 	level := zapcore.InfoLevel
 	if debug {
 		level = zapcore.DebugLevel
 	}
-	return zap.New(
+	var encoder zapcore.Encoder
+	switch logFormat {
+	case "", "text":
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	case "json":
+		encoder = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+		level = zapcore.DebugLevel
+	default:
+		return nil, nil, fmt.Errorf("unknown log format %q, must be text or json", logFormat)
+	}
+	if quiet && logFile == "" {
+		level = zapcore.ErrorLevel
+	}
+	writeSyncer := zapcore.Lock(zapcore.AddSync(stderr))
+	closeFile := func() {}
+	if logFile != "" {
+		file, err := os.OpenFile(logFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, nil, err
+		}
+		writeSyncer = zapcore.Lock(file)
+		closeFile = func() { _ = file.Close() }
+	}
+	logger := zap.New(
 		zapcore.NewCore(
-			zapcore.NewConsoleEncoder(
-				zap.NewDevelopmentEncoderConfig(),
-			),
-			zapcore.Lock(zapcore.AddSync(stderr)),
+			encoder,
+			writeSyncer,
 			zap.NewAtomicLevelAt(level),
 		),
-	), nil
+	)
+	return logger, func() {
+		_ = logger.Sync()
+		closeFile()
+	}, nil
+}
+
+// newProgressReporter returns a Reporter that writes to stderr, drawing the
+// progress in place if stderr is a terminal.
+func newProgressReporter(stderr io.Writer) progress.Reporter {
+	if file, ok := stderr.(*os.File); ok && terminal.IsTerminal(int(file.Fd())) {
+		return progress.NewReporter(stderr, progress.ReporterWithTerminal())
+	}
+	return progress.NewReporter(stderr)
+}
+
+// printTiming prints each phase with its wall time and the number of times
+// it ran, followed by the total wall time.
+func printTiming(timer timing.Timer, stderr io.Writer) {
+	tabWriter := tabwriter.NewWriter(stderr, 0, 0, 2, ' ', 0)
+	for _, phase := range timer.Phases() {
+		_, _ = fmt.Fprintf(tabWriter, "%s\t%v\t%d\n", phase.Name, phase.Duration, phase.Count)
+	}
+	_, _ = fmt.Fprintf(tabWriter, "total\t%v\t\n", timer.Total())
+	_ = tabWriter.Flush()
 }
 
 func printAndGetErrorExitCode(err error, stdout io.Writer) int {
 	if errString := err.Error(); errString != "" {
 		_, _ = fmt.Fprintln(stdout, errString)
 	}
+	return getErrorExitCode(err)
+}
+
+// getErrorExitCode returns the exit code for the error, which is 0 if
+// the error is nil.
+func getErrorExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
 	if exitError, ok := err.(*exec.ExitError); ok {
 		return exitError.Code
 	}
@@ -21,8 +21,11 @@
 package cmd
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -33,15 +36,26 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/uber/prototool/internal/cmd/testdata/grpc/gen/grpcpb"
+	"github.com/uber/prototool/internal/envelope"
 	"github.com/uber/prototool/internal/lint"
 	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
 	"github.com/uber/prototool/internal/vars"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -104,6 +118,25 @@ func TestCompile(t *testing.T) {
 	)
 }
 
+func TestCompileRoots(t *testing.T) {
+	t.Parallel()
+	assertDo(t, 0, "", "compile", "testdata/roots")
+}
+
+func TestCompileParserOnly(t *testing.T) {
+	t.Parallel()
+	assertDo(t, 255, `testdata/compile/not_imported.proto:11:3:"Dep" is not defined.`, "compile", "--parser-only", "testdata/compile/not_imported.proto")
+	assertDo(t, 0, "", "compile", "--parser-only", "testdata/compile/dep.proto", "testdata/compile/errors_on_import.proto")
+	assertDo(t, 255, "cannot use --dry-run with --parser-only as protoc is not run", "compile", "--parser-only", "--dry-run", "testdata/compile/dep.proto")
+}
+
+func TestCompileProtocVersions(t *testing.T) {
+	t.Parallel()
+	assertDo(t, 255, `testdata/compile/dep_errors.proto:6:1:Expected ";".`, "compile", "--protoc-versions", "3.5.1", "testdata/compile/dep_errors.proto")
+	assertDo(t, 0, "", "compile", "--protoc-versions", "3.5.1,3.5.1", "testdata/compile/dep.proto")
+	assertDo(t, 255, "cannot use --protoc-versions with --dry-run or --parser-only", "compile", "--protoc-versions", "3.5.1", "--parser-only", "testdata/compile/dep.proto")
+}
+
 func TestInit(t *testing.T) {
 	t.Parallel()
 
@@ -118,6 +151,54 @@ func TestInit(t *testing.T) {
 	assertDo(t, 1, fmt.Sprintf("%s already exists", filepath.Join(tmpDir, settings.DefaultConfigFilename)), "init", tmpDir)
 }
 
+func TestInitFromProtoc(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	require.NotEmpty(t, tmpDir)
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+
+	assertDo(t, 0, "", "init", tmpDir, "--from-protoc", "protoc -I proto -I vendor --go_out=paths=source_relative:gen/go --include_imports proto/foo/foo.proto")
+	config, err := settings.NewConfigProvider().GetForDir(tmpDir)
+	require.NoError(t, err)
+	require.Len(t, config.Compile.Roots, 1)
+	assert.Equal(t, filepath.Join(tmpDir, "proto"), config.Compile.Roots[0].DirPath)
+	require.Len(t, config.Gen.Plugins, 1)
+	assert.Equal(t, "go", config.Gen.Plugins[0].Name)
+	assert.Equal(t, "paths=source_relative", config.Gen.Plugins[0].Flags)
+	assertDo(t, 255, "no protoc command found", "init", filepath.Join(tmpDir, "other"), "--from-protoc", "make all")
+}
+
+func TestConfigExport(t *testing.T) {
+	t.Parallel()
+	assertDo(t, 255, `unknown config export format "foo"`, "config", "export", "--format", "foo", "testdata/foo")
+	assertDo(t, 255, "no lint IDs in use have an equivalent buf lint rule", "config", "export", "--dry-run", "testdata/lint/owners")
+}
+
+func TestAnnotationsList(t *testing.T) {
+	t.Parallel()
+	assertExact(
+		t,
+		0,
+		`testdata/annotations/foo.proto:10:20: field foo.v1.User.email (foo.v1.sensitive) = true
+testdata/annotations/foo.proto:13:22: field foo.v1.User.phone (sensitive) = true
+testdata/annotations/foo.proto:16:23: field foo.v1.User.Address.street (v1.sensitive) = true`,
+		"annotations", "list", "--option", "foo.v1.sensitive", "testdata/annotations",
+	)
+	assertExact(
+		t,
+		0,
+		`filename,line,column,element_type,element,option,value
+testdata/annotations/foo.proto,9,3,message,foo.v1.User,(visibility),"""internal"""
+testdata/annotations/foo.proto,22,18,enum_value,foo.v1.Role.ROLE_ADMIN,(visibility),"""internal"""`,
+		"annotations", "list", "--option", "visibility", "--output-format", "csv", "testdata/annotations/foo.proto",
+	)
+	assertDo(t, 255, `output-format must be text, json, or csv but was "xml"`, "annotations", "list", "--output-format", "xml", "testdata/annotations")
+}
+
 func TestLint(t *testing.T) {
 	t.Parallel()
 	assertDoLintFile(
@@ -241,6 +322,7 @@ func TestLint(t *testing.T) {
 		1:1:FILE_OPTIONS_REQUIRE_JAVA_MULTIPLE_FILES
 		1:1:FILE_OPTIONS_REQUIRE_JAVA_OUTER_CLASSNAME
 		1:1:FILE_OPTIONS_REQUIRE_JAVA_PACKAGE
+		3:1:PACKAGE_HAS_VERSION_SUFFIX
 		3:1:PACKAGE_LOWER_SNAKE_CASE
 		7:1:MESSAGES_HAVE_COMMENTS
 		7:1:MESSAGES_HAVE_COMMENTS_EXCEPT_REQUEST_RESPONSE_TYPES
@@ -260,8 +342,10 @@ func TestLint(t *testing.T) {
 		23:3:COMMENTS_NO_C_STYLE
 		26:1:SERVICES_HAVE_COMMENTS
 		26:1:SERVICE_NAMES_CAPITALIZED
+		26:1:SERVICE_NAMES_HAVE_SUFFIX
 		28:1:SERVICES_HAVE_COMMENTS
 		28:1:SERVICE_NAMES_CAMEL_CASE
+		28:1:SERVICE_NAMES_HAVE_SUFFIX
 		30:1:MESSAGES_HAVE_COMMENTS
 		31:1:MESSAGES_HAVE_COMMENTS
 		34:1:MESSAGES_HAVE_COMMENTS
@@ -272,32 +356,48 @@ func TestLint(t *testing.T) {
 		40:1:MESSAGES_HAVE_COMMENTS_EXCEPT_REQUEST_RESPONSE_TYPES
 		41:3:MESSAGES_HAVE_COMMENTS
 		44:1:SERVICES_HAVE_COMMENTS
+		44:1:SERVICE_NAMES_HAVE_SUFFIX
 		45:3:RPCS_HAVE_COMMENTS
+		45:3:RPC_NAMES_HAVE_PREFIX
+		46:3:AIP_REQUEST_RESPONSE_NAMES
+		46:3:AIP_REQUEST_RESPONSE_NAMES
 		46:3:REQUEST_RESPONSE_NAMES_MATCH_RPC
 		46:3:REQUEST_RESPONSE_NAMES_MATCH_RPC
 		46:3:REQUEST_RESPONSE_TYPES_UNIQUE
 		46:3:REQUEST_RESPONSE_TYPES_UNIQUE
 		46:3:RPCS_HAVE_COMMENTS
+		46:3:RPC_NAMES_HAVE_PREFIX
+		47:3:AIP_REQUEST_RESPONSE_NAMES
+		47:3:AIP_REQUEST_RESPONSE_NAMES
 		47:3:REQUEST_RESPONSE_NAMES_MATCH_RPC
 		47:3:REQUEST_RESPONSE_NAMES_MATCH_RPC
 		47:3:REQUEST_RESPONSE_TYPES_UNIQUE
 		47:3:REQUEST_RESPONSE_TYPES_UNIQUE
 		47:3:RPCS_HAVE_COMMENTS
+		47:3:RPC_NAMES_HAVE_PREFIX
+		48:3:AIP_REQUEST_RESPONSE_NAMES
+		48:3:AIP_REQUEST_RESPONSE_NAMES
 		48:3:REQUEST_RESPONSE_NAMES_MATCH_RPC
 		48:3:REQUEST_RESPONSE_NAMES_MATCH_RPC
 		48:3:RPCS_HAVE_COMMENTS
 		48:3:RPC_NAMES_CAPITALIZED
+		48:3:RPC_NAMES_HAVE_PREFIX
+		49:3:AIP_REQUEST_RESPONSE_NAMES
+		49:3:AIP_REQUEST_RESPONSE_NAMES
 		49:3:REQUEST_RESPONSE_NAMES_MATCH_RPC
 		49:3:REQUEST_RESPONSE_NAMES_MATCH_RPC
 		49:3:REQUEST_RESPONSE_TYPES_IN_SAME_FILE
 		49:3:REQUEST_RESPONSE_TYPES_UNIQUE
 		49:3:RPCS_HAVE_COMMENTS
+		49:3:RPC_NAMES_HAVE_PREFIX
+		50:3:AIP_REQUEST_RESPONSE_NAMES
 		50:3:REQUEST_RESPONSE_NAMES_MATCH_RPC
 		50:3:REQUEST_RESPONSE_NAMES_MATCH_RPC
 		50:3:REQUEST_RESPONSE_TYPES_IN_SAME_FILE
 		50:3:REQUEST_RESPONSE_TYPES_IN_SAME_FILE
 		50:3:REQUEST_RESPONSE_TYPES_UNIQUE
 		50:3:RPCS_HAVE_COMMENTS
+		50:3:RPC_NAMES_HAVE_PREFIX
 		53:1:ENUMS_HAVE_COMMENTS
 		58:3:ENUM_FIELD_PREFIXES
 		61:1:MESSAGES_HAVE_COMMENTS
@@ -324,6 +424,126 @@ func TestLint(t *testing.T) {
 		93:1:ENUM_NAMES_CAMEL_CASE`,
 		"testdata/lint/allgroup/lots.proto",
 	)
+	assertDoLintFile(
+		t,
+		false,
+		`23:1:AIP_RESOURCES_ANNOTATED
+		23:1:AIP_RESOURCES_ANNOTATED
+		81:3:AIP_PAGINATION_FIELDS
+		81:3:AIP_PAGINATION_FIELDS
+		83:3:AIP_STANDARD_METHODS_VALID
+		86:3:AIP_STANDARD_METHODS_VALID
+		86:3:AIP_STANDARD_METHODS_VALID
+		87:3:AIP_REQUEST_RESPONSE_NAMES
+		88:3:AIP_PAGINATION_FIELDS`,
+		"testdata/lint/aip/library.proto",
+	)
+	assertDoLintFile(
+		t,
+		false,
+		`17:5:ENUM_ZERO_VALUES_INVALID`,
+		"testdata/lint/enums/foo.proto",
+	)
+	assertDoLintFile(
+		t,
+		false,
+		`24:3:REQUEST_RESPONSE_NAMES_MATCH_RPC
+		27:1:SERVICE_NAMES_HAVE_SUFFIX
+		28:3:RPC_NAMES_HAVE_PREFIX`,
+		"testdata/lint/naming/foo.proto",
+	)
+	assertDoLintFile(
+		t,
+		false,
+		`7:3:FIELD_NUMBERS_LOW_FOR_HOT_FIELDS
+		7:3:FIELD_NUMBERS_SEQUENTIAL
+		8:3:FIELD_NUMBERS_LOW_FOR_HOT_FIELDS
+		11:1:MESSAGE_FIELDS_MAX_COUNT
+		16:3:FIELD_NUMBERS_SEQUENTIAL
+		21:3:FIELD_NUMBERS_NOT_IN_RESERVED_RANGE
+		21:3:FIELD_NUMBERS_SEQUENTIAL
+		24:1:MESSAGE_FIELDS_MAX_COUNT
+		33:5:FIELD_NUMBERS_SEQUENTIAL`,
+		"testdata/lint/fields/foo.proto",
+	)
+	assertDoLintFile(
+		t,
+		false,
+		`11:3:CUSTOM_OPTIONS_NUMBERS_IN_RANGE
+		12:3:CUSTOM_OPTIONS_HAVE_COMMENTS
+		23:5:CUSTOM_OPTIONS_NUMBERS_IN_RANGE`,
+		"testdata/lint/options/foo.proto",
+	)
+	assertDoLintFile(
+		t,
+		false,
+		`7:3:SENSITIVE_FIELDS_ANNOTATED
+		11:5:SENSITIVE_FIELDS_ANNOTATED
+		13:3:LOG_EXEMPT_TYPES_NOT_IN_LOG_PACKAGES
+		14:3:LOG_EXEMPT_TYPES_NOT_IN_LOG_PACKAGES
+		18:3:LOG_EXEMPT_TYPES_NOT_IN_LOG_PACKAGES
+		18:3:LOG_EXEMPT_TYPES_NOT_IN_LOG_PACKAGES`,
+		"testdata/lint/sensitive/foo.proto",
+	)
+	assertDoLintFile(
+		t,
+		true,
+		"",
+		"testdata/lint/versions/foo/v1/ok.proto",
+	)
+	assertDoLintFile(
+		t,
+		false,
+		`3:1:PACKAGE_HAS_VERSION_SUFFIX`,
+		"testdata/lint/versions/foo/v1/wrong_dir.proto",
+	)
+	assertDoLintFile(
+		t,
+		false,
+		`3:1:PACKAGE_HAS_VERSION_SUFFIX`,
+		"testdata/lint/versions/foo/v1/beta.proto",
+	)
+	assertDoLintFile(
+		t,
+		false,
+		`3:1:PACKAGE_HAS_VERSION_SUFFIX`,
+		"testdata/lint/versions/foo/unversioned.proto",
+	)
+	assertDoLintFile(
+		t,
+		false,
+		`1:1:FILE_OPTIONS_REQUIRED_FOR_LANGUAGES`,
+		"testdata/lint/languages/foo/v1/foo.proto",
+	)
+	assertDoLintFile(
+		t,
+		true,
+		"",
+		"testdata/lint/languages/foo/v1/bar.proto",
+	)
+	assertDoLintFile(
+		t,
+		false,
+		`6:1:IMPORTS_AND_TYPES_NOT_FORBIDDEN
+		9:3:IMPORTS_AND_TYPES_NOT_FORBIDDEN
+		10:3:IMPORTS_AND_TYPES_NOT_FORBIDDEN
+		11:3:IMPORTS_AND_TYPES_NOT_FORBIDDEN
+		13:5:IMPORTS_AND_TYPES_NOT_FORBIDDEN
+		18:3:IMPORTS_AND_TYPES_NOT_FORBIDDEN`,
+		"testdata/lint/forbidden/foo/bad.proto",
+	)
+	assertDoLintFile(
+		t,
+		true,
+		"",
+		"testdata/lint/forbidden/allowed/ok.proto",
+	)
+	assertDoLintFile(
+		t,
+		true,
+		"",
+		"testdata/lint/forbidden/foo/internal/internal.proto",
+	)
 	assertDoLintFile(
 		t,
 		false,
@@ -333,6 +553,340 @@ func TestLint(t *testing.T) {
 		1:1:FILE_OPTIONS_REQUIRE_JAVA_PACKAGE`,
 		"testdata/lint/package_starts_with_keyword.proto",
 	)
+	assertDoLintFile(
+		t,
+		false,
+		`22:3:MESSAGE_FIELDS_NO_OPTIONAL_MESSAGES
+		23:3:MESSAGE_FIELDS_NO_OPTIONAL_MESSAGES`,
+		"testdata/lint/optional/message_fields_optional_messages.proto",
+	)
+	assertDoLintFile(
+		t,
+		false,
+		`6:1:FILE_HEADER_CANONICAL_ORDER
+		9:1:FILE_HEADER_CANONICAL_ORDER`,
+		"testdata/lint/canonical/file_header_canonical_order.proto",
+	)
+}
+
+func TestLintSeverity(t *testing.T) {
+	t.Parallel()
+	assertDo(
+		t,
+		0,
+		"testdata/lint/severity/syntax_proto2.proto:1:1:SYNTAX_PROTO3:warning: Syntax should be proto3",
+		"lint", "testdata/lint/severity/syntax_proto2.proto",
+	)
+	assertDo(
+		t,
+		255,
+		`testdata/lint/severity/syntax_proto2.proto:1:1:SYNTAX_PROTO3:warning: Syntax should be proto3
+		1 lint warnings exceeded the maximum of 0`,
+		"lint", "--max-warnings", "0", "testdata/lint/severity/syntax_proto2.proto",
+	)
+}
+
+func TestLintSummary(t *testing.T) {
+	t.Parallel()
+	assertExact(
+		t,
+		255,
+		`Total failures:  1
+
+LINTER         FAILURES
+SYNTAX_PROTO3  1
+
+DIRECTORY      FAILURES
+testdata/lint  1
+
+FILE                               FAILURES
+testdata/lint/syntax_proto2.proto  1`,
+		"lint", "--summary", "testdata/lint/syntax_proto2.proto",
+	)
+}
+
+func TestLintTemplate(t *testing.T) {
+	t.Parallel()
+	assertExact(
+		t,
+		0,
+		`SYNTAX_PROTO3 warning testdata/lint/severity/syntax_proto2.proto:1`,
+		"lint", "--template", "{{.ID}} {{.Severity}} {{.Filename}}:{{.Line}}", "testdata/lint/severity/syntax_proto2.proto",
+	)
+	assertDo(
+		t,
+		255,
+		`--template can only be used with the default output format`,
+		"lint", "--template", "{{.ID}}", "--output-format", "checkstyle", "testdata/lint/severity/syntax_proto2.proto",
+	)
+}
+
+func TestLintOutputPreset(t *testing.T) {
+	t.Parallel()
+	assertExact(
+		t,
+		0,
+		`testdata/lint/severity/syntax_proto2.proto:1.1: warning: Syntax should be proto3 but was "proto2". [SYNTAX_PROTO3]`,
+		"lint", "--output-preset", "emacs", "testdata/lint/severity/syntax_proto2.proto",
+	)
+	assertExact(
+		t,
+		1,
+		`testdata/lint/owners/payments/syntax_proto2.proto:1:1: error: Syntax should be proto3 but was "proto2". [SYNTAX_PROTO3]`,
+		"lint", "--output-preset", "vim", "testdata/lint/owners/payments/syntax_proto2.proto",
+	)
+	assertDo(
+		t,
+		255,
+		`--output-preset can only be used with the default output format`,
+		"lint", "--output-preset", "vim", "--harbormaster", "testdata/lint/severity/syntax_proto2.proto",
+	)
+}
+
+func TestLintExitCode(t *testing.T) {
+	t.Parallel()
+	assertExact(
+		t,
+		3,
+		`testdata/lint/owners/payments/syntax_proto2.proto:1:1:SYNTAX_PROTO3:Syntax should be proto3 but was "proto2".`,
+		"lint", "--lint-exit-code", "3", "testdata/lint/owners/payments/syntax_proto2.proto",
+	)
+	assertDo(
+		t,
+		1,
+		`--lint-exit-code must be between 0 and 255: 256`,
+		"lint", "--lint-exit-code", "256", "testdata/lint/owners/payments/syntax_proto2.proto",
+	)
+}
+
+func TestGenOutputArchive(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "prototool-gen-archive")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	archiveFilePath := filepath.Join(tmpDirPath, "out.tar.gz")
+
+	output, exitCode := testDo(t, "gen", "--output-archive", archiveFilePath, "testdata/gen-archive")
+	require.Equal(t, 0, exitCode, output)
+	archiveData, err := ioutil.ReadFile(archiveFilePath)
+	require.NoError(t, err)
+	assertGenArchive(t, archiveData)
+	// nothing is written to the working tree
+	_, err = os.Stat("testdata/gen-archive/doc")
+	assert.True(t, os.IsNotExist(err))
+
+	buffer := bytes.NewBuffer(nil)
+	exitCode = Do([]string{"gen", "--output-archive", "-", "testdata/gen-archive"}, os.Stdin, buffer, os.Stderr)
+	require.Equal(t, 0, exitCode)
+	assertGenArchive(t, buffer.Bytes())
+	_, err = os.Stat("testdata/gen-archive/doc")
+	assert.True(t, os.IsNotExist(err))
+
+	assertDo(t, 255, "cannot use --output-archive with --dry-run or --print-plan", "gen", "--output-archive", archiveFilePath, "--dry-run", "testdata/gen-archive")
+}
+
+func assertGenArchive(t *testing.T, archiveData []byte) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archiveData))
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	pathToData := make(map[string]string)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(tarReader)
+		require.NoError(t, err)
+		pathToData[header.Name] = string(data)
+	}
+	require.Len(t, pathToData, 1)
+	require.Contains(t, pathToData, "doc/api.md")
+	assert.Contains(t, pathToData["doc/api.md"], "## foo/foo.proto")
+	assert.Contains(t, pathToData["doc/api.md"], "| id | string |  | 1 | The ID of the foo. |")
+}
+
+func TestAllSkipsCompileWhenFormatted(t *testing.T) {
+	t.Parallel()
+	dirPath, logFilePath, cleanup := newTestAllDir(t, "exit 0\n")
+	defer cleanup()
+	protoFilePath := filepath.Join(dirPath, "foo.proto")
+	formatted := `syntax = "proto3";
+
+package foo;
+
+message Foo {
+  string id = 1;
+}
+`
+	require.NoError(t, ioutil.WriteFile(protoFilePath, []byte(formatted), 0644))
+	output, exitCode := testDo(t, "all", "--no-rewrite", dirPath)
+	assert.Equal(t, 0, exitCode, output)
+	// only the compile that generates runs
+	assert.Equal(t, 1, getTestProtocInvocations(t, logFilePath))
+
+	require.NoError(t, ioutil.WriteFile(logFilePath, nil, 0644))
+	require.NoError(t, ioutil.WriteFile(protoFilePath, []byte(strings.Replace(formatted, "  string", "    string", 1)), 0644))
+	output, exitCode = testDo(t, "all", "--no-rewrite", dirPath)
+	assert.Equal(t, 0, exitCode, output)
+	// the files are compiled before they are overwritten
+	assert.Equal(t, 2, getTestProtocInvocations(t, logFilePath))
+	data, err := ioutil.ReadFile(protoFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, formatted, string(data))
+}
+
+func TestAllLintAndCompileFailures(t *testing.T) {
+	t.Parallel()
+	dirPath, _, cleanup := newTestAllDir(t, `>&2 echo 'foo.proto:7:3: "Bar" is not defined.'
+exit 1
+`)
+	defer cleanup()
+	protoFilePath := filepath.Join(dirPath, "foo.proto")
+	require.NoError(t, ioutil.WriteFile(protoFilePath, []byte(`syntax = "proto3";
+
+package foo;
+
+message foo_bar {
+  string id = 1;
+  Bar bar = 2;
+}
+`), 0644))
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	displayPath, err := filepath.Rel(cwd, protoFilePath)
+	require.NoError(t, err)
+	expectedOutput := displayPath + `:7:3:"Bar" is not defined.
+` + displayPath + `:5:1:MESSAGE_NAMES_CAMEL_CASE:Message name "foo_bar" must be CamelCase.`
+	assertExact(t, 255, expectedOutput, "all", "--no-rewrite", dirPath)
+	assertExact(t, 3, expectedOutput, "all", "--no-rewrite", "--compile-exit-code", "3", "--lint-exit-code", "4", dirPath)
+}
+
+// newTestAllDir creates a temporary directory with a prototool.yaml that uses
+// a fake protoc that logs each invocation and then runs the given script.
+//
+// Returns the directory path, the path of the log file, and a cleanup function.
+func newTestAllDir(t *testing.T, protocScript string) (string, string, func()) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool-all")
+	require.NoError(t, err)
+	dirPath := filepath.Join(tmpDirPath, "proto")
+	binDirPath := filepath.Join(tmpDirPath, "protoc", "bin")
+	wktDirPath := filepath.Join(tmpDirPath, "protoc", "include")
+	for _, iDirPath := range []string{dirPath, binDirPath, filepath.Join(wktDirPath, "google", "protobuf")} {
+		require.NoError(t, os.MkdirAll(iDirPath, 0755))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(wktDirPath, "google", "protobuf", "descriptor.proto"), []byte(`syntax = "proto2";`), 0644))
+	logFilePath := filepath.Join(tmpDirPath, "log")
+	protocBinPath := filepath.Join(binDirPath, "protoc")
+	require.NoError(t, ioutil.WriteFile(protocBinPath, []byte(`#!/bin/sh
+if [ "$1" = "--version" ]; then
+  echo "libprotoc 3.11.0"
+  exit 0
+fi
+echo "$@" >> `+logFilePath+`
+`+protocScript), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "prototool.yaml"), []byte(`protoc:
+  bin_path: `+protocBinPath+`
+  wkt_path: `+wktDirPath+`
+lint:
+  ids:
+    - MESSAGE_NAMES_CAMEL_CASE
+`), 0644))
+	return dirPath, logFilePath, func() { _ = os.RemoveAll(tmpDirPath) }
+}
+
+func getTestProtocInvocations(t *testing.T, logFilePath string) int {
+	data, err := ioutil.ReadFile(logFilePath)
+	require.NoError(t, err)
+	return len(getCleanLines(string(data)))
+}
+
+func TestJobsNegative(t *testing.T) {
+	t.Parallel()
+	for _, command := range []string{"all", "compile", "gen", "lint"} {
+		assertDo(t, 1, `--jobs must be positive: -1`, command, "--jobs", "-1", "testdata/lint/owners/payments/syntax_proto2.proto")
+	}
+}
+
+func TestLintOwners(t *testing.T) {
+	t.Parallel()
+	output, exitCode := testDo(t, "lint", "--json", "testdata/lint/owners")
+	assert.Equal(t, 255, exitCode)
+	result := &envelope.Envelope{}
+	require.NoError(t, json.Unmarshal([]byte(output), result))
+	assert.Equal(t, "lint", result.Command)
+	assert.Equal(t, 255, result.ExitCode)
+	assert.Empty(t, result.Error)
+	assert.Equal(
+		t,
+		[]*text.JSONFailure{
+			{
+				Filename: "testdata/lint/owners/payments/syntax_proto2.proto",
+				Line:     1,
+				Column:   1,
+				ID:       "SYNTAX_PROTO3",
+				Message:  `Syntax should be proto3 but was "proto2".`,
+				Severity: "error",
+				Owner:    "payments",
+			},
+			{
+				Filename: "testdata/lint/owners/syntax_proto2.proto",
+				Line:     1,
+				Column:   1,
+				ID:       "SYNTAX_PROTO3",
+				Message:  `Syntax should be proto3 but was "proto2".`,
+				Severity: "error",
+				Owner:    "api-platform",
+			},
+		},
+		result.Failures,
+	)
+}
+
+func TestJSONEnvelope(t *testing.T) {
+	t.Parallel()
+	output, exitCode := testDo(t, "files", "--json", "testdata/lint/owners")
+	assert.Equal(t, 0, exitCode)
+	result := &envelope.Envelope{}
+	require.NoError(t, json.Unmarshal([]byte(output), result))
+	assert.Equal(t, "files", result.Command)
+	assert.Empty(t, result.Failures)
+	assert.Equal(t, `"testdata/lint/owners/syntax_proto2.proto\ntestdata/lint/owners/payments/syntax_proto2.proto"`, string(result.Data))
+
+	output, exitCode = testDo(t, "grpc", "--json", "testdata/grpc")
+	assert.Equal(t, 255, exitCode)
+	result = &envelope.Envelope{}
+	require.NoError(t, json.Unmarshal([]byte(output), result))
+	assert.Equal(t, "grpc", result.Command)
+	assert.Equal(t, "must set address or target", result.Error)
+	assert.Nil(t, result.Data)
+}
+
+func TestLintList(t *testing.T) {
+	t.Parallel()
+	output, exitCode := testDo(t, "lint", "list", "--json")
+	assert.Equal(t, 0, exitCode)
+	result := &envelope.Envelope{}
+	require.NoError(t, json.Unmarshal([]byte(output), result))
+	assert.Equal(t, "lint list", result.Command)
+	var metadatas []*lint.Metadata
+	require.NoError(t, json.Unmarshal(result.Data, &metadatas))
+	assert.Equal(t, lint.AllMetadata(), metadatas)
+}
+
+func TestLintCheckstyle(t *testing.T) {
+	t.Parallel()
+	assertExact(
+		t,
+		255,
+		`<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="8.0">
+  <file name="testdata/lint/syntax_proto2.proto">
+    <error line="1" column="1" severity="error" message="Syntax should be proto3 but was &#34;proto2&#34;." source="SYNTAX_PROTO3"></error>
+  </file>
+</checkstyle>`,
+		"lint", "--output-format", "checkstyle", "testdata/lint/syntax_proto2.proto",
+	)
 }
 
 func TestGoldenFormat(t *testing.T) {
@@ -342,6 +896,51 @@ func TestGoldenFormat(t *testing.T) {
 	assertGoldenFormat(t, false, false, "testdata/format/foo/foo.proto")
 	assertGoldenFormat(t, false, false, "testdata/format/foo/foo_proto2.proto")
 	assertGoldenFormat(t, false, true, "testdata/format-rewrite/foo.proto")
+	assertGoldenFormat(t, false, false, "testdata/format-style/foo.proto")
+	assertGoldenFormat(t, false, true, "testdata/format-templates/foo_bar.proto")
+	assertGoldenFormat(t, false, true, "testdata/format-languages/foo_bar.proto")
+}
+
+func TestFormatDiffStat(t *testing.T) {
+	t.Parallel()
+	assertDo(
+		t,
+		255,
+		`DIRECTORY            FILES  INSERTIONS  DELETIONS
+testdata/format/bar  2      10          6
+testdata/format/foo  2      138         93
+total                4      148         99`,
+		"format", "-d", "--stat", "--no-rewrite", "testdata/format",
+	)
+	assertDo(t, 255, "can only set stat with diff", "format", "--stat", "testdata/format")
+}
+
+func TestMigrateEditions(t *testing.T) {
+	t.Parallel()
+	output, exitCode := testDo(t, "migrate", "editions", "testdata/migrate/foo.proto")
+	// a diff results in a non-zero exit code, as with format
+	assert.Equal(t, 255, exitCode)
+	golden, err := ioutil.ReadFile("testdata/migrate/foo.proto.golden")
+	assert.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(string(golden)), output)
+}
+
+func TestMigrateProto3(t *testing.T) {
+	t.Parallel()
+	output, exitCode := testDo(t, "migrate", "proto3", "testdata/migrate/proto2.proto")
+	assert.Equal(t, 255, exitCode)
+	golden, err := ioutil.ReadFile("testdata/migrate/proto2.proto.golden")
+	assert.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(string(golden)), output)
+}
+
+func TestMigrateEnums(t *testing.T) {
+	t.Parallel()
+	output, exitCode := testDo(t, "migrate", "enums", "testdata/migrate-enums/foo.proto")
+	assert.Equal(t, 255, exitCode)
+	golden, err := ioutil.ReadFile("testdata/migrate-enums/foo.proto.golden")
+	assert.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(string(golden)), output)
 }
 
 func TestJSONToBinaryToJSON(t *testing.T) {
@@ -349,6 +948,86 @@ func TestJSONToBinaryToJSON(t *testing.T) {
 	assertJSONToBinaryToJSON(t, "testdata/foo/success.proto", "foo.Baz", `{"hello":100}`)
 }
 
+func TestJSONToBinaryToJSONDelimited(t *testing.T) {
+	t.Parallel()
+	binaryData, exitCode := testDoStdin(t, strings.NewReader("{\"hello\":100}\n{\"hello\":200}\n"), "json-to-binary", "--delimited", "testdata/foo/success.proto", "foo.Baz", "-")
+	assert.Equal(t, 0, exitCode)
+	stdout, exitCode := testDoStdin(t, strings.NewReader(binaryData), "binary-to-json", "--delimited", "testdata/foo/success.proto", "foo.Baz", "-")
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "{\"hello\":100}\n{\"hello\":200}", stdout)
+	// each message stays on one line even if an indent is given
+	stdout, exitCode = testDoStdin(t, strings.NewReader(binaryData), "binary-to-json", "--delimited", "--indent", "2", "testdata/foo/success.proto", "foo.Baz", "-")
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "{\"hello\":100}\n{\"hello\":200}", stdout)
+}
+
+func TestDescriptorSet(t *testing.T) {
+	t.Parallel()
+	descriptorSetPath, cleanup := newTestDescriptorSet(t, "testdata/descriptor-set", "foo/foo.proto")
+	defer cleanup()
+	jsonData := `{"hello":100,"color":"COLOR_RED","names":["a","b"],"bar":{"barValue":"baz"}}`
+
+	binaryData, exitCode := testDo(t, "json-to-binary", "--descriptor-set", descriptorSetPath, "foo.Foo", jsonData)
+	assert.Equal(t, 0, exitCode)
+	assertExact(t, 0, jsonData, "binary-to-json", "--descriptor-set", descriptorSetPath, "foo.Foo", binaryData)
+
+	textData, exitCode := testDo(t, "binary-to-text", "--descriptor-set", descriptorSetPath, "foo.Foo", binaryData)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "hello: 100\ncolor: COLOR_RED\nnames: \"a\"\nnames: \"b\"\nbar: <\n  bar_value: \"baz\"\n>", textData)
+	assertExact(t, 0, binaryData, "text-to-binary", "--descriptor-set", descriptorSetPath, "foo.Foo", textData)
+	assertExact(t, 0, textData, "json-to-text", "--descriptor-set", descriptorSetPath, "foo.Foo", jsonData)
+
+	yamlData, exitCode := testDo(t, "binary-to-yaml", "--descriptor-set", descriptorSetPath, "foo.Foo", binaryData)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "bar:\n  barValue: baz\ncolor: COLOR_RED\nhello: 100\nnames:\n- a\n- b", yamlData)
+	assertExact(t, 0, binaryData, "yaml-to-binary", "--descriptor-set", descriptorSetPath, "foo.Foo", yamlData)
+
+	assertDo(t, 255, "cannot specify both files and descriptor-set", "binary-to-json", "--descriptor-set", descriptorSetPath, "testdata/foo/success.proto", "foo.Foo", binaryData)
+	assertRegexp(t, 1, "no message", "json-to-binary", "--descriptor-set", descriptorSetPath, "foo.Baz", jsonData)
+	stdout, exitCode := testDo(t, "json-to-binary", "--descriptor-set", "testdata/descriptor-set/foo/foo.proto", "foo.Foo", jsonData)
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, stdout, "could not parse FileDescriptorSet from testdata/descriptor-set/foo/foo.proto")
+}
+
+func TestJSONMarshalerFlags(t *testing.T) {
+	t.Parallel()
+	descriptorSetPath, cleanup := newTestDescriptorSet(t, "testdata/descriptor-set", "foo/foo.proto")
+	defer cleanup()
+	binaryData, exitCode := testDo(t, "json-to-binary", "--descriptor-set", descriptorSetPath, "foo.Foo", `{"color":"COLOR_RED","names":["a"],"bar":{"barValue":"baz"}}`)
+	assert.Equal(t, 0, exitCode)
+	assertExact(
+		t,
+		0,
+		`{
+  "hello": 0,
+  "color": 1,
+  "names": [
+    "a"
+  ],
+  "bar": {
+    "bar_value": "baz"
+  },
+  "any": null
+}`,
+		"binary-to-json", "--descriptor-set", descriptorSetPath, "--emit-defaults", "--orig-name", "--enums-as-ints", "--indent", "2", "foo.Foo", binaryData,
+	)
+	assertExact(t, 0, "bar:\n  bar_value: baz\ncolor: 1\nnames:\n- a", "binary-to-yaml", "--descriptor-set", descriptorSetPath, "--orig-name", "--enums-as-ints", "foo.Foo", binaryData)
+	// a negative indent turns off indentation
+	assertExact(t, 0, `{"color":"COLOR_RED","names":["a"],"bar":{"barValue":"baz"}}`, "binary-to-json", "--descriptor-set", descriptorSetPath, "--indent", "-1", "foo.Foo", binaryData)
+}
+
+func TestGenerateData(t *testing.T) {
+	t.Parallel()
+	stdout, exitCode := testDo(t, "generate-data", "testdata/foo/success.proto", "foo.Baz", "--count", "3", "--seed", "1")
+	assert.Equal(t, 0, exitCode)
+	lines := getCleanLines(stdout)
+	assert.Len(t, lines, 3)
+	for _, line := range lines {
+		_, exitCode := testDo(t, "json-to-binary", "testdata/foo/success.proto", "foo.Baz", line)
+		assert.Equal(t, 0, exitCode)
+	}
+}
+
 func TestCreate(t *testing.T) {
 	t.Parallel()
 	// package override with also matching shorter override "a"
@@ -459,6 +1138,169 @@ option java_package = "com.foo";`,
 	)
 }
 
+func TestCreateLayouts(t *testing.T) {
+	t.Parallel()
+	assertDoCreateFile(
+		t,
+		true,
+		true,
+		"testdata/create/layouts/idl/payments/ledger/v2/ledger.proto",
+		"",
+		`syntax = "proto3";
+
+package payments.ledger.v2;
+
+option go_package = "github.com/example/gen/go/payments/ledger/v2;ledgerpb";
+option java_multiple_files = true;
+option java_outer_classname = "LedgerProto";
+option java_package = "com.payments.ledger.v2";
+option csharp_namespace = "Example.ledger.V2";`,
+	)
+	// does not match the layout, use dir_to_base_package
+	assertDoCreateFile(
+		t,
+		true,
+		true,
+		"testdata/create/layouts/idl/payments/ledger/baz.proto",
+		"",
+		`syntax = "proto3";
+
+package fallback.payments.ledger;
+
+option go_package = "ledgerpb";
+option java_multiple_files = true;
+option java_outer_classname = "BazProto";
+option java_package = "com.fallback.payments.ledger";`,
+	)
+}
+
+func TestCreateHeader(t *testing.T) {
+	t.Parallel()
+	dirPath := "testdata/create/header/idl/payments"
+	assert.NoError(t, os.MkdirAll(dirPath, 0755))
+	defer func() { _ = os.RemoveAll(filepath.Dir(dirPath)) }()
+	filePath := filepath.Join(dirPath, "ledger.proto")
+	// the header uses <ticket>
+	_, exitCode := testDo(t, "create", filePath)
+	assert.NotEqual(t, 0, exitCode)
+	_, exitCode = testDo(t, "create", filePath, "--ticket", "PAY-123")
+	assert.Equal(t, 0, exitCode)
+	fileData, err := ioutil.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, `// Owned by payments.
+// Created on `+time.Now().Format("2006-01-02")+` for PAY-123.
+//
+// Package payments.v1 in ledger.proto.
+
+syntax = "proto3";
+
+package payments.v1;
+
+option go_package = "v1pb";
+option java_multiple_files = true;
+option java_outer_classname = "LedgerProto";
+option java_package = "com.payments.v1";`, string(fileData))
+}
+
+func TestCreateLanguages(t *testing.T) {
+	t.Parallel()
+	dirPath := "testdata/create/languages/foo/v1"
+	assert.NoError(t, os.MkdirAll(dirPath, 0755))
+	defer func() { _ = os.RemoveAll(filepath.Dir(dirPath)) }()
+	_, exitCode := testDo(t, "create", filepath.Join(dirPath, "bar.proto"))
+	assert.Equal(t, 0, exitCode)
+	fileData, err := ioutil.ReadFile(filepath.Join(dirPath, "bar.proto"))
+	assert.NoError(t, err)
+	assert.Equal(t, `syntax = "proto3";
+
+package foo.v1;
+
+option csharp_namespace = "Foo.V1";
+option objc_class_prefix = "FV";`, string(fileData))
+}
+
+func TestCreateVersion(t *testing.T) {
+	t.Parallel()
+	dirPath := "testdata/create/one/a/b/qux"
+	assert.NoError(t, os.MkdirAll(dirPath, 0755))
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	_, exitCode := testDo(t, "create", filepath.Join(dirPath, "baz.proto"), "--version", "v2")
+	assert.Equal(t, 0, exitCode)
+	fileData, err := ioutil.ReadFile(filepath.Join(dirPath, "v2", "baz.proto"))
+	assert.NoError(t, err)
+	assert.Equal(t, `syntax = "proto3";
+
+package foo.qux.v2;
+
+option go_package = "v2pb";
+option java_multiple_files = true;
+option java_outer_classname = "BazProto";
+option java_package = "com.foo.qux.v2";`, string(fileData))
+	// the package does not end with the version
+	_, exitCode = testDo(t, "create", filepath.Join(dirPath, "bar.proto"), "--version", "v2", "--package", "foo.qux.v1")
+	assert.NotEqual(t, 0, exitCode)
+	// not a version
+	_, exitCode = testDo(t, "create", filepath.Join(dirPath, "bar.proto"), "--version", "2")
+	assert.NotEqual(t, 0, exitCode)
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	output, exitCode := testDo(t, "diff", "testdata/diff/previous", "testdata/diff/current")
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, `+ message foo.Bar
+~ field foo.Foo.one renumbered from 1 to 3
++ field foo.Foo.three
+- field foo.Foo.two
+~ rpc foo.FooService.Get type changed from (Foo) returns (Foo) to (stream Foo) returns (Foo)`, output)
+	_, exitCode = testDo(t, "diff", "testdata/diff/previous", "testdata/diff/current", "--json")
+	assert.Equal(t, 0, exitCode)
+}
+
+func TestCreateVersionDir(t *testing.T) {
+	t.Parallel()
+	defer func() { _ = os.RemoveAll("testdata/create/version/foo/v2") }()
+	_, exitCode := testDo(t, "create", "version", "testdata/create/version/foo/v1", "v2")
+	assert.Equal(t, 0, exitCode)
+	fileData, err := ioutil.ReadFile("testdata/create/version/foo/v2/foo.proto")
+	assert.NoError(t, err)
+	assert.Equal(t, `syntax = "proto3";
+
+// TODO: This file was copied from v1 by prototool create version. Make any
+// incompatible changes for v2, such as removing deprecated elements, and
+// then remove the TODO comments.
+package acme.foo.v2;
+
+import "foo/v2/bar.proto";
+import "google/protobuf/timestamp.proto";
+
+option csharp_namespace = "Acme.Foo.V2";
+option go_package = "github.com/acme/gen/go/foo/v2;foov2";
+option java_multiple_files = true;
+option java_outer_classname = "FooProto";
+option java_package = "com.acme.foo.v2";
+
+// Foo is a foo.
+message Foo {
+  string id = 1;
+  acme.foo.v2.Bar bar = 2;
+  .acme.foo.v2.Bar other_bar = 3;
+  // TODO: This is deprecated, consider removing it in v2.
+  int64 legacy_id = 4 [deprecated = true];
+  google.protobuf.Timestamp create_time = 5;
+}
+`, string(fileData))
+	fileData, err = ioutil.ReadFile("testdata/create/version/foo/v2/bar.proto")
+	assert.NoError(t, err)
+	assert.Contains(t, string(fileData), `option go_package = "v2pb";`)
+	// the new version directory already exists
+	_, exitCode = testDo(t, "create", "version", "testdata/create/version/foo/v1", "v2")
+	assert.NotEqual(t, 0, exitCode)
+	// not a version directory
+	_, exitCode = testDo(t, "create", "version", "testdata/create/version/foo", "v3")
+	assert.NotEqual(t, 0, exitCode)
+}
+
 func TestGRPC(t *testing.T) {
 	t.Parallel()
 	assertGRPC(t,
@@ -525,14 +1367,408 @@ func TestGRPC(t *testing.T) {
 		`{"value":"hello"}
 		{"value":"salutations"}`,
 	)
+	assertGRPC(t,
+		0,
+		`
+		[
+			{
+				"value": "hello!"
+			},
+			{
+				"value": "salutations!"
+			}
+		]
+		`,
+		"testdata/grpc/grpc.proto",
+		"grpc.ExcitedService/Exclamation",
+		`[{"value":"hello"}, {"value":"salutations"}]`,
+	)
+}
+
+func TestGRPCRetry(t *testing.T) {
+	t.Parallel()
+	excitedTestCase := startExcitedTestCase(t)
+	defer excitedTestCase.Close()
+	atomic.StoreInt32(&excitedTestCase.excitedServer.unavailableCount, 2)
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		0,
+		`{
+  "value": "hello!"
+}`,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--address", excitedTestCase.Address(),
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--max-attempts", "3",
+		"--retry-backoff", "1ms",
+		"--stdin",
+	)
+	atomic.StoreInt32(&excitedTestCase.excitedServer.unavailableCount, 2)
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		1,
+		`rpc error: code = Unavailable desc = unavailable`,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--address", excitedTestCase.Address(),
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--max-attempts", "2",
+		"--retry-backoff", "1ms",
+		"--stdin",
+	)
+	atomic.StoreInt32(&excitedTestCase.excitedServer.unavailableCount, 0)
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		1,
+		`rpc error: code = ResourceExhausted`,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--address", excitedTestCase.Address(),
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--max-recv-msg-size", "1",
+		"--wait-for-ready",
+		"--stdin",
+	)
+}
+
+func TestGRPCHeaders(t *testing.T) {
+	t.Parallel()
+	require.NoError(t, os.Setenv("PROTOTOOL_TEST_GRPC_HEADER_EXCLAMATION_SUFFIX", "?"))
+	defer func() { _ = os.Unsetenv("PROTOTOOL_TEST_GRPC_HEADER_EXCLAMATION_SUFFIX") }()
+	excitedTestCase := startExcitedTestCase(t)
+	defer excitedTestCase.Close()
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		0,
+		`{
+  "value": "hello!?"
+}`,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--address", excitedTestCase.Address(),
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--header-env", "PROTOTOOL_TEST_GRPC_HEADER_",
+		"--stdin",
+	)
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		0,
+		`{
+  "value": "hello!??"
+}`,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--address", excitedTestCase.Address(),
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--header-env", "PROTOTOOL_TEST_GRPC_HEADER_",
+		"--header", "@testdata/grpc/headers.yaml",
+		"--stdin",
+	)
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		1,
+		`headers must be key:value but got exclamation-suffix`,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--address", excitedTestCase.Address(),
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--header", "exclamation-suffix",
+		"--stdin",
+	)
+}
+
+func TestGRPCTarget(t *testing.T) {
+	t.Parallel()
+	excitedTestCase := startExcitedTestCase(t)
+	defer excitedTestCase.Close()
+	require.NoError(t, os.Setenv("PROTOTOOL_TEST_GRPC_TARGET_ADDRESS", excitedTestCase.Address()))
+	defer func() { _ = os.Unsetenv("PROTOTOOL_TEST_GRPC_TARGET_ADDRESS") }()
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		0,
+		`{
+  "value": "hello!?"
+}`,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--target", "excited",
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--stdin",
+	)
+	// headers on the command line take precedence
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		0,
+		`{
+  "value": "hello!??"
+}`,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--target", "excited",
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--header", "@testdata/grpc/headers.yaml",
+		"--stdin",
+	)
+	// the test server does not use TLS
+	_, exitCode := testDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		"grpc", "testdata/grpc/grpc.proto",
+		"--target", "excited-tls",
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--connect-timeout", "1s",
+		"--stdin",
+	)
+	assert.NotEqual(t, 0, exitCode)
+	assertDo(t, 255, `unknown target "missing", targets must be configured in grpc.targets`, "grpc", "testdata/grpc/grpc.proto", "--target", "missing", "--method", "grpc.ExcitedService/Exclamation")
+	assertDo(t, 255, "must set only one of address or target", "grpc", "testdata/grpc/grpc.proto", "--address", excitedTestCase.Address(), "--target", "excited", "--method", "grpc.ExcitedService/Exclamation")
+}
+
+func TestGRPCExpect(t *testing.T) {
+	t.Parallel()
+	excitedTestCase := startExcitedTestCase(t)
+	defer excitedTestCase.Close()
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		0,
+		`{
+  "value": "hello!"
+}`,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--address", excitedTestCase.Address(),
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--expect-json", "testdata/grpc/expect.json",
+		"--expect-field", "$.value=hello!",
+		"--stdin",
+	)
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		1,
+		`{
+  "value": "hello!?"
+}
+response 1: expected {"value":"hello!"} but got {"value":"hello!?"}
+response 1: expected value to be "hello!" but got "hello!?"`,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--address", excitedTestCase.Address(),
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--header", "exclamation-suffix:?",
+		"--expect-json", "testdata/grpc/expect.json",
+		"--expect-field", `value="hello!"`,
+		"--stdin",
+	)
+	atomic.StoreInt32(&excitedTestCase.excitedServer.unavailableCount, 1)
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		0,
+		``,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--address", excitedTestCase.Address(),
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--expect-code", "UNAVAILABLE",
+		"--stdin",
+	)
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		1,
+		`{
+  "value": "hello!"
+}
+expected code Unavailable but got OK`,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--address", excitedTestCase.Address(),
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--expect-code", "unavailable",
+		"--stdin",
+	)
+	assertDo(t, 255, `unknown expect-code "FOO"`, "grpc", "testdata/grpc/grpc.proto", "--address", excitedTestCase.Address(), "--method", "grpc.ExcitedService/Exclamation", "--data", "{}", "--expect-code", "FOO")
+}
+
+func TestScenario(t *testing.T) {
+	t.Parallel()
+	excitedTestCase := startExcitedTestCase(t)
+	defer excitedTestCase.Close()
+	assertDo(
+		t,
+		255,
+		`PASS setup: exclaim
+		PASS steps: exclaim again
+		PASS steps: client stream
+		FAIL steps: wrong
+		response 1: expected value to be "hi?" but got "hi!"
+		PASS teardown: 1 grpc.ExcitedService/Exclamation
+		4 passed, 1 failed, 0 skipped`,
+		"test", "testdata/grpc/grpc.proto", "testdata/grpc/scenario.yaml",
+		"--address", excitedTestCase.Address(),
+	)
+	assertDo(t, 255, "testdata/grpc/scenario.yaml: address must be set in the scenario file or as a flag", "test", "testdata/grpc/grpc.proto", "testdata/grpc/scenario.yaml")
+}
+
+func TestGRPCRecord(t *testing.T) {
+	t.Parallel()
+	excitedTestCase := startExcitedTestCase(t)
+	defer excitedTestCase.Close()
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+	recordFilePath := filepath.Join(tmpDir, "recording.yaml")
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		0,
+		`{
+  "value": "hello!"
+}`,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--address", excitedTestCase.Address(),
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--record", recordFilePath,
+		"--stdin",
+	)
+	atomic.StoreInt32(&excitedTestCase.excitedServer.unavailableCount, 1)
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		1,
+		`rpc error: code = Unavailable desc = unavailable`,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--address", excitedTestCase.Address(),
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--record", recordFilePath,
+		"--stdin",
+	)
+	assertDo(
+		t,
+		255,
+		`PASS steps: 1 grpc.ExcitedService/Exclamation
+		FAIL steps: 2 grpc.ExcitedService/Exclamation
+		expected code Unavailable but got OK
+		1 passed, 1 failed, 0 skipped`,
+		"test", "testdata/grpc/grpc.proto", recordFilePath,
+		"--address", excitedTestCase.Address(),
+	)
+}
+
+func TestGRPCOutput(t *testing.T) {
+	t.Parallel()
+	excitedTestCase := startExcitedTestCase(t)
+	defer excitedTestCase.Close()
+	assertDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		0,
+		`value: "hello!"`,
+		"grpc", "testdata/grpc/grpc.proto",
+		"--address", excitedTestCase.Address(),
+		"--method", "grpc.ExcitedService/Exclamation",
+		"--output-format", "text",
+		"--stdin",
+	)
+
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	for _, testCase := range []struct {
+		method   string
+		expected []byte
+	}{
+		{
+			method:   "grpc.ExcitedService/Exclamation",
+			expected: []byte("\n\x03hi!"),
+		},
+		{
+			method:   "grpc.ExcitedService/ExclamationServerStream",
+			expected: []byte("\x03\n\x01h\x03\n\x01i\x03\n\x01!"),
+		},
+	} {
+		outputFilePath := filepath.Join(tmpDir, "output.bin")
+		_, exitCode := testDoStdin(
+			t,
+			strings.NewReader(`{"value":"hi"}`),
+			"grpc", "testdata/grpc/grpc.proto",
+			"--address", excitedTestCase.Address(),
+			"--method", testCase.method,
+			"--output", outputFilePath,
+			"--output-format", "binary",
+			"--stdin",
+		)
+		require.Equal(t, 0, exitCode)
+		data, err := ioutil.ReadFile(outputFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, data)
+	}
+}
+
+func TestGetLoggerJSON(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	logger, closeLogger, err := getLogger(buffer, false, false, "json", "")
+	require.NoError(t, err)
+	logger.Debug("ran protoc", zap.String("command", "protoc foo.proto"))
+	closeLogger()
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &entry))
+	assert.Equal(t, "debug", entry["level"])
+	assert.Equal(t, "ran protoc", entry["msg"])
+	assert.Equal(t, "protoc foo.proto", entry["command"])
+
+	_, _, err = getLogger(buffer, false, false, "xml", "")
+	assert.Error(t, err)
+}
+
+func TestGetLoggerQuiet(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	logger, closeLogger, err := getLogger(buffer, false, true, "text", "")
+	require.NoError(t, err)
+	logger.Warn("deprecated setting")
+	logger.Error("failed")
+	closeLogger()
+	assert.NotContains(t, buffer.String(), "deprecated setting")
+	assert.Contains(t, buffer.String(), "failed")
+
+	_, _, err = getLogger(buffer, true, true, "text", "")
+	assert.Error(t, err)
 }
 
 func TestVersion(t *testing.T) {
 	assertRegexp(t, 0, fmt.Sprintf("Version:.*%s\nDefault protoc version:.*%s\n", vars.Version, vars.DefaultProtocVersion), "version")
 }
 
+func TestGRPCMethods(t *testing.T) {
+	assertExact(
+		t,
+		0,
+		`grpc.ExcitedService/Exclamation
+grpc.ExcitedService/ExclamationBidiStream
+grpc.ExcitedService/ExclamationClientStream
+grpc.ExcitedService/ExclamationServerStream
+`,
+		"grpc-methods", "testdata/grpc",
+	)
+}
+
+func TestGRPCList(t *testing.T) {
+	assertExact(
+		t,
+		0,
+		`grpc.ExcitedService/Exclamation(grpc.ExclamationRequest) returns (grpc.ExclamationResponse)
+grpc.ExcitedService/ExclamationBidiStream(stream grpc.ExclamationRequest) returns (stream grpc.ExclamationResponse)
+grpc.ExcitedService/ExclamationClientStream(stream grpc.ExclamationRequest) returns (grpc.ExclamationResponse)
+grpc.ExcitedService/ExclamationServerStream(grpc.ExclamationRequest) returns (stream grpc.ExclamationResponse)
+`,
+		"grpc", "testdata/grpc", "--list",
+	)
+	assertDo(t, 255, "must not set method, data, stdin, or interactive with list", "grpc", "testdata/grpc", "--list", "--method", "grpc.ExcitedService/Exclamation")
+}
+
 func TestListAllLintGroups(t *testing.T) {
-	assertExact(t, 0, "all\ndefault", "list-all-lint-groups")
+	assertExact(t, 0, "aip\nall\ndefault\ngateway\noptions\nvalidate", "list-all-lint-groups")
 }
 
 func TestDescriptorProto(t *testing.T) {
@@ -728,6 +1964,35 @@ func assertJSONToBinaryToJSON(t *testing.T, filePath string, messagePath string,
 	assert.Equal(t, jsonData, stdout)
 }
 
+// newTestDescriptorSet writes a FileDescriptorSet with the given files
+// and their imports to a temporary file and returns the path to it.
+func newTestDescriptorSet(t *testing.T, dirPath string, filePaths ...string) (string, func()) {
+	parser := protoparse.Parser{
+		ImportPaths: []string{dirPath},
+	}
+	fileDescriptors, err := parser.ParseFiles(filePaths...)
+	require.NoError(t, err)
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	seen := make(map[string]struct{})
+	for _, fileDescriptor := range fileDescriptors {
+		for _, dependency := range fileDescriptor.GetDependencies() {
+			if _, ok := seen[dependency.GetName()]; !ok {
+				seen[dependency.GetName()] = struct{}{}
+				fileDescriptorSet.File = append(fileDescriptorSet.File, dependency.AsFileDescriptorProto())
+			}
+		}
+		seen[fileDescriptor.GetName()] = struct{}{}
+		fileDescriptorSet.File = append(fileDescriptorSet.File, fileDescriptor.AsFileDescriptorProto())
+	}
+	data, err := proto.Marshal(fileDescriptorSet)
+	require.NoError(t, err)
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	filePath := filepath.Join(tmpDir, "descriptor_set.bin")
+	require.NoError(t, ioutil.WriteFile(filePath, data, 0644))
+	return filePath, func() { _ = os.RemoveAll(tmpDir) }
+}
+
 func assertGRPC(t *testing.T, expectedExitCode int, expectedLinePrefixes string, filePath string, method string, jsonData string) {
 	excitedTestCase := startExcitedTestCase(t)
 	defer excitedTestCase.Close()
@@ -811,15 +2076,25 @@ func (c *excitedTestCase) Close() {
 	}
 }
 
-type excitedServer struct{}
+type excitedServer struct {
+	// the number of remaining Exclamation calls that fail with UNAVAILABLE
+	unavailableCount int32
+}
 
 func newExcitedServer() *excitedServer {
 	return &excitedServer{}
 }
 
 func (s *excitedServer) Exclamation(ctx context.Context, request *grpcpb.ExclamationRequest) (*grpcpb.ExclamationResponse, error) {
+	if atomic.AddInt32(&s.unavailableCount, -1) >= 0 {
+		return nil, status.Error(codes.Unavailable, "unavailable")
+	}
+	suffix := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md["exclamation-suffix"]) > 0 {
+		suffix = md["exclamation-suffix"][0]
+	}
 	return &grpcpb.ExclamationResponse{
-		Value: request.Value + "!",
+		Value: request.Value + "!" + suffix,
 	}, nil
 }
 
@@ -890,6 +2165,10 @@ func testDoInternal(stdin io.Reader, args ...string) (string, int) {
 	args = append(args,
 		"--print-fields", "filename:line:column:id:message",
 	)
+	// do not write lint results to the cache of the user running the tests
+	if len(args) > 0 && (args[0] == "lint" || args[0] == "all") {
+		args = append(args, "--no-cache")
+	}
 	if stdin == nil {
 		stdin = os.Stdin
 	}
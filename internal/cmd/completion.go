@@ -0,0 +1,152 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/uber/prototool/internal/lint"
+)
+
+// grpcMethodsCompletionFunc is the name of the bash function that
+// completes gRPC methods from the files in the current directory.
+const grpcMethodsCompletionFunc = "__prototool_grpc_methods"
+
+const bashCompletionFunction = `__prototool_grpc_methods()
+{
+    local methods
+    if methods=$(prototool grpc-methods 2>/dev/null); then
+        COMPREPLY=( $(compgen -W "${methods}" -- "${cur}") )
+    fi
+}
+`
+
+// fishCompletionCommands maps the bash completion functions to the
+// equivalent commands for fish.
+var fishCompletionCommands = map[string]string{
+	grpcMethodsCompletionFunc: "prototool grpc-methods 2>/dev/null",
+}
+
+func genCompletion(rootCmd *cobra.Command, shell string, writer io.Writer) error {
+	switch shell {
+	case "bash":
+		return rootCmd.GenBashCompletion(writer)
+	case "zsh":
+		return rootCmd.GenZshCompletion(writer)
+	case "fish":
+		return genFishCompletion(rootCmd, writer)
+	default:
+		return fmt.Errorf("unknown shell %q, must be one of bash, zsh, or fish", shell)
+	}
+}
+
+// genFishCompletion generates a fish completion file to the writer.
+//
+// The cobra version we use does not support fish, so we do this ourselves.
+func genFishCompletion(rootCmd *cobra.Command, writer io.Writer) error {
+	bufWriter := bufio.NewWriter(writer)
+	name := rootCmd.Name()
+	_, _ = fmt.Fprintf(bufWriter, "# fish completion for %s\n\n", name)
+	_, _ = fmt.Fprintf(bufWriter, "complete -c %s -f\n", name)
+	rootCmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		writeFishFlag(bufWriter, name, "", flag)
+	})
+	writeFishCommands(bufWriter, name, nil, rootCmd)
+	return bufWriter.Flush()
+}
+
+func writeFishCommands(writer io.Writer, name string, parents []string, cmd *cobra.Command) {
+	var subCmdNames []string
+	for _, subCmd := range cmd.Commands() {
+		if subCmd.IsAvailableCommand() {
+			subCmdNames = append(subCmdNames, subCmd.Name())
+		}
+	}
+	for _, subCmd := range cmd.Commands() {
+		if !subCmd.IsAvailableCommand() {
+			continue
+		}
+		condition := "__fish_use_subcommand"
+		if len(parents) > 0 {
+			condition = fmt.Sprintf(
+				"__fish_seen_subcommand_from %s; and not __fish_seen_subcommand_from %s",
+				parents[len(parents)-1],
+				strings.Join(subCmdNames, " "),
+			)
+		}
+		_, _ = fmt.Fprintf(writer, "complete -c %s -n '%s' -a %s -d %s\n", name, condition, subCmd.Name(), fishQuote(subCmd.Short))
+		subCmdCondition := "__fish_seen_subcommand_from " + subCmd.Name()
+		if len(subCmd.ValidArgs) > 0 {
+			_, _ = fmt.Fprintf(writer, "complete -c %s -n '%s' -a %s\n", name, subCmdCondition, fishQuote(strings.Join(subCmd.ValidArgs, " ")))
+		}
+		subCmd.NonInheritedFlags().VisitAll(func(flag *pflag.Flag) {
+			writeFishFlag(writer, name, subCmdCondition, flag)
+		})
+		writeFishCommands(writer, name, append(parents, subCmd.Name()), subCmd)
+	}
+}
+
+func writeFishFlag(writer io.Writer, name string, condition string, flag *pflag.Flag) {
+	if flag.Hidden {
+		return
+	}
+	line := "complete -c " + name
+	if condition != "" {
+		line += " -n '" + condition + "'"
+	}
+	line += " -l " + flag.Name
+	if flag.Shorthand != "" {
+		line += " -s " + flag.Shorthand
+	}
+	if flag.Value.Type() != "bool" {
+		// the flag requires a value
+		line += " -r"
+		for _, function := range flag.Annotations[cobra.BashCompCustom] {
+			if command, ok := fishCompletionCommands[function]; ok {
+				line += " -a " + fishQuote("("+command+")")
+			}
+		}
+		if _, ok := flag.Annotations[cobra.BashCompFilenameExt]; ok {
+			line += " -F"
+		}
+	}
+	_, _ = fmt.Fprintln(writer, line+" -d "+fishQuote(flag.Usage))
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.Replace(strings.Replace(s, `\`, `\\`, -1), "'", `\'`, -1) + "'"
+}
+
+// getLintGroups returns the sorted lint groups.
+func getLintGroups() []string {
+	groups := make([]string, 0, len(lint.GroupToLinters))
+	for group := range lint.GroupToLinters {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups
+}
@@ -21,37 +21,146 @@
 package cmd
 
 import (
+	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
 type flags struct {
-	address        string
-	cachePath      string
-	callTimeout    string
-	connectTimeout string
-	data           string
-	debug          bool
-	diffMode       bool
-	dirMode        bool
-	disableFormat  bool
-	disableLint    bool
-	dryRun         bool
-	harbormaster   bool
-	headers        []string
-	keepaliveTime  string
-	lintMode       bool
-	method         string
-	overwrite      bool
-	pkg            string
-	printFields    string
-	protocURL      string
-	stdin          bool
-	uncomment      bool
-	noRewrite      bool
+	address            string
+	annotationsFormat  string
+	authority          string
+	authToken          string
+	authTokenFile      string
+	basicAuth          string
+	cachePath          string
+	callTimeout        string
+	cancelAfter        string
+	cancelAfterBytes   int
+	checkRoundTrip     bool
+	compileExitCode    int
+	compress           string
+	connectTimeout     string
+	count              int
+	data               string
+	deadline           string
+	debug              bool
+	diffMode           bool
+	dirMode            bool
+	disableFormat      bool
+	disableLint        bool
+	delimited          bool
+	descriptorSet      string
+	dryRun             bool
+	edition            string
+	emitDefaults       bool
+	enumsAsInts        bool
+	expectCode         string
+	expectFields       []string
+	expectJSON         string
+	explain            bool
+	exportFormat       string
+	failureFormat      string
+	fields             []string
+	fixtures           string
+	formatExitCode     int
+	gitRef             string
+	framework          string
+	fromBuf            string
+	fromMakefile       string
+	fromProtoc         string
+	harbormaster       bool
+	headers            []string
+	headerEnvPrefix    string
+	hookType           string
+	indent             int
+	interactive        bool
+	jobs               int
+	jsonOutput         bool
+	keepaliveTime      string
+	lintExitCode       int
+	lintMode           bool
+	list               bool
+	listen             string
+	logFile            string
+	logFormat          string
+	maxAttempts        int
+	maxRecvMsgSize     int
+	maxSendMsgSize     int
+	maxWarnings        int
+	metricsURL         string
+	method             string
+	name               string
+	optionNames        []string
+	origName           bool
+	output             string
+	outputFormat       string
+	outputPreset       string
+	overwrite          bool
+	packageVersion     string
+	parserOnly         bool
+	pkg                string
+	printFields        string
+	printPlan          bool
+	printMetadata      bool
+	protocBinPath      string
+	protocURL          string
+	protocVersions     []string
+	protocWKTPath      string
+	quiet              bool
+	record             string
+	remoteExecutionURL string
+	retryBackoff       string
+	retryCodes         []string
+	schemaVersion      string
+	seed               int64
+	since              string
+	stat               bool
+	stdin              bool
+	subject            string
+	summary            bool
+	target             string
+	template           string
+	ticket             string
+	timing             bool
+	traceExec          bool
+	uncomment          bool
+	url                string
+	userAgent          string
+	version            string
+	waitForReady       bool
+	warningsAsErrors   bool
+	noCache            bool
+	outDirPath         string
+	outputArchive      string
+	noRewrite          bool
+	notify             bool
+
+	// the command path without the binary name, set before the command runs
+	command string
 }
 
 func (f *flags) bindAddress(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(&f.address, "address", "", "The GRPC endpoint to connect to. This is required.")
+	flagSet.StringVar(&f.address, "address", "", "The GRPC endpoint to connect to. Either this or target is required.")
+}
+
+func (f *flags) bindAnnotationsOutputFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.annotationsFormat, "output-format", "text", "The output format, either text, json, or csv.")
+}
+
+func (f *flags) bindAuthority(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.authority, "authority", "", "The value to use for the :authority pseudo-header, otherwise uses the address.")
+}
+
+func (f *flags) bindAuthToken(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.authToken, "auth-token", "", "A token to attach to each call as the header 'authorization: Bearer token', or a reference to one such as env://NAME, file://path, or cmd://command.")
+}
+
+func (f *flags) bindAuthTokenFile(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.authTokenFile, "auth-token-file", "", "A file containing a token to attach to each call as the header 'authorization: Bearer token'.")
+}
+
+func (f *flags) bindBasicAuth(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.basicAuth, "basic-auth", "", "The username:password to use for HTTP basic authentication, or a reference to it such as env://NAME, file://path, or cmd://command.")
 }
 
 func (f *flags) bindCachePath(flagSet *pflag.FlagSet) {
@@ -62,22 +171,62 @@ func (f *flags) bindCallTimeout(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.callTimeout, "call-timeout", "60s", "The maximum time to for all calls to be completed.")
 }
 
+func (f *flags) bindCheckRoundTrip(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.checkRoundTrip, "check-roundtrip", false, "Fail if formatting a file is not idempotent, or if the formatted file does not parse to the same descriptor as the original file. This is always done by all.")
+}
+
+func (f *flags) bindCompileExitCode(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.compileExitCode, "compile-exit-code", 0, "The exit code if there are compile failures. The default is 255, or 1 with --output-preset.")
+}
+
+func (f *flags) bindCancelAfter(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.cancelAfter, "cancel-after", "", "Cancel the call after this duration, to test server-side cancellation handling.")
+}
+
+func (f *flags) bindCancelAfterBytes(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.cancelAfterBytes, "cancel-after-bytes", 0, "Cancel the call once at least this many bytes of responses are received, to test server-side cancellation handling.")
+}
+
+func (f *flags) bindDeadline(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.deadline, "deadline", "", "The deadline of the call sent to the server in the grpc-timeout header. Takes precedence over call-timeout for the call.")
+}
+
+func (f *flags) bindCompress(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.compress, "compress", "", "The compression to use for requests. The only supported value is gzip. Gzip-compressed responses are always accepted.")
+}
+
 func (f *flags) bindConnectTimeout(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.connectTimeout, "connect-timeout", "10s", "The maximum time to wait for the connection to be established.")
 }
 
+func (f *flags) bindCount(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.count, "count", 1, "The number of messages to generate.")
+}
+
 func (f *flags) bindData(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(&f.data, "data", "", "The GRPC request data in JSON format. Either this or --stdin is required.")
+	flagSet.StringVar(&f.data, "data", "", "The GRPC request data in JSON format. For unary methods, this can be a JSON array of requests, which calls the method once per request. Either this or --stdin is required.")
 }
 
 func (f *flags) bindDebug(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.debug, "debug", false, "Run in debug mode, which will print out debug logging.")
 }
 
+func (f *flags) bindEdition(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.edition, "edition", "", "The Protobuf edition to use, for example 2023.")
+}
+
 func (f *flags) bindDiffMode(flagSet *pflag.FlagSet) {
 	flagSet.BoolVarP(&f.diffMode, "diff", "d", false, "Write a diff instead of writing the formatted file to stdout.")
 }
 
+func (f *flags) bindDelimited(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.delimited, "delimited", false, "Convert a stream of messages. Binary messages are each prefixed with their length as a varint, and JSON messages are a stream of JSON objects, usually one per line.")
+}
+
+func (f *flags) bindDescriptorSet(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.descriptorSet, "descriptor-set", "", "Read the FileDescriptorSet from the given file instead of compiling Protobuf files. The file must be produced with protoc --include_imports --descriptor_set_out.")
+}
+
 func (f *flags) bindDirMode(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.dirMode, "dir-mode", false, "Run as if the directory the file was given, but only print the errors from the file. Useful for integration with editors.")
 }
@@ -94,24 +243,217 @@ func (f *flags) bindDryRun(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.dryRun, "dry-run", false, "Print the protoc commands that would have been run without actually running them.")
 }
 
+func (f *flags) bindEmitDefaults(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.emitDefaults, "emit-defaults", false, "Output fields with default values in JSON.")
+}
+
+func (f *flags) bindEnumsAsInts(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.enumsAsInts, "enums-as-ints", false, "Output enum values as integers instead of names in JSON.")
+}
+
+func (f *flags) bindExpectCode(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.expectCode, "expect-code", "", "The gRPC status code the call is expected to end with, such as OK or NOT_FOUND. If set, the call succeeds if it ends with this code, and fails otherwise.")
+}
+
+func (f *flags) bindExpectFields(flagSet *pflag.FlagSet) {
+	flagSet.StringArrayVar(&f.expectFields, "expect-field", []string{}, "The expected value of a field of each response in the form 'path=value', such as 'items[0].name=\"foo\"'. The value is JSON, or a string if it is not valid JSON. Can be set multiple times.")
+}
+
+func (f *flags) bindExpectJSON(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.expectJSON, "expect-json", "", "The file of the expected responses as JSON, one JSON value per response. The responses are compared as JSON, so formatting and the order of fields do not matter.")
+}
+
+func (f *flags) bindExplain(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.explain, "explain", false, "Print the include path that satisfies each import, the later include paths that also contain the imported file, and the configured include paths that are never used.")
+}
+
+func (f *flags) bindExportFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.exportFormat, "format", "buf", "The format to export to. The only supported format is buf, which writes buf.yaml and buf.gen.yaml files next to the config file.")
+}
+
+func (f *flags) bindFailureFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.failureFormat, "output-format", "", "The format to print failures in. The supported formats are checkstyle, github-actions, and gitlab. By default, failures are printed as text.")
+}
+
+func (f *flags) bindFields(flagSet *pflag.FlagSet) {
+	flagSet.StringSliceVar(&f.fields, "fields", nil, "The fields of responses to print, such as 'a.b,c'. Fields are dot-separated paths of proto or JSON field names. All other fields are cleared before responses are printed.")
+}
+
+func (f *flags) bindFixtures(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.fixtures, "fixtures", "", "The YAML file of responses to use for methods, keyed by package.Service/Method. Responses are randomly generated for methods without fixtures.")
+}
+
+func (f *flags) bindFormatExitCode(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.formatExitCode, "format-exit-code", 0, "The exit code if there are format failures or diffs. For all, if set, also exit with this code if formatting changed any files and there were no other failures.")
+}
+
+func (f *flags) bindFramework(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.framework, "framework", "", "Print the hook entries for the given hook framework instead of installing a hook. The only valid value is pre-commit.")
+}
+
+func (f *flags) bindFromBuf(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.fromBuf, "from-buf", "", "Generate the config file from the buf.yaml and buf.gen.yaml files in the given directory, or from the given buf.yaml file and the buf.gen.yaml file next to it.")
+}
+
+func (f *flags) bindFromMakefile(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.fromMakefile, "from-makefile", "", "Generate the config file from the protoc commands in the recipes of the given Makefile, which is assumed to be run from the config directory.")
+}
+
+func (f *flags) bindFromProtoc(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.fromProtoc, "from-protoc", "", "Generate the config file from the given protoc command line, which is assumed to be run from the config directory.")
+}
+
+func (f *flags) bindGitRef(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.gitRef, "git-ref", "HEAD", "The git ref to compare against.")
+}
+
 func (f *flags) bindHarbormaster(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.harbormaster, "harbormaster", false, "Print failures in JSON compatible with the Harbormaster API.")
 }
 
 func (f *flags) bindHeaders(flagSet *pflag.FlagSet) {
-	flagSet.StringSliceVarP(&f.headers, "header", "H", []string{}, "Additional request headers in 'name:value' format.")
+	flagSet.StringSliceVarP(&f.headers, "header", "H", []string{}, "Additional request headers in 'name:value' format, or '@file' to read headers from a YAML or JSON file of names to values.")
+}
+
+func (f *flags) bindHeaderEnvPrefix(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.headerEnvPrefix, "header-env", "", "Add a request header for each environment variable with this prefix, named by the rest of the variable lowercased with underscores replaced by dashes.")
+}
+
+func (f *flags) bindHookType(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.hookType, "hook", "pre-commit", "The hook type to install, either pre-commit or pre-push.")
+}
+
+func (f *flags) bindIndent(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.indent, "indent", 0, "The number of spaces to indent JSON with. Set to a negative value for no indentation. By default, uses the config file value or the command default.")
+}
+
+func (f *flags) bindInteractive(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.interactive, "interactive", false, "Start an interactive session that keeps the connection and headers between calls, with tab-completion of methods from the input files or server reflection.")
+}
+
+func (f *flags) bindJSONOutput(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.jsonOutput, "json", false, "Print the result of the command as a JSON object with the fields command, duration, exit_code, error, failures, and data, where failures have the fields filename, line, column, id, message, severity, and owner, and data is the output of the command.")
 }
 
 func (f *flags) bindKeepaliveTime(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.keepaliveTime, "keepalive-time", "", "The maximum idle time after which a keepalive probe is sent.")
 }
 
+func (f *flags) bindLintExitCode(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.lintExitCode, "lint-exit-code", 0, "The exit code if there are lint failures. The default is 255, or 1 with --output-preset.")
+}
+
 func (f *flags) bindLintMode(flagSet *pflag.FlagSet) {
 	flagSet.BoolVarP(&f.lintMode, "lint", "l", false, "Write a lint error saying that the file is not formatted instead of writing the formatted file to stdout.")
 }
 
+func (f *flags) bindList(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.list, "list", false, "List the available methods with their request and response types instead of calling a method. If there are no services in the input files, server reflection is used, which requires address to be set.")
+}
+
+func (f *flags) bindListen(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.listen, "listen", "", "The address to listen on for calls to forward, for example :9000. This is required.")
+}
+
+func (f *flags) bindLogFile(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.logFile, "log-file", "", "The file to append logs to instead of stderr.")
+}
+
+func (f *flags) bindLogFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.logFormat, "log-format", "text", "The format of logs, either text or json. JSON logs include debug logs such as the protoc command lines and their durations.")
+}
+
+func (f *flags) bindMaxAttempts(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.maxAttempts, "max-attempts", 1, "The maximum number of attempts of a call that fails with one of the retry codes before any response is received.")
+}
+
+func (f *flags) bindMaxRecvMsgSize(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.maxRecvMsgSize, "max-recv-msg-size", 0, "The maximum size in bytes of a response message. By default, uses the gRPC default of 4MB.")
+}
+
+func (f *flags) bindMaxSendMsgSize(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.maxSendMsgSize, "max-send-msg-size", 0, "The maximum size in bytes of a request message. By default, there is no limit.")
+}
+
+func (f *flags) bindMaxWarnings(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.maxWarnings, "max-warnings", -1, "The maximum number of lint warnings before lint fails. By default, lint warnings never fail lint.")
+}
+
 func (f *flags) bindMethod(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.method, "method", "", "The GRPC method to call in the form package.Service/Method. This is required.")
+	_ = flagSet.SetAnnotation("method", cobra.BashCompCustom, []string{grpcMethodsCompletionFunc})
+}
+
+func (f *flags) bindModuleName(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.name, "name", "", "The name of the module. By default, modules.name from the config file is used.")
+}
+
+func (f *flags) bindModuleRegistryURL(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.url, "url", "", "The URL of the module registry. By default, modules.registry_url from the config file is used.")
+}
+
+func (f *flags) bindModuleVersion(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.version, "version", "", "The version of the module. This is required.")
+}
+
+func (f *flags) bindOptionNames(flagSet *pflag.FlagSet) {
+	flagSet.StringArrayVar(&f.optionNames, "option", []string{}, "The name of an option to list the usages of, such as foo.v1.sensitive. Also matches the fields of the option. Can be set multiple times. If not set, all custom options are listed.")
+}
+
+func (f *flags) bindOrigName(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.origName, "orig-name", false, "Use the original proto field names instead of lowerCamelCase names in JSON.")
+}
+
+func (f *flags) bindWarningsAsErrors(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.warningsAsErrors, "warnings-as-errors", false, "Treat protoc warnings as errors.")
+}
+
+func (f *flags) bindJobs(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.jobs, "jobs", 0, "The maximum number of protoc and plugin invocations to run at once. The default is the number of CPUs.")
+}
+
+// bindJSON binds all flags that control JSON output of messages.
+func (f *flags) bindJSON(flagSet *pflag.FlagSet) {
+	f.bindEmitDefaults(flagSet)
+	f.bindEnumsAsInts(flagSet)
+	f.bindIndent(flagSet)
+	f.bindOrigName(flagSet)
+}
+
+func (f *flags) bindGRPCOutput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.output, "output", "", "The file to write responses to instead of stdout.")
+}
+
+func (f *flags) bindGRPCOutputFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.outputFormat, "output-format", "json", "The format to write responses in, either json, binary, or text. If more than one binary response is written, each is prefixed with its length as a varint.")
+}
+
+func (f *flags) bindGithookOverwrite(flagSet *pflag.FlagSet) {
+	flagSet.BoolVarP(&f.overwrite, "overwrite", "w", false, "Overwrite an existing hook.")
+}
+
+func (f *flags) bindOutDirPath(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.outDirPath, "out", "", "The directory to write files to.")
+}
+
+func (f *flags) bindOutputFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.outputFormat, "output-format", "json", "The output format, either json or binary. If more than one binary message is output, each is prefixed with its length as a varint.")
+}
+
+func (f *flags) bindOutputPreset(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.outputPreset, "output-preset", "", "The editor to print failures for instead of --print-fields, either vim, emacs, or vscode. Commands exit with code 1 instead of 255 if there are failures.")
+}
+
+func (f *flags) bindOutputArchive(flagSet *pflag.FlagSet) {
+	flagSet.StringVarP(&f.outputArchive, "output-archive", "o", "", "Write the generated files to a tar.gz archive at the given path, or to stdout if the path is -, instead of to the plugin output paths.")
+}
+
+func (f *flags) bindParserOnly(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.parserOnly, "parser-only", false, "Check for syntax errors and undefined types with the internal parser instead of protoc. This is faster and does not download protoc, but does not catch every failure that protoc does.")
+}
+
+func (f *flags) bindPrintPlan(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.printPlan, "print-plan", false, "Print the protoc commands, plugins, include paths, and output directories that would be used without running protoc. With --json, print the commands as JSON objects.")
 }
 
 func (f *flags) bindOverwrite(flagSet *pflag.FlagSet) {
@@ -122,22 +464,138 @@ func (f *flags) bindPackage(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.pkg, "package", "", "The Protobuf package to use in the created file.")
 }
 
+func (f *flags) bindPackageVersion(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.packageVersion, "version", "", "The package version such as v1 or v1beta1 to create the file with. The file is created in a directory named after the version, and the package ends with the version.")
+}
+
 func (f *flags) bindPrintFields(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.printFields, "print-fields", "filename:line:column:message", "The colon-separated fields to print out on error.")
 }
 
+func (f *flags) bindPrintMetadata(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.printMetadata, "print-metadata", false, "Print the response headers and trailers as JSON in addition to the response messages.")
+}
+
+func (f *flags) bindProtocBinPath(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.protocBinPath, "protoc-bin-path", "", "The path to a protoc binary to use instead of downloading protoc, or a name to look up in PATH. Setting this option will ignore the config protoc_version and protoc bin_path settings.")
+}
+
+func (f *flags) bindProtocVersions(flagSet *pflag.FlagSet) {
+	flagSet.StringSliceVar(&f.protocVersions, "protoc-versions", nil, "Compile with each of the given comma-separated protoc versions instead of the config protoc_version, and print the failures that only happen with some versions with the versions they happen with.")
+}
+
 func (f *flags) bindProtocURL(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.protocURL, "protoc-url", "", "The url to use to download the protoc zip file, otherwise uses GitHub Releases. Setting this option will ignore the config protoc_version setting.")
 }
 
+func (f *flags) bindProtocWKTPath(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.protocWKTPath, "protoc-wkt-path", "", "The path to include for the well-known types when using --protoc-bin-path or the config protoc bin_path setting. Setting this option will ignore the config protoc wkt_path setting.")
+}
+
+func (f *flags) bindQuiet(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.quiet, "quiet", false, "Only print failures and errors, without the progress of downloads, compiling, and generating, or warnings.")
+}
+
+func (f *flags) bindRecord(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.record, "record", "", "The scenario file to append the requests, responses, and status code of the call to, creating it if it does not exist. Replay the recorded calls against any address with prototool test.")
+}
+
+func (f *flags) bindRemoteExecutionURL(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.remoteExecutionURL, "remote-execution-url", "", "The http or https URL of a remote execution worker to run protoc and plugins on instead of running them locally. The worker must have protoc and the plugins installed.")
+}
+
+func (f *flags) bindRetryBackoff(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.retryBackoff, "retry-backoff", "100ms", "The backoff before the first retry, which doubles after each retry.")
+}
+
+func (f *flags) bindRetryCodes(flagSet *pflag.FlagSet) {
+	flagSet.StringSliceVar(&f.retryCodes, "retry-code", []string{"UNAVAILABLE"}, "The gRPC status codes to retry calls for if max-attempts is greater than 1.")
+}
+
+func (f *flags) bindSeed(flagSet *pflag.FlagSet) {
+	flagSet.Int64Var(&f.seed, "seed", 0, "The seed for random data, for reproducible output. By default, a seed based on the current time is used.")
+}
+
+func (f *flags) bindScenarioAddress(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.address, "address", "", "The gRPC endpoint to connect to. This overrides the address in the scenario file.")
+}
+
+func (f *flags) bindServeAddress(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.address, "address", "", "The address to serve on, for example :8080. This is required.")
+}
+
+func (f *flags) bindSince(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.since, "since", "", "The git ref to start the changelog after, such as a release tag. Required.")
+}
+
+func (f *flags) bindStat(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.stat, "stat", false, "With --diff, print the number of files, insertions, and deletions that formatting would change per directory instead of the diffs. With --json, the counts are printed as JSON.")
+}
+
 func (f *flags) bindStdin(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.stdin, "stdin", false, "Read the GRPC request data from stdin in JSON format. Either this or --data is required.")
 }
 
+func (f *flags) bindSubject(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.subject, "subject", "", "The schema registry subject, for example topic-value. This is required.")
+}
+
+func (f *flags) bindSchemaRegistryURL(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.url, "url", "", "The URL of the schema registry. This is required.")
+}
+
+func (f *flags) bindSchemaVersion(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.schemaVersion, "version", "latest", "The version of the schema under the subject.")
+}
+
+func (f *flags) bindSummary(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.summary, "summary", false, "Print the number of failures per linter and per directory, and the files with the most failures, instead of each failure.")
+}
+
+func (f *flags) bindTarget(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.target, "target", "", "The name of a target in grpc.targets of the config file to connect to instead of an address, using its TLS settings and default headers.")
+}
+
+func (f *flags) bindTemplate(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.template, "template", "", "The text/template to print each failure with instead of --print-fields, for example '{{.Filename}}:{{.Line}} {{.ID}} {{.Message}}'. The fields are Filename, Line, Column, ID, Message, Severity, and Owner.")
+}
+
+func (f *flags) bindTicket(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.ticket, "ticket", "", "The ticket ID to use for the <ticket> variable of the create header, for example PROJ-123.")
+}
+
+func (f *flags) bindTiming(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.timing, "timing", false, "Print the wall time spent in each phase of the command to stderr.")
+}
+
+func (f *flags) bindTraceExec(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.traceExec, "trace-exec", false, "Print every external process run with its arguments, environment changes, duration, and exit code to stderr.")
+}
+
 func (f *flags) bindUncomment(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.uncomment, "uncomment", false, "Uncomment the example config settings.")
 }
 
+func (f *flags) bindUserAgent(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.userAgent, "user-agent", "", "The value to prepend to the gRPC library user-agent.")
+}
+
+func (f *flags) bindWaitForReady(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.waitForReady, "wait-for-ready", false, "Wait for the connection to be ready up to the call timeout instead of failing immediately if the server is unavailable.")
+}
+
+func (f *flags) bindMetricsURL(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.metricsURL, "metrics-url", "", "Emit the duration and failure counts of the command to statsd with a URL of the form statsd://host:port/prefix, or to a Prometheus Pushgateway with a URL of the form http://host:port.")
+}
+
+func (f *flags) bindNoCache(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.noCache, "no-cache", false, "Do not use or store cached lint results, and lint every file.")
+}
+
+func (f *flags) bindNotify(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.notify, "notify", false, "Post failures as JSON to the webhook configured with webhook.url in prototool.yaml.")
+}
+
 func (f *flags) bindNoRewrite(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.noRewrite, "no-rewrite", false, "Do not rewrite the file options go_package, java_multiple_files, java_outer_classname, and java_package to match the package per the guidelines of the style guide.")
 }
@@ -20,7 +20,10 @@
 
 package create
 
-import "go.uber.org/zap"
+import (
+	"github.com/uber/prototool/internal/exectrace"
+	"go.uber.org/zap"
+)
 
 // DefaultPackage is the default package to use in lieu of one being able to be
 // derived.
@@ -30,6 +33,11 @@ const DefaultPackage = "uber.prototool.generated"
 type Handler interface {
 	// Create the files at the given filePaths.
 	Create(filePaths ...string) error
+	// CreateVersion copies the Protobuf files in the directory, which is
+	// expected to be named after a version such as v1, to a sibling directory
+	// named after the given version, rewriting the package, imports, and
+	// file options for the new version.
+	CreateVersion(dirPath string, version string) error
 }
 
 // HandlerOption is an option for a new Handler.
@@ -54,6 +62,46 @@ func HandlerWithPackage(pkg string) HandlerOption {
 	}
 }
 
+// HandlerWithEdition returns a HandlerOption that creates files with the
+// given edition instead of proto3.
+func HandlerWithEdition(edition string) HandlerOption {
+	return func(handler *handler) {
+		handler.edition = edition
+	}
+}
+
+// HandlerWithVersion returns a HandlerOption that creates files with a
+// package ending with the given version, such as v1 or v1beta1, in a
+// directory named after the version.
+//
+// If the directory of a file is not named after the version, the file is
+// created in a subdirectory named after the version.
+func HandlerWithVersion(version string) HandlerOption {
+	return func(handler *handler) {
+		handler.version = version
+	}
+}
+
+// HandlerWithTicket returns a HandlerOption that uses the given ticket ID
+// for the <ticket> variable of the create header.
+//
+// The default is to fail if the header uses <ticket>.
+func HandlerWithTicket(ticket string) HandlerOption {
+	return func(handler *handler) {
+		handler.ticket = ticket
+	}
+}
+
+// HandlerWithExecTracer returns a HandlerOption that traces the git
+// processes run to read the author for the create header.
+//
+// The default is to use exectrace.NewNopTracer().
+func HandlerWithExecTracer(execTracer exectrace.Tracer) HandlerOption {
+	return func(handler *handler) {
+		handler.execTracer = execTracer
+	}
+}
+
 // NewHandler returns a new Handler.
 func NewHandler(options ...HandlerOption) Handler {
 	return newHandler(options...)
@@ -27,39 +27,72 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
+	"github.com/uber/prototool/internal/editions"
+	"github.com/uber/prototool/internal/exectrace"
+	"github.com/uber/prototool/internal/git"
 	"github.com/uber/prototool/internal/protostrs"
 	"github.com/uber/prototool/internal/settings"
 	"go.uber.org/zap"
 )
 
-var tmpl = template.Must(template.New("tmpl").Parse(`syntax = "proto3";
+var tmpl = template.Must(template.New("tmpl").Parse(`{{.Header}}{{if .Edition}}edition = "{{.Edition}}";{{else}}syntax = "proto3";{{end}}
 
-package {{.Pkg}};
-
-option go_package = "{{.GoPkg}}";
-option java_multiple_files = true;
-option java_outer_classname = "{{.JavaOuterClassname}}";
-option java_package = "{{.JavaPkg}}";`))
+package {{.Pkg}};{{if .HasOptions}}
+{{end}}{{if .GoPkg}}
+option go_package = "{{.GoPkg}}";{{end}}{{if .JavaMultipleFiles}}
+option java_multiple_files = {{.JavaMultipleFiles}};{{end}}{{if .JavaOuterClassname}}
+option java_outer_classname = "{{.JavaOuterClassname}}";{{end}}{{if .JavaPkg}}
+option java_package = "{{.JavaPkg}}";{{end}}{{range .Options}}
+option {{.Name}} = {{.Value}};{{end}}`))
 
 type tmplData struct {
+	// comment lines followed by a blank line, if any
+	Header             string
+	Edition            string
 	Pkg                string
 	GoPkg              string
+	JavaMultipleFiles  string
 	JavaOuterClassname string
 	JavaPkg            string
+	// additional options from the languages and a create layout, sorted by name
+	Options []*tmplOption
+}
+
+// HasOptions returns true if the template data has any file options.
+func (d *tmplData) HasOptions() bool {
+	return d.GoPkg != "" || d.JavaMultipleFiles != "" || d.JavaOuterClassname != "" || d.JavaPkg != "" || len(d.Options) > 0
+}
+
+type tmplOption struct {
+	Name string
+	// quoted unless a bool
+	Value string
 }
 
 type handler struct {
 	logger         *zap.Logger
+	execTracer     exectrace.Tracer
 	configProvider settings.ConfigProvider
 	pkg            string
+	edition        string
+	version        string
+	ticket         string
+
+	// for testing
+	now func() time.Time
 }
 
 func newHandler(options ...HandlerOption) *handler {
 	handler := &handler{
-		logger: zap.NewNop(),
+		logger:     zap.NewNop(),
+		execTracer: exectrace.NewNopTracer(),
+		now:        time.Now,
 	}
 	for _, option := range options {
 		option(handler)
@@ -71,6 +104,22 @@ func newHandler(options ...HandlerOption) *handler {
 }
 
 func (h *handler) Create(filePaths ...string) error {
+	if h.edition != "" && !editions.IsSupported(h.edition) {
+		return fmt.Errorf("unsupported edition %q, supported editions are %v", h.edition, editions.SupportedEditions)
+	}
+	if h.version != "" {
+		if !protostrs.IsPackageVersion(h.version) {
+			return fmt.Errorf("invalid version %q, versions must be of the form v1, v2alpha1, or v1beta1", h.version)
+		}
+		if h.pkg != "" && protostrs.PackageVersion(h.pkg) != h.version {
+			return fmt.Errorf("package %q does not end with version %s", h.pkg, h.version)
+		}
+		versionFilePaths := make([]string, len(filePaths))
+		for i, filePath := range filePaths {
+			versionFilePaths[i] = getVersionFilePath(filePath, h.version)
+		}
+		filePaths = versionFilePaths
+	}
 	for _, filePath := range filePaths {
 		if err := h.checkFilePath(filePath); err != nil {
 			return err
@@ -89,6 +138,12 @@ func (h *handler) checkFilePath(filePath string) error {
 		return errors.New("filePath empty")
 	}
 	dirPath := filepath.Dir(filePath)
+	// the version directory is created if it does not exist
+	if h.version != "" && filepath.Base(dirPath) == h.version {
+		if _, err := os.Stat(dirPath); os.IsNotExist(err) {
+			dirPath = filepath.Dir(dirPath)
+		}
+	}
 
 	fileInfo, err := os.Stat(dirPath)
 	if err != nil {
@@ -104,37 +159,196 @@ func (h *handler) checkFilePath(filePath string) error {
 }
 
 func (h *handler) create(filePath string) error {
-	pkg, err := h.getPkg(filePath)
+	absFilePath, err := filepath.Abs(filePath)
 	if err != nil {
 		return err
 	}
-	data, err := getData(
-		&tmplData{
-			Pkg:                pkg,
-			GoPkg:              protostrs.GoPackage(pkg),
-			JavaOuterClassname: protostrs.JavaOuterClassname(filePath),
-			JavaPkg:            protostrs.JavaPackage(pkg),
-		},
-	)
+	absDirPath := filepath.Dir(absFilePath)
+	config, err := h.configProvider.GetForDir(absDirPath)
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(filePath, data, 0644)
-}
-
-func (h *handler) getPkg(filePath string) (string, error) {
-	if h.pkg != "" {
-		return h.pkg, nil
+	layout, variables, err := matchLayout(config.Create.Layouts, absDirPath)
+	if err != nil {
+		return err
 	}
-	absFilePath, err := filepath.Abs(filePath)
+	var pkg string
+	switch {
+	case h.pkg != "":
+		pkg = h.pkg
+	case layout != nil:
+		pkg = expandLayoutTemplate(layout.Package, variables)
+	default:
+		pkg, err = getPkg(config, absDirPath)
+		if err != nil {
+			return err
+		}
+	}
+	if h.version != "" && protostrs.PackageVersion(pkg) != h.version {
+		pkg = pkg + "." + h.version
+	}
+	data := &tmplData{
+		Edition: h.edition,
+		Pkg:     pkg,
+	}
+	if err := setLanguageOptions(data, config.Languages, filePath); err != nil {
+		return err
+	}
+	if layout != nil {
+		setLayoutOptions(data, layout.Options, variables)
+	}
+	data.Header, err = h.getHeader(config, filePath, pkg, variables)
 	if err != nil {
-		return "", err
+		return err
 	}
-	absDirPath := filepath.Dir(absFilePath)
-	config, err := h.configProvider.GetForDir(absDirPath)
+	fileData, err := getData(data)
 	if err != nil {
-		return "", err
+		return err
 	}
+	if h.version != "" {
+		if err := os.MkdirAll(absDirPath, 0755); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(filePath, fileData, 0644)
+}
+
+// getHeader returns the create header of the config as comment lines
+// followed by a blank line, with the variables of the layout and the
+// built-in variables replaced, or an empty string if there is no header.
+func (h *handler) getHeader(config settings.Config, filePath string, pkg string, layoutVariables map[string]string) (string, error) {
+	if config.Create.Header == "" {
+		return "", nil
+	}
+	now := h.now()
+	variables := make(map[string]string, len(layoutVariables)+4)
+	for name, value := range layoutVariables {
+		variables[name] = value
+	}
+	// built-in variables take precedence over variables of the layout
+	variables["date"] = now.Format("2006-01-02")
+	variables["year"] = strconv.Itoa(now.Year())
+	variables["package"] = pkg
+	variables["file"] = filepath.Base(filePath)
+	for _, match := range layoutVariableRegexp.FindAllStringSubmatch(config.Create.Header, -1) {
+		name := match[1]
+		if _, ok := variables[name]; ok {
+			continue
+		}
+		switch name {
+		case "author", "author_email":
+			key := "user.name"
+			if name == "author_email" {
+				key = "user.email"
+			}
+			value, ok := git.ConfigValue(h.execTracer, config.DirPath, key)
+			if !ok || value == "" {
+				return "", fmt.Errorf("create header uses <%s> but git config %s is not set", name, key)
+			}
+			variables[name] = value
+		case "ticket":
+			if h.ticket == "" {
+				return "", fmt.Errorf("create header uses <ticket> but no ticket was given")
+			}
+			variables[name] = h.ticket
+		default:
+			return "", fmt.Errorf("create header uses unknown variable <%s>", name)
+		}
+	}
+	lines := strings.Split(strings.TrimRight(expandLayoutTemplate(config.Create.Header, variables), "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "//"):
+		case line == "":
+			lines[i] = "//"
+		default:
+			lines[i] = "// " + line
+		}
+	}
+	return strings.Join(lines, "\n") + "\n\n", nil
+}
+
+// getVersionFilePath returns the file path in the directory named after
+// the version, which is the directory of the file if it is already named
+// after the version.
+func getVersionFilePath(filePath string, version string) string {
+	dirPath := filepath.Dir(filePath)
+	if filepath.Base(dirPath) == version {
+		return filePath
+	}
+	return filepath.Join(dirPath, version, filepath.Base(filePath))
+}
+
+// setLanguageOptions sets the file options of the languages on the template
+// data, or the file options in protostrs.DefaultFileOptionTemplates if there
+// are no languages.
+func setLanguageOptions(data *tmplData, languages []string, filePath string) error {
+	templates := protostrs.DefaultFileOptionTemplates
+	if len(languages) > 0 {
+		templates = protostrs.LanguageFileOptionTemplates(languages)
+	}
+	options := make(map[string]string, len(templates))
+	for name, templateText := range templates {
+		value, err := protostrs.FileOptionValue(templateText, data.Pkg, filePath)
+		if err != nil {
+			return fmt.Errorf("could not compute file option %q: %v", name, err)
+		}
+		options[name] = value
+	}
+	setOptions(data, options)
+	return nil
+}
+
+// setLayoutOptions sets the options of the layout on the template data,
+// replacing the values of the options that are already set.
+func setLayoutOptions(data *tmplData, options map[string]string, variables map[string]string) {
+	expandedOptions := make(map[string]string, len(options))
+	for name, value := range options {
+		expandedOptions[name] = expandLayoutTemplate(value, variables)
+	}
+	setOptions(data, expandedOptions)
+}
+
+func setOptions(data *tmplData, options map[string]string) {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := options[name]
+		switch name {
+		case "go_package":
+			data.GoPkg = value
+		case "java_multiple_files":
+			data.JavaMultipleFiles = value
+		case "java_outer_classname":
+			data.JavaOuterClassname = value
+		case "java_package":
+			data.JavaPkg = value
+		default:
+			if value != "true" && value != "false" {
+				value = strconv.Quote(value)
+			}
+			setTmplOption(data, name, value)
+		}
+	}
+}
+
+// setTmplOption replaces the value of the additional option with the name,
+// or adds the option so that the additional options stay sorted by name.
+func setTmplOption(data *tmplData, name string, value string) {
+	for _, option := range data.Options {
+		if option.Name == name {
+			option.Value = value
+			return
+		}
+	}
+	data.Options = append(data.Options, &tmplOption{Name: name, Value: value})
+	sort.Slice(data.Options, func(i int, j int) bool { return data.Options[i].Name < data.Options[j].Name })
+}
+
+func getPkg(config settings.Config, absDirPath string) (string, error) {
 	// no config file found, can't compute package
 	if config.DirPath == "" {
 		return DefaultPackage, nil
@@ -0,0 +1,100 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package create
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/uber/prototool/internal/settings"
+)
+
+// layoutVariableRegexp matches the <name> variables of create layouts.
+var layoutVariableRegexp = regexp.MustCompile(`<([a-zA-Z_][a-zA-Z0-9_]*)>`)
+
+// matchLayout returns the first layout whose path matches the directory,
+// and the values of the variables in the path.
+func matchLayout(layouts []settings.CreateLayout, absDirPath string) (*settings.CreateLayout, map[string]string, error) {
+	for i, layout := range layouts {
+		rel, err := filepath.Rel(layout.DirPath, absDirPath)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+		layoutRegexp, names, err := getLayoutRegexp(layout.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		matches := layoutRegexp.FindStringSubmatch(rel)
+		if matches == nil {
+			continue
+		}
+		variables := make(map[string]string, len(names))
+		for j, name := range names {
+			value := matches[j+1]
+			if existing, ok := variables[name]; ok && existing != value {
+				// the same variable must match the same value everywhere
+				matches = nil
+				break
+			}
+			variables[name] = value
+		}
+		if matches == nil {
+			continue
+		}
+		return &layouts[i], variables, nil
+	}
+	return nil, nil, nil
+}
+
+// getLayoutRegexp returns the regexp for the layout path, where each
+// variable matches one or more characters within a directory name, and
+// the variable names in the order of their groups.
+func getLayoutRegexp(layoutPath string) (*regexp.Regexp, []string, error) {
+	var names []string
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, indexes := range layoutVariableRegexp.FindAllStringSubmatchIndex(layoutPath, -1) {
+		pattern.WriteString(regexp.QuoteMeta(layoutPath[last:indexes[0]]))
+		pattern.WriteString("([^/]+)")
+		names = append(names, layoutPath[indexes[2]:indexes[3]])
+		last = indexes[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(layoutPath[last:]))
+	pattern.WriteString("$")
+	layoutRegexp, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	return layoutRegexp, names, nil
+}
+
+// expandLayoutTemplate replaces the variables in the template with their values.
+func expandLayoutTemplate(template string, variables map[string]string) string {
+	return layoutVariableRegexp.ReplaceAllStringFunc(template, func(variable string) string {
+		return variables[variable[1:len(variable)-1]]
+	})
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package create
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/uber/prototool/internal/protostrs"
+)
+
+var (
+	versionPackageRegexp    = regexp.MustCompile(`(?m)^[ \t]*package[ \t]+([a-zA-Z0-9_.]+)[ \t]*;`)
+	versionImportRegexp     = regexp.MustCompile(`(?m)^([ \t]*import[ \t]+(?:public[ \t]+|weak[ \t]+)?")([^"]+)(")`)
+	versionFileOptionRegexp = regexp.MustCompile(`(?m)^(option[ \t]+([a-zA-Z0-9_.()]+)[ \t]*=[ \t]*")([^"]*)(")`)
+	versionDeprecatedRegexp = regexp.MustCompile(`(?m)^([ \t]*)\S.*\bdeprecated[ \t]*=[ \t]*true\b.*$`)
+)
+
+func (h *handler) CreateVersion(dirPath string, version string) error {
+	if !protostrs.IsPackageVersion(version) {
+		return fmt.Errorf("invalid version %q, versions must be of the form v1, v2alpha1, or v1beta1", version)
+	}
+	absDirPath, err := filepath.Abs(dirPath)
+	if err != nil {
+		return err
+	}
+	fromVersion := filepath.Base(absDirPath)
+	if !protostrs.IsPackageVersion(fromVersion) {
+		return fmt.Errorf("%q is not a directory named after a version such as v1", dirPath)
+	}
+	if fromVersion == version {
+		return fmt.Errorf("%q already has version %s", dirPath, version)
+	}
+	toDirPath := filepath.Join(filepath.Dir(dirPath), version)
+	if _, err := os.Stat(toDirPath); err == nil {
+		return fmt.Errorf("%q already exists", toDirPath)
+	}
+	fileInfos, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		return err
+	}
+	var filenames []string
+	for _, fileInfo := range fileInfos {
+		if fileInfo.Mode().IsRegular() && filepath.Ext(fileInfo.Name()) == ".proto" {
+			filenames = append(filenames, fileInfo.Name())
+		}
+	}
+	if len(filenames) == 0 {
+		return fmt.Errorf("no Protobuf files in %q", dirPath)
+	}
+	sort.Strings(filenames)
+	rewriter := &versionRewriter{
+		fromVersion: fromVersion,
+		toVersion:   version,
+		slashDir:    filepath.ToSlash(absDirPath),
+		filenames:   make(map[string]struct{}, len(filenames)),
+	}
+	for _, filename := range filenames {
+		rewriter.filenames[filename] = struct{}{}
+	}
+	// rewrite all files before writing any so that nothing is written on error
+	filenameToData := make(map[string][]byte, len(filenames))
+	for _, filename := range filenames {
+		data, err := ioutil.ReadFile(filepath.Join(dirPath, filename))
+		if err != nil {
+			return err
+		}
+		data, err = rewriter.rewrite(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", filepath.Join(dirPath, filename), err)
+		}
+		filenameToData[filename] = data
+	}
+	if err := os.MkdirAll(toDirPath, 0755); err != nil {
+		return err
+	}
+	for _, filename := range filenames {
+		if err := ioutil.WriteFile(filepath.Join(toDirPath, filename), filenameToData[filename], 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionRewriter rewrites the files of a version directory for a new version.
+type versionRewriter struct {
+	fromVersion string
+	toVersion   string
+	// the absolute path of the version directory with / separators
+	slashDir string
+	// the names of the Protobuf files in the version directory
+	filenames map[string]struct{}
+}
+
+// rewrite rewrites the package, references to types in the package, imports
+// of files in the version directory, and the versions in file options, and
+// adds TODO comments for the changes that need review.
+func (v *versionRewriter) rewrite(data []byte) ([]byte, error) {
+	matches := versionPackageRegexp.FindSubmatch(data)
+	if matches == nil {
+		return nil, fmt.Errorf("no package declared")
+	}
+	fromPkg := string(matches[1])
+	if protostrs.PackageVersion(fromPkg) != v.fromVersion {
+		return nil, fmt.Errorf("package %q does not end with version %s", fromPkg, v.fromVersion)
+	}
+	toPkg := strings.TrimSuffix(fromPkg, v.fromVersion) + v.toVersion
+
+	// the package and fully-qualified references, with or without a leading period,
+	// but not as part of a longer name
+	pkgRegexp := regexp.MustCompile(`(^|[^a-zA-Z0-9_.]|[^a-zA-Z0-9_.]\.)` + regexp.QuoteMeta(fromPkg) + `\b`)
+	data = pkgRegexp.ReplaceAll(data, []byte("${1}"+toPkg))
+	data = versionImportRegexp.ReplaceAllFunc(data, func(match []byte) []byte {
+		submatches := versionImportRegexp.FindSubmatch(match)
+		return concatBytes(submatches[1], []byte(v.rewriteImport(string(submatches[2]))), submatches[3])
+	})
+	data = versionFileOptionRegexp.ReplaceAllFunc(data, func(match []byte) []byte {
+		submatches := versionFileOptionRegexp.FindSubmatch(match)
+		return concatBytes(submatches[1], []byte(v.rewriteOptionValue(string(submatches[2]), string(submatches[3]))), submatches[4])
+	})
+	data = versionDeprecatedRegexp.ReplaceAll(data, []byte(fmt.Sprintf("${1}// TODO: This is deprecated, consider removing it in %s.\n$0", v.toVersion)))
+	loc := versionPackageRegexp.FindIndex(data)
+	todo := fmt.Sprintf(
+		"// TODO: This file was copied from %s by prototool create version. Make any\n// incompatible changes for %s, such as removing deprecated elements, and\n// then remove the TODO comments.\n",
+		v.fromVersion,
+		v.toVersion,
+	)
+	return concatBytes(data[:loc[0]], []byte(todo), data[loc[0]:]), nil
+}
+
+// rewriteImport returns the import path for the new version if the import
+// is of a file in the version directory, otherwise the import path.
+func (v *versionRewriter) rewriteImport(importPath string) string {
+	dir, filename := path.Split(importPath)
+	dir = strings.TrimSuffix(dir, "/")
+	if _, ok := v.filenames[filename]; !ok || path.Base(dir) != v.fromVersion {
+		return importPath
+	}
+	if dir != v.slashDir && !strings.HasSuffix(v.slashDir, "/"+dir) {
+		return importPath
+	}
+	return path.Join(path.Dir(dir), v.toVersion, filename)
+}
+
+// rewriteOptionValue replaces the version in the value of a file option,
+// for example in go_package, java_package, and csharp_namespace.
+func (v *versionRewriter) rewriteOptionValue(name string, value string) string {
+	if name == "go_package" {
+		// the Go package name, such as v1pb or foov1, may end with the version
+		prefix, goPkg := "", value
+		if i := strings.LastIndex(value, ";"); i >= 0 {
+			prefix, goPkg = value[:i+1], value[i+1:]
+		}
+		switch {
+		case strings.HasSuffix(goPkg, v.fromVersion):
+			goPkg = strings.TrimSuffix(goPkg, v.fromVersion) + v.toVersion
+		case strings.HasSuffix(goPkg, v.fromVersion+"pb"):
+			goPkg = strings.TrimSuffix(goPkg, v.fromVersion+"pb") + v.toVersion + "pb"
+		}
+		value = prefix + goPkg
+	}
+	for _, replace := range [][2]string{
+		{v.fromVersion, v.toVersion},
+		{strings.ToUpper(v.fromVersion[:1]) + v.fromVersion[1:], strings.ToUpper(v.toVersion[:1]) + v.toVersion[1:]},
+	} {
+		valueRegexp := regexp.MustCompile(`(^|[^a-zA-Z0-9])` + regexp.QuoteMeta(replace[0]) + `($|[^a-zA-Z0-9])`)
+		value = valueRegexp.ReplaceAllString(value, "${1}"+replace[1]+"${2}")
+	}
+	return value
+}
+
+func concatBytes(slices ...[]byte) []byte {
+	return bytes.Join(slices, nil)
+}
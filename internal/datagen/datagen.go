@@ -0,0 +1,81 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package datagen generates random Protobuf messages that are valid
+// for their message types.
+package datagen
+
+import (
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"go.uber.org/zap"
+)
+
+// DefaultMaxDepth is the default maximum depth of nested messages.
+const DefaultMaxDepth = 3
+
+// Generator generates random messages.
+type Generator interface {
+	// Generate generates a random message of the given type.
+	//
+	// Every field is populated with a valid value for its type, except
+	// that message fields are not populated past the maximum depth, and
+	// only one field of each oneof is populated.
+	Generate(messageDescriptor *desc.MessageDescriptor) (*dynamic.Message, error)
+}
+
+// GeneratorOption is an option for a new Generator.
+type GeneratorOption func(*generator)
+
+// GeneratorWithLogger returns a GeneratorOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func GeneratorWithLogger(logger *zap.Logger) GeneratorOption {
+	return func(generator *generator) {
+		generator.logger = logger
+	}
+}
+
+// GeneratorWithSeed returns a GeneratorOption that uses the given seed
+// for random data, so that the generated messages are reproducible.
+//
+// The default is to use a seed based on the current time.
+func GeneratorWithSeed(seed int64) GeneratorOption {
+	return func(generator *generator) {
+		generator.seed = seed
+	}
+}
+
+// GeneratorWithMaxDepth returns a GeneratorOption that only populates
+// nested message fields up to the given depth.
+//
+// The default is to use DefaultMaxDepth.
+func GeneratorWithMaxDepth(maxDepth int) GeneratorOption {
+	return func(generator *generator) {
+		generator.maxDepth = maxDepth
+	}
+}
+
+// NewGenerator returns a new Generator.
+//
+// Generators are safe for concurrent use.
+func NewGenerator(options ...GeneratorOption) Generator {
+	return newGenerator(options...)
+}
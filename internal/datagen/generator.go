@@ -0,0 +1,224 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package datagen
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"go.uber.org/zap"
+)
+
+const (
+	maxRepeatedLen = 3
+	maxStringLen   = 10
+	// we do not want timestamps past the year 2100 or durations
+	// longer than a year, as these are not interesting
+	maxTimestampSeconds = 4102444800
+	maxDurationSeconds  = 31536000
+	maxNanos            = 1000000000
+
+	stringRunes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+type generator struct {
+	logger   *zap.Logger
+	seed     int64
+	maxDepth int
+
+	rand *rand.Rand
+	lock sync.Mutex
+}
+
+func newGenerator(options ...GeneratorOption) *generator {
+	generator := &generator{
+		logger:   zap.NewNop(),
+		seed:     time.Now().UnixNano(),
+		maxDepth: DefaultMaxDepth,
+	}
+	for _, option := range options {
+		option(generator)
+	}
+	generator.rand = rand.New(rand.NewSource(generator.seed))
+	return generator
+}
+
+func (g *generator) Generate(messageDescriptor *desc.MessageDescriptor) (*dynamic.Message, error) {
+	// rand.Rand is not safe for concurrent use
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.generateMessage(messageDescriptor, 0)
+}
+
+func (g *generator) generateMessage(messageDescriptor *desc.MessageDescriptor, depth int) (*dynamic.Message, error) {
+	dynamicMessage := dynamic.NewMessage(messageDescriptor)
+	switch messageDescriptor.GetFullyQualifiedName() {
+	case "google.protobuf.Any":
+		// we have no type to pack, and an empty Any cannot be marshalled to JSON
+		return nil, fmt.Errorf("cannot generate a google.protobuf.Any")
+	case "google.protobuf.Timestamp":
+		return g.setSecondsAndNanos(dynamicMessage, maxTimestampSeconds)
+	case "google.protobuf.Duration":
+		return g.setSecondsAndNanos(dynamicMessage, maxDurationSeconds)
+	}
+	for _, fieldDescriptor := range messageDescriptor.GetFields() {
+		if fieldDescriptor.GetOneOf() != nil || !g.canGenerate(fieldDescriptor, depth) {
+			continue
+		}
+		if err := g.setField(dynamicMessage, fieldDescriptor, depth); err != nil {
+			return nil, err
+		}
+	}
+	for _, oneOfDescriptor := range messageDescriptor.GetOneOfs() {
+		var choices []*desc.FieldDescriptor
+		for _, fieldDescriptor := range oneOfDescriptor.GetChoices() {
+			if g.canGenerate(fieldDescriptor, depth) {
+				choices = append(choices, fieldDescriptor)
+			}
+		}
+		if len(choices) == 0 {
+			continue
+		}
+		if err := g.setField(dynamicMessage, choices[g.rand.Intn(len(choices))], depth); err != nil {
+			return nil, err
+		}
+	}
+	return dynamicMessage, nil
+}
+
+func (g *generator) setSecondsAndNanos(dynamicMessage *dynamic.Message, maxSeconds int64) (*dynamic.Message, error) {
+	if err := dynamicMessage.TrySetFieldByName("seconds", g.rand.Int63n(maxSeconds)); err != nil {
+		return nil, err
+	}
+	if err := dynamicMessage.TrySetFieldByName("nanos", g.rand.Int31n(maxNanos)); err != nil {
+		return nil, err
+	}
+	return dynamicMessage, nil
+}
+
+// canGenerate returns false for message fields past the maximum depth,
+// and for fields that can never be generated.
+func (g *generator) canGenerate(fieldDescriptor *desc.FieldDescriptor, depth int) bool {
+	if fieldDescriptor.IsMap() {
+		return g.canGenerate(fieldDescriptor.GetMapValueType(), depth)
+	}
+	messageDescriptor := fieldDescriptor.GetMessageType()
+	if messageDescriptor == nil {
+		return true
+	}
+	return depth < g.maxDepth && messageDescriptor.GetFullyQualifiedName() != "google.protobuf.Any"
+}
+
+func (g *generator) setField(dynamicMessage *dynamic.Message, fieldDescriptor *desc.FieldDescriptor, depth int) error {
+	n := g.rand.Intn(maxRepeatedLen) + 1
+	switch {
+	case fieldDescriptor.IsMap():
+		for i := 0; i < n; i++ {
+			key, err := g.generateValue(fieldDescriptor.GetMapKeyType(), depth)
+			if err != nil {
+				return err
+			}
+			value, err := g.generateValue(fieldDescriptor.GetMapValueType(), depth)
+			if err != nil {
+				return err
+			}
+			if err := dynamicMessage.TryPutMapField(fieldDescriptor, key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case fieldDescriptor.IsRepeated():
+		for i := 0; i < n; i++ {
+			value, err := g.generateValue(fieldDescriptor, depth)
+			if err != nil {
+				return err
+			}
+			if err := dynamicMessage.TryAddRepeatedField(fieldDescriptor, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		value, err := g.generateValue(fieldDescriptor, depth)
+		if err != nil {
+			return err
+		}
+		return dynamicMessage.TrySetField(fieldDescriptor, value)
+	}
+}
+
+// generateValue generates a single value for the field, ignoring
+// whether or not the field is repeated.
+func (g *generator) generateValue(fieldDescriptor *desc.FieldDescriptor, depth int) (interface{}, error) {
+	switch fieldDescriptor.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return g.rand.NormFloat64() * math.MaxInt16, nil
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return float32(g.rand.NormFloat64() * math.MaxInt16), nil
+	case descriptor.FieldDescriptorProto_TYPE_INT64,
+		descriptor.FieldDescriptorProto_TYPE_SINT64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return g.rand.Int63() - g.rand.Int63(), nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT64,
+		descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return g.rand.Uint64(), nil
+	case descriptor.FieldDescriptorProto_TYPE_INT32,
+		descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return g.rand.Int31() - g.rand.Int31(), nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT32,
+		descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return g.rand.Uint32(), nil
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return g.rand.Intn(2) == 1, nil
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return g.generateString(), nil
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		value := make([]byte, g.rand.Intn(maxStringLen)+1)
+		_, _ = g.rand.Read(value)
+		return value, nil
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		values := fieldDescriptor.GetEnumType().GetValues()
+		if len(values) == 0 {
+			return nil, fmt.Errorf("no values for enum %s", fieldDescriptor.GetEnumType().GetFullyQualifiedName())
+		}
+		return values[g.rand.Intn(len(values))].GetNumber(), nil
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE,
+		descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return g.generateMessage(fieldDescriptor.GetMessageType(), depth+1)
+	default:
+		return nil, fmt.Errorf("unknown type for field %s: %v", fieldDescriptor.GetFullyQualifiedName(), fieldDescriptor.GetType())
+	}
+}
+
+func (g *generator) generateString() string {
+	value := make([]byte, g.rand.Intn(maxStringLen)+1)
+	for i := range value {
+		value[i] = stringRunes[g.rand.Intn(len(stringRunes))]
+	}
+	return string(value)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package decompile reconstructs Protobuf source files from
+// FileDescriptorSets, for when only compiled descriptors are available.
+package decompile
+
+import (
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/uber/prototool/internal/format"
+	"go.uber.org/zap"
+)
+
+// File is a decompiled file.
+type File struct {
+	// The path of the file, which is the name of the FileDescriptorProto.
+	// Will be relative.
+	Path string
+	// The data of the file.
+	Data []byte
+}
+
+// Decompiler decompiles FileDescriptorSets.
+type Decompiler interface {
+	// Decompile returns the source of each file in the FileDescriptorSet
+	// sorted by path, excluding the well-known types in google/protobuf.
+	//
+	// Comments are included if the FileDescriptorSet has source code info.
+	Decompile(fileDescriptorSet *descriptor.FileDescriptorSet) ([]*File, error)
+}
+
+// DecompilerOption is an option for a new Decompiler.
+type DecompilerOption func(*decompiler)
+
+// DecompilerWithLogger returns a DecompilerOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func DecompilerWithLogger(logger *zap.Logger) DecompilerOption {
+	return func(decompiler *decompiler) {
+		decompiler.logger = logger
+	}
+}
+
+// DecompilerWithTransformer returns a DecompilerOption that formats each
+// file with the given Transformer.
+//
+// If a file cannot be formatted, it is returned as printed from the
+// descriptors. The default is to not format files.
+func DecompilerWithTransformer(transformer format.Transformer) DecompilerOption {
+	return func(decompiler *decompiler) {
+		decompiler.transformer = transformer
+	}
+}
+
+// NewDecompiler returns a new Decompiler.
+func NewDecompiler(options ...DecompilerOption) Decompiler {
+	return newDecompiler(options...)
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package decompile
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/format"
+)
+
+const testSource = `syntax = "proto3";
+
+package foo.v1;
+
+import "google/protobuf/timestamp.proto";
+
+option go_package = "foov1";
+
+// Hello is a hello.
+message Hello {
+  // The name.
+  string name = 1;
+  google.protobuf.Timestamp time = 2;
+  map<string, int64> counts = 3;
+  reserved 4;
+}
+
+// HelloService says hello.
+service HelloService {
+  // Say says hello.
+  rpc Say(Hello) returns (stream Hello);
+}
+`
+
+func TestDecompile(t *testing.T) {
+	files, err := NewDecompiler(DecompilerWithTransformer(format.NewTransformer())).Decompile(newTestFileDescriptorSet(t, true))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "foo/v1/foo.proto", files[0].Path)
+	assert.Equal(t, `syntax = "proto3";
+
+package foo.v1;
+
+option go_package = "foov1";
+
+import "google/protobuf/timestamp.proto";
+
+// Hello is a hello.
+message Hello {
+  // The name.
+  string name = 1;
+  google.protobuf.Timestamp time = 2;
+  map<string, int64> counts = 3;
+  reserved 4;
+}
+
+// HelloService says hello.
+service HelloService {
+  // Say says hello.
+  rpc Say(Hello) returns (stream Hello);
+}
+`, string(files[0].Data))
+}
+
+func TestDecompileWithoutSourceInfo(t *testing.T) {
+	files, err := NewDecompiler(DecompilerWithTransformer(format.NewTransformer())).Decompile(newTestFileDescriptorSet(t, false))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, `syntax = "proto3";
+
+package foo.v1;
+
+option go_package = "foov1";
+
+import "google/protobuf/timestamp.proto";
+
+message Hello {
+  reserved 4;
+  string name = 1;
+  google.protobuf.Timestamp time = 2;
+  map<string, int64> counts = 3;
+}
+
+service HelloService {
+  rpc Say(Hello) returns (stream Hello);
+}
+`, string(files[0].Data))
+}
+
+func TestDecompileEmpty(t *testing.T) {
+	_, err := NewDecompiler().Decompile(&descriptor.FileDescriptorSet{})
+	assert.Error(t, err)
+}
+
+func newTestFileDescriptorSet(t *testing.T, includeSourceCodeInfo bool) *descriptor.FileDescriptorSet {
+	parser := protoparse.Parser{
+		IncludeSourceCodeInfo: includeSourceCodeInfo,
+		Accessor: func(filename string) (io.ReadCloser, error) {
+			if filename == "foo/v1/foo.proto" {
+				return ioutil.NopCloser(strings.NewReader(testSource)), nil
+			}
+			return nil, fmt.Errorf("unknown file %s", filename)
+		},
+	}
+	fileDescriptors, err := parser.ParseFiles("foo/v1/foo.proto")
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 1)
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	for _, dependency := range fileDescriptors[0].GetDependencies() {
+		fileDescriptorSet.File = append(fileDescriptorSet.File, dependency.AsFileDescriptorProto())
+	}
+	fileDescriptorSet.File = append(fileDescriptorSet.File, fileDescriptors[0].AsFileDescriptorProto())
+	return fileDescriptorSet
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package decompile
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoprint"
+	"github.com/uber/prototool/internal/format"
+	"go.uber.org/zap"
+)
+
+const wktPrefix = "google/protobuf/"
+
+type decompiler struct {
+	logger      *zap.Logger
+	transformer format.Transformer
+}
+
+func newDecompiler(options ...DecompilerOption) *decompiler {
+	decompiler := &decompiler{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(decompiler)
+	}
+	return decompiler
+}
+
+func (d *decompiler) Decompile(fileDescriptorSet *descriptor.FileDescriptorSet) ([]*File, error) {
+	if len(fileDescriptorSet.File) == 0 {
+		return nil, fmt.Errorf("no FileDescriptorProtos in FileDescriptorSet")
+	}
+	nameToFileDescriptor, err := desc.CreateFileDescriptors(fileDescriptorSet.File)
+	if err != nil {
+		return nil, err
+	}
+	printer := &protoprint.Printer{}
+	var files []*File
+	for name, fileDescriptor := range nameToFileDescriptor {
+		if strings.HasPrefix(name, wktPrefix) {
+			continue
+		}
+		buffer := bytes.NewBuffer(nil)
+		if err := printer.PrintProtoFile(fileDescriptor, buffer); err != nil {
+			return nil, fmt.Errorf("could not print %s: %v", name, err)
+		}
+		files = append(files, &File{
+			Path: name,
+			Data: d.format(name, buffer.Bytes()),
+		})
+	}
+	sort.Slice(files, func(i int, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// format formats the data with the transformer if there is one,
+// returning the data as is if it cannot be formatted.
+func (d *decompiler) format(name string, data []byte) []byte {
+	if d.transformer == nil {
+		return data
+	}
+	formatted, failures, err := d.transformer.Transform(name, data)
+	if err != nil {
+		d.logger.Warn("could not format decompiled file", zap.String("file", name), zap.Error(err))
+		return data
+	}
+	if len(failures) > 0 {
+		d.logger.Warn("could not format decompiled file", zap.String("file", name), zap.String("failure", failures[0].String()))
+		return data
+	}
+	return formatted
+}
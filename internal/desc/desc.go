@@ -23,7 +23,10 @@ package desc
 import (
 	"fmt"
 
+	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	reflectdesc "github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
 )
 
 // SortFileDescriptorSet sorts a FileDescriptorSet for github.com/jhump/protoreflect
@@ -54,3 +57,45 @@ func SortFileDescriptorSet(fileDescriptorSet *descriptor.FileDescriptorSet, file
 	newFileDescriptorSet.File = append(newFileDescriptorSet.File, fileDescriptorProto)
 	return newFileDescriptorSet, nil
 }
+
+// NewAnyResolver returns a new jsonpb.AnyResolver that resolves the message
+// types in all of the given FileDescriptorSets, so that google.protobuf.Any
+// values can be marshalled to and from JSON with an @type field.
+func NewAnyResolver(fileDescriptorSets ...*descriptor.FileDescriptorSet) (jsonpb.AnyResolver, error) {
+	fileDescriptors, err := CreateFileDescriptors(fileDescriptorSets...)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.AnyResolver(nil, fileDescriptors...), nil
+}
+
+// CreateFileDescriptors creates a github.com/jhump/protoreflect FileDescriptor
+// for every FileDescriptorProto in the given FileDescriptorSets.
+//
+// FileDescriptorProtos that appear in more than one FileDescriptorSet are
+// only used once, and the first FileDescriptorProto with a given name is used.
+func CreateFileDescriptors(fileDescriptorSets ...*descriptor.FileDescriptorSet) ([]*reflectdesc.FileDescriptor, error) {
+	names := make(map[string]struct{})
+	var fileDescriptorProtos []*descriptor.FileDescriptorProto
+	for _, fileDescriptorSet := range fileDescriptorSets {
+		for _, fileDescriptorProto := range fileDescriptorSet.File {
+			if fileDescriptorProto.GetName() == "" {
+				return nil, fmt.Errorf("no name on FileDescriptorProto")
+			}
+			if _, ok := names[fileDescriptorProto.GetName()]; ok {
+				continue
+			}
+			names[fileDescriptorProto.GetName()] = struct{}{}
+			fileDescriptorProtos = append(fileDescriptorProtos, fileDescriptorProto)
+		}
+	}
+	fileDescriptorMap, err := reflectdesc.CreateFileDescriptors(fileDescriptorProtos)
+	if err != nil {
+		return nil, err
+	}
+	fileDescriptors := make([]*reflectdesc.FileDescriptor, 0, len(fileDescriptorMap))
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		fileDescriptors = append(fileDescriptors, fileDescriptorMap[fileDescriptorProto.GetName()])
+	}
+	return fileDescriptors, nil
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package desc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// the field number of proto3_optional in FieldDescriptorProto
+const proto3OptionalFieldNumber = 17
+
+// IsProto3Optional returns true if the field is an optional field in a proto3 file.
+//
+// The version of github.com/golang/protobuf that is used does not know about
+// the proto3_optional field of FieldDescriptorProto, so it is read from the
+// unrecognized fields.
+func IsProto3Optional(field *descriptor.FieldDescriptorProto) bool {
+	buffer := proto.NewBuffer(field.XXX_unrecognized)
+	for {
+		key, err := buffer.DecodeVarint()
+		if err != nil {
+			return false
+		}
+		fieldNumber, wireType := key>>3, key&7
+		switch wireType {
+		case proto.WireVarint:
+			value, err := buffer.DecodeVarint()
+			if err != nil {
+				return false
+			}
+			if fieldNumber == proto3OptionalFieldNumber {
+				return value != 0
+			}
+		case proto.WireFixed64:
+			if _, err := buffer.DecodeFixed64(); err != nil {
+				return false
+			}
+		case proto.WireBytes:
+			if _, err := buffer.DecodeRawBytes(false); err != nil {
+				return false
+			}
+		case proto.WireFixed32:
+			if _, err := buffer.DecodeFixed32(); err != nil {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+}
+
+// MarshalJSON marshals the DescriptorProto or FieldDescriptorProto to JSON
+// with the marshaler, adding "proto3Optional": true to the optional fields
+// in proto3 files, which the marshaler does not know about.
+//
+// Other messages are marshalled as is.
+func MarshalJSON(marshaler *jsonpb.Marshaler, message proto.Message) (string, error) {
+	s, err := marshaler.MarshalToString(message)
+	if err != nil {
+		return "", err
+	}
+	var add func(*jsonObject)
+	switch m := message.(type) {
+	case *descriptor.DescriptorProto:
+		if !hasProto3Optional(m) {
+			return s, nil
+		}
+		add = func(object *jsonObject) { addMessageProto3Optional(object, m) }
+	case *descriptor.FieldDescriptorProto:
+		if !IsProto3Optional(m) {
+			return s, nil
+		}
+		add = func(object *jsonObject) { object.set("proto3Optional", true) }
+	default:
+		return s, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	value, err := decodeJSON(decoder)
+	if err != nil {
+		return "", err
+	}
+	object, ok := value.(*jsonObject)
+	if !ok {
+		return s, nil
+	}
+	add(object)
+	data, err := object.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	if marshaler.Indent == "" {
+		return string(data), nil
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := json.Indent(buffer, data, "", marshaler.Indent); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+func hasProto3Optional(message *descriptor.DescriptorProto) bool {
+	for _, field := range append(append([]*descriptor.FieldDescriptorProto{}, message.Field...), message.Extension...) {
+		if IsProto3Optional(field) {
+			return true
+		}
+	}
+	for _, nestedMessage := range message.NestedType {
+		if hasProto3Optional(nestedMessage) {
+			return true
+		}
+	}
+	return false
+}
+
+func addMessageProto3Optional(object *jsonObject, message *descriptor.DescriptorProto) {
+	for key, fields := range map[string][]*descriptor.FieldDescriptorProto{
+		"field":     message.Field,
+		"extension": message.Extension,
+	} {
+		values, _ := object.values[key].([]interface{})
+		// the values are in the same order as the fields
+		for i := 0; i < len(values) && i < len(fields); i++ {
+			if fieldObject, ok := values[i].(*jsonObject); ok && IsProto3Optional(fields[i]) {
+				fieldObject.set("proto3Optional", true)
+			}
+		}
+	}
+	values, _ := object.values["nestedType"].([]interface{})
+	for i := 0; i < len(values) && i < len(message.NestedType); i++ {
+		if nestedObject, ok := values[i].(*jsonObject); ok {
+			addMessageProto3Optional(nestedObject, message.NestedType[i])
+		}
+	}
+}
+
+// jsonObject is a JSON object that keeps the order of its keys.
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *jsonObject) set(key string, value interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *jsonObject) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	buffer.WriteString("{")
+	for i, key := range o.keys {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		if err := encodeJSON(buffer, key); err != nil {
+			return nil, err
+		}
+		buffer.WriteString(":")
+		if err := encodeJSON(buffer, o.values[key]); err != nil {
+			return nil, err
+		}
+	}
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+// encodeJSON encodes the value without escaping HTML characters, as jsonpb does.
+func encodeJSON(buffer *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case *jsonObject:
+		data, err := v.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		_, _ = buffer.Write(data)
+		return nil
+	case []interface{}:
+		buffer.WriteString("[")
+		for i, element := range v {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			if err := encodeJSON(buffer, element); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString("]")
+		return nil
+	default:
+		encoder := json.NewEncoder(buffer)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		// Encode adds a newline
+		buffer.Truncate(buffer.Len() - 1)
+		return nil
+	}
+}
+
+func decodeJSON(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+	switch delim {
+	case '{':
+		object := &jsonObject{values: make(map[string]interface{})}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := keyToken.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a JSON object key but got %v", keyToken)
+			}
+			value, err := decodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object.set(key, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return object, nil
+	case '[':
+		array := make([]interface{}, 0)
+		for decoder.More() {
+			value, err := decodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return array, nil
+	default:
+		return nil, fmt.Errorf("unexpected JSON delimiter %v", delim)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package desc
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsProto3Optional(t *testing.T) {
+	assert.False(t, IsProto3Optional(&descriptor.FieldDescriptorProto{}))
+	assert.True(t, IsProto3Optional(newProto3OptionalField("one")))
+	assert.False(t, IsProto3Optional(&descriptor.FieldDescriptorProto{
+		XXX_unrecognized: []byte{0x88, 0x01, 0x00},
+	}))
+}
+
+func TestMarshalJSON(t *testing.T) {
+	marshaler := &jsonpb.Marshaler{}
+	s, err := MarshalJSON(marshaler, &descriptor.DescriptorProto{
+		Name: proto.String("Foo"),
+		Field: []*descriptor.FieldDescriptorProto{
+			{
+				Name: proto.String("two"),
+			},
+			newProto3OptionalField("one"),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"Foo","field":[{"name":"two"},{"name":"one","proto3Optional":true}]}`, s)
+
+	s, err = MarshalJSON(marshaler, newProto3OptionalField("one"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"one","proto3Optional":true}`, s)
+
+	s, err = MarshalJSON(marshaler, &descriptor.FieldDescriptorProto{Name: proto.String("two")})
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"two"}`, s)
+}
+
+func newProto3OptionalField(name string) *descriptor.FieldDescriptorProto {
+	return &descriptor.FieldDescriptorProto{
+		Name:             proto.String(name),
+		XXX_unrecognized: []byte{0x88, 0x01, 0x01},
+	}
+}
@@ -33,10 +33,13 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+
+	"github.com/uber/prototool/internal/exectrace"
 )
 
-// Do does a diff between an input and output.
-func Do(input []byte, output []byte, filename string) ([]byte, error) {
+// Do does a diff between an input and output, tracing the diff process
+// with the given tracer.
+func Do(execTracer exectrace.Tracer, input []byte, output []byte, filename string) ([]byte, error) {
 	f1, err := writeTempFile("", "prototool-diff", input)
 	if err != nil {
 		return nil, err
@@ -54,7 +57,10 @@ func Do(input []byte, output []byte, filename string) ([]byte, error) {
 		cmd = "/bin/ape/diff"
 	}
 
-	data, err := exec.Command(cmd, "-u", f1, f2).CombinedOutput()
+	execCmd := exec.Command(cmd, "-u", f1, f2)
+	finish := execTracer.Start(execCmd)
+	data, err := execCmd.CombinedOutput()
+	finish(err)
 	if len(data) > 0 {
 		// diff exits with a non-zero status when the files don't match.
 		// Ignore that failure as long as we get output.
@@ -63,6 +69,25 @@ func Do(input []byte, output []byte, filename string) ([]byte, error) {
 	return nil, err
 }
 
+// CountLines returns the number of lines inserted and deleted in a diff
+// returned by Do.
+func CountLines(diff []byte) (insertions int, deletions int) {
+	inHunk := false
+	for _, line := range bytes.Split(diff, []byte{'\n'}) {
+		switch {
+		case bytes.HasPrefix(line, []byte("@@")):
+			inHunk = true
+		case !inHunk:
+			// the --- and +++ header lines
+		case bytes.HasPrefix(line, []byte("+")):
+			insertions++
+		case bytes.HasPrefix(line, []byte("-")):
+			deletions++
+		}
+	}
+	return insertions, deletions
+}
+
 func writeTempFile(dir, prefix string, data []byte) (string, error) {
 	file, err := ioutil.TempFile(dir, prefix)
 	if err != nil {
@@ -29,6 +29,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/exectrace"
 )
 
 func TestDo(t *testing.T) {
@@ -39,6 +41,17 @@ func TestDo(t *testing.T) {
 	testDo(t, "abc\nabc", "abc\nabc", nil)
 }
 
+func TestCountLines(t *testing.T) {
+	diff, err := Do(exectrace.NewNopTracer(), []byte("a\n-b\nc\nd\n"), []byte("a\n--- b\nc\n+ e\nf\n"), "foo.proto")
+	require.NoError(t, err)
+	insertions, deletions := CountLines(diff)
+	assert.Equal(t, 3, insertions)
+	assert.Equal(t, 2, deletions)
+	insertions, deletions = CountLines(nil)
+	assert.Equal(t, 0, insertions)
+	assert.Equal(t, 0, deletions)
+}
+
 func testDo(
 	t *testing.T,
 	input string,
@@ -46,7 +59,7 @@ func testDo(
 	expectedError error,
 	expectedDiffs ...string,
 ) {
-	diff, err := Do([]byte(input), []byte(output), "")
+	diff, err := Do(exectrace.NewNopTracer(), []byte(input), []byte(output), "")
 	for _, expectedDiff := range expectedDiffs {
 		assert.Contains(t, string(diff), expectedDiff, "diff does not contain %s", expectedDiff)
 	}
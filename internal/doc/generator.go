@@ -0,0 +1,507 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/editions"
+	"github.com/uber/prototool/internal/file"
+	"go.uber.org/zap"
+)
+
+var (
+	templateFuncs = map[string]interface{}{
+		"link":      markdownLink,
+		"tableCell": markdownTableCell,
+	}
+
+	markdownTemplate = template.Must(template.New("markdown").Funcs(templateFuncs).Parse(`# API Documentation
+
+## Table of Contents
+{{range .}}
+- [{{.Name}}](#{{.Name}})
+{{- range .Messages}}
+  - [{{.FullName}}](#{{.FullName}})
+{{- end}}
+{{- range .Enums}}
+  - [{{.FullName}}](#{{.FullName}})
+{{- end}}
+{{- range .Services}}
+  - [{{.FullName}}](#{{.FullName}})
+{{- end}}
+{{- end}}
+{{range .}}
+<a name="{{.Name}}"></a>
+
+## {{.Name}}
+{{if .Package}}
+Package: ` + "`{{.Package}}`" + `
+{{end}}{{if .Description}}
+{{.Description}}
+{{end}}{{range .Messages}}
+<a name="{{.FullName}}"></a>
+
+### {{.FullName}}
+{{if .Description}}
+{{.Description}}
+{{end}}{{if .Fields}}
+| Field | Type | Label | Number | Description |
+| ----- | ---- | ----- | ------ | ----------- |
+{{range .Fields}}| {{.Name}} | {{link .Type .TypeAnchor}} | {{.Label}} | {{.Number}} | {{tableCell .Description}} |
+{{end}}{{end}}{{end}}{{range .Enums}}
+<a name="{{.FullName}}"></a>
+
+### {{.FullName}}
+{{if .Description}}
+{{.Description}}
+{{end}}{{if .Values}}
+| Name | Number | Description |
+| ---- | ------ | ----------- |
+{{range .Values}}| {{.Name}} | {{.Number}} | {{tableCell .Description}} |
+{{end}}{{end}}{{end}}{{range .Services}}
+<a name="{{.FullName}}"></a>
+
+### {{.FullName}}
+{{if .Description}}
+{{.Description}}
+{{end}}{{if .Methods}}
+| Method | Request | Response | Description |
+| ------ | ------- | -------- | ----------- |
+{{range .Methods}}| {{.Name}} | {{if .RequestStreaming}}stream {{end}}{{link .RequestType .RequestAnchor}} | {{if .ResponseStreaming}}stream {{end}}{{link .ResponseType .ResponseAnchor}} | {{tableCell .Description}} |
+{{end}}{{end}}{{end}}{{end}}`))
+
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API Documentation</title>
+</head>
+<body>
+<h1>API Documentation</h1>
+<h2>Table of Contents</h2>
+<ul>
+{{- range .}}
+<li><a href="#{{.Name}}">{{.Name}}</a>
+<ul>
+{{- range .Messages}}
+<li><a href="#{{.FullName}}">{{.FullName}}</a></li>
+{{- end}}
+{{- range .Enums}}
+<li><a href="#{{.FullName}}">{{.FullName}}</a></li>
+{{- end}}
+{{- range .Services}}
+<li><a href="#{{.FullName}}">{{.FullName}}</a></li>
+{{- end}}
+</ul>
+</li>
+{{- end}}
+</ul>
+{{- range .}}
+<h2 id="{{.Name}}">{{.Name}}</h2>
+{{- if .Package}}
+<p>Package: <code>{{.Package}}</code></p>
+{{- end}}
+{{- if .Description}}
+<p>{{.Description}}</p>
+{{- end}}
+{{- range .Messages}}
+<h3 id="{{.FullName}}">{{.FullName}}</h3>
+{{- if .Description}}
+<p>{{.Description}}</p>
+{{- end}}
+{{- if .Fields}}
+<table>
+<tr><th>Field</th><th>Type</th><th>Label</th><th>Number</th><th>Description</th></tr>
+{{- range .Fields}}
+<tr><td>{{.Name}}</td><td>{{if .TypeAnchor}}<a href="#{{.TypeAnchor}}">{{.Type}}</a>{{else}}{{.Type}}{{end}}</td><td>{{.Label}}</td><td>{{.Number}}</td><td>{{.Description}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- range .Enums}}
+<h3 id="{{.FullName}}">{{.FullName}}</h3>
+{{- if .Description}}
+<p>{{.Description}}</p>
+{{- end}}
+{{- if .Values}}
+<table>
+<tr><th>Name</th><th>Number</th><th>Description</th></tr>
+{{- range .Values}}
+<tr><td>{{.Name}}</td><td>{{.Number}}</td><td>{{.Description}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- range .Services}}
+<h3 id="{{.FullName}}">{{.FullName}}</h3>
+{{- if .Description}}
+<p>{{.Description}}</p>
+{{- end}}
+{{- if .Methods}}
+<table>
+<tr><th>Method</th><th>Request</th><th>Response</th><th>Description</th></tr>
+{{- range .Methods}}
+<tr><td>{{.Name}}</td><td>{{if .RequestStreaming}}stream {{end}}{{if .RequestAnchor}}<a href="#{{.RequestAnchor}}">{{.RequestType}}</a>{{else}}{{.RequestType}}{{end}}</td><td>{{if .ResponseStreaming}}stream {{end}}{{if .ResponseAnchor}}<a href="#{{.ResponseAnchor}}">{{.ResponseType}}</a>{{else}}{{.ResponseType}}{{end}}</td><td>{{.Description}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+)
+
+type generator struct {
+	logger *zap.Logger
+}
+
+func newGenerator(options ...GeneratorOption) *generator {
+	generator := &generator{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(generator)
+	}
+	return generator
+}
+
+func (g *generator) Generate(protoSet *file.ProtoSet) (*File, error) {
+	config := protoSet.Config.Doc
+	if config.OutputPath == "" {
+		return nil, nil
+	}
+	var protoFiles []*file.ProtoFile
+	for _, dirProtoFiles := range protoSet.DirPathToFiles {
+		protoFiles = append(protoFiles, dirProtoFiles...)
+	}
+	sort.Slice(protoFiles, func(i int, j int) bool { return protoFiles[i].Path < protoFiles[j].Path })
+
+	names := make([]string, len(protoFiles))
+	descriptors := make([]*proto.Proto, len(protoFiles))
+	index := make(typeIndex)
+	for i, protoFile := range protoFiles {
+		name, err := file.ImportPath(protoSet, protoFile.Path)
+		if err != nil {
+			return nil, err
+		}
+		names[i] = name
+		descriptor, err := parse(protoFile)
+		if err != nil {
+			return nil, err
+		}
+		descriptors[i] = descriptor
+		index.add(getPackage(descriptor), descriptor.Elements)
+	}
+	docFiles := make([]*docFile, len(descriptors))
+	for i, descriptor := range descriptors {
+		docFiles[i] = newDocFile(names[i], descriptor, index)
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	switch config.Format {
+	case "", FormatMarkdown:
+		if err := markdownTemplate.Execute(buffer, docFiles); err != nil {
+			return nil, err
+		}
+	case FormatHTML:
+		if err := htmlTemplate.Execute(buffer, docFiles); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown doc format: %s", config.Format)
+	}
+	g.logger.Debug("generated documentation", zap.String("path", config.OutputPath), zap.Int("numFiles", len(docFiles)))
+	return &File{
+		Path: config.OutputPath,
+		Data: buffer.Bytes(),
+	}, nil
+}
+
+type docFile struct {
+	Name        string
+	Package     string
+	Description string
+	Messages    []*docMessage
+	Enums       []*docEnum
+	Services    []*docService
+}
+
+type docMessage struct {
+	FullName    string
+	Description string
+	Fields      []*docField
+}
+
+type docField struct {
+	Name        string
+	Type        string
+	TypeAnchor  string
+	Label       string
+	Number      int
+	Description string
+}
+
+type docEnum struct {
+	FullName    string
+	Description string
+	Values      []*docEnumValue
+}
+
+type docEnumValue struct {
+	Name        string
+	Number      int
+	Description string
+}
+
+type docService struct {
+	FullName    string
+	Description string
+	Methods     []*docMethod
+}
+
+type docMethod struct {
+	Name              string
+	RequestType       string
+	RequestAnchor     string
+	RequestStreaming  bool
+	ResponseType      string
+	ResponseAnchor    string
+	ResponseStreaming bool
+	Description       string
+}
+
+func newDocFile(name string, descriptor *proto.Proto, index typeIndex) *docFile {
+	docFile := &docFile{
+		Name:    name,
+		Package: getPackage(descriptor),
+	}
+	for _, element := range descriptor.Elements {
+		switch e := element.(type) {
+		case *proto.Package:
+			docFile.Description = getDescription(e.Comment, nil)
+		case *proto.Message:
+			docFile.addMessage(docFile.Package, e, index)
+		case *proto.Enum:
+			docFile.addEnum(docFile.Package, e)
+		case *proto.Service:
+			docFile.addService(e, index)
+		}
+	}
+	return docFile
+}
+
+func (f *docFile) addMessage(scope string, message *proto.Message, index typeIndex) {
+	fullName := joinName(scope, message.Name)
+	docMessage := &docMessage{
+		FullName:    fullName,
+		Description: getDescription(message.Comment, nil),
+	}
+	f.Messages = append(f.Messages, docMessage)
+	for _, element := range message.Elements {
+		switch e := element.(type) {
+		case *proto.NormalField:
+			label := ""
+			switch {
+			case e.Repeated:
+				label = "repeated"
+			case e.Optional:
+				label = "optional"
+			case e.Required:
+				label = "required"
+			}
+			docMessage.Fields = append(docMessage.Fields, newDocField(fullName, e.Field, e.Type, e.Type, label, index))
+		case *proto.MapField:
+			docMessage.Fields = append(docMessage.Fields, newDocField(fullName, e.Field, fmt.Sprintf("map<%s, %s>", e.KeyType, e.Type), e.Type, "", index))
+		case *proto.Oneof:
+			for _, oneofElement := range e.Elements {
+				if field, ok := oneofElement.(*proto.OneOfField); ok {
+					docMessage.Fields = append(docMessage.Fields, newDocField(fullName, field.Field, field.Type, field.Type, "oneof "+e.Name, index))
+				}
+			}
+		case *proto.Message:
+			f.addMessage(fullName, e, index)
+		case *proto.Enum:
+			f.addEnum(fullName, e)
+		}
+	}
+}
+
+func (f *docFile) addEnum(scope string, enum *proto.Enum) {
+	docEnum := &docEnum{
+		FullName:    joinName(scope, enum.Name),
+		Description: getDescription(enum.Comment, nil),
+	}
+	for _, element := range enum.Elements {
+		if enumField, ok := element.(*proto.EnumField); ok {
+			docEnum.Values = append(docEnum.Values, &docEnumValue{
+				Name:        enumField.Name,
+				Number:      enumField.Integer,
+				Description: getDescription(enumField.Comment, enumField.InlineComment),
+			})
+		}
+	}
+	f.Enums = append(f.Enums, docEnum)
+}
+
+func (f *docFile) addService(service *proto.Service, index typeIndex) {
+	docService := &docService{
+		FullName:    joinName(f.Package, service.Name),
+		Description: getDescription(service.Comment, nil),
+	}
+	for _, element := range service.Elements {
+		if rpc, ok := element.(*proto.RPC); ok {
+			docService.Methods = append(docService.Methods, &docMethod{
+				Name:              rpc.Name,
+				RequestType:       rpc.RequestType,
+				RequestAnchor:     index.resolve(f.Package, rpc.RequestType),
+				RequestStreaming:  rpc.StreamsRequest,
+				ResponseType:      rpc.ReturnsType,
+				ResponseAnchor:    index.resolve(f.Package, rpc.ReturnsType),
+				ResponseStreaming: rpc.StreamsReturns,
+				Description:       getDescription(rpc.Comment, rpc.InlineComment),
+			})
+		}
+	}
+	f.Services = append(f.Services, docService)
+}
+
+func newDocField(scope string, field *proto.Field, displayType string, linkType string, label string, index typeIndex) *docField {
+	return &docField{
+		Name:        field.Name,
+		Type:        displayType,
+		TypeAnchor:  index.resolve(scope, linkType),
+		Label:       label,
+		Number:      field.Sequence,
+		Description: getDescription(field.Comment, field.InlineComment),
+	}
+}
+
+// typeIndex is the set of the full names of all messages and enums.
+//
+// The full name of a type is used as its anchor.
+type typeIndex map[string]struct{}
+
+func (t typeIndex) add(scope string, elements []proto.Visitee) {
+	for _, element := range elements {
+		switch e := element.(type) {
+		case *proto.Message:
+			fullName := joinName(scope, e.Name)
+			t[fullName] = struct{}{}
+			t.add(fullName, e.Elements)
+		case *proto.Enum:
+			t[joinName(scope, e.Name)] = struct{}{}
+		}
+	}
+}
+
+// resolve returns the full name of typeName referenced from within scope,
+// following the Protobuf scoping rules, or "" if the type is not known,
+// for example if typeName is a scalar type or in a file not in the ProtoSet.
+func (t typeIndex) resolve(scope string, typeName string) string {
+	if strings.HasPrefix(typeName, ".") {
+		if _, ok := t[typeName[1:]]; ok {
+			return typeName[1:]
+		}
+		return ""
+	}
+	for {
+		candidate := joinName(scope, typeName)
+		if _, ok := t[candidate]; ok {
+			return candidate
+		}
+		if scope == "" {
+			return ""
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+func getPackage(descriptor *proto.Proto) string {
+	for _, element := range descriptor.Elements {
+		if pkg, ok := element.(*proto.Package); ok {
+			return pkg.Name
+		}
+	}
+	return ""
+}
+
+// getDescription returns the text of the comment, or of the inline
+// comment if the comment is not set.
+func getDescription(comment *proto.Comment, inlineComment *proto.Comment) string {
+	if comment == nil {
+		comment = inlineComment
+	}
+	if comment == nil {
+		return ""
+	}
+	lines := make([]string, len(comment.Lines))
+	for i, line := range comment.Lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func joinName(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func markdownLink(name string, anchor string) string {
+	// map types would otherwise be interpreted as HTML
+	name = strings.NewReplacer("<", "&lt;", ">", "&gt;").Replace(name)
+	if anchor == "" {
+		return name
+	}
+	return fmt.Sprintf("[%s](#%s)", name, anchor)
+}
+
+func markdownTableCell(s string) string {
+	return strings.Replace(strings.Replace(s, "|", `\|`, -1), "\n", " ", -1)
+}
+
+func parse(protoFile *file.ProtoFile) (*proto.Proto, error) {
+	file, err := os.Open(protoFile.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	parser, err := editions.NewParser(file)
+	if err != nil {
+		return nil, err
+	}
+	parser.Filename(protoFile.DisplayPath)
+	return parser.Parse()
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package doc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/file"
+)
+
+func TestGenerateMarkdown(t *testing.T) {
+	protoSet := getTestProtoSet(t, "testdata/api")
+	assertGolden(t, protoSet, "testdata/api/api.md")
+}
+
+func TestGenerateHTML(t *testing.T) {
+	protoSet := getTestProtoSet(t, "testdata/api")
+	protoSet.Config.Doc.OutputPath = filepath.Join(protoSet.Config.DirPath, "api.html")
+	protoSet.Config.Doc.Format = FormatHTML
+	assertGolden(t, protoSet, "testdata/api/api.html")
+}
+
+func TestGenerateNoOutput(t *testing.T) {
+	protoSet := getTestProtoSet(t, "testdata/api")
+	protoSet.Config.Doc.OutputPath = ""
+	docFile, err := newGenerator().Generate(protoSet)
+	require.NoError(t, err)
+	assert.Nil(t, docFile)
+}
+
+func TestGenerateUnknownFormat(t *testing.T) {
+	protoSet := getTestProtoSet(t, "testdata/api")
+	protoSet.Config.Doc.Format = "pdf"
+	_, err := newGenerator().Generate(protoSet)
+	assert.EqualError(t, err, "unknown doc format: pdf")
+}
+
+func assertGolden(t *testing.T, protoSet *file.ProtoSet, expectedPath string) {
+	docFile, err := newGenerator().Generate(protoSet)
+	require.NoError(t, err)
+	require.NotNil(t, docFile)
+	absExpectedPath, err := filepath.Abs(expectedPath)
+	require.NoError(t, err)
+	assert.Equal(t, absExpectedPath, docFile.Path)
+	golden, err := ioutil.ReadFile(docFile.Path + ".golden")
+	require.NoError(t, err)
+	assert.Equal(t, string(golden), string(docFile.Data), docFile.Path)
+}
+
+func getTestProtoSet(t *testing.T, dirPath string) *file.ProtoSet {
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	absDirPath, err := filepath.Abs(dirPath)
+	require.NoError(t, err)
+	protoSet, err := file.NewProtoSetProvider().GetForDir(cwd, absDirPath)
+	require.NoError(t, err)
+	return protoSet
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package editions handles Protobuf Editions files, which declare an
+// edition such as edition = "2023"; instead of a syntax.
+package editions
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/emicklei/proto"
+)
+
+// DefaultEdition is the edition that files are migrated to by default.
+const DefaultEdition = "2023"
+
+var (
+	// SupportedEditions are the supported editions.
+	SupportedEditions = []string{
+		"2023",
+	}
+
+	// matches the start of an edition declaration, keeping the whitespace
+	// before and after the edition keyword
+	editionDeclarationRegexp = regexp.MustCompile(`(?m)^(\s*)edition(\s*=)`)
+)
+
+// NewParser returns a new parser for the .proto file read from the reader.
+//
+// The parser from github.com/emicklei/proto does not know about editions,
+// so an edition declaration is parsed as a syntax declaration with the
+// edition as the value. The positions of all elements stay the same.
+// Use Edition to get the edition of a parsed file.
+func NewParser(reader io.Reader) (*proto.Parser, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if loc := editionDeclarationRegexp.FindSubmatchIndex(data); loc != nil {
+		// "syntax " is the same length as "edition"
+		data = append(append(append([]byte{}, data[:loc[3]]...), []byte("syntax ")...), data[loc[4]:]...)
+	}
+	return proto.NewParser(bytes.NewReader(data)), nil
+}
+
+// Edition returns the edition of the file parsed with NewParser, or
+// empty if the file declares a syntax instead.
+func Edition(descriptor *proto.Proto) string {
+	for _, element := range descriptor.Elements {
+		if syntax, ok := element.(*proto.Syntax); ok && IsEdition(syntax.Value) {
+			return syntax.Value
+		}
+	}
+	return ""
+}
+
+// IsEdition returns true if the value of a syntax declaration parsed
+// with NewParser is an edition.
+func IsEdition(syntaxValue string) bool {
+	return syntaxValue != "" && !strings.HasPrefix(syntaxValue, "proto")
+}
+
+// IsSupported returns true if the edition is supported.
+func IsSupported(edition string) bool {
+	for _, supportedEdition := range SupportedEditions {
+		if edition == supportedEdition {
+			return true
+		}
+	}
+	return false
+}
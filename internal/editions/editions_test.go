@@ -0,0 +1,98 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package editions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emicklei/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewParser(t *testing.T) {
+	descriptor := parse(t, `// Comment.
+edition = "2023";
+
+package foo;
+
+option features.field_presence = IMPLICIT;
+
+message Foo {
+  int64 one = 1 [features.field_presence = EXPLICIT];
+}
+`)
+	assert.Equal(t, "2023", Edition(descriptor))
+	syntax := descriptor.Elements[0].(*proto.Syntax)
+	assert.Equal(t, 2, syntax.Position.Line)
+	assert.Equal(t, 1, syntax.Position.Column)
+	message := descriptor.Elements[3].(*proto.Message)
+	assert.Equal(t, 8, message.Position.Line)
+
+	assert.Equal(t, "", Edition(parse(t, `syntax = "proto3";`)))
+}
+
+func TestMigrate(t *testing.T) {
+	descriptor := parse(t, `syntax = "proto3";
+
+package foo;
+
+message Foo {
+  message Bar {
+    optional int64 one = 1;
+  }
+  optional int64 one = 1 [deprecated = true];
+  int64 two = 2;
+}
+`)
+	require.NoError(t, Migrate(descriptor, "2023"))
+	assert.Equal(t, "2023", Edition(descriptor))
+	option := descriptor.Elements[len(descriptor.Elements)-1].(*proto.Option)
+	assert.Equal(t, "features.field_presence", option.Name)
+	assert.Equal(t, "IMPLICIT", option.Constant.Source)
+	message := descriptor.Elements[2].(*proto.Message)
+	nestedField := message.Elements[0].(*proto.Message).Elements[0].(*proto.NormalField)
+	assert.False(t, nestedField.Optional)
+	require.Len(t, nestedField.Options, 1)
+	assert.Equal(t, "EXPLICIT", nestedField.Options[0].Constant.Source)
+	field := message.Elements[1].(*proto.NormalField)
+	assert.False(t, field.Optional)
+	require.Len(t, field.Options, 2)
+	assert.Equal(t, "features.field_presence", field.Options[1].Name)
+	assert.Empty(t, message.Elements[2].(*proto.NormalField).Options)
+
+	// already migrated
+	numElements := len(descriptor.Elements)
+	require.NoError(t, Migrate(descriptor, "2023"))
+	assert.Len(t, descriptor.Elements, numElements)
+
+	assert.Error(t, Migrate(parse(t, `syntax = "proto2";`), "2023"))
+	assert.Error(t, Migrate(parse(t, `syntax = "proto3";`), "2020"))
+}
+
+func parse(t *testing.T, data string) *proto.Proto {
+	parser, err := NewParser(strings.NewReader(data))
+	require.NoError(t, err)
+	descriptor, err := parser.Parse()
+	require.NoError(t, err)
+	return descriptor
+}
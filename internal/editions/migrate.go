@@ -0,0 +1,88 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package editions
+
+import (
+	"fmt"
+
+	"github.com/emicklei/proto"
+)
+
+// Migrate converts the proto3 file parsed with NewParser to the given
+// edition in place, so that it has the same semantics as before.
+//
+// Fields in proto3 have implicit presence unless they are optional,
+// while in editions they have explicit presence by default, so the
+// file gets the feature field_presence set to IMPLICIT, and optional
+// fields get the feature field_presence set to EXPLICIT. The other
+// defaults of proto3 are the same as the defaults of edition 2023.
+//
+// Files that are already in the given edition are left unchanged.
+func Migrate(descriptor *proto.Proto, edition string) error {
+	if !IsSupported(edition) {
+		return fmt.Errorf("unsupported edition %q, supported editions are %v", edition, SupportedEditions)
+	}
+	var syntax *proto.Syntax
+	for _, element := range descriptor.Elements {
+		if s, ok := element.(*proto.Syntax); ok {
+			syntax = s
+		}
+	}
+	if syntax == nil {
+		return fmt.Errorf("%s: no syntax declaration found, only proto3 files can be migrated to editions", descriptor.Filename)
+	}
+	if syntax.Value == edition {
+		return nil
+	}
+	if syntax.Value != "proto3" {
+		return fmt.Errorf("%s: syntax is %q, only proto3 files can be migrated to editions", descriptor.Filename, syntax.Value)
+	}
+	syntax.Value = edition
+	for _, element := range descriptor.Elements {
+		if message, ok := element.(*proto.Message); ok {
+			migrateMessage(message)
+		}
+	}
+	descriptor.Elements = append(descriptor.Elements, &proto.Option{
+		Name:     "features.field_presence",
+		Constant: proto.Literal{Source: "IMPLICIT"},
+		Parent:   descriptor,
+	})
+	return nil
+}
+
+func migrateMessage(message *proto.Message) {
+	for _, element := range message.Elements {
+		switch e := element.(type) {
+		case *proto.NormalField:
+			if e.Optional {
+				e.Optional = false
+				e.Options = append(e.Options, &proto.Option{
+					Name:     "features.field_presence",
+					Constant: proto.Literal{Source: "EXPLICIT"},
+					Parent:   e,
+				})
+			}
+		case *proto.Message:
+			migrateMessage(e)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package envelope
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/uber/prototool/internal/text"
+)
+
+type builder struct {
+	start    time.Time
+	failures []*text.Failure
+	lock     sync.Mutex
+}
+
+func newBuilder() *builder {
+	return &builder{
+		start: time.Now(),
+	}
+}
+
+func (b *builder) AddFailures(failures ...*text.Failure) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.failures = append(b.failures, failures...)
+}
+
+func (b *builder) Build(command string, exitCode int, err error, output []byte) (*Envelope, error) {
+	b.lock.Lock()
+	failures := make([]*text.Failure, len(b.failures))
+	copy(failures, b.failures)
+	b.lock.Unlock()
+
+	text.SortFailures(failures)
+	jsonFailures := make([]*text.JSONFailure, 0, len(failures))
+	for _, failure := range failures {
+		jsonFailures = append(jsonFailures, failure.JSONFailure())
+	}
+	data, dataErr := getData(output)
+	if dataErr != nil {
+		return nil, dataErr
+	}
+	envelope := &Envelope{
+		Command:  command,
+		Duration: time.Since(b.start).String(),
+		ExitCode: exitCode,
+		Failures: jsonFailures,
+		Data:     data,
+	}
+	if err != nil {
+		envelope.Error = err.Error()
+	}
+	return envelope, nil
+}
+
+// getData returns the output as JSON, or nil if the output is empty.
+func getData(output []byte) (json.RawMessage, error) {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return nil, nil
+	}
+	if json.Valid(output) {
+		return json.RawMessage(output), nil
+	}
+	if values, ok := getJSONValues(output); ok {
+		return json.Marshal(values)
+	}
+	if utf8.Valid(output) {
+		return json.Marshal(string(output))
+	}
+	return json.Marshal(output)
+}
+
+// getJSONValues returns the JSON values in the output if the output is
+// a stream of JSON values, such as JSON objects one per line.
+func getJSONValues(output []byte) ([]json.RawMessage, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	var values []json.RawMessage
+	for {
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			if err == io.EOF {
+				return values, true
+			}
+			return nil, false
+		}
+		values = append(values, value)
+	}
+}
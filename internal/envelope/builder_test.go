@@ -0,0 +1,79 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package envelope
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/text"
+)
+
+func TestBuild(t *testing.T) {
+	builder := NewBuilder()
+	builder.AddFailures(
+		&text.Failure{Filename: "b.proto", Line: 2, Column: 1, ID: "FOO", Message: "foo"},
+		&text.Failure{Filename: "a.proto", Line: 1, Column: 1, ID: "BAR", Message: "bar", Severity: text.SeverityWarning},
+	)
+	envelope, err := builder.Build("lint", 255, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "lint", envelope.Command)
+	assert.NotEmpty(t, envelope.Duration)
+	assert.Equal(t, 255, envelope.ExitCode)
+	assert.Empty(t, envelope.Error)
+	assert.Nil(t, envelope.Data)
+	require.Len(t, envelope.Failures, 2)
+	assert.Equal(t, "a.proto", envelope.Failures[0].Filename)
+	assert.Equal(t, "warning", envelope.Failures[0].Severity)
+	assert.Equal(t, "b.proto", envelope.Failures[1].Filename)
+	assert.Equal(t, "error", envelope.Failures[1].Severity)
+
+	data, err := json.Marshal(&Envelope{Command: "compile", Duration: "1s"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"command":"compile","duration":"1s","exit_code":0,"failures":null}`, string(data))
+}
+
+func TestBuildError(t *testing.T) {
+	envelope, err := NewBuilder().Build("grpc", 1, errors.New("must set address"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "must set address", envelope.Error)
+	assert.NotNil(t, envelope.Failures)
+	assert.Empty(t, envelope.Failures)
+}
+
+func TestBuildData(t *testing.T) {
+	testBuildData(t, "", "")
+	testBuildData(t, "\n", "")
+	testBuildData(t, `{"value":"hello"}`+"\n", `{"value":"hello"}`)
+	testBuildData(t, "[1, 2]", "[1, 2]")
+	testBuildData(t, `{"value":"hello"}`+"\n"+`{"value":"goodbye"}`+"\n", `[{"value":"hello"},{"value":"goodbye"}]`)
+	testBuildData(t, "foo.proto\nbar.proto\n", `"foo.proto\nbar.proto"`)
+	testBuildData(t, "\xff\xfe", `"//4="`)
+}
+
+func testBuildData(t *testing.T, output string, expectedData string) {
+	envelope, err := NewBuilder().Build("files", 0, nil, []byte(output))
+	require.NoError(t, err)
+	assert.Equal(t, expectedData, string(envelope.Data))
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package envelope wraps the result of a command in a JSON envelope with
+// the command, duration, failures, and output, so that automation can
+// handle the result of every command the same way.
+package envelope
+
+import (
+	"encoding/json"
+
+	"github.com/uber/prototool/internal/text"
+)
+
+// Envelope is the result of a command.
+type Envelope struct {
+	// The command path without the binary name, for example lint or
+	// break check.
+	Command string `json:"command"`
+	// The duration of the command, for example 1.5s.
+	Duration string `json:"duration"`
+	ExitCode int    `json:"exit_code"`
+	// The error of the command other than failures, if any.
+	Error string `json:"error,omitempty"`
+	// The failures found by the command, sorted.
+	// Will never be nil.
+	Failures []*text.JSONFailure `json:"failures"`
+	// The output of the command.
+	//
+	// JSON output is included as is, or as an array if the output is
+	// more than one JSON value. Other output is included as a string,
+	// or as a base64 string if it is not valid UTF-8.
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Builder records the failures found by a command and builds the
+// Envelope for the command when it is done.
+//
+// Builders are safe for concurrent use.
+type Builder interface {
+	// AddFailures adds the failures to the failures found by the command.
+	AddFailures(failures ...*text.Failure)
+	// Build builds the Envelope with the duration since the Builder was
+	// created, and the failures added.
+	//
+	// The error is the error of the command, and may be nil.
+	// The output is the output of the command, and may be empty.
+	Build(command string, exitCode int, err error, output []byte) (*Envelope, error)
+}
+
+// NewBuilder returns a new Builder.
+func NewBuilder() Builder {
+	return newBuilder()
+}
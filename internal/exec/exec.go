@@ -26,9 +26,42 @@ package exec
 import (
 	"io"
 
+	"github.com/uber/prototool/internal/envelope"
+	"github.com/uber/prototool/internal/exectrace"
+	"github.com/uber/prototool/internal/metrics"
+	"github.com/uber/prototool/internal/progress"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/timing"
 	"go.uber.org/zap"
 )
 
+const (
+	// OutputFormatCheckstyle is the output format that prints failures
+	// as a Checkstyle XML report.
+	OutputFormatCheckstyle = "checkstyle"
+	// OutputFormatGitHubActions is the output format that prints failures
+	// as GitHub Actions workflow commands, one per line.
+	OutputFormatGitHubActions = "github-actions"
+	// OutputFormatGitLab is the output format that prints failures
+	// as a GitLab Code Quality JSON report.
+	OutputFormatGitLab = "gitlab"
+)
+
+const (
+	// OutputPresetEmacs is the output preset that prints failures in the
+	// GNU format that Emacs compilation mode parses, such as
+	// foo.proto:1.2: warning: Message [ID].
+	OutputPresetEmacs = "emacs"
+	// OutputPresetVim is the output preset that prints failures in a
+	// format that the Vim errorformat %f:%l:%c:\ %t%*[a-z]:\ %m parses,
+	// such as foo.proto:1:2: warning: Message [ID].
+	OutputPresetVim = "vim"
+	// OutputPresetVSCode is the output preset that prints failures with
+	// absolute paths for a VS Code problem matcher, such as
+	// /path/to/foo.proto:1:2: warning: Message [ID].
+	OutputPresetVSCode = "vscode"
+)
+
 // ExitError is an error that signals to exit with a certain code.
 type ExitError struct {
 	Code    int
@@ -45,27 +78,112 @@ func (e *ExitError) Error() string {
 // The args given are the args from the command line.
 // Each additional parameter generally refers to a command-specific flag.
 type Runner interface {
-	Init(args []string, uncomment bool) error
-	Create(args []string, pkg string) error
+	Init(args []string, uncomment bool, fromProtoc, fromMakefile, fromBuf string) error
+	GithookInstall(args []string, hookType, framework string, overwrite bool) error
+	Create(args []string, pkg, edition, version, ticket string) error
+	CreateVersion(dirPath, version string) error
 	Version() error
 	Download() error
 	Clean() error
 	Files(args []string) error
-	Compile(args []string, dryRun bool) error
-	Gen(args []string, dryRun bool) error
+	Compile(args []string, dryRun, parserOnly bool, protocVersions []string) error
+	Gen(args []string, dryRun, printPlan bool, outputArchive string) error
+	Decompile(filePath, outDirPath string) error
 	DescriptorProto(args []string) error
 	FieldDescriptorProto(args []string) error
 	ServiceDescriptorProto(args []string) error
-	Lint(args []string) error
+	Lint(args []string, summary bool) error
 	ListLinters() error
 	ListAllLinters() error
 	ListLintGroup(group string) error
+	LintExplain(id string) error
+	LintList() error
 	ListAllLintGroups() error
-	Format(args []string, overwrite, diffMode, lintMode, rewrite bool) error
-	BinaryToJSON(args []string) error
-	JSONToBinary(args []string) error
+	Format(args []string, overwrite, diffMode, lintMode, statMode, rewrite, checkRoundTrip bool) error
+	BinaryToJSON(args []string, delimited bool) error
+	JSONToBinary(args []string, delimited bool) error
+	BinaryToText(args []string) error
+	TextToBinary(args []string) error
+	JSONToText(args []string) error
+	BinaryToYAML(args []string) error
+	YAMLToBinary(args []string) error
 	All(args []string, disableFormat, disableLint, rewrite bool) error
-	GRPC(args, headers []string, address, method, data, callTimeout, connectTimeout, keepaliveTime string, stdin bool) error
+	GRPC(args []string, options GRPCOptions) error
+	GRPCProxy(args []string, options GRPCProxyOptions) error
+	BazelGen(args []string, dryRun bool) error
+	ConfigExport(args []string, format string, dryRun bool) error
+	ConfigIncludes(args []string, explain bool) error
+	AnnotationsList(args, optionNames []string, outputFormat string) error
+	Serve(args []string, address, fixturesFile string) error
+	Test(args, headers []string, address, callTimeout, connectTimeout string) error
+	GenerateData(args []string, count int, outputFormat string, seed int64) error
+	RegistryPush(args []string, url, subject, basicAuth string) error
+	RegistryPull(url, subject, version, basicAuth string) error
+	ModulePush(args []string, registryURL, name, version string) error
+	ModuleFetch(args []string, registryURL string) error
+	BreakCheck(args []string, gitRef string) error
+	Diff(from, to, dirPath string) error
+	Changelog(dirPath, since string) error
+	GRPCMethods(args []string) error
+	Vet(args []string) error
+	MigrateEditions(args []string, edition string, overwrite, diffMode, lintMode bool) error
+	MigrateProto3(args []string, overwrite, diffMode, lintMode bool) error
+	MigrateEnums(args []string, overwrite, diffMode, lintMode bool) error
+}
+
+// GRPCOptions are the options for Runner.GRPC.
+//
+// Durations are strings in the format of time.ParseDuration.
+type GRPCOptions struct {
+	Address          string
+	Target           string
+	Method           string
+	Data             string
+	Stdin            bool
+	Interactive      bool
+	List             bool
+	Headers          []string
+	HeaderEnvPrefix  string
+	Authority        string
+	UserAgent        string
+	AuthToken        string
+	AuthTokenFile    string
+	Compress         string
+	CallTimeout      string
+	ConnectTimeout   string
+	KeepaliveTime    string
+	Deadline         string
+	CancelAfter      string
+	CancelAfterBytes int
+	WaitForReady     bool
+	MaxRecvMsgSize   int
+	MaxSendMsgSize   int
+	MaxAttempts      int
+	RetryBackoff     string
+	RetryCodes       []string
+	Output           string
+	OutputFormat     string
+	PrintMetadata    bool
+	Fields           []string
+	ExpectJSON       string
+	ExpectCode       string
+	ExpectFields     []string
+	Record           string
+}
+
+// GRPCProxyOptions are the options for Runner.GRPCProxy.
+type GRPCProxyOptions struct {
+	Address         string
+	Target          string
+	Listen          string
+	Headers         []string
+	HeaderEnvPrefix string
+	Authority       string
+	UserAgent       string
+	AuthToken       string
+	AuthTokenFile   string
+	ConnectTimeout  string
+	Record          string
 }
 
 // RunnerOption is an option for a new Runner.
@@ -94,6 +212,30 @@ func RunnerWithProtocURL(protocURL string) RunnerOption {
 	}
 }
 
+// RunnerWithProtocBinPath returns a RunnerOption that uses the given protoc
+// binary instead of downloading protoc, overriding the config.
+func RunnerWithProtocBinPath(protocBinPath string) RunnerOption {
+	return func(runner *runner) {
+		runner.protocBinPath = protocBinPath
+	}
+}
+
+// RunnerWithRemoteExecutionURL returns a RunnerOption that runs protoc and
+// plugins on the remote execution worker at the given URL.
+func RunnerWithRemoteExecutionURL(remoteExecutionURL string) RunnerOption {
+	return func(runner *runner) {
+		runner.remoteExecutionURL = remoteExecutionURL
+	}
+}
+
+// RunnerWithProtocWKTPath returns a RunnerOption that uses the given path
+// to include for the well-known types, overriding the config.
+func RunnerWithProtocWKTPath(protocWKTPath string) RunnerOption {
+	return func(runner *runner) {
+		runner.protocWKTPath = protocWKTPath
+	}
+}
+
 // RunnerWithPrintFields returns a RunnerOption that uses the given colon-separated
 // print fields. The default is filename:line:column:message.
 func RunnerWithPrintFields(printFields string) RunnerOption {
@@ -102,6 +244,15 @@ func RunnerWithPrintFields(printFields string) RunnerOption {
 	}
 }
 
+// RunnerWithFailureTemplate returns a RunnerOption that prints failures
+// with the given text/template instead of the print fields, such as
+// {{.Filename}}:{{.Line}} {{.ID}} {{.Message}}.
+func RunnerWithFailureTemplate(failureTemplate string) RunnerOption {
+	return func(runner *runner) {
+		runner.failureTemplate = failureTemplate
+	}
+}
+
 // RunnerWithDirMode returns a RunnerOption that will act as if the file
 // given is the directory of the file, but only print the failures
 // from that file.
@@ -121,6 +272,181 @@ func RunnerWithHarbormaster() RunnerOption {
 	}
 }
 
+// RunnerWithEnvelope returns a RunnerOption that will add failures to
+// the given envelope.Builder instead of printing them.
+//
+// The caller prints the envelope when the command is done.
+func RunnerWithEnvelope(builder envelope.Builder) RunnerOption {
+	return func(runner *runner) {
+		runner.envelopeBuilder = builder
+	}
+}
+
+// RunnerWithMaxWarnings returns a RunnerOption that will fail lint
+// if there are more than the given number of lint warnings.
+//
+// The default is to allow any number of lint warnings.
+func RunnerWithMaxWarnings(maxWarnings int) RunnerOption {
+	return func(runner *runner) {
+		runner.maxWarnings = maxWarnings
+	}
+}
+
+// RunnerWithNoLintCache returns a RunnerOption that will not use or
+// store cached lint results.
+//
+// The default is to cache lint results in the lint directory of the
+// cache path, or of the user cache directory if there is no cache path.
+func RunnerWithNoLintCache() RunnerOption {
+	return func(runner *runner) {
+		runner.noLintCache = true
+	}
+}
+
+// RunnerWithMetrics returns a RunnerOption that will add the failures
+// found by the command to the given metrics.Recorder.
+//
+// The caller emits the metrics when the command is done.
+func RunnerWithMetrics(recorder metrics.Recorder) RunnerOption {
+	return func(runner *runner) {
+		runner.metricsRecorder = recorder
+	}
+}
+
+// RunnerWithNotify returns a RunnerOption that will post compile, lint,
+// and break check failures to the webhook in the config, if any.
+//
+// The default is to not post failures, so that local runs do not notify.
+func RunnerWithNotify() RunnerOption {
+	return func(runner *runner) {
+		runner.notify = true
+	}
+}
+
+// RunnerWithOutputPreset returns a RunnerOption that will print failures
+// in the format that the given editor parses, and exit with code 1
+// instead of 255 if there are failures, as compilers do.
+//
+// The default is to print failures with the print fields.
+func RunnerWithOutputPreset(outputPreset string) RunnerOption {
+	return func(runner *runner) {
+		runner.outputPreset = outputPreset
+	}
+}
+
+// RunnerWithCompileExitCode returns a RunnerOption that will exit with
+// the given code if there are compile failures.
+//
+// The default is to exit with 255, or 1 if an output preset is set.
+func RunnerWithCompileExitCode(code int) RunnerOption {
+	return func(runner *runner) {
+		runner.compileExitCode = code
+	}
+}
+
+// RunnerWithLintExitCode returns a RunnerOption that will exit with
+// the given code if there are lint failures.
+//
+// The default is to exit with 255, or 1 if an output preset is set.
+func RunnerWithLintExitCode(code int) RunnerOption {
+	return func(runner *runner) {
+		runner.lintExitCode = code
+	}
+}
+
+// RunnerWithFormatExitCode returns a RunnerOption that will exit with
+// the given code if there are format failures or diffs, and for all,
+// if formatting changed any file and there were no other failures.
+//
+// The default is to exit with 255, or 1 if an output preset is set,
+// for format failures and diffs, and to exit with 0 if all changed files.
+func RunnerWithFormatExitCode(code int) RunnerOption {
+	return func(runner *runner) {
+		runner.formatExitCode = code
+	}
+}
+
+// RunnerWithOutputFormat returns a RunnerOption that will print
+// failures in the given output format.
+//
+// The default is to print failures as text.
+func RunnerWithOutputFormat(outputFormat string) RunnerOption {
+	return func(runner *runner) {
+		runner.outputFormat = outputFormat
+	}
+}
+
+// RunnerWithWarningsAsErrors returns a RunnerOption that will treat
+// protoc warnings as errors.
+func RunnerWithWarningsAsErrors() RunnerOption {
+	return func(runner *runner) {
+		runner.warningsAsErrors = true
+	}
+}
+
+// RunnerWithJobs returns a RunnerOption that runs at most the given number
+// of protoc and plugin invocations at once.
+func RunnerWithJobs(jobs int) RunnerOption {
+	return func(runner *runner) {
+		runner.jobs = jobs
+	}
+}
+
+// RunnerWithJSONConfig returns a RunnerOption that uses the given JSON
+// config for JSON output of messages. Set values override the values
+// from the json section of the config file.
+func RunnerWithJSONConfig(jsonConfig settings.JSONConfig) RunnerOption {
+	return func(runner *runner) {
+		runner.jsonConfig = jsonConfig
+	}
+}
+
+// RunnerWithDescriptorSetPath returns a RunnerOption that reads the
+// FileDescriptorSet to use for message conversion and grpc from the given
+// file instead of compiling Protobuf files.
+//
+// The FileDescriptorSet must include all imports, as produced by
+// protoc --include_imports --descriptor_set_out.
+func RunnerWithDescriptorSetPath(descriptorSetPath string) RunnerOption {
+	return func(runner *runner) {
+		runner.descriptorSetPath = descriptorSetPath
+	}
+}
+
+// RunnerWithTiming returns a RunnerOption that records the wall time
+// spent in each phase of a command with the given timer, such as config
+// resolution, the protoc download, parsing, each protoc and plugin run,
+// and each linter.
+//
+// The default is to not record anything.
+func RunnerWithTiming(timer timing.Timer) RunnerOption {
+	return func(runner *runner) {
+		runner.timer = timer
+	}
+}
+
+// RunnerWithProgress returns a RunnerOption that reports the progress of
+// downloading protoc and the plugins, and of compiling and generating, to
+// the given Reporter.
+//
+// The default is to not report anything.
+func RunnerWithProgress(progressReporter progress.Reporter) RunnerOption {
+	return func(runner *runner) {
+		runner.progressReporter = progressReporter
+	}
+}
+
+// RunnerWithExecTracer returns a RunnerOption that traces every external
+// process the runner spawns with the given tracer, such as protoc, git,
+// diff, and secret commands.
+//
+// The default is to not trace anything.
+func RunnerWithExecTracer(execTracer exectrace.Tracer) RunnerOption {
+	return func(runner *runner) {
+		runner.execTracer = execTracer
+	}
+}
+
 // NewRunner returns a new Runner.
 //
 // workDirPath should generally be the current directory.
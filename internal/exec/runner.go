@@ -21,43 +21,102 @@
 package exec
 
 import (
+	"archive/tar"
 	"bufio"
 	"bytes"
+	"compress/gzip"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"text/scanner"
 	"text/tabwriter"
+	"text/template"
 	"time"
 
+	protoast "github.com/emicklei/proto"
+	"github.com/ghodss/yaml"
 	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	reflectdesc "github.com/jhump/protoreflect/desc"
+	"github.com/uber/prototool/internal/annotations"
+	"github.com/uber/prototool/internal/bazel"
+	"github.com/uber/prototool/internal/breaking"
 	"github.com/uber/prototool/internal/cfginit"
+	"github.com/uber/prototool/internal/checkstyle"
 	"github.com/uber/prototool/internal/create"
+	"github.com/uber/prototool/internal/datagen"
+	"github.com/uber/prototool/internal/decompile"
+	"github.com/uber/prototool/internal/desc"
 	"github.com/uber/prototool/internal/diff"
+	"github.com/uber/prototool/internal/doc"
+	"github.com/uber/prototool/internal/editions"
+	"github.com/uber/prototool/internal/envelope"
+	"github.com/uber/prototool/internal/exectrace"
 	"github.com/uber/prototool/internal/extract"
 	"github.com/uber/prototool/internal/file"
 	"github.com/uber/prototool/internal/format"
+	"github.com/uber/prototool/internal/git"
+	"github.com/uber/prototool/internal/githook"
+	"github.com/uber/prototool/internal/github"
+	"github.com/uber/prototool/internal/gitlab"
 	"github.com/uber/prototool/internal/grpc"
+	"github.com/uber/prototool/internal/includes"
 	"github.com/uber/prototool/internal/lint"
+	"github.com/uber/prototool/internal/metrics"
+	"github.com/uber/prototool/internal/mock"
+	"github.com/uber/prototool/internal/module"
+	"github.com/uber/prototool/internal/parsecheck"
 	"github.com/uber/prototool/internal/phab"
+	"github.com/uber/prototool/internal/progress"
 	"github.com/uber/prototool/internal/protoc"
+	"github.com/uber/prototool/internal/provenance"
 	"github.com/uber/prototool/internal/reflect"
+	"github.com/uber/prototool/internal/scenario"
+	"github.com/uber/prototool/internal/schemaregistry"
+	"github.com/uber/prototool/internal/secret"
 	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/strs"
+	"github.com/uber/prototool/internal/stub"
+	"github.com/uber/prototool/internal/syntax"
 	"github.com/uber/prototool/internal/text"
+	"github.com/uber/prototool/internal/timing"
 	"github.com/uber/prototool/internal/vars"
+	"github.com/uber/prototool/internal/vet"
+	"github.com/uber/prototool/internal/webhook"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
 )
 
+// lintSummaryMaxFiles is the number of files with the most failures
+// printed by lint --summary.
+const lintSummaryMaxFiles = 10
+
 var jsonMarshaler = &jsonpb.Marshaler{Indent: "  "}
 
+// outputPresetToFailureTemplate maps each output preset to the failure
+// template that prints failures as the editor's error parser expects.
+//
+// The severity is printed as the word matched by %t in the Vim
+// errorformat, and failures without a severity are errors.
+var outputPresetToFailureTemplate = map[string]string{
+	OutputPresetEmacs:  `{{.Filename}}:{{.Line}}.{{.Column}}: {{or .Severity "error"}}: {{.Message}}{{if .ID}} [{{.ID}}]{{end}}`,
+	OutputPresetVim:    `{{.Filename}}:{{.Line}}:{{.Column}}: {{or .Severity "error"}}: {{.Message}}{{if .ID}} [{{.ID}}]{{end}}`,
+	OutputPresetVSCode: `{{.Filename}}:{{.Line}}:{{.Column}}: {{or .Severity "error"}}: {{.Message}}{{if .ID}} [{{.ID}}]{{end}}`,
+}
+
 type runner struct {
 	configProvider   settings.ConfigProvider
 	protoSetProvider file.ProtoSetProvider
@@ -66,19 +125,45 @@ type runner struct {
 	input       io.Reader
 	output      io.Writer
 
-	logger       *zap.Logger
-	cachePath    string
-	protocURL    string
-	printFields  string
-	dirMode      bool
-	harbormaster bool
+	logger             *zap.Logger
+	cachePath          string
+	protocURL          string
+	protocBinPath      string
+	protocWKTPath      string
+	remoteExecutionURL string
+	printFields        string
+	failureTemplate    string
+	dirMode            bool
+	harbormaster       bool
+	maxWarnings        int
+	noLintCache        bool
+	notify             bool
+	outputFormat       string
+	outputPreset       string
+	compileExitCode    int
+	lintExitCode       int
+	formatExitCode     int
+	warningsAsErrors   bool
+	jobs               int
+	jsonConfig         settings.JSONConfig
+	descriptorSetPath  string
+	timer              timing.Timer
+	execTracer         exectrace.Tracer
+	progressReporter   progress.Reporter
+	metricsRecorder    metrics.Recorder
+	// failures are added to envelopeBuilder instead of printed if set
+	envelopeBuilder envelope.Builder
 }
 
 func newRunner(workDirPath string, input io.Reader, output io.Writer, options ...RunnerOption) *runner {
 	runner := &runner{
-		workDirPath: workDirPath,
-		input:       input,
-		output:      output,
+		workDirPath:     workDirPath,
+		input:           input,
+		output:          output,
+		maxWarnings:     -1,
+		timer:           timing.NewNopTimer(),
+		execTracer:      exectrace.NewNopTracer(),
+		metricsRecorder: metrics.NewNopRecorder(),
 	}
 	for _, option := range options {
 		option(runner)
@@ -119,10 +204,22 @@ func (r *runner) Version() error {
 	return tabWriter.Flush()
 }
 
-func (r *runner) Init(args []string, uncomment bool) error {
+func (r *runner) Init(args []string, uncomment bool, fromProtoc, fromMakefile, fromBuf string) error {
 	if len(args) > 1 {
 		return errors.New("must provide one arg dirPath")
 	}
+	numFrom := 0
+	for _, from := range []string{fromProtoc, fromMakefile, fromBuf} {
+		if from != "" {
+			numFrom++
+		}
+	}
+	if numFrom > 1 {
+		return newExitErrorf(255, "only one of --from-protoc, --from-makefile, and --from-buf can be set")
+	}
+	if uncomment && numFrom > 0 {
+		return newExitErrorf(255, "--uncomment cannot be used with --from-protoc, --from-makefile, or --from-buf")
+	}
 	// TODO(pedge): cleanup
 	dirPath := r.workDirPath
 	if len(args) == 1 {
@@ -135,15 +232,107 @@ func (r *runner) Init(args []string, uncomment bool) error {
 	if _, err := os.Stat(filePath); err == nil {
 		return fmt.Errorf("%s already exists", filePath)
 	}
-	data, err := cfginit.Generate(vars.DefaultProtocVersion, uncomment)
+	var data []byte
+	var err error
+	switch {
+	case fromProtoc != "":
+		data, err = cfginit.GenerateFromProtoc(vars.DefaultProtocVersion, fromProtoc)
+	case fromMakefile != "":
+		var makefileData []byte
+		makefileData, err = ioutil.ReadFile(fromMakefile)
+		if err != nil {
+			return err
+		}
+		var commandLines []string
+		commandLines, err = cfginit.ProtocCommandLinesFromMakefile(makefileData)
+		if err != nil {
+			return err
+		}
+		data, err = cfginit.GenerateFromProtoc(vars.DefaultProtocVersion, commandLines...)
+	case fromBuf != "":
+		var bufYAMLData, bufGenYAMLData []byte
+		bufYAMLData, bufGenYAMLData, err = readBufConfigFiles(fromBuf)
+		if err != nil {
+			return err
+		}
+		data, err = cfginit.GenerateFromBuf(vars.DefaultProtocVersion, bufYAMLData, bufGenYAMLData)
+	default:
+		data, err = cfginit.Generate(vars.DefaultProtocVersion, uncomment)
+	}
 	if err != nil {
-		return err
+		return newExitErrorf(255, "%v", err)
 	}
 	return ioutil.WriteFile(filePath, data, 0644)
 }
 
-func (r *runner) Create(args []string, pkg string) error {
-	return r.newCreateHandler(pkg).Create(args...)
+// readBufConfigFiles reads the buf.yaml and buf.gen.yaml files in the
+// given directory, or next to the given buf.yaml file. Files that do not
+// exist are returned as nil.
+func readBufConfigFiles(bufPath string) ([]byte, []byte, error) {
+	dirPath := bufPath
+	bufYAMLFilePath := filepath.Join(bufPath, "buf.yaml")
+	fileInfo, err := os.Stat(bufPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !fileInfo.IsDir() {
+		dirPath = filepath.Dir(bufPath)
+		bufYAMLFilePath = bufPath
+	}
+	var data [2][]byte
+	for i, filePath := range []string{bufYAMLFilePath, filepath.Join(dirPath, "buf.gen.yaml")} {
+		data[i], err = ioutil.ReadFile(filePath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, nil, err
+		}
+	}
+	return data[0], data[1], nil
+}
+
+func (r *runner) GithookInstall(args []string, hookType, framework string, overwrite bool) error {
+	if len(args) > 1 {
+		return errors.New("must provide one arg dirPath")
+	}
+	if framework != "" {
+		data, err := githook.GenerateFramework(framework)
+		if err != nil {
+			return newExitErrorf(255, "%v", err)
+		}
+		_, err = r.output.Write(data)
+		return err
+	}
+	data, err := githook.Generate(hookType)
+	if err != nil {
+		return newExitErrorf(255, "%v", err)
+	}
+	dirPath := r.workDirPath
+	if len(args) == 1 {
+		dirPath = args[0]
+	}
+	hooksDirPath, err := git.HooksDirPath(r.execTracer, dirPath)
+	if err != nil {
+		return err
+	}
+	filePath := filepath.Join(hooksDirPath, hookType)
+	if _, err := os.Stat(filePath); err == nil && !overwrite {
+		return fmt.Errorf("%s already exists, pass --overwrite to replace it", filePath)
+	}
+	if err := os.MkdirAll(hooksDirPath, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filePath, data, 0755); err != nil {
+		return err
+	}
+	// WriteFile does not change the mode of an existing file
+	return os.Chmod(filePath, 0755)
+}
+
+func (r *runner) Create(args []string, pkg, edition, version, ticket string) error {
+	return r.newCreateHandler(pkg, edition, version, ticket).Create(args...)
+}
+
+func (r *runner) CreateVersion(dirPath, version string) error {
+	return r.newCreateHandler("", "", "", "").CreateVersion(dirPath, version)
 }
 
 func (r *runner) Download() error {
@@ -163,7 +352,13 @@ func (r *runner) Clean() error {
 	if err != nil {
 		return err
 	}
-	return r.newDownloader(config).Delete()
+	if err := r.newDownloader(config).Delete(); err != nil {
+		return err
+	}
+	if lintCachePath := r.getLintCachePath(); lintCachePath != "" {
+		return os.RemoveAll(lintCachePath)
+	}
+	return nil
 }
 
 func (r *runner) Files(args []string) error {
@@ -171,8 +366,13 @@ func (r *runner) Files(args []string) error {
 	if err != nil {
 		return err
 	}
-	for _, files := range meta.ProtoSet.DirPathToFiles {
-		for _, file := range files {
+	dirPaths := make([]string, 0, len(meta.ProtoSet.DirPathToFiles))
+	for dirPath := range meta.ProtoSet.DirPathToFiles {
+		dirPaths = append(dirPaths, dirPath)
+	}
+	sort.Strings(dirPaths)
+	for _, dirPath := range dirPaths {
+		for _, file := range meta.ProtoSet.DirPathToFiles[dirPath] {
 			if err := r.println(file.DisplayPath); err != nil {
 				return err
 			}
@@ -181,24 +381,376 @@ func (r *runner) Files(args []string) error {
 	return nil
 }
 
-func (r *runner) Compile(args []string, dryRun bool) error {
+func (r *runner) Compile(args []string, dryRun, parserOnly bool, protocVersions []string) error {
+	if dryRun && parserOnly {
+		return newExitErrorf(255, "cannot use --dry-run with --parser-only as protoc is not run")
+	}
+	if len(protocVersions) > 0 {
+		if dryRun || parserOnly {
+			return newExitErrorf(255, "cannot use --protoc-versions with --dry-run or --parser-only")
+		}
+		if r.protocBinPath != "" || r.protocURL != "" {
+			return newExitErrorf(255, "cannot use --protoc-versions with --protoc-bin-path or --protoc-url")
+		}
+	}
 	meta, err := r.getMeta(args)
 	if err != nil {
 		return err
 	}
 	r.printAffectedFiles(meta)
+	if parserOnly {
+		return r.parseCheck(meta)
+	}
+	if len(protocVersions) > 0 {
+		return r.compileVersions(protocVersions, meta)
+	}
 	_, err = r.compile(false, false, dryRun, meta)
 	return err
 }
 
-func (r *runner) Gen(args []string, dryRun bool) error {
+// compileVersions compiles with each of the protoc versions. Failures that
+// happen with every version are printed as they are, and failures that only
+// happen with some versions are printed with the versions they happen with.
+func (r *runner) compileVersions(protocVersions []string, meta *meta) error {
+	if meta.ProtoSet.Config.Compile.ProtocBinPath != "" {
+		return newExitErrorf(255, "cannot use --protoc-versions with the config protoc bin_path setting")
+	}
+	versionToFailures := make(map[string][]*text.Failure, len(protocVersions))
+	var versions []string
+	for _, protocVersion := range protocVersions {
+		if _, ok := versionToFailures[protocVersion]; ok {
+			continue
+		}
+		versions = append(versions, protocVersion)
+		protoSet := *meta.ProtoSet
+		protoSet.Config.Compile.ProtobufVersion = protocVersion
+		protoSet.Config.Compile.AutoProtobufVersion = false
+		compileResult, err := r.newCompiler(false, false).Compile(&protoSet)
+		if err != nil {
+			return fmt.Errorf("protoc %s: %v", protocVersion, err)
+		}
+		versionToFailures[protocVersion] = compileResult.Failures
+	}
+	failures := mergeVersionFailures(versions, versionToFailures)
+	if err := r.printFailures("", meta, failures...); err != nil {
+		return err
+	}
+	r.notifyWebhook("compile", meta, failures)
+	if text.ContainsError(failures...) {
+		return r.newCompileFailuresExitError()
+	}
+	return nil
+}
+
+// mergeVersionFailures returns the failures for each version, with the
+// failures that do not happen with every version prefixed with the versions
+// they happen with, for example "protoc 3.11.4: ".
+func mergeVersionFailures(versions []string, versionToFailures map[string][]*text.Failure) []*text.Failure {
+	type failureKey struct {
+		filename string
+		line     int
+		column   int
+		message  string
+	}
+	var keys []failureKey
+	keyToFailure := make(map[failureKey]*text.Failure)
+	keyToVersions := make(map[failureKey][]string)
+	for _, version := range versions {
+		for _, failure := range versionToFailures[version] {
+			key := failureKey{
+				filename: failure.Filename,
+				line:     failure.Line,
+				column:   failure.Column,
+				message:  failure.Message,
+			}
+			if _, ok := keyToFailure[key]; !ok {
+				keys = append(keys, key)
+				keyToFailure[key] = failure
+			}
+			if keyVersions := keyToVersions[key]; len(keyVersions) == 0 || keyVersions[len(keyVersions)-1] != version {
+				keyToVersions[key] = append(keyVersions, version)
+			}
+		}
+	}
+	failures := make([]*text.Failure, 0, len(keys))
+	for _, key := range keys {
+		failure := *keyToFailure[key]
+		if keyVersions := keyToVersions[key]; len(keyVersions) < len(versions) {
+			failure.Message = fmt.Sprintf("protoc %s: %s", strings.Join(keyVersions, ", "), failure.Message)
+		}
+		failures = append(failures, &failure)
+	}
+	text.SortFailures(failures)
+	return failures
+}
+
+func (r *runner) parseCheck(meta *meta) error {
+	failures, err := r.newParseChecker().Check(meta.ProtoSet)
+	if err != nil {
+		return err
+	}
+	if err := r.printFailures("", meta, failures...); err != nil {
+		return err
+	}
+	if len(failures) > 0 {
+		return r.newCompileFailuresExitError()
+	}
+	return nil
+}
+
+func (r *runner) Gen(args []string, dryRun, printPlan bool, outputArchive string) error {
+	if outputArchive != "" && (dryRun || printPlan) {
+		return newExitErrorf(255, "cannot use --output-archive with --dry-run or --print-plan")
+	}
 	meta, err := r.getMeta(args)
 	if err != nil {
 		return err
 	}
 	r.printAffectedFiles(meta)
-	_, err = r.compile(true, false, dryRun, meta)
-	return err
+	if printPlan {
+		return r.printPlan(meta.ProtoSet)
+	}
+	if outputArchive != "" {
+		return r.genArchive(outputArchive, meta)
+	}
+	since := time.Now()
+	fileDescriptorSets, err := r.compile(true, meta.ProtoSet.Config.Gen.GoStubsOutputPath != "", dryRun, meta)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+	if err := r.genStubs(meta, fileDescriptorSets); err != nil {
+		return err
+	}
+	if err := r.genProvenance(meta, since); err != nil {
+		return err
+	}
+	return r.genDoc(meta)
+}
+
+// genArchive generates into a temporary directory instead of the plugin
+// output paths, and writes the generated files to a tar.gz archive at
+// outputArchive, or to stdout if outputArchive is "-".
+//
+// The paths in the archive are relative to the directory of the config
+// file, or to the closest parent directory of it that contains all the
+// plugin output paths, the Go stubs output path, and the doc output path.
+func (r *runner) genArchive(outputArchive string, meta *meta) (retErr error) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDirPath); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+	config := meta.ProtoSet.Config
+	baseDirPath := config.DirPath
+	outputPaths := []string{config.Gen.GoStubsOutputPath, config.Doc.OutputPath}
+	for _, genPlugin := range config.Gen.Plugins {
+		outputPaths = append(outputPaths, genPlugin.OutputPath.AbsPath)
+	}
+	for _, outputPath := range outputPaths {
+		for outputPath != "" && !isInDirPath(outputPath, baseDirPath) {
+			baseDirPath = filepath.Dir(baseDirPath)
+		}
+	}
+	getArchivePath := func(outputPath string) (string, error) {
+		rel, err := filepath.Rel(baseDirPath, outputPath)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(tmpDirPath, rel), nil
+	}
+	genPlugins := make([]settings.GenPlugin, len(config.Gen.Plugins))
+	for i, genPlugin := range config.Gen.Plugins {
+		if genPlugin.OutputPath.AbsPath, err = getArchivePath(genPlugin.OutputPath.AbsPath); err != nil {
+			return err
+		}
+		genPlugins[i] = genPlugin
+	}
+	config.Gen.Plugins = genPlugins
+	if config.Gen.GoStubsOutputPath != "" {
+		if config.Gen.GoStubsOutputPath, err = getArchivePath(config.Gen.GoStubsOutputPath); err != nil {
+			return err
+		}
+	}
+	if config.Doc.OutputPath != "" {
+		if config.Doc.OutputPath, err = getArchivePath(config.Doc.OutputPath); err != nil {
+			return err
+		}
+	}
+	protoSet := *meta.ProtoSet
+	protoSet.Config = config
+	archiveMeta := *meta
+	archiveMeta.ProtoSet = &protoSet
+	since := time.Now()
+	compileResult, err := r.newCompiler(true, config.Gen.GoStubsOutputPath != "").Compile(&protoSet)
+	if err != nil {
+		return err
+	}
+	fileDescriptorSets := compileResult.FileDescriptorSets
+	if outputArchive == "-" && !text.ContainsError(compileResult.Failures...) {
+		// printing warnings would corrupt the archive on stdout
+		for _, failure := range compileResult.Failures {
+			r.logger.Warn(failure.String())
+		}
+	} else if fileDescriptorSets, err = r.handleCompileResult(compileResult, &archiveMeta); err != nil {
+		return err
+	}
+	if err := r.genStubs(&archiveMeta, fileDescriptorSets); err != nil {
+		return err
+	}
+	if err := r.genProvenance(&archiveMeta, since); err != nil {
+		return err
+	}
+	if err := r.genDoc(&archiveMeta); err != nil {
+		return err
+	}
+	if outputArchive == "-" {
+		return writeTarGz(r.output, tmpDirPath)
+	}
+	file, err := os.Create(outputArchive)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := file.Close(); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+	return writeTarGz(file, tmpDirPath)
+}
+
+// isInDirPath returns true if the path is the directory path or is
+// within the directory path.
+func isInDirPath(path string, dirPath string) bool {
+	return path == dirPath || strings.HasPrefix(path, strings.TrimSuffix(dirPath, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// writeTarGz writes the files in the directory to a tar.gz archive,
+// with paths relative to the directory.
+func writeTarGz(writer io.Writer, dirPath string) error {
+	gzipWriter := gzip.NewWriter(writer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	if err := filepath.Walk(dirPath, func(path string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fileInfo.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(fileInfo, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err = tarWriter.Write(data)
+		return err
+	}); err != nil {
+		return err
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+func (r *runner) genStubs(meta *meta, fileDescriptorSets []*descriptor.FileDescriptorSet) error {
+	stubFiles, err := r.newStubGenerator().Generate(meta.ProtoSet, fileDescriptorSets)
+	if err != nil {
+		return err
+	}
+	for _, stubFile := range stubFiles {
+		r.logger.Debug("writing stubs", zap.String("path", stubFile.Path))
+		if err := os.MkdirAll(filepath.Dir(stubFile.Path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(stubFile.Path, stubFile.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// genProvenance stamps the files generated since the given time with the
+// provenance header of the gen config, if enabled.
+func (r *runner) genProvenance(meta *meta, since time.Time) error {
+	if !meta.ProtoSet.Config.Gen.Provenance.Enabled {
+		return nil
+	}
+	// the version is not known if protoc is not downloaded
+	protocVersion := ""
+	if r.protocBinPath == "" && r.protocURL == "" && meta.ProtoSet.Config.Compile.ProtocBinPath == "" {
+		var err error
+		if protocVersion, err = protoc.GetProtobufVersion(meta.ProtoSet); err != nil {
+			return err
+		}
+	}
+	filePaths, err := r.newStamper().Stamp(meta.ProtoSet, protocVersion, since)
+	if err != nil {
+		return err
+	}
+	r.logger.Debug("stamped generated files", zap.Int("count", len(filePaths)))
+	return nil
+}
+
+func (r *runner) genDoc(meta *meta) error {
+	docFile, err := r.newDocGenerator().Generate(meta.ProtoSet)
+	if err != nil {
+		return err
+	}
+	if docFile == nil {
+		return nil
+	}
+	r.logger.Debug("writing documentation", zap.String("path", docFile.Path))
+	if err := os.MkdirAll(filepath.Dir(docFile.Path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(docFile.Path, docFile.Data, 0644)
+}
+
+func (r *runner) Decompile(filePath, outDirPath string) error {
+	if outDirPath == "" {
+		return newExitErrorf(255, "must set out")
+	}
+	config, err := r.getConfig(r.workDirPath)
+	if err != nil {
+		return err
+	}
+	fileDescriptorSet, err := r.readFileDescriptorSet(filePath)
+	if err != nil {
+		return err
+	}
+	files, err := r.newDecompiler(config.Format).Decompile(fileDescriptorSet)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		path := filepath.Join(outDirPath, filepath.FromSlash(file.Path))
+		r.logger.Debug("writing decompiled file", zap.String("path", path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, file.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (r *runner) DescriptorProto(args []string) error {
@@ -224,7 +776,7 @@ func (r *runner) DescriptorProto(args []string) error {
 	if err != nil {
 		return err
 	}
-	data, err := jsonMarshaler.MarshalToString(message.DescriptorProto)
+	data, err := desc.MarshalJSON(jsonMarshaler, message.DescriptorProto)
 	if err != nil {
 		return err
 	}
@@ -254,7 +806,7 @@ func (r *runner) FieldDescriptorProto(args []string) error {
 	if err != nil {
 		return err
 	}
-	data, err := jsonMarshaler.MarshalToString(field.FieldDescriptorProto)
+	data, err := desc.MarshalJSON(jsonMarshaler, field.FieldDescriptorProto)
 	if err != nil {
 		return err
 	}
@@ -299,11 +851,18 @@ func (r *runner) compile(doGen, doFileDescriptorSet, dryRun bool, meta *meta) ([
 	if err != nil {
 		return nil, err
 	}
+	return r.handleCompileResult(compileResult, meta)
+}
+
+// handleCompileResult prints the failures of the compile result, and
+// returns the FileDescriptorSets if there are no errors.
+func (r *runner) handleCompileResult(compileResult *protoc.CompileResult, meta *meta) ([]*descriptor.FileDescriptorSet, error) {
 	if err := r.printFailures("", meta, compileResult.Failures...); err != nil {
 		return nil, err
 	}
-	if len(compileResult.Failures) > 0 {
-		return nil, newExitErrorf(255, "")
+	r.notifyWebhook("compile", meta, compileResult.Failures)
+	if text.ContainsError(compileResult.Failures...) {
+		return nil, r.newCompileFailuresExitError()
 	}
 	r.logger.Debug("protoc command exited without errors")
 	return compileResult.FileDescriptorSets, nil
@@ -322,7 +881,28 @@ func (r *runner) printCommands(doGen bool, protoSet *file.ProtoSet) error {
 	return nil
 }
 
-func (r *runner) Lint(args []string) error {
+// printPlan prints the protoc commands for gen, as JSON if --json is set.
+func (r *runner) printPlan(protoSet *file.ProtoSet) error {
+	if r.envelopeBuilder == nil {
+		return r.printCommands(true, protoSet)
+	}
+	protocCommands, err := r.newCompiler(true, false).ProtocPlan(protoSet)
+	if err != nil {
+		return err
+	}
+	for _, protocCommand := range protocCommands {
+		data, err := json.Marshal(protocCommand)
+		if err != nil {
+			return err
+		}
+		if err := r.println(string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) Lint(args []string, summary bool) error {
 	meta, err := r.getMeta(args)
 	if err != nil {
 		return err
@@ -331,20 +911,41 @@ func (r *runner) Lint(args []string) error {
 	if _, err := r.compile(false, false, false, meta); err != nil {
 		return err
 	}
-	return r.lint(meta)
+	return r.lint(meta, summary)
 }
 
-func (r *runner) lint(meta *meta) error {
+func (r *runner) lint(meta *meta, summary bool) error {
 	r.logger.Debug("calling LintRunner")
 	failures, err := r.newLintRunner().Run(meta.ProtoSet)
 	if err != nil {
 		return err
 	}
-	if err := r.printFailures("", meta, failures...); err != nil {
+	return r.handleLintFailures(failures, meta, summary)
+}
+
+// handleLintFailures prints the lint failures or their summary, and
+// returns an error if lint should fail.
+func (r *runner) handleLintFailures(failures []*text.Failure, meta *meta, summary bool) error {
+	if summary {
+		r.metricsRecorder.AddFailures(failures...)
+		if err := setFailureOwners(meta, failures); err != nil {
+			return err
+		}
+		if r.envelopeBuilder != nil {
+			r.envelopeBuilder.AddFailures(failures...)
+		}
+		if err := r.printLintSummary(failures); err != nil {
+			return err
+		}
+	} else if err := r.printFailures("", meta, failures...); err != nil {
 		return err
 	}
-	if len(failures) > 0 {
-		return newExitErrorf(255, "")
+	r.notifyWebhook("lint", meta, failures)
+	if text.ContainsError(failures...) {
+		return r.newLintFailuresExitError()
+	}
+	if numWarnings := text.CountWarnings(failures...); r.maxWarnings >= 0 && numWarnings > r.maxWarnings {
+		return newExitErrorf(255, "%d lint warnings exceeded the maximum of %d", numWarnings, r.maxWarnings)
 	}
 	return nil
 }
@@ -373,12 +974,78 @@ func (r *runner) ListLintGroup(group string) error {
 	return r.printLinters(linters)
 }
 
-func (r *runner) ListAllLintGroups() error {
-	groups := make([]string, 0, len(lint.GroupToLinters))
-	for group := range lint.GroupToLinters {
-		groups = append(groups, group)
-	}
-	sort.Strings(groups)
+func (r *runner) LintExplain(id string) error {
+	config, err := r.getConfig(r.workDirPath)
+	if err != nil {
+		return err
+	}
+	explanation, err := lint.Explain(id, config.Lint)
+	if err != nil {
+		return newExitErrorf(255, "%v", err)
+	}
+	lines := []string{
+		explanation.ID,
+		"",
+		explanation.Purpose,
+		"",
+		explanation.Rationale,
+		"",
+		"Bad:",
+		"",
+		indent(explanation.Bad),
+		"",
+		"Good:",
+		"",
+		indent(explanation.Good),
+		"",
+		"Groups: " + strings.Join(explanation.Groups, ", "),
+	}
+	_, err = fmt.Fprintln(r.output, strings.Join(lines, "\n"))
+	return err
+}
+
+func (r *runner) LintList() error {
+	metadatas := lint.AllMetadata()
+	if r.envelopeBuilder != nil {
+		data, err := json.Marshal(metadatas)
+		if err != nil {
+			return err
+		}
+		return r.println(string(data))
+	}
+	tabWriter := newTabWriter(r.output)
+	if _, err := fmt.Fprintln(tabWriter, "ID\tGROUPS\tFIX\tSETTINGS"); err != nil {
+		return err
+	}
+	for _, metadata := range metadatas {
+		if _, err := fmt.Fprintf(
+			tabWriter,
+			"%s\t%s\t%s\t%s\n",
+			metadata.ID,
+			strings.Join(metadata.Groups, ","),
+			orDash(metadata.Fix),
+			orDash(strings.Join(metadata.Settings, ",")),
+		); err != nil {
+			return err
+		}
+	}
+	return tabWriter.Flush()
+}
+
+// orDash returns s, or - if s is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func (r *runner) ListAllLintGroups() error {
+	groups := make([]string, 0, len(lint.GroupToLinters))
+	for group := range lint.GroupToLinters {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
 	for _, group := range groups {
 		if err := r.println(group); err != nil {
 			return err
@@ -387,7 +1054,54 @@ func (r *runner) ListAllLintGroups() error {
 	return nil
 }
 
-func (r *runner) Format(args []string, overwrite, diffMode, lintMode, rewrite bool) error {
+func (r *runner) Format(args []string, overwrite, diffMode, lintMode, statMode, rewrite, checkRoundTrip bool) error {
+	if (overwrite && diffMode) || (overwrite && lintMode) || (diffMode && lintMode) {
+		return newExitErrorf(255, "can only set one of overwrite, diff, lint")
+	}
+	if statMode && !diffMode {
+		return newExitErrorf(255, "can only set stat with diff")
+	}
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	if _, err := r.compile(false, false, false, meta); err != nil {
+		return err
+	}
+	var options []format.TransformerOption
+	if checkRoundTrip {
+		option, err := r.newRoundTripCheckOption(meta)
+		if err != nil {
+			return err
+		}
+		options = append(options, option)
+	}
+	return r.format(overwrite, diffMode, lintMode, statMode, r.newFormatTransformer(rewrite, meta.ProtoSet.Config.Languages, meta.ProtoSet.Config.Format, options...), meta)
+}
+
+func (r *runner) MigrateEditions(args []string, edition string, overwrite, diffMode, lintMode bool) error {
+	if (overwrite && diffMode) || (overwrite && lintMode) || (diffMode && lintMode) {
+		return newExitErrorf(255, "can only set one of overwrite, diff, lint")
+	}
+	if edition == "" {
+		edition = editions.DefaultEdition
+	}
+	if !editions.IsSupported(edition) {
+		return newExitErrorf(255, "unsupported edition %q, supported editions are %v", edition, editions.SupportedEditions)
+	}
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	if _, err := r.compile(false, false, false, meta); err != nil {
+		return err
+	}
+	return r.format(overwrite, diffMode, lintMode, false, r.newTransformer(format.TransformerWithEdition(edition)), meta)
+}
+
+func (r *runner) MigrateProto3(args []string, overwrite, diffMode, lintMode bool) error {
 	if (overwrite && diffMode) || (overwrite && lintMode) || (diffMode && lintMode) {
 		return newExitErrorf(255, "can only set one of overwrite, diff, lint")
 	}
@@ -399,24 +1113,54 @@ func (r *runner) Format(args []string, overwrite, diffMode, lintMode, rewrite bo
 	if _, err := r.compile(false, false, false, meta); err != nil {
 		return err
 	}
-	return r.format(overwrite, diffMode, lintMode, rewrite, meta)
+	return r.format(overwrite, diffMode, lintMode, false, r.newTransformer(format.TransformerWithProto3Migration()), meta)
 }
 
-func (r *runner) format(overwrite, diffMode, lintMode, rewrite bool, meta *meta) error {
+func (r *runner) MigrateEnums(args []string, overwrite, diffMode, lintMode bool) error {
+	if (overwrite && diffMode) || (overwrite && lintMode) || (diffMode && lintMode) {
+		return newExitErrorf(255, "can only set one of overwrite, diff, lint")
+	}
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	if _, err := r.compile(false, false, false, meta); err != nil {
+		return err
+	}
+	enumNaming := lint.GetEnumNaming(meta.ProtoSet.Config.Lint)
+	return r.format(overwrite, diffMode, lintMode, false, r.newTransformer(format.TransformerWithEnumNaming(enumNaming.ValuePrefix, enumNaming.ZeroValueSuffix)), meta)
+}
+
+// format formats the files in the meta with the transformer.
+//
+// In diff mode, the diffs are summarized per directory instead of printed
+// if statMode or --json is set.
+func (r *runner) format(overwrite, diffMode, lintMode, statMode bool, transformer format.Transformer, meta *meta) error {
+	summarize := diffMode && (statMode || r.envelopeBuilder != nil)
 	success := true
+	var diffStats []*formatDiffStat
 	for _, protoFiles := range meta.ProtoSet.DirPathToFiles {
 		for _, protoFile := range protoFiles {
-			fileSuccess, err := r.formatFile(overwrite, diffMode, lintMode, rewrite, meta, protoFile)
+			fileSuccess, diffStat, err := r.formatFile(overwrite, diffMode, lintMode, summarize, transformer, meta, protoFile)
 			if err != nil {
 				return err
 			}
 			if !fileSuccess {
 				success = false
 			}
+			if diffStat != nil {
+				diffStats = append(diffStats, diffStat)
+			}
+		}
+	}
+	if summarize {
+		if err := r.printFormatDiffStats(diffStats); err != nil {
+			return err
 		}
 	}
 	if !success {
-		return newExitErrorf(255, "")
+		return r.newFormatFailuresExitError()
 	}
 	return nil
 }
@@ -424,86 +1168,195 @@ func (r *runner) format(overwrite, diffMode, lintMode, rewrite bool, meta *meta)
 // return true if there was no unexpected diff and we should exit with 0
 // return false if we should exit with non-zero
 // if false and nil error, we will return an ExitError outside of this function
-func (r *runner) formatFile(overwrite bool, diffMode bool, lintMode bool, rewrite bool, meta *meta, protoFile *file.ProtoFile) (bool, error) {
+// if summarize is set and there was a diff, the diff is returned instead of printed
+func (r *runner) formatFile(overwrite bool, diffMode bool, lintMode bool, summarize bool, transformer format.Transformer, meta *meta, protoFile *file.ProtoFile) (bool, *formatDiffStat, error) {
 	input, err := ioutil.ReadFile(protoFile.Path)
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
-	data, failures, err := r.newTransformer(rewrite).Transform(protoFile.Path, input)
+	data, failures, err := transformer.Transform(protoFile.Path, input)
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 	if len(failures) > 0 {
-		return false, r.printFailures(protoFile.DisplayPath, meta, failures...)
+		return false, nil, r.printFailures(protoFile.DisplayPath, meta, failures...)
 	}
 	if !bytes.Equal(input, data) {
 		if overwrite {
 			// 0 exit code in overwrite case
-			return true, ioutil.WriteFile(protoFile.Path, data, os.ModePerm)
+			return true, nil, ioutil.WriteFile(protoFile.Path, data, os.ModePerm)
 		}
 		if lintMode {
-			return false, r.printFailures("", meta, text.NewFailuref(scanner.Position{
+			return false, nil, r.printFailures("", meta, text.NewFailuref(scanner.Position{
 				Filename: protoFile.DisplayPath,
 			}, "FORMAT_DIFF", "Format returned a diff."))
 		}
 		if diffMode {
-			d, err := diff.Do(input, data, protoFile.DisplayPath)
+			d, err := diff.Do(r.execTracer, input, data, protoFile.DisplayPath)
 			if err != nil {
-				return false, err
+				return false, nil, err
+			}
+			if summarize {
+				insertions, deletions := diff.CountLines(d)
+				return false, &formatDiffStat{
+					Filename:   filepath.ToSlash(protoFile.DisplayPath),
+					Insertions: insertions,
+					Deletions:  deletions,
+				}, nil
 			}
 			if _, err := io.Copy(r.output, bytes.NewReader(d)); err != nil {
-				return false, err
+				return false, nil, err
 			}
-			return false, nil
+			return false, nil, nil
 		}
 		//!overwrite && !lintMode && !diffMode
 		if _, err := io.Copy(r.output, bytes.NewReader(data)); err != nil {
-			return false, err
+			return false, nil, err
 		}
 		// there was a diff, return non-zero exit code
-		return false, nil
+		return false, nil, nil
 	}
 	// we still print the formatted file to stdout
 	if !overwrite && !lintMode && !diffMode {
 		if _, err := io.Copy(r.output, bytes.NewReader(data)); err != nil {
-			return false, err
+			return false, nil, err
 		}
 	}
-	return true, nil
+	return true, nil, nil
 }
 
-func (r *runner) BinaryToJSON(args []string) error {
-	if len(args) < 2 {
-		return nil
+// formatDiffStat is the number of lines formatting changes in a file.
+type formatDiffStat struct {
+	Filename   string `json:"filename"`
+	Insertions int    `json:"insertions"`
+	Deletions  int    `json:"deletions"`
+}
+
+// formatDirDiffStat is the number of files and lines formatting changes
+// in a directory.
+type formatDirDiffStat struct {
+	Directory    string            `json:"directory"`
+	FilesChanged int               `json:"files_changed"`
+	Insertions   int               `json:"insertions"`
+	Deletions    int               `json:"deletions"`
+	Files        []*formatDiffStat `json:"files"`
+}
+
+// printFormatDiffStats prints the number of files and lines formatting
+// changes per directory, sorted by directory, as JSON if --json is set.
+func (r *runner) printFormatDiffStats(diffStats []*formatDiffStat) error {
+	sort.Slice(diffStats, func(i int, j int) bool { return diffStats[i].Filename < diffStats[j].Filename })
+	total := &formatDirDiffStat{}
+	dirDiffStats := make([]*formatDirDiffStat, 0)
+	dirToDiffStat := make(map[string]*formatDirDiffStat)
+	for _, diffStat := range diffStats {
+		dir := path.Dir(diffStat.Filename)
+		dirDiffStat, ok := dirToDiffStat[dir]
+		if !ok {
+			dirDiffStat = &formatDirDiffStat{
+				Directory: dir,
+			}
+			dirToDiffStat[dir] = dirDiffStat
+			dirDiffStats = append(dirDiffStats, dirDiffStat)
+		}
+		for _, stat := range []*formatDirDiffStat{dirDiffStat, total} {
+			stat.FilesChanged++
+			stat.Insertions += diffStat.Insertions
+			stat.Deletions += diffStat.Deletions
+		}
+		dirDiffStat.Files = append(dirDiffStat.Files, diffStat)
+	}
+	sort.Slice(dirDiffStats, func(i int, j int) bool { return dirDiffStats[i].Directory < dirDiffStats[j].Directory })
+	if r.envelopeBuilder != nil {
+		data, err := json.Marshal(struct {
+			FilesChanged int                  `json:"files_changed"`
+			Insertions   int                  `json:"insertions"`
+			Deletions    int                  `json:"deletions"`
+			Directories  []*formatDirDiffStat `json:"directories"`
+		}{
+			FilesChanged: total.FilesChanged,
+			Insertions:   total.Insertions,
+			Deletions:    total.Deletions,
+			Directories:  dirDiffStats,
+		})
+		if err != nil {
+			return err
+		}
+		return r.println(string(data))
 	}
-	path := args[len(args)-2]
-	data, err := r.getInputData(args[len(args)-1])
-	if err != nil {
+	tabWriter := newTabWriter(r.output)
+	if _, err := fmt.Fprintln(tabWriter, "DIRECTORY\tFILES\tINSERTIONS\tDELETIONS"); err != nil {
 		return err
 	}
-	args = args[:len(args)-2]
-
-	meta, err := r.getMeta(args)
-	if err != nil {
-		return err
+	for _, dirDiffStat := range dirDiffStats {
+		if _, err := fmt.Fprintf(tabWriter, "%s\t%d\t%d\t%d\n", dirDiffStat.Directory, dirDiffStat.FilesChanged, dirDiffStat.Insertions, dirDiffStat.Deletions); err != nil {
+			return err
+		}
 	}
-	r.printAffectedFiles(meta)
-	fileDescriptorSets, err := r.compile(false, true, false, meta)
-	if err != nil {
+	if _, err := fmt.Fprintf(tabWriter, "total\t%d\t%d\t%d\n", total.FilesChanged, total.Insertions, total.Deletions); err != nil {
 		return err
 	}
-	if len(fileDescriptorSets) == 0 {
-		return fmt.Errorf("no FileDescriptorSets returned")
+	return tabWriter.Flush()
+}
+
+func (r *runner) BinaryToJSON(args []string, delimited bool) error {
+	if delimited {
+		return r.convertDelimited(args, reflect.Handler.BinaryToJSON, splitLengthDelimited, writeNewlineDelimitedJSON)
 	}
-	out, err := r.newReflectHandler().BinaryToJSON(fileDescriptorSets, path, data)
-	if err != nil {
-		return err
+	return r.convert(args, reflect.Handler.BinaryToJSON)
+}
+
+func (r *runner) JSONToBinary(args []string, delimited bool) error {
+	if delimited {
+		return r.convertDelimited(args, reflect.Handler.JSONToBinary, splitJSONStream, writeLengthDelimited)
 	}
-	_, err = r.output.Write(out)
-	return err
+	return r.convert(args, reflect.Handler.JSONToBinary)
+}
+
+func (r *runner) BinaryToText(args []string) error {
+	return r.convert(args, reflect.Handler.BinaryToText)
+}
+
+func (r *runner) TextToBinary(args []string) error {
+	return r.convert(args, reflect.Handler.TextToBinary)
+}
+
+func (r *runner) JSONToText(args []string) error {
+	return r.convert(args, reflect.Handler.JSONToText)
+}
+
+func (r *runner) BinaryToYAML(args []string) error {
+	return r.convert(args, reflect.Handler.BinaryToYAML)
+}
+
+func (r *runner) YAMLToBinary(args []string) error {
+	return r.convert(args, reflect.Handler.YAMLToBinary)
+}
+
+type convertFunc func(reflect.Handler, []*descriptor.FileDescriptorSet, string, []byte) ([]byte, error)
+
+// convert takes args of the form dirOrProtoFiles... messagePath data
+// and writes the result of the conversion to the output.
+func (r *runner) convert(args []string, f convertFunc) error {
+	return r.convertDelimited(
+		args,
+		f,
+		func(data []byte) ([][]byte, error) { return [][]byte{data}, nil },
+		func(writer io.Writer, data []byte) error {
+			_, err := writer.Write(data)
+			return err
+		},
+	)
 }
 
-func (r *runner) JSONToBinary(args []string) error {
+// convertDelimited is like convert, but splits the data into multiple
+// messages with split, and writes the result of each conversion with write.
+func (r *runner) convertDelimited(
+	args []string,
+	f convertFunc,
+	split func([]byte) ([][]byte, error),
+	write func(io.Writer, []byte) error,
+) error {
 	if len(args) < 2 {
 		return nil
 	}
@@ -514,24 +1367,78 @@ func (r *runner) JSONToBinary(args []string) error {
 	}
 	args = args[:len(args)-2]
 
-	meta, err := r.getMeta(args)
+	fileDescriptorSets, config, err := r.getFileDescriptorSets(args)
+	if err != nil {
+		return err
+	}
+	messages, err := split(data)
 	if err != nil {
 		return err
 	}
+	reflectHandler := r.newReflectHandler(config)
+	for i, message := range messages {
+		out, err := f(reflectHandler, fileDescriptorSets, path, message)
+		if err != nil {
+			if len(messages) > 1 {
+				return fmt.Errorf("message %d: %v", i, err)
+			}
+			return err
+		}
+		if err := write(r.output, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getFileDescriptorSets compiles the files for args and returns the resulting
+// FileDescriptorSets along with the config for the files.
+//
+// If a descriptor set path was given, the FileDescriptorSet is read from it
+// instead, and the config is the config for the working directory.
+func (r *runner) getFileDescriptorSets(args []string) ([]*descriptor.FileDescriptorSet, settings.Config, error) {
+	if r.descriptorSetPath != "" {
+		if len(args) > 0 {
+			return nil, settings.Config{}, newExitErrorf(255, "cannot specify both files and descriptor-set")
+		}
+		config, err := r.getConfig(r.workDirPath)
+		if err != nil {
+			return nil, settings.Config{}, err
+		}
+		fileDescriptorSet, err := r.readFileDescriptorSet(r.descriptorSetPath)
+		if err != nil {
+			return nil, settings.Config{}, err
+		}
+		return []*descriptor.FileDescriptorSet{fileDescriptorSet}, config, nil
+	}
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return nil, settings.Config{}, err
+	}
 	r.printAffectedFiles(meta)
 	fileDescriptorSets, err := r.compile(false, true, false, meta)
 	if err != nil {
-		return err
+		return nil, settings.Config{}, err
 	}
 	if len(fileDescriptorSets) == 0 {
-		return fmt.Errorf("no FileDescriptorSets returned")
+		return nil, settings.Config{}, fmt.Errorf("no FileDescriptorSets returned")
 	}
-	out, err := r.newReflectHandler().JSONToBinary(fileDescriptorSets, path, data)
+	return fileDescriptorSets, meta.ProtoSet.Config, nil
+}
+
+func (r *runner) readFileDescriptorSet(filePath string) (*descriptor.FileDescriptorSet, error) {
+	data, err := ioutil.ReadFile(filePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	_, err = r.output.Write(out)
-	return err
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, fileDescriptorSet); err != nil {
+		return nil, fmt.Errorf("could not parse FileDescriptorSet from %s: %v", filePath, err)
+	}
+	if len(fileDescriptorSet.File) == 0 {
+		return nil, fmt.Errorf("no FileDescriptorProtos in FileDescriptorSet from %s", filePath)
+	}
+	return fileDescriptorSet, nil
 }
 
 func (r *runner) All(args []string, disableFormat, disableLint, rewrite bool) error {
@@ -540,92 +1447,1470 @@ func (r *runner) All(args []string, disableFormat, disableLint, rewrite bool) er
 		return err
 	}
 	r.printAffectedFiles(meta)
-	if _, err := r.compile(false, false, false, meta); err != nil {
-		return err
-	}
+	formatChanged := false
+	// the files that format parsed and did not change are not parsed
+	// again by lint
+	var filePathToDescriptor map[string]*protoast.Proto
 	if !disableFormat {
-		if err := r.format(true, false, false, rewrite, meta); err != nil {
+		// formatting overwrites the files, so it is always checked
+		roundTripCheckOption, err := r.newRoundTripCheckOption(meta)
+		if err != nil {
+			return err
+		}
+		formatChanged, filePathToDescriptor, err = r.allFormat(r.newFormatTransformer(rewrite, meta.ProtoSet.Config.Languages, meta.ProtoSet.Config.Format, roundTripCheckOption), meta)
+		if err != nil {
 			return err
 		}
 	}
-	if _, err := r.compile(true, false, false, meta); err != nil {
+	// lint only reads the files, so it runs while protoc generates
+	var lintFailures []*text.Failure
+	var lintErr error
+	var wg sync.WaitGroup
+	if !disableLint {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.logger.Debug("calling LintRunner")
+			lintFailures, lintErr = r.newLintRunner(lint.RunnerWithDescriptors(filePathToDescriptor)).Run(meta.ProtoSet)
+		}()
+	}
+	compileResult, compileErr := r.newCompiler(true, false).Compile(meta.ProtoSet)
+	wg.Wait()
+	// results are printed in the order of the stages so that the output
+	// does not depend on which stage finishes first
+	if compileErr != nil {
+		return compileErr
+	}
+	// compile failures do not stop lint failures from being printed, so
+	// that all failures are reported in one run
+	var compileFailuresErr *ExitError
+	if _, err := r.handleCompileResult(compileResult, meta); err != nil {
+		exitErr, ok := err.(*ExitError)
+		if !ok {
+			return err
+		}
+		compileFailuresErr = exitErr
+	} else if err := r.genDoc(meta); err != nil {
 		return err
 	}
 	if !disableLint {
-		return r.lint(meta)
+		if lintErr != nil {
+			return lintErr
+		}
+		if err := r.handleLintFailures(lintFailures, meta, false); err != nil {
+			if _, ok := err.(*ExitError); !ok || compileFailuresErr == nil {
+				return err
+			}
+		}
+	}
+	if compileFailuresErr != nil {
+		return compileFailuresErr
+	}
+	// formatting drift is only reported once the other stages pass so
+	// that compile and lint failures take precedence
+	if formatChanged && r.formatExitCode != 0 {
+		return newExitErrorf(r.formatExitCode, "")
 	}
 	return nil
 }
 
-func (r *runner) GRPC(args, headers []string, address, method, data, callTimeout, connectTimeout, keepaliveTime string, stdin bool) error {
-	if address == "" {
-		return newExitErrorf(255, "must set address")
+// allFormat parses and formats the files with the transformer concurrently
+// and in memory, and only compiles the files to check them before overwriting
+// them if formatting changes or fails for any file. Otherwise, the files are
+// unchanged and the compile that generates checks them.
+//
+// Returns true if any file was changed, and the descriptors of the files
+// that were not changed by path.
+func (r *runner) allFormat(transformer format.Transformer, meta *meta) (bool, map[string]*protoast.Proto, error) {
+	var protoFiles []*file.ProtoFile
+	for _, dirProtoFiles := range meta.ProtoSet.DirPathToFiles {
+		protoFiles = append(protoFiles, dirProtoFiles...)
+	}
+	sort.Slice(protoFiles, func(i int, j int) bool { return protoFiles[i].Path < protoFiles[j].Path })
+	results := make([]*formatResult, len(protoFiles))
+	jobs := r.jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	semaphore := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	stop := r.timer.Start("format")
+	for i, protoFile := range protoFiles {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, protoFile *file.ProtoFile) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			results[i] = transformFile(transformer, protoFile)
+		}(i, protoFile)
+	}
+	wg.Wait()
+	stop()
+	unchanged := true
+	for _, result := range results {
+		if result.err != nil || len(result.failures) > 0 || result.output != nil {
+			unchanged = false
+			break
+		}
 	}
-	if method == "" {
-		return newExitErrorf(255, "must set method")
+	if unchanged {
+		return false, getFormatResultDescriptors(results), nil
 	}
-	if data == "" && !stdin {
-		return newExitErrorf(255, "must set one of data or stdin")
+	if _, err := r.compile(false, false, false, meta); err != nil {
+		return false, nil, err
 	}
-	if data != "" && stdin {
-		return newExitErrorf(255, "must set only one of data or stdin")
+	success := true
+	changed := false
+	for _, result := range results {
+		if result.err != nil {
+			return false, nil, result.err
+		}
+		if len(result.failures) > 0 {
+			if err := r.printFailures(result.protoFile.DisplayPath, meta, result.failures...); err != nil {
+				return false, nil, err
+			}
+			success = false
+			continue
+		}
+		if result.output != nil {
+			if err := ioutil.WriteFile(result.protoFile.Path, result.output, os.ModePerm); err != nil {
+				return false, nil, err
+			}
+			changed = true
+		}
+	}
+	if !success {
+		return false, nil, r.newFormatFailuresExitError()
 	}
-	reader := r.getInputReader(data, stdin)
+	return changed, getFormatResultDescriptors(results), nil
+}
 
-	parsedHeaders := make(map[string]string)
-	for _, header := range headers {
-		split := strings.SplitN(header, ":", 2)
-		if len(split) != 2 {
-			return fmt.Errorf("headers must be key:value but got %s", header)
+// formatResult is the result of formatting a file in memory.
+type formatResult struct {
+	protoFile *file.ProtoFile
+	// output is only set if the file changed so that the contents of
+	// unchanged files are not held in memory
+	output []byte
+	// descriptor is only set if the file did not change
+	descriptor *protoast.Proto
+	failures   []*text.Failure
+	err        error
+}
+
+// transformFile parses the file with the display path so that the
+// descriptor can be used by lint, and formats it.
+func transformFile(transformer format.Transformer, protoFile *file.ProtoFile) *formatResult {
+	result := &formatResult{
+		protoFile: protoFile,
+	}
+	input, err := ioutil.ReadFile(protoFile.Path)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	descriptor, syntaxFailures, err := syntax.Parse(bytes.NewReader(input), protoFile.DisplayPath)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	if len(syntaxFailures) > 0 {
+		result.failures = syntaxFailures
+		return result
+	}
+	output, failures, err := transformer.TransformDescriptor(protoFile.Path, input, descriptor)
+	result.failures = failures
+	result.err = err
+	if err == nil && len(failures) == 0 {
+		if bytes.Equal(input, output) {
+			result.descriptor = descriptor
+		} else {
+			result.output = output
 		}
-		parsedHeaders[split[0]] = split[1]
 	}
-	var parsedCallTimeout time.Duration
-	var parsedConnectTimeout time.Duration
-	var parsedKeepaliveTime time.Duration
-	var err error
-	if callTimeout != "" {
-		parsedCallTimeout, err = time.ParseDuration(callTimeout)
+	return result
+}
+
+// getFormatResultDescriptors returns the descriptors of the files that
+// formatting did not change by path.
+func getFormatResultDescriptors(results []*formatResult) map[string]*protoast.Proto {
+	filePathToDescriptor := make(map[string]*protoast.Proto, len(results))
+	for _, result := range results {
+		if result.descriptor != nil {
+			filePathToDescriptor[result.protoFile.Path] = result.descriptor
+		}
+	}
+	return filePathToDescriptor
+}
+
+func (r *runner) GRPC(args []string, options GRPCOptions) error {
+	if options.Address != "" && options.Target != "" {
+		return newExitErrorf(255, "must set only one of address or target")
+	}
+	if options.List {
+		if options.Method != "" || options.Data != "" || options.Stdin || options.Interactive {
+			return newExitErrorf(255, "must not set method, data, stdin, or interactive with list")
+		}
+		if options.Output != "" || (options.OutputFormat != "" && options.OutputFormat != grpc.OutputFormatJSON) || options.PrintMetadata {
+			return newExitErrorf(255, "must not set output, output-format, or print-metadata with list")
+		}
+	} else if options.Address == "" && options.Target == "" {
+		return newExitErrorf(255, "must set address or target")
+	}
+	if options.Interactive {
+		if options.Method != "" || options.Data != "" || options.Stdin {
+			return newExitErrorf(255, "must not set method, data, or stdin with interactive")
+		}
+	} else if options.Method == "" && !options.List {
+		return newExitErrorf(255, "must set method")
+	}
+	if options.Data != "" && options.Stdin {
+		return newExitErrorf(255, "must set only one of data or stdin")
+	}
+	if options.AuthToken != "" && options.AuthTokenFile != "" {
+		return newExitErrorf(255, "must set only one of auth-token or auth-token-file")
+	}
+	switch options.OutputFormat {
+	case "", grpc.OutputFormatJSON, grpc.OutputFormatBinary, grpc.OutputFormatText:
+	default:
+		return newExitErrorf(255, "output-format must be json, binary, or text but was %q", options.OutputFormat)
+	}
+	if options.Interactive && (options.Output != "" || (options.OutputFormat != "" && options.OutputFormat != grpc.OutputFormatJSON)) {
+		return newExitErrorf(255, "must not set output or output-format with interactive")
+	}
+	if options.PrintMetadata && options.OutputFormat == grpc.OutputFormatBinary {
+		return newExitErrorf(255, "must not set print-metadata with output-format binary")
+	}
+	if options.MaxRecvMsgSize < 0 || options.MaxSendMsgSize < 0 {
+		return newExitErrorf(255, "max-recv-msg-size and max-send-msg-size must not be negative")
+	}
+	if options.MaxAttempts < 1 {
+		return newExitErrorf(255, "max-attempts must be at least 1 but was %d", options.MaxAttempts)
+	}
+	if options.CancelAfterBytes < 0 {
+		return newExitErrorf(255, "cancel-after-bytes must not be negative")
+	}
+	if (options.Deadline != "" || options.CancelAfter != "" || options.CancelAfterBytes != 0) && (options.List || options.Interactive) {
+		return newExitErrorf(255, "must not set deadline, cancel-after, or cancel-after-bytes with list or interactive")
+	}
+	hasExpectations := options.ExpectJSON != "" || options.ExpectCode != "" || len(options.ExpectFields) > 0
+	if hasExpectations && (options.List || options.Interactive) {
+		return newExitErrorf(255, "must not set expect-json, expect-code, or expect-field with list or interactive")
+	}
+	if options.Record != "" && (options.List || options.Interactive) {
+		return newExitErrorf(255, "must not set record with list or interactive")
+	}
+	if len(options.Fields) > 0 && (options.List || options.Interactive) {
+		return newExitErrorf(255, "must not set fields with list or interactive")
+	}
+	parsedRetryCodes := make([]codes.Code, 0, len(options.RetryCodes))
+	for _, retryCode := range options.RetryCodes {
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(retryCode)))); err != nil {
+			return newExitErrorf(255, "unknown retry code %q", retryCode)
+		}
+		parsedRetryCodes = append(parsedRetryCodes, code)
+	}
+	var expectations *grpc.Expectations
+	if hasExpectations {
+		var err error
+		expectations, err = getGRPCExpectations(options.ExpectJSON, options.ExpectCode, options.ExpectFields)
 		if err != nil {
 			return err
 		}
 	}
-	if connectTimeout != "" {
-		parsedConnectTimeout, err = time.ParseDuration(connectTimeout)
+	// if there is no data, the handler uses default requests
+	var reader io.Reader
+	if options.Data != "" || options.Stdin {
+		reader = r.getInputReader(options.Data, options.Stdin)
+	}
+	secretResolver := r.newSecretResolver()
+	if options.AuthTokenFile != "" {
+		authTokenData, err := ioutil.ReadFile(options.AuthTokenFile)
 		if err != nil {
 			return err
 		}
-	}
-	if keepaliveTime != "" {
-		parsedKeepaliveTime, err = time.ParseDuration(keepaliveTime)
+		options.AuthToken = strings.TrimSpace(string(authTokenData))
+	} else if options.AuthToken != "" {
+		var err error
+		options.AuthToken, err = secretResolver.Resolve(options.AuthToken)
 		if err != nil {
 			return err
 		}
 	}
 
-	meta, err := r.getMeta(args)
+	parsedHeaders, err := getGRPCHeaders(options.Headers, options.HeaderEnvPrefix, os.Environ())
 	if err != nil {
 		return err
 	}
-	r.printAffectedFiles(meta)
-	fileDescriptorSets, err := r.compile(false, true, false, meta)
-	if err != nil {
-		return err
+	var parsedCallTimeout time.Duration
+	var parsedConnectTimeout time.Duration
+	var parsedKeepaliveTime time.Duration
+	var parsedRetryBackoff time.Duration
+	var parsedDeadline time.Duration
+	var parsedCancelAfter time.Duration
+	if options.CallTimeout != "" {
+		parsedCallTimeout, err = time.ParseDuration(options.CallTimeout)
+		if err != nil {
+			return err
+		}
+	}
+	if options.ConnectTimeout != "" {
+		parsedConnectTimeout, err = time.ParseDuration(options.ConnectTimeout)
+		if err != nil {
+			return err
+		}
+	}
+	if options.KeepaliveTime != "" {
+		parsedKeepaliveTime, err = time.ParseDuration(options.KeepaliveTime)
+		if err != nil {
+			return err
+		}
+	}
+	if options.RetryBackoff != "" {
+		parsedRetryBackoff, err = time.ParseDuration(options.RetryBackoff)
+		if err != nil {
+			return err
+		}
+	}
+	if options.Deadline != "" {
+		parsedDeadline, err = time.ParseDuration(options.Deadline)
+		if err != nil {
+			return err
+		}
+		if parsedDeadline <= 0 {
+			return newExitErrorf(255, "deadline must be positive but was %q", options.Deadline)
+		}
+	}
+	if options.CancelAfter != "" {
+		parsedCancelAfter, err = time.ParseDuration(options.CancelAfter)
+		if err != nil {
+			return err
+		}
+		if parsedCancelAfter <= 0 {
+			return newExitErrorf(255, "cancel-after must be positive but was %q", options.CancelAfter)
+		}
+	}
+
+	fileDescriptorSets, config, err := r.getFileDescriptorSets(args)
+	if err != nil {
+		return err
+	}
+	var tlsConfig *grpc.TLSConfig
+	if options.Target != "" {
+		options.Address, options.AuthToken, tlsConfig, err = r.resolveGRPCTarget(config, secretResolver, options.Target, parsedHeaders, options.AuthToken)
+		if err != nil {
+			return err
+		}
+	}
+	// there is a recording per request if data is a JSON array of requests
+	var recordings []*grpc.Recording
+	var recordFunc func(*grpc.Recording)
+	if options.Record != "" {
+		recordFunc = func(callRecording *grpc.Recording) { recordings = append(recordings, callRecording) }
+	}
+	handler := r.newGRPCHandler(
+		config,
+		parsedHeaders,
+		parsedCallTimeout,
+		parsedConnectTimeout,
+		parsedKeepaliveTime,
+		options.Compress,
+		options.Authority,
+		options.UserAgent,
+		options.AuthToken,
+		tlsConfig,
+		options.OutputFormat,
+		options.PrintMetadata,
+		options.WaitForReady,
+		options.MaxRecvMsgSize,
+		options.MaxSendMsgSize,
+		options.MaxAttempts,
+		parsedRetryBackoff,
+		parsedRetryCodes,
+		parsedDeadline,
+		parsedCancelAfter,
+		options.CancelAfterBytes,
+		options.Fields,
+		expectations,
+		recordFunc,
+	)
+	if options.List {
+		methods, err := handler.List(fileDescriptorSets, options.Address)
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			if err := r.println(method.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if options.Interactive {
+		return handler.Interactive(fileDescriptorSets, options.Address, r.input, r.output)
+	}
+	invokeErr := r.grpcInvoke(handler, fileDescriptorSets, options.Address, options.Method, reader, options.Output)
+	for _, recording := range recordings {
+		if err := appendGRPCRecording(options.Record, recording); err != nil {
+			return err
+		}
+	}
+	return invokeErr
+}
+
+func (r *runner) GRPCProxy(args []string, options GRPCProxyOptions) error {
+	if options.Address != "" && options.Target != "" {
+		return newExitErrorf(255, "must set only one of address or target")
+	}
+	if options.Address == "" && options.Target == "" {
+		return newExitErrorf(255, "must set address or target")
+	}
+	if options.Listen == "" {
+		return newExitErrorf(255, "must set listen")
+	}
+	if options.AuthToken != "" && options.AuthTokenFile != "" {
+		return newExitErrorf(255, "must set only one of auth-token or auth-token-file")
+	}
+	secretResolver := r.newSecretResolver()
+	if options.AuthTokenFile != "" {
+		authTokenData, err := ioutil.ReadFile(options.AuthTokenFile)
+		if err != nil {
+			return err
+		}
+		options.AuthToken = strings.TrimSpace(string(authTokenData))
+	} else if options.AuthToken != "" {
+		var err error
+		options.AuthToken, err = secretResolver.Resolve(options.AuthToken)
+		if err != nil {
+			return err
+		}
+	}
+	parsedHeaders, err := getGRPCHeaders(options.Headers, options.HeaderEnvPrefix, os.Environ())
+	if err != nil {
+		return err
+	}
+	var parsedConnectTimeout time.Duration
+	if options.ConnectTimeout != "" {
+		parsedConnectTimeout, err = time.ParseDuration(options.ConnectTimeout)
+		if err != nil {
+			return err
+		}
+	}
+	fileDescriptorSets, config, err := r.getFileDescriptorSets(args)
+	if err != nil {
+		return err
+	}
+	var tlsConfig *grpc.TLSConfig
+	if options.Target != "" {
+		options.Address, options.AuthToken, tlsConfig, err = r.resolveGRPCTarget(config, secretResolver, options.Target, parsedHeaders, options.AuthToken)
+		if err != nil {
+			return err
+		}
+	}
+	// the proxy serializes calls to the record function
+	var recordFunc func(*grpc.Recording)
+	if options.Record != "" {
+		recordFunc = func(recording *grpc.Recording) {
+			if err := appendGRPCRecording(options.Record, recording); err != nil {
+				r.logger.Warn("could not record call", zap.String("method", recording.Method), zap.Error(err))
+			}
+		}
+	}
+	handler := r.newGRPCHandler(
+		config,
+		parsedHeaders,
+		0,
+		parsedConnectTimeout,
+		0,
+		"",
+		options.Authority,
+		options.UserAgent,
+		options.AuthToken,
+		tlsConfig,
+		"",
+		false,
+		false,
+		0,
+		0,
+		1,
+		0,
+		nil,
+		0,
+		0,
+		0,
+		nil,
+		nil,
+		recordFunc,
+	)
+	listener, err := net.Listen("tcp", options.Listen)
+	if err != nil {
+		return err
+	}
+	r.logger.Info("proxying", zap.String("listen", listener.Addr().String()), zap.String("address", options.Address))
+	return handler.Proxy(fileDescriptorSets, options.Address, listener, r.output)
+}
+
+// resolveGRPCTarget returns the address, auth token, and TLS config of the
+// target in the config, adding the headers of the target to the headers.
+//
+// The auth token is only replaced if it is empty.
+func (r *runner) resolveGRPCTarget(config settings.Config, secretResolver secret.Resolver, target string, headers map[string]string, authToken string) (string, string, *grpc.TLSConfig, error) {
+	grpcTarget, ok := config.GRPC.Targets[target]
+	if !ok {
+		return "", "", nil, newExitErrorf(255, "unknown target %q, targets must be configured in grpc.targets", target)
+	}
+	address, err := secretResolver.Resolve(grpcTarget.Address)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if address == "" {
+		return "", "", nil, newExitErrorf(255, "target %q has an empty address", target)
+	}
+	if err := addGRPCTargetHeaders(secretResolver, headers, grpcTarget.Headers); err != nil {
+		return "", "", nil, err
+	}
+	if authToken == "" && grpcTarget.AuthToken != "" {
+		authToken, err = secretResolver.Resolve(grpcTarget.AuthToken)
+		if err != nil {
+			return "", "", nil, err
+		}
+	}
+	if grpcTarget.TLS == nil {
+		return address, authToken, nil, nil
+	}
+	return address, authToken, &grpc.TLSConfig{
+		CACertPath:         grpcTarget.TLS.CACertPath,
+		CertPath:           grpcTarget.TLS.CertPath,
+		KeyPath:            grpcTarget.TLS.KeyPath,
+		InsecureSkipVerify: grpcTarget.TLS.InsecureSkipVerify,
+		ServerName:         grpcTarget.TLS.ServerName,
+	}, nil
+}
+
+// grpcInvoke invokes the method, writing the responses to the output file,
+// or to stdout if the output file is empty.
+func (r *runner) grpcInvoke(handler grpc.Handler, fileDescriptorSets []*descriptor.FileDescriptorSet, address string, method string, reader io.Reader, output string) error {
+	if output == "" {
+		return handler.Invoke(fileDescriptorSets, address, method, reader, r.output)
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := handler.Invoke(fileDescriptorSets, address, method, reader, file); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// appendGRPCRecording appends the recording to the scenario file,
+// creating the file if it does not exist.
+func appendGRPCRecording(record string, recording *grpc.Recording) error {
+	scenarioData, err := ioutil.ReadFile(record)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	scenarioData, err = scenario.AppendRecording(scenarioData, recording)
+	if err != nil {
+		return newExitErrorf(255, "%s: %v", record, err)
+	}
+	return ioutil.WriteFile(record, scenarioData, 0644)
+}
+
+func (r *runner) GRPCMethods(args []string) error {
+	fileDescriptorSets, _, err := r.getFileDescriptorSets(args)
+	if err != nil {
+		return err
+	}
+	for _, method := range grpc.GetMethods(fileDescriptorSets) {
+		if err := r.println(method); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) BazelGen(args []string, dryRun bool) error {
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	if _, err := r.compile(false, false, false, meta); err != nil {
+		return err
+	}
+	buildFiles, err := r.newBazelGenerator().Generate(meta.ProtoSet)
+	if err != nil {
+		return err
+	}
+	for _, buildFile := range buildFiles {
+		if dryRun {
+			if err := r.println("# " + buildFile.Path); err != nil {
+				return err
+			}
+			if _, err := r.output.Write(buildFile.Data); err != nil {
+				return err
+			}
+			continue
+		}
+		r.logger.Debug("writing BUILD file", zap.String("path", buildFile.Path))
+		if err := ioutil.WriteFile(buildFile.Path, buildFile.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) ConfigExport(args []string, format string, dryRun bool) error {
+	if format != "buf" {
+		return newExitErrorf(255, "unknown config export format %q", format)
+	}
+	dirPath := "."
+	if len(args) == 1 {
+		dirPath = args[0]
+	}
+	absDirPath, err := absClean(dirPath)
+	if err != nil {
+		return err
+	}
+	config, err := r.getConfig(absDirPath)
+	if err != nil {
+		return err
+	}
+	if config.DirPath == "" {
+		return newExitErrorf(255, "no %s found in %s or a parent directory", settings.DefaultConfigFilename, dirPath)
+	}
+	bufYAMLData, bufGenYAMLData, err := cfginit.GenerateBuf(config)
+	if err != nil {
+		return newExitErrorf(255, "%v", err)
+	}
+	for _, bufFile := range []struct {
+		Path string
+		Data []byte
+	}{
+		{Path: filepath.Join(config.DirPath, "buf.yaml"), Data: bufYAMLData},
+		{Path: filepath.Join(config.DirPath, "buf.gen.yaml"), Data: bufGenYAMLData},
+	} {
+		if bufFile.Data == nil {
+			continue
+		}
+		if dryRun {
+			if err := r.println("# " + bufFile.Path); err != nil {
+				return err
+			}
+			if _, err := r.output.Write(bufFile.Data); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := os.Stat(bufFile.Path); err == nil {
+			return newExitErrorf(255, "%s already exists", bufFile.Path)
+		}
+		if err := ioutil.WriteFile(bufFile.Path, bufFile.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) ConfigIncludes(args []string, explain bool) error {
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	protocCommands, err := r.newCompiler(false, false).ProtocPlan(meta.ProtoSet)
+	if err != nil {
+		return err
+	}
+	var includePaths []string
+	seenIncludePaths := make(map[string]struct{})
+	seenDirPaths := make(map[string]struct{})
+	usedIncludePaths := make(map[string]struct{})
+	var imports []*includes.Import
+	explainer := includes.NewExplainer(includes.ExplainerWithLogger(r.logger))
+	for _, protocCommand := range protocCommands {
+		for _, includePath := range protocCommand.IncludePaths {
+			if _, ok := seenIncludePaths[includePath]; !ok {
+				seenIncludePaths[includePath] = struct{}{}
+				includePaths = append(includePaths, includePath)
+			}
+		}
+		if _, ok := seenDirPaths[protocCommand.DirPath]; ok || !explain {
+			continue
+		}
+		seenDirPaths[protocCommand.DirPath] = struct{}{}
+		explanation, err := explainer.Explain(meta.ProtoSet.DirPathToFiles[protocCommand.DirPath], protocCommand.IncludePaths)
+		if err != nil {
+			return err
+		}
+		imports = append(imports, explanation.Imports...)
+		for _, includePath := range explanation.UsedIncludePaths {
+			usedIncludePaths[includePath] = struct{}{}
+		}
+	}
+	if !explain {
+		for _, includePath := range includePaths {
+			if err := r.println(r.getIncludeDisplayPath(includePath)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// only the include paths in the config can be removed, so only
+	// these are checked for whether they are used
+	var unusedIncludePaths []string
+	configIncludePaths := append([]string{}, meta.ProtoSet.Config.Compile.IncludePaths...)
+	for _, root := range meta.ProtoSet.Config.Compile.Roots {
+		configIncludePaths = append(configIncludePaths, root.IncludePaths...)
+	}
+	for _, includePath := range strs.DedupeSort(configIncludePaths, nil) {
+		if _, ok := usedIncludePaths[includePath]; !ok {
+			unusedIncludePaths = append(unusedIncludePaths, includePath)
+		}
+	}
+	if r.envelopeBuilder != nil {
+		data, err := json.Marshal(struct {
+			IncludePaths       []string           `json:"include_paths"`
+			Imports            []*includes.Import `json:"imports"`
+			UnusedIncludePaths []string           `json:"unused_include_paths"`
+		}{
+			IncludePaths:       includePaths,
+			Imports:            imports,
+			UnusedIncludePaths: unusedIncludePaths,
+		})
+		if err != nil {
+			return err
+		}
+		return r.println(string(data))
+	}
+	for _, importInfo := range imports {
+		line := fmt.Sprintf("%s:%d:%d: %q ", importInfo.Filename, importInfo.Line, importInfo.Column, importInfo.ImportFilename)
+		if importInfo.IncludePath == "" {
+			line += "not found in any include path"
+		} else {
+			line += "from " + r.getIncludeDisplayPath(importInfo.IncludePath)
+		}
+		for _, shadowedIncludePath := range importInfo.ShadowedIncludePaths {
+			line += ", shadowing " + r.getIncludeDisplayPath(shadowedIncludePath)
+		}
+		if err := r.println(line); err != nil {
+			return err
+		}
+	}
+	for _, includePath := range unusedIncludePaths {
+		if err := r.println("unused include path " + r.getIncludeDisplayPath(includePath)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) AnnotationsList(args []string, optionNames []string, outputFormat string) error {
+	switch outputFormat {
+	case "", "text", "json", "csv":
+	default:
+		return newExitErrorf(255, "output-format must be text, json, or csv but was %q", outputFormat)
+	}
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	var protoFiles []*file.ProtoFile
+	for _, files := range meta.ProtoSet.DirPathToFiles {
+		protoFiles = append(protoFiles, files...)
+	}
+	annotationList, err := annotations.NewExtractor(annotations.ExtractorWithLogger(r.logger)).Extract(protoFiles, optionNames)
+	if err != nil {
+		return err
+	}
+	if r.envelopeBuilder != nil || outputFormat == "json" {
+		if annotationList == nil {
+			annotationList = []*annotations.Annotation{}
+		}
+		data, err := json.Marshal(annotationList)
+		if err != nil {
+			return err
+		}
+		return r.println(string(data))
+	}
+	if outputFormat == "csv" {
+		csvWriter := csv.NewWriter(r.output)
+		if err := csvWriter.Write([]string{"filename", "line", "column", "element_type", "element", "option", "value"}); err != nil {
+			return err
+		}
+		for _, annotation := range annotationList {
+			if err := csvWriter.Write([]string{
+				annotation.Filename,
+				strconv.Itoa(annotation.Line),
+				strconv.Itoa(annotation.Column),
+				annotation.ElementType,
+				annotation.Element,
+				annotation.Option,
+				annotation.Value,
+			}); err != nil {
+				return err
+			}
+		}
+		csvWriter.Flush()
+		return csvWriter.Error()
+	}
+	for _, annotation := range annotationList {
+		if err := r.println(fmt.Sprintf("%s:%d:%d: %s %s %s = %s", annotation.Filename, annotation.Line, annotation.Column, annotation.ElementType, annotation.Element, annotation.Option, annotation.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getIncludeDisplayPath returns the include path relative to the working
+// directory if it is in the working directory, otherwise the include path.
+func (r *runner) getIncludeDisplayPath(includePath string) string {
+	relPath, err := filepath.Rel(r.workDirPath, includePath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return includePath
+	}
+	return relPath
+}
+
+func (r *runner) Test(args, headers []string, address, callTimeout, connectTimeout string) error {
+	scenarioFile := args[len(args)-1]
+	args = args[:len(args)-1]
+	scenarioData, err := ioutil.ReadFile(scenarioFile)
+	if err != nil {
+		return err
+	}
+	parsedHeaders, err := getGRPCHeaders(headers, "", os.Environ())
+	if err != nil {
+		return err
+	}
+	var parsedCallTimeout time.Duration
+	var parsedConnectTimeout time.Duration
+	if callTimeout != "" {
+		parsedCallTimeout, err = time.ParseDuration(callTimeout)
+		if err != nil {
+			return err
+		}
+	}
+	if connectTimeout != "" {
+		parsedConnectTimeout, err = time.ParseDuration(connectTimeout)
+		if err != nil {
+			return err
+		}
+	}
+	fileDescriptorSets, config, err := r.getFileDescriptorSets(args)
+	if err != nil {
+		return err
+	}
+	handlerOptions := []grpc.HandlerOption{
+		grpc.HandlerWithLogger(r.logger),
+		grpc.HandlerWithExecTracer(r.execTracer),
+		grpc.HandlerWithJSONMarshaler(r.getJSONMarshaler(config, 0)),
+	}
+	for key, value := range parsedHeaders {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithHeader(key, value))
+	}
+	if parsedCallTimeout != 0 {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithCallTimeout(parsedCallTimeout))
+	}
+	if parsedConnectTimeout != 0 {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithConnectTimeout(parsedConnectTimeout))
+	}
+	numFailed, err := scenario.NewRunner(
+		scenario.RunnerWithLogger(r.logger),
+		scenario.RunnerWithHandlerOptions(handlerOptions...),
+	).Run(fileDescriptorSets, scenarioData, address, r.output)
+	if err != nil {
+		return newExitErrorf(255, "%s: %v", scenarioFile, err)
+	}
+	if numFailed > 0 {
+		return newExitErrorf(255, "")
+	}
+	return nil
+}
+
+func (r *runner) Serve(args []string, address, fixturesFile string) error {
+	if address == "" {
+		return newExitErrorf(255, "must set address")
+	}
+	var fixtureData []byte
+	if fixturesFile != "" {
+		var err error
+		fixtureData, err = ioutil.ReadFile(fixturesFile)
+		if err != nil {
+			return err
+		}
+	}
+	fileDescriptorSets, _, err := r.getFileDescriptorSets(args)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	if err := r.println(fmt.Sprintf("serving on %s", listener.Addr().String())); err != nil {
+		return err
+	}
+	return r.newMockServer(fixtureData).Serve(fileDescriptorSets, listener)
+}
+
+func (r *runner) GenerateData(args []string, count int, outputFormat string, seed int64) error {
+	if len(args) < 1 {
+		return nil
+	}
+	if count < 1 {
+		return newExitErrorf(255, "count must be at least 1 but was %d", count)
+	}
+	if outputFormat != "json" && outputFormat != "binary" {
+		return newExitErrorf(255, "output-format must be json or binary but was %q", outputFormat)
+	}
+	path := strings.TrimPrefix(args[len(args)-1], ".")
+	args = args[:len(args)-1]
+
+	fileDescriptorSets, config, err := r.getFileDescriptorSets(args)
+	if err != nil {
+		return err
+	}
+	fileDescriptors, err := desc.CreateFileDescriptors(fileDescriptorSets...)
+	if err != nil {
+		return err
+	}
+	var messageDescriptor *reflectdesc.MessageDescriptor
+	for _, fileDescriptor := range fileDescriptors {
+		if messageDescriptor = fileDescriptor.FindMessage(path); messageDescriptor != nil {
+			break
+		}
+	}
+	if messageDescriptor == nil {
+		return fmt.Errorf("no message for path %s", path)
+	}
+	generator := r.newDataGenerator(seed)
+	jsonMarshaler := r.getJSONMarshaler(config, 0)
+	for i := 0; i < count; i++ {
+		dynamicMessage, err := generator.Generate(messageDescriptor)
+		if err != nil {
+			return err
+		}
+		switch outputFormat {
+		case "json":
+			data, err := dynamicMessage.MarshalJSONPB(jsonMarshaler)
+			if err != nil {
+				return err
+			}
+			if err := writeNewlineDelimited(r.output, data); err != nil {
+				return err
+			}
+		case "binary":
+			data, err := dynamicMessage.Marshal()
+			if err != nil {
+				return err
+			}
+			if count == 1 {
+				_, err = r.output.Write(data)
+			} else {
+				err = writeLengthDelimited(r.output, data)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *runner) RegistryPush(args []string, url, subject, basicAuth string) error {
+	if url == "" {
+		return newExitErrorf(255, "must set url")
+	}
+	if subject == "" {
+		return newExitErrorf(255, "must set subject")
+	}
+	client, err := r.newSchemaRegistryClient(url, basicAuth)
+	if err != nil {
+		return err
+	}
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	var protoFiles []*file.ProtoFile
+	for _, dirProtoFiles := range meta.ProtoSet.DirPathToFiles {
+		protoFiles = append(protoFiles, dirProtoFiles...)
+	}
+	if len(protoFiles) != 1 {
+		return newExitErrorf(255, "must push exactly one file but got %d", len(protoFiles))
+	}
+	r.printAffectedFiles(meta)
+	fileDescriptorSets, err := r.compile(false, true, false, meta)
+	if err != nil {
+		return err
+	}
+	nameToFileDescriptorProto := make(map[string]*descriptor.FileDescriptorProto)
+	for _, fileDescriptorSet := range fileDescriptorSets {
+		for _, fileDescriptorProto := range fileDescriptorSet.File {
+			nameToFileDescriptorProto[fileDescriptorProto.GetName()] = fileDescriptorProto
+		}
+	}
+	name, err := file.ImportPath(meta.ProtoSet, protoFiles[0].Path)
+	if err != nil {
+		return err
+	}
+	_, err = r.registerSchema(
+		client,
+		meta.ProtoSet.Config,
+		nameToFileDescriptorProto,
+		filepath.ToSlash(name),
+		subject,
+		make(map[string]*schemaregistry.Reference),
+	)
+	return err
+}
+
+// registerSchema registers the imports of the file with the given name
+// under subjects named by their import paths, and then registers the file
+// under the given subject, checking compatibility before each registration.
+//
+// The Well-Known Types are not registered as the schema registry provides them.
+func (r *runner) registerSchema(
+	client schemaregistry.Client,
+	config settings.Config,
+	nameToFileDescriptorProto map[string]*descriptor.FileDescriptorProto,
+	name string,
+	subject string,
+	nameToReference map[string]*schemaregistry.Reference,
+) (*schemaregistry.Schema, error) {
+	fileDescriptorProto, ok := nameToFileDescriptorProto[name]
+	if !ok {
+		return nil, fmt.Errorf("no FileDescriptorProto for %s", name)
+	}
+	var references []*schemaregistry.Reference
+	for _, dependency := range fileDescriptorProto.GetDependency() {
+		if strings.HasPrefix(dependency, "google/protobuf/") {
+			continue
+		}
+		reference, ok := nameToReference[dependency]
+		if !ok {
+			schema, err := r.registerSchema(client, config, nameToFileDescriptorProto, dependency, dependency, nameToReference)
+			if err != nil {
+				return nil, err
+			}
+			reference = &schemaregistry.Reference{
+				Name:    dependency,
+				Subject: schema.Subject,
+				Version: schema.Version,
+			}
+			nameToReference[dependency] = reference
+		}
+		references = append(references, reference)
+	}
+	data, err := readIncludedFile(config, name)
+	if err != nil {
+		return nil, err
+	}
+	compatible, err := client.IsCompatible(subject, string(data), references)
+	if err != nil {
+		return nil, err
+	}
+	if !compatible {
+		return nil, newExitErrorf(255, "%s is not compatible with the latest version registered under subject %s", name, subject)
+	}
+	schema, err := client.Register(subject, string(data), references)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.println(fmt.Sprintf("%s registered under subject %s with version %d and id %d", name, subject, schema.Version, schema.ID)); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (r *runner) RegistryPull(url, subject, version, basicAuth string) error {
+	if url == "" {
+		return newExitErrorf(255, "must set url")
+	}
+	if subject == "" {
+		return newExitErrorf(255, "must set subject")
+	}
+	if version == "" {
+		version = "latest"
+	}
+	client, err := r.newSchemaRegistryClient(url, basicAuth)
+	if err != nil {
+		return err
+	}
+	schema, err := client.Get(subject, version)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(r.output, schema.Schema)
+	return err
+}
+
+func (r *runner) ModulePush(args []string, registryURL, name, version string) error {
+	if version == "" {
+		return newExitErrorf(255, "must set version")
+	}
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	config := meta.ProtoSet.Config
+	if registryURL == "" {
+		registryURL = config.Modules.RegistryURL
+	}
+	if registryURL == "" {
+		return newExitErrorf(255, "must set url or modules.registry_url in the config file")
+	}
+	if name == "" {
+		name = config.Modules.Name
+	}
+	if name == "" {
+		return newExitErrorf(255, "must set name or modules.name in the config file")
+	}
+	r.printAffectedFiles(meta)
+	fileDescriptorSets, err := r.compile(false, true, false, meta)
+	if err != nil {
+		return err
 	}
-	if len(fileDescriptorSets) == 0 {
-		return fmt.Errorf("no FileDescriptorSets returned")
+	// the FileDescriptorSets are topologically sorted, so keeping the
+	// first of each file keeps the merged FileDescriptorSet sorted
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	names := make(map[string]struct{})
+	for _, iFileDescriptorSet := range fileDescriptorSets {
+		for _, fileDescriptorProto := range iFileDescriptorSet.File {
+			if _, ok := names[fileDescriptorProto.GetName()]; ok {
+				continue
+			}
+			names[fileDescriptorProto.GetName()] = struct{}{}
+			fileDescriptorSet.File = append(fileDescriptorSet.File, fileDescriptorProto)
+		}
 	}
-	return r.newGRPCHandler(
-		parsedHeaders,
-		parsedCallTimeout,
-		parsedConnectTimeout,
-		parsedKeepaliveTime,
-	).Invoke(fileDescriptorSets, address, method, reader, r.output)
+	var files []string
+	for _, protoFiles := range meta.ProtoSet.DirPathToFiles {
+		for _, protoFile := range protoFiles {
+			importPath, err := file.ImportPath(meta.ProtoSet, protoFile.Path)
+			if err != nil {
+				return err
+			}
+			files = append(files, importPath)
+		}
+	}
+	protocVersion, err := protoc.GetProtobufVersion(meta.ProtoSet)
+	if err != nil {
+		return err
+	}
+	if err := r.newModuleClient(registryURL).Push(
+		fileDescriptorSet,
+		&module.Metadata{
+			Name:          name,
+			Version:       version,
+			Files:         strs.DedupeSort(files, nil),
+			ProtocVersion: protocVersion,
+			CreateTime:    time.Now().UTC(),
+		},
+	); err != nil {
+		return err
+	}
+	return r.println(fmt.Sprintf("pushed %s %s", name, version))
+}
+
+func (r *runner) ModuleFetch(args []string, registryURL string) error {
+	dirPath := "."
+	if len(args) == 1 {
+		dirPath = args[0]
+	}
+	absDirPath, err := absClean(dirPath)
+	if err != nil {
+		return err
+	}
+	config, err := r.getConfig(absDirPath)
+	if err != nil {
+		return err
+	}
+	if len(config.Modules.Deps) == 0 {
+		return nil
+	}
+	if registryURL == "" {
+		registryURL = config.Modules.RegistryURL
+	}
+	if registryURL == "" {
+		return newExitErrorf(255, "must set url or modules.registry_url in the config file")
+	}
+	client := r.newModuleClient(registryURL)
+	for _, dep := range config.Modules.Deps {
+		fileDescriptorSet, _, err := client.Fetch(dep.Name, dep.Version)
+		if err != nil {
+			return err
+		}
+		data, err := proto.Marshal(fileDescriptorSet)
+		if err != nil {
+			return err
+		}
+		filePath := module.DescriptorSetFilePath(config.DirPath, dep.Name, dep.Version)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filePath, data, 0644); err != nil {
+			return err
+		}
+		if err := r.println(fmt.Sprintf("fetched %s %s", dep.Name, dep.Version)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) BreakCheck(args []string, gitRef string) error {
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	if err := git.VerifyRef(r.execTracer, meta.ProtoSet.WorkDirPath, gitRef); err != nil {
+		return newExitErrorf(255, "%v", err)
+	}
+	dirPaths := make([]string, 0, len(meta.ProtoSet.DirPathToFiles))
+	for dirPath := range meta.ProtoSet.DirPathToFiles {
+		dirPaths = append(dirPaths, dirPath)
+	}
+	sort.Strings(dirPaths)
+	var files []*breaking.File
+	for _, dirPath := range dirPaths {
+		for _, protoFile := range meta.ProtoSet.DirPathToFiles[dirPath] {
+			currentData, err := ioutil.ReadFile(protoFile.Path)
+			if err != nil {
+				return err
+			}
+			// previousData is nil if the file did not exist at gitRef
+			previousData, _, err := git.ReadFile(r.execTracer, gitRef, protoFile.Path)
+			if err != nil {
+				return err
+			}
+			files = append(files, &breaking.File{
+				Filename:     protoFile.DisplayPath,
+				PreviousData: previousData,
+				CurrentData:  currentData,
+			})
+		}
+	}
+	breakingChecker, err := r.newBreakingChecker(meta.ProtoSet.Config.Break)
+	if err != nil {
+		return err
+	}
+	failures, err := breakingChecker.Check(files...)
+	if err != nil {
+		return err
+	}
+	if err := r.printFailures("", meta, failures...); err != nil {
+		return err
+	}
+	r.notifyWebhook("break", meta, failures)
+	if len(failures) > 0 {
+		return r.newFailuresExitError()
+	}
+	return nil
+}
+
+func (r *runner) Diff(from string, to string, dirPath string) error {
+	if dirPath == "" {
+		dirPath = "."
+	}
+	previousFiles, err := r.getDiffFiles(from, dirPath)
+	if err != nil {
+		return err
+	}
+	currentFiles, err := r.getDiffFiles(to, dirPath)
+	if err != nil {
+		return err
+	}
+	changes, err := breaking.Diff(newBreakingFiles(previousFiles, currentFiles)...)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if err := r.println(change.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getDiffFiles returns the data of the Protobuf files for diff, keyed by
+// their paths relative to the directory they were found in.
+//
+// If fromOrTo is a directory, these are the files in the directory.
+// Otherwise, fromOrTo is a git ref, and these are the files in dirPath
+// at the ref.
+func (r *runner) getDiffFiles(fromOrTo string, dirPath string) (map[string][]byte, error) {
+	if fileInfo, err := os.Stat(fromOrTo); err == nil && fileInfo.IsDir() {
+		meta, err := r.getMeta([]string{fromOrTo})
+		if err != nil {
+			return nil, err
+		}
+		files := make(map[string][]byte)
+		for _, protoFiles := range meta.ProtoSet.DirPathToFiles {
+			for _, protoFile := range protoFiles {
+				relPath, err := filepath.Rel(meta.ProtoSet.DirPath, protoFile.Path)
+				if err != nil {
+					return nil, err
+				}
+				// the ProtoSet has all the files for the config, which can
+				// be outside of the directory
+				if relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+					continue
+				}
+				data, err := ioutil.ReadFile(protoFile.Path)
+				if err != nil {
+					return nil, err
+				}
+				files[filepath.ToSlash(relPath)] = data
+			}
+		}
+		return files, nil
+	}
+	meta, err := r.getMeta([]string{dirPath})
+	if err != nil {
+		return nil, err
+	}
+	if err := git.VerifyRef(r.execTracer, meta.ProtoSet.DirPath, fromOrTo); err != nil {
+		return nil, newExitErrorf(255, "%s is neither a directory nor a valid git ref: %v", fromOrTo, err)
+	}
+	return r.getGitDiffFiles(meta, fromOrTo)
+}
+
+// getGitDiffFiles returns the data of the Protobuf files in the directory
+// of the meta at the git ref, keyed by their paths relative to the
+// directory, excluding the files excluded by the config.
+func (r *runner) getGitDiffFiles(meta *meta, ref string) (map[string][]byte, error) {
+	filePaths, err := git.ListFiles(r.execTracer, ref, meta.ProtoSet.DirPath)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	for _, filePath := range filePaths {
+		absFilePath := filepath.Join(meta.ProtoSet.DirPath, filePath)
+		if filepath.Ext(filePath) != ".proto" || hasAnyPrefix(absFilePath, meta.ProtoSet.Config.ExcludePrefixes) {
+			continue
+		}
+		data, _, err := git.ReadFile(r.execTracer, ref, absFilePath)
+		if err != nil {
+			return nil, err
+		}
+		files[filePath] = data
+	}
+	return files, nil
+}
+
+func (r *runner) Changelog(dirPath string, since string) error {
+	if since == "" {
+		return newExitErrorf(255, "must set since")
+	}
+	if dirPath == "" {
+		dirPath = "."
+	}
+	meta, err := r.getMeta([]string{dirPath})
+	if err != nil {
+		return err
+	}
+	if err := git.VerifyRef(r.execTracer, meta.ProtoSet.DirPath, since); err != nil {
+		return newExitErrorf(255, "%v", err)
+	}
+	gitCommits, err := git.Log(r.execTracer, meta.ProtoSet.DirPath, since)
+	if err != nil {
+		return err
+	}
+	var commits []*breaking.Commit
+	var previousFiles map[string][]byte
+	for i, gitCommit := range gitCommits {
+		// only the first commit needs its parent read, as the commits between
+		// the logged commits did not change any files in the directory
+		if i == 0 {
+			previousFiles, err = r.getGitDiffFiles(meta, gitCommit.Hash+"^")
+			if err != nil {
+				return err
+			}
+		}
+		currentFiles, err := r.getGitDiffFiles(meta, gitCommit.Hash)
+		if err != nil {
+			return err
+		}
+		changes, err := breaking.Diff(newBreakingFiles(previousFiles, currentFiles)...)
+		if err != nil {
+			return err
+		}
+		commits = append(commits, &breaking.Commit{
+			Hash:    gitCommit.Hash,
+			Subject: gitCommit.Subject,
+			Changes: changes,
+		})
+		previousFiles = currentFiles
+	}
+	return breaking.WriteChangelog(r.output, commits...)
+}
+
+// newBreakingFiles returns the files for the union of the paths of the
+// previous and current files, sorted by path.
+func newBreakingFiles(previousFiles map[string][]byte, currentFiles map[string][]byte) []*breaking.File {
+	filenames := make([]string, 0, len(previousFiles)+len(currentFiles))
+	for filename := range previousFiles {
+		filenames = append(filenames, filename)
+	}
+	for filename := range currentFiles {
+		if _, ok := previousFiles[filename]; !ok {
+			filenames = append(filenames, filename)
+		}
+	}
+	sort.Strings(filenames)
+	files := make([]*breaking.File, 0, len(filenames))
+	for _, filename := range filenames {
+		files = append(files, &breaking.File{
+			Filename:     filename,
+			PreviousData: previousFiles[filename],
+			CurrentData:  currentFiles[filename],
+		})
+	}
+	return files
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *runner) Vet(args []string) error {
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	if _, err := r.compile(false, false, false, meta); err != nil {
+		return err
+	}
+	failures, err := r.newVetter().Vet(meta.ProtoSet)
+	if err != nil {
+		return err
+	}
+	if err := r.printFailures("", meta, failures...); err != nil {
+		return err
+	}
+	if len(failures) > 0 {
+		return r.newFailuresExitError()
+	}
+	return nil
 }
 
 func (r *runner) newDownloader(config settings.Config) protoc.Downloader {
 	downloaderOptions := []protoc.DownloaderOption{
 		protoc.DownloaderWithLogger(r.logger),
+		protoc.DownloaderWithExecTracer(r.execTracer),
 	}
 	if r.cachePath != "" {
 		downloaderOptions = append(
@@ -639,12 +2924,32 @@ func (r *runner) newDownloader(config settings.Config) protoc.Downloader {
 			protoc.DownloaderWithProtocURL(r.protocURL),
 		)
 	}
+	if r.protocBinPath != "" {
+		downloaderOptions = append(
+			downloaderOptions,
+			protoc.DownloaderWithProtocBinPath(r.protocBinPath),
+		)
+	}
+	if r.protocWKTPath != "" {
+		downloaderOptions = append(
+			downloaderOptions,
+			protoc.DownloaderWithProtocWKTPath(r.protocWKTPath),
+		)
+	}
+	if r.progressReporter != nil {
+		downloaderOptions = append(
+			downloaderOptions,
+			protoc.DownloaderWithProgress(r.progressReporter),
+		)
+	}
 	return protoc.NewDownloader(config, downloaderOptions...)
 }
 
 func (r *runner) newCompiler(doGen bool, doFileDescriptorSet bool) protoc.Compiler {
 	compilerOptions := []protoc.CompilerOption{
 		protoc.CompilerWithLogger(r.logger),
+		protoc.CompilerWithTimer(r.timer),
+		protoc.CompilerWithExecTracer(r.execTracer),
 	}
 	if r.cachePath != "" {
 		compilerOptions = append(
@@ -658,6 +2963,30 @@ func (r *runner) newCompiler(doGen bool, doFileDescriptorSet bool) protoc.Compil
 			protoc.CompilerWithProtocURL(r.protocURL),
 		)
 	}
+	if r.protocBinPath != "" {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithProtocBinPath(r.protocBinPath),
+		)
+	}
+	if r.protocWKTPath != "" {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithProtocWKTPath(r.protocWKTPath),
+		)
+	}
+	if r.progressReporter != nil {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithProgress(r.progressReporter),
+		)
+	}
+	if r.remoteExecutionURL != "" {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithRemoteExecutionURL(r.remoteExecutionURL),
+		)
+	}
 	if doGen {
 		compilerOptions = append(
 			compilerOptions,
@@ -670,21 +2999,186 @@ func (r *runner) newCompiler(doGen bool, doFileDescriptorSet bool) protoc.Compil
 			protoc.CompilerWithFileDescriptorSet(),
 		)
 	}
-	return protoc.NewCompiler(compilerOptions...)
+	if r.warningsAsErrors {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithWarningsAsErrors(),
+		)
+	}
+	if r.jobs > 0 {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithJobs(r.jobs),
+		)
+	}
+	return protoc.NewCompiler(compilerOptions...)
+}
+
+func (r *runner) newLintRunner(extraOptions ...lint.RunnerOption) lint.Runner {
+	options := []lint.RunnerOption{
+		lint.RunnerWithLogger(r.logger),
+		lint.RunnerWithTimer(r.timer),
+	}
+	if lintCachePath := r.getLintCachePath(); lintCachePath != "" {
+		options = append(options, lint.RunnerWithCachePath(lintCachePath))
+	}
+	return lint.NewRunner(append(options, extraOptions...)...)
+}
+
+// getLintCachePath returns the directory to cache lint results in,
+// or empty if lint results should not be cached.
+func (r *runner) getLintCachePath() string {
+	if r.noLintCache {
+		return ""
+	}
+	if r.cachePath != "" {
+		return filepath.Join(r.cachePath, "lint")
+	}
+	lintCachePath, err := getDefaultLintCachePath(runtime.GOOS, os.Getenv)
+	if err != nil {
+		r.logger.Debug("not caching lint results", zap.Error(err))
+		return ""
+	}
+	return lintCachePath
+}
+
+// getDefaultLintCachePath mirrors the default cache directory of the
+// protoc downloader, as os.UserCacheDir is not available in Go 1.10.
+func getDefaultLintCachePath(goos string, getenvFunc func(string) string) (string, error) {
+	if xdgCacheHome := getenvFunc("XDG_CACHE_HOME"); xdgCacheHome != "" {
+		return filepath.Join(xdgCacheHome, "prototool", "lint"), nil
+	}
+	if goos == "windows" {
+		localAppData := getenvFunc("LOCALAPPDATA")
+		if localAppData == "" {
+			return "", fmt.Errorf("LOCALAPPDATA is not set")
+		}
+		return filepath.Join(localAppData, "prototool", "lint"), nil
+	}
+	home := getenvFunc("HOME")
+	if home == "" {
+		return "", fmt.Errorf("HOME is not set")
+	}
+	if goos == "darwin" {
+		return filepath.Join(home, "Library", "Caches", "prototool", "lint"), nil
+	}
+	return filepath.Join(home, ".cache", "prototool", "lint"), nil
+}
+
+func (r *runner) newFormatTransformer(rewrite bool, languages []string, config settings.FormatConfig, extraOptions ...format.TransformerOption) format.Transformer {
+	var options []format.TransformerOption
+	if rewrite {
+		options = append(options, format.TransformerWithRewrite())
+		if len(languages) > 0 {
+			options = append(options, format.TransformerWithLanguages(languages))
+		}
+		if len(config.FileOptionTemplates) > 0 {
+			options = append(options, format.TransformerWithFileOptionTemplates(config.FileOptionTemplates))
+		}
+	}
+	if config.Indent != "" {
+		options = append(options, format.TransformerWithIndent(config.Indent))
+	}
+	if config.AlignFields {
+		options = append(options, format.TransformerWithAlignFields())
+	}
+	if config.AlignOptions {
+		options = append(options, format.TransformerWithAlignOptions())
+	}
+	if config.TopLevelBlankLines > 0 {
+		options = append(options, format.TransformerWithTopLevelBlankLines(config.TopLevelBlankLines))
+	}
+	if config.CanonicalOrder {
+		options = append(options, format.TransformerWithCanonicalOrder())
+	}
+	return r.newTransformer(append(options, extraOptions...)...)
+}
+
+// newRoundTripCheckOption returns a format.TransformerOption that checks
+// formatting against the include paths that protoc uses for the files.
+func (r *runner) newRoundTripCheckOption(meta *meta) (format.TransformerOption, error) {
+	protocCommands, err := r.newCompiler(false, false).ProtocPlan(meta.ProtoSet)
+	if err != nil {
+		return nil, err
+	}
+	var includePaths []string
+	seenIncludePaths := make(map[string]struct{})
+	for _, protocCommand := range protocCommands {
+		for _, includePath := range protocCommand.IncludePaths {
+			if _, ok := seenIncludePaths[includePath]; !ok {
+				seenIncludePaths[includePath] = struct{}{}
+				includePaths = append(includePaths, includePath)
+			}
+		}
+	}
+	return format.TransformerWithRoundTripCheck(includePaths), nil
+}
+
+func (r *runner) newTransformer(options ...format.TransformerOption) format.Transformer {
+	return format.NewTransformer(append([]format.TransformerOption{format.TransformerWithLogger(r.logger)}, options...)...)
+}
+
+func (r *runner) newBazelGenerator() bazel.Generator {
+	return bazel.NewGenerator(
+		bazel.GeneratorWithLogger(r.logger),
+	)
+}
+
+func (r *runner) newBreakingChecker(config settings.BreakConfig) (breaking.Checker, error) {
+	var exceptions []*breaking.Exception
+	if config.ExceptionsFilePath != "" {
+		data, err := ioutil.ReadFile(config.ExceptionsFilePath)
+		if err != nil {
+			return nil, err
+		}
+		exceptions, err = breaking.ParseExceptions(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", config.ExceptionsFilePath, err)
+		}
+	}
+	return breaking.NewChecker(
+		breaking.CheckerWithLogger(r.logger),
+		breaking.CheckerWithIgnoreIDs(config.IgnoreIDs...),
+		breaking.CheckerWithIgnoreTypes(config.IgnoreTypes...),
+		breaking.CheckerWithExceptions(exceptions...),
+	), nil
 }
 
-func (r *runner) newLintRunner() lint.Runner {
-	return lint.NewRunner(
-		lint.RunnerWithLogger(r.logger),
+func (r *runner) newParseChecker() parsecheck.Checker {
+	return parsecheck.NewChecker(
+		parsecheck.CheckerWithLogger(r.logger),
 	)
 }
 
-func (r *runner) newTransformer(rewrite bool) format.Transformer {
-	transformerOptions := []format.TransformerOption{format.TransformerWithLogger(r.logger)}
-	if rewrite {
-		transformerOptions = append(transformerOptions, format.TransformerWithRewrite())
-	}
-	return format.NewTransformer(transformerOptions...)
+func (r *runner) newVetter() vet.Vetter {
+	return vet.NewVetter(
+		vet.VetterWithLogger(r.logger),
+	)
+}
+
+func (r *runner) newDocGenerator() doc.Generator {
+	return doc.NewGenerator(
+		doc.GeneratorWithLogger(r.logger),
+	)
+}
+
+func (r *runner) newStamper() provenance.Stamper {
+	return provenance.NewStamper(
+		provenance.StamperWithLogger(r.logger),
+	)
+}
+
+func (r *runner) newStubGenerator() stub.Generator {
+	return stub.NewGenerator(
+		stub.GeneratorWithLogger(r.logger),
+	)
+}
+
+func (r *runner) newDecompiler(config settings.FormatConfig) decompile.Decompiler {
+	return decompile.NewDecompiler(
+		decompile.DecompilerWithLogger(r.logger),
+		decompile.DecompilerWithTransformer(r.newFormatTransformer(false, nil, config)),
+	)
 }
 
 func (r *runner) newGetter() extract.Getter {
@@ -693,28 +3187,199 @@ func (r *runner) newGetter() extract.Getter {
 	)
 }
 
-func (r *runner) newReflectHandler() reflect.Handler {
+func (r *runner) newReflectHandler(config settings.Config) reflect.Handler {
 	return reflect.NewHandler(
 		reflect.HandlerWithLogger(r.logger),
+		reflect.HandlerWithJSONMarshaler(r.getJSONMarshaler(config, 0)),
+	)
+}
+
+func (r *runner) newDataGenerator(seed int64) datagen.Generator {
+	generatorOptions := []datagen.GeneratorOption{datagen.GeneratorWithLogger(r.logger)}
+	if seed != 0 {
+		generatorOptions = append(generatorOptions, datagen.GeneratorWithSeed(seed))
+	}
+	return datagen.NewGenerator(generatorOptions...)
+}
+
+func (r *runner) newSchemaRegistryClient(url string, basicAuth string) (schemaregistry.Client, error) {
+	clientOptions := []schemaregistry.ClientOption{schemaregistry.ClientWithLogger(r.logger)}
+	if basicAuth != "" {
+		basicAuth, err := r.newSecretResolver().Resolve(basicAuth)
+		if err != nil {
+			return nil, err
+		}
+		split := strings.SplitN(basicAuth, ":", 2)
+		if len(split) != 2 {
+			return nil, newExitErrorf(255, "basic-auth must be username:password")
+		}
+		clientOptions = append(clientOptions, schemaregistry.ClientWithBasicAuth(split[0], split[1]))
+	}
+	return schemaregistry.NewClient(url, clientOptions...), nil
+}
+
+func (r *runner) newSecretResolver() secret.Resolver {
+	return secret.NewResolver(
+		secret.ResolverWithLogger(r.logger),
+		secret.ResolverWithExecTracer(r.execTracer),
 	)
 }
 
-func (r *runner) newCreateHandler(pkg string) create.Handler {
-	handlerOptions := []create.HandlerOption{create.HandlerWithLogger(r.logger)}
+func (r *runner) newModuleClient(registryURL string) module.Client {
+	return module.NewClient(registryURL, module.ClientWithLogger(r.logger))
+}
+
+func (r *runner) newMockServer(fixtureData []byte) mock.Server {
+	serverOptions := []mock.ServerOption{mock.ServerWithLogger(r.logger)}
+	if len(fixtureData) > 0 {
+		serverOptions = append(serverOptions, mock.ServerWithFixtureData(fixtureData))
+	}
+	return mock.NewServer(serverOptions...)
+}
+
+func (r *runner) newCreateHandler(pkg, edition, version, ticket string) create.Handler {
+	handlerOptions := []create.HandlerOption{
+		create.HandlerWithLogger(r.logger),
+		create.HandlerWithExecTracer(r.execTracer),
+	}
 	if pkg != "" {
 		handlerOptions = append(handlerOptions, create.HandlerWithPackage(pkg))
 	}
+	if edition != "" {
+		handlerOptions = append(handlerOptions, create.HandlerWithEdition(edition))
+	}
+	if version != "" {
+		handlerOptions = append(handlerOptions, create.HandlerWithVersion(version))
+	}
+	if ticket != "" {
+		handlerOptions = append(handlerOptions, create.HandlerWithTicket(ticket))
+	}
 	return create.NewHandler(handlerOptions...)
 }
 
+// getGRPCExpectations returns the expectations for the expect-json file,
+// the expect-code, and the expect-field flags.
+func getGRPCExpectations(expectJSON string, expectCode string, expectFields []string) (*grpc.Expectations, error) {
+	expectations := &grpc.Expectations{}
+	if expectJSON != "" {
+		file, err := os.Open(expectJSON)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = file.Close() }()
+		expectations.JSONResponses, err = grpc.ParseJSONResponses(file)
+		if err != nil {
+			return nil, newExitErrorf(255, "%s: %v", expectJSON, err)
+		}
+	}
+	if expectCode != "" {
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(expectCode)))); err != nil {
+			return nil, newExitErrorf(255, "unknown expect-code %q", expectCode)
+		}
+		expectations.Code = &code
+	}
+	for _, expectField := range expectFields {
+		fieldExpectation, err := grpc.ParseFieldExpectation(expectField)
+		if err != nil {
+			return nil, newExitErrorf(255, "%v", err)
+		}
+		expectations.Fields = append(expectations.Fields, fieldExpectation)
+	}
+	return expectations, nil
+}
+
+// getGRPCHeaders returns the headers for the header-env prefix and the headers,
+// which are either name:value or @file. Headers override headers from the
+// environment, and later headers override earlier headers.
+func getGRPCHeaders(headers []string, headerEnvPrefix string, environ []string) (map[string]string, error) {
+	parsedHeaders := make(map[string]string)
+	if headerEnvPrefix != "" {
+		for _, env := range environ {
+			split := strings.SplitN(env, "=", 2)
+			if len(split) != 2 || len(split[0]) <= len(headerEnvPrefix) || !strings.HasPrefix(split[0], headerEnvPrefix) {
+				continue
+			}
+			name := strings.ToLower(strings.Replace(strings.TrimPrefix(split[0], headerEnvPrefix), "_", "-", -1))
+			parsedHeaders[name] = split[1]
+		}
+	}
+	for _, header := range headers {
+		if strings.HasPrefix(header, "@") {
+			data, err := ioutil.ReadFile(strings.TrimPrefix(header, "@"))
+			if err != nil {
+				return nil, err
+			}
+			fileHeaders := make(map[string]string)
+			if err := yaml.Unmarshal(data, &fileHeaders); err != nil {
+				return nil, fmt.Errorf("headers file %s must be a map of names to string values: %v", strings.TrimPrefix(header, "@"), err)
+			}
+			for name, value := range fileHeaders {
+				parsedHeaders[name] = value
+			}
+			continue
+		}
+		split := strings.SplitN(header, ":", 2)
+		if len(split) != 2 {
+			return nil, fmt.Errorf("headers must be key:value but got %s", header)
+		}
+		parsedHeaders[split[0]] = split[1]
+	}
+	return parsedHeaders, nil
+}
+
+// addGRPCTargetHeaders adds the default headers of a grpc target to the
+// headers, except for those already set, as header names are case-insensitive.
+//
+// Values that are references to secrets are resolved.
+func addGRPCTargetHeaders(secretResolver secret.Resolver, headers map[string]string, targetHeaders map[string]string) error {
+	names := make(map[string]struct{}, len(headers))
+	for name := range headers {
+		names[strings.ToLower(name)] = struct{}{}
+	}
+	for name, value := range targetHeaders {
+		if _, ok := names[strings.ToLower(name)]; ok {
+			continue
+		}
+		value, err := secretResolver.Resolve(value)
+		if err != nil {
+			return err
+		}
+		headers[name] = value
+	}
+	return nil
+}
+
 func (r *runner) newGRPCHandler(
+	config settings.Config,
 	headers map[string]string,
 	callTimeout time.Duration,
 	connectTimeout time.Duration,
 	keepaliveTime time.Duration,
+	compress string,
+	authority string,
+	userAgent string,
+	authToken string,
+	tlsConfig *grpc.TLSConfig,
+	outputFormat string,
+	printMetadata bool,
+	waitForReady bool,
+	maxRecvMsgSize int,
+	maxSendMsgSize int,
+	maxAttempts int,
+	retryBackoff time.Duration,
+	retryCodes []codes.Code,
+	deadline time.Duration,
+	cancelAfter time.Duration,
+	cancelAfterBytes int,
+	fields []string,
+	expectations *grpc.Expectations,
+	recordFunc func(*grpc.Recording),
 ) grpc.Handler {
 	handlerOptions := []grpc.HandlerOption{
 		grpc.HandlerWithLogger(r.logger),
+		grpc.HandlerWithExecTracer(r.execTracer),
+		grpc.HandlerWithJSONMarshaler(r.getJSONMarshaler(config, 2)),
 	}
 	for key, value := range headers {
 		handlerOptions = append(handlerOptions, grpc.HandlerWithHeader(key, value))
@@ -728,9 +3393,91 @@ func (r *runner) newGRPCHandler(
 	if keepaliveTime != 0 {
 		handlerOptions = append(handlerOptions, grpc.HandlerWithKeepaliveTime(keepaliveTime))
 	}
+	if compress != "" {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithCompression(compress))
+	}
+	if authority != "" {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithAuthority(authority))
+	}
+	if userAgent != "" {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithUserAgent(userAgent))
+	}
+	if authToken != "" {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithAuthToken(authToken))
+	}
+	if tlsConfig != nil {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithTLS(*tlsConfig))
+	}
+	if outputFormat != "" {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithOutputFormat(outputFormat))
+	}
+	if printMetadata {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithPrintMetadata())
+	}
+	if waitForReady {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithWaitForReady())
+	}
+	if maxRecvMsgSize != 0 {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithMaxRecvMsgSize(maxRecvMsgSize))
+	}
+	if maxSendMsgSize != 0 {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithMaxSendMsgSize(maxSendMsgSize))
+	}
+	if maxAttempts > 1 {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithRetryPolicy(maxAttempts, retryBackoff, retryCodes...))
+	}
+	if deadline != 0 {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithDeadline(deadline))
+	}
+	if cancelAfter != 0 {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithCancelAfter(cancelAfter))
+	}
+	if cancelAfterBytes != 0 {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithCancelAfterBytes(cancelAfterBytes))
+	}
+	if len(fields) > 0 {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithFields(fields))
+	}
+	if expectations != nil {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithExpectations(expectations))
+	}
+	if recordFunc != nil {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithRecordFunc(recordFunc))
+	}
 	return grpc.NewHandler(handlerOptions...)
 }
 
+// getJSONMarshaler merges the JSON config from the flags with the JSON config
+// from the config file, with defaultIndent used if no indent is set.
+func (r *runner) getJSONMarshaler(config settings.Config, defaultIndent int) *jsonpb.Marshaler {
+	jsonConfig := config.JSON
+	if r.jsonConfig.EmitDefaults {
+		jsonConfig.EmitDefaults = true
+	}
+	if r.jsonConfig.OrigName {
+		jsonConfig.OrigName = true
+	}
+	if r.jsonConfig.EnumsAsInts {
+		jsonConfig.EnumsAsInts = true
+	}
+	if r.jsonConfig.Indent != 0 {
+		jsonConfig.Indent = r.jsonConfig.Indent
+	}
+	indent := jsonConfig.Indent
+	if indent == 0 {
+		indent = defaultIndent
+	}
+	marshaler := &jsonpb.Marshaler{
+		EmitDefaults: jsonConfig.EmitDefaults,
+		OrigName:     jsonConfig.OrigName,
+		EnumsAsInts:  jsonConfig.EnumsAsInts,
+	}
+	if indent > 0 {
+		marshaler.Indent = strings.Repeat(" ", indent)
+	}
+	return marshaler
+}
+
 func (r *runner) getConfig(dirPath string) (settings.Config, error) {
 	return r.configProvider.GetForDir(dirPath)
 }
@@ -743,6 +3490,7 @@ type meta struct {
 }
 
 func (r *runner) getMeta(args []string) (*meta, error) {
+	defer r.timer.Start("config resolution")()
 	if len(args) == 0 {
 		// TODO: does not fit in with workDirPath paradigm
 		args = []string{"."}
@@ -803,6 +3551,44 @@ func (r *runner) getMeta(args []string) (*meta, error) {
 	}, nil
 }
 
+// notifyWebhook posts the failures to the webhook in the config if
+// notifying is enabled and there are failures.
+//
+// Errors are logged instead of returned so that a webhook that is down
+// does not change the result of the command.
+func (r *runner) notifyWebhook(command string, meta *meta, failures []*text.Failure) {
+	webhookConfig := meta.ProtoSet.Config.Webhook
+	if !r.notify || webhookConfig.URL == "" || len(failures) == 0 {
+		return
+	}
+	secretResolver := r.newSecretResolver()
+	headers := make(map[string]string, len(webhookConfig.Headers))
+	for name, value := range webhookConfig.Headers {
+		value, err := secretResolver.Resolve(value)
+		if err != nil {
+			r.logger.Warn("failed to resolve webhook header", zap.String("name", name), zap.Error(err))
+			return
+		}
+		headers[name] = value
+	}
+	notifierOptions := []webhook.NotifierOption{
+		webhook.NotifierWithLogger(r.logger),
+		webhook.NotifierWithHeaders(headers),
+	}
+	if webhookConfig.Template != "" {
+		// validated in the settings package
+		tmpl, err := webhook.ParseTemplate(webhookConfig.Template)
+		if err != nil {
+			r.logger.Warn("invalid webhook template", zap.Error(err))
+			return
+		}
+		notifierOptions = append(notifierOptions, webhook.NotifierWithTemplate(tmpl))
+	}
+	if err := webhook.NewNotifier(webhookConfig.URL, notifierOptions...).Notify(webhook.NewPayload(command, failures...)); err != nil {
+		r.logger.Warn("failed to post failures to webhook", zap.String("command", command), zap.Error(err))
+	}
+}
+
 // TODO: we filter failures in dir mode in printFailures but above we count any failure
 // as an error with a non-zero exit code, seems inconsistent, this needs refactoring
 
@@ -810,17 +3596,52 @@ func (r *runner) getMeta(args []string) (*meta, error) {
 // if set, it will update the Failures to have this filename
 // will be sorted
 func (r *runner) printFailures(filename string, meta *meta, failures ...*text.Failure) error {
+	r.metricsRecorder.AddFailures(failures...)
 	for _, failure := range failures {
 		if filename != "" {
 			failure.Filename = filename
 		}
 	}
+	if err := setFailureOwners(meta, failures); err != nil {
+		return err
+	}
 	failureFields, err := text.ParseColonSeparatedFailureFields(r.printFields)
 	if err != nil {
 		return err
 	}
+	var failureTemplate *template.Template
+	if r.failureTemplate != "" {
+		if r.outputFormat != "" || r.outputPreset != "" || r.harbormaster || r.envelopeBuilder != nil {
+			return newExitErrorf(255, "--template can only be used with the default output format")
+		}
+		failureTemplate, err = text.ParseFailureTemplate(r.failureTemplate)
+		if err != nil {
+			return newExitErrorf(255, "invalid --template: %v", err)
+		}
+	}
+	if r.outputPreset != "" {
+		if r.outputFormat != "" || r.harbormaster || r.envelopeBuilder != nil {
+			return newExitErrorf(255, "--output-preset can only be used with the default output format")
+		}
+		outputPresetTemplate, ok := outputPresetToFailureTemplate[r.outputPreset]
+		if !ok {
+			return newExitErrorf(255, "unknown output preset %q", r.outputPreset)
+		}
+		failureTemplate, err = text.ParseFailureTemplate(outputPresetTemplate)
+		if err != nil {
+			return err
+		}
+		if r.outputPreset == OutputPresetVSCode {
+			// the problem matcher does not know the working directory
+			for _, failure := range failures {
+				if failure.Filename, err = absClean(failure.Filename); err != nil {
+					return err
+				}
+			}
+		}
+	}
 	text.SortFailures(failures)
-	bufWriter := bufio.NewWriter(r.output)
+	var printableFailures []*text.Failure
 	for _, failure := range failures {
 		shouldPrint := false
 		if meta.InDirModeSingleFilename == "" || meta.InDirModeSingleFilename == failure.Filename {
@@ -840,26 +3661,108 @@ func (r *runner) printFailures(filename string, meta *meta, failures ...*text.Fa
 			}
 		}
 		if shouldPrint {
-			if r.harbormaster {
-				harbormasterLintResult, err := phab.TextFailureToHarbormasterLintResult(failure)
-				if err != nil {
-					return err
-				}
-				data, err := json.Marshal(harbormasterLintResult)
-				if err != nil {
-					return err
-				}
-				if _, err := fmt.Fprintln(bufWriter, string(data)); err != nil {
-					return err
-				}
-			} else if err := failure.Fprintln(bufWriter, failureFields...); err != nil {
+			printableFailures = append(printableFailures, failure)
+		}
+	}
+	if r.envelopeBuilder != nil {
+		if r.outputFormat != "" || r.harbormaster {
+			return newExitErrorf(255, "--json can only be used with the default output format")
+		}
+		r.envelopeBuilder.AddFailures(printableFailures...)
+		return nil
+	}
+	bufWriter := bufio.NewWriter(r.output)
+	switch r.outputFormat {
+	case "", OutputFormatGitHubActions:
+	case OutputFormatCheckstyle, OutputFormatGitLab:
+		// reports are only printed for failures so that successful
+		// steps do not print empty reports
+		if len(printableFailures) == 0 {
+			return nil
+		}
+		writeReport := checkstyle.WriteReport
+		if r.outputFormat == OutputFormatGitLab {
+			writeReport = gitlab.WriteReport
+		}
+		if err := writeReport(bufWriter, printableFailures...); err != nil {
+			return err
+		}
+		return bufWriter.Flush()
+	default:
+		return newExitErrorf(255, "unknown output format %q", r.outputFormat)
+	}
+	for _, failure := range printableFailures {
+		if r.outputFormat == OutputFormatGitHubActions {
+			if _, err := fmt.Fprintln(bufWriter, github.TextFailureToWorkflowCommand(failure)); err != nil {
+				return err
+			}
+		} else if r.harbormaster {
+			harbormasterLintResult, err := phab.TextFailureToHarbormasterLintResult(failure)
+			if err != nil {
+				return err
+			}
+			data, err := json.Marshal(harbormasterLintResult)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(bufWriter, string(data)); err != nil {
+				return err
+			}
+		} else if failureTemplate != nil {
+			if err := failure.FprintlnTemplate(bufWriter, failureTemplate); err != nil {
 				return err
 			}
+		} else if err := failure.Fprintln(bufWriter, failureFields...); err != nil {
+			return err
 		}
 	}
 	return bufWriter.Flush()
 }
 
+// printLintSummary prints the number of failures per linter and per
+// directory, and the files with the most failures.
+func (r *runner) printLintSummary(failures []*text.Failure) error {
+	idToCount := make(map[string]int)
+	ownerToCount := make(map[string]int)
+	dirToCount := make(map[string]int)
+	fileToCount := make(map[string]int)
+	for _, failure := range failures {
+		idToCount[failure.ID]++
+		if failure.Owner != "" {
+			ownerToCount[failure.Owner]++
+		}
+		dirToCount[filepath.Dir(failure.Filename)]++
+		fileToCount[failure.Filename]++
+	}
+	tabWriter := newTabWriter(r.output)
+	if _, err := fmt.Fprintf(tabWriter, "Total failures:\t%d\n", len(failures)); err != nil {
+		return err
+	}
+	for _, section := range []struct {
+		header     string
+		keyToCount map[string]int
+		limit      int
+	}{
+		{"LINTER", idToCount, 0},
+		{"OWNER", ownerToCount, 0},
+		{"DIRECTORY", dirToCount, 0},
+		{"FILE", fileToCount, lintSummaryMaxFiles},
+	} {
+		if len(section.keyToCount) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(tabWriter, "\n%s\tFAILURES\n", section.header); err != nil {
+			return err
+		}
+		for _, key := range sortByCount(section.keyToCount, section.limit) {
+			if _, err := fmt.Fprintf(tabWriter, "%s\t%d\n", key, section.keyToCount[key]); err != nil {
+				return err
+			}
+		}
+	}
+	return tabWriter.Flush()
+}
+
 func (r *runner) printLinters(linters []lint.Linter) error {
 	sort.Slice(linters, func(i int, j int) bool { return linters[i].ID() < linters[j].ID() })
 	tabWriter := newTabWriter(r.output)
@@ -879,6 +3782,17 @@ func (r *runner) printAffectedFiles(meta *meta) {
 	}
 }
 
+// indent indents every non-empty line of s by two spaces.
+func indent(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "  " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (r *runner) println(s string) error {
 	if s == "" {
 		return nil
@@ -901,6 +3815,43 @@ func (r *runner) getInputReader(data string, stdin bool) io.Reader {
 	return bytes.NewReader([]byte(data))
 }
 
+// newFailuresExitError returns the error to return after failures
+// were printed, which has code 1 for output presets as editors expect
+// the exit code of a compiler, and 255 otherwise.
+func (r *runner) newFailuresExitError() *ExitError {
+	if r.outputPreset != "" {
+		return newExitErrorf(1, "")
+	}
+	return newExitErrorf(255, "")
+}
+
+// newCompileFailuresExitError returns the error to return after compile
+// failures were printed.
+func (r *runner) newCompileFailuresExitError() *ExitError {
+	if r.compileExitCode != 0 {
+		return newExitErrorf(r.compileExitCode, "")
+	}
+	return r.newFailuresExitError()
+}
+
+// newLintFailuresExitError returns the error to return after lint
+// failures were printed.
+func (r *runner) newLintFailuresExitError() *ExitError {
+	if r.lintExitCode != 0 {
+		return newExitErrorf(r.lintExitCode, "")
+	}
+	return r.newFailuresExitError()
+}
+
+// newFormatFailuresExitError returns the error to return after format
+// failures or diffs were printed.
+func (r *runner) newFormatFailuresExitError() *ExitError {
+	if r.formatExitCode != 0 {
+		return newExitErrorf(r.formatExitCode, "")
+	}
+	return r.newFailuresExitError()
+}
+
 func newExitErrorf(code int, format string, args ...interface{}) *ExitError {
 	return &ExitError{
 		Code:    code,
@@ -919,6 +3870,146 @@ func absClean(path string) (string, error) {
 	return filepath.Clean(path), nil
 }
 
+// setFailureOwners sets the Owner of each Failure to the team of the last
+// owner in the config whose pattern matches the file of the Failure.
+func setFailureOwners(meta *meta, failures []*text.Failure) error {
+	owners := meta.ProtoSet.Config.Owners
+	if len(owners) == 0 {
+		return nil
+	}
+	for _, failure := range failures {
+		if failure.Filename == "" {
+			continue
+		}
+		absFilePath, err := absClean(failure.Filename)
+		if err != nil {
+			return err
+		}
+		relFilePath, err := filepath.Rel(meta.ProtoSet.Config.DirPath, absFilePath)
+		if err != nil {
+			return err
+		}
+		relFilePath = filepath.ToSlash(relFilePath)
+		if strings.HasPrefix(relFilePath, "../") {
+			continue
+		}
+		for i := len(owners) - 1; i >= 0; i-- {
+			matched, err := strs.MatchGlob(owners[i].Pattern, relFilePath)
+			if err != nil {
+				return err
+			}
+			if matched {
+				failure.Owner = owners[i].Team
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// sortByCount returns the keys sorted by descending count, then by key.
+//
+// If limit is greater than zero, at most limit keys are returned.
+func sortByCount(keyToCount map[string]int, limit int) []string {
+	keys := make([]string, 0, len(keyToCount))
+	for key := range keyToCount {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i int, j int) bool {
+		if keyToCount[keys[i]] != keyToCount[keys[j]] {
+			return keyToCount[keys[i]] > keyToCount[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys
+}
+
 func newTabWriter(writer io.Writer) *tabwriter.Writer {
 	return tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
 }
+
+// splitLengthDelimited splits data that is a stream of binary messages
+// each prefixed with their length as a varint.
+func splitLengthDelimited(data []byte) ([][]byte, error) {
+	var messages [][]byte
+	for len(data) > 0 {
+		length, n := proto.DecodeVarint(data)
+		if n == 0 {
+			return nil, fmt.Errorf("invalid length prefix for message %d", len(messages))
+		}
+		data = data[n:]
+		if uint64(len(data)) < length {
+			return nil, fmt.Errorf("message %d has length %d but only %d bytes remain", len(messages), length, len(data))
+		}
+		messages = append(messages, data[:length])
+		data = data[length:]
+	}
+	return messages, nil
+}
+
+// splitJSONStream splits data that is a stream of JSON values, usually
+// one per line.
+func splitJSONStream(data []byte) ([][]byte, error) {
+	var messages [][]byte
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var rawMessage json.RawMessage
+		if err := decoder.Decode(&rawMessage); err != nil {
+			if err == io.EOF {
+				return messages, nil
+			}
+			return nil, fmt.Errorf("invalid JSON for message %d: %v", len(messages), err)
+		}
+		messages = append(messages, rawMessage)
+	}
+}
+
+func writeLengthDelimited(writer io.Writer, data []byte) error {
+	if _, err := writer.Write(proto.EncodeVarint(uint64(len(data)))); err != nil {
+		return err
+	}
+	_, err := writer.Write(data)
+	return err
+}
+
+func writeNewlineDelimited(writer io.Writer, data []byte) error {
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	_, err := writer.Write([]byte{'\n'})
+	return err
+}
+
+// writeNewlineDelimitedJSON is like writeNewlineDelimited, but compacts
+// the JSON first so that each message stays on one line even if an indent
+// is configured.
+func writeNewlineDelimitedJSON(writer io.Writer, data []byte) error {
+	buffer := bytes.NewBuffer(nil)
+	if err := json.Compact(buffer, data); err != nil {
+		return err
+	}
+	return writeNewlineDelimited(writer, buffer.Bytes())
+}
+
+// readIncludedFile reads the file with the given import path from the
+// config directory, the roots, or the include paths of the config.
+func readIncludedFile(config settings.Config, name string) ([]byte, error) {
+	dirPaths := []string{config.DirPath}
+	for _, root := range config.Compile.Roots {
+		dirPaths = append(dirPaths, root.DirPath)
+		dirPaths = append(dirPaths, root.IncludePaths...)
+	}
+	for _, dirPath := range append(dirPaths, config.Compile.IncludePaths...) {
+		data, err := ioutil.ReadFile(filepath.Join(dirPath, filepath.FromSlash(name)))
+		if err == nil {
+			return data, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("could not find %s in %s or the include paths", name, config.DirPath)
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package exectrace traces the external processes that prototool spawns.
+package exectrace
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tracer traces external processes.
+type Tracer interface {
+	// Start records that the given command is about to be run and returns
+	// a function to call with the error returned from running it.
+	Start(cmd *exec.Cmd) func(err error)
+}
+
+// NewTracer returns a new Tracer that writes to the given writer.
+func NewTracer(writer io.Writer) Tracer {
+	return newTracer(writer)
+}
+
+// NewNopTracer returns a new Tracer that does nothing.
+func NewNopTracer() Tracer {
+	return nopTracer{}
+}
+
+type tracer struct {
+	writer io.Writer
+	lastID int
+	lock   sync.Mutex
+}
+
+func newTracer(writer io.Writer) *tracer {
+	return &tracer{
+		writer: writer,
+	}
+}
+
+func (t *tracer) Start(cmd *exec.Cmd) func(error) {
+	t.lock.Lock()
+	t.lastID++
+	id := t.lastID
+	lines := []string{quoteArgs(cmd.Args)}
+	if cmd.Dir != "" {
+		lines = append(lines, "dir "+cmd.Dir)
+	}
+	if cmd.Env != nil {
+		if diff := envDiff(os.Environ(), cmd.Env); len(diff) > 0 {
+			lines = append(lines, "env "+strings.Join(diff, " "))
+		}
+	}
+	for _, line := range lines {
+		_, _ = fmt.Fprintf(t.writer, "exec[%d]: %s\n", id, line)
+	}
+	t.lock.Unlock()
+	start := time.Now()
+	return func(err error) {
+		duration := time.Since(start)
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		_, _ = fmt.Fprintf(t.writer, "exec[%d]: %s in %v\n", id, getResult(cmd, err), duration)
+	}
+}
+
+type nopTracer struct{}
+
+func (nopTracer) Start(*exec.Cmd) func(error) { return func(error) {} }
+
+func getResult(cmd *exec.Cmd, err error) string {
+	if cmd.ProcessState != nil {
+		return fmt.Sprintf("exited with code %d", cmd.ProcessState.ExitCode())
+	}
+	if err != nil {
+		return fmt.Sprintf("failed: %v", err)
+	}
+	return "finished"
+}
+
+// envDiff returns the variables added or changed in env as NAME=VALUE
+// and the variables removed from base as -NAME, sorted by name.
+func envDiff(base []string, env []string) []string {
+	baseMap := envToMap(base)
+	envMap := envToMap(env)
+	var diff []string
+	for name, value := range envMap {
+		if baseValue, ok := baseMap[name]; !ok || baseValue != value {
+			diff = append(diff, "+"+name+"="+value)
+		}
+	}
+	for name := range baseMap {
+		if _, ok := envMap[name]; !ok {
+			diff = append(diff, "-"+name)
+		}
+	}
+	sort.Slice(diff, func(i int, j int) bool {
+		return diff[i][1:] < diff[j][1:]
+	})
+	return diff
+}
+
+func envToMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, s := range env {
+		if i := strings.IndexByte(s, '='); i >= 0 {
+			m[s[:i]] = s[i+1:]
+		}
+	}
+	return m
+}
+
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$") {
+			quoted[i] = strconv.Quote(arg)
+		} else {
+			quoted[i] = arg
+		}
+	}
+	return strings.Join(quoted, " ")
+}
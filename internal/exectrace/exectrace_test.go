@@ -0,0 +1,54 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package exectrace
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracer(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	tracer := NewTracer(buffer)
+	cmd := exec.Command("sh", "-c", "exit 3")
+	cmd.Env = append(os.Environ(), "PROTOTOOL_EXECTRACE_TEST=bar baz")
+	finish := tracer.Start(cmd)
+	finish(cmd.Run())
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, `exec[1]: sh -c "exit 3"`, lines[0])
+	assert.Equal(t, "exec[1]: env +PROTOTOOL_EXECTRACE_TEST=bar baz", lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "exec[1]: exited with code 3 in "))
+}
+
+func TestEnvDiff(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{"-BAR", "+BAZ=2", "+FOO=2"},
+		envDiff([]string{"FOO=1", "BAR=1", "QUX=1"}, []string{"FOO=2", "BAZ=2", "QUX=1"}),
+	)
+}
@@ -21,6 +21,8 @@
 package file
 
 import (
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/uber/prototool/internal/settings"
@@ -132,3 +134,65 @@ func ProtoSetProviderWithWalkTimeout(walkTimeout time.Duration) ProtoSetProvider
 func NewProtoSetProvider(options ...ProtoSetProviderOption) ProtoSetProvider {
 	return newProtoSetProvider(options...)
 }
+
+// RootDirPath returns the directory that the import paths of the .proto files
+// in the given directory start from.
+//
+// This is the root in the config that contains the directory, otherwise the
+// config directory, otherwise the working directory if there is no config file.
+func RootDirPath(protoSet *ProtoSet, dirPath string) string {
+	if root, ok := GetRoot(protoSet.Config, dirPath); ok {
+		return root.DirPath
+	}
+	if protoSet.Config.DirPath != "" {
+		return protoSet.Config.DirPath
+	}
+	return protoSet.WorkDirPath
+}
+
+// ImportPath returns the path that the given .proto file is imported with,
+// which is relative to RootDirPath for the directory of the file.
+//
+// The returned path uses forward slashes.
+func ImportPath(protoSet *ProtoSet, filePath string) (string, error) {
+	importPath, err := filepath.Rel(RootDirPath(protoSet, filepath.Dir(filePath)), filePath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(importPath), nil
+}
+
+// GetRoot returns the root in the config that contains the given directory,
+// if any.
+func GetRoot(config settings.Config, dirPath string) (settings.Root, bool) {
+	for _, root := range config.Compile.Roots {
+		if dirPath == root.DirPath || strings.HasPrefix(dirPath, root.DirPath+string(filepath.Separator)) {
+			return root, true
+		}
+	}
+	return settings.Root{}, false
+}
+
+// GetIncludePaths returns the paths that imports of the files in the
+// ProtoSet are looked up in, in order, which are the roots, the include
+// paths of the roots, the config directory, and the include paths of the
+// config.
+//
+// Include paths of files that are downloaded by Prototool, such as the
+// Well-Known Types, are not returned.
+func GetIncludePaths(protoSet *ProtoSet) []string {
+	config := protoSet.Config
+	var includePaths []string
+	for _, root := range config.Compile.Roots {
+		includePaths = append(includePaths, root.DirPath)
+	}
+	for _, root := range config.Compile.Roots {
+		includePaths = append(includePaths, root.IncludePaths...)
+	}
+	configDirPath := config.DirPath
+	if configDirPath == "" {
+		configDirPath = protoSet.WorkDirPath
+	}
+	includePaths = append(includePaths, configDirPath)
+	return append(includePaths, config.Compile.IncludePaths...)
+}
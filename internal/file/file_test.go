@@ -0,0 +1,62 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package file
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/settings"
+)
+
+func TestImportPath(t *testing.T) {
+	protoSet := &ProtoSet{
+		WorkDirPath: "/work",
+		Config: settings.Config{
+			DirPath: "/work/config",
+			Compile: settings.CompileConfig{
+				Roots: []settings.Root{
+					{
+						DirPath: "/work/config/a",
+					},
+					{
+						DirPath: "/work/config/b",
+					},
+				},
+			},
+		},
+	}
+	for filePath, expectedImportPath := range map[string]string{
+		"/work/config/a/foo/v1/foo.proto": "foo/v1/foo.proto",
+		"/work/config/b/bar.proto":        "bar.proto",
+		"/work/config/ab/baz.proto":       "ab/baz.proto",
+		"/work/config/c/bat.proto":        "c/bat.proto",
+	} {
+		importPath, err := ImportPath(protoSet, filePath)
+		require.NoError(t, err)
+		assert.Equal(t, expectedImportPath, importPath, filePath)
+	}
+	protoSet.Config = settings.Config{}
+	importPath, err := ImportPath(protoSet, "/work/foo/foo.proto")
+	require.NoError(t, err)
+	assert.Equal(t, "foo/foo.proto", importPath)
+}
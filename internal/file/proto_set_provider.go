@@ -115,6 +115,15 @@ func (c *protoSetProvider) GetMultipleForDir(workDirPath string, dirPath string)
 	if err != nil {
 		return nil, err
 	}
+	if configFilePath != "" {
+		config, err := c.configProvider.Get(configFilePath)
+		if err != nil {
+			return nil, err
+		}
+		if len(config.Workspace.RepoDirPaths) > 0 {
+			return c.getWorkspaceProtoSets(workDirPath, absDirPath, config, protoFiles)
+		}
+	}
 	dirPathToProtoFiles := getDirPathToProtoFiles(protoFiles)
 	protoSets, err := c.getBaseProtoSets(dirPathToProtoFiles)
 	if err != nil {
@@ -150,6 +159,39 @@ func (c *protoSetProvider) GetMultipleForFiles(workDirPath string, filePaths ...
 	return protoSets, nil
 }
 
+// getWorkspaceProtoSets returns a single ProtoSet with the config of the
+// workspace for the files in the directory of the config and the files in
+// the repositories of the workspace, ignoring the config files of the
+// repositories, so that the files of all repositories are compiled and
+// linted together.
+func (c *protoSetProvider) getWorkspaceProtoSets(workDirPath string, absDirPath string, config settings.Config, protoFiles []*ProtoFile) ([]*ProtoSet, error) {
+	seenFilePaths := make(map[string]struct{}, len(protoFiles))
+	for _, protoFile := range protoFiles {
+		seenFilePaths[protoFile.Path] = struct{}{}
+	}
+	for _, repoDirPath := range config.Workspace.RepoDirPaths {
+		repoProtoFiles, err := c.walkAndGetAllProtoFiles(workDirPath, repoDirPath)
+		if err != nil {
+			return nil, fmt.Errorf("workspace repo %s: %v", repoDirPath, err)
+		}
+		// repositories can be within the directory of the config
+		for _, protoFile := range repoProtoFiles {
+			if _, ok := seenFilePaths[protoFile.Path]; !ok {
+				seenFilePaths[protoFile.Path] = struct{}{}
+				protoFiles = append(protoFiles, protoFile)
+			}
+		}
+	}
+	protoSet := &ProtoSet{
+		WorkDirPath:    workDirPath,
+		DirPath:        absDirPath,
+		DirPathToFiles: getDirPathToProtoFiles(protoFiles),
+		Config:         config,
+	}
+	c.logger.Debug("returning workspace ProtoSet", zap.String("workDirPath", workDirPath), zap.String("dirPath", absDirPath), zap.Strings("repoDirPaths", config.Workspace.RepoDirPaths))
+	return []*ProtoSet{protoSet}, nil
+}
+
 func (c *protoSetProvider) getBaseProtoSets(dirPathToProtoFiles map[string][]*ProtoFile) ([]*ProtoSet, error) {
 	filePathToProtoSet := make(map[string]*ProtoSet)
 	for dirPath, protoFiles := range dirPathToProtoFiles {
@@ -21,7 +21,9 @@
 package file
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -622,6 +624,75 @@ func TestProtoSetProviderGetMultipleForDirCwdSubRel(t *testing.T) {
 	)
 }
 
+func TestProtoSetProviderGetMultipleForDirWorkspace(t *testing.T) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	workspaceDirPath := filepath.Join(tmpDirPath, "workspace")
+	writeTestFile(t, filepath.Join(workspaceDirPath, "prototool.yaml"), `workspace:
+  repos:
+    - path: ../payments
+      roots:
+        - proto
+    - path: ../users
+`)
+	writeTestFile(t, filepath.Join(workspaceDirPath, "foo", "foo.proto"), `syntax = "proto3";`)
+	writeTestFile(t, filepath.Join(tmpDirPath, "payments", "proto", "payments", "v1", "payments.proto"), `syntax = "proto3";`)
+	writeTestFile(t, filepath.Join(tmpDirPath, "payments", "prototool.yaml"), `lint:
+  group: google
+`)
+	writeTestFile(t, filepath.Join(tmpDirPath, "users", "users", "v1", "users.proto"), `syntax = "proto3";`)
+	protoSetProvider := newTestProtoSetProvider(t)
+	protoSets, err := protoSetProvider.GetMultipleForDir(workspaceDirPath, workspaceDirPath)
+	require.NoError(t, err)
+	require.Len(t, protoSets, 1)
+	protoSet := protoSets[0]
+	require.Equal(t, workspaceDirPath, protoSet.Config.DirPath)
+	require.Equal(
+		t,
+		[]string{
+			filepath.Join(tmpDirPath, "payments"),
+			filepath.Join(tmpDirPath, "users"),
+		},
+		protoSet.Config.Workspace.RepoDirPaths,
+	)
+	var rootDirPaths []string
+	for _, root := range protoSet.Config.Compile.Roots {
+		rootDirPaths = append(rootDirPaths, root.DirPath)
+	}
+	require.Contains(t, rootDirPaths, filepath.Join(tmpDirPath, "payments", "proto"))
+	require.Contains(t, rootDirPaths, filepath.Join(tmpDirPath, "users"))
+	require.Equal(
+		t,
+		map[string][]*ProtoFile{
+			filepath.Join(workspaceDirPath, "foo"): []*ProtoFile{
+				{
+					Path:        filepath.Join(workspaceDirPath, "foo", "foo.proto"),
+					DisplayPath: "foo/foo.proto",
+				},
+			},
+			filepath.Join(tmpDirPath, "payments", "proto", "payments", "v1"): []*ProtoFile{
+				{
+					Path:        filepath.Join(tmpDirPath, "payments", "proto", "payments", "v1", "payments.proto"),
+					DisplayPath: "../payments/proto/payments/v1/payments.proto",
+				},
+			},
+			filepath.Join(tmpDirPath, "users", "users", "v1"): []*ProtoFile{
+				{
+					Path:        filepath.Join(tmpDirPath, "users", "users", "v1", "users.proto"),
+					DisplayPath: "../users/users/v1/users.proto",
+				},
+			},
+		},
+		protoSet.DirPathToFiles,
+	)
+}
+
+func writeTestFile(t *testing.T, filePath string, data string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(data), 0644))
+}
+
 func newTestProtoSetProvider(t *testing.T) ProtoSetProvider {
 	logger, err := zap.NewDevelopment()
 	require.NoError(t, err)
@@ -36,8 +36,8 @@ type baseVisitor struct {
 	Failures []*text.Failure
 }
 
-func newBaseVisitor() *baseVisitor {
-	return &baseVisitor{printer: newPrinter()}
+func newBaseVisitor(style *style) *baseVisitor {
+	return &baseVisitor{printer: newPrinter(style)}
 }
 
 func (v *baseVisitor) AddFailure(position scanner.Position, format string, args ...interface{}) {
@@ -69,7 +69,7 @@ func (v *baseVisitor) PComment(comment *proto.Comment) {
 	// this is weird for now
 	// we always want non-c-style after formatting
 	for _, line := range comment.Lines {
-		v.P(`//`, cleanCommentLine(line))
+		v.PNonBreaking(`//`, cleanCommentLine(line))
 	}
 }
 
@@ -77,7 +77,14 @@ func (v *baseVisitor) POptions(isFieldOption bool, options ...*proto.Option) {
 	if len(options) == 0 {
 		return
 	}
+	// sort a copy so that the descriptor is not modified
+	options = append([]*proto.Option(nil), options...)
 	sort.Slice(options, func(i int, j int) bool { return options[i].Name < options[j].Name })
+	v.pOptions(isFieldOption, options...)
+}
+
+// pOptions prints the options in the given order.
+func (v *baseVisitor) pOptions(isFieldOption bool, options ...*proto.Option) {
 	prefix := "option "
 	if isFieldOption {
 		prefix = ""
@@ -116,7 +123,7 @@ func (v *baseVisitor) POptions(isFieldOption bool, options ...*proto.Option) {
 	}
 }
 
-// should only be called by POptions
+// should only be called by pOptions
 func (v *baseVisitor) pInnerLiteral(name string, literal proto.Literal, suffix string) {
 	prefix := ""
 	if name != "" {
@@ -154,15 +161,42 @@ func (v *baseVisitor) pInnerLiteral(name string, literal proto.Literal, suffix s
 
 func (v *baseVisitor) PField(prefix string, t string, field *proto.Field) {
 	v.PComment(field.Comment)
-	if len(field.Options) == 0 {
-		v.PWithInlineComment(field.InlineComment, prefix, t, " ", field.Name, " = ", field.Sequence, ";")
+	v.PAssignment(prefix+t+" "+field.Name, field.Sequence, field.InlineComment, field.Options...)
+}
+
+// PAssignment prints name = value; for fields and enum values, with the
+// given options in brackets if there are any.
+//
+// These lines are aligned with each other if the style says to.
+func (v *baseVisitor) PAssignment(name string, value int, inlineComment *proto.Comment, options ...*proto.Option) {
+	assignment := fmt.Sprintf(" = %d", value)
+	if len(options) == 0 {
+		v.PAligned(name, assignment, ";"+inlineCommentSuffix(inlineComment))
+		v.pInlineCommentRest(inlineComment)
 		return
 	}
-	v.P(prefix, t, " ", field.Name, " = ", field.Sequence, " [")
+	v.PAligned(name, assignment, " [")
 	v.In()
-	v.POptions(true, field.Options...)
+	v.POptions(true, options...)
 	v.Out()
-	v.PWithInlineComment(field.InlineComment, "];")
+	v.PNonBreaking("];", inlineCommentSuffix(inlineComment))
+	v.pInlineCommentRest(inlineComment)
+}
+
+func (v *baseVisitor) pInlineCommentRest(inlineComment *proto.Comment) {
+	if inlineComment == nil || len(inlineComment.Lines) == 0 {
+		return
+	}
+	for _, line := range inlineComment.Lines[1:] {
+		v.PNonBreaking(`//`, cleanCommentLine(line))
+	}
+}
+
+func inlineCommentSuffix(inlineComment *proto.Comment) string {
+	if inlineComment == nil || len(inlineComment.Lines) == 0 {
+		return ""
+	}
+	return ` //` + cleanCommentLine(inlineComment.Lines[0])
 }
 
 func cleanCommentLine(line string) string {
@@ -0,0 +1,91 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/protostrs"
+)
+
+// renameEnumValues renames the values of the enums in the elements, and the
+// enums nested in the messages in the elements, to have the enum value prefix
+// and the zero value suffix.
+//
+// Values are not renamed if the new name is already used in the enum.
+func renameEnumValues(elements []proto.Visitee, messageNames []string, enumValuePrefix string, enumZeroValueSuffix string) {
+	for _, element := range elements {
+		switch element := element.(type) {
+		case *proto.Message:
+			if element.IsExtend {
+				continue
+			}
+			nestedMessageNames := append(append([]string{}, messageNames...), element.Name)
+			renameEnumValues(element.Elements, nestedMessageNames, enumValuePrefix, enumZeroValueSuffix)
+		case *proto.Enum:
+			renameEnumValuesForEnum(element, messageNames, enumValuePrefix, enumZeroValueSuffix)
+		}
+	}
+}
+
+func renameEnumValuesForEnum(enum *proto.Enum, messageNames []string, enumValuePrefix string, enumZeroValueSuffix string) {
+	expectedPrefix := protostrs.EnumValuePrefix(enumValuePrefix, messageNames, enum.Name)
+	zeroValueName := protostrs.EnumZeroValueName(enumValuePrefix, enumZeroValueSuffix, messageNames, enum.Name)
+	// values may have the prefix for another enum value prefix, in which case
+	// it is replaced, the nested prefix is first as it is the longest
+	otherPrefixes := []string{
+		protostrs.EnumValuePrefix(protostrs.EnumValuePrefixNested, messageNames, enum.Name),
+		protostrs.EnumValuePrefix(protostrs.EnumValuePrefixEnum, messageNames, enum.Name),
+	}
+	names := make(map[string]struct{})
+	for _, element := range enum.Elements {
+		if enumField, ok := element.(*proto.EnumField); ok {
+			names[enumField.Name] = struct{}{}
+		}
+	}
+	seenZeroValue := false
+	for _, element := range enum.Elements {
+		enumField, ok := element.(*proto.EnumField)
+		if !ok {
+			continue
+		}
+		name := enumField.Name
+		if enumField.Integer == 0 && !seenZeroValue {
+			seenZeroValue = true
+			name = zeroValueName
+		} else if !strings.HasPrefix(name, expectedPrefix) {
+			for _, otherPrefix := range otherPrefixes {
+				if strings.HasPrefix(name, otherPrefix) {
+					name = strings.TrimPrefix(name, otherPrefix)
+					break
+				}
+			}
+			name = expectedPrefix + name
+		}
+		if _, ok := names[name]; ok {
+			continue
+		}
+		delete(names, enumField.Name)
+		names[name] = struct{}{}
+		enumField.Name = name
+	}
+}
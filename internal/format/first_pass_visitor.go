@@ -24,6 +24,7 @@ import (
 	"sort"
 
 	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/editions"
 	"github.com/uber/prototool/internal/protostrs"
 	"github.com/uber/prototool/internal/text"
 )
@@ -40,16 +41,20 @@ type firstPassVisitor struct {
 
 	haveHitNonComment bool
 
-	filename                 string
-	rewrite                  bool
-	goPackageOption          *proto.Option
-	javaMultipleFilesOption  *proto.Option
-	javaOuterClassnameOption *proto.Option
-	javaPackageOption        *proto.Option
+	filename            string
+	rewrite             bool
+	fileOptionTemplates map[string]string
+	rewriteOptions      map[string]*proto.Option
 }
 
-func newFirstPassVisitor(filename string, rewrite bool) *firstPassVisitor {
-	return &firstPassVisitor{baseVisitor: newBaseVisitor(), filename: filename, rewrite: rewrite}
+func newFirstPassVisitor(filename string, rewrite bool, fileOptionTemplates map[string]string, style *style) *firstPassVisitor {
+	return &firstPassVisitor{
+		baseVisitor:         newBaseVisitor(style),
+		filename:            filename,
+		rewrite:             rewrite,
+		fileOptionTemplates: fileOptionTemplates,
+		rewriteOptions:      make(map[string]*proto.Option),
+	}
 }
 
 func (v *firstPassVisitor) Do() []*text.Failure {
@@ -58,59 +63,35 @@ func (v *firstPassVisitor) Do() []*text.Failure {
 		if v.Syntax.Comment != nil {
 			// special case, we add a newline in between the first comment and syntax
 			// to separate licenses, file descriptions, etc.
-			v.P()
+			v.PTopLevelSeparator()
+		}
+		if editions.IsEdition(v.Syntax.Value) {
+			v.PWithInlineComment(v.Syntax.InlineComment, `edition = "`, v.Syntax.Value, `";`)
+		} else {
+			v.PWithInlineComment(v.Syntax.InlineComment, `syntax = "`, v.Syntax.Value, `";`)
 		}
-		v.PWithInlineComment(v.Syntax.InlineComment, `syntax = "`, v.Syntax.Value, `";`)
-		v.P()
+		v.PTopLevelSeparator()
 	}
 	if v.Package != nil {
 		v.PComment(v.Package.Comment)
 		v.PWithInlineComment(v.Package.InlineComment, `package `, v.Package.Name, `;`)
-		v.P()
+		v.PTopLevelSeparator()
 	}
-	if v.rewrite && v.Package != nil {
-		if v.goPackageOption == nil {
-			v.goPackageOption = &proto.Option{Name: "go_package"}
-		}
-		if v.javaMultipleFilesOption == nil {
-			v.javaMultipleFilesOption = &proto.Option{Name: "java_multiple_files"}
-		}
-		if v.javaOuterClassnameOption == nil {
-			v.javaOuterClassnameOption = &proto.Option{Name: "java_outer_classname"}
-		}
-		if v.javaPackageOption == nil {
-			v.javaPackageOption = &proto.Option{Name: "java_package"}
-		}
-		v.goPackageOption.Constant = proto.Literal{
-			Source:   protostrs.GoPackage(v.Package.Name),
-			IsString: true,
-		}
-		v.javaMultipleFilesOption.Constant = proto.Literal{
-			Source: "true",
-		}
-		v.javaOuterClassnameOption.Constant = proto.Literal{
-			Source:   protostrs.JavaOuterClassname(v.filename),
-			IsString: true,
-		}
-		v.javaPackageOption.Constant = proto.Literal{
-			Source:   protostrs.JavaPackage(v.Package.Name),
-			IsString: true,
-		}
-		v.Options = append(
-			v.Options,
-			v.goPackageOption,
-			v.javaMultipleFilesOption,
-			v.javaOuterClassnameOption,
-			v.javaPackageOption,
-		)
+	if v.rewrite {
+		v.Options = append(v.Options, v.getRewriteOptions()...)
 	}
 	if len(v.Options) > 0 {
-		v.POptions(false, v.Options...)
-		v.P()
+		if v.style.canonicalOrder {
+			sort.Slice(v.Options, func(i int, j int) bool { return protostrs.FileOptionLess(v.Options[i].Name, v.Options[j].Name) })
+			v.pOptions(false, v.Options...)
+		} else {
+			v.POptions(false, v.Options...)
+		}
+		v.PTopLevelSeparator()
 	}
 	if len(v.Imports) > 0 {
 		v.PImports(v.Imports)
-		v.P()
+		v.PTopLevelSeparator()
 	}
 	return v.Failures
 }
@@ -146,19 +127,11 @@ func (v *firstPassVisitor) VisitOption(element *proto.Option) {
 	// visiting of children in this visitor
 	v.haveHitNonComment = true
 	if v.rewrite {
-		switch element.Name {
-		case "go_package":
-			v.goPackageOption = element
-			return
-		case "java_multiple_files":
-			v.javaMultipleFilesOption = element
-			return
-		case "java_outer_classname":
-			v.javaOuterClassnameOption = element
-			return
-		case "java_package":
-			v.javaPackageOption = element
-			return
+		for _, name := range protostrs.FileOptionTemplateNames {
+			if element.Name == name {
+				v.rewriteOptions[name] = element
+				return
+			}
 		}
 	}
 	v.Options = append(v.Options, element)
@@ -186,7 +159,7 @@ func (v *firstPassVisitor) VisitComment(element *proto.Comment) {
 	// or package if they are at the top of the file
 	if !v.haveHitNonComment {
 		v.PComment(element)
-		v.P()
+		v.PTopLevelSeparator()
 	}
 }
 
@@ -218,11 +191,44 @@ func (v *firstPassVisitor) VisitExtensions(element *proto.Extensions) {
 	v.haveHitNonComment = true
 }
 
+// getRewriteOptions returns the file options that can be set from templates,
+// with their values computed from the templates if there is a package.
+func (v *firstPassVisitor) getRewriteOptions() []*proto.Option {
+	var options []*proto.Option
+	for _, name := range protostrs.FileOptionTemplateNames {
+		option := v.rewriteOptions[name]
+		if templateText, ok := v.fileOptionTemplates[name]; ok && v.Package != nil {
+			value, err := protostrs.FileOptionValue(templateText, v.Package.Name, v.filename)
+			if err != nil {
+				v.AddFailure(v.Package.Position, "could not compute file option %q: %v", name, err)
+				continue
+			}
+			if option == nil {
+				option = &proto.Option{Name: name}
+			}
+			option.Constant = proto.Literal{
+				Source:   value,
+				IsString: protostrs.FileOptionIsString(name),
+			}
+		}
+		if option != nil {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
 func (v *firstPassVisitor) PImports(imports []*proto.Import) {
 	if len(imports) == 0 {
 		return
 	}
-	sort.Slice(imports, func(i int, j int) bool { return imports[i].Filename < imports[j].Filename })
+	if v.style.canonicalOrder {
+		sort.Slice(imports, func(i int, j int) bool {
+			return protostrs.ImportLess(imports[i].Kind, imports[i].Filename, imports[j].Kind, imports[j].Filename)
+		})
+	} else {
+		sort.Slice(imports, func(i int, j int) bool { return imports[i].Filename < imports[j].Filename })
+	}
 	for _, i := range imports {
 		v.PComment(i.Comment)
 		// kind can be "weak", "public", or empty
@@ -21,6 +21,7 @@
 package format
 
 import (
+	"github.com/emicklei/proto"
 	"github.com/uber/prototool/internal/text"
 	"go.uber.org/zap"
 )
@@ -33,6 +34,12 @@ type Transformer interface {
 	// through protoc first, but this is done because we want to verify
 	// code correctness here and protect against the bad case.
 	Transform(filename string, data []byte) ([]byte, []*text.Failure, error)
+	// TransformDescriptor is Transform for a descriptor that was already
+	// parsed from the data with syntax.Parse, so that the data does not
+	// have to be parsed again.
+	//
+	// The descriptor is only modified by migrations.
+	TransformDescriptor(filename string, data []byte, descriptor *proto.Proto) ([]byte, []*text.Failure, error)
 }
 
 // TransformerOption is an option for a new Transformer.
@@ -55,6 +62,129 @@ func TransformerWithRewrite() TransformerOption {
 	}
 }
 
+// TransformerWithFileOptionTemplates returns a TransformerOption that computes the
+// values of the given file options from the given templates when rewriting.
+// The keys are expected to be in protostrs.FileOptionTemplateNames, and the
+// templates are executed with a protostrs.FileOptionTemplateData.
+//
+// The given templates override protostrs.DefaultFileOptionTemplates, or the
+// templates for the languages if TransformerWithLanguages is used.
+// This has no effect if TransformerWithRewrite is not used.
+func TransformerWithFileOptionTemplates(fileOptionTemplates map[string]string) TransformerOption {
+	return func(transformer *transformer) {
+		for name, templateText := range fileOptionTemplates {
+			transformer.fileOptionTemplateOverrides[name] = templateText
+		}
+	}
+}
+
+// TransformerWithLanguages returns a TransformerOption that sets the file
+// options of the given languages when rewriting, using the templates from
+// protostrs.LanguageFileOptionTemplates instead of
+// protostrs.DefaultFileOptionTemplates. The file options of other languages
+// are left as they are. The languages are expected to be in protostrs.Languages.
+//
+// This has no effect if TransformerWithRewrite is not used.
+func TransformerWithLanguages(languages []string) TransformerOption {
+	return func(transformer *transformer) {
+		transformer.languages = languages
+	}
+}
+
+// TransformerWithEdition returns a TransformerOption that will migrate proto3 files
+// to the given edition before formatting them.
+func TransformerWithEdition(edition string) TransformerOption {
+	return func(transformer *transformer) {
+		transformer.edition = edition
+	}
+}
+
+// TransformerWithProto3Migration returns a TransformerOption that will migrate
+// proto2 files to proto3 before formatting them.
+//
+// Constructs that cannot be migrated are returned as failures.
+func TransformerWithProto3Migration() TransformerOption {
+	return func(transformer *transformer) {
+		transformer.proto3Migration = true
+	}
+}
+
+// TransformerWithEnumNaming returns a TransformerOption that will rename enum
+// values to have the given prefix, which is one of protostrs.EnumValuePrefixes,
+// and enum zero values to have the given suffix.
+//
+// References to the renamed values, such as in default values and options,
+// are not updated.
+func TransformerWithEnumNaming(enumValuePrefix string, enumZeroValueSuffix string) TransformerOption {
+	return func(transformer *transformer) {
+		transformer.enumValuePrefix = enumValuePrefix
+		transformer.enumZeroValueSuffix = enumZeroValueSuffix
+	}
+}
+
+// TransformerWithIndent returns a TransformerOption that indents with the given string.
+//
+// The default is to indent with two spaces.
+func TransformerWithIndent(indent string) TransformerOption {
+	return func(transformer *transformer) {
+		transformer.style.indent = indent
+	}
+}
+
+// TransformerWithAlignFields returns a TransformerOption that aligns the = signs
+// of consecutive fields and enum values.
+func TransformerWithAlignFields() TransformerOption {
+	return func(transformer *transformer) {
+		transformer.style.alignFields = true
+	}
+}
+
+// TransformerWithAlignOptions returns a TransformerOption that aligns the opening
+// brackets of the options of consecutive fields and enum values.
+func TransformerWithAlignOptions() TransformerOption {
+	return func(transformer *transformer) {
+		transformer.style.alignOptions = true
+	}
+}
+
+// TransformerWithTopLevelBlankLines returns a TransformerOption that prints the
+// given number of blank lines between top-level elements.
+//
+// The default is to print one blank line.
+func TransformerWithTopLevelBlankLines(topLevelBlankLines int) TransformerOption {
+	return func(transformer *transformer) {
+		transformer.style.topLevelBlankLines = topLevelBlankLines
+	}
+}
+
+// TransformerWithCanonicalOrder returns a TransformerOption that orders imports and
+// file options canonically. Regular imports come before public imports, which come
+// before weak imports, and built-in file options come before custom file options.
+//
+// The default is to sort imports by filename and file options by name.
+func TransformerWithCanonicalOrder() TransformerOption {
+	return func(transformer *transformer) {
+		transformer.style.canonicalOrder = true
+	}
+}
+
+// TransformerWithRoundTripCheck returns a TransformerOption that checks that
+// formatting is idempotent, and that the formatted file parses to the same
+// FileDescriptorProto as the original file, ignoring source info. Imports are
+// resolved against the given include paths. Differences are returned as
+// FORMAT_ROUNDTRIP failures.
+//
+// File options are not compared if TransformerWithRewrite is used, as these are
+// expected to change. Files that cannot be parsed into descriptors without protoc,
+// such as files that use editions, are only checked for idempotency.
+// This is not expected to be used with migrations, which change descriptors.
+func TransformerWithRoundTripCheck(includePaths []string) TransformerOption {
+	return func(transformer *transformer) {
+		transformer.roundTripCheck = true
+		transformer.roundTripIncludePaths = includePaths
+	}
+}
+
 // NewTransformer returns a new Transformer.
 func NewTransformer(options ...TransformerOption) Transformer {
 	return newTransformer(options...)
@@ -38,8 +38,8 @@ type mainVisitor struct {
 	parent            proto.Visitee
 }
 
-func newMainVisitor(isProto2 bool) *mainVisitor {
-	return &mainVisitor{isProto2: isProto2, baseVisitor: newBaseVisitor()}
+func newMainVisitor(isProto2 bool, style *style) *mainVisitor {
+	return &mainVisitor{isProto2: isProto2, baseVisitor: newBaseVisitor(style)}
 }
 
 func (v *mainVisitor) Do() []*text.Failure {
@@ -55,7 +55,7 @@ func (v *mainVisitor) VisitMessage(element *proto.Message) {
 	}
 	if len(element.Elements) == 0 {
 		v.P(prefix, element.Name, " {}")
-		v.P()
+		v.pSeparator()
 		return
 	}
 	v.P(prefix, element.Name, " {")
@@ -69,7 +69,7 @@ func (v *mainVisitor) VisitMessage(element *proto.Message) {
 	v.Out()
 	v.P("}")
 	if v.parent == nil {
-		v.P()
+		v.PTopLevelSeparator()
 	}
 }
 
@@ -78,7 +78,7 @@ func (v *mainVisitor) VisitService(element *proto.Service) {
 	v.PComment(element.Comment)
 	if len(element.Elements) == 0 {
 		v.P("service ", element.Name, " {}")
-		v.P()
+		v.pSeparator()
 		return
 	}
 	v.P("service ", element.Name, " {")
@@ -91,7 +91,7 @@ func (v *mainVisitor) VisitService(element *proto.Service) {
 	v.parent = originalParent
 	v.Out()
 	v.P("}")
-	v.P()
+	v.pSeparator()
 }
 
 func (v *mainVisitor) VisitSyntax(element *proto.Syntax) {
@@ -132,17 +132,15 @@ func (v *mainVisitor) VisitImport(element *proto.Import) {
 func (v *mainVisitor) VisitNormalField(element *proto.NormalField) {
 	v.haveHitNonComment = true
 	prefix := ""
-	if element.Repeated {
+	switch {
+	case element.Repeated:
 		prefix = "repeated "
-	}
-	if v.isProto2 {
-		// technically these are only set if the file is proto2
-		// but doing this just to make sure
-		if element.Required {
-			prefix = "required "
-		} else {
-			prefix = "optional "
-		}
+	case element.Required:
+		prefix = "required "
+	case element.Optional || v.isProto2:
+		// fields in proto2 files always have a label, and optional
+		// fields in proto3 files have explicit presence
+		prefix = "optional "
 	}
 	v.PField(prefix, element.Type, element.Field)
 }
@@ -151,14 +149,10 @@ func (v *mainVisitor) VisitEnumField(element *proto.EnumField) {
 	v.haveHitNonComment = true
 	v.PComment(element.Comment)
 	if element.ValueOption == nil {
-		v.PWithInlineComment(element.InlineComment, element.Name, " = ", element.Integer, ";")
+		v.PAssignment(element.Name, element.Integer, element.InlineComment)
 		return
 	}
-	v.P(" ", element.Name, " = ", element.Integer, " [")
-	v.In()
-	v.POptions(true, element.ValueOption)
-	v.Out()
-	v.PWithInlineComment(element.InlineComment, "];")
+	v.PAssignment(element.Name, element.Integer, element.InlineComment, element.ValueOption)
 }
 
 func (v *mainVisitor) VisitEnum(element *proto.Enum) {
@@ -166,7 +160,7 @@ func (v *mainVisitor) VisitEnum(element *proto.Enum) {
 	v.PComment(element.Comment)
 	if len(element.Elements) == 0 {
 		v.P("enum ", element.Name, " {}")
-		v.P()
+		v.pSeparator()
 		return
 	}
 	v.P("enum ", element.Name, " {")
@@ -180,17 +174,28 @@ func (v *mainVisitor) VisitEnum(element *proto.Enum) {
 	v.Out()
 	v.P("}")
 	if v.parent == nil {
-		v.P()
+		v.PTopLevelSeparator()
 	}
 }
 
 func (v *mainVisitor) VisitComment(element *proto.Comment) {
 	if v.haveHitNonComment {
 		v.PComment(element)
-		v.P()
+		v.pSeparator()
 	}
 }
 
+// pSeparator prints the separator after an element that is always
+// followed by a blank line, which is the top-level separator if the
+// element is at the top level.
+func (v *mainVisitor) pSeparator() {
+	if v.parent == nil {
+		v.PTopLevelSeparator()
+		return
+	}
+	v.P()
+}
+
 func (v *mainVisitor) VisitOneof(element *proto.Oneof) {
 	v.haveHitNonComment = true
 	v.PComment(element.Comment)
@@ -24,38 +24,81 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 )
 
-const indentString = "  "
+const defaultIndent = "  "
+
+// style is the configurable part of how proto files are printed.
+type style struct {
+	indent             string
+	alignFields        bool
+	alignOptions       bool
+	topLevelBlankLines int
+	canonicalOrder     bool
+}
+
+func newStyle() *style {
+	return &style{
+		indent:             defaultIndent,
+		topLevelBlankLines: 1,
+	}
+}
 
 // printer is a convenience struct that helps when printing proto files.
 // The concept was taken from the golang/protobuf plugin.
+//
+// Lines are buffered until Bytes is called so that consecutive
+// fields can be aligned.
 type printer struct {
-	buffer      *bytes.Buffer
+	style       *style
+	lines       []*printerLine
 	indentCount int
 }
 
-func newPrinter() *printer {
-	return &printer{bytes.NewBuffer(nil), 0}
+// printerLine is a single printed line.
+//
+// Aligned lines have their columns padded to the width of the same
+// column of the other aligned lines in the same section. A section ends
+// at a blank line, a line with less indentation, or a line with the same
+// indentation that is not aligned and breaks alignment.
+type printerLine struct {
+	indentCount     int
+	columns         []string
+	aligned         bool
+	breaksAlignment bool
+}
+
+func newPrinter(style *style) *printer {
+	return &printer{style: style}
 }
 
 // P prints the args concatenated on the same line after printing the current indent and then prints a newline.
-//
-// TODO: There is a lot of unnecessary memory allocation going on here, optimize
 func (p *printer) P(args ...interface{}) {
-	lineBuffer := bytes.NewBuffer(nil)
-	if p.indentCount > 0 {
-		_, _ = fmt.Fprint(lineBuffer, strings.Repeat(indentString, p.indentCount))
-	}
-	for _, arg := range args {
-		_, _ = fmt.Fprint(lineBuffer, arg)
-	}
-	line := lineBuffer.Bytes()
+	p.addLine(false, true, concat(args...))
+}
+
+// PNonBreaking is like P, but the line does not end the current alignment section.
+//
+// This is used for comments and closing brackets of fields.
+func (p *printer) PNonBreaking(args ...interface{}) {
+	p.addLine(false, false, concat(args...))
+}
+
+// PAligned prints a line of columns that are aligned with other consecutive
+// aligned lines if the style says to.
+//
+// The columns are expected to be the part before the = sign, the part from
+// the = sign up to the options, and the rest of the line.
+func (p *printer) PAligned(name string, value string, rest string) {
+	p.addLine(true, false, name, value, rest)
+}
 
-	if len(bytes.TrimSpace(line)) != 0 {
-		_, _ = p.buffer.Write(line)
+// PTopLevelSeparator prints the blank lines between top-level elements.
+func (p *printer) PTopLevelSeparator() {
+	for i := 0; i < p.style.topLevelBlankLines; i++ {
+		p.P()
 	}
-	_, _ = p.buffer.WriteRune('\n')
 }
 
 // In adds one indent.
@@ -73,5 +116,129 @@ func (p *printer) Out() {
 
 // Bytes returns the printed bytes.
 func (p *printer) Bytes() []byte {
-	return p.buffer.Bytes()
+	buffer := bytes.NewBuffer(nil)
+	for i := 0; i < len(p.lines); {
+		if !p.lines[i].aligned {
+			p.writeLine(buffer, p.lines[i], nil)
+			i++
+			continue
+		}
+		end := p.sectionEnd(i)
+		widths := p.alignedWidths(p.lines[i:end])
+		for _, line := range p.lines[i:end] {
+			p.writeLine(buffer, line, widths)
+		}
+		i = end
+	}
+	return buffer.Bytes()
+}
+
+func (p *printer) addLine(aligned bool, breaksAlignment bool, columns ...string) {
+	p.lines = append(p.lines, &printerLine{
+		indentCount:     p.indentCount,
+		columns:         columns,
+		aligned:         aligned,
+		breaksAlignment: breaksAlignment,
+	})
+}
+
+// sectionEnd returns the index after the last line in the alignment
+// section that starts at the aligned line at index start.
+func (p *printer) sectionEnd(start int) int {
+	indentCount := p.lines[start].indentCount
+	end := start + 1
+	for ; end < len(p.lines); end++ {
+		line := p.lines[end]
+		if line.isBlank() || line.indentCount < indentCount {
+			break
+		}
+		if line.indentCount == indentCount && !line.aligned && line.breaksAlignment {
+			break
+		}
+	}
+	return end
+}
+
+// alignedWidths returns the widths to pad the name and value columns
+// of the aligned lines to, or nil if there is nothing to align.
+func (p *printer) alignedWidths(lines []*printerLine) []int {
+	if !p.style.alignFields && !p.style.alignOptions {
+		return nil
+	}
+	widths := make([]int, 2)
+	for _, line := range lines {
+		if !line.aligned {
+			continue
+		}
+		if p.style.alignFields {
+			widths[0] = maxInt(widths[0], utf8.RuneCountInString(line.columns[0]))
+		}
+	}
+	for _, line := range lines {
+		if !line.aligned || !line.hasOptions() {
+			continue
+		}
+		widths[1] = maxInt(widths[1], maxInt(widths[0], utf8.RuneCountInString(line.columns[0]))+utf8.RuneCountInString(line.columns[1]))
+	}
+	if !p.style.alignOptions {
+		widths[1] = 0
+	}
+	return widths
+}
+
+func (p *printer) writeLine(buffer *bytes.Buffer, line *printerLine, widths []int) {
+	lineBuffer := bytes.NewBuffer(nil)
+	if line.indentCount > 0 {
+		_, _ = lineBuffer.WriteString(strings.Repeat(p.style.indent, line.indentCount))
+	}
+	if line.aligned && widths != nil {
+		name := pad(line.columns[0], widths[0])
+		value := line.columns[1]
+		if line.hasOptions() {
+			value = pad(value, widths[1]-utf8.RuneCountInString(name))
+		}
+		_, _ = lineBuffer.WriteString(name + value + line.columns[2])
+	} else {
+		_, _ = lineBuffer.WriteString(strings.Join(line.columns, ""))
+	}
+	if !line.isBlank() {
+		_, _ = buffer.Write(lineBuffer.Bytes())
+	}
+	_, _ = buffer.WriteRune('\n')
+}
+
+func (l *printerLine) isBlank() bool {
+	for _, column := range l.columns {
+		if strings.TrimSpace(column) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *printerLine) hasOptions() bool {
+	return l.aligned && strings.HasPrefix(l.columns[2], " [")
+}
+
+// concat concatenates the args without adding spaces between them.
+func concat(args ...interface{}) string {
+	buffer := bytes.NewBuffer(nil)
+	for _, arg := range args {
+		_, _ = fmt.Fprint(buffer, arg)
+	}
+	return buffer.String()
+}
+
+func pad(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
 }
@@ -0,0 +1,184 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/uber/prototool/internal/text"
+	"go.uber.org/zap"
+)
+
+const roundTripFailureID = "FORMAT_ROUNDTRIP"
+
+// checkRoundTrip checks that formatting the output again does not change
+// it, and that the output parses to the same descriptor as the input.
+func (t *transformer) checkRoundTrip(filename string, input []byte, output []byte) ([]*text.Failure, error) {
+	secondOutput, secondFailures, err := t.transform(filename, output)
+	if err != nil {
+		return []*text.Failure{
+			text.NewFailuref(scanner.Position{Filename: filename}, roundTripFailureID, "Formatted file could not be formatted again: %v", err),
+		}, nil
+	}
+	if len(secondFailures) > 0 {
+		return []*text.Failure{
+			text.NewFailuref(scanner.Position{Filename: filename}, roundTripFailureID, "Formatted file could not be formatted again: %s", secondFailures[0].Message),
+		}, nil
+	}
+	if !bytes.Equal(output, secondOutput) {
+		return []*text.Failure{
+			text.NewFailuref(scanner.Position{Filename: filename, Line: getFirstDifferentLine(output, secondOutput)}, roundTripFailureID, "Formatting is not idempotent, formatting the formatted file again changes this line."),
+		}, nil
+	}
+	inputDescriptor, err := t.parseFileDescriptor(filename, input)
+	if err != nil {
+		// protoparse does not support everything that protoc does, and
+		// protoc already checked that the input is valid
+		t.logger.Debug("skipping descriptor round trip check", zap.String("filename", filename), zap.Error(err))
+		return nil, nil
+	}
+	outputDescriptor, err := t.parseFileDescriptor(filename, output)
+	if err != nil {
+		return []*text.Failure{
+			text.NewFailuref(scanner.Position{Filename: filename}, roundTripFailureID, "Formatted file does not parse: %v", err),
+		}, nil
+	}
+	normalizeFileDescriptor(inputDescriptor, t.rewrite)
+	normalizeFileDescriptor(outputDescriptor, t.rewrite)
+	if !proto.Equal(inputDescriptor, outputDescriptor) {
+		inputText := proto.MarshalTextString(inputDescriptor)
+		outputText := proto.MarshalTextString(outputDescriptor)
+		return []*text.Failure{
+			text.NewFailuref(scanner.Position{Filename: filename}, roundTripFailureID, "Formatted file does not parse to the same descriptor as the original file, the first difference is %q.", getLine(outputText, getFirstDifferentLine([]byte(inputText), []byte(outputText)))),
+		}, nil
+	}
+	return nil, nil
+}
+
+// parseFileDescriptor parses the data as the file at the path, resolving
+// imports against the include paths.
+//
+// protoparse panics on some valid files, which are returned as errors.
+func (t *transformer) parseFileDescriptor(path string, data []byte) (_ *descriptor.FileDescriptorProto, retErr error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			retErr = fmt.Errorf("could not parse %s: %v", path, recovered)
+		}
+	}()
+	path = filepath.Clean(path)
+	importPaths := t.roundTripIncludePaths
+	name := ""
+	for _, includePath := range importPaths {
+		relPath, err := filepath.Rel(includePath, path)
+		if err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			name = relPath
+			break
+		}
+	}
+	if name == "" {
+		importPaths = append([]string{filepath.Dir(path)}, importPaths...)
+		name = filepath.Base(path)
+	}
+	parser := protoparse.Parser{
+		ImportPaths: importPaths,
+		Accessor: func(filename string) (io.ReadCloser, error) {
+			if filepath.Clean(filename) == path {
+				return ioutil.NopCloser(bytes.NewReader(data)), nil
+			}
+			return os.Open(filename)
+		},
+	}
+	fileDescriptors, err := parser.ParseFiles(filepath.ToSlash(name))
+	if err != nil {
+		return nil, err
+	}
+	return fileDescriptors[0].AsFileDescriptorProto(), nil
+}
+
+// normalizeFileDescriptor clears the parts of the descriptor that
+// formatting is expected to change, which are the order of imports and
+// file options, and the file options if rewriting.
+func normalizeFileDescriptor(fileDescriptor *descriptor.FileDescriptorProto, rewrite bool) {
+	publicDependencies := make(map[string]struct{}, len(fileDescriptor.PublicDependency))
+	for _, index := range fileDescriptor.PublicDependency {
+		publicDependencies[fileDescriptor.Dependency[index]] = struct{}{}
+	}
+	weakDependencies := make(map[string]struct{}, len(fileDescriptor.WeakDependency))
+	for _, index := range fileDescriptor.WeakDependency {
+		weakDependencies[fileDescriptor.Dependency[index]] = struct{}{}
+	}
+	sort.Strings(fileDescriptor.Dependency)
+	fileDescriptor.PublicDependency = nil
+	fileDescriptor.WeakDependency = nil
+	for i, dependency := range fileDescriptor.Dependency {
+		if _, ok := publicDependencies[dependency]; ok {
+			fileDescriptor.PublicDependency = append(fileDescriptor.PublicDependency, int32(i))
+		}
+		if _, ok := weakDependencies[dependency]; ok {
+			fileDescriptor.WeakDependency = append(fileDescriptor.WeakDependency, int32(i))
+		}
+	}
+	if rewrite {
+		fileDescriptor.Options = nil
+	}
+	if fileDescriptor.Options != nil {
+		uninterpretedOptions := fileDescriptor.Options.UninterpretedOption
+		sort.Slice(uninterpretedOptions, func(i int, j int) bool {
+			return proto.CompactTextString(uninterpretedOptions[i]) < proto.CompactTextString(uninterpretedOptions[j])
+		})
+	}
+	fileDescriptor.SourceCodeInfo = nil
+}
+
+// getFirstDifferentLine returns the 1-based number of the first line that
+// differs between the two, which are expected to be different.
+func getFirstDifferentLine(one []byte, two []byte) int {
+	oneLines := strings.Split(string(one), "\n")
+	twoLines := strings.Split(string(two), "\n")
+	for i := 0; i < len(oneLines) && i < len(twoLines); i++ {
+		if oneLines[i] != twoLines[i] {
+			return i + 1
+		}
+	}
+	if len(oneLines) < len(twoLines) {
+		return len(oneLines) + 1
+	}
+	return len(twoLines) + 1
+}
+
+func getLine(s string, line int) string {
+	lines := strings.Split(s, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[line-1])
+}
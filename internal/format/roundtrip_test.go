@@ -0,0 +1,141 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTripNotIdempotent(t *testing.T) {
+	dirPath, cleanup := newTestRoundTripDir(t, nil)
+	defer cleanup()
+	filePath := filepath.Join(dirPath, "foo.proto")
+	transformer := newTransformer(TransformerWithRoundTripCheck([]string{dirPath}))
+	// formatting this output again fixes the indentation on line 6
+	failures, err := transformer.checkRoundTrip(filePath, []byte(`syntax = "proto3";
+
+package foo;
+
+message Foo {
+      string id = 1;
+}
+`), []byte(`syntax = "proto3";
+
+package foo;
+
+message Foo {
+      string id = 1;
+}
+`))
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, roundTripFailureID, failures[0].ID)
+	assert.Equal(t, filePath, failures[0].Filename)
+	assert.Equal(t, 6, failures[0].Line)
+	assert.Equal(t, "Formatting is not idempotent, formatting the formatted file again changes this line.", failures[0].Message)
+}
+
+func TestRoundTripDescriptorDiffers(t *testing.T) {
+	dirPath, cleanup := newTestRoundTripDir(t, nil)
+	defer cleanup()
+	filePath := filepath.Join(dirPath, "foo.proto")
+	transformer := newTransformer(TransformerWithRoundTripCheck([]string{dirPath}))
+	// the output is formatted, but the field number changed
+	failures, err := transformer.checkRoundTrip(filePath, []byte(`syntax = "proto3";
+
+package foo;
+
+message Foo {
+  string id = 1;
+}
+`), []byte(`syntax = "proto3";
+
+package foo;
+
+message Foo {
+  string id = 2;
+}
+`))
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, roundTripFailureID, failures[0].ID)
+	assert.Equal(t, filePath, failures[0].Filename)
+	assert.Equal(t, `Formatted file does not parse to the same descriptor as the original file, the first difference is "number: 2".`, failures[0].Message)
+}
+
+func TestRoundTripReorderedImports(t *testing.T) {
+	dirPath, cleanup := newTestRoundTripDir(
+		t,
+		map[string]string{
+			"a.proto": "syntax = \"proto3\";\n\npackage a;\n\nmessage A {}\n",
+			"b.proto": "syntax = \"proto3\";\n\npackage b;\n\nmessage B {}\n",
+		},
+	)
+	defer cleanup()
+	filePath := filepath.Join(dirPath, "foo.proto")
+	transformer := newTransformer(TransformerWithRoundTripCheck([]string{dirPath}))
+	input := []byte(`syntax = "proto3";
+
+package foo;
+
+import "b.proto";
+import public "a.proto";
+
+message Foo {
+  a.A a = 1;
+  b.B b = 2;
+}
+`)
+	output, failures, err := transformer.Transform(filePath, input)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+	assert.Equal(t, `syntax = "proto3";
+
+package foo;
+
+import public "a.proto";
+import "b.proto";
+
+message Foo {
+  a.A a = 1;
+  b.B b = 2;
+}
+`, string(output))
+	failures, err = transformer.checkRoundTrip(filePath, input, output)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+}
+
+// newTestRoundTripDir creates a temporary directory with the given files.
+func newTestRoundTripDir(t *testing.T, relPathToData map[string]string) (string, func()) {
+	dirPath, err := ioutil.TempDir("", "prototool-format")
+	require.NoError(t, err)
+	for relPath, data := range relPathToData {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, relPath), []byte(data), 0644))
+	}
+	return dirPath, func() { _ = os.RemoveAll(dirPath) }
+}
@@ -26,33 +26,110 @@ import (
 	"strings"
 
 	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/editions"
+	"github.com/uber/prototool/internal/proto3"
+	"github.com/uber/prototool/internal/protostrs"
+	"github.com/uber/prototool/internal/syntax"
 	"github.com/uber/prototool/internal/text"
 	"go.uber.org/zap"
 )
 
 type transformer struct {
-	logger  *zap.Logger
-	rewrite bool
+	logger          *zap.Logger
+	rewrite         bool
+	edition         string
+	proto3Migration bool
+	style           *style
+	// only used if enumValuePrefix is set
+	enumValuePrefix     string
+	enumZeroValueSuffix string
+	// only used if rewrite is set
+	languages                   []string
+	fileOptionTemplateOverrides map[string]string
+	fileOptionTemplates         map[string]string
+	// only used if roundTripCheck is set
+	roundTripCheck        bool
+	roundTripIncludePaths []string
 }
 
 func newTransformer(options ...TransformerOption) *transformer {
 	transformer := &transformer{
-		logger: zap.NewNop(),
+		logger:                      zap.NewNop(),
+		style:                       newStyle(),
+		fileOptionTemplateOverrides: make(map[string]string),
 	}
 	for _, option := range options {
 		option(transformer)
 	}
+	if len(transformer.languages) > 0 {
+		transformer.fileOptionTemplates = protostrs.LanguageFileOptionTemplates(transformer.languages)
+	} else {
+		transformer.fileOptionTemplates = copyFileOptionTemplates(protostrs.DefaultFileOptionTemplates)
+	}
+	for name, templateText := range transformer.fileOptionTemplateOverrides {
+		transformer.fileOptionTemplates[name] = templateText
+	}
 	return transformer
 }
 
 func (t *transformer) Transform(filename string, data []byte) ([]byte, []*text.Failure, error) {
-	descriptor, err := proto.NewParser(bytes.NewReader(data)).Parse()
+	descriptor, syntaxFailures, err := syntax.Parse(bytes.NewReader(data), filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(syntaxFailures) > 0 {
+		return nil, syntaxFailures, nil
+	}
+	descriptor.Filename = filename
+	return t.TransformDescriptor(filename, data, descriptor)
+}
+
+func (t *transformer) TransformDescriptor(filename string, data []byte, descriptor *proto.Proto) ([]byte, []*text.Failure, error) {
+	output, failures, err := t.transformDescriptor(filename, descriptor)
+	// if formatting does not change the file, formatting is idempotent
+	// for the file and the descriptors are the same
+	if err != nil || len(failures) > 0 || !t.roundTripCheck || bytes.Equal(data, output) {
+		return output, failures, err
+	}
+	failures, err = t.checkRoundTrip(filename, data, output)
+	if err != nil {
+		return nil, nil, err
+	}
+	return output, failures, nil
+}
+
+func (t *transformer) transform(filename string, data []byte) ([]byte, []*text.Failure, error) {
+	descriptor, syntaxFailures, err := syntax.Parse(bytes.NewReader(data), filename)
 	if err != nil {
 		return nil, nil, err
 	}
+	if len(syntaxFailures) > 0 {
+		return nil, syntaxFailures, nil
+	}
 	descriptor.Filename = filename
+	return t.transformDescriptor(filename, descriptor)
+}
+
+func (t *transformer) transformDescriptor(filename string, descriptor *proto.Proto) ([]byte, []*text.Failure, error) {
+	if t.proto3Migration {
+		failures, err := proto3.Migrate(descriptor)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(failures) > 0 {
+			return nil, failures, nil
+		}
+	}
+	if t.edition != "" {
+		if err := editions.Migrate(descriptor, t.edition); err != nil {
+			return nil, nil, err
+		}
+	}
+	if t.enumValuePrefix != "" {
+		renameEnumValues(descriptor.Elements, nil, t.enumValuePrefix, t.enumZeroValueSuffix)
+	}
 
-	firstPassVisitor := newFirstPassVisitor(filename, t.rewrite)
+	firstPassVisitor := newFirstPassVisitor(filename, t.rewrite, t.fileOptionTemplates, t.style)
 	for _, element := range descriptor.Elements {
 		element.Accept(firstPassVisitor)
 	}
@@ -68,11 +145,18 @@ func (t *transformer) Transform(filename string, data []byte) ([]byte, []*text.F
 		case "proto3":
 			syntaxVersion = 3
 		default:
-			return nil, nil, fmt.Errorf("unknown syntax: %s", firstPassVisitor.Syntax.Value)
+			if !editions.IsEdition(firstPassVisitor.Syntax.Value) {
+				return nil, nil, fmt.Errorf("unknown syntax: %s", firstPassVisitor.Syntax.Value)
+			}
+			if !editions.IsSupported(firstPassVisitor.Syntax.Value) {
+				return nil, nil, fmt.Errorf("unsupported edition: %s", firstPassVisitor.Syntax.Value)
+			}
+			// fields in editions do not have labels other than repeated
+			syntaxVersion = 3
 		}
 	}
 
-	mainVisitor := newMainVisitor(syntaxVersion == 2)
+	mainVisitor := newMainVisitor(syntaxVersion == 2, t.style)
 	for _, element := range descriptor.Elements {
 		element.Accept(mainVisitor)
 	}
@@ -88,3 +172,11 @@ func (t *transformer) Transform(filename string, data []byte) ([]byte, []*text.F
 	}
 	return nil, failures, nil
 }
+
+func copyFileOptionTemplates(fileOptionTemplates map[string]string) map[string]string {
+	c := make(map[string]string, len(fileOptionTemplates))
+	for name, templateText := range fileOptionTemplates {
+		c[name] = templateText
+	}
+	return c
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package git reads files at revisions of a git repository using the git
+// command, which must be on the PATH.
+//
+// Every git process is traced with the given exectrace.Tracer.
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/uber/prototool/internal/exectrace"
+)
+
+// VerifyRef returns an error if ref is not a valid commit in the
+// repository that contains dirPath.
+func VerifyRef(execTracer exectrace.Tracer, dirPath string, ref string) error {
+	if _, err := run(execTracer, dirPath, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return fmt.Errorf("invalid git ref %q: %v", ref, err)
+	}
+	return nil
+}
+
+// ReadFile returns the contents of the file at filePath at the given ref.
+//
+// Returns false if the file does not exist at the ref.
+func ReadFile(execTracer exectrace.Tracer, ref string, filePath string) ([]byte, bool, error) {
+	dirPath, filename := filepath.Split(filePath)
+	// ./ makes the path relative to dirPath instead of the repository root
+	object := ref + ":./" + filename
+	if _, err := run(execTracer, dirPath, "cat-file", "-e", object); err != nil {
+		return nil, false, nil
+	}
+	data, err := run(execTracer, dirPath, "show", object)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Commit is a commit in a repository.
+type Commit struct {
+	Hash    string
+	Subject string
+}
+
+// Log returns the commits after since up to and including HEAD that changed
+// files within dirPath, oldest first.
+//
+// Only first parents are followed, so the changes of a merged branch are
+// attributed to the merge commit.
+func Log(execTracer exectrace.Tracer, dirPath string, since string) ([]*Commit, error) {
+	data, err := run(execTracer, dirPath, "log", "--first-parent", "--reverse", "--format=%H %s", since+"..HEAD", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	var commits []*Commit
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		split := strings.SplitN(line, " ", 2)
+		commit := &Commit{Hash: split[0]}
+		if len(split) == 2 {
+			commit.Subject = split[1]
+		}
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// ListFiles returns the paths of the files within dirPath at the given ref,
+// relative to dirPath.
+func ListFiles(execTracer exectrace.Tracer, ref string, dirPath string) ([]string, error) {
+	data, err := run(execTracer, dirPath, "ls-tree", "-r", "--name-only", ref, "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	var filePaths []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			filePaths = append(filePaths, line)
+		}
+	}
+	return filePaths, nil
+}
+
+// ConfigValue returns the value of the config key, such as user.name, for
+// the repository that contains dirPath, falling back to the global config.
+//
+// Returns false if the key is not set.
+func ConfigValue(execTracer exectrace.Tracer, dirPath string, key string) (string, bool) {
+	// git config exits with 1 if the key is not set, and dirPath does not
+	// need to be in a repository
+	data, err := run(execTracer, dirPath, "config", "--get", key)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+// HooksDirPath returns the path of the hooks directory of the repository
+// that contains dirPath, respecting core.hooksPath.
+func HooksDirPath(execTracer exectrace.Tracer, dirPath string) (string, error) {
+	data, err := run(execTracer, dirPath, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	hooksDirPath := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(hooksDirPath) {
+		hooksDirPath = filepath.Join(dirPath, hooksDirPath)
+	}
+	return hooksDirPath, nil
+}
+
+func run(execTracer exectrace.Tracer, dirPath string, args ...string) ([]byte, error) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dirPath
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	finish := execTracer.Start(cmd)
+	err := cmd.Run()
+	finish(err)
+	if err != nil {
+		if stderrString := strings.TrimSpace(stderr.String()); stderrString != "" {
+			return nil, fmt.Errorf("git %s: %s", strings.Join(args, " "), stderrString)
+		}
+		return nil, fmt.Errorf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package githook generates git hooks that lint and check the formatting
+// of changed Protobuf files.
+package githook
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+const (
+	// HookTypePreCommit is the hook type that checks staged files.
+	HookTypePreCommit = "pre-commit"
+	// HookTypePrePush is the hook type that checks the files changed
+	// in the pushed commits.
+	HookTypePrePush = "pre-push"
+
+	// FrameworkPreCommit is the pre-commit framework at https://pre-commit.com.
+	FrameworkPreCommit = "pre-commit"
+)
+
+var (
+	hookTypeToFilesCommand = map[string]string{
+		HookTypePreCommit: `files="$(git diff --cached --name-only --diff-filter=ACMR -- '*.proto')"`,
+		// each line of stdin is "local_ref local_sha remote_ref remote_sha"
+		// if the remote ref does not exist yet, all files are checked
+		HookTypePrePush: `zero="0000000000000000000000000000000000000000"
+files=""
+while read -r local_ref local_sha remote_ref remote_sha; do
+  if [ "${local_sha}" = "${zero}" ]; then
+    continue
+  fi
+  if [ "${remote_sha}" = "${zero}" ]; then
+    files="${files} $(git ls-files -- '*.proto')"
+  else
+    files="${files} $(git diff --name-only --diff-filter=ACMR "${remote_sha}" "${local_sha}" -- '*.proto')"
+  fi
+done
+files="$(echo ${files} | tr ' ' '\n' | sort -u)"`,
+	}
+
+	hookTmpl = template.Must(template.New("hook").Parse(`#!/bin/sh
+# {{.HookType}} hook installed by prototool githook install.
+# Lints and checks the formatting of the changed Protobuf files.
+
+set -e
+
+cd "$(git rev-parse --show-toplevel)"
+{{.FilesCommand}}
+if [ -z "${files}" ]; then
+  exit 0
+fi
+prototool lint ${files}
+prototool format -l ${files}
+`))
+
+	preCommitHooksYAML = []byte(`- id: prototool-lint
+  name: prototool lint
+  description: Lint Protobuf files with prototool.
+  entry: prototool lint
+  language: system
+  files: \.proto$
+- id: prototool-format
+  name: prototool format
+  description: Check that Protobuf files are formatted with prototool.
+  entry: prototool format -l
+  language: system
+  files: \.proto$
+`)
+)
+
+// Generate generates the hook script for the hook type, either
+// HookTypePreCommit or HookTypePrePush.
+//
+// The script expects prototool to be on the PATH.
+func Generate(hookType string) ([]byte, error) {
+	filesCommand, ok := hookTypeToFilesCommand[hookType]
+	if !ok {
+		return nil, fmt.Errorf("unknown hook type %q, must be %s or %s", hookType, HookTypePreCommit, HookTypePrePush)
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := hookTmpl.Execute(buffer, struct {
+		HookType     string
+		FilesCommand string
+	}{
+		HookType:     hookType,
+		FilesCommand: filesCommand,
+	}); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// GenerateFramework generates the hook entries for the given framework,
+// which must be FrameworkPreCommit.
+//
+// For FrameworkPreCommit, this is a .pre-commit-hooks.yaml file.
+func GenerateFramework(framework string) ([]byte, error) {
+	if framework != FrameworkPreCommit {
+		return nil, fmt.Errorf("unknown framework %q, must be %s", framework, FrameworkPreCommit)
+	}
+	return preCommitHooksYAML, nil
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package githook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	for _, hookType := range []string{HookTypePreCommit, HookTypePrePush} {
+		data, err := Generate(hookType)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "#!/bin/sh\n# "+hookType+" hook")
+		assert.Contains(t, string(data), "prototool lint ${files}\n")
+		assert.Contains(t, string(data), "prototool format -l ${files}\n")
+	}
+	_, err := Generate("post-commit")
+	assert.Error(t, err)
+}
+
+func TestGenerateFramework(t *testing.T) {
+	data, err := GenerateFramework(FrameworkPreCommit)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "- id: prototool-lint\n")
+	_, err = GenerateFramework("husky")
+	assert.Error(t, err)
+}
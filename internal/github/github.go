@@ -0,0 +1,73 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package github provides functionality to print failures as GitHub
+// Actions workflow commands, which annotate the failures on pull requests.
+//
+// https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+package github
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/uber/prototool/internal/text"
+)
+
+var (
+	dataEscaper = strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+	)
+	propertyEscaper = strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+		":", "%3A",
+		",", "%2C",
+	)
+)
+
+// TextFailureToWorkflowCommand converts a text.Failure to a GitHub Actions
+// workflow command such as "::error file=foo.proto,line=1,col=2,title=FOO::Foo is a foo.".
+//
+// Warnings are converted to warning commands, informational failures
+// are converted to notice commands, and all other failures are converted
+// to error commands.
+func TextFailureToWorkflowCommand(textFailure *text.Failure) string {
+	jsonFailure := textFailure.JSONFailure()
+	command := "error"
+	switch jsonFailure.Severity {
+	case text.SeverityWarning:
+		command = "warning"
+	case text.SeverityInfo:
+		command = "notice"
+	}
+	properties := []string{
+		"file=" + propertyEscaper.Replace(jsonFailure.Filename),
+		fmt.Sprintf("line=%d", jsonFailure.Line),
+		fmt.Sprintf("col=%d", jsonFailure.Column),
+	}
+	if jsonFailure.ID != "" {
+		properties = append(properties, "title="+propertyEscaper.Replace(jsonFailure.ID))
+	}
+	return fmt.Sprintf("::%s %s::%s", command, strings.Join(properties, ","), dataEscaper.Replace(jsonFailure.Message))
+}
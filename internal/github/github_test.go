@@ -0,0 +1,55 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/prototool/internal/text"
+)
+
+func TestTextFailureToWorkflowCommand(t *testing.T) {
+	assert.Equal(
+		t,
+		"::error file=path/to/foo.proto,line=2,col=3,title=FOO::Foo is a foo.",
+		TextFailureToWorkflowCommand(
+			&text.Failure{
+				Filename: "path/to/foo.proto",
+				Line:     2,
+				Column:   3,
+				ID:       "FOO",
+				Message:  "Foo is a foo.",
+			},
+		),
+	)
+	assert.Equal(
+		t,
+		"::warning file=path/to/foo%2Cbar.proto,line=1,col=1::Foo is 100%25 a foo.%0AReally.",
+		TextFailureToWorkflowCommand(
+			&text.Failure{
+				Filename: "path/to/foo,bar.proto",
+				Message:  "Foo is 100% a foo.\nReally.",
+				Severity: text.SeverityWarning,
+			},
+		),
+	)
+}
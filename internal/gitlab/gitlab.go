@@ -0,0 +1,111 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package gitlab provides functionality to print failures as GitLab
+// Code Quality reports.
+//
+// https://docs.gitlab.com/ee/user/project/merge_requests/code_quality.html
+package gitlab
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+
+	"github.com/uber/prototool/internal/text"
+)
+
+const (
+	// DefaultCheckName is the check name used when a failure has no ID.
+	DefaultCheckName = "PROTOTOOL"
+	// SeverityMajor is the severity used for errors.
+	SeverityMajor = "major"
+	// SeverityMinor is the severity used for warnings.
+	SeverityMinor = "minor"
+	// SeverityInfo is the severity used for informational failures.
+	SeverityInfo = "info"
+)
+
+// CodeQualityIssue represents a text.Failure in a structure compatible
+// with a GitLab Code Quality issue. It is meant to be encoded to JSON.
+type CodeQualityIssue struct {
+	Description string    `json:"description"`
+	CheckName   string    `json:"check_name"`
+	Fingerprint string    `json:"fingerprint"`
+	Severity    string    `json:"severity"`
+	Location    *Location `json:"location"`
+}
+
+// Location is the location of a CodeQualityIssue.
+type Location struct {
+	Path  string `json:"path"`
+	Lines *Lines `json:"lines"`
+}
+
+// Lines are the lines of a Location.
+type Lines struct {
+	Begin int `json:"begin"`
+}
+
+// TextFailureToCodeQualityIssue converts a text.Failure to a CodeQualityIssue.
+//
+// The fingerprint does not include the line and column so that the
+// issue is tracked across changes that move it within the file.
+func TextFailureToCodeQualityIssue(textFailure *text.Failure) *CodeQualityIssue {
+	jsonFailure := textFailure.JSONFailure()
+	codeQualityIssue := &CodeQualityIssue{
+		Description: jsonFailure.Message,
+		CheckName:   jsonFailure.ID,
+		Severity:    SeverityMajor,
+		Location: &Location{
+			Path: jsonFailure.Filename,
+			Lines: &Lines{
+				Begin: jsonFailure.Line,
+			},
+		},
+	}
+	if codeQualityIssue.CheckName == "" {
+		codeQualityIssue.CheckName = DefaultCheckName
+	}
+	switch jsonFailure.Severity {
+	case text.SeverityWarning:
+		codeQualityIssue.Severity = SeverityMinor
+	case text.SeverityInfo:
+		codeQualityIssue.Severity = SeverityInfo
+	}
+	hash := sha256.Sum256([]byte(codeQualityIssue.Location.Path + ":" + codeQualityIssue.CheckName + ":" + codeQualityIssue.Description))
+	codeQualityIssue.Fingerprint = hex.EncodeToString(hash[:])
+	return codeQualityIssue
+}
+
+// WriteReport writes the text.Failures as a GitLab Code Quality report.
+func WriteReport(writer io.Writer, textFailures ...*text.Failure) error {
+	codeQualityIssues := make([]*CodeQualityIssue, 0, len(textFailures))
+	for _, textFailure := range textFailures {
+		codeQualityIssues = append(codeQualityIssues, TextFailureToCodeQualityIssue(textFailure))
+	}
+	data, err := json.MarshalIndent(codeQualityIssues, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"google.golang.org/grpc/credentials"
+)
+
+var _ credentials.PerRPCCredentials = &bearerTokenCredentials{}
+
+// bearerTokenCredentials attaches a bearer token to every call.
+//
+// Unlike golang.org/x/oauth2 based credentials, this does not require
+// transport security, as the grpc command dials without TLS by default.
+type bearerTokenCredentials struct {
+	token string
+}
+
+func newBearerTokenCredentials(token string) *bearerTokenCredentials {
+	return &bearerTokenCredentials{
+		token: token,
+	}
+}
+
+func (b *bearerTokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{
+		"authorization": "Bearer " + b.token,
+	}, nil
+}
+
+func (b *bearerTokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// newTransportCredentials returns the TLS credentials for the TLSConfig.
+func newTransportCredentials(tlsConfig *TLSConfig) (credentials.TransportCredentials, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: tlsConfig.InsecureSkipVerify,
+		ServerName:         tlsConfig.ServerName,
+	}
+	if tlsConfig.CACertPath != "" {
+		data, err := ioutil.ReadFile(tlsConfig.CACertPath)
+		if err != nil {
+			return nil, err
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", tlsConfig.CACertPath)
+		}
+		config.RootCAs = certPool
+	}
+	if tlsConfig.CertPath != "" {
+		certificate, err := tls.LoadX509KeyPair(tlsConfig.CertPath, tlsConfig.KeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return credentials.NewTLS(config), nil
+}
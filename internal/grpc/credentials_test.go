@@ -0,0 +1,49 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTransportCredentials(t *testing.T) {
+	transportCredentials, err := newTransportCredentials(&TLSConfig{ServerName: "example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "tls", transportCredentials.Info().SecurityProtocol)
+
+	tmpDir, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	caCertPath := filepath.Join(tmpDir, "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caCertPath, []byte("not a certificate"), 0644))
+	_, err = newTransportCredentials(&TLSConfig{CACertPath: caCertPath})
+	assert.Error(t, err)
+	_, err = newTransportCredentials(&TLSConfig{CACertPath: filepath.Join(tmpDir, "missing.pem")})
+	assert.Error(t, err)
+	_, err = newTransportCredentials(&TLSConfig{CertPath: caCertPath, KeyPath: caCertPath})
+	assert.Error(t, err)
+}
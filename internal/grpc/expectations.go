@@ -0,0 +1,315 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Expectations are assertions on the result of a call.
+//
+// If any assertion fails, the call returns an error that describes
+// all the failed assertions.
+type Expectations struct {
+	// Code is the expected status code of the call.
+	// If nil, the call is expected to succeed.
+	Code *codes.Code
+	// JSONResponses are the expected responses, each unmarshalled from JSON
+	// as with ParseJSONResponses. The responses are compared as JSON, so the
+	// order of fields does not matter.
+	// If nil, the responses are not compared.
+	JSONResponses []interface{}
+	// Fields are the expected values of fields of each response.
+	Fields []*FieldExpectation
+}
+
+// FieldExpectation is the expected value of a field of a response.
+type FieldExpectation struct {
+	// Path is the path of the field in the JSON response, such as
+	// $.items[0].name. The leading $ and period are optional.
+	Path string
+	// Value is the expected value unmarshalled from JSON with numbers
+	// as json.Number, as with ParseFieldExpectation.
+	Value interface{}
+}
+
+// ParseJSONResponses parses the expected responses from the reader, which
+// contains one JSON value per response, in the format of the JSON output of
+// the grpc command. Numbers are unmarshalled as json.Number.
+func ParseJSONResponses(reader io.Reader) ([]interface{}, error) {
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+	responses := make([]interface{}, 0)
+	for {
+		var response interface{}
+		if err := decoder.Decode(&response); err != nil {
+			if err == io.EOF {
+				return responses, nil
+			}
+			return nil, fmt.Errorf("could not parse expected responses: %v", err)
+		}
+		responses = append(responses, response)
+	}
+}
+
+// ParseFieldExpectation parses a FieldExpectation in the form path=value.
+//
+// The value is parsed as JSON, and if it is not valid JSON, it is used as a string.
+func ParseFieldExpectation(s string) (*FieldExpectation, error) {
+	split := strings.SplitN(s, "=", 2)
+	if len(split) != 2 || split[0] == "" {
+		return nil, fmt.Errorf("field expectation must be in the form path=value: %q", s)
+	}
+	if _, err := parseJSONPath(split[0]); err != nil {
+		return nil, err
+	}
+	value, err := unmarshalJSON(split[1])
+	if err != nil {
+		value = split[1]
+	}
+	return &FieldExpectation{
+		Path:  split[0],
+		Value: value,
+	}, nil
+}
+
+// GetJSONPathValue returns the value at the path of a FieldExpectation in
+// the value unmarshalled from JSON, and false if there is no value at the path.
+func GetJSONPathValue(value interface{}, path string) (interface{}, bool, error) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false, err
+	}
+	pathValue, ok := getJSONPathValue(value, segments)
+	return pathValue, ok, nil
+}
+
+// check checks the result of the call against the expectations.
+//
+// The callErr is the error of the call, if any, which is ignored if it
+// has the expected code.
+func (e *Expectations) check(code codes.Code, callErr error, jsonResponses []string) error {
+	var failures []string
+	if e.Code != nil {
+		if code != *e.Code {
+			failures = append(failures, fmt.Sprintf("expected code %v but got %v", *e.Code, code))
+		}
+	} else if callErr != nil {
+		return callErr
+	}
+	responses := make([]interface{}, len(jsonResponses))
+	for i, jsonResponse := range jsonResponses {
+		response, err := unmarshalJSON(jsonResponse)
+		if err != nil {
+			return err
+		}
+		responses[i] = response
+	}
+	if e.JSONResponses != nil {
+		if len(responses) != len(e.JSONResponses) {
+			failures = append(failures, fmt.Sprintf("expected %d responses but got %d", len(e.JSONResponses), len(responses)))
+		} else {
+			for i, response := range responses {
+				if !jsonValuesEqual(e.JSONResponses[i], response) {
+					failures = append(failures, fmt.Sprintf("response %d: expected %s but got %s", i+1, marshalJSON(e.JSONResponses[i]), marshalJSON(response)))
+				}
+			}
+		}
+	}
+	if len(e.Fields) > 0 && len(responses) == 0 {
+		failures = append(failures, "expected field values but got no responses")
+	}
+	for _, field := range e.Fields {
+		for i, response := range responses {
+			value, ok, err := GetJSONPathValue(response, field.Path)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				failures = append(failures, fmt.Sprintf("response %d: expected %s to be %s but it is not set", i+1, field.Path, marshalJSON(field.Value)))
+				continue
+			}
+			if !jsonValuesEqual(field.Value, value) {
+				failures = append(failures, fmt.Sprintf("response %d: expected %s to be %s but got %s", i+1, field.Path, marshalJSON(field.Value), marshalJSON(value)))
+			}
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(failures, "\n"))
+}
+
+// jsonPathSegment is either a field name or an array index.
+type jsonPathSegment struct {
+	name  string
+	index int
+	// isIndex is true if this is an array index
+	isIndex bool
+}
+
+func parseJSONPath(path string) ([]*jsonPathSegment, error) {
+	remaining := strings.TrimPrefix(path, "$")
+	remaining = strings.TrimPrefix(remaining, ".")
+	var segments []*jsonPathSegment
+	for remaining != "" {
+		if strings.HasPrefix(remaining, "[") {
+			end := strings.Index(remaining, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unclosed [", path)
+			}
+			index, err := strconv.Atoi(remaining[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid path %q: index must be a non-negative integer: %q", path, remaining[1:end])
+			}
+			segments = append(segments, &jsonPathSegment{index: index, isIndex: true})
+			remaining = strings.TrimPrefix(remaining[end+1:], ".")
+			continue
+		}
+		end := strings.IndexAny(remaining, ".[")
+		if end < 0 {
+			end = len(remaining)
+		}
+		if end == 0 {
+			return nil, fmt.Errorf("invalid path %q: empty field name", path)
+		}
+		segments = append(segments, &jsonPathSegment{name: remaining[:end]})
+		remaining = remaining[end:]
+		if strings.HasPrefix(remaining, ".") {
+			remaining = remaining[1:]
+			if remaining == "" {
+				return nil, fmt.Errorf("invalid path %q: empty field name", path)
+			}
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("invalid path %q: no fields", path)
+	}
+	return segments, nil
+}
+
+func getJSONPathValue(value interface{}, segments []*jsonPathSegment) (interface{}, bool) {
+	for _, segment := range segments {
+		if segment.isIndex {
+			array, ok := value.([]interface{})
+			if !ok || segment.index >= len(array) {
+				return nil, false
+			}
+			value = array[segment.index]
+			continue
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = object[segment.name]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func unmarshalJSON(s string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("more than one JSON value: %s", s)
+	}
+	return value, nil
+}
+
+func marshalJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// jsonValuesEqual compares two values unmarshalled from JSON.
+//
+// Numbers are compared by value, and 64-bit integers, which are strings
+// in the JSON output of responses, are equal to the same number.
+func jsonValuesEqual(expected interface{}, actual interface{}) bool {
+	switch e := expected.(type) {
+	case json.Number:
+		switch a := actual.(type) {
+		case json.Number:
+			return numbersEqual(e.String(), a.String())
+		case string:
+			return numbersEqual(e.String(), a)
+		}
+		return false
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(e) != len(a) {
+			return false
+		}
+		for i := range e {
+			if !jsonValuesEqual(e[i], a[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok || len(e) != len(a) {
+			return false
+		}
+		for key, value := range e {
+			actualValue, ok := a[key]
+			if !ok || !jsonValuesEqual(value, actualValue) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(expected, actual)
+	}
+}
+
+func numbersEqual(expected string, actual string) bool {
+	if expected == actual {
+		return true
+	}
+	expectedFloat, err := strconv.ParseFloat(expected, 64)
+	if err != nil {
+		return false
+	}
+	actualFloat, err := strconv.ParseFloat(actual, 64)
+	if err != nil {
+		return false
+	}
+	return expectedFloat == actualFloat
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestParseFieldExpectation(t *testing.T) {
+	fieldExpectation, err := ParseFieldExpectation(`$.items[0].name="foo"`)
+	require.NoError(t, err)
+	assert.Equal(t, &FieldExpectation{Path: "$.items[0].name", Value: "foo"}, fieldExpectation)
+	fieldExpectation, err = ParseFieldExpectation(`count=3`)
+	require.NoError(t, err)
+	assert.Equal(t, &FieldExpectation{Path: "count", Value: json.Number("3")}, fieldExpectation)
+	fieldExpectation, err = ParseFieldExpectation(`value=hello world`)
+	require.NoError(t, err)
+	assert.Equal(t, &FieldExpectation{Path: "value", Value: "hello world"}, fieldExpectation)
+	_, err = ParseFieldExpectation(`value`)
+	assert.Error(t, err)
+	_, err = ParseFieldExpectation(`items[a]=1`)
+	assert.Error(t, err)
+	_, err = ParseFieldExpectation(`items..name=1`)
+	assert.Error(t, err)
+}
+
+func TestExpectationsCheck(t *testing.T) {
+	jsonResponses := []string{
+		`{"id": "123", "items": [{"name": "foo"}, {"name": "bar"}], "ok": true}`,
+		`{"id": "456", "items": [], "ok": true}`,
+	}
+	expectedJSONResponses, err := ParseJSONResponses(strings.NewReader(`{"ok": true, "id": "123", "items": [{"name": "foo"}, {"name": "bar"}]}
+{"ok": true, "id": "456", "items": []}`))
+	require.NoError(t, err)
+	assert.NoError(t, (&Expectations{JSONResponses: expectedJSONResponses}).check(codes.OK, nil, jsonResponses))
+	assert.EqualError(
+		t,
+		(&Expectations{JSONResponses: expectedJSONResponses[:1]}).check(codes.OK, nil, jsonResponses),
+		"expected 1 responses but got 2",
+	)
+	assert.NoError(
+		t,
+		(&Expectations{Fields: []*FieldExpectation{{Path: "ok", Value: true}}}).check(codes.OK, nil, jsonResponses),
+	)
+	assert.EqualError(
+		t,
+		(&Expectations{
+			Fields: []*FieldExpectation{
+				{Path: "$.id", Value: json.Number("123")},
+				{Path: "items[1].name", Value: "bar"},
+			},
+		}).check(codes.OK, nil, jsonResponses),
+		`response 2: expected $.id to be 123 but got "456"
+response 2: expected items[1].name to be "bar" but it is not set`,
+	)
+	callErr := errors.New("rpc error: code = NotFound desc = not found")
+	assert.Equal(t, callErr, (&Expectations{}).check(codes.NotFound, callErr, nil))
+	notFound := codes.NotFound
+	assert.NoError(t, (&Expectations{Code: &notFound}).check(codes.NotFound, callErr, nil))
+	assert.EqualError(
+		t,
+		(&Expectations{Code: &notFound, Fields: []*FieldExpectation{{Path: "ok", Value: true}}}).check(codes.OK, nil, nil),
+		`expected code NotFound but got OK
+expected field values but got no responses`,
+	)
+}
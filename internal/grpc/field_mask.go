@@ -0,0 +1,133 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	reflectdesc "github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// fieldMask is the map from field name to the field mask of the field,
+// which is nil if the whole field is kept.
+type fieldMask map[string]fieldMask
+
+// newFieldMask returns the field mask for the message with the given paths,
+// which are dot-separated field names such as a.b. Field names can be the
+// Protobuf or JSON names of the fields.
+//
+// Fields of repeated message fields can be selected, which keeps the
+// fields of each message in the repeated field.
+func newFieldMask(messageDescriptor *reflectdesc.MessageDescriptor, paths []string) (fieldMask, error) {
+	mask := make(fieldMask)
+	for _, path := range paths {
+		if err := mask.add(messageDescriptor, path, strings.Split(path, ".")); err != nil {
+			return nil, err
+		}
+	}
+	return mask, nil
+}
+
+func (m fieldMask) add(messageDescriptor *reflectdesc.MessageDescriptor, path string, names []string) error {
+	field := findField(messageDescriptor, names[0])
+	if field == nil {
+		return fmt.Errorf("invalid field path %q: %s does not have field %q", path, messageDescriptor.GetFullyQualifiedName(), names[0])
+	}
+	name := field.GetName()
+	if len(names) == 1 {
+		// the whole field is kept even if fields of it were also given
+		m[name] = nil
+		return nil
+	}
+	if field.GetMessageType() == nil || field.IsMap() {
+		return fmt.Errorf("invalid field path %q: field %q is not a message field", path, names[0])
+	}
+	subMask, ok := m[name]
+	if ok && subMask == nil {
+		return nil
+	}
+	if !ok {
+		subMask = make(fieldMask)
+		m[name] = subMask
+	}
+	return subMask.add(field.GetMessageType(), path, names[1:])
+}
+
+// apply clears the fields of the message that are not in the field mask,
+// and returns the message.
+//
+// The message is converted to a dynamic message if it is not one.
+func (m fieldMask) apply(message proto.Message, messageDescriptor *reflectdesc.MessageDescriptor) (proto.Message, error) {
+	dynamicMessage, ok := message.(*dynamic.Message)
+	if !ok {
+		dynamicMessage = dynamic.NewMessage(messageDescriptor)
+		if err := dynamicMessage.ConvertFrom(message); err != nil {
+			return nil, err
+		}
+	}
+	for _, field := range messageDescriptor.GetFields() {
+		subMask, ok := m[field.GetName()]
+		if !ok {
+			dynamicMessage.ClearField(field)
+			continue
+		}
+		if subMask == nil || !dynamicMessage.HasField(field) {
+			continue
+		}
+		if field.IsRepeated() {
+			var values []interface{}
+			for _, element := range dynamicMessage.GetField(field).([]interface{}) {
+				value, err := subMask.apply(element.(proto.Message), field.GetMessageType())
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, value)
+			}
+			if err := dynamicMessage.TrySetField(field, values); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		value, err := subMask.apply(dynamicMessage.GetField(field).(proto.Message), field.GetMessageType())
+		if err != nil {
+			return nil, err
+		}
+		if err := dynamicMessage.TrySetField(field, value); err != nil {
+			return nil, err
+		}
+	}
+	return dynamicMessage, nil
+}
+
+func findField(messageDescriptor *reflectdesc.MessageDescriptor, name string) *reflectdesc.FieldDescriptor {
+	if field := messageDescriptor.FindFieldByName(name); field != nil {
+		return field
+	}
+	for _, field := range messageDescriptor.GetFields() {
+		if field.GetJSONName() == name {
+			return field
+		}
+	}
+	return nil
+}
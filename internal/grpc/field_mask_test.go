@@ -0,0 +1,99 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	reflectdesc "github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldMask(t *testing.T) {
+	messageDescriptor := newTestFieldMaskDescriptor(t)
+	input := `{"id":1,"helloWorld":"a","bar":{"a":"b","b":"c"},"bars":[{"a":"d","b":"e"},{"a":"f"}]}`
+
+	testFieldMask(t, messageDescriptor, []string{"id"}, input, `{"id":1}`)
+	testFieldMask(t, messageDescriptor, []string{"hello_world", "bar.a"}, input, `{"helloWorld":"a","bar":{"a":"b"}}`)
+	testFieldMask(t, messageDescriptor, []string{"helloWorld", "bars.b"}, input, `{"helloWorld":"a","bars":[{"b":"e"},{}]}`)
+	testFieldMask(t, messageDescriptor, []string{"bar.a", "bar"}, input, `{"bar":{"a":"b","b":"c"}}`)
+	testFieldMask(t, messageDescriptor, []string{"bar", "bar.a"}, input, `{"bar":{"a":"b","b":"c"}}`)
+	testFieldMask(t, messageDescriptor, []string{"bar.b"}, `{"id":1}`, `{}`)
+
+	_, err := newFieldMask(messageDescriptor, []string{"baz"})
+	assert.EqualError(t, err, `invalid field path "baz": foo.Foo does not have field "baz"`)
+	_, err = newFieldMask(messageDescriptor, []string{"bar.c"})
+	assert.EqualError(t, err, `invalid field path "bar.c": foo.Bar does not have field "c"`)
+	_, err = newFieldMask(messageDescriptor, []string{"id.a"})
+	assert.EqualError(t, err, `invalid field path "id.a": field "id" is not a message field`)
+}
+
+func testFieldMask(t *testing.T, messageDescriptor *reflectdesc.MessageDescriptor, paths []string, input string, expected string) {
+	mask, err := newFieldMask(messageDescriptor, paths)
+	require.NoError(t, err)
+	message := dynamic.NewMessage(messageDescriptor)
+	require.NoError(t, jsonpb.UnmarshalString(input, message))
+	masked, err := mask.apply(message, messageDescriptor)
+	require.NoError(t, err)
+	output, err := (&jsonpb.Marshaler{}).MarshalToString(masked)
+	require.NoError(t, err)
+	assert.JSONEq(t, expected, output)
+}
+
+func newTestFieldMaskDescriptor(t *testing.T) *reflectdesc.MessageDescriptor {
+	optional := descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+	repeated := descriptor.FieldDescriptorProto_LABEL_REPEATED
+	int32Type := descriptor.FieldDescriptorProto_TYPE_INT32
+	stringType := descriptor.FieldDescriptorProto_TYPE_STRING
+	messageType := descriptor.FieldDescriptorProto_TYPE_MESSAGE
+	fileDescriptor, err := reflectdesc.CreateFileDescriptor(&descriptor.FileDescriptorProto{
+		Name:    proto.String("foo.proto"),
+		Package: proto.String("foo"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Foo"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{Name: proto.String("id"), JsonName: proto.String("id"), Number: proto.Int32(1), Label: &optional, Type: &int32Type},
+					{Name: proto.String("hello_world"), JsonName: proto.String("helloWorld"), Number: proto.Int32(2), Label: &optional, Type: &stringType},
+					{Name: proto.String("bar"), JsonName: proto.String("bar"), Number: proto.Int32(3), Label: &optional, Type: &messageType, TypeName: proto.String(".foo.Bar")},
+					{Name: proto.String("bars"), JsonName: proto.String("bars"), Number: proto.Int32(4), Label: &repeated, Type: &messageType, TypeName: proto.String(".foo.Bar")},
+				},
+			},
+			{
+				Name: proto.String("Bar"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{Name: proto.String("a"), JsonName: proto.String("a"), Number: proto.Int32(1), Label: &optional, Type: &stringType},
+					{Name: proto.String("b"), JsonName: proto.String("b"), Number: proto.Int32(2), Label: &optional, Type: &stringType},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	messageDescriptor := fileDescriptor.FindMessage("foo.Foo")
+	require.NotNil(t, messageDescriptor)
+	return messageDescriptor
+}
@@ -21,12 +21,19 @@
 package grpc
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"net"
 	"time"
 
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	reflectdesc "github.com/jhump/protoreflect/desc"
+	"github.com/uber/prototool/internal/exectrace"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
 )
 
 const (
@@ -34,11 +41,78 @@ const (
 	DefaultCallTimeout = 60 * time.Second
 	// DefaultConnectTimeout is the default connect timeout.
 	DefaultConnectTimeout = 10 * time.Second
+	// DefaultRetryBackoff is the default backoff before the first retry.
+	DefaultRetryBackoff = 100 * time.Millisecond
+
+	// OutputFormatJSON prints each response message as JSON on its own line.
+	OutputFormatJSON = "json"
+	// OutputFormatBinary writes the response messages in the binary wire format.
+	// If there is more than one response message, each is prefixed with
+	// its length as a varint.
+	OutputFormatBinary = "binary"
+	// OutputFormatText prints each response message in the text format.
+	OutputFormatText = "text"
 )
 
 // Handler handles gRPC calls.
 type Handler interface {
+	// Invoke calls the method with the JSON requests read from the input.
+	//
+	// If the input is nil, the method is called with an empty request, or
+	// with no requests if it is client streaming, which is an error if the
+	// request type has required fields.
+	//
+	// If the method is unary and the input is a JSON array of requests, the
+	// method is called once per request and the responses are written as a
+	// JSON array.
 	Invoke(fileDescriptorSets []*descriptor.FileDescriptorSet, address string, method string, inputReader io.Reader, outputWriter io.Writer) error
+	// InvokeMessages calls the method with the requests and returns the
+	// responses, for use as a library.
+	//
+	// The requests must be of the input type of the method. If the method
+	// is not client streaming, there must be at most one request, and an
+	// empty request is used if there is none. The error of a failed call is
+	// the gRPC status error. The output options, expectations, recording,
+	// and retries of the Handler are not used.
+	InvokeMessages(ctx context.Context, fileDescriptorSets []*descriptor.FileDescriptorSet, address string, method string, requests []proto.Message) (*MessagesResult, error)
+	// GetMethodDescriptor returns the descriptor of the method in the
+	// FileDescriptorSets, in the form package.Service/Method.
+	GetMethodDescriptor(fileDescriptorSets []*descriptor.FileDescriptorSet, method string) (*reflectdesc.MethodDescriptor, error)
+	// Interactive starts an interactive session that reads commands from the
+	// input and keeps the connection and headers between calls.
+	//
+	// If the input is a terminal, commands and methods are tab-completed.
+	// If there are no services in the FileDescriptorSets, server reflection
+	// is used to find the methods.
+	Interactive(fileDescriptorSets []*descriptor.FileDescriptorSet, address string, input io.Reader, output io.Writer) error
+	// List returns the methods in the FileDescriptorSets sorted by name.
+	//
+	// If there are no services in the FileDescriptorSets, server reflection
+	// is used to find the methods, which requires the address to be set.
+	List(fileDescriptorSets []*descriptor.FileDescriptorSet, address string) ([]*Method, error)
+	// Proxy serves on the listener and forwards every call to the address
+	// unchanged, writing a line of JSON to the output for each request and
+	// response and for the end of each call, with the messages decoded if
+	// the method is in the FileDescriptorSets.
+	//
+	// The headers are added to the forwarded calls, and each call to a
+	// method in the FileDescriptorSets is recorded if recording is set.
+	Proxy(fileDescriptorSets []*descriptor.FileDescriptorSet, address string, listener net.Listener, output io.Writer) error
+}
+
+// TLSConfig is the TLS configuration of connections.
+type TLSConfig struct {
+	// The path to the PEM file of the certificate authorities to verify
+	// the server with. If empty, the system certificate authorities are used.
+	CACertPath string
+	// The paths to the PEM files of the client certificate and key, if any.
+	CertPath string
+	KeyPath  string
+	// Do not verify the server certificate.
+	InsecureSkipVerify bool
+	// The name to verify the server certificate with.
+	// If empty, the host of the address is used.
+	ServerName string
 }
 
 // HandlerOption is an option for a new Handler.
@@ -64,6 +138,53 @@ func HandlerWithCallTimeout(callTimeout time.Duration) HandlerOption {
 	}
 }
 
+// HandlerWithDeadline returns a HandlerOption that sets the deadline of
+// each invocation, which is sent to the server in the grpc-timeout header.
+//
+// This takes precedence over the call timeout for invocations, so that
+// server-side deadline handling can be exercised with an exact deadline.
+//
+// The default is to use the call timeout.
+func HandlerWithDeadline(deadline time.Duration) HandlerOption {
+	return func(handler *handler) {
+		handler.deadline = deadline
+	}
+}
+
+// HandlerWithCancelAfter returns a HandlerOption that cancels each
+// invocation after the given duration, so that server-side cancellation
+// handling can be exercised.
+//
+// The default is to never cancel an invocation.
+func HandlerWithCancelAfter(cancelAfter time.Duration) HandlerOption {
+	return func(handler *handler) {
+		handler.cancelAfter = cancelAfter
+	}
+}
+
+// HandlerWithCancelAfterBytes returns a HandlerOption that cancels each
+// invocation once at least the given number of bytes of responses have
+// been received, for example to cancel a server stream partway through.
+//
+// The default is to never cancel an invocation.
+func HandlerWithCancelAfterBytes(cancelAfterBytes int) HandlerOption {
+	return func(handler *handler) {
+		handler.cancelAfterBytes = cancelAfterBytes
+	}
+}
+
+// HandlerWithFields returns a HandlerOption that only prints the given
+// fields of responses, which are dot-separated field paths such as a.b.
+// This does not affect expectations and recordings, which use the whole
+// responses.
+//
+// The default is to print all fields.
+func HandlerWithFields(fields []string) HandlerOption {
+	return func(handler *handler) {
+		handler.fields = fields
+	}
+}
+
 // HandlerWithConnectTimeout returns a HandlerOption that has the given connect timeout.
 //
 // The default is to use DefaultConnectTimeout.
@@ -82,6 +203,107 @@ func HandlerWithKeepaliveTime(keepaliveTime time.Duration) HandlerOption {
 	}
 }
 
+// HandlerWithWaitForReady returns a HandlerOption that waits for the
+// connection to be ready instead of failing the call immediately if the
+// connection is in a transient failure state, up to the call timeout.
+//
+// The default is to fail immediately.
+func HandlerWithWaitForReady() HandlerOption {
+	return func(handler *handler) {
+		handler.waitForReady = true
+	}
+}
+
+// HandlerWithMaxRecvMsgSize returns a HandlerOption that sets the maximum
+// size in bytes of a response message.
+//
+// The default is to use the gRPC default of 4MB.
+func HandlerWithMaxRecvMsgSize(maxRecvMsgSize int) HandlerOption {
+	return func(handler *handler) {
+		handler.maxRecvMsgSize = maxRecvMsgSize
+	}
+}
+
+// HandlerWithMaxSendMsgSize returns a HandlerOption that sets the maximum
+// size in bytes of a request message.
+//
+// The default is to use the gRPC default, which has no limit.
+func HandlerWithMaxSendMsgSize(maxSendMsgSize int) HandlerOption {
+	return func(handler *handler) {
+		handler.maxSendMsgSize = maxSendMsgSize
+	}
+}
+
+// HandlerWithRetryPolicy returns a HandlerOption that makes up to maxAttempts
+// attempts of a call that fails with one of the given codes. The backoff
+// before the first retry is initialBackoff, and doubles after each retry.
+//
+// A call is only retried if no response or metadata was written for it.
+// The default is to make one attempt.
+func HandlerWithRetryPolicy(maxAttempts int, initialBackoff time.Duration, retryableCodes ...codes.Code) HandlerOption {
+	return func(handler *handler) {
+		handler.maxAttempts = maxAttempts
+		handler.retryBackoff = initialBackoff
+		handler.retryableCodes = retryableCodes
+	}
+}
+
+// HandlerWithPrintMetadata returns a HandlerOption that prints the response
+// headers and trailers in addition to the response messages.
+//
+// The default is to only print the response messages.
+func HandlerWithPrintMetadata() HandlerOption {
+	return func(handler *handler) {
+		handler.printMetadata = true
+	}
+}
+
+// HandlerWithCompression returns a HandlerOption that compresses requests
+// with the given compressor. The only supported compressor is "gzip".
+//
+// Gzip-compressed responses are always accepted regardless of this option.
+// The default is to not compress requests.
+func HandlerWithCompression(compression string) HandlerOption {
+	return func(handler *handler) {
+		handler.compression = compression
+	}
+}
+
+// HandlerWithAuthority returns a HandlerOption that uses the given value for
+// the :authority pseudo-header.
+//
+// The default is to use the address.
+func HandlerWithAuthority(authority string) HandlerOption {
+	return func(handler *handler) {
+		handler.authority = authority
+	}
+}
+
+// HandlerWithUserAgent returns a HandlerOption that uses the given value as the
+// user-agent. This is prepended to the gRPC library user-agent.
+func HandlerWithUserAgent(userAgent string) HandlerOption {
+	return func(handler *handler) {
+		handler.userAgent = userAgent
+	}
+}
+
+// HandlerWithAuthToken returns a HandlerOption that attaches the given token
+// to each call as the header "authorization: Bearer token".
+func HandlerWithAuthToken(authToken string) HandlerOption {
+	return func(handler *handler) {
+		handler.authToken = authToken
+	}
+}
+
+// HandlerWithTLS returns a HandlerOption that connects with TLS.
+//
+// The default is to connect without TLS.
+func HandlerWithTLS(tlsConfig TLSConfig) HandlerOption {
+	return func(handler *handler) {
+		handler.tlsConfig = &tlsConfig
+	}
+}
+
 // HandlerWithHeader returns a HandlerOption that adds the given key/value header.
 func HandlerWithHeader(key string, value string) HandlerOption {
 	return func(handler *handler) {
@@ -92,6 +314,69 @@ func HandlerWithHeader(key string, value string) HandlerOption {
 	}
 }
 
+// HandlerWithJSONMarshaler returns a HandlerOption that uses the given
+// marshaler to print responses.
+//
+// The default is to use a marshaler that indents with two spaces.
+func HandlerWithJSONMarshaler(jsonMarshaler *jsonpb.Marshaler) HandlerOption {
+	return func(handler *handler) {
+		handler.jsonMarshaler = jsonMarshaler
+	}
+}
+
+// HandlerWithOutputFormat returns a HandlerOption that writes responses
+// in the given format, which must be one of OutputFormatJSON,
+// OutputFormatBinary, or OutputFormatText.
+//
+// The default is to use OutputFormatJSON.
+func HandlerWithOutputFormat(outputFormat string) HandlerOption {
+	return func(handler *handler) {
+		handler.outputFormat = outputFormat
+	}
+}
+
+// HandlerWithExpectations returns a HandlerOption that checks the result of
+// each call against the given expectations.
+//
+// The default is to have no expectations, in which case a call fails if
+// it does not succeed.
+func HandlerWithExpectations(expectations *Expectations) HandlerOption {
+	return func(handler *handler) {
+		handler.expectations = expectations
+	}
+}
+
+// HandlerWithRecordFunc returns a HandlerOption that calls recordFunc with
+// the Recording of each call after it completes, including calls that fail.
+//
+// The default is to not record calls.
+func HandlerWithRecordFunc(recordFunc func(*Recording)) HandlerOption {
+	return func(handler *handler) {
+		handler.recordFunc = recordFunc
+	}
+}
+
+// HandlerWithExecTracer returns a HandlerOption that traces the editor
+// processes started by interactive sessions with the given tracer.
+//
+// The default is to not trace anything.
+func HandlerWithExecTracer(execTracer exectrace.Tracer) HandlerOption {
+	return func(handler *handler) {
+		handler.execTracer = execTracer
+	}
+}
+
+// GetMethods returns the sorted gRPC methods in the given FileDescriptorSets
+// in the form package.Service/Method.
+func GetMethods(fileDescriptorSets []*descriptor.FileDescriptorSet) []string {
+	methodsWithTypes := getMethodsWithTypes(fileDescriptorSets)
+	methods := make([]string, len(methodsWithTypes))
+	for i, method := range methodsWithTypes {
+		methods[i] = method.Name
+	}
+	return methods
+}
+
 // NewHandler returns a new Handler.
 func NewHandler(options ...HandlerOption) Handler {
 	return newHandler(options...)
@@ -21,20 +21,30 @@
 package grpc
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strings"
 	"time"
 
 	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	reflectdesc "github.com/jhump/protoreflect/desc"
 	"github.com/uber/prototool/internal/desc"
+	"github.com/uber/prototool/internal/exectrace"
 	"github.com/uber/prototool/internal/extract"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 )
 
 type handler struct {
@@ -43,13 +53,36 @@ type handler struct {
 	connectTimeout time.Duration
 	keepaliveTime  time.Duration
 	headers        []string
+	printMetadata  bool
+	compression    string
+	authority      string
+	userAgent      string
+	authToken      string
+	tlsConfig      *TLSConfig
+	jsonMarshaler  *jsonpb.Marshaler
+	outputFormat   string
+	waitForReady   bool
+	maxRecvMsgSize int
+	maxSendMsgSize int
+	maxAttempts    int
+	retryBackoff   time.Duration
+	retryableCodes []codes.Code
+	expectations   *Expectations
+	recordFunc     func(*Recording)
+	execTracer     exectrace.Tracer
+	// deadline overrides callTimeout for invocations if set
+	deadline         time.Duration
+	cancelAfter      time.Duration
+	cancelAfterBytes int
+	fields           []string
 
 	getter extract.Getter
 }
 
 func newHandler(options ...HandlerOption) *handler {
 	handler := &handler{
-		logger: zap.NewNop(),
+		logger:     zap.NewNop(),
+		execTracer: exectrace.NewNopTracer(),
 	}
 	for _, option := range options {
 		option(handler)
@@ -60,6 +93,18 @@ func newHandler(options ...HandlerOption) *handler {
 	if handler.connectTimeout == 0 {
 		handler.connectTimeout = DefaultConnectTimeout
 	}
+	if handler.jsonMarshaler == nil {
+		handler.jsonMarshaler = &jsonpb.Marshaler{Indent: "  "}
+	}
+	if handler.outputFormat == "" {
+		handler.outputFormat = OutputFormatJSON
+	}
+	if handler.maxAttempts < 1 {
+		handler.maxAttempts = 1
+	}
+	if handler.retryBackoff == 0 {
+		handler.retryBackoff = DefaultRetryBackoff
+	}
 	// TODO(pedge): composition
 	handler.getter = extract.NewGetter(
 		extract.GetterWithLogger(handler.logger),
@@ -68,18 +113,199 @@ func newHandler(options ...HandlerOption) *handler {
 }
 
 func (h *handler) Invoke(fileDescriptorSets []*descriptor.FileDescriptorSet, address string, method string, inputReader io.Reader, outputWriter io.Writer) error {
+	switch h.outputFormat {
+	case OutputFormatJSON, OutputFormatBinary, OutputFormatText:
+	default:
+		return fmt.Errorf("unsupported output format %q, must be one of %s, %s, or %s", h.outputFormat, OutputFormatJSON, OutputFormatBinary, OutputFormatText)
+	}
 	descriptorSource, err := h.getDescriptorSourceForMethod(fileDescriptorSets, method)
 	if err != nil {
 		return err
 	}
-	clientConn, err := h.dial(address)
+	methodDescriptor, err := getMethodDescriptor(descriptorSource, method)
+	if err != nil {
+		return err
+	}
+	if inputReader == nil {
+		h.logger.Debug("no data set, using default requests", zap.String("method", method))
+		inputReader, err = getDefaultInputReader(methodDescriptor)
+		if err != nil {
+			return err
+		}
+	}
+	requiredFields := getRequiredFields(methodDescriptor.GetInputType())
+	var mask fieldMask
+	if len(h.fields) > 0 {
+		mask, err = newFieldMask(methodDescriptor.GetOutputType(), h.fields)
+		if err != nil {
+			return err
+		}
+	}
+	dialOptions, err := h.getDialOptions()
+	if err != nil {
+		return err
+	}
+	clientConn, err := h.dial(address, dialOptions)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = clientConn.Close() }()
-	invocationEventHandler := newInvocationEventHandler(outputWriter, h.logger)
-	ctx, cancel := context.WithTimeout(context.Background(), h.callTimeout)
+	anyResolver, err := desc.NewAnyResolver(fileDescriptorSets...)
+	if err != nil {
+		return err
+	}
+	jsonMarshaler := *h.jsonMarshaler
+	jsonMarshaler.AnyResolver = anyResolver
+	call := func(inputReader io.Reader, outputWriter io.Writer) error {
+		return h.call(descriptorSource, clientConn, method, requiredFields, methodDescriptor.GetOutputType(), mask, inputReader, outputWriter, &jsonMarshaler)
+	}
+	if methodDescriptor.IsClientStreaming() || methodDescriptor.IsServerStreaming() {
+		return call(inputReader, outputWriter)
+	}
+	input, err := ioutil.ReadAll(inputReader)
+	if err != nil {
+		return err
+	}
+	requests, err := getJSONArrayRequests(input)
+	if err != nil {
+		return err
+	}
+	if requests == nil {
+		return call(bytes.NewReader(input), outputWriter)
+	}
+	return h.callEach(requests, call, outputWriter, jsonMarshaler.Indent)
+}
+
+// call calls the method with retries, and records the call and checks
+// the expectations if set.
+func (h *handler) call(
+	descriptorSource grpcurl.DescriptorSource,
+	clientConn *grpc.ClientConn,
+	method string,
+	requiredFields []*reflectdesc.FieldDescriptor,
+	responseDescriptor *reflectdesc.MessageDescriptor,
+	mask fieldMask,
+	inputReader io.Reader,
+	outputWriter io.Writer,
+	jsonMarshaler *jsonpb.Marshaler,
+) error {
+	if h.maxAttempts == 1 {
+		if h.recordFunc == nil {
+			return h.checkExpectations(h.invoke(descriptorSource, clientConn, method, requiredFields, responseDescriptor, mask, inputReader, outputWriter, jsonMarshaler))
+		}
+		recordingReader := &recordingReader{reader: inputReader}
+		result, err := h.invoke(descriptorSource, clientConn, method, requiredFields, responseDescriptor, mask, recordingReader, outputWriter, jsonMarshaler)
+		h.recordFunc(newRecording(method, recordingReader.buffer.Bytes(), result))
+		return h.checkExpectations(result, err)
+	}
+	// the input is read for each attempt
+	input, err := ioutil.ReadAll(inputReader)
+	if err != nil {
+		return err
+	}
+	backoff := h.retryBackoff
+	for attempt := 1; ; attempt++ {
+		result, err := h.invoke(descriptorSource, clientConn, method, requiredFields, responseDescriptor, mask, bytes.NewReader(input), outputWriter, jsonMarshaler)
+		if err == nil || result.written || attempt >= h.maxAttempts || !h.isRetryable(result.code) {
+			if h.recordFunc != nil {
+				h.recordFunc(newRecording(method, input, result))
+			}
+			return h.checkExpectations(result, err)
+		}
+		h.logger.Debug("retrying call", zap.Int("attempt", attempt), zap.Duration("backoff", backoff), zap.Error(err))
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// callEach calls a unary method once per request, in order, and writes the
+// responses as a JSON array indented with the given indent.
+//
+// The calls stop at the first error, in which case the responses so far
+// are written and the error is returned.
+func (h *handler) callEach(requests []json.RawMessage, call func(io.Reader, io.Writer) error, outputWriter io.Writer, indent string) error {
+	if h.outputFormat != OutputFormatJSON {
+		return fmt.Errorf("a JSON array of requests can only be used with output format %s", OutputFormatJSON)
+	}
+	if h.printMetadata {
+		return errors.New("a JSON array of requests cannot be used when printing metadata")
+	}
+	responses := make([]json.RawMessage, 0, len(requests))
+	var callErr error
+	for i, request := range requests {
+		buffer := bytes.NewBuffer(nil)
+		if err := call(bytes.NewReader(request), buffer); err != nil {
+			callErr = fmt.Errorf("request %d: %v", i, err)
+		}
+		if response := bytes.TrimSpace(buffer.Bytes()); len(response) > 0 {
+			responses = append(responses, response)
+		}
+		if callErr != nil {
+			break
+		}
+	}
+	data, err := json.Marshal(responses)
+	if err != nil {
+		return err
+	}
+	if indent != "" {
+		indented := bytes.NewBuffer(nil)
+		if err := json.Indent(indented, data, "", indent); err != nil {
+			return err
+		}
+		data = indented.Bytes()
+	}
+	if _, err := outputWriter.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return callErr
+}
+
+// invocationResult is the result of one attempt of a call.
+type invocationResult struct {
+	// whether anything was written to the output
+	written bool
+	code    codes.Code
+	// the responses as JSON, only recorded if there are expectations
+	// or calls are recorded
+	jsonResponses []string
+}
+
+// invoke makes one attempt of the call.
+func (h *handler) invoke(
+	descriptorSource grpcurl.DescriptorSource,
+	clientConn *grpc.ClientConn,
+	method string,
+	requiredFields []*reflectdesc.FieldDescriptor,
+	responseDescriptor *reflectdesc.MessageDescriptor,
+	mask fieldMask,
+	inputReader io.Reader,
+	outputWriter io.Writer,
+	jsonMarshaler *jsonpb.Marshaler,
+) (*invocationResult, error) {
+	invocationEventHandler := newInvocationEventHandler(outputWriter, h.logger, jsonMarshaler, h.printMetadata, h.outputFormat, h.expectations != nil || h.recordFunc != nil)
+	timeout := h.callTimeout
+	if h.deadline != 0 {
+		timeout = h.deadline
+	}
+	h.logger.Debug("invoking", zap.String("method", method), zap.Duration("grpc_timeout", timeout))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
+	if h.cancelAfter != 0 {
+		timer := time.AfterFunc(h.cancelAfter, func() {
+			h.logger.Debug("canceling call", zap.Duration("after", h.cancelAfter))
+			cancel()
+		})
+		defer timer.Stop()
+	}
+	if h.cancelAfterBytes != 0 {
+		invocationEventHandler.cancelAfterBytes = h.cancelAfterBytes
+		invocationEventHandler.cancel = cancel
+	}
+	if mask != nil {
+		invocationEventHandler.fieldMask = mask
+		invocationEventHandler.responseDescriptor = responseDescriptor
+	}
 	if err := grpcurl.InvokeRpc(
 		ctx,
 		descriptorSource,
@@ -87,21 +313,86 @@ func (h *handler) Invoke(fileDescriptorSets []*descriptor.FileDescriptorSet, add
 		method,
 		h.headers,
 		invocationEventHandler,
-		decodeFunc(inputReader),
+		decodeFunc(inputReader, requiredFields),
 	); err != nil {
+		return invocationEventHandler.Result(status.Code(err)), err
+	}
+	// binary responses are written after the call completes, as whether
+	// they are length-delimited depends on how many there are
+	if err := invocationEventHandler.Flush(); err != nil {
+		return invocationEventHandler.Result(codes.Unknown), err
+	}
+	return invocationEventHandler.Result(invocationEventHandler.Code()), invocationEventHandler.Err()
+}
+
+// checkExpectations checks the result of the call against the expectations,
+// if there are any, and otherwise returns the error of the call.
+func (h *handler) checkExpectations(result *invocationResult, err error) error {
+	if h.expectations == nil {
 		return err
 	}
-	return invocationEventHandler.Err()
+	return h.expectations.check(result.code, err, result.jsonResponses)
+}
+
+func (h *handler) isRetryable(code codes.Code) bool {
+	for _, retryableCode := range h.retryableCodes {
+		if code == retryableCode {
+			return true
+		}
+	}
+	return false
 }
 
-func (h *handler) dial(address string) (*grpc.ClientConn, error) {
+func (h *handler) dial(address string, dialOptions []grpc.DialOption) (*grpc.ClientConn, error) {
+	var transportCredentials credentials.TransportCredentials
+	if h.tlsConfig != nil {
+		var err error
+		transportCredentials, err = newTransportCredentials(h.tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), h.connectTimeout)
 	defer cancel()
-	return grpcurl.BlockingDial(ctx, "tcp", address, nil, h.getDialOptions()...)
+	return grpcurl.BlockingDial(ctx, "tcp", address, transportCredentials, dialOptions...)
 }
 
-func (h *handler) getDialOptions() []grpc.DialOption {
+func (h *handler) getDialOptions() ([]grpc.DialOption, error) {
 	var dialOptions []grpc.DialOption
+	switch h.compression {
+	case "", "identity":
+	case gzip.Name:
+		// importing the gzip package registers the compressor, which also
+		// results in gzip being advertised in grpc-accept-encoding
+		dialOptions = append(
+			dialOptions,
+			grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
+		)
+	default:
+		return nil, fmt.Errorf("unsupported compression %q, only %q is supported", h.compression, gzip.Name)
+	}
+	if h.authority != "" {
+		dialOptions = append(dialOptions, grpc.WithAuthority(h.authority))
+	}
+	if h.userAgent != "" {
+		dialOptions = append(dialOptions, grpc.WithUserAgent(h.userAgent))
+	}
+	if h.authToken != "" {
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(newBearerTokenCredentials(h.authToken)))
+	}
+	var callOptions []grpc.CallOption
+	if h.waitForReady {
+		callOptions = append(callOptions, grpc.FailFast(false))
+	}
+	if h.maxRecvMsgSize != 0 {
+		callOptions = append(callOptions, grpc.MaxCallRecvMsgSize(h.maxRecvMsgSize))
+	}
+	if h.maxSendMsgSize != 0 {
+		callOptions = append(callOptions, grpc.MaxCallSendMsgSize(h.maxSendMsgSize))
+	}
+	if len(callOptions) > 0 {
+		dialOptions = append(dialOptions, grpc.WithDefaultCallOptions(callOptions...))
+	}
 	if h.keepaliveTime != 0 {
 		dialOptions = append(
 			dialOptions,
@@ -113,7 +404,7 @@ func (h *handler) getDialOptions() []grpc.DialOption {
 			),
 		)
 	}
-	return dialOptions
+	return dialOptions, nil
 }
 
 func (h *handler) getDescriptorSourceForMethod(fileDescriptorSets []*descriptor.FileDescriptorSet, method string) (grpcurl.DescriptorSource, error) {
@@ -140,13 +431,18 @@ func getServiceForMethod(method string) (string, error) {
 	return split[0], nil
 }
 
-func decodeFunc(reader io.Reader) func() ([]byte, error) {
+// decodeFunc returns a function that reads the next JSON request from
+// the reader, checking that it has the given required fields.
+func decodeFunc(reader io.Reader, requiredFields []*reflectdesc.FieldDescriptor) func() ([]byte, error) {
 	decoder := json.NewDecoder(reader)
 	return func() ([]byte, error) {
 		var rawMessage json.RawMessage
 		if err := decoder.Decode(&rawMessage); err != nil {
 			return nil, err
 		}
+		if err := checkRequiredFields(rawMessage, requiredFields); err != nil {
+			return nil, err
+		}
 		return rawMessage, nil
 	}
 }
@@ -0,0 +1,133 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/mock"
+)
+
+func TestCallEach(t *testing.T) {
+	requests := []json.RawMessage{
+		json.RawMessage(`{"value":"a"}`),
+		json.RawMessage(`{"value":"b"}`),
+		json.RawMessage(`{"value":"c"}`),
+	}
+	var calls []string
+	call := func(inputReader io.Reader, outputWriter io.Writer) error {
+		data, err := ioutil.ReadAll(inputReader)
+		if err != nil {
+			return err
+		}
+		calls = append(calls, string(data))
+		if strings.Contains(string(data), "b") {
+			return errors.New("b is not allowed")
+		}
+		_, err = outputWriter.Write([]byte(strings.ToUpper(string(data)) + "\n"))
+		return err
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	err := newHandler().callEach(requests[:1], call, buffer, "  ")
+	assert.NoError(t, err)
+	assert.Equal(t, "[\n  {\n    \"VALUE\": \"A\"\n  }\n]\n", buffer.String())
+
+	calls = nil
+	buffer.Reset()
+	err = newHandler().callEach(requests, call, buffer, "")
+	assert.EqualError(t, err, "request 1: b is not allowed")
+	assert.Equal(t, []string{`{"value":"a"}`, `{"value":"b"}`}, calls)
+	assert.Equal(t, "[{\"VALUE\":\"A\"}]\n", buffer.String())
+
+	err = newHandler(HandlerWithOutputFormat(OutputFormatBinary)).callEach(requests, call, buffer, "")
+	assert.Error(t, err)
+}
+
+func TestInvokeCompression(t *testing.T) {
+	fileDescriptorSets := newEchoFileDescriptorSets()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	server := mock.NewServer(mock.ServerWithFixtureData([]byte("proxytest.EchoAPI/Echo:\n  value: pong\n")))
+	go func() { _ = server.Serve(fileDescriptorSets, listener) }()
+
+	for _, compression := range []string{"", "identity", "gzip"} {
+		output := bytes.NewBuffer(nil)
+		err := newHandler(HandlerWithCompression(compression)).Invoke(fileDescriptorSets, listener.Addr().String(), "proxytest.EchoAPI/Echo", strings.NewReader(`{"value":"ping"}`), output)
+		assert.NoError(t, err, compression)
+		assert.Equal(t, "{\n  \"value\": \"pong\"\n}\n", output.String(), compression)
+	}
+	err = newHandler(HandlerWithCompression("snappy")).Invoke(fileDescriptorSets, listener.Addr().String(), "proxytest.EchoAPI/Echo", strings.NewReader(`{"value":"ping"}`), bytes.NewBuffer(nil))
+	assert.EqualError(t, err, `unsupported compression "snappy", only "gzip" is supported`)
+}
+
+// newEchoFileDescriptorSets returns the FileDescriptorSets for a
+// proxytest.EchoAPI service with a single unary Echo method.
+func newEchoFileDescriptorSets() []*descriptor.FileDescriptorSet {
+	fileDescriptorSet := &descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("proxytest/echo.proto"),
+				Package: proto.String("proxytest"),
+				Syntax:  proto.String("proto3"),
+				MessageType: []*descriptor.DescriptorProto{
+					{
+						Name: proto.String("Value"),
+						Field: []*descriptor.FieldDescriptorProto{
+							{
+								Name:     proto.String("value"),
+								JsonName: proto.String("value"),
+								Number:   proto.Int32(1),
+								Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+								Type:     descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+							},
+						},
+					},
+				},
+				Service: []*descriptor.ServiceDescriptorProto{
+					{
+						Name: proto.String("EchoAPI"),
+						Method: []*descriptor.MethodDescriptorProto{
+							{
+								Name:       proto.String("Echo"),
+								InputType:  proto.String(".proxytest.Value"),
+								OutputType: proto.String(".proxytest.Value"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	return []*descriptor.FileDescriptorSet{fileDescriptorSet}
+}
@@ -0,0 +1,415 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"github.com/uber/prototool/internal/desc"
+	"github.com/uber/prototool/internal/exectrace"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh/terminal"
+	"google.golang.org/grpc"
+	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+const (
+	interactivePrompt = "> "
+	reflectionService = "grpc.reflection.v1alpha.ServerReflection"
+)
+
+var interactiveCommands = []string{
+	"edit",
+	"exit",
+	"header",
+	"headers",
+	"help",
+	"methods",
+	"unheader",
+}
+
+const interactiveHelp = `Commands:
+  package.Service/Method [json]  Call the method with the JSON request. If no request is given,
+                                 the last request for the method is used, or {} if there is none.
+  edit package.Service/Method    Edit the request for the method with $EDITOR and call the method.
+  header key:value               Set a header for all subsequent calls.
+  unheader key                   Remove a header.
+  headers                        Print the headers.
+  methods                        Print the available methods.
+  help                           Print this help.
+  exit                           Exit.
+Press tab to complete commands and methods.`
+
+// session is an interactive session that keeps a single connection and a
+// set of headers between calls.
+type session struct {
+	handler            *handler
+	fileDescriptorSets []*descriptor.FileDescriptorSet
+	clientConn         *grpc.ClientConn
+	// reflectionSource is set if the methods were resolved with server reflection
+	reflectionSource grpcurl.DescriptorSource
+	reflectionCancel context.CancelFunc
+	methods          []string
+	headers          map[string]string
+	lastRequests     map[string]string
+	output           io.Writer
+	// editFunc is set if the session is running in a terminal
+	editFunc func(string) (string, error)
+}
+
+func (h *handler) Interactive(fileDescriptorSets []*descriptor.FileDescriptorSet, address string, input io.Reader, output io.Writer) error {
+	session, err := h.newSession(fileDescriptorSets, address)
+	if err != nil {
+		return err
+	}
+	defer session.close()
+	if file, ok := input.(*os.File); ok && terminal.IsTerminal(int(file.Fd())) {
+		return session.runTerminal(file, output)
+	}
+	return session.runLines(input, output)
+}
+
+func (h *handler) newSession(fileDescriptorSets []*descriptor.FileDescriptorSet, address string) (*session, error) {
+	dialOptions, err := h.getDialOptions()
+	if err != nil {
+		return nil, err
+	}
+	clientConn, err := h.dial(address, dialOptions)
+	if err != nil {
+		return nil, err
+	}
+	session := &session{
+		handler:            h,
+		fileDescriptorSets: fileDescriptorSets,
+		clientConn:         clientConn,
+		headers:            make(map[string]string),
+		lastRequests:       make(map[string]string),
+	}
+	for _, header := range h.headers {
+		split := strings.SplitN(header, ":", 2)
+		session.headers[split[0]] = split[1]
+	}
+	session.methods = GetMethods(fileDescriptorSets)
+	if len(session.methods) == 0 {
+		if err := session.resolveWithReflection(); err != nil {
+			session.close()
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+// resolveWithReflection uses server reflection to find the methods
+// when there are no services in the file descriptor sets.
+func (s *session) resolveWithReflection() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.reflectionCancel = cancel
+	s.reflectionSource = grpcurl.DescriptorSourceFromServer(ctx, grpcreflect.NewClient(ctx, reflectpb.NewServerReflectionClient(s.clientConn)))
+	methods, err := getReflectionMethods(s.reflectionSource)
+	if err != nil {
+		return fmt.Errorf("no services in the given files and could not use server reflection: %v", err)
+	}
+	for _, method := range methods {
+		s.methods = append(s.methods, method.Name)
+	}
+	return nil
+}
+
+func (s *session) close() {
+	if s.reflectionCancel != nil {
+		s.reflectionCancel()
+	}
+	_ = s.clientConn.Close()
+}
+
+func (s *session) runLines(input io.Reader, output io.Writer) error {
+	s.output = output
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		if exit := s.handleLine(scanner.Text()); exit {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *session) runTerminal(file *os.File, output io.Writer) error {
+	fd := int(file.Fd())
+	oldState, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = terminal.Restore(fd, oldState) }()
+	term := terminal.NewTerminal(
+		struct {
+			io.Reader
+			io.Writer
+		}{file, output},
+		interactivePrompt,
+	)
+	term.AutoCompleteCallback = s.autoComplete
+	s.output = term
+	s.editFunc = func(data string) (string, error) {
+		if err := terminal.Restore(fd, oldState); err != nil {
+			return "", err
+		}
+		defer func() {
+			// the state is restored when the session ends
+			_, _ = terminal.MakeRaw(fd)
+		}()
+		return editWithEditor(s.handler.execTracer, data, file, output)
+	}
+	s.println("Type help for a list of commands.")
+	for {
+		line, err := term.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if exit := s.handleLine(line); exit {
+			return nil
+		}
+	}
+}
+
+// handleLine handles a single line of input and returns true if the
+// session should end.
+//
+// Errors are printed so that the session can continue.
+func (s *session) handleLine(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return false
+	}
+	split := strings.SplitN(line, " ", 2)
+	command := split[0]
+	argument := ""
+	if len(split) == 2 {
+		argument = strings.TrimSpace(split[1])
+	}
+	switch command {
+	case "exit", "quit":
+		return true
+	case "help":
+		s.println(interactiveHelp)
+	case "methods":
+		for _, method := range s.methods {
+			s.println(method)
+		}
+	case "headers":
+		keys := make([]string, 0, len(s.headers))
+		for key := range s.headers {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s.println(key + ":" + s.headers[key])
+		}
+	case "header":
+		split := strings.SplitN(argument, ":", 2)
+		if len(split) != 2 || split[0] == "" {
+			s.printError(fmt.Errorf("headers must be key:value but got %s", argument))
+			return false
+		}
+		s.headers[split[0]] = split[1]
+	case "unheader":
+		delete(s.headers, argument)
+	case "edit":
+		s.printError(s.edit(argument))
+	default:
+		s.printError(s.invoke(command, argument))
+	}
+	return false
+}
+
+func (s *session) edit(method string) error {
+	if s.editFunc == nil {
+		return fmt.Errorf("edit is only supported when running in a terminal")
+	}
+	if method == "" {
+		return fmt.Errorf("must set method")
+	}
+	data, err := s.editFunc(s.getRequest(method))
+	if err != nil {
+		return err
+	}
+	return s.invoke(method, data)
+}
+
+func (s *session) invoke(method string, data string) error {
+	if _, err := getServiceForMethod(method); err != nil {
+		return fmt.Errorf("unknown command or invalid gRPC method: %s", method)
+	}
+	if data == "" {
+		data = s.getRequest(method)
+	}
+	descriptorSource := s.reflectionSource
+	if descriptorSource == nil {
+		var err error
+		descriptorSource, err = s.handler.getDescriptorSourceForMethod(s.fileDescriptorSets, method)
+		if err != nil {
+			return err
+		}
+	}
+	anyResolver, err := desc.NewAnyResolver(s.fileDescriptorSets...)
+	if err != nil {
+		return err
+	}
+	s.lastRequests[method] = data
+	jsonMarshaler := *s.handler.jsonMarshaler
+	jsonMarshaler.AnyResolver = anyResolver
+	invocationEventHandler := newInvocationEventHandler(s.output, s.handler.logger, &jsonMarshaler, s.handler.printMetadata, OutputFormatJSON, false)
+	ctx, cancel := context.WithTimeout(context.Background(), s.handler.callTimeout)
+	defer cancel()
+	if err := grpcurl.InvokeRpc(
+		ctx,
+		descriptorSource,
+		s.clientConn,
+		method,
+		s.getHeaders(),
+		invocationEventHandler,
+		decodeFunc(strings.NewReader(data), nil),
+	); err != nil {
+		return err
+	}
+	return invocationEventHandler.Err()
+}
+
+func (s *session) getRequest(method string) string {
+	if data, ok := s.lastRequests[method]; ok {
+		return data
+	}
+	return "{}"
+}
+
+func (s *session) getHeaders() []string {
+	headers := make([]string, 0, len(s.headers))
+	for key, value := range s.headers {
+		headers = append(headers, fmt.Sprintf("%s:%s", key, value))
+	}
+	sort.Strings(headers)
+	return headers
+}
+
+// autoComplete completes the command or method before the cursor
+// to the longest common prefix of the matching candidates.
+func (s *session) autoComplete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+	before := line[:pos]
+	var candidates []string
+	var prefix string
+	switch split := strings.SplitN(before, " ", 2); {
+	case len(split) == 1:
+		candidates = append(append(candidates, interactiveCommands...), s.methods...)
+		prefix = split[0]
+	case split[0] == "edit" && !strings.Contains(split[1], " "):
+		candidates = s.methods
+		prefix = split[1]
+	default:
+		return "", 0, false
+	}
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matches = append(matches, candidate)
+		}
+	}
+	if len(matches) == 0 {
+		return "", 0, false
+	}
+	completion := longestCommonPrefix(matches)
+	if len(matches) == 1 {
+		completion += " "
+	}
+	newBefore := before[:len(before)-len(prefix)] + completion
+	return newBefore + line[pos:], len(newBefore), true
+}
+
+func (s *session) println(value string) {
+	if _, err := fmt.Fprintln(s.output, value); err != nil {
+		s.handler.logger.Error("write error", zap.Error(err))
+	}
+}
+
+func (s *session) printError(err error) {
+	if err != nil {
+		s.println("error: " + err.Error())
+	}
+}
+
+func editWithEditor(execTracer exectrace.Tracer, data string, input io.Reader, output io.Writer) (string, error) {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	file, err := ioutil.TempFile("", "prototool-grpc-")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+	if _, err := file.WriteString(data); err != nil {
+		_ = file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	cmd := exec.Command(editor, file.Name())
+	cmd.Stdin = input
+	cmd.Stdout = output
+	cmd.Stderr = output
+	finish := execTracer.Start(cmd)
+	err = cmd.Run()
+	finish(err)
+	if err != nil {
+		return "", err
+	}
+	edited, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(edited)), nil
+}
+
+func longestCommonPrefix(values []string) string {
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -21,31 +21,61 @@
 package grpc
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
 
 	"github.com/fullstorydev/grpcurl"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"github.com/jhump/protoreflect/desc"
 	"go.uber.org/zap"
+	// registers the google.rpc error detail types such as BadRequest and
+	// RetryInfo so that they can be decoded from a google.rpc.Status
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
-var jsonpbMarshaler = &jsonpb.Marshaler{Indent: "  "}
-
 var _ grpcurl.InvocationEventHandler = &invocationEventHandler{}
 
 type invocationEventHandler struct {
-	output io.Writer
-	logger *zap.Logger
-	err    error
+	output        io.Writer
+	logger        *zap.Logger
+	jsonMarshaler *jsonpb.Marshaler
+	printMetadata bool
+	outputFormat  string
+	// binary responses are held until Flush
+	binaryResponses [][]byte
+	// whether anything was written or is held to be written
+	written bool
+	code    codes.Code
+	err     error
+	// the responses are recorded as JSON if recordResponses is set
+	recordResponses bool
+	jsonResponses   []string
+	// cancel is called once receivedBytes reaches cancelAfterBytes,
+	// if cancelAfterBytes is set
+	cancelAfterBytes int
+	receivedBytes    int
+	cancel           func()
+	// the fields not in fieldMask are cleared before responses are
+	// printed, if fieldMask is set
+	fieldMask          fieldMask
+	responseDescriptor *desc.MessageDescriptor
 }
 
-func newInvocationEventHandler(output io.Writer, logger *zap.Logger) *invocationEventHandler {
+func newInvocationEventHandler(output io.Writer, logger *zap.Logger, jsonMarshaler *jsonpb.Marshaler, printMetadata bool, outputFormat string, recordResponses bool) *invocationEventHandler {
 	return &invocationEventHandler{
-		output: output,
-		logger: logger,
+		output:          output,
+		logger:          logger,
+		jsonMarshaler:   jsonMarshaler,
+		printMetadata:   printMetadata,
+		outputFormat:    outputFormat,
+		recordResponses: recordResponses,
 	}
 }
 
@@ -53,15 +83,55 @@ func (i *invocationEventHandler) OnResolveMethod(*desc.MethodDescriptor) {}
 
 func (i *invocationEventHandler) OnSendHeaders(metadata.MD) {}
 
-func (i *invocationEventHandler) OnReceiveHeaders(metadata.MD) {}
+func (i *invocationEventHandler) OnReceiveHeaders(md metadata.MD) {
+	if i.printMetadata {
+		i.println(i.marshalMetadata("headers", md))
+	}
+}
 
 func (i *invocationEventHandler) OnReceiveResponse(message proto.Message) {
-	i.println(i.marshal(message))
+	if i.recordResponses {
+		i.jsonResponses = append(i.jsonResponses, i.marshal(message))
+	}
+	size := proto.Size(message)
+	if i.fieldMask != nil {
+		var err error
+		message, err = i.fieldMask.apply(message, i.responseDescriptor)
+		if err != nil {
+			i.logger.Error("field mask error", zap.Error(err))
+			return
+		}
+	}
+	switch i.outputFormat {
+	case OutputFormatBinary:
+		data, err := proto.Marshal(message)
+		if err != nil {
+			i.logger.Error("marshal error", zap.Error(err))
+			return
+		}
+		i.binaryResponses = append(i.binaryResponses, data)
+		i.written = true
+	case OutputFormatText:
+		i.println(i.marshalText(message))
+	default:
+		i.println(i.marshal(message))
+	}
+	if i.cancelAfterBytes > 0 && i.cancel != nil {
+		i.receivedBytes += size
+		if i.receivedBytes >= i.cancelAfterBytes {
+			i.logger.Debug("canceling call", zap.Int("received_bytes", i.receivedBytes))
+			i.cancel()
+		}
+	}
 }
 
-func (i *invocationEventHandler) OnReceiveTrailers(s *status.Status, _ metadata.MD) {
+func (i *invocationEventHandler) OnReceiveTrailers(s *status.Status, md metadata.MD) {
+	if i.printMetadata {
+		i.println(i.marshalMetadata("trailers", md))
+	}
+	i.code = s.Code()
 	if err := s.Err(); err != nil {
-		i.err = err
+		i.err = i.statusError(s)
 	}
 }
 
@@ -69,8 +139,59 @@ func (i *invocationEventHandler) Err() error {
 	return i.err
 }
 
+func (i *invocationEventHandler) Code() codes.Code {
+	return i.code
+}
+
+// Result returns the result of the call with the given code.
+func (i *invocationEventHandler) Result(code codes.Code) *invocationResult {
+	return &invocationResult{
+		written:       i.written,
+		code:          code,
+		jsonResponses: i.jsonResponses,
+	}
+}
+
+// Flush writes the binary responses. A single response is written as is,
+// while multiple responses are each prefixed with their length as a varint.
+func (i *invocationEventHandler) Flush() error {
+	for _, data := range i.binaryResponses {
+		if len(i.binaryResponses) > 1 {
+			if _, err := i.output.Write(proto.EncodeVarint(uint64(len(data)))); err != nil {
+				return err
+			}
+		}
+		if _, err := i.output.Write(data); err != nil {
+			return err
+		}
+	}
+	i.binaryResponses = nil
+	return nil
+}
+
+// statusError returns an error for the non-OK status that includes any
+// error details sent by the server, marshalled to JSON.
+func (i *invocationEventHandler) statusError(s *status.Status) error {
+	details := s.Details()
+	if len(details) == 0 {
+		return s.Err()
+	}
+	lines := []string{s.Err().Error(), "details:"}
+	for _, detail := range details {
+		switch t := detail.(type) {
+		case proto.Message:
+			lines = append(lines, i.marshal(t))
+		case error:
+			lines = append(lines, fmt.Sprintf("could not decode detail: %v", t))
+		default:
+			lines = append(lines, fmt.Sprintf("%v", t))
+		}
+	}
+	return errors.New(strings.Join(lines, "\n"))
+}
+
 func (i *invocationEventHandler) marshal(message proto.Message) string {
-	s, err := jsonpbMarshaler.MarshalToString(message)
+	s, err := i.jsonMarshaler.MarshalToString(message)
 	if err != nil {
 		i.logger.Error("marshal error", zap.Error(err))
 		return ""
@@ -78,10 +199,38 @@ func (i *invocationEventHandler) marshal(message proto.Message) string {
 	return s
 }
 
+func (i *invocationEventHandler) marshalText(message proto.Message) string {
+	// dynamic messages can indent their own text format
+	if textIndentMarshaler, ok := message.(interface {
+		MarshalTextIndent() ([]byte, error)
+	}); ok {
+		data, err := textIndentMarshaler.MarshalTextIndent()
+		if err != nil {
+			i.logger.Error("marshal error", zap.Error(err))
+			return ""
+		}
+		return strings.TrimSuffix(string(data), "\n")
+	}
+	return strings.TrimSuffix(proto.MarshalTextString(message), "\n")
+}
+
+func (i *invocationEventHandler) marshalMetadata(key string, md metadata.MD) string {
+	if md == nil {
+		md = metadata.MD{}
+	}
+	data, err := json.MarshalIndent(map[string]metadata.MD{key: md}, "", "  ")
+	if err != nil {
+		i.logger.Error("marshal error", zap.Error(err))
+		return ""
+	}
+	return string(data)
+}
+
 func (i *invocationEventHandler) println(s string) {
 	if s == "" {
 		return
 	}
+	i.written = true
 	if _, err := i.output.Write([]byte(s + "\n")); err != nil {
 		i.logger.Error("write error", zap.Error(err))
 	}
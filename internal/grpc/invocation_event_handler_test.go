@@ -0,0 +1,116 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestInvocationEventHandlerCancelAfterBytes(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	invocationEventHandler := newInvocationEventHandler(buffer, zap.NewNop(), &jsonpb.Marshaler{}, false, OutputFormatJSON, false)
+	canceled := 0
+	invocationEventHandler.cancelAfterBytes = 10
+	invocationEventHandler.cancel = func() { canceled++ }
+	// each response is 7 bytes, a tag byte, a length byte, and 5 bytes of data
+	invocationEventHandler.OnReceiveResponse(&wrappers.StringValue{Value: "hello"})
+	assert.Equal(t, 0, canceled)
+	invocationEventHandler.OnReceiveResponse(&wrappers.StringValue{Value: "hello"})
+	assert.Equal(t, 1, canceled)
+	assert.Equal(t, "\"hello\"\n\"hello\"\n", buffer.String())
+}
+
+func TestInvocationEventHandlerPrintMetadata(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	invocationEventHandler := newInvocationEventHandler(buffer, zap.NewNop(), &jsonpb.Marshaler{}, true, OutputFormatJSON, false)
+	invocationEventHandler.OnReceiveHeaders(metadata.Pairs("foo", "bar"))
+	invocationEventHandler.OnReceiveResponse(&wrappers.StringValue{Value: "hello"})
+	invocationEventHandler.OnReceiveTrailers(status.New(codes.OK, ""), nil)
+	assert.NoError(t, invocationEventHandler.Err())
+	assert.Equal(
+		t,
+		`{
+  "headers": {
+    "foo": [
+      "bar"
+    ]
+  }
+}
+"hello"
+{
+  "trailers": {}
+}
+`,
+		buffer.String(),
+	)
+
+	buffer.Reset()
+	invocationEventHandler = newInvocationEventHandler(buffer, zap.NewNop(), &jsonpb.Marshaler{}, false, OutputFormatJSON, false)
+	invocationEventHandler.OnReceiveHeaders(metadata.Pairs("foo", "bar"))
+	invocationEventHandler.OnReceiveResponse(&wrappers.StringValue{Value: "hello"})
+	invocationEventHandler.OnReceiveTrailers(status.New(codes.OK, ""), metadata.Pairs("baz", "bat"))
+	assert.Equal(t, "\"hello\"\n", buffer.String())
+}
+
+func TestInvocationEventHandlerStatusDetails(t *testing.T) {
+	s, err := status.New(codes.InvalidArgument, "bad request").WithDetails(
+		&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{
+					Field:       "value",
+					Description: "must be set",
+				},
+			},
+		},
+		&errdetails.RetryInfo{
+			RetryDelay: &duration.Duration{Seconds: 1},
+		},
+	)
+	require.NoError(t, err)
+	invocationEventHandler := newInvocationEventHandler(bytes.NewBuffer(nil), zap.NewNop(), &jsonpb.Marshaler{}, false, OutputFormatJSON, false)
+	invocationEventHandler.OnReceiveTrailers(s, nil)
+	assert.Equal(t, codes.InvalidArgument, invocationEventHandler.Code())
+	assert.EqualError(
+		t,
+		invocationEventHandler.Err(),
+		`rpc error: code = InvalidArgument desc = bad request
+details:
+{"fieldViolations":[{"field":"value","description":"must be set"}]}
+{"retryDelay":"1s"}`,
+	)
+
+	// without details the error is the status error
+	invocationEventHandler = newInvocationEventHandler(bytes.NewBuffer(nil), zap.NewNop(), &jsonpb.Marshaler{}, false, OutputFormatJSON, false)
+	invocationEventHandler.OnReceiveTrailers(status.New(codes.NotFound, "not found"), nil)
+	assert.EqualError(t, invocationEventHandler.Err(), "rpc error: code = NotFound desc = not found")
+}
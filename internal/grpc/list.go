@@ -0,0 +1,157 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	reflectdesc "github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/grpcreflect"
+	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// Method is a gRPC method with its request and response types.
+type Method struct {
+	// Name is the name of the method in the form package.Service/Method.
+	Name string
+	// RequestType is the fully-qualified name of the request type without a leading period.
+	RequestType string
+	// ResponseType is the fully-qualified name of the response type without a leading period.
+	ResponseType string
+	// ClientStreaming is true if the client sends a stream of requests.
+	ClientStreaming bool
+	// ServerStreaming is true if the server sends a stream of responses.
+	ServerStreaming bool
+}
+
+// String returns the method in the form
+// package.Service/Method(package.Request) returns (package.Response),
+// with stream before the request or response type if it is streamed.
+func (m *Method) String() string {
+	return fmt.Sprintf("%s(%s) returns (%s)", m.Name, streamTypeString(m.RequestType, m.ClientStreaming), streamTypeString(m.ResponseType, m.ServerStreaming))
+}
+
+func (h *handler) List(fileDescriptorSets []*descriptor.FileDescriptorSet, address string) ([]*Method, error) {
+	if methods := getMethodsWithTypes(fileDescriptorSets); len(methods) > 0 {
+		return methods, nil
+	}
+	if address == "" {
+		return nil, fmt.Errorf("no services in the given files, set address to use server reflection")
+	}
+	dialOptions, err := h.getDialOptions()
+	if err != nil {
+		return nil, err
+	}
+	clientConn, err := h.dial(address, dialOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = clientConn.Close() }()
+	ctx, cancel := context.WithTimeout(context.Background(), h.callTimeout)
+	defer cancel()
+	methods, err := getReflectionMethods(grpcurl.DescriptorSourceFromServer(ctx, grpcreflect.NewClient(ctx, reflectpb.NewServerReflectionClient(clientConn))))
+	if err != nil {
+		return nil, fmt.Errorf("no services in the given files and could not use server reflection: %v", err)
+	}
+	return methods, nil
+}
+
+// getMethodsWithTypes returns the gRPC methods in the given FileDescriptorSets
+// sorted by name.
+func getMethodsWithTypes(fileDescriptorSets []*descriptor.FileDescriptorSet) []*Method {
+	nameToMethod := make(map[string]*Method)
+	for _, fileDescriptorSet := range fileDescriptorSets {
+		for _, fileDescriptorProto := range fileDescriptorSet.File {
+			prefix := ""
+			if pkg := fileDescriptorProto.GetPackage(); pkg != "" {
+				prefix = pkg + "."
+			}
+			for _, serviceDescriptorProto := range fileDescriptorProto.GetService() {
+				for _, methodDescriptorProto := range serviceDescriptorProto.GetMethod() {
+					name := prefix + serviceDescriptorProto.GetName() + "/" + methodDescriptorProto.GetName()
+					nameToMethod[name] = &Method{
+						Name:            name,
+						RequestType:     strings.TrimPrefix(methodDescriptorProto.GetInputType(), "."),
+						ResponseType:    strings.TrimPrefix(methodDescriptorProto.GetOutputType(), "."),
+						ClientStreaming: methodDescriptorProto.GetClientStreaming(),
+						ServerStreaming: methodDescriptorProto.GetServerStreaming(),
+					}
+				}
+			}
+		}
+	}
+	return sortMethods(nameToMethod)
+}
+
+// getReflectionMethods returns the gRPC methods of the services listed by
+// the descriptor source sorted by name, excluding the reflection service.
+func getReflectionMethods(descriptorSource grpcurl.DescriptorSource) ([]*Method, error) {
+	services, err := grpcurl.ListServices(descriptorSource)
+	if err != nil {
+		return nil, err
+	}
+	nameToMethod := make(map[string]*Method)
+	for _, service := range services {
+		if service == reflectionService {
+			continue
+		}
+		d, err := descriptorSource.FindSymbol(service)
+		if err != nil {
+			return nil, err
+		}
+		serviceDescriptor, ok := d.(*reflectdesc.ServiceDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a service", service)
+		}
+		for _, method := range serviceDescriptor.GetMethods() {
+			name := service + "/" + method.GetName()
+			nameToMethod[name] = &Method{
+				Name:            name,
+				RequestType:     method.GetInputType().GetFullyQualifiedName(),
+				ResponseType:    method.GetOutputType().GetFullyQualifiedName(),
+				ClientStreaming: method.IsClientStreaming(),
+				ServerStreaming: method.IsServerStreaming(),
+			}
+		}
+	}
+	return sortMethods(nameToMethod), nil
+}
+
+func sortMethods(nameToMethod map[string]*Method) []*Method {
+	methods := make([]*Method, 0, len(nameToMethod))
+	for _, method := range nameToMethod {
+		methods = append(methods, method)
+	}
+	sort.Slice(methods, func(i int, j int) bool { return methods[i].Name < methods[j].Name })
+	return methods
+}
+
+func streamTypeString(typeName string, streaming bool) string {
+	if streaming {
+		return "stream " + typeName
+	}
+	return typeName
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	reflectdesc "github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// MessagesResult is the result of a call with InvokeMessages.
+type MessagesResult struct {
+	// The response messages, of the output type of the method.
+	Responses []*dynamic.Message
+	// The response headers and trailers.
+	Headers  metadata.MD
+	Trailers metadata.MD
+}
+
+// GetMethodDescriptor returns the descriptor of the method in the
+// FileDescriptorSets, in the form package.Service/Method.
+func (h *handler) GetMethodDescriptor(fileDescriptorSets []*descriptor.FileDescriptorSet, method string) (*reflectdesc.MethodDescriptor, error) {
+	descriptorSource, err := h.getDescriptorSourceForMethod(fileDescriptorSets, method)
+	if err != nil {
+		return nil, err
+	}
+	return getMethodDescriptor(descriptorSource, method)
+}
+
+func (h *handler) InvokeMessages(ctx context.Context, fileDescriptorSets []*descriptor.FileDescriptorSet, address string, method string, requests []proto.Message) (*MessagesResult, error) {
+	methodDescriptor, err := h.GetMethodDescriptor(fileDescriptorSets, method)
+	if err != nil {
+		return nil, err
+	}
+	if !methodDescriptor.IsClientStreaming() {
+		switch len(requests) {
+		case 0:
+			if requiredFields := getRequiredFields(methodDescriptor.GetInputType()); len(requiredFields) > 0 {
+				return nil, fmt.Errorf("%s has required fields %s, must set a request", methodDescriptor.GetInputType().GetFullyQualifiedName(), getFieldNames(requiredFields))
+			}
+			requests = []proto.Message{dynamic.NewMessage(methodDescriptor.GetInputType())}
+		case 1:
+		default:
+			return nil, fmt.Errorf("%s is not client streaming but %d requests were given", method, len(requests))
+		}
+	}
+	dialOptions, err := h.getDialOptions()
+	if err != nil {
+		return nil, err
+	}
+	clientConn, err := h.dial(address, dialOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = clientConn.Close() }()
+	timeout := h.callTimeout
+	if h.deadline != 0 {
+		timeout = h.deadline
+	}
+	// the call has the earlier of the deadline of the context and the timeout
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if len(h.headers) > 0 {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		ctx = metadata.NewOutgoingContext(ctx, metadata.Join(md, grpcurl.MetadataFromHeaders(h.headers)))
+	}
+	h.logger.Debug("invoking with messages", zap.String("method", method), zap.Int("requests", len(requests)))
+	result := &MessagesResult{}
+	var responses []proto.Message
+	stub := grpcdynamic.NewStub(clientConn)
+	switch {
+	case methodDescriptor.IsClientStreaming() && methodDescriptor.IsServerStreaming():
+		stream, err := stub.InvokeRpcBidiStream(ctx, methodDescriptor)
+		if err != nil {
+			return nil, err
+		}
+		for _, request := range requests {
+			if err := stream.SendMsg(request); err != nil {
+				return nil, err
+			}
+		}
+		if err := stream.CloseSend(); err != nil {
+			return nil, err
+		}
+		if responses, err = receiveAll(stream.RecvMsg); err != nil {
+			return nil, err
+		}
+		if result.Headers, err = stream.Header(); err != nil {
+			return nil, err
+		}
+		result.Trailers = stream.Trailer()
+	case methodDescriptor.IsClientStreaming():
+		stream, err := stub.InvokeRpcClientStream(ctx, methodDescriptor)
+		if err != nil {
+			return nil, err
+		}
+		for _, request := range requests {
+			if err := stream.SendMsg(request); err != nil {
+				return nil, err
+			}
+		}
+		response, err := stream.CloseAndReceive()
+		if err != nil {
+			return nil, err
+		}
+		responses = []proto.Message{response}
+		if result.Headers, err = stream.Header(); err != nil {
+			return nil, err
+		}
+		result.Trailers = stream.Trailer()
+	case methodDescriptor.IsServerStreaming():
+		stream, err := stub.InvokeRpcServerStream(ctx, methodDescriptor, requests[0])
+		if err != nil {
+			return nil, err
+		}
+		if responses, err = receiveAll(stream.RecvMsg); err != nil {
+			return nil, err
+		}
+		if result.Headers, err = stream.Header(); err != nil {
+			return nil, err
+		}
+		result.Trailers = stream.Trailer()
+	default:
+		response, err := stub.InvokeRpc(ctx, methodDescriptor, requests[0], grpc.Header(&result.Headers), grpc.Trailer(&result.Trailers))
+		if err != nil {
+			return nil, err
+		}
+		responses = []proto.Message{response}
+	}
+	for _, response := range responses {
+		dynamicResponse, err := toDynamicMessage(methodDescriptor.GetOutputType(), response)
+		if err != nil {
+			return nil, err
+		}
+		result.Responses = append(result.Responses, dynamicResponse)
+	}
+	return result, nil
+}
+
+// receiveAll receives messages until the end of the stream.
+func receiveAll(recvMsg func() (proto.Message, error)) ([]proto.Message, error) {
+	var messages []proto.Message
+	for {
+		message, err := recvMsg()
+		if err == io.EOF {
+			return messages, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+}
+
+func toDynamicMessage(messageDescriptor *reflectdesc.MessageDescriptor, message proto.Message) (*dynamic.Message, error) {
+	if dynamicMessage, ok := message.(*dynamic.Message); ok {
+		return dynamicMessage, nil
+	}
+	dynamicMessage := dynamic.NewMessage(messageDescriptor)
+	if err := dynamicMessage.ConvertFrom(message); err != nil {
+		return nil, err
+	}
+	return dynamicMessage, nil
+}
@@ -0,0 +1,330 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	reflectdesc "github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/uber/prototool/internal/desc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// proxyEvent is a line of JSON written by Proxy for each message and the
+// end of each call.
+type proxyEvent struct {
+	// Call is the number of the call, starting at 1.
+	Call int `json:"call"`
+	// Method is the method in the form package.Service/Method.
+	Method string `json:"method"`
+	// Type is either request, response, or end.
+	Type string `json:"type"`
+	// Message is the request or response as JSON, if the method is in
+	// the FileDescriptorSets.
+	Message json.RawMessage `json:"message,omitempty"`
+	// Size is the size in bytes of the request or response on the wire.
+	Size int `json:"size,omitempty"`
+	// Code is the status code the call ended with.
+	Code string `json:"code,omitempty"`
+	// Error is the status message the call ended with, if the code is not OK.
+	Error string `json:"error,omitempty"`
+	// Duration is the duration of the call.
+	Duration string `json:"duration,omitempty"`
+}
+
+// rawCodec passes messages through without decoding them, so that calls
+// are forwarded byte for byte, including calls to methods that are not in
+// the FileDescriptorSets.
+type rawCodec struct{}
+
+type rawMessage struct {
+	data []byte
+}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	message, ok := v.(*rawMessage)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return message.data, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	message, ok := v.(*rawMessage)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	message.data = append([]byte(nil), data...)
+	return nil
+}
+
+func (rawCodec) String() string {
+	return "proto"
+}
+
+type proxy struct {
+	handler           *handler
+	clientConn        *grpc.ClientConn
+	methodDescriptors map[string]*reflectdesc.MethodDescriptor
+	jsonMarshaler     *jsonpb.Marshaler
+	output            io.Writer
+
+	// protects output, numCalls, and calls to recordFunc
+	lock     sync.Mutex
+	numCalls int
+}
+
+func (h *handler) Proxy(fileDescriptorSets []*descriptor.FileDescriptorSet, address string, listener net.Listener, output io.Writer) error {
+	fileDescriptors, err := desc.CreateFileDescriptors(fileDescriptorSets...)
+	if err != nil {
+		return err
+	}
+	methodDescriptors := make(map[string]*reflectdesc.MethodDescriptor)
+	for _, fileDescriptor := range fileDescriptors {
+		for _, serviceDescriptor := range fileDescriptor.GetServices() {
+			for _, methodDescriptor := range serviceDescriptor.GetMethods() {
+				methodDescriptors[serviceDescriptor.GetFullyQualifiedName()+"/"+methodDescriptor.GetName()] = methodDescriptor
+			}
+		}
+	}
+	anyResolver, err := desc.NewAnyResolver(fileDescriptorSets...)
+	if err != nil {
+		return err
+	}
+	// each event is written on a single line
+	jsonMarshaler := *h.jsonMarshaler
+	jsonMarshaler.Indent = ""
+	jsonMarshaler.AnyResolver = anyResolver
+	dialOptions, err := h.getDialOptions()
+	if err != nil {
+		return err
+	}
+	clientConn, err := h.dial(address, dialOptions)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = clientConn.Close() }()
+	proxy := &proxy{
+		handler:           h,
+		clientConn:        clientConn,
+		methodDescriptors: methodDescriptors,
+		jsonMarshaler:     &jsonMarshaler,
+		output:            output,
+	}
+	serverOptions := []grpc.ServerOption{
+		grpc.CustomCodec(rawCodec{}),
+		grpc.UnknownServiceHandler(proxy.handle),
+	}
+	// requests are received by the server and sent by the client,
+	// and responses the other way around
+	if h.maxSendMsgSize != 0 {
+		serverOptions = append(serverOptions, grpc.MaxRecvMsgSize(h.maxSendMsgSize))
+	}
+	if h.maxRecvMsgSize != 0 {
+		serverOptions = append(serverOptions, grpc.MaxSendMsgSize(h.maxRecvMsgSize))
+	}
+	return grpc.NewServer(serverOptions...).Serve(listener)
+}
+
+// handle forwards the call on the server stream to the client connection.
+func (p *proxy) handle(_ interface{}, serverStream grpc.ServerStream) error {
+	fullMethod, ok := grpc.MethodFromServerStream(serverStream)
+	if !ok {
+		return status.Error(codes.Internal, "no method for stream")
+	}
+	method := strings.TrimPrefix(fullMethod, "/")
+	call := p.newCall(method)
+	start := time.Now()
+
+	ctx, cancel := context.WithCancel(serverStream.Context())
+	defer cancel()
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	// the authority is set by the client connection
+	delete(md, ":authority")
+	for _, header := range p.handler.headers {
+		split := strings.SplitN(header, ":", 2)
+		md.Set(split[0], split[1])
+	}
+	clientStream, err := p.clientConn.NewStream(
+		metadata.NewOutgoingContext(ctx, md),
+		&grpc.StreamDesc{
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		fullMethod,
+		grpc.CallCustomCodec(rawCodec{}),
+	)
+	if err != nil {
+		p.end(call, start, err)
+		return err
+	}
+
+	go func() {
+		for {
+			message := &rawMessage{}
+			if err := serverStream.RecvMsg(message); err != nil {
+				if err == io.EOF {
+					_ = clientStream.CloseSend()
+				} else {
+					// the client went away
+					cancel()
+				}
+				return
+			}
+			p.message(call, "request", message)
+			// errors are returned by RecvMsg on the client stream
+			if err := clientStream.SendMsg(message); err != nil {
+				return
+			}
+		}
+	}()
+
+	if header, err := clientStream.Header(); err == nil {
+		if err := serverStream.SendHeader(header); err != nil {
+			p.end(call, start, err)
+			return err
+		}
+	}
+	for {
+		message := &rawMessage{}
+		if err := clientStream.RecvMsg(message); err != nil {
+			serverStream.SetTrailer(clientStream.Trailer())
+			if err == io.EOF {
+				err = nil
+			}
+			p.end(call, start, err)
+			return err
+		}
+		p.message(call, "response", message)
+		if err := serverStream.SendMsg(message); err != nil {
+			p.end(call, start, err)
+			return err
+		}
+	}
+}
+
+// proxyCall is the state of a proxied call.
+type proxyCall struct {
+	number           int
+	method           string
+	methodDescriptor *reflectdesc.MethodDescriptor
+	recording        *Recording
+}
+
+func (p *proxy) newCall(method string) *proxyCall {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.numCalls++
+	call := &proxyCall{
+		number:           p.numCalls,
+		method:           method,
+		methodDescriptor: p.methodDescriptors[method],
+	}
+	// calls to unknown methods cannot be replayed
+	if p.handler.recordFunc != nil && call.methodDescriptor != nil {
+		call.recording = &Recording{
+			Method: method,
+		}
+	}
+	return call
+}
+
+// message writes the event for the request or response message.
+func (p *proxy) message(call *proxyCall, eventType string, message *rawMessage) {
+	event := &proxyEvent{
+		Call:   call.number,
+		Method: call.method,
+		Type:   eventType,
+		Size:   len(message.data),
+	}
+	if call.methodDescriptor != nil {
+		messageDescriptor := call.methodDescriptor.GetInputType()
+		if eventType == "response" {
+			messageDescriptor = call.methodDescriptor.GetOutputType()
+		}
+		dynamicMessage := dynamic.NewMessage(messageDescriptor)
+		if err := dynamicMessage.Unmarshal(message.data); err != nil {
+			p.handler.logger.Warn("could not decode message", zap.String("method", call.method), zap.String("type", eventType), zap.Error(err))
+		} else if s, err := p.jsonMarshaler.MarshalToString(dynamicMessage); err != nil {
+			p.handler.logger.Warn("could not marshal message", zap.String("method", call.method), zap.String("type", eventType), zap.Error(err))
+		} else {
+			event.Message = json.RawMessage(s)
+		}
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if call.recording != nil && event.Message != nil {
+		if eventType == "request" {
+			call.recording.Requests = append(call.recording.Requests, event.Message)
+		} else {
+			call.recording.Responses = append(call.recording.Responses, event.Message)
+		}
+	}
+	p.write(event)
+}
+
+// end writes the event for the end of the call, and records the call.
+func (p *proxy) end(call *proxyCall, start time.Time, err error) {
+	code := status.Code(err)
+	event := &proxyEvent{
+		Call:     call.number,
+		Method:   call.method,
+		Type:     "end",
+		Code:     code.String(),
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		event.Error = status.Convert(err).Message()
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.write(event)
+	if call.recording != nil {
+		call.recording.Code = code
+		p.handler.recordFunc(call.recording)
+	}
+}
+
+func (p *proxy) write(event *proxyEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		p.handler.logger.Warn("could not marshal event", zap.Error(err))
+		return
+	}
+	if _, err := p.output.Write(append(data, '\n')); err != nil {
+		p.handler.logger.Warn("could not write event", zap.Error(err))
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/mock"
+	"google.golang.org/grpc/codes"
+)
+
+func TestProxy(t *testing.T) {
+	fileDescriptorSets := newEchoFileDescriptorSets()
+
+	upstreamListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = upstreamListener.Close() }()
+	upstream := mock.NewServer(mock.ServerWithFixtureData([]byte("proxytest.EchoAPI/Echo:\n  value: pong\n")))
+	go func() { _ = upstream.Serve(fileDescriptorSets, upstreamListener) }()
+
+	proxyListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = proxyListener.Close() }()
+	var lock sync.Mutex
+	proxyOutput := bytes.NewBuffer(nil)
+	var recordings []*Recording
+	go func() {
+		_ = newHandler(
+			HandlerWithRecordFunc(func(recording *Recording) { recordings = append(recordings, recording) }),
+		).Proxy(fileDescriptorSets, upstreamListener.Addr().String(), proxyListener, &lockedWriter{lock: &lock, writer: proxyOutput})
+	}()
+
+	output := bytes.NewBuffer(nil)
+	require.NoError(t, newHandler().Invoke(fileDescriptorSets, proxyListener.Addr().String(), "proxytest.EchoAPI/Echo", strings.NewReader(`{"value":"ping"}`), output))
+	assert.Equal(t, "{\n  \"value\": \"pong\"\n}\n", output.String())
+
+	lock.Lock()
+	defer lock.Unlock()
+	lines := strings.Split(strings.TrimSpace(proxyOutput.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, `{"call":1,"method":"proxytest.EchoAPI/Echo","type":"request","message":{"value":"ping"},"size":6}`, lines[0])
+	assert.Equal(t, `{"call":1,"method":"proxytest.EchoAPI/Echo","type":"response","message":{"value":"pong"},"size":6}`, lines[1])
+	assert.Contains(t, lines[2], `{"call":1,"method":"proxytest.EchoAPI/Echo","type":"end","code":"OK","duration":`)
+	require.Len(t, recordings, 1)
+	assert.Equal(t, "proxytest.EchoAPI/Echo", recordings[0].Method)
+	assert.Equal(t, codes.OK, recordings[0].Code)
+	require.Len(t, recordings[0].Requests, 1)
+	assert.Equal(t, `{"value":"ping"}`, string(recordings[0].Requests[0]))
+}
+
+// lockedWriter lets the test read what the proxy writes once the call
+// has ended.
+type lockedWriter struct {
+	lock   *sync.Mutex
+	writer *bytes.Buffer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.writer.Write(p)
+}
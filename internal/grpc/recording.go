@@ -0,0 +1,83 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Recording is the recording of a call.
+type Recording struct {
+	// Method is the method in the form package.Service/Method.
+	Method string
+	// Requests are the requests as compact JSON.
+	Requests []json.RawMessage
+	// Responses are the responses as compact JSON.
+	Responses []json.RawMessage
+	// Code is the status code the call ended with.
+	Code codes.Code
+}
+
+// newRecording returns a new Recording for the result of the call,
+// with the requests read from the JSON input.
+func newRecording(method string, input []byte, result *invocationResult) *Recording {
+	recording := &Recording{
+		Method: method,
+		Code:   result.code,
+	}
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	for {
+		var request json.RawMessage
+		if err := decoder.Decode(&request); err != nil {
+			// invalid requests fail the call before anything is sent
+			break
+		}
+		recording.Requests = append(recording.Requests, compactJSON(request))
+	}
+	for _, jsonResponse := range result.jsonResponses {
+		recording.Responses = append(recording.Responses, compactJSON([]byte(jsonResponse)))
+	}
+	return recording
+}
+
+func compactJSON(data []byte) json.RawMessage {
+	buffer := bytes.NewBuffer(nil)
+	if err := json.Compact(buffer, data); err != nil {
+		return json.RawMessage(data)
+	}
+	return json.RawMessage(buffer.Bytes())
+}
+
+// recordingReader records the data read from the reader.
+type recordingReader struct {
+	reader io.Reader
+	buffer bytes.Buffer
+}
+
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	_, _ = r.buffer.Write(p[:n])
+	return n, err
+}
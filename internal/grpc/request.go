@@ -0,0 +1,131 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fullstorydev/grpcurl"
+	reflectdesc "github.com/jhump/protoreflect/desc"
+)
+
+// getMethodDescriptor returns the descriptor of the method, which is
+// of the form package.Service/Method.
+func getMethodDescriptor(descriptorSource grpcurl.DescriptorSource, method string) (*reflectdesc.MethodDescriptor, error) {
+	split := strings.Split(method, "/")
+	if len(split) != 2 {
+		return nil, fmt.Errorf("invalid gRPC method: %s", method)
+	}
+	d, err := descriptorSource.FindSymbol(split[0])
+	if err != nil {
+		return nil, err
+	}
+	serviceDescriptor, ok := d.(*reflectdesc.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", split[0])
+	}
+	methodDescriptor := serviceDescriptor.FindMethodByName(split[1])
+	if methodDescriptor == nil {
+		return nil, fmt.Errorf("service %s does not have method %s", split[0], split[1])
+	}
+	return methodDescriptor, nil
+}
+
+// getDefaultInputReader returns the input to use if no data was given,
+// which is an empty request for methods that take a single request and
+// no requests for client streaming methods.
+//
+// Requests can only be omitted if the input type has no required fields,
+// such as google.protobuf.Empty or any proto3 message.
+func getDefaultInputReader(methodDescriptor *reflectdesc.MethodDescriptor) (io.Reader, error) {
+	if requiredFields := getRequiredFields(methodDescriptor.GetInputType()); len(requiredFields) > 0 {
+		return nil, fmt.Errorf("%s has required fields %s, must set data or stdin", methodDescriptor.GetInputType().GetFullyQualifiedName(), getFieldNames(requiredFields))
+	}
+	if methodDescriptor.IsClientStreaming() {
+		return strings.NewReader(""), nil
+	}
+	return strings.NewReader("{}"), nil
+}
+
+// getJSONArrayRequests returns the requests in the input if the input is a
+// JSON array of requests, and nil otherwise.
+func getJSONArrayRequests(input []byte) ([]json.RawMessage, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(input), []byte("[")) {
+		return nil, nil
+	}
+	requests := make([]json.RawMessage, 0)
+	if err := json.Unmarshal(input, &requests); err != nil {
+		return nil, fmt.Errorf("invalid JSON array of requests: %v", err)
+	}
+	return requests, nil
+}
+
+// getRequiredFields returns the proto2 required fields of the message.
+func getRequiredFields(messageDescriptor *reflectdesc.MessageDescriptor) []*reflectdesc.FieldDescriptor {
+	var requiredFields []*reflectdesc.FieldDescriptor
+	for _, field := range messageDescriptor.GetFields() {
+		if field.IsRequired() {
+			requiredFields = append(requiredFields, field)
+		}
+	}
+	return requiredFields
+}
+
+// checkRequiredFields returns an error naming the required fields missing
+// from the JSON request, if any.
+//
+// Requests that are not JSON objects are left to fail when unmarshalled.
+func checkRequiredFields(data []byte, requiredFields []*reflectdesc.FieldDescriptor) error {
+	if len(requiredFields) == 0 {
+		return nil
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil
+	}
+	var missingFields []*reflectdesc.FieldDescriptor
+	for _, field := range requiredFields {
+		if !hasJSONField(object, field.GetJSONName()) && !hasJSONField(object, field.GetName()) {
+			missingFields = append(missingFields, field)
+		}
+	}
+	if len(missingFields) > 0 {
+		return fmt.Errorf("request is missing required fields %s", getFieldNames(missingFields))
+	}
+	return nil
+}
+
+func hasJSONField(object map[string]json.RawMessage, name string) bool {
+	value, ok := object[name]
+	return ok && string(value) != "null"
+}
+
+func getFieldNames(fields []*reflectdesc.FieldDescriptor) string {
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		names = append(names, field.GetName())
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	reflectdesc "github.com/jhump/protoreflect/desc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDefaultInputReader(t *testing.T) {
+	fileDescriptor := newTestFileDescriptor(t)
+	service := fileDescriptor.FindService("foo.FooService")
+	require.NotNil(t, service)
+
+	inputReader, err := getDefaultInputReader(service.FindMethodByName("Optional"))
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(inputReader)
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(data))
+
+	inputReader, err = getDefaultInputReader(service.FindMethodByName("OptionalStream"))
+	require.NoError(t, err)
+	data, err = ioutil.ReadAll(inputReader)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	_, err = getDefaultInputReader(service.FindMethodByName("Required"))
+	assert.EqualError(t, err, "foo.Required has required fields id, name, must set data or stdin")
+}
+
+func TestCheckRequiredFields(t *testing.T) {
+	requiredFields := getRequiredFields(newTestFileDescriptor(t).FindMessage("foo.Required"))
+	require.Len(t, requiredFields, 2)
+	assert.NoError(t, checkRequiredFields([]byte(`{"id":1,"name":"a"}`), requiredFields))
+	assert.NoError(t, checkRequiredFields([]byte(`{"id":1,"name":"a","hello_world":"b"}`), requiredFields))
+	assert.NoError(t, checkRequiredFields([]byte(`[]`), requiredFields))
+	assert.EqualError(t, checkRequiredFields([]byte(`{}`), requiredFields), "request is missing required fields id, name")
+	assert.EqualError(t, checkRequiredFields([]byte(`{"id":1,"name":null}`), requiredFields), "request is missing required fields name")
+	assert.NoError(t, checkRequiredFields([]byte(`{}`), nil))
+}
+
+func TestGetJSONArrayRequests(t *testing.T) {
+	requests, err := getJSONArrayRequests([]byte(` [{"id":1}, {"id":2}]`))
+	require.NoError(t, err)
+	assert.Equal(t, []json.RawMessage{json.RawMessage(`{"id":1}`), json.RawMessage(`{"id":2}`)}, requests)
+	requests, err = getJSONArrayRequests([]byte(`[]`))
+	require.NoError(t, err)
+	assert.NotNil(t, requests)
+	assert.Empty(t, requests)
+	requests, err = getJSONArrayRequests([]byte(`{"id":1} {"id":2}`))
+	require.NoError(t, err)
+	assert.Nil(t, requests)
+	_, err = getJSONArrayRequests([]byte(`[{"id":1}`))
+	assert.Error(t, err)
+}
+
+func newTestFileDescriptor(t *testing.T) *reflectdesc.FileDescriptor {
+	optional := descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+	required := descriptor.FieldDescriptorProto_LABEL_REQUIRED
+	int64Type := descriptor.FieldDescriptorProto_TYPE_INT64
+	stringType := descriptor.FieldDescriptorProto_TYPE_STRING
+	fileDescriptor, err := reflectdesc.CreateFileDescriptor(&descriptor.FileDescriptorProto{
+		Name:    proto.String("foo.proto"),
+		Package: proto.String("foo"),
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Optional"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{Name: proto.String("id"), Number: proto.Int32(1), Label: &optional, Type: &int64Type},
+				},
+			},
+			{
+				Name: proto.String("Required"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{Name: proto.String("id"), Number: proto.Int32(1), Label: &required, Type: &int64Type},
+					{Name: proto.String("name"), Number: proto.Int32(2), Label: &required, Type: &stringType},
+					{Name: proto.String("hello_world"), Number: proto.Int32(3), Label: &optional, Type: &stringType},
+				},
+			},
+		},
+		Service: []*descriptor.ServiceDescriptorProto{
+			{
+				Name: proto.String("FooService"),
+				Method: []*descriptor.MethodDescriptorProto{
+					{Name: proto.String("Optional"), InputType: proto.String(".foo.Optional"), OutputType: proto.String(".foo.Optional")},
+					{Name: proto.String("OptionalStream"), InputType: proto.String(".foo.Optional"), OutputType: proto.String(".foo.Optional"), ClientStreaming: proto.Bool(true)},
+					{Name: proto.String("Required"), InputType: proto.String(".foo.Required"), OutputType: proto.String(".foo.Optional")},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	return fileDescriptor
+}
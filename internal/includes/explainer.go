@@ -0,0 +1,171 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package includes
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/editions"
+	"github.com/uber/prototool/internal/file"
+	"go.uber.org/zap"
+)
+
+type explainer struct {
+	logger *zap.Logger
+}
+
+func newExplainer(options ...ExplainerOption) *explainer {
+	explainer := &explainer{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(explainer)
+	}
+	return explainer
+}
+
+func (e *explainer) Explain(protoFiles []*file.ProtoFile, includePaths []string) (*Explanation, error) {
+	state := &explainState{
+		includePaths:     includePaths,
+		usedIncludePaths: make(map[string]struct{}),
+		seen:             make(map[string]struct{}),
+	}
+	var imports []*Import
+	for _, protoFile := range protoFiles {
+		// protoc maps the file to the first include path that contains it
+		for _, includePath := range includePaths {
+			if isInDir(protoFile.Path, includePath) {
+				state.usedIncludePaths[includePath] = struct{}{}
+				break
+			}
+		}
+		fileImports, err := state.explainFile(protoFile.Path, protoFile.DisplayPath)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, fileImports...)
+	}
+	sort.SliceStable(imports, func(i int, j int) bool {
+		if imports[i].Filename != imports[j].Filename {
+			return imports[i].Filename < imports[j].Filename
+		}
+		return imports[i].Line < imports[j].Line
+	})
+	usedIncludePaths := make([]string, 0, len(state.usedIncludePaths))
+	for includePath := range state.usedIncludePaths {
+		usedIncludePaths = append(usedIncludePaths, includePath)
+	}
+	sort.Strings(usedIncludePaths)
+	e.logger.Debug("explained includes", zap.Int("imports", len(imports)), zap.Strings("usedIncludePaths", usedIncludePaths))
+	return &Explanation{
+		Imports:          imports,
+		UsedIncludePaths: usedIncludePaths,
+	}, nil
+}
+
+type explainState struct {
+	includePaths     []string
+	usedIncludePaths map[string]struct{}
+	// the paths of the files that were explained
+	seen map[string]struct{}
+}
+
+// explainFile returns the imports of the file, and explains the imported
+// files that were found so that their include paths are used.
+func (s *explainState) explainFile(path string, displayPath string) ([]*Import, error) {
+	if _, ok := s.seen[path]; ok {
+		return nil, nil
+	}
+	s.seen[path] = struct{}{}
+	descriptor, err := parse(path, displayPath)
+	if err != nil {
+		return nil, err
+	}
+	if descriptor == nil {
+		return nil, nil
+	}
+	var imports []*Import
+	for _, element := range descriptor.Elements {
+		protoImport, ok := element.(*proto.Import)
+		if !ok {
+			continue
+		}
+		importInfo := &Import{
+			Filename:       displayPath,
+			Line:           protoImport.Position.Line,
+			Column:         protoImport.Position.Column,
+			ImportFilename: protoImport.Filename,
+		}
+		for _, includePath := range s.includePaths {
+			if !isFile(filepath.Join(includePath, filepath.FromSlash(protoImport.Filename))) {
+				continue
+			}
+			if importInfo.IncludePath == "" {
+				importInfo.IncludePath = includePath
+			} else {
+				importInfo.ShadowedIncludePaths = append(importInfo.ShadowedIncludePaths, includePath)
+			}
+		}
+		imports = append(imports, importInfo)
+		if importInfo.IncludePath == "" {
+			continue
+		}
+		s.usedIncludePaths[importInfo.IncludePath] = struct{}{}
+		importPath := filepath.Join(importInfo.IncludePath, filepath.FromSlash(protoImport.Filename))
+		if _, err := s.explainFile(importPath, importPath); err != nil {
+			return nil, err
+		}
+	}
+	return imports, nil
+}
+
+// parse parses the file, returning nil if it has a syntax error.
+func parse(path string, displayPath string) (*proto.Proto, error) {
+	osFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	parser, err := editions.NewParser(osFile)
+	_ = osFile.Close()
+	if err != nil {
+		return nil, err
+	}
+	parser.Filename(displayPath)
+	descriptor, err := parser.Parse()
+	if err != nil {
+		return nil, nil
+	}
+	return descriptor, nil
+}
+
+func isFile(path string) bool {
+	fileInfo, err := os.Stat(path)
+	return err == nil && !fileInfo.IsDir()
+}
+
+func isInDir(path string, dirPath string) bool {
+	relPath, err := filepath.Rel(dirPath, path)
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
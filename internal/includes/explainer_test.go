@@ -0,0 +1,102 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package includes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/file"
+)
+
+func TestExplain(t *testing.T) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	protoPath := filepath.Join(tmpDirPath, "proto")
+	vendorPath := filepath.Join(tmpDirPath, "vendor")
+	transitivePath := filepath.Join(tmpDirPath, "transitive")
+	unusedPath := filepath.Join(tmpDirPath, "unused")
+	writeFile(t, filepath.Join(protoPath, "a", "a.proto"), `syntax = "proto3";
+
+import "b/b.proto";
+import "c/c.proto";
+import "missing.proto";
+`)
+	writeFile(t, filepath.Join(protoPath, "b", "b.proto"), `syntax = "proto3";`)
+	writeFile(t, filepath.Join(vendorPath, "b", "b.proto"), `syntax = "proto3";`)
+	writeFile(t, filepath.Join(vendorPath, "c", "c.proto"), `syntax = "proto3";
+
+import "d/d.proto";
+`)
+	writeFile(t, filepath.Join(transitivePath, "d", "d.proto"), `syntax = "proto3";`)
+	require.NoError(t, os.MkdirAll(unusedPath, 0755))
+
+	explanation, err := NewExplainer().Explain(
+		[]*file.ProtoFile{
+			{
+				Path:        filepath.Join(protoPath, "a", "a.proto"),
+				DisplayPath: "a/a.proto",
+			},
+		},
+		[]string{protoPath, vendorPath, transitivePath, unusedPath},
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		&Explanation{
+			Imports: []*Import{
+				{
+					Filename:             "a/a.proto",
+					Line:                 3,
+					Column:               1,
+					ImportFilename:       "b/b.proto",
+					IncludePath:          protoPath,
+					ShadowedIncludePaths: []string{vendorPath},
+				},
+				{
+					Filename:       "a/a.proto",
+					Line:           4,
+					Column:         1,
+					ImportFilename: "c/c.proto",
+					IncludePath:    vendorPath,
+				},
+				{
+					Filename:       "a/a.proto",
+					Line:           5,
+					Column:         1,
+					ImportFilename: "missing.proto",
+				},
+			},
+			UsedIncludePaths: []string{protoPath, transitivePath, vendorPath},
+		},
+		explanation,
+	)
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package includes explains how the imports of Protobuf files are
+// resolved against the include paths passed to protoc.
+package includes
+
+import (
+	"github.com/uber/prototool/internal/file"
+	"go.uber.org/zap"
+)
+
+// Import is an import of a Protobuf file.
+type Import struct {
+	// The display path of the importing file.
+	Filename string `json:"filename"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	// The path of the imported file, as in the import statement.
+	ImportFilename string `json:"import_filename"`
+	// The include path that the imported file was found in first,
+	// which is the one protoc uses.
+	// Empty if the imported file is not in any include path.
+	IncludePath string `json:"include_path,omitempty"`
+	// The include paths after IncludePath that also contain the imported
+	// file, and are shadowed by IncludePath.
+	ShadowedIncludePaths []string `json:"shadowed_include_paths,omitempty"`
+}
+
+// Explanation explains how the imports of Protobuf files are resolved.
+type Explanation struct {
+	// The imports of the files, sorted by filename and position.
+	Imports []*Import
+	// The include paths that contain one of the files, or one of the
+	// files that they transitively import, sorted.
+	UsedIncludePaths []string
+}
+
+// Explainer explains how the imports of Protobuf files are resolved.
+type Explainer interface {
+	// Explain resolves the imports of the files against the include
+	// paths in order, as protoc does.
+	//
+	// Imported files are also parsed so that include paths that only
+	// satisfy transitive imports are used. Files that cannot be parsed
+	// are skipped, as protoc reports these.
+	Explain(protoFiles []*file.ProtoFile, includePaths []string) (*Explanation, error)
+}
+
+// ExplainerOption is an option for a new Explainer.
+type ExplainerOption func(*explainer)
+
+// ExplainerWithLogger returns an ExplainerOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func ExplainerWithLogger(logger *zap.Logger) ExplainerOption {
+	return func(explainer *explainer) {
+		explainer.logger = logger
+	}
+}
+
+// NewExplainer returns a new Explainer.
+func NewExplainer(options ...ExplainerOption) Explainer {
+	return newExplainer(options...)
+}
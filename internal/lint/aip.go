@@ -0,0 +1,160 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/strs"
+)
+
+const (
+	aipResourceOptionName = "(google.api.resource)"
+	aipEmptyType          = "google.protobuf.Empty"
+	aipFieldMaskType      = "google.protobuf.FieldMask"
+	aipOperationType      = "google.longrunning.Operation"
+)
+
+// aipStandardMethodVerbs are the verbs of the standard methods in AIP-131 to AIP-135.
+var aipStandardMethodVerbs = []string{
+	"Get",
+	"List",
+	"Create",
+	"Update",
+	"Delete",
+}
+
+// aipDir has the top-level messages and the RPCs of the files in a directory.
+type aipDir struct {
+	// message name to message, both by name and by package-qualified name
+	messages map[string]*proto.Message
+	rpcs     []*proto.RPC
+}
+
+func newAIPDir(descriptors []*proto.Proto) *aipDir {
+	dir := &aipDir{
+		messages: make(map[string]*proto.Message),
+	}
+	for _, descriptor := range descriptors {
+		pkg := ""
+		for _, element := range descriptor.Elements {
+			if protoPackage, ok := element.(*proto.Package); ok {
+				pkg = protoPackage.Name
+			}
+		}
+		for _, element := range descriptor.Elements {
+			switch element := element.(type) {
+			case *proto.Message:
+				if element.IsExtend {
+					continue
+				}
+				dir.messages[element.Name] = element
+				if pkg != "" {
+					dir.messages[pkg+"."+element.Name] = element
+				}
+			case *proto.Service:
+				for _, serviceElement := range element.Elements {
+					if rpc, ok := serviceElement.(*proto.RPC); ok {
+						dir.rpcs = append(dir.rpcs, rpc)
+					}
+				}
+			}
+		}
+	}
+	return dir
+}
+
+// getMessage returns the message for the type name, or nil if
+// the message is not a top-level message in the directory.
+func (d *aipDir) getMessage(typeName string) *proto.Message {
+	return d.messages[strings.TrimPrefix(typeName, ".")]
+}
+
+// getAIPStandardMethod returns the verb and the resource of the RPC name
+// if the RPC is a standard method, otherwise it returns empty strings.
+//
+// For List methods, the resource is plural.
+func getAIPStandardMethod(rpcName string) (string, string) {
+	for _, verb := range aipStandardMethodVerbs {
+		resource := strings.TrimPrefix(rpcName, verb)
+		if resource != rpcName && strs.IsCapitalized(resource) {
+			return verb, resource
+		}
+	}
+	return "", ""
+}
+
+// getAIPField returns the field with the name in the message, or nil.
+func getAIPField(message *proto.Message, name string) *aipField {
+	for _, element := range message.Elements {
+		switch element := element.(type) {
+		case *proto.NormalField:
+			if element.Name == name {
+				return &aipField{typeName: element.Type, repeated: element.Repeated}
+			}
+		case *proto.MapField:
+			if element.Name == name {
+				return &aipField{typeName: "map"}
+			}
+		case *proto.Oneof:
+			for _, oneofElement := range element.Elements {
+				if field, ok := oneofElement.(*proto.OneOfField); ok && field.Name == name {
+					return &aipField{typeName: field.Type}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+type aipField struct {
+	typeName string
+	repeated bool
+}
+
+// is returns true if the field is not repeated and has the type, which can
+// be a simple name of a message in the same package.
+func (f *aipField) is(typeName string) bool {
+	return !f.repeated && aipTypeNamesEqual(f.typeName, typeName)
+}
+
+// aipTypeNamesEqual returns true if the type names are the same, or one is the
+// simple name of the other, as a type in the same package.
+func aipTypeNamesEqual(one string, two string) bool {
+	one = strings.TrimPrefix(one, ".")
+	two = strings.TrimPrefix(two, ".")
+	return one == two || strings.HasSuffix(one, "."+two) || strings.HasSuffix(two, "."+one)
+}
+
+// aipSimpleName returns the last part of the type name.
+func aipSimpleName(typeName string) string {
+	if i := strings.LastIndexByte(typeName, '.'); i >= 0 {
+		return typeName[i+1:]
+	}
+	return typeName
+}
+
+// aipLowerSnakeCase converts the message name to the lower_snake_case
+// field name used for it in requests.
+func aipLowerSnakeCase(messageName string) string {
+	return strings.ToLower(strs.ToUpperSnakeCase(messageName))
+}
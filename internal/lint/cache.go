@@ -0,0 +1,170 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/text"
+	"github.com/uber/prototool/internal/vars"
+)
+
+// cacheVersion is part of every cache key, and should be incremented
+// if the format of cached results changes.
+const cacheVersion = "1"
+
+// cache stores the lint failures of directories keyed on the content
+// of the files, the config, the linters, and the prototool binary.
+//
+// Linters check a directory of files at a time, so results are cached
+// per directory, and a directory is only checked again if a file in it
+// changed.
+type cache struct {
+	dirPath string
+	baseKey []byte
+}
+
+// newCache returns a new cache in dirPath for the given ProtoSet and linters.
+func newCache(dirPath string, protoSet *file.ProtoSet, linters []Linter) (*cache, error) {
+	hash := sha256.New()
+	if err := writeCacheKeyPart(hash, cacheVersion, vars.Version, vars.GitCommit); err != nil {
+		return nil, err
+	}
+	// the binary changes whenever prototool is rebuilt, including in
+	// development when the version does not change
+	executablePath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	executableFileInfo, err := os.Stat(executablePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeCacheKeyPart(hash, executablePath, executableFileInfo.Size(), executableFileInfo.ModTime().UnixNano()); err != nil {
+		return nil, err
+	}
+	if err := writeCacheKeyPart(hash, protoSet.WorkDirPath, protoSet.Config); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(linters))
+	for _, linter := range linters {
+		ids = append(ids, linter.ID())
+	}
+	sort.Strings(ids)
+	if err := writeCacheKeyPart(hash, ids); err != nil {
+		return nil, err
+	}
+	return &cache{
+		dirPath: dirPath,
+		baseKey: hash.Sum(nil),
+	}, nil
+}
+
+// key returns the key for the files in a directory.
+func (c *cache) key(dirPath string, protoFiles []*file.ProtoFile) (string, error) {
+	hash := sha256.New()
+	if _, err := hash.Write(c.baseKey); err != nil {
+		return "", err
+	}
+	if err := writeCacheKeyPart(hash, dirPath); err != nil {
+		return "", err
+	}
+	for _, protoFile := range protoFiles {
+		data, err := ioutil.ReadFile(protoFile.Path)
+		if err != nil {
+			return "", err
+		}
+		if err := writeCacheKeyPart(hash, protoFile.Path, protoFile.DisplayPath, data); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// get returns the cached failures for the key, and true if they were found.
+func (c *cache) get(key string) ([]*text.Failure, bool) {
+	data, err := ioutil.ReadFile(c.filePath(key))
+	if err != nil {
+		return nil, false
+	}
+	var failures []*text.Failure
+	if err := json.Unmarshal(data, &failures); err != nil {
+		return nil, false
+	}
+	return failures, true
+}
+
+// put stores the failures for the key.
+func (c *cache) put(key string, failures []*text.Failure) error {
+	if failures == nil {
+		failures = []*text.Failure{}
+	}
+	data, err := json.Marshal(failures)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dirPath, 0755); err != nil {
+		return err
+	}
+	// write to a temporary file and rename so that concurrent runs
+	// never read a partially written file
+	tempFile, err := ioutil.TempFile(c.dirPath, key+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tempFile.Write(data); err != nil {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		_ = os.Remove(tempFile.Name())
+		return err
+	}
+	return os.Rename(tempFile.Name(), c.filePath(key))
+}
+
+func (c *cache) filePath(key string) string {
+	return filepath.Join(c.dirPath, key+".json")
+}
+
+// writeCacheKeyPart writes each value as JSON followed by a newline, so
+// that adjacent values cannot run together.
+func writeCacheKeyPart(writer io.Writer, values ...interface{}) error {
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+var aipPaginationFieldsLinter = NewLinter(
+	"AIP_PAGINATION_FIELDS",
+	`Verifies that List methods, and all methods with a "page_token" request field, have an int32 "page_size" and a string "page_token" request field and a string "next_page_token" response field, as in AIP-158.`,
+	checkAIPPaginationFields,
+)
+
+func checkAIPPaginationFields(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+	dir := newAIPDir(descriptors)
+	for _, rpc := range dir.rpcs {
+		request := dir.getMessage(rpc.RequestType)
+		if request == nil {
+			continue
+		}
+		if verb, _ := getAIPStandardMethod(rpc.Name); verb != "List" && getAIPField(request, "page_token") == nil {
+			continue
+		}
+		if field := getAIPField(request, "page_size"); field == nil || !field.is("int32") {
+			add(text.NewFailuref(rpc.Position, "", `Paginated method %q should have an int32 field "page_size" in request %q.`, rpc.Name, rpc.RequestType))
+		}
+		if field := getAIPField(request, "page_token"); field == nil || !field.is("string") {
+			add(text.NewFailuref(rpc.Position, "", `Paginated method %q should have a string field "page_token" in request %q.`, rpc.Name, rpc.RequestType))
+		}
+		// the response is only checked if it is in the directory
+		if response := dir.getMessage(rpc.ReturnsType); response != nil {
+			if field := getAIPField(response, "next_page_token"); field == nil || !field.is("string") {
+				add(text.NewFailuref(rpc.Position, "", `Paginated method %q should have a string field "next_page_token" in response %q.`, rpc.Name, rpc.ReturnsType))
+			}
+		}
+	}
+	return nil
+}
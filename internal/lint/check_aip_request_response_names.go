@@ -0,0 +1,50 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+var aipRequestResponseNamesLinter = NewLinter(
+	"AIP_REQUEST_RESPONSE_NAMES",
+	`Verifies that all request names are RpcNameRequest, and all response names are RpcNameResponse except for Get, Create, Update, and Delete methods and methods that return google.longrunning.Operation, as in AIP-131 to AIP-136.`,
+	checkAIPRequestResponseNames,
+)
+
+func checkAIPRequestResponseNames(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+	for _, rpc := range newAIPDir(descriptors).rpcs {
+		if aipSimpleName(rpc.RequestType) != rpc.Name+"Request" {
+			add(text.NewFailuref(rpc.Position, "", "Name of request type %q should be %q.", rpc.RequestType, rpc.Name+"Request"))
+		}
+		if verb, _ := getAIPStandardMethod(rpc.Name); verb != "" && verb != "List" {
+			continue
+		}
+		if aipTypeNamesEqual(rpc.ReturnsType, aipOperationType) {
+			continue
+		}
+		if aipSimpleName(rpc.ReturnsType) != rpc.Name+"Response" {
+			add(text.NewFailuref(rpc.Position, "", "Name of response type %q should be %q.", rpc.ReturnsType, rpc.Name+"Response"))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+var aipResourcesAnnotatedLinter = NewLinter(
+	"AIP_RESOURCES_ANNOTATED",
+	`Verifies that all resources, which are the response types of Get, Create, and Update methods, have a google.api.resource annotation with a type and a pattern, and a string "name" field, as in AIP-123.`,
+	checkAIPResourcesAnnotated,
+)
+
+func checkAIPResourcesAnnotated(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+	dir := newAIPDir(descriptors)
+	checked := make(map[*proto.Message]struct{})
+	for _, rpc := range dir.rpcs {
+		verb, resource := getAIPStandardMethod(rpc.Name)
+		if verb != "Get" && verb != "Create" && verb != "Update" {
+			continue
+		}
+		if aipSimpleName(rpc.ReturnsType) != resource {
+			continue
+		}
+		message := dir.getMessage(rpc.ReturnsType)
+		if message == nil {
+			continue
+		}
+		if _, ok := checked[message]; ok {
+			continue
+		}
+		checked[message] = struct{}{}
+		resourceType, pattern := getAIPResourceAnnotation(message)
+		if resourceType == "" {
+			add(text.NewFailuref(message.Position, "", "Resource %q should have a google.api.resource annotation with a type.", message.Name))
+		}
+		if pattern == "" {
+			add(text.NewFailuref(message.Position, "", "Resource %q should have a google.api.resource annotation with a pattern.", message.Name))
+		}
+		if field := getAIPField(message, "name"); field == nil || !field.is("string") {
+			add(text.NewFailuref(message.Position, "", `Resource %q should have a string field "name".`, message.Name))
+		}
+	}
+	return nil
+}
+
+// getAIPResourceAnnotation returns the type and the first pattern of the
+// google.api.resource annotation of the message, which are empty if not set.
+func getAIPResourceAnnotation(message *proto.Message) (string, string) {
+	var resourceType string
+	var pattern string
+	set := func(name string, literal *proto.Literal) {
+		switch name {
+		case "type":
+			resourceType = literal.Source
+		case "pattern":
+			if pattern == "" {
+				pattern = literal.Source
+				if len(literal.Array) > 0 {
+					pattern = literal.Array[0].Source
+				}
+			}
+		}
+	}
+	for _, element := range message.Elements {
+		option, ok := element.(*proto.Option)
+		if !ok {
+			continue
+		}
+		switch option.Name {
+		case aipResourceOptionName:
+			for _, namedLiteral := range option.Constant.OrderedMap {
+				set(namedLiteral.Name, namedLiteral.Literal)
+			}
+		case aipResourceOptionName + ".type":
+			set("type", &option.Constant)
+		case aipResourceOptionName + ".pattern":
+			set("pattern", &option.Constant)
+		}
+	}
+	return resourceType, pattern
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+var aipStandardMethodsValidLinter = NewLinter(
+	"AIP_STANDARD_METHODS_VALID",
+	`Verifies that Get, Create, and Update methods return the resource, Delete methods return the resource or google.protobuf.Empty, and that requests have the standard fields, as in AIP-131 to AIP-135. Create, Update, and Delete methods can also return google.longrunning.Operation.`,
+	checkAIPStandardMethodsValid,
+)
+
+func checkAIPStandardMethodsValid(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+	dir := newAIPDir(descriptors)
+	for _, rpc := range dir.rpcs {
+		verb, resource := getAIPStandardMethod(rpc.Name)
+		if verb == "" {
+			continue
+		}
+		addFailuref := func(format string, args ...interface{}) {
+			add(text.NewFailuref(rpc.Position, "", format, args...))
+		}
+		checkResponse := func(allowedTypeNames ...string) {
+			for _, allowedTypeName := range allowedTypeNames {
+				if aipTypeNamesEqual(rpc.ReturnsType, allowedTypeName) {
+					return
+				}
+			}
+			addFailuref("%s method %q should return %s but returns %q.", verb, rpc.Name, joinQuoted(allowedTypeNames), rpc.ReturnsType)
+		}
+		switch verb {
+		case "Get":
+			checkResponse(resource)
+		case "Create", "Update":
+			checkResponse(resource, aipOperationType)
+		case "Delete":
+			checkResponse(aipEmptyType, resource, aipOperationType)
+		}
+		request := dir.getMessage(rpc.RequestType)
+		if request == nil {
+			continue
+		}
+		checkRequestField := func(name string, typeName string) {
+			if field := getAIPField(request, name); field == nil || !field.is(typeName) {
+				addFailuref("%s method %q should have a %s field %q in request %q.", verb, rpc.Name, typeName, name, rpc.RequestType)
+			}
+		}
+		switch verb {
+		case "Get", "Delete":
+			checkRequestField("name", "string")
+		case "Create":
+			checkRequestField(aipLowerSnakeCase(resource), resource)
+		case "Update":
+			checkRequestField(aipLowerSnakeCase(resource), resource)
+			checkRequestField("update_mask", aipFieldMaskType)
+		}
+	}
+	return nil
+}
+
+func joinQuoted(values []string) string {
+	s := ""
+	for i, value := range values {
+		switch {
+		case i == 0:
+		case i == len(values)-1:
+			s += " or "
+		default:
+			s += ", "
+		}
+		s += `"` + value + `"`
+	}
+	return s
+}
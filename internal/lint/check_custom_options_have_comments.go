@@ -0,0 +1,52 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+var customOptionsHaveCommentsLinter = NewLinter(
+	"CUSTOM_OPTIONS_HAVE_COMMENTS",
+	`Verifies that all custom options have a comment of the form "// option_name ...".`,
+	checkCustomOptionsHaveComments,
+)
+
+func checkCustomOptionsHaveComments(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+	for _, descriptor := range descriptors {
+		for _, extend := range getCustomOptionsExtends(descriptor) {
+			for _, element := range extend.message.Elements {
+				field, ok := element.(*proto.NormalField)
+				if !ok {
+					continue
+				}
+				if field.Comment == nil || len(field.Comment.Lines) == 0 || !strings.HasPrefix(field.Comment.Lines[0], fmt.Sprintf(" %s ", field.Name)) {
+					add(text.NewFailuref(field.Position, "", `Custom option %q needs a comment of the form "// %s ..."`, field.Name, field.Name))
+				}
+			}
+		}
+	}
+	return nil
+}
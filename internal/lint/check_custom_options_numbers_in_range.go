@@ -0,0 +1,74 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
+)
+
+var customOptionsNumbersInRangeLinter = newCustomOptionsNumbersInRangeLinter(defaultCustomOptionNumberRanges)
+
+// newCustomOptionsNumbersInRangeLinter returns a new CUSTOM_OPTIONS_NUMBERS_IN_RANGE
+// linter for the field number ranges that custom options may use.
+func newCustomOptionsNumbersInRangeLinter(numberRanges []settings.NumberRange) Linter {
+	return NewLinter(
+		"CUSTOM_OPTIONS_NUMBERS_IN_RANGE",
+		"Verifies that all custom options use field numbers in "+getNumberRangesString(numberRanges)+".",
+		func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+			for _, descriptor := range descriptors {
+				for _, extend := range getCustomOptionsExtends(descriptor) {
+					for _, field := range getMessageFields(extend.message) {
+						if !numberInRanges(field.number, numberRanges) {
+							add(text.NewFailuref(field.position, "", "Custom option %q of %s has number %d which is not in %s.", field.name, extend.extendee, field.number, getNumberRangesString(numberRanges)))
+						}
+					}
+				}
+			}
+			return nil
+		},
+	)
+}
+
+func numberInRanges(number int, numberRanges []settings.NumberRange) bool {
+	for _, numberRange := range numberRanges {
+		if number >= numberRange.Start && number <= numberRange.End {
+			return true
+		}
+	}
+	return false
+}
+
+func getNumberRangesString(numberRanges []settings.NumberRange) string {
+	strs := make([]string, 0, len(numberRanges))
+	for _, numberRange := range numberRanges {
+		if numberRange.Start == numberRange.End {
+			strs = append(strs, strconv.Itoa(numberRange.Start))
+			continue
+		}
+		strs = append(strs, strconv.Itoa(numberRange.Start)+" to "+strconv.Itoa(numberRange.End))
+	}
+	return strings.Join(strs, " or ")
+}
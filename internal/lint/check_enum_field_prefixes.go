@@ -24,45 +24,54 @@ import (
 	"strings"
 
 	"github.com/emicklei/proto"
-	"github.com/uber/prototool/internal/strs"
+	"github.com/uber/prototool/internal/protostrs"
 	"github.com/uber/prototool/internal/text"
 )
 
-var enumFieldPrefixesLinter = NewLinter(
-	"ENUM_FIELD_PREFIXES",
-	"Verifies that all enum fields are prefixed with [NESTED_MESSAGE_NAME_]ENUM_NAME_.",
-	checkEnumFieldPrefixes,
-)
+var enumFieldPrefixesLinter = newEnumFieldPrefixesLinter(protostrs.DefaultEnumValuePrefix)
 
-func checkEnumFieldPrefixes(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
-	return runVisitor(&enumFieldPrefixesVisitor{baseAddVisitor: newBaseAddVisitor(add)}, descriptors)
+// newEnumFieldPrefixesLinter returns a new ENUM_FIELD_PREFIXES
+// linter for the enum value prefix.
+func newEnumFieldPrefixesLinter(enumValuePrefix string) Linter {
+	purpose := "Verifies that all enum fields are prefixed with [NESTED_MESSAGE_NAME_]ENUM_NAME_."
+	switch enumValuePrefix {
+	case protostrs.EnumValuePrefixEnum:
+		purpose = "Verifies that all enum fields are prefixed with ENUM_NAME_."
+	case protostrs.EnumValuePrefixNone:
+		purpose = "Does nothing as enum fields are not required to have a prefix."
+	}
+	return NewLinter(
+		"ENUM_FIELD_PREFIXES",
+		purpose,
+		func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+			return runVisitor(&enumFieldPrefixesVisitor{
+				baseAddVisitor:  newBaseAddVisitor(add),
+				enumValuePrefix: enumValuePrefix,
+			}, descriptors)
+		},
+	)
 }
 
 type enumFieldPrefixesVisitor struct {
 	baseAddVisitor
 
-	nestedNames []string
+	enumValuePrefix string
+	messageNames    []string
 }
 
 func (v *enumFieldPrefixesVisitor) VisitMessage(message *proto.Message) {
-	v.nestedNames = append(v.nestedNames, strs.ToUpperSnakeCase(message.Name))
+	v.messageNames = append(v.messageNames, message.Name)
 	for _, child := range message.Elements {
 		child.Accept(v)
 	}
-	v.nestedNames = v.nestedNames[0 : len(v.nestedNames)-1]
+	v.messageNames = v.messageNames[0 : len(v.messageNames)-1]
 }
 
 func (v *enumFieldPrefixesVisitor) VisitEnum(enum *proto.Enum) {
-	v.nestedNames = append(v.nestedNames, strs.ToUpperSnakeCase(enum.Name))
+	expectedPrefix := protostrs.EnumValuePrefix(v.enumValuePrefix, v.messageNames, enum.Name)
 	for _, child := range enum.Elements {
-		child.Accept(v)
-	}
-	v.nestedNames = v.nestedNames[0 : len(v.nestedNames)-1]
-}
-
-func (v *enumFieldPrefixesVisitor) VisitEnumField(enumField *proto.EnumField) {
-	expectedPrefix := strings.Join(v.nestedNames, "_") + "_"
-	if !strings.HasPrefix(enumField.Name, expectedPrefix) {
-		v.AddFailuref(enumField.Position, "Enum field %q is expected to have the prefix %q.", enumField.Name, expectedPrefix)
+		if enumField, ok := child.(*proto.EnumField); ok && !strings.HasPrefix(enumField.Name, expectedPrefix) {
+			v.AddFailuref(enumField.Position, "Enum field %q is expected to have the prefix %q.", enumField.Name, expectedPrefix)
+		}
 	}
 }
@@ -21,49 +21,53 @@
 package lint
 
 import (
-	"strings"
-
 	"github.com/emicklei/proto"
-	"github.com/uber/prototool/internal/strs"
+	"github.com/uber/prototool/internal/protostrs"
 	"github.com/uber/prototool/internal/text"
 )
 
-var enumZeroValuesInvalidLinter = NewLinter(
-	"ENUM_ZERO_VALUES_INVALID",
-	"Verifies that all enum zero value names are [NESTED_MESSAGE_NAME_]ENUM_NAME_INVALID.",
-	checkEnumZeroValuesInvalid,
-)
+var enumZeroValuesInvalidLinter = newEnumZeroValuesInvalidLinter(protostrs.DefaultEnumValuePrefix, protostrs.DefaultEnumZeroValueSuffix)
 
-func checkEnumZeroValuesInvalid(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
-	return runVisitor(&enumZeroValuesInvalidVisitor{baseAddVisitor: newBaseAddVisitor(add)}, descriptors)
+// newEnumZeroValuesInvalidLinter returns a new ENUM_ZERO_VALUES_INVALID
+// linter for the enum value prefix and the zero value suffix.
+func newEnumZeroValuesInvalidLinter(enumValuePrefix string, enumZeroValueSuffix string) Linter {
+	expectedName := protostrs.EnumZeroValueName(enumValuePrefix, enumZeroValueSuffix, []string{"NESTED_MESSAGE_NAME"}, "ENUM_NAME")
+	if enumValuePrefix == protostrs.EnumValuePrefixNested {
+		expectedName = "[NESTED_MESSAGE_NAME_]ENUM_NAME_" + enumZeroValueSuffix
+	}
+	return NewLinter(
+		"ENUM_ZERO_VALUES_INVALID",
+		"Verifies that all enum zero value names are "+expectedName+".",
+		func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+			return runVisitor(&enumZeroValuesInvalidVisitor{
+				baseAddVisitor:      newBaseAddVisitor(add),
+				enumValuePrefix:     enumValuePrefix,
+				enumZeroValueSuffix: enumZeroValueSuffix,
+			}, descriptors)
+		},
+	)
 }
 
 type enumZeroValuesInvalidVisitor struct {
 	baseAddVisitor
 
-	nestedNames []string
+	enumValuePrefix     string
+	enumZeroValueSuffix string
+	messageNames        []string
 }
 
 func (v *enumZeroValuesInvalidVisitor) VisitMessage(message *proto.Message) {
-	v.nestedNames = append(v.nestedNames, strs.ToUpperSnakeCase(message.Name))
+	v.messageNames = append(v.messageNames, message.Name)
 	for _, child := range message.Elements {
 		child.Accept(v)
 	}
-	v.nestedNames = v.nestedNames[0 : len(v.nestedNames)-1]
+	v.messageNames = v.messageNames[0 : len(v.messageNames)-1]
 }
 
 func (v *enumZeroValuesInvalidVisitor) VisitEnum(enum *proto.Enum) {
-	v.nestedNames = append(v.nestedNames, strs.ToUpperSnakeCase(enum.Name))
+	expectedName := protostrs.EnumZeroValueName(v.enumValuePrefix, v.enumZeroValueSuffix, v.messageNames, enum.Name)
 	for _, child := range enum.Elements {
-		child.Accept(v)
-	}
-	v.nestedNames = v.nestedNames[0 : len(v.nestedNames)-1]
-}
-
-func (v *enumZeroValuesInvalidVisitor) VisitEnumField(enumField *proto.EnumField) {
-	if enumField.Integer == 0 {
-		expectedName := strings.Join(v.nestedNames, "_") + "_INVALID"
-		if enumField.Name != expectedName {
+		if enumField, ok := child.(*proto.EnumField); ok && enumField.Integer == 0 && enumField.Name != expectedName {
 			v.AddFailuref(enumField.Position, "Zero value enum field %q is expected to have the name %q.", enumField.Name, expectedName)
 		}
 	}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+const maxHotFieldNumber = 15
+
+var fieldNumbersLowForHotFieldsLinter = newFieldNumbersLowForHotFieldsLinter(nil)
+
+// newFieldNumbersLowForHotFieldsLinter returns a new FIELD_NUMBERS_LOW_FOR_HOT_FIELDS
+// linter for the map from fully-qualified message name to the names of its hot fields.
+func newFieldNumbersLowForHotFieldsLinter(hotFields map[string][]string) Linter {
+	return NewLinter(
+		"FIELD_NUMBERS_LOW_FOR_HOT_FIELDS",
+		"Verifies that the configured hot fields of a message use field numbers 1 to 15 and that no other fields of the message do.",
+		func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+			return runVisitor(&fieldNumbersLowForHotFieldsVisitor{
+				baseAddVisitor: newBaseAddVisitor(add),
+				hotFields:      hotFields,
+			}, descriptors)
+		},
+	)
+}
+
+type fieldNumbersLowForHotFieldsVisitor struct {
+	baseAddVisitor
+
+	hotFields    map[string][]string
+	pkg          string
+	messageNames []string
+}
+
+func (v *fieldNumbersLowForHotFieldsVisitor) OnStart(*proto.Proto) error {
+	v.pkg = ""
+	v.messageNames = nil
+	return nil
+}
+
+func (v *fieldNumbersLowForHotFieldsVisitor) VisitPackage(pkg *proto.Package) {
+	v.pkg = pkg.Name
+}
+
+func (v *fieldNumbersLowForHotFieldsVisitor) VisitMessage(message *proto.Message) {
+	v.messageNames = append(v.messageNames, message.Name)
+	v.checkMessage(message)
+	for _, element := range message.Elements {
+		if child, ok := element.(*proto.Message); ok {
+			child.Accept(v)
+		}
+	}
+	v.messageNames = v.messageNames[0 : len(v.messageNames)-1]
+}
+
+func (v *fieldNumbersLowForHotFieldsVisitor) checkMessage(message *proto.Message) {
+	messageName := strings.Join(v.messageNames, ".")
+	if v.pkg != "" {
+		messageName = v.pkg + "." + messageName
+	}
+	hotFieldNames, ok := v.hotFields[messageName]
+	if !ok {
+		return
+	}
+	hotFieldNameMap := make(map[string]struct{}, len(hotFieldNames))
+	for _, hotFieldName := range hotFieldNames {
+		hotFieldNameMap[hotFieldName] = struct{}{}
+	}
+	for _, field := range getMessageFields(message) {
+		_, isHot := hotFieldNameMap[field.name]
+		isLow := field.number >= 1 && field.number <= maxHotFieldNumber
+		if isHot && !isLow {
+			v.AddFailuref(field.position, "Field %q of message %q is a hot field and should have a number from 1 to %d but has number %d.", field.name, messageName, maxHotFieldNumber, field.number)
+		}
+		if !isHot && isLow {
+			v.AddFailuref(field.position, "Field %q of message %q has number %d but numbers 1 to %d are reserved for the hot fields of the message.", field.name, messageName, field.number, maxHotFieldNumber)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+const (
+	reservedFieldNumberRangeStart = 19000
+	reservedFieldNumberRangeEnd   = 19999
+)
+
+var fieldNumbersNotInReservedRangeLinter = NewLinter(
+	"FIELD_NUMBERS_NOT_IN_RESERVED_RANGE",
+	"Verifies that no field numbers are in the range 19000 to 19999 reserved for the Protocol Buffers implementation.",
+	checkFieldNumbersNotInReservedRange,
+)
+
+func checkFieldNumbersNotInReservedRange(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+	return runVisitor(fieldNumbersNotInReservedRangeVisitor{baseAddVisitor: newBaseAddVisitor(add)}, descriptors)
+}
+
+type fieldNumbersNotInReservedRangeVisitor struct {
+	baseAddVisitor
+}
+
+func (v fieldNumbersNotInReservedRangeVisitor) VisitMessage(message *proto.Message) {
+	for _, field := range getMessageFields(message) {
+		if field.number >= reservedFieldNumberRangeStart && field.number <= reservedFieldNumberRangeEnd {
+			v.AddFailuref(field.position, "Field %q has number %d which is in the range %d to %d reserved for the Protocol Buffers implementation.", field.name, field.number, reservedFieldNumberRangeStart, reservedFieldNumberRangeEnd)
+		}
+	}
+	for _, element := range message.Elements {
+		if child, ok := element.(*proto.Message); ok {
+			child.Accept(v)
+		}
+	}
+}
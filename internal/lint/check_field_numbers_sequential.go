@@ -0,0 +1,102 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+var fieldNumbersSequentialLinter = NewLinter(
+	"FIELD_NUMBERS_SEQUENTIAL",
+	"Warns if field numbers are not sequential, unless the skipped numbers are reserved or extension ranges.",
+	checkFieldNumbersSequential,
+)
+
+func checkFieldNumbersSequential(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+	return runVisitor(&fieldNumbersSequentialVisitor{baseAddVisitor: newBaseAddVisitor(add)}, descriptors)
+}
+
+type fieldNumbersSequentialVisitor struct {
+	baseAddVisitor
+
+	messageNames []string
+}
+
+func (v *fieldNumbersSequentialVisitor) VisitMessage(message *proto.Message) {
+	// extensions, such as custom options, use numbers allocated from
+	// the extension ranges of another message
+	if message.IsExtend {
+		return
+	}
+	v.messageNames = append(v.messageNames, message.Name)
+	v.checkMessage(message)
+	for _, element := range message.Elements {
+		if child, ok := element.(*proto.Message); ok {
+			child.Accept(v)
+		}
+	}
+	v.messageNames = v.messageNames[0 : len(v.messageNames)-1]
+}
+
+func (v *fieldNumbersSequentialVisitor) checkMessage(message *proto.Message) {
+	fields := getMessageFields(message)
+	sort.SliceStable(fields, func(i int, j int) bool { return fields[i].number < fields[j].number })
+	ranges := getMessageReservedRanges(message)
+	expectedNumber := 1
+	for _, field := range fields {
+		if field.number < expectedNumber {
+			// duplicate numbers are reported by protoc
+			continue
+		}
+		for number := expectedNumber; number < field.number; number++ {
+			if !fieldNumberInRanges(number, ranges) {
+				failure := text.NewFailuref(field.position, "", "Field %q of message %q has number %d but number %d is unused and not reserved.", field.name, strings.Join(v.messageNames, "."), field.number, number)
+				failure.Severity = text.SeverityWarning
+				v.add(failure)
+				break
+			}
+		}
+		expectedNumber = field.number + 1
+	}
+}
+
+func fieldNumberInRanges(number int, ranges []proto.Range) bool {
+	for _, r := range ranges {
+		if r.Max {
+			if number >= r.From {
+				return true
+			}
+			continue
+		}
+		to := r.To
+		if to == 0 {
+			to = r.From
+		}
+		if number >= r.From && number <= to {
+			return true
+		}
+	}
+	return false
+}
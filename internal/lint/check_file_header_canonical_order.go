@@ -0,0 +1,65 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/protostrs"
+	"github.com/uber/prototool/internal/text"
+)
+
+var fileHeaderCanonicalOrderLinter = NewLinter(
+	"FILE_HEADER_CANONICAL_ORDER",
+	`Verifies that imports are sorted with regular imports before public imports before weak imports, and that file options are sorted with built-in options before custom options, as done by format with canonical_order set.`,
+	checkFileHeaderCanonicalOrder,
+)
+
+func checkFileHeaderCanonicalOrder(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+	return runVisitor(&fileHeaderCanonicalOrderVisitor{baseAddVisitor: newBaseAddVisitor(add)}, descriptors)
+}
+
+type fileHeaderCanonicalOrderVisitor struct {
+	baseAddVisitor
+
+	lastImport *proto.Import
+	lastOption *proto.Option
+}
+
+func (v *fileHeaderCanonicalOrderVisitor) OnStart(*proto.Proto) error {
+	v.lastImport = nil
+	v.lastOption = nil
+	return nil
+}
+
+func (v *fileHeaderCanonicalOrderVisitor) VisitImport(element *proto.Import) {
+	if v.lastImport != nil && protostrs.ImportLess(element.Kind, element.Filename, v.lastImport.Kind, v.lastImport.Filename) {
+		v.AddFailuref(element.Position, "Import %q should come before import %q.", element.Filename, v.lastImport.Filename)
+	}
+	v.lastImport = element
+}
+
+func (v *fileHeaderCanonicalOrderVisitor) VisitOption(element *proto.Option) {
+	// we only visit top-level elements, so this is a file option
+	if v.lastOption != nil && protostrs.FileOptionLess(element.Name, v.lastOption.Name) {
+		v.AddFailuref(element.Position, "File option %q should come before file option %q.", element.Name, v.lastOption.Name)
+	}
+	v.lastOption = element
+}
@@ -21,9 +21,12 @@
 package lint
 
 import (
+	"fmt"
+	"strings"
 	"text/scanner"
 
 	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/protostrs"
 	"github.com/uber/prototool/internal/text"
 )
 
@@ -51,11 +54,82 @@ var fileOptionsRequireJavaPackageLinter = NewLinter(
 	newCheckFileOptionsRequire("java_package"),
 )
 
-func newCheckFileOptionsRequire(fileOption string) func(func(*text.Failure), string, []*proto.Proto) error {
+var fileOptionsRequiredForLanguagesLinter = NewLinter(
+	"FILE_OPTIONS_REQUIRED_FOR_LANGUAGES",
+	`Verifies that the file options for the languages in the "languages" setting are set.`,
+	func(func(*text.Failure), string, []*proto.Proto) error {
+		return nil
+	},
+)
+
+// languageToFileOptionsLinters is the map from language to the linters
+// that check the file options of the language.
+var languageToFileOptionsLinters = map[string][]Linter{
+	"go": {
+		fileOptionsEqualGoPackagePbSuffixLinter,
+		fileOptionsGoPackageSameInDirLinter,
+		fileOptionsRequireGoPackageLinter,
+	},
+	"java": {
+		fileOptionsEqualJavaMultipleFilesTrueLinter,
+		fileOptionsEqualJavaOuterClassnameProtoSuffixLinter,
+		fileOptionsEqualJavaPackageComPrefixLinter,
+		fileOptionsJavaMultipleFilesSameInDirLinter,
+		fileOptionsJavaPackageSameInDirLinter,
+		fileOptionsRequireJavaMultipleFilesLinter,
+		fileOptionsRequireJavaOuterClassnameLinter,
+		fileOptionsRequireJavaPackageLinter,
+	},
+}
+
+// fileOptionsRequireLinters are the linters that each require one file option,
+// which FILE_OPTIONS_REQUIRED_FOR_LANGUAGES replaces if languages are set.
+var fileOptionsRequireLinters = []Linter{
+	fileOptionsRequireGoPackageLinter,
+	fileOptionsRequireJavaMultipleFilesLinter,
+	fileOptionsRequireJavaOuterClassnameLinter,
+	fileOptionsRequireJavaPackageLinter,
+}
+
+func newFileOptionsRequiredForLanguagesLinter(languages []string) Linter {
+	var fileOptions []string
+	for _, language := range languages {
+		fileOptions = append(fileOptions, protostrs.LanguageToFileOptionNames[language]...)
+	}
+	return NewLinter(
+		fileOptionsRequiredForLanguagesLinter.ID(),
+		fmt.Sprintf(`Verifies that the file options for the languages %s are set.`, strings.Join(languages, ", ")),
+		newCheckFileOptionsRequire(fileOptions...),
+	)
+}
+
+// getLanguageLinters returns the linters without the file option linters of
+// the languages that are not in languages.
+//
+// If the linters contain FILE_OPTIONS_REQUIRED_FOR_LANGUAGES, the linters
+// that each require one file option are also removed.
+func getLanguageLinters(linters []Linter, languages []string) []Linter {
+	languageMap := make(map[string]struct{}, len(languages))
+	for _, language := range languages {
+		languageMap[language] = struct{}{}
+	}
+	var removeLinters []Linter
+	for language, languageLinters := range languageToFileOptionsLinters {
+		if _, ok := languageMap[language]; !ok {
+			removeLinters = append(removeLinters, languageLinters...)
+		}
+	}
+	if linterIn(fileOptionsRequiredForLanguagesLinter, linters) {
+		removeLinters = append(removeLinters, fileOptionsRequireLinters...)
+	}
+	return copyLintersWithout(linters, removeLinters...)
+}
+
+func newCheckFileOptionsRequire(fileOptions ...string) func(func(*text.Failure), string, []*proto.Proto) error {
 	return func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
 		return runVisitor(&fileOptionsRequireVisitor{
 			baseAddVisitor: newBaseAddVisitor(add),
-			fileOption:     fileOption,
+			fileOptions:    fileOptions,
 		}, descriptors)
 	}
 }
@@ -63,28 +137,28 @@ func newCheckFileOptionsRequire(fileOption string) func(func(*text.Failure), str
 type fileOptionsRequireVisitor struct {
 	baseAddVisitor
 
-	fileOption string
+	fileOptions []string
 
 	filename string
-	seen     bool
+	seen     map[string]struct{}
 }
 
 func (v *fileOptionsRequireVisitor) OnStart(descriptor *proto.Proto) error {
 	v.filename = descriptor.Filename
-	v.seen = false
+	v.seen = make(map[string]struct{})
 	return nil
 }
 
 func (v *fileOptionsRequireVisitor) VisitOption(element *proto.Option) {
 	// TODO: not validating this is a file option, or are we since we're not recursing on other elements?
-	if element.Name == v.fileOption {
-		v.seen = true
-	}
+	v.seen[element.Name] = struct{}{}
 }
 
 func (v *fileOptionsRequireVisitor) Finally() error {
-	if !v.seen {
-		v.AddFailuref(scanner.Position{Filename: v.filename}, "File option %q is required.", v.fileOption)
+	for _, fileOption := range v.fileOptions {
+		if _, ok := v.seen[fileOption]; !ok {
+			v.AddFailuref(scanner.Position{Filename: v.filename}, "File option %q is required.", fileOption)
+		}
 	}
 	return nil
 }
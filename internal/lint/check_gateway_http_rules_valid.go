@@ -0,0 +1,204 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+const gatewayHTTPOptionName = "(google.api.http)"
+
+var (
+	gatewayHTTPRulesValidLinter = NewLinter(
+		"GATEWAY_HTTP_RULES_VALID",
+		`Verifies that all google.api.http annotations have exactly one pattern with a path starting with "/", no body for get and delete, and that path variables and the body refer to fields of the request type.`,
+		checkGatewayHTTPRulesValid,
+	)
+
+	gatewayHTTPPatterns = map[string]struct{}{
+		"get":    struct{}{},
+		"put":    struct{}{},
+		"post":   struct{}{},
+		"delete": struct{}{},
+		"patch":  struct{}{},
+		"custom": struct{}{},
+	}
+
+	gatewayPathVariableRegexp = regexp.MustCompile(`{([^}=]+)(=[^}]*)?}`)
+)
+
+func checkGatewayHTTPRulesValid(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+	return runVisitor(&gatewayHTTPRulesValidVisitor{baseAddVisitor: newBaseAddVisitor(add)}, descriptors)
+}
+
+type gatewayHTTPRulesValidVisitor struct {
+	baseAddVisitor
+	messageToFieldNames map[string]map[string]struct{}
+	rpcs                []*proto.RPC
+}
+
+func (v *gatewayHTTPRulesValidVisitor) OnStart(*proto.Proto) error {
+	v.messageToFieldNames = make(map[string]map[string]struct{})
+	v.rpcs = nil
+	return nil
+}
+
+func (v *gatewayHTTPRulesValidVisitor) VisitMessage(message *proto.Message) {
+	fieldNames := make(map[string]struct{})
+	for _, element := range message.Elements {
+		switch e := element.(type) {
+		case *proto.NormalField:
+			fieldNames[e.Name] = struct{}{}
+		case *proto.MapField:
+			fieldNames[e.Name] = struct{}{}
+		case *proto.Oneof:
+			for _, oneofElement := range e.Elements {
+				if field, ok := oneofElement.(*proto.OneOfField); ok {
+					fieldNames[field.Name] = struct{}{}
+				}
+			}
+		}
+	}
+	// only top-level messages are resolved, request types should not be nested
+	if _, ok := message.Parent.(*proto.Proto); ok && !message.IsExtend {
+		v.messageToFieldNames[message.Name] = fieldNames
+	}
+}
+
+func (v *gatewayHTTPRulesValidVisitor) VisitService(service *proto.Service) {
+	for _, element := range service.Elements {
+		element.Accept(v)
+	}
+}
+
+func (v *gatewayHTTPRulesValidVisitor) VisitRPC(rpc *proto.RPC) {
+	v.rpcs = append(v.rpcs, rpc)
+}
+
+func (v *gatewayHTTPRulesValidVisitor) Finally() error {
+	for _, rpc := range v.rpcs {
+		// nil if the request type is not in the same file
+		fieldNames := v.messageToFieldNames[rpc.RequestType]
+		rule := &gatewayHTTPRule{}
+		found := false
+		for _, element := range rpc.Elements {
+			option, ok := element.(*proto.Option)
+			if !ok {
+				continue
+			}
+			if option.Name == gatewayHTTPOptionName {
+				rule.addLiteralMap(option.Constant.OrderedMap)
+				found = true
+			} else if strings.HasPrefix(option.Name, gatewayHTTPOptionName+".") {
+				rule.add(strings.TrimPrefix(option.Name, gatewayHTTPOptionName+"."), &option.Constant)
+				found = true
+			}
+		}
+		if !found {
+			continue
+		}
+		v.checkRule(rpc, fieldNames, rule)
+		for _, additionalBinding := range rule.additionalBindings {
+			if len(additionalBinding.additionalBindings) > 0 {
+				v.AddFailuref(rpc.Position, "RPC %q has google.api.http additional_bindings that themselves have additional_bindings.", rpc.Name)
+			}
+			v.checkRule(rpc, fieldNames, additionalBinding)
+		}
+	}
+	return nil
+}
+
+func (v *gatewayHTTPRulesValidVisitor) checkRule(rpc *proto.RPC, fieldNames map[string]struct{}, rule *gatewayHTTPRule) {
+	if len(rule.patterns) != 1 {
+		patterns := make([]string, 0, len(rule.patterns))
+		for pattern := range rule.patterns {
+			patterns = append(patterns, pattern)
+		}
+		sort.Strings(patterns)
+		v.AddFailuref(rpc.Position, "RPC %q has google.api.http annotation with patterns %v but must have exactly one of get, put, post, delete, patch, custom.", rpc.Name, patterns)
+		return
+	}
+	for pattern, path := range rule.patterns {
+		if !strings.HasPrefix(path, "/") {
+			v.AddFailuref(rpc.Position, "RPC %q has google.api.http %s path %q which does not start with \"/\".", rpc.Name, pattern, path)
+		}
+		if rule.body != "" && (pattern == "get" || pattern == "delete") {
+			v.AddFailuref(rpc.Position, "RPC %q has google.api.http %s with a body but %s requests cannot have a body.", rpc.Name, pattern, pattern)
+		}
+		if fieldNames == nil {
+			continue
+		}
+		for _, match := range gatewayPathVariableRegexp.FindAllStringSubmatch(path, -1) {
+			fieldName := strings.SplitN(strings.TrimSpace(match[1]), ".", 2)[0]
+			if _, ok := fieldNames[fieldName]; !ok {
+				v.AddFailuref(rpc.Position, "RPC %q has google.api.http path variable %q which is not a field of %q.", rpc.Name, match[1], rpc.RequestType)
+			}
+		}
+	}
+	if fieldNames != nil && rule.body != "" && rule.body != "*" {
+		if _, ok := fieldNames[strings.SplitN(rule.body, ".", 2)[0]]; !ok {
+			v.AddFailuref(rpc.Position, "RPC %q has google.api.http body %q which is not a field of %q.", rpc.Name, rule.body, rpc.RequestType)
+		}
+	}
+}
+
+type gatewayHTTPRule struct {
+	// pattern to path, custom patterns use the custom path
+	patterns           map[string]string
+	body               string
+	additionalBindings []*gatewayHTTPRule
+}
+
+func (r *gatewayHTTPRule) addLiteralMap(literalMap proto.LiteralMap) {
+	for _, namedLiteral := range literalMap {
+		r.add(namedLiteral.Name, namedLiteral.Literal)
+	}
+}
+
+func (r *gatewayHTTPRule) add(name string, literal *proto.Literal) {
+	if _, ok := gatewayHTTPPatterns[name]; ok {
+		if r.patterns == nil {
+			r.patterns = make(map[string]string)
+		}
+		if name == "custom" {
+			r.patterns[name] = ""
+			if path, ok := literal.OrderedMap.Get("path"); ok {
+				r.patterns[name] = path.Source
+			}
+			return
+		}
+		r.patterns[name] = literal.Source
+		return
+	}
+	switch name {
+	case "body":
+		r.body = literal.Source
+	case "additional_bindings":
+		additionalBinding := &gatewayHTTPRule{}
+		additionalBinding.addLiteralMap(literal.OrderedMap)
+		r.additionalBindings = append(r.additionalBindings, additionalBinding)
+	}
+}
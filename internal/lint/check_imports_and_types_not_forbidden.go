@@ -0,0 +1,224 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/strs"
+	"github.com/uber/prototool/internal/text"
+)
+
+var importsAndTypesNotForbiddenLinter = newImportsAndTypesNotForbiddenLinter(nil, nil)
+
+// newImportsAndTypesNotForbiddenLinter returns a new IMPORTS_AND_TYPES_NOT_FORBIDDEN
+// linter for the forbidden imports and types.
+func newImportsAndTypesNotForbiddenLinter(forbiddenImports []settings.ForbiddenRule, forbiddenTypes []settings.ForbiddenRule) Linter {
+	return NewLinter(
+		"IMPORTS_AND_TYPES_NOT_FORBIDDEN",
+		"Verifies that files do not have the configured forbidden imports or use the configured forbidden types for fields or RPCs, unless the files are allowed to.",
+		func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+			if len(forbiddenImports) == 0 && len(forbiddenTypes) == 0 {
+				return nil
+			}
+			return runVisitor(&importsAndTypesNotForbiddenVisitor{
+				baseAddVisitor:   newBaseAddVisitor(add),
+				forbiddenImports: forbiddenImports,
+				forbiddenTypes:   forbiddenTypes,
+			}, descriptors)
+		},
+	)
+}
+
+type importsAndTypesNotForbiddenVisitor struct {
+	baseAddVisitor
+
+	forbiddenImports []settings.ForbiddenRule
+	forbiddenTypes   []settings.ForbiddenRule
+	// the rules that apply to the current file
+	fileForbiddenImports []settings.ForbiddenRule
+	fileForbiddenTypes   []settings.ForbiddenRule
+	pkg                  string
+}
+
+func (v *importsAndTypesNotForbiddenVisitor) OnStart(descriptor *proto.Proto) error {
+	var err error
+	v.fileForbiddenImports, err = getForbiddenRulesForFile(v.forbiddenImports, descriptor.Filename)
+	if err != nil {
+		return err
+	}
+	v.fileForbiddenTypes, err = getForbiddenRulesForFile(v.forbiddenTypes, descriptor.Filename)
+	if err != nil {
+		return err
+	}
+	v.pkg = ""
+	return nil
+}
+
+func (v *importsAndTypesNotForbiddenVisitor) VisitPackage(pkg *proto.Package) {
+	v.pkg = pkg.Name
+}
+
+func (v *importsAndTypesNotForbiddenVisitor) VisitImport(element *proto.Import) {
+	for _, forbiddenImport := range v.fileForbiddenImports {
+		// the pattern was validated by the settings package
+		if matched, _ := strs.MatchGlob(forbiddenImport.Pattern, element.Filename); matched {
+			v.AddFailuref(element.Position, "Import %q is forbidden.", element.Filename)
+			return
+		}
+	}
+}
+
+func (v *importsAndTypesNotForbiddenVisitor) VisitMessage(message *proto.Message) {
+	for _, element := range message.Elements {
+		element.Accept(v)
+	}
+}
+
+func (v *importsAndTypesNotForbiddenVisitor) VisitOneof(oneof *proto.Oneof) {
+	for _, element := range oneof.Elements {
+		element.Accept(v)
+	}
+}
+
+func (v *importsAndTypesNotForbiddenVisitor) VisitService(service *proto.Service) {
+	for _, element := range service.Elements {
+		element.Accept(v)
+	}
+}
+
+func (v *importsAndTypesNotForbiddenVisitor) VisitNormalField(field *proto.NormalField) {
+	v.checkField(field.Field)
+}
+
+func (v *importsAndTypesNotForbiddenVisitor) VisitOneofField(field *proto.OneOfField) {
+	v.checkField(field.Field)
+}
+
+func (v *importsAndTypesNotForbiddenVisitor) VisitMapField(field *proto.MapField) {
+	v.checkField(field.Field)
+}
+
+func (v *importsAndTypesNotForbiddenVisitor) VisitRPC(rpc *proto.RPC) {
+	for _, typeName := range []string{rpc.RequestType, rpc.ReturnsType} {
+		if v.isForbiddenType(typeName) {
+			v.AddFailuref(rpc.Position, "RPC %q uses the forbidden type %q.", rpc.Name, typeName)
+		}
+	}
+}
+
+func (v *importsAndTypesNotForbiddenVisitor) checkField(field *proto.Field) {
+	if v.isForbiddenType(field.Type) {
+		v.AddFailuref(field.Position, "Field %q uses the forbidden type %q.", field.Name, field.Type)
+	}
+}
+
+// isForbiddenType returns true if the type as referenced in the file
+// could be a forbidden type.
+//
+// References are resolved against the package of the file and each of
+// its parent packages, but not against enclosing messages.
+func (v *importsAndTypesNotForbiddenVisitor) isForbiddenType(typeName string) bool {
+	var candidates []string
+	if strings.HasPrefix(typeName, ".") {
+		candidates = []string{strings.TrimPrefix(typeName, ".")}
+	} else {
+		candidates = []string{typeName}
+		for pkg := v.pkg; pkg != ""; pkg = getParentPackage(pkg) {
+			candidates = append(candidates, pkg+"."+typeName)
+		}
+	}
+	for _, forbiddenType := range v.fileForbiddenTypes {
+		for _, candidate := range candidates {
+			if matchTypePattern(forbiddenType.Pattern, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getForbiddenRulesForFile returns the rules that apply to the file,
+// which are the rules whose allowed files do not match the file.
+func getForbiddenRulesForFile(forbiddenRules []settings.ForbiddenRule, filename string) ([]settings.ForbiddenRule, error) {
+	if len(forbiddenRules) == 0 {
+		return nil, nil
+	}
+	absFilePath, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	var fileForbiddenRules []settings.ForbiddenRule
+	for _, forbiddenRule := range forbiddenRules {
+		allowed, err := isAllowedFile(forbiddenRule, absFilePath)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			fileForbiddenRules = append(fileForbiddenRules, forbiddenRule)
+		}
+	}
+	return fileForbiddenRules, nil
+}
+
+func isAllowedFile(forbiddenRule settings.ForbiddenRule, absFilePath string) (bool, error) {
+	if len(forbiddenRule.AllowPatterns) == 0 {
+		return false, nil
+	}
+	relFilePath, err := filepath.Rel(forbiddenRule.AllowDirPath, absFilePath)
+	if err != nil {
+		return false, err
+	}
+	relFilePath = filepath.ToSlash(relFilePath)
+	if strings.HasPrefix(relFilePath, "../") {
+		return false, nil
+	}
+	for _, allowPattern := range forbiddenRule.AllowPatterns {
+		matched, err := strs.MatchGlob(allowPattern, relFilePath)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchTypePattern returns true if the fully-qualified type name matches
+// the pattern, which is either a type name, or a package followed by .*
+// which matches all types in the package and the packages within it.
+func matchTypePattern(pattern string, typeName string) bool {
+	if prefix := strings.TrimSuffix(pattern, ".*"); prefix != pattern {
+		return strings.HasPrefix(typeName, prefix+".")
+	}
+	return typeName == pattern
+}
+
+func getParentPackage(pkg string) string {
+	if i := strings.LastIndex(pkg, "."); i >= 0 {
+		return pkg[:i]
+	}
+	return ""
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+var logExemptTypesNotInLogPackagesLinter = newLogExemptTypesNotInLogPackagesLinter(nil, nil)
+
+// newLogExemptTypesNotInLogPackagesLinter returns a new LOG_EXEMPT_TYPES_NOT_IN_LOG_PACKAGES
+// linter for the types that are exempt from logging and the packages whose messages are logged.
+func newLogExemptTypesNotInLogPackagesLinter(logExemptTypes []string, logPackages []string) Linter {
+	return NewLinter(
+		"LOG_EXEMPT_TYPES_NOT_IN_LOG_PACKAGES",
+		"Verifies that the configured types that are exempt from logging are not used by fields or RPCs in the configured packages whose messages are logged.",
+		func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+			if len(logExemptTypes) == 0 || len(logPackages) == 0 {
+				return nil
+			}
+			return runVisitor(&logExemptTypesNotInLogPackagesVisitor{
+				baseAddVisitor: newBaseAddVisitor(add),
+				logExemptTypes: logExemptTypes,
+				logPackages:    logPackages,
+			}, descriptors)
+		},
+	)
+}
+
+type logExemptTypesNotInLogPackagesVisitor struct {
+	baseAddVisitor
+
+	logExemptTypes []string
+	logPackages    []string
+	pkg            string
+	// whether the current file is in one of the log packages
+	isLogPackage bool
+}
+
+func (v *logExemptTypesNotInLogPackagesVisitor) OnStart(*proto.Proto) error {
+	v.pkg = ""
+	v.isLogPackage = false
+	return nil
+}
+
+func (v *logExemptTypesNotInLogPackagesVisitor) VisitPackage(pkg *proto.Package) {
+	v.pkg = pkg.Name
+	v.isLogPackage = false
+	for _, logPackage := range v.logPackages {
+		if pkg.Name == strings.TrimSuffix(logPackage, ".*") || matchTypePattern(logPackage, pkg.Name) {
+			v.isLogPackage = true
+			return
+		}
+	}
+}
+
+func (v *logExemptTypesNotInLogPackagesVisitor) VisitMessage(message *proto.Message) {
+	if !v.isLogPackage {
+		return
+	}
+	for _, element := range message.Elements {
+		element.Accept(v)
+	}
+}
+
+func (v *logExemptTypesNotInLogPackagesVisitor) VisitOneof(oneof *proto.Oneof) {
+	for _, element := range oneof.Elements {
+		element.Accept(v)
+	}
+}
+
+func (v *logExemptTypesNotInLogPackagesVisitor) VisitService(service *proto.Service) {
+	if !v.isLogPackage {
+		return
+	}
+	for _, element := range service.Elements {
+		element.Accept(v)
+	}
+}
+
+func (v *logExemptTypesNotInLogPackagesVisitor) VisitNormalField(field *proto.NormalField) {
+	v.checkField(field.Field)
+}
+
+func (v *logExemptTypesNotInLogPackagesVisitor) VisitOneofField(field *proto.OneOfField) {
+	v.checkField(field.Field)
+}
+
+func (v *logExemptTypesNotInLogPackagesVisitor) VisitMapField(field *proto.MapField) {
+	v.checkField(field.Field)
+}
+
+func (v *logExemptTypesNotInLogPackagesVisitor) VisitRPC(rpc *proto.RPC) {
+	for _, typeName := range []string{rpc.RequestType, rpc.ReturnsType} {
+		if v.isLogExemptType(typeName) {
+			v.AddFailuref(rpc.Position, "RPC %q uses the type %q which is exempt from logging, but package %q is logged.", rpc.Name, typeName, v.pkg)
+		}
+	}
+}
+
+func (v *logExemptTypesNotInLogPackagesVisitor) checkField(field *proto.Field) {
+	if v.isLogExemptType(field.Type) {
+		v.AddFailuref(field.Position, "Field %q uses the type %q which is exempt from logging, but package %q is logged.", field.Name, field.Type, v.pkg)
+	}
+}
+
+// isLogExemptType returns true if the type as referenced in the file
+// could be a type that is exempt from logging.
+//
+// References are resolved against the package of the file and each of
+// its parent packages, but not against enclosing messages.
+func (v *logExemptTypesNotInLogPackagesVisitor) isLogExemptType(typeName string) bool {
+	var candidates []string
+	if strings.HasPrefix(typeName, ".") {
+		candidates = []string{strings.TrimPrefix(typeName, ".")}
+	} else {
+		candidates = []string{typeName}
+		for pkg := v.pkg; pkg != ""; pkg = getParentPackage(pkg) {
+			candidates = append(candidates, pkg+"."+typeName)
+		}
+	}
+	for _, logExemptType := range v.logExemptTypes {
+		for _, candidate := range candidates {
+			if matchTypePattern(logExemptType, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
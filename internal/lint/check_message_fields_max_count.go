@@ -0,0 +1,69 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+// defaultMaxFieldsPerMessage is the default maximum number of fields a message can have.
+const defaultMaxFieldsPerMessage = 100
+
+var messageFieldsMaxCountLinter = newMessageFieldsMaxCountLinter(defaultMaxFieldsPerMessage)
+
+// newMessageFieldsMaxCountLinter returns a new MESSAGE_FIELDS_MAX_COUNT
+// linter for the maximum number of fields per message.
+func newMessageFieldsMaxCountLinter(maxFieldsPerMessage int) Linter {
+	return NewLinter(
+		"MESSAGE_FIELDS_MAX_COUNT",
+		"Verifies that all messages have at most "+strconv.Itoa(maxFieldsPerMessage)+" fields.",
+		func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+			return runVisitor(&messageFieldsMaxCountVisitor{
+				baseAddVisitor:      newBaseAddVisitor(add),
+				maxFieldsPerMessage: maxFieldsPerMessage,
+			}, descriptors)
+		},
+	)
+}
+
+type messageFieldsMaxCountVisitor struct {
+	baseAddVisitor
+
+	maxFieldsPerMessage int
+	messageNames        []string
+}
+
+func (v *messageFieldsMaxCountVisitor) VisitMessage(message *proto.Message) {
+	v.messageNames = append(v.messageNames, message.Name)
+	if numFields := len(getMessageFields(message)); numFields > v.maxFieldsPerMessage {
+		v.AddFailuref(message.Position, "Message %q has %d fields which is more than the maximum of %d.", strings.Join(v.messageNames, "."), numFields, v.maxFieldsPerMessage)
+	}
+	for _, element := range message.Elements {
+		if child, ok := element.(*proto.Message); ok {
+			child.Accept(v)
+		}
+	}
+	v.messageNames = v.messageNames[0 : len(v.messageNames)-1]
+}
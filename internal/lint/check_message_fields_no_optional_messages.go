@@ -0,0 +1,147 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+var messageFieldsNoOptionalMessagesLinter = NewLinter(
+	"MESSAGE_FIELDS_NO_OPTIONAL_MESSAGES",
+	"Verifies that optional is only used in proto3 files for fields of scalar and enum types, as fields of message types always have presence.",
+	checkMessageFieldsNoOptionalMessages,
+)
+
+func checkMessageFieldsNoOptionalMessages(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+	// the fully-qualified names of all messages and enums in the directory
+	// to whether the name is of a message
+	nameToIsMessage := make(map[string]bool)
+	for _, descriptor := range descriptors {
+		walkScopes(descriptor, func(scope string, message *proto.Message) {
+			nameToIsMessage[scope] = true
+			for _, element := range message.Elements {
+				if enum, ok := element.(*proto.Enum); ok {
+					nameToIsMessage[scope+"."+enum.Name] = false
+				}
+			}
+		}, func(scope string, enum *proto.Enum) {
+			nameToIsMessage[scope] = false
+		})
+	}
+	for _, descriptor := range descriptors {
+		if getSyntax(descriptor) != "proto3" {
+			continue
+		}
+		walkScopes(descriptor, func(scope string, message *proto.Message) {
+			for _, element := range message.Elements {
+				field, ok := element.(*proto.NormalField)
+				if !ok || !field.Optional {
+					continue
+				}
+				if isMessageType(nameToIsMessage, scope, field.Type) {
+					add(text.NewFailuref(field.Position, "", "Field %q is of message type %q and should not be optional, as fields of message types always have presence.", field.Name, field.Type))
+				}
+			}
+		}, nil)
+	}
+	return nil
+}
+
+// walkScopes calls the functions for every message and top-level
+// enum with their fully-qualified names.
+func walkScopes(descriptor *proto.Proto, messageFunc func(string, *proto.Message), enumFunc func(string, *proto.Enum)) {
+	pkg := ""
+	for _, element := range descriptor.Elements {
+		if p, ok := element.(*proto.Package); ok {
+			pkg = p.Name
+		}
+	}
+	for _, element := range descriptor.Elements {
+		switch e := element.(type) {
+		case *proto.Message:
+			if !e.IsExtend {
+				walkScopesMessage(joinScope(pkg, e.Name), e, messageFunc)
+			}
+		case *proto.Enum:
+			if enumFunc != nil {
+				enumFunc(joinScope(pkg, e.Name), e)
+			}
+		}
+	}
+}
+
+func walkScopesMessage(scope string, message *proto.Message, messageFunc func(string, *proto.Message)) {
+	messageFunc(scope, message)
+	for _, element := range message.Elements {
+		if nestedMessage, ok := element.(*proto.Message); ok {
+			walkScopesMessage(scope+"."+nestedMessage.Name, nestedMessage, messageFunc)
+		}
+	}
+}
+
+// isMessageType returns true if the type of a field in the message
+// with the given scope is a message, following the scoping rules of
+// Protobuf. Types that are not in the directory are only known to be
+// messages if they are Well-Known Types.
+func isMessageType(nameToIsMessage map[string]bool, scope string, fieldType string) bool {
+	if strings.HasPrefix(fieldType, ".") {
+		return isKnownMessageType(nameToIsMessage, strings.TrimPrefix(fieldType, "."))
+	}
+	for {
+		if isMessage, ok := nameToIsMessage[joinScope(scope, fieldType)]; ok {
+			return isMessage
+		}
+		if scope == "" {
+			return isKnownMessageType(nameToIsMessage, fieldType)
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+func isKnownMessageType(nameToIsMessage map[string]bool, name string) bool {
+	if isMessage, ok := nameToIsMessage[name]; ok {
+		return isMessage
+	}
+	return strings.HasPrefix(name, "google.protobuf.") && name != "google.protobuf.NullValue"
+}
+
+func joinScope(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func getSyntax(descriptor *proto.Proto) string {
+	for _, element := range descriptor.Elements {
+		if syntax, ok := element.(*proto.Syntax); ok {
+			return syntax.Value
+		}
+	}
+	return "proto2"
+}
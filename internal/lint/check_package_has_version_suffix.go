@@ -0,0 +1,89 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"path/filepath"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/protostrs"
+	"github.com/uber/prototool/internal/text"
+)
+
+var packageHasVersionSuffixLinter = newPackageHasVersionSuffixLinter(false)
+
+// newPackageHasVersionSuffixLinter returns a new PACKAGE_HAS_VERSION_SUFFIX
+// linter. If stableOnly is set, alpha and beta versions are not allowed.
+func newPackageHasVersionSuffixLinter(stableOnly bool) Linter {
+	purpose := "Verifies that the package ends with a version such as v1 or v1beta1 that is the name of the directory of the file."
+	if stableOnly {
+		purpose = "Verifies that the package ends with a stable version such as v1 that is the name of the directory of the file."
+	}
+	return NewLinter(
+		"PACKAGE_HAS_VERSION_SUFFIX",
+		purpose,
+		func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+			return runVisitor(&packageHasVersionSuffixVisitor{
+				baseAddVisitor: newBaseAddVisitor(add),
+				dirName:        filepath.Base(dirPath),
+				stableOnly:     stableOnly,
+			}, descriptors)
+		},
+	)
+}
+
+type packageHasVersionSuffixVisitor struct {
+	baseAddVisitor
+
+	dirName    string
+	stableOnly bool
+
+	pkg *proto.Package
+}
+
+func (v *packageHasVersionSuffixVisitor) OnStart(*proto.Proto) error {
+	v.pkg = nil
+	return nil
+}
+
+func (v *packageHasVersionSuffixVisitor) VisitPackage(pkg *proto.Package) {
+	if v.pkg != nil {
+		v.AddFailuref(pkg.Position, "multiple package declarations, first was %v", v.pkg)
+		return
+	}
+	v.pkg = pkg
+}
+
+func (v *packageHasVersionSuffixVisitor) Finally() error {
+	if v.pkg == nil {
+		return nil
+	}
+	version := protostrs.PackageVersion(v.pkg.Name)
+	switch {
+	case version == "":
+		v.AddFailuref(v.pkg.Position, "Package %q should end with a version such as v1 or v1beta1.", v.pkg.Name)
+	case v.stableOnly && !protostrs.IsStablePackageVersion(version):
+		v.AddFailuref(v.pkg.Position, "Package %q should end with a stable version such as v1 but ended with %q.", v.pkg.Name, version)
+	case version != v.dirName:
+		v.AddFailuref(v.pkg.Position, "Package %q has version %q but is in directory %q, files should be in a directory named after the package version.", v.pkg.Name, version, v.dirName)
+	}
+	return nil
+}
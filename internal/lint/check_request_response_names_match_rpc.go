@@ -22,35 +22,71 @@ package lint
 
 import (
 	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/protostrs"
 	"github.com/uber/prototool/internal/text"
 )
 
-var requestResponseNamesMatchRPCLinter = NewLinter(
-	"REQUEST_RESPONSE_NAMES_MATCH_RPC",
-	"Verifies that all request names are RpcNameRequest and all response names are RpcNameResponse.",
-	checkRequestResponseNamesMatchRPC,
-)
+var requestResponseNamesMatchRPCLinter = newRequestResponseNamesMatchRPCLinter(protostrs.DefaultRequestNameTemplate, protostrs.DefaultResponseNameTemplate)
 
-func checkRequestResponseNamesMatchRPC(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
-	return runVisitor(requestResponseNamesMatchRPCVisitor{baseAddVisitor: newBaseAddVisitor(add)}, descriptors)
+// newRequestResponseNamesMatchRPCLinter returns a new REQUEST_RESPONSE_NAMES_MATCH_RPC
+// linter for the request and response name templates.
+func newRequestResponseNamesMatchRPCLinter(requestNameTemplate string, responseNameTemplate string) Linter {
+	purpose := "Verifies that all request names are RpcNameRequest and all response names are RpcNameResponse."
+	if requestNameTemplate != protostrs.DefaultRequestNameTemplate || responseNameTemplate != protostrs.DefaultResponseNameTemplate {
+		purpose = "Verifies that all request names match " + requestNameTemplate + " and all response names match " + responseNameTemplate + "."
+	}
+	return NewLinter(
+		"REQUEST_RESPONSE_NAMES_MATCH_RPC",
+		purpose,
+		func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+			return runVisitor(&requestResponseNamesMatchRPCVisitor{
+				baseAddVisitor:       newBaseAddVisitor(add),
+				requestNameTemplate:  requestNameTemplate,
+				responseNameTemplate: responseNameTemplate,
+			}, descriptors)
+		},
+	)
 }
 
 type requestResponseNamesMatchRPCVisitor struct {
 	baseAddVisitor
+
+	requestNameTemplate  string
+	responseNameTemplate string
+	err                  error
 }
 
-func (v requestResponseNamesMatchRPCVisitor) VisitService(service *proto.Service) {
+func (v *requestResponseNamesMatchRPCVisitor) OnStart(*proto.Proto) error {
+	v.err = nil
+	return nil
+}
+
+func (v *requestResponseNamesMatchRPCVisitor) VisitService(service *proto.Service) {
 	for _, child := range service.Elements {
-		child.Accept(v)
+		rpc, ok := child.(*proto.RPC)
+		if !ok {
+			continue
+		}
+		// TODO: toCamelCase for rpc.Name
+		requestName, err := protostrs.RPCTypeName(v.requestNameTemplate, service.Name, rpc.Name)
+		if err != nil {
+			v.err = err
+			return
+		}
+		responseName, err := protostrs.RPCTypeName(v.responseNameTemplate, service.Name, rpc.Name)
+		if err != nil {
+			v.err = err
+			return
+		}
+		if rpc.RequestType != requestName {
+			v.AddFailuref(rpc.Position, "Name of request type %q should be %q.", rpc.RequestType, requestName)
+		}
+		if rpc.ReturnsType != responseName {
+			v.AddFailuref(rpc.Position, "Name of response type %q should be %q.", rpc.ReturnsType, responseName)
+		}
 	}
 }
 
-func (v requestResponseNamesMatchRPCVisitor) VisitRPC(rpc *proto.RPC) {
-	// TODO: toCamelCase for rpc.Name
-	if rpc.RequestType != rpc.Name+"Request" {
-		v.AddFailuref(rpc.Position, "Name of request type %q should be %q.", rpc.RequestType, rpc.Name+"Request")
-	}
-	if rpc.ReturnsType != rpc.Name+"Response" {
-		v.AddFailuref(rpc.Position, "Name of response type %q should be %q.", rpc.ReturnsType, rpc.Name+"Response")
-	}
+func (v *requestResponseNamesMatchRPCVisitor) Finally() error {
+	return v.err
 }
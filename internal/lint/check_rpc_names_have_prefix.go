@@ -0,0 +1,70 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/strs"
+	"github.com/uber/prototool/internal/text"
+)
+
+// defaultRPCNamePrefixes are the default prefixes that RPC names are expected to have one of.
+var defaultRPCNamePrefixes = []string{"Create", "Delete", "Get", "List", "Update"}
+
+var rpcNamesHavePrefixLinter = newRPCNamesHavePrefixLinter(defaultRPCNamePrefixes)
+
+// newRPCNamesHavePrefixLinter returns a new RPC_NAMES_HAVE_PREFIX
+// linter for the prefixes.
+func newRPCNamesHavePrefixLinter(prefixes []string) Linter {
+	return NewLinter(
+		"RPC_NAMES_HAVE_PREFIX",
+		"Verifies that all RPC names start with one of "+strings.Join(prefixes, ", ")+" followed by a capitalized word.",
+		func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+			return runVisitor(rpcNamesHavePrefixVisitor{
+				baseAddVisitor: newBaseAddVisitor(add),
+				prefixes:       prefixes,
+			}, descriptors)
+		},
+	)
+}
+
+type rpcNamesHavePrefixVisitor struct {
+	baseAddVisitor
+
+	prefixes []string
+}
+
+func (v rpcNamesHavePrefixVisitor) VisitService(service *proto.Service) {
+	for _, child := range service.Elements {
+		child.Accept(v)
+	}
+}
+
+func (v rpcNamesHavePrefixVisitor) VisitRPC(rpc *proto.RPC) {
+	for _, prefix := range v.prefixes {
+		if strings.HasPrefix(rpc.Name, prefix) && strs.IsCapitalized(strings.TrimPrefix(rpc.Name, prefix)) {
+			return
+		}
+	}
+	v.AddFailuref(rpc.Position, "RPC name %q should start with one of %s.", rpc.Name, strings.Join(v.prefixes, ", "))
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"path"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+// defaultSensitiveFieldPatterns are the globs of the names of the fields
+// that are sensitive if no patterns are configured.
+var defaultSensitiveFieldPatterns = []string{
+	"*email*",
+	"*password*",
+	"*ssn*",
+}
+
+var sensitiveFieldsAnnotatedLinter = newSensitiveFieldsAnnotatedLinter("", nil)
+
+// newSensitiveFieldsAnnotatedLinter returns a new SENSITIVE_FIELDS_ANNOTATED
+// linter for the option that sensitive fields must set and the globs of
+// the names of sensitive fields. The linter does nothing if option is empty.
+func newSensitiveFieldsAnnotatedLinter(option string, fieldPatterns []string) Linter {
+	if len(fieldPatterns) == 0 {
+		fieldPatterns = defaultSensitiveFieldPatterns
+	}
+	return NewLinter(
+		"SENSITIVE_FIELDS_ANNOTATED",
+		"Verifies that fields whose names match the configured sensitive field patterns set the configured sensitivity option.",
+		func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+			if option == "" {
+				return nil
+			}
+			return runVisitor(&sensitiveFieldsAnnotatedVisitor{
+				baseAddVisitor: newBaseAddVisitor(add),
+				option:         option,
+				fieldPatterns:  fieldPatterns,
+			}, descriptors)
+		},
+	)
+}
+
+type sensitiveFieldsAnnotatedVisitor struct {
+	baseAddVisitor
+
+	option        string
+	fieldPatterns []string
+	pkg           string
+}
+
+func (v *sensitiveFieldsAnnotatedVisitor) OnStart(*proto.Proto) error {
+	v.pkg = ""
+	return nil
+}
+
+func (v *sensitiveFieldsAnnotatedVisitor) VisitPackage(pkg *proto.Package) {
+	v.pkg = pkg.Name
+}
+
+func (v *sensitiveFieldsAnnotatedVisitor) VisitMessage(message *proto.Message) {
+	for _, element := range message.Elements {
+		element.Accept(v)
+	}
+}
+
+func (v *sensitiveFieldsAnnotatedVisitor) VisitOneof(oneof *proto.Oneof) {
+	for _, element := range oneof.Elements {
+		element.Accept(v)
+	}
+}
+
+func (v *sensitiveFieldsAnnotatedVisitor) VisitNormalField(field *proto.NormalField) {
+	v.checkField(field.Field)
+}
+
+func (v *sensitiveFieldsAnnotatedVisitor) VisitOneofField(field *proto.OneOfField) {
+	v.checkField(field.Field)
+}
+
+func (v *sensitiveFieldsAnnotatedVisitor) VisitMapField(field *proto.MapField) {
+	v.checkField(field.Field)
+}
+
+func (v *sensitiveFieldsAnnotatedVisitor) checkField(field *proto.Field) {
+	if !v.isSensitiveField(field.Name) {
+		return
+	}
+	for _, option := range field.Options {
+		if v.isSensitiveOption(option.Name) {
+			return
+		}
+	}
+	v.AddFailuref(field.Position, "Field %q looks sensitive and should set the option (%s).", field.Name, v.option)
+}
+
+func (v *sensitiveFieldsAnnotatedVisitor) isSensitiveField(fieldName string) bool {
+	fieldName = strings.ToLower(fieldName)
+	for _, fieldPattern := range v.fieldPatterns {
+		// the pattern was validated by the settings package
+		if matched, _ := path.Match(fieldPattern, fieldName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isSensitiveOption returns true if the option as written in the file
+// could be the sensitivity option, or one of its fields.
+//
+// References are resolved against the package of the file and each of
+// its parent packages.
+func (v *sensitiveFieldsAnnotatedVisitor) isSensitiveOption(optionName string) bool {
+	if !strings.HasPrefix(optionName, "(") {
+		return false
+	}
+	name := strings.Replace(strings.Replace(optionName, "(", "", -1), ")", "", -1)
+	candidates := []string{strings.TrimPrefix(name, ".")}
+	if !strings.HasPrefix(name, ".") {
+		for pkg := v.pkg; pkg != ""; pkg = getParentPackage(pkg) {
+			candidates = append(candidates, pkg+"."+name)
+		}
+	}
+	for _, candidate := range candidates {
+		if candidate == v.option || strings.HasPrefix(candidate, v.option+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+// defaultServiceNameSuffixes are the default suffixes that service names are expected to have one of.
+var defaultServiceNameSuffixes = []string{"API", "Service"}
+
+var serviceNamesHaveSuffixLinter = newServiceNamesHaveSuffixLinter(defaultServiceNameSuffixes)
+
+// newServiceNamesHaveSuffixLinter returns a new SERVICE_NAMES_HAVE_SUFFIX
+// linter for the suffixes.
+func newServiceNamesHaveSuffixLinter(suffixes []string) Linter {
+	return NewLinter(
+		"SERVICE_NAMES_HAVE_SUFFIX",
+		"Verifies that all service names end with one of "+strings.Join(suffixes, ", ")+".",
+		func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+			return runVisitor(serviceNamesHaveSuffixVisitor{
+				baseAddVisitor: newBaseAddVisitor(add),
+				suffixes:       suffixes,
+			}, descriptors)
+		},
+	)
+}
+
+type serviceNamesHaveSuffixVisitor struct {
+	baseAddVisitor
+
+	suffixes []string
+}
+
+func (v serviceNamesHaveSuffixVisitor) VisitService(service *proto.Service) {
+	for _, suffix := range v.suffixes {
+		if strings.HasSuffix(service.Name, suffix) && service.Name != suffix {
+			return
+		}
+	}
+	v.AddFailuref(service.Position, "Service name %q should end with one of %s.", service.Name, strings.Join(v.suffixes, ", "))
+}
@@ -24,12 +24,13 @@ import (
 	"text/scanner"
 
 	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/editions"
 	"github.com/uber/prototool/internal/text"
 )
 
 var syntaxProto3Linter = NewLinter(
 	"SYNTAX_PROTO3",
-	"Verifies that the syntax is proto3.",
+	"Verifies that the syntax is proto3, or that the file uses an edition.",
 	checkSyntaxProto3,
 )
 
@@ -63,7 +64,7 @@ func (v *syntaxProto3Visitor) Finally() error {
 		v.AddFailuref(scanner.Position{Filename: v.filename}, "No syntax declaration found.")
 		return nil
 	}
-	if v.syntax.Value != "proto3" {
+	if v.syntax.Value != "proto3" && !editions.IsEdition(v.syntax.Value) {
 		v.AddFailuref(v.syntax.Position, "Syntax should be proto3 but was %q.", v.syntax.Value)
 	}
 	return nil
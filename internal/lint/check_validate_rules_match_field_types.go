@@ -0,0 +1,182 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+const validateRulesOptionName = "(validate.rules)"
+
+var (
+	validateRulesMatchFieldTypesLinter = NewLinter(
+		"VALIDATE_RULES_MATCH_FIELD_TYPES",
+		"Verifies that all protoc-gen-validate rules on fields are for the type of the field.",
+		checkValidateRulesMatchFieldTypes,
+	)
+
+	validateScalarTypes = map[string]struct{}{
+		"double":   struct{}{},
+		"float":    struct{}{},
+		"int32":    struct{}{},
+		"int64":    struct{}{},
+		"uint32":   struct{}{},
+		"uint64":   struct{}{},
+		"sint32":   struct{}{},
+		"sint64":   struct{}{},
+		"fixed32":  struct{}{},
+		"fixed64":  struct{}{},
+		"sfixed32": struct{}{},
+		"sfixed64": struct{}{},
+		"bool":     struct{}{},
+		"string":   struct{}{},
+		"bytes":    struct{}{},
+	}
+
+	// the rule types other than message that apply to Well-Known Types
+	validateWKTToRuleType = map[string]string{
+		"google.protobuf.Any":         "any",
+		"google.protobuf.Duration":    "duration",
+		"google.protobuf.Timestamp":   "timestamp",
+		"google.protobuf.DoubleValue": "double",
+		"google.protobuf.FloatValue":  "float",
+		"google.protobuf.Int64Value":  "int64",
+		"google.protobuf.UInt64Value": "uint64",
+		"google.protobuf.Int32Value":  "int32",
+		"google.protobuf.UInt32Value": "uint32",
+		"google.protobuf.BoolValue":   "bool",
+		"google.protobuf.StringValue": "string",
+		"google.protobuf.BytesValue":  "bytes",
+	}
+)
+
+func checkValidateRulesMatchFieldTypes(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+	return runVisitor(validateRulesMatchFieldTypesVisitor{baseAddVisitor: newBaseAddVisitor(add)}, descriptors)
+}
+
+type validateRulesMatchFieldTypesVisitor struct {
+	baseAddVisitor
+}
+
+func (v validateRulesMatchFieldTypesVisitor) VisitMessage(message *proto.Message) {
+	for _, element := range message.Elements {
+		element.Accept(v)
+	}
+}
+
+func (v validateRulesMatchFieldTypesVisitor) VisitOneof(oneof *proto.Oneof) {
+	for _, element := range oneof.Elements {
+		element.Accept(v)
+	}
+}
+
+func (v validateRulesMatchFieldTypesVisitor) VisitNormalField(field *proto.NormalField) {
+	if field.Repeated {
+		v.checkRuleTypes(field.Field, "repeated "+field.Type, "repeated")
+		return
+	}
+	v.checkRuleTypes(field.Field, field.Type, getValidateRuleTypes(field.Type)...)
+}
+
+func (v validateRulesMatchFieldTypesVisitor) VisitOneofField(field *proto.OneOfField) {
+	v.checkRuleTypes(field.Field, field.Type, getValidateRuleTypes(field.Type)...)
+}
+
+func (v validateRulesMatchFieldTypesVisitor) VisitMapField(field *proto.MapField) {
+	v.checkRuleTypes(field.Field, "map<"+field.KeyType+", "+field.Type+">", "map")
+}
+
+func (v validateRulesMatchFieldTypesVisitor) checkRuleTypes(field *proto.Field, fieldType string, allowedRuleTypes ...string) {
+	for _, ruleType := range getValidateRuleTypesUsed(field.Options) {
+		if !stringInSlice(ruleType, allowedRuleTypes) {
+			v.AddFailuref(field.Position, "Field %q has validate rules of type %q which do not apply to fields of type %q.", field.Name, ruleType, fieldType)
+		}
+	}
+}
+
+// getValidateRuleTypes returns the validate rule types that apply to
+// a non-repeated field of the given type.
+func getValidateRuleTypes(fieldType string) []string {
+	fieldType = strings.TrimPrefix(fieldType, ".")
+	if _, ok := validateScalarTypes[fieldType]; ok {
+		return []string{fieldType}
+	}
+	if ruleType, ok := validateWKTToRuleType[fieldType]; ok {
+		return []string{ruleType, "message"}
+	}
+	// we do not know if this is an enum or message without resolving the type
+	return []string{"enum", "message"}
+}
+
+// getValidateRuleTypesUsed returns the sorted rule types used in the
+// (validate.rules) options, for example string for (validate.rules).string.min_len.
+func getValidateRuleTypesUsed(options []*proto.Option) []string {
+	m := make(map[string]struct{})
+	for name := range getValidateRules(options) {
+		m[strings.SplitN(name, ".", 2)[0]] = struct{}{}
+	}
+	ruleTypes := make([]string, 0, len(m))
+	for ruleType := range m {
+		ruleTypes = append(ruleTypes, ruleType)
+	}
+	sort.Strings(ruleTypes)
+	return ruleTypes
+}
+
+// getValidateRules returns the map from rule name to the source value for
+// all (validate.rules) options, for example string.min_len to 1.
+//
+// Aggregate values such as (validate.rules).string = {min_len: 1} are flattened.
+func getValidateRules(options []*proto.Option) map[string]string {
+	rules := make(map[string]string)
+	for _, option := range options {
+		addValidateRules(rules, option.Name, &option.Constant)
+	}
+	return rules
+}
+
+func addValidateRules(rules map[string]string, name string, literal *proto.Literal) {
+	if name != validateRulesOptionName && !strings.HasPrefix(name, validateRulesOptionName+".") {
+		return
+	}
+	if len(literal.OrderedMap) == 0 {
+		if name != validateRulesOptionName {
+			rules[strings.TrimPrefix(name, validateRulesOptionName+".")] = literal.Source
+		}
+		return
+	}
+	for _, namedLiteral := range literal.OrderedMap {
+		addValidateRules(rules, name+"."+namedLiteral.Name, namedLiteral.Literal)
+	}
+}
+
+func stringInSlice(s string, slice []string) bool {
+	for _, e := range slice {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
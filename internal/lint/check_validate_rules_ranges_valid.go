@@ -0,0 +1,109 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"strconv"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/text"
+)
+
+var (
+	validateRulesRangesValidLinter = NewLinter(
+		"VALIDATE_RULES_RANGES_VALID",
+		"Verifies that no protoc-gen-validate minimum length, count, or size rule is greater than the corresponding maximum.",
+		checkValidateRulesRangesValid,
+	)
+
+	// the min and max rule name suffixes that apply to the same value
+	validateMinMaxSuffixes = [][2]string{
+		{"min_len", "max_len"},
+		{"min_bytes", "max_bytes"},
+		{"min_items", "max_items"},
+		{"min_pairs", "max_pairs"},
+	}
+)
+
+func checkValidateRulesRangesValid(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+	return runVisitor(validateRulesRangesValidVisitor{baseAddVisitor: newBaseAddVisitor(add)}, descriptors)
+}
+
+type validateRulesRangesValidVisitor struct {
+	baseAddVisitor
+}
+
+func (v validateRulesRangesValidVisitor) VisitMessage(message *proto.Message) {
+	for _, element := range message.Elements {
+		element.Accept(v)
+	}
+}
+
+func (v validateRulesRangesValidVisitor) VisitOneof(oneof *proto.Oneof) {
+	for _, element := range oneof.Elements {
+		element.Accept(v)
+	}
+}
+
+func (v validateRulesRangesValidVisitor) VisitNormalField(field *proto.NormalField) {
+	v.checkRanges(field.Field)
+}
+
+func (v validateRulesRangesValidVisitor) VisitOneofField(field *proto.OneOfField) {
+	v.checkRanges(field.Field)
+}
+
+func (v validateRulesRangesValidVisitor) VisitMapField(field *proto.MapField) {
+	v.checkRanges(field.Field)
+}
+
+func (v validateRulesRangesValidVisitor) checkRanges(field *proto.Field) {
+	rules := getValidateRules(field.Options)
+	for _, ruleType := range getValidateRuleTypesUsed(field.Options) {
+		for _, minMaxSuffix := range validateMinMaxSuffixes {
+			minName := ruleType + "." + minMaxSuffix[0]
+			maxName := ruleType + "." + minMaxSuffix[1]
+			minValue, ok := parseValidateRuleUint(rules, minName)
+			if !ok {
+				continue
+			}
+			maxValue, ok := parseValidateRuleUint(rules, maxName)
+			if !ok {
+				continue
+			}
+			if minValue > maxValue {
+				v.AddFailuref(field.Position, "Field %q has validate rule %s of %d which is greater than %s of %d.", field.Name, minName, minValue, maxName, maxValue)
+			}
+		}
+	}
+}
+
+func parseValidateRuleUint(rules map[string]string, name string) (uint64, bool) {
+	source, ok := rules[name]
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(source, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/settings"
+)
+
+// defaultCustomOptionNumberRanges are the field numbers that descriptor.proto
+// sets aside for custom options used within a single organization.
+var defaultCustomOptionNumberRanges = []settings.NumberRange{
+	{
+		Start: 50000,
+		End:   99999,
+	},
+}
+
+// customOptionsExtendees are the messages in google/protobuf/descriptor.proto
+// that custom options extend.
+var customOptionsExtendees = map[string]struct{}{
+	"FileOptions":           {},
+	"MessageOptions":        {},
+	"FieldOptions":          {},
+	"OneofOptions":          {},
+	"EnumOptions":           {},
+	"EnumValueOptions":      {},
+	"ServiceOptions":        {},
+	"MethodOptions":         {},
+	"ExtensionRangeOptions": {},
+}
+
+// customOptionsExtend is an extend block that defines custom options.
+type customOptionsExtend struct {
+	// the name of the extended options message without the package, such as FieldOptions
+	extendee string
+	message  *proto.Message
+}
+
+// getCustomOptionsExtends returns the extend blocks of the descriptor,
+// including nested ones, that extend a message in descriptor.proto.
+func getCustomOptionsExtends(descriptor *proto.Proto) []*customOptionsExtend {
+	pkg := ""
+	for _, element := range descriptor.Elements {
+		if protoPackage, ok := element.(*proto.Package); ok {
+			pkg = protoPackage.Name
+		}
+	}
+	var extends []*customOptionsExtend
+	var addExtends func([]proto.Visitee)
+	addExtends = func(elements []proto.Visitee) {
+		for _, element := range elements {
+			message, ok := element.(*proto.Message)
+			if !ok {
+				continue
+			}
+			if message.IsExtend {
+				if extendee := getCustomOptionsExtendee(pkg, message.Name); extendee != "" {
+					extends = append(extends, &customOptionsExtend{
+						extendee: extendee,
+						message:  message,
+					})
+				}
+				continue
+			}
+			addExtends(message.Elements)
+		}
+	}
+	addExtends(descriptor.Elements)
+	return extends
+}
+
+// getCustomOptionsExtendee returns the name of the options message without
+// the package if the extended type name refers to a message in descriptor.proto
+// that custom options extend, otherwise it returns the empty string.
+func getCustomOptionsExtendee(pkg string, typeName string) string {
+	name := strings.TrimPrefix(typeName, ".")
+	switch {
+	case strings.HasPrefix(name, "google.protobuf."):
+		name = strings.TrimPrefix(name, "google.protobuf.")
+	case pkg != "google.protobuf" || strings.HasPrefix(typeName, "."):
+		return ""
+	}
+	if _, ok := customOptionsExtendees[name]; !ok {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,638 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+// linterDoc is the documentation of a linter printed by lint explain.
+type linterDoc struct {
+	// why the rule exists
+	rationale string
+	// Protobuf that fails the rule
+	bad string
+	// Protobuf that passes the rule
+	good string
+}
+
+// idToLinterDoc is the map from linter ID to its documentation.
+//
+// Every linter in AllLinters must have an entry, this is checked by a test.
+var idToLinterDoc = map[string]linterDoc{
+	"AIP_PAGINATION_FIELDS": {
+		rationale: "Clients of paginated methods expect the same request and response fields on every List method so that they can page through results generically.",
+		bad: `message ListBooksRequest {
+  int32 max_results = 1;
+}`,
+		good: `message ListBooksRequest {
+  int32 page_size = 1;
+  string page_token = 2;
+}
+
+message ListBooksResponse {
+  repeated Book books = 1;
+  string next_page_token = 2;
+}`,
+	},
+	"AIP_REQUEST_RESPONSE_NAMES": {
+		rationale: "Consistent request and response names make it obvious which messages belong to which method, and standard methods return the resource itself instead of a wrapper.",
+		bad:       `rpc ListBooks(BooksQuery) returns (BooksResult);`,
+		good: `rpc ListBooks(ListBooksRequest) returns (ListBooksResponse);
+rpc GetBook(GetBookRequest) returns (Book);`,
+	},
+	"AIP_RESOURCES_ANNOTATED": {
+		rationale: "Resource annotations let clients and tools build and parse resource names, and the name field is the resource's unique identifier.",
+		bad: `message Book {
+  string title = 1;
+}`,
+		good: `message Book {
+  option (google.api.resource) = {
+    type: "library.example.com/Book"
+    pattern: "shelves/{shelf}/books/{book}"
+  };
+  string name = 1;
+  string title = 2;
+}`,
+	},
+	"AIP_STANDARD_METHODS_VALID": {
+		rationale: "Standard methods have well-known shapes so that clients can use any API the same way without reading its documentation.",
+		bad: `rpc GetBook(GetBookRequest) returns (GetBookResponse);
+
+message GetBookRequest {
+  string id = 1;
+}`,
+		good: `rpc GetBook(GetBookRequest) returns (Book);
+
+message GetBookRequest {
+  string name = 1;
+}`,
+	},
+	"COMMENTS_NO_C_STYLE": {
+		rationale: "Generators and documentation tools handle // comments consistently, while /* */ comments are not attached to elements the same way by every tool.",
+		bad: `/* Foo is a foo. */
+message Foo {}`,
+		good: `// Foo is a foo.
+message Foo {}`,
+	},
+	"CUSTOM_OPTIONS_HAVE_COMMENTS": {
+		rationale: "Custom options are used by other teams and read by generators and tools, so what they do and which code reads them must be documented where they are defined.",
+		bad: `extend google.protobuf.FieldOptions {
+  bool sensitive = 50000;
+}`,
+		good: `extend google.protobuf.FieldOptions {
+  // sensitive says that the field must be redacted before it is logged.
+  bool sensitive = 50000;
+}`,
+	},
+	"CUSTOM_OPTIONS_NUMBERS_IN_RANGE": {
+		rationale: "Custom options of the same options message must have unique field numbers across every file that is imported together, so organizations register a range of numbers and allocate custom options from it. Numbers 50000 to 99999 are reserved by descriptor.proto for use within a single organization.",
+		bad: `extend google.protobuf.FieldOptions {
+  // sensitive says that the field must be redacted before it is logged.
+  bool sensitive = 1000;
+}`,
+		good: `extend google.protobuf.FieldOptions {
+  // sensitive says that the field must be redacted before it is logged.
+  bool sensitive = 50000;
+}`,
+	},
+	"ENUM_FIELD_NAMES_UPPER_SNAKE_CASE": {
+		rationale: "Enum values are constants in most generated languages, and UPPER_SNAKE_CASE is the Protobuf style guide's convention for them.",
+		bad: `enum Color {
+  colorInvalid = 0;
+}`,
+		good: `enum Color {
+  COLOR_INVALID = 0;
+}`,
+	},
+	"ENUM_FIELD_NAMES_UPPERCASE": {
+		rationale: "Enum values are constants in most generated languages and are conventionally upper-case.",
+		bad: `enum Color {
+  color_invalid = 0;
+}`,
+		good: `enum Color {
+  COLOR_INVALID = 0;
+}`,
+	},
+	"ENUM_FIELD_PREFIXES": {
+		rationale: "Enum values are siblings of the enum in the package scope in C++ and other languages, so values without a prefix collide between enums in the same package.",
+		bad: `enum Color {
+  INVALID = 0;
+  RED = 1;
+}`,
+		good: `enum Color {
+  COLOR_INVALID = 0;
+  COLOR_RED = 1;
+}`,
+	},
+	"ENUM_NAMES_CAMEL_CASE": {
+		rationale: "Enum names become type names in generated code, and CamelCase is the Protobuf style guide's convention for types.",
+		bad:       `enum Traffic_Light {}`,
+		good:      `enum TrafficLight {}`,
+	},
+	"ENUM_NAMES_CAPITALIZED": {
+		rationale: "Enum names become type names in generated code, and lower-case type names are unexported in Go.",
+		bad:       `enum color {}`,
+		good:      `enum Color {}`,
+	},
+	"ENUM_ZERO_VALUES_INVALID": {
+		rationale: "The zero value is the default when a field is not set, so it should mean that no value was given instead of being a valid choice.",
+		bad: `enum Color {
+  COLOR_RED = 0;
+}`,
+		good: `enum Color {
+  COLOR_INVALID = 0;
+  COLOR_RED = 1;
+}`,
+	},
+	"ENUMS_HAVE_COMMENTS": {
+		rationale: "Comments on enums become the documentation of the generated types, and starting with the name matches Go doc comment conventions.",
+		bad:       `enum Color {}`,
+		good: `// Color is a color.
+enum Color {}`,
+	},
+	"ENUMS_NO_ALLOW_ALIAS": {
+		rationale: "Aliases make the name of a value ambiguous when converting to and from JSON and text, and are usually left over from renames that should have reserved the old name instead.",
+		bad: `enum Color {
+  option allow_alias = true;
+  COLOR_INVALID = 0;
+  COLOR_GREY = 1;
+  COLOR_GRAY = 1;
+}`,
+		good: `enum Color {
+  COLOR_INVALID = 0;
+  COLOR_GRAY = 1;
+}`,
+	},
+	"FIELD_NUMBERS_LOW_FOR_HOT_FIELDS": {
+		rationale: "Field numbers 1 to 15 are encoded in one byte, so frequently set fields should use them and rarely set fields should leave them free.",
+		bad: `message Event {
+  string debug_info = 1;
+  int64 timestamp = 16;
+}`,
+		good: `message Event {
+  int64 timestamp = 1;
+  string debug_info = 16;
+}`,
+	},
+	"FIELD_NUMBERS_NOT_IN_RESERVED_RANGE": {
+		rationale: "protoc rejects field numbers 19000 to 19999 as they are reserved for the Protocol Buffers implementation.",
+		bad: `message Foo {
+  int64 id = 19000;
+}`,
+		good: `message Foo {
+  int64 id = 1;
+}`,
+	},
+	"FIELD_NUMBERS_SEQUENTIAL": {
+		rationale: "Gaps in field numbers are usually deleted fields, which should be reserved so that the numbers are not reused with a different meaning.",
+		bad: `message Foo {
+  int64 one = 1;
+  int64 three = 3;
+}`,
+		good: `message Foo {
+  reserved 2;
+  int64 one = 1;
+  int64 three = 3;
+}`,
+	},
+	"FILE_HEADER_CANONICAL_ORDER": {
+		rationale: "A single order for imports and options keeps diffs small and is what format produces with canonical_order set.",
+		bad: `import public "foo/v1/foo.proto";
+import "bar/v1/bar.proto";`,
+		good: `import "bar/v1/bar.proto";
+import public "foo/v1/foo.proto";`,
+	},
+	"FILE_OPTIONS_EQUAL_GO_PACKAGE_PB_SUFFIX": {
+		rationale: "A pb suffix on Go package names avoids collisions between generated packages and hand-written packages with the same name.",
+		bad: `package uber.trip.v1;
+
+option go_package = "trip";`,
+		good: `package uber.trip.v1;
+
+option go_package = "trippb";`,
+	},
+	"FILE_OPTIONS_EQUAL_JAVA_MULTIPLE_FILES_TRUE": {
+		rationale: "Generating one Java file per type avoids very large outer classes and makes generated types easier to import.",
+		bad:       `option java_multiple_files = false;`,
+		good:      `option java_multiple_files = true;`,
+	},
+	"FILE_OPTIONS_EQUAL_JAVA_OUTER_CLASSNAME_PROTO_SUFFIX": {
+		rationale: "Deriving the outer class name from the file name keeps it unique and predictable, and the Proto suffix avoids collisions with the types in the file.",
+		bad: `// trip.proto
+option java_outer_classname = "Trip";`,
+		good: `// trip.proto
+option java_outer_classname = "TripProto";`,
+	},
+	"FILE_OPTIONS_EQUAL_JAVA_PACKAGE_COM_PREFIX": {
+		rationale: "Deriving the Java package from the Protobuf package keeps them in sync and follows the reverse domain name convention.",
+		bad: `package uber.trip.v1;
+
+option java_package = "trip";`,
+		good: `package uber.trip.v1;
+
+option java_package = "com.uber.trip.v1";`,
+	},
+	"FILE_OPTIONS_GO_PACKAGE_SAME_IN_DIR": {
+		rationale: "All files in a directory are generated into the same Go package, which must have a single name.",
+		bad: `// foo/a.proto
+option go_package = "foopb";
+
+// foo/b.proto
+option go_package = "barpb";`,
+		good: `// foo/a.proto
+option go_package = "foopb";
+
+// foo/b.proto
+option go_package = "foopb";`,
+	},
+	"FILE_OPTIONS_JAVA_MULTIPLE_FILES_SAME_IN_DIR": {
+		rationale: "Files in the same package should generate Java code the same way so that the generated types are imported the same way.",
+		bad: `// foo/a.proto
+option java_multiple_files = true;
+
+// foo/b.proto
+option java_multiple_files = false;`,
+		good: `// foo/a.proto
+option java_multiple_files = true;
+
+// foo/b.proto
+option java_multiple_files = true;`,
+	},
+	"FILE_OPTIONS_JAVA_PACKAGE_SAME_IN_DIR": {
+		rationale: "Files in the same Protobuf package should generate into the same Java package.",
+		bad: `// foo/a.proto
+option java_package = "com.foo";
+
+// foo/b.proto
+option java_package = "com.bar";`,
+		good: `// foo/a.proto
+option java_package = "com.foo";
+
+// foo/b.proto
+option java_package = "com.foo";`,
+	},
+	"FILE_OPTIONS_REQUIRED_FOR_LANGUAGES": {
+		rationale: "Code generators for each language need their own file options, and repositories only need the options for the languages they target.",
+		bad: `package uber.trip.v1;
+
+option go_package = "trippb";`,
+		good: `package uber.trip.v1;
+
+option csharp_namespace = "Uber.Trip.V1";
+option go_package = "trippb";`,
+	},
+	"FILE_OPTIONS_REQUIRE_GO_PACKAGE": {
+		rationale: "Without go_package, the Go package of generated code depends on how protoc is invoked.",
+		bad:       `package uber.trip.v1;`,
+		good: `package uber.trip.v1;
+
+option go_package = "trippb";`,
+	},
+	"FILE_OPTIONS_REQUIRE_JAVA_MULTIPLE_FILES": {
+		rationale: "Setting java_multiple_files explicitly makes the layout of generated Java code clear to readers.",
+		bad:       `package uber.trip.v1;`,
+		good: `package uber.trip.v1;
+
+option java_multiple_files = true;`,
+	},
+	"FILE_OPTIONS_REQUIRE_JAVA_OUTER_CLASSNAME": {
+		rationale: "Without java_outer_classname, the outer class is derived from the file name and may collide with a type in the file.",
+		bad:       `package uber.trip.v1;`,
+		good: `package uber.trip.v1;
+
+option java_outer_classname = "TripProto";`,
+	},
+	"FILE_OPTIONS_REQUIRE_JAVA_PACKAGE": {
+		rationale: "Without java_package, the Java package is the Protobuf package, which does not follow the reverse domain name convention.",
+		bad:       `package uber.trip.v1;`,
+		good: `package uber.trip.v1;
+
+option java_package = "com.uber.trip.v1";`,
+	},
+	"FILE_OPTIONS_UNSET_JAVA_MULTIPLE_FILES": {
+		rationale: "Some code generators set java_multiple_files themselves and conflict with a value in the file.",
+		bad:       `option java_multiple_files = true;`,
+		good:      `option java_package = "com.uber.trip.v1";`,
+	},
+	"FILE_OPTIONS_UNSET_JAVA_OUTER_CLASSNAME": {
+		rationale: "Some code generators set java_outer_classname themselves and conflict with a value in the file.",
+		bad:       `option java_outer_classname = "TripProto";`,
+		good:      `option java_package = "com.uber.trip.v1";`,
+	},
+	"GATEWAY_HTTP_RULES_VALID": {
+		rationale: "grpc-gateway only fails on invalid google.api.http annotations at generation or request time, so they are better caught when linting.",
+		bad: `rpc GetBook(GetBookRequest) returns (Book) {
+  option (google.api.http) = {
+    get: "books/{id}"
+    body: "*"
+  };
+}`,
+		good: `rpc GetBook(GetBookRequest) returns (Book) {
+  option (google.api.http) = {
+    get: "/v1/{name=books/*}"
+  };
+}`,
+	},
+	"IMPORTS_AND_TYPES_NOT_FORBIDDEN": {
+		rationale: "Dependencies on deprecated or internal-only files and types spread once they are in public APIs, so they are better forbidden where they are not expected, with the files that may use them listed.",
+		bad: `import "gogo.proto";
+
+message Foo {
+  google.protobuf.Any details = 1;
+}`,
+		good: `message Foo {
+  FooDetails details = 1;
+}`,
+	},
+	"LOG_EXEMPT_TYPES_NOT_IN_LOG_PACKAGES": {
+		rationale: "Messages in packages that are logged end up in log storage with all their fields, so types that must never be logged cannot be nested in them.",
+		bad: `package foo.log.v1;
+
+message PaymentEvent {
+  foo.v1.CardDetails card = 1;
+}`,
+		good: `package foo.log.v1;
+
+message PaymentEvent {
+  string card_id = 1;
+}`,
+	},
+	"MESSAGE_FIELD_NAMES_LOWER_SNAKE_CASE": {
+		rationale: "Generators convert lower_snake_case field names to the conventions of each language, and the JSON names are derived from them.",
+		bad: `message Foo {
+  int64 userId = 1;
+}`,
+		good: `message Foo {
+  int64 user_id = 1;
+}`,
+	},
+	"MESSAGE_FIELD_NAMES_LOWERCASE": {
+		rationale: "Generators convert lower-case field names to the conventions of each language, and mixed case names convert inconsistently.",
+		bad: `message Foo {
+  int64 UserID = 1;
+}`,
+		good: `message Foo {
+  int64 user_id = 1;
+}`,
+	},
+	"MESSAGE_FIELDS_MAX_COUNT": {
+		rationale: "Messages with a very large number of fields are hard to evolve and generate very large code, and usually group several concepts that should be separate messages.",
+		bad: `message Foo {
+  // more fields than the maximum
+}`,
+		good: `message Foo {
+  Address address = 1;
+  Contact contact = 2;
+}`,
+	},
+	"MESSAGE_FIELDS_NO_OPTIONAL_MESSAGES": {
+		rationale: "Fields of message types always track presence in proto3, so optional has no effect on them and only confuses readers.",
+		bad: `message Foo {
+  optional Bar bar = 1;
+}`,
+		good: `message Foo {
+  Bar bar = 1;
+  optional int64 count = 2;
+}`,
+	},
+	"MESSAGE_FIELDS_NOT_FLOATS": {
+		rationale: "Floating point numbers cannot represent many decimal values exactly, so they should not be used for values such as money.",
+		bad: `message Price {
+  double amount = 1;
+}`,
+		good: `message Price {
+  int64 units = 1;
+  int32 nanos = 2;
+}`,
+	},
+	"MESSAGE_NAMES_CAMEL_CASE": {
+		rationale: "Message names become type names in generated code, and CamelCase is the Protobuf style guide's convention for types.",
+		bad:       `message Trip_Summary {}`,
+		good:      `message TripSummary {}`,
+	},
+	"MESSAGE_NAMES_CAPITALIZED": {
+		rationale: "Message names become type names in generated code, and lower-case type names are unexported in Go.",
+		bad:       `message trip {}`,
+		good:      `message Trip {}`,
+	},
+	"MESSAGES_HAVE_COMMENTS": {
+		rationale: "Comments on messages become the documentation of the generated types, and starting with the name matches Go doc comment conventions.",
+		bad:       `message Trip {}`,
+		good: `// Trip is a trip.
+message Trip {}`,
+	},
+	"MESSAGES_HAVE_COMMENTS_EXCEPT_REQUEST_RESPONSE_TYPES": {
+		rationale: "Comments on messages become the documentation of the generated types, but request and response types are documented by their RPC.",
+		bad:       `message Trip {}`,
+		good: `// Trip is a trip.
+message Trip {}
+
+message GetTripRequest {}`,
+	},
+	"ONEOF_NAMES_LOWER_SNAKE_CASE": {
+		rationale: "Generators convert lower_snake_case oneof names to the conventions of each language, the same as field names.",
+		bad: `message Foo {
+  oneof Value {
+    int64 number = 1;
+  }
+}`,
+		good: `message Foo {
+  oneof value {
+    int64 number = 1;
+  }
+}`,
+	},
+	"PACKAGE_HAS_VERSION_SUFFIX": {
+		rationale: "Versioned packages let breaking changes be made in a new package alongside the old one, and keeping the version in the directory name makes the layout predictable.",
+		bad: `// uber/trip/trip.proto
+package uber.trip;`,
+		good: `// uber/trip/v1/trip.proto
+package uber.trip.v1;`,
+	},
+	"PACKAGE_IS_DECLARED": {
+		rationale: "Files without a package put their types in the global scope, where they can collide with the types of any other file.",
+		bad: `syntax = "proto3";
+
+message Trip {}`,
+		good: `syntax = "proto3";
+
+package uber.trip.v1;
+
+message Trip {}`,
+	},
+	"PACKAGE_LOWER_SNAKE_CASE": {
+		rationale: "Packages become namespaces or directories in generated code, and lower_snake.case maps cleanly to every language.",
+		bad:       `package Uber.TripService.v1;`,
+		good:      `package uber.trip_service.v1;`,
+	},
+	"PACKAGES_SAME_IN_DIR": {
+		rationale: "Most languages generate all files in a directory into the same package, so the files must agree on it.",
+		bad: `// trip/a.proto
+package uber.trip.v1;
+
+// trip/b.proto
+package uber.trips.v1;`,
+		good: `// trip/a.proto
+package uber.trip.v1;
+
+// trip/b.proto
+package uber.trip.v1;`,
+	},
+	"REQUEST_RESPONSE_NAMES_MATCH_RPC": {
+		rationale: "Request and response names that match the RPC make it obvious which messages belong to which method.",
+		bad:       `rpc GetTrip(TripQuery) returns (TripResult);`,
+		good:      `rpc GetTrip(GetTripRequest) returns (GetTripResponse);`,
+	},
+	"REQUEST_RESPONSE_TYPES_IN_SAME_FILE": {
+		rationale: "Keeping request and response types next to their service makes the API easy to read in one place.",
+		bad: `// trip_api.proto
+import "uber/trip/v1/messages.proto";
+
+service TripAPI {
+  rpc GetTrip(GetTripRequest) returns (GetTripResponse);
+}`,
+		good: `// trip_api.proto
+service TripAPI {
+  rpc GetTrip(GetTripRequest) returns (GetTripResponse);
+}
+
+message GetTripRequest {}
+
+message GetTripResponse {}`,
+	},
+	"REQUEST_RESPONSE_TYPES_UNIQUE": {
+		rationale: "Sharing request or response types between RPCs means that adding a field for one RPC changes the others.",
+		bad: `rpc GetTrip(TripRequest) returns (Trip);
+rpc DeleteTrip(TripRequest) returns (Trip);`,
+		good: `rpc GetTrip(GetTripRequest) returns (GetTripResponse);
+rpc DeleteTrip(DeleteTripRequest) returns (DeleteTripResponse);`,
+	},
+	"RPC_NAMES_CAMEL_CASE": {
+		rationale: "RPC names become method names in generated code, and CamelCase is the Protobuf style guide's convention for them.",
+		bad:       `rpc get_trip(GetTripRequest) returns (GetTripResponse);`,
+		good:      `rpc GetTrip(GetTripRequest) returns (GetTripResponse);`,
+	},
+	"RPC_NAMES_CAPITALIZED": {
+		rationale: "RPC names become method names in generated code, and lower-case method names are unexported in Go.",
+		bad:       `rpc getTrip(GetTripRequest) returns (GetTripResponse);`,
+		good:      `rpc GetTrip(GetTripRequest) returns (GetTripResponse);`,
+	},
+	"RPC_NAMES_HAVE_PREFIX": {
+		rationale: "Starting RPC names with a standard verb makes it clear what each method does to which resource.",
+		bad:       `rpc TripDetails(TripDetailsRequest) returns (TripDetailsResponse);`,
+		good:      `rpc GetTrip(GetTripRequest) returns (GetTripResponse);`,
+	},
+	"RPCS_HAVE_COMMENTS": {
+		rationale: "Comments on RPCs become the documentation of the generated methods, and starting with the name matches Go doc comment conventions.",
+		bad:       `rpc GetTrip(GetTripRequest) returns (GetTripResponse);`,
+		good: `// GetTrip gets a trip.
+rpc GetTrip(GetTripRequest) returns (GetTripResponse);`,
+	},
+	"SENSITIVE_FIELDS_ANNOTATED": {
+		rationale: "An annotation on each sensitive field lets privacy reviews, log redaction, and data retention tooling find the fields without relying on names.",
+		bad:       `string email = 1;`,
+		good:      `string email = 1 [(foo.v1.sensitive) = true];`,
+	},
+	"SERVICE_NAMES_CAMEL_CASE": {
+		rationale: "Service names become type names in generated code, and CamelCase is the Protobuf style guide's convention for types.",
+		bad:       `service Trip_API {}`,
+		good:      `service TripAPI {}`,
+	},
+	"SERVICE_NAMES_CAPITALIZED": {
+		rationale: "Service names become type names in generated code, and lower-case type names are unexported in Go.",
+		bad:       `service tripAPI {}`,
+		good:      `service TripAPI {}`,
+	},
+	"SERVICE_NAMES_HAVE_SUFFIX": {
+		rationale: "A standard suffix distinguishes generated service types from the message types of the same resource.",
+		bad:       `service Trip {}`,
+		good:      `service TripAPI {}`,
+	},
+	"SERVICES_HAVE_COMMENTS": {
+		rationale: "Comments on services become the documentation of the generated clients and servers, and starting with the name matches Go doc comment conventions.",
+		bad:       `service TripAPI {}`,
+		good: `// TripAPI manages trips.
+service TripAPI {}`,
+	},
+	"SYNTAX_PROTO3": {
+		rationale: "proto2 features such as required fields and default values make schemas hard to evolve and are not supported by every language.",
+		bad:       `syntax = "proto2";`,
+		good:      `syntax = "proto3";`,
+	},
+	"VALIDATE_RULES_MATCH_FIELD_TYPES": {
+		rationale: "protoc-gen-validate fails to generate code when a rule does not match the type of its field.",
+		bad: `message Foo {
+  string name = 1 [(validate.rules).int64.gt = 0];
+}`,
+		good: `message Foo {
+  string name = 1 [(validate.rules).string.min_len = 1];
+}`,
+	},
+	"VALIDATE_RULES_RANGES_VALID": {
+		rationale: "A minimum that is greater than the maximum can never be satisfied, so every message fails validation.",
+		bad: `message Foo {
+  string name = 1 [(validate.rules).string = {min_len: 10, max_len: 5}];
+}`,
+		good: `message Foo {
+  string name = 1 [(validate.rules).string = {min_len: 5, max_len: 10}];
+}`,
+	},
+	"WKT_DIRECTLY_IMPORTED": {
+		rationale: "The Well-Known Types are only found by generators and other tools when imported from google/protobuf.",
+		bad:       `import "timestamp.proto";`,
+		good:      `import "google/protobuf/timestamp.proto";`,
+	},
+}
+
+// idToFix is the map from linter ID to the command that fixes failures
+// of the linter. Failures of linters without an entry are fixed by hand.
+var idToFix = map[string]string{
+	"ENUM_FIELD_PREFIXES":                                  "prototool migrate enums -w",
+	"ENUM_ZERO_VALUES_INVALID":                             "prototool migrate enums -w",
+	"FILE_HEADER_CANONICAL_ORDER":                          "prototool format -w with format.canonical_order",
+	"FILE_OPTIONS_EQUAL_GO_PACKAGE_PB_SUFFIX":              "prototool format -w",
+	"FILE_OPTIONS_EQUAL_JAVA_MULTIPLE_FILES_TRUE":          "prototool format -w",
+	"FILE_OPTIONS_EQUAL_JAVA_OUTER_CLASSNAME_PROTO_SUFFIX": "prototool format -w",
+	"FILE_OPTIONS_EQUAL_JAVA_PACKAGE_COM_PREFIX":           "prototool format -w",
+	"FILE_OPTIONS_GO_PACKAGE_SAME_IN_DIR":                  "prototool format -w",
+	"FILE_OPTIONS_JAVA_MULTIPLE_FILES_SAME_IN_DIR":         "prototool format -w",
+	"FILE_OPTIONS_JAVA_PACKAGE_SAME_IN_DIR":                "prototool format -w",
+	"FILE_OPTIONS_REQUIRED_FOR_LANGUAGES":                  "prototool format -w",
+	"FILE_OPTIONS_REQUIRE_GO_PACKAGE":                      "prototool format -w",
+	"FILE_OPTIONS_REQUIRE_JAVA_MULTIPLE_FILES":             "prototool format -w",
+	"FILE_OPTIONS_REQUIRE_JAVA_OUTER_CLASSNAME":            "prototool format -w",
+	"FILE_OPTIONS_REQUIRE_JAVA_PACKAGE":                    "prototool format -w",
+}
+
+// idToSettings is the map from linter ID to the config keys that configure
+// the linter, which must match configureLinter.
+var idToSettings = map[string][]string{
+	"CUSTOM_OPTIONS_NUMBERS_IN_RANGE":      {"lint.options.number_ranges"},
+	"ENUM_FIELD_PREFIXES":                  {"lint.enums.value_prefix"},
+	"ENUM_ZERO_VALUES_INVALID":             {"lint.enums.value_prefix", "lint.enums.zero_value_suffix"},
+	"FIELD_NUMBERS_LOW_FOR_HOT_FIELDS":     {"lint.fields.hot_fields"},
+	"FILE_OPTIONS_REQUIRED_FOR_LANGUAGES":  {"languages"},
+	"IMPORTS_AND_TYPES_NOT_FORBIDDEN":      {"lint.forbidden.imports", "lint.forbidden.types"},
+	"LOG_EXEMPT_TYPES_NOT_IN_LOG_PACKAGES": {"lint.sensitive.log_exempt_types", "lint.sensitive.log_packages"},
+	"MESSAGE_FIELDS_MAX_COUNT":             {"lint.fields.max_per_message"},
+	"PACKAGE_HAS_VERSION_SUFFIX":           {"lint.packages.stable_versions_only"},
+	"REQUEST_RESPONSE_NAMES_MATCH_RPC":     {"lint.naming.request_template", "lint.naming.response_template"},
+	"RPC_NAMES_HAVE_PREFIX":                {"lint.naming.rpc_prefixes"},
+	"SENSITIVE_FIELDS_ANNOTATED":           {"lint.sensitive.option", "lint.sensitive.field_patterns"},
+	"SERVICE_NAMES_HAVE_SUFFIX":            {"lint.naming.service_suffixes"},
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/settings"
+)
+
+func TestLinterDocs(t *testing.T) {
+	ids := make(map[string]struct{}, len(AllLinters))
+	for _, linter := range AllLinters {
+		ids[linter.ID()] = struct{}{}
+		doc, ok := idToLinterDoc[linter.ID()]
+		if assert.True(t, ok, "no documentation for %s", linter.ID()) {
+			assert.NotEmpty(t, doc.rationale, linter.ID())
+			assert.NotEmpty(t, doc.bad, linter.ID())
+			assert.NotEmpty(t, doc.good, linter.ID())
+		}
+	}
+	for id := range idToLinterDoc {
+		_, ok := ids[id]
+		assert.True(t, ok, "documentation for unknown linter %s", id)
+	}
+	for id := range idToFix {
+		_, ok := ids[id]
+		assert.True(t, ok, "fix for unknown linter %s", id)
+	}
+	for id := range idToSettings {
+		_, ok := ids[id]
+		assert.True(t, ok, "settings for unknown linter %s", id)
+	}
+}
+
+func TestAllMetadata(t *testing.T) {
+	metadatas := AllMetadata()
+	require.Len(t, metadatas, len(AllLinters))
+	idToMetadata := make(map[string]*Metadata, len(metadatas))
+	for i, metadata := range metadatas {
+		if i > 0 {
+			assert.True(t, metadatas[i-1].ID < metadata.ID)
+		}
+		assert.NotEmpty(t, metadata.Purpose, metadata.ID)
+		idToMetadata[metadata.ID] = metadata
+	}
+	assert.Equal(
+		t,
+		&Metadata{
+			ID:       "SERVICE_NAMES_HAVE_SUFFIX",
+			Purpose:  serviceNamesHaveSuffixLinter.Purpose(),
+			Groups:   []string{AllGroup},
+			Settings: []string{"lint.naming.service_suffixes"},
+		},
+		idToMetadata["SERVICE_NAMES_HAVE_SUFFIX"],
+	)
+	assert.Equal(t, "prototool migrate enums -w", idToMetadata["ENUM_FIELD_PREFIXES"].Fix)
+	assert.Empty(t, idToMetadata["ENUM_NAMES_CAPITALIZED"].Fix)
+	assert.Empty(t, idToMetadata["ENUM_NAMES_CAPITALIZED"].Settings)
+}
+
+func TestExplain(t *testing.T) {
+	explanation, err := Explain("service_names_have_suffix", settings.LintConfig{ServiceNameSuffixes: []string{"API"}})
+	require.NoError(t, err)
+	assert.Equal(t, "SERVICE_NAMES_HAVE_SUFFIX", explanation.ID)
+	assert.Equal(t, "Verifies that all service names end with one of API.", explanation.Purpose)
+	assert.Equal(t, []string{AllGroup}, explanation.Groups)
+	assert.NotEmpty(t, explanation.Rationale)
+
+	explanation, err = Explain("ENUM_NAMES_CAPITALIZED", settings.LintConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{AIPGroup, AllGroup, DefaultGroup, GatewayGroup, OptionsGroup, ValidateGroup}, explanation.Groups)
+
+	explanation, err = Explain("CUSTOM_OPTIONS_NUMBERS_IN_RANGE", settings.LintConfig{CustomOptionNumberRanges: []settings.NumberRange{{Start: 50000, End: 50999}, {Start: 70000, End: 70000}}})
+	require.NoError(t, err)
+	assert.Equal(t, "Verifies that all custom options use field numbers in 50000 to 50999 or 70000.", explanation.Purpose)
+	assert.Equal(t, []string{AllGroup, OptionsGroup}, explanation.Groups)
+
+	_, err = Explain("NOT_A_LINTER", settings.LintConfig{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"strings"
+	"text/scanner"
+
+	"github.com/emicklei/proto"
+)
+
+// messageField is a field of a message, including fields in oneofs,
+// map fields, and groups.
+type messageField struct {
+	position scanner.Position
+	name     string
+	number   int
+}
+
+// getMessageFields returns the fields of the message in declaration order.
+func getMessageFields(message *proto.Message) []*messageField {
+	var messageFields []*messageField
+	for _, element := range message.Elements {
+		switch element := element.(type) {
+		case *proto.NormalField:
+			messageFields = append(messageFields, newMessageField(element.Field))
+		case *proto.MapField:
+			messageFields = append(messageFields, newMessageField(element.Field))
+		case *proto.Oneof:
+			for _, oneofElement := range element.Elements {
+				switch oneofElement := oneofElement.(type) {
+				case *proto.OneOfField:
+					messageFields = append(messageFields, newMessageField(oneofElement.Field))
+				case *proto.Group:
+					messageFields = append(messageFields, newGroupMessageField(oneofElement))
+				}
+			}
+		case *proto.Group:
+			messageFields = append(messageFields, newGroupMessageField(element))
+		}
+	}
+	return messageFields
+}
+
+func newMessageField(field *proto.Field) *messageField {
+	return &messageField{
+		position: field.Position,
+		name:     field.Name,
+		number:   field.Sequence,
+	}
+}
+
+func newGroupMessageField(group *proto.Group) *messageField {
+	return &messageField{
+		position: group.Position,
+		// the field name of a group is the lowercased group name
+		name:   strings.ToLower(group.Name),
+		number: group.Sequence,
+	}
+}
+
+// getMessageReservedRanges returns the reserved and extension ranges of the message.
+func getMessageReservedRanges(message *proto.Message) []proto.Range {
+	var ranges []proto.Range
+	for _, element := range message.Elements {
+		switch element := element.(type) {
+		case *proto.Reserved:
+			ranges = append(ranges, element.Ranges...)
+		case *proto.Extensions:
+			ranges = append(ranges, element.Ranges...)
+		}
+	}
+	return ranges
+}
@@ -21,21 +21,34 @@
 package lint
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/emicklei/proto"
 	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/protostrs"
 	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/strs"
+	"github.com/uber/prototool/internal/syntax"
 	"github.com/uber/prototool/internal/text"
+	"github.com/uber/prototool/internal/timing"
 	"go.uber.org/zap"
 )
 
 var (
 	// AllLinters is the slice of all known Linters.
 	AllLinters = []Linter{
+		aipPaginationFieldsLinter,
+		aipRequestResponseNamesLinter,
+		aipResourcesAnnotatedLinter,
+		aipStandardMethodsValidLinter,
 		commentsNoCStyleLinter,
+		customOptionsHaveCommentsLinter,
+		customOptionsNumbersInRangeLinter,
 		enumFieldNamesUppercaseLinter,
 		enumFieldNamesUpperSnakeCaseLinter,
 		enumFieldPrefixesLinter,
@@ -44,6 +57,10 @@ var (
 		enumZeroValuesInvalidLinter,
 		enumsHaveCommentsLinter,
 		enumsNoAllowAliasLinter,
+		fieldNumbersLowForHotFieldsLinter,
+		fieldNumbersNotInReservedRangeLinter,
+		fieldNumbersSequentialLinter,
+		fileHeaderCanonicalOrderLinter,
 		fileOptionsEqualGoPackagePbSuffixLinter,
 		fileOptionsEqualJavaMultipleFilesTrueLinter,
 		fileOptionsEqualJavaOuterClassnameProtoSuffixLinter,
@@ -55,8 +72,14 @@ var (
 		fileOptionsRequireJavaMultipleFilesLinter,
 		fileOptionsRequireJavaOuterClassnameLinter,
 		fileOptionsRequireJavaPackageLinter,
+		fileOptionsRequiredForLanguagesLinter,
 		fileOptionsUnsetJavaMultipleFilesLinter,
 		fileOptionsUnsetJavaOuterClassnameLinter,
+		gatewayHTTPRulesValidLinter,
+		importsAndTypesNotForbiddenLinter,
+		logExemptTypesNotInLogPackagesLinter,
+		messageFieldsMaxCountLinter,
+		messageFieldsNoOptionalMessagesLinter,
 		messageFieldsNotFloatsLinter,
 		messageFieldNamesLowerSnakeCaseLinter,
 		messageFieldNamesLowercaseLinter,
@@ -65,48 +88,140 @@ var (
 		messagesHaveCommentsLinter,
 		messagesHaveCommentsExceptRequestResponseTypesLinter,
 		oneofNamesLowerSnakeCaseLinter,
+		packageHasVersionSuffixLinter,
 		packageIsDeclaredLinter,
 		packageLowerSnakeCaseLinter,
 		packagesSameInDirLinter,
 		rpcsHaveCommentsLinter,
 		rpcNamesCamelCaseLinter,
 		rpcNamesCapitalizedLinter,
+		rpcNamesHavePrefixLinter,
 		requestResponseTypesInSameFileLinter,
 		requestResponseTypesUniqueLinter,
 		requestResponseNamesMatchRPCLinter,
+		sensitiveFieldsAnnotatedLinter,
 		servicesHaveCommentsLinter,
 		serviceNamesCamelCaseLinter,
 		serviceNamesCapitalizedLinter,
+		serviceNamesHaveSuffixLinter,
 		syntaxProto3Linter,
+		validateRulesMatchFieldTypesLinter,
+		validateRulesRangesValidLinter,
 		wktDirectlyImportedLinter,
 	}
 
 	// DefaultLinters is the slice of default Linters.
 	DefaultLinters = copyLintersWithout(
 		AllLinters,
+		aipPaginationFieldsLinter,
+		aipRequestResponseNamesLinter,
+		aipResourcesAnnotatedLinter,
+		aipStandardMethodsValidLinter,
+		customOptionsHaveCommentsLinter,
+		customOptionsNumbersInRangeLinter,
 		enumFieldNamesUppercaseLinter,
 		enumsHaveCommentsLinter,
+		fieldNumbersLowForHotFieldsLinter,
+		fieldNumbersNotInReservedRangeLinter,
+		fieldNumbersSequentialLinter,
+		fileHeaderCanonicalOrderLinter,
 		fileOptionsUnsetJavaMultipleFilesLinter,
 		fileOptionsUnsetJavaOuterClassnameLinter,
+		gatewayHTTPRulesValidLinter,
+		importsAndTypesNotForbiddenLinter,
+		logExemptTypesNotInLogPackagesLinter,
+		messageFieldsMaxCountLinter,
 		messageFieldsNotFloatsLinter,
 		messagesHaveCommentsLinter,
 		messagesHaveCommentsExceptRequestResponseTypesLinter,
 		messageFieldNamesLowercaseLinter,
+		packageHasVersionSuffixLinter,
 		requestResponseNamesMatchRPCLinter,
+		rpcNamesHavePrefixLinter,
 		rpcsHaveCommentsLinter,
+		sensitiveFieldsAnnotatedLinter,
+		serviceNamesHaveSuffixLinter,
 		servicesHaveCommentsLinter,
+		validateRulesMatchFieldTypesLinter,
+		validateRulesRangesValidLinter,
 	)
 
+	// ValidateLinters is the slice of Linters that check protoc-gen-validate rules.
+	ValidateLinters = []Linter{
+		validateRulesMatchFieldTypesLinter,
+		validateRulesRangesValidLinter,
+	}
+
+	// GatewayLinters is the slice of Linters that check grpc-gateway annotations.
+	GatewayLinters = []Linter{
+		gatewayHTTPRulesValidLinter,
+	}
+
+	// AIPLinters is the slice of Linters that check the Google API Improvement Proposals.
+	AIPLinters = []Linter{
+		aipPaginationFieldsLinter,
+		aipRequestResponseNamesLinter,
+		aipResourcesAnnotatedLinter,
+		aipStandardMethodsValidLinter,
+	}
+
+	// OptionsLinters is the slice of Linters that check custom options definitions.
+	OptionsLinters = []Linter{
+		customOptionsHaveCommentsLinter,
+		customOptionsNumbersInRangeLinter,
+	}
+
 	// DefaultGroup is the default group.
 	DefaultGroup = "default"
 
 	// AllGroup is the group of all known linters.
 	AllGroup = "all"
 
+	// ValidateGroup is the group of the default linters and the
+	// linters that check protoc-gen-validate rules.
+	ValidateGroup = "validate"
+
+	// GatewayGroup is the group of the default linters and the
+	// linters that check google.api.http annotations for grpc-gateway.
+	GatewayGroup = "gateway"
+
+	// AIPGroup is the group of the default linters and the linters that
+	// check the Google API Improvement Proposals at https://google.aip.dev.
+	//
+	// The default linters that require request and response types to be
+	// unique and in the same file as the service are not included, as
+	// standard methods return resources and google.protobuf.Empty.
+	AIPGroup = "aip"
+
+	// OptionsGroup is the group of the default linters and the linters
+	// that check files that define custom options.
+	OptionsGroup = "options"
+
+	// GroupToEnumNaming is the map from linter group to the default enum value
+	// prefix and enum zero value suffix for the group, for groups that do not
+	// use protostrs.DefaultEnumValuePrefix and protostrs.DefaultEnumZeroValueSuffix.
+	GroupToEnumNaming = map[string]EnumNaming{
+		AIPGroup: {
+			ValuePrefix:     protostrs.EnumValuePrefixEnum,
+			ZeroValueSuffix: "UNSPECIFIED",
+		},
+	}
+
 	// GroupToLinters is the map from linter group to the corresponding slice of linters.
 	GroupToLinters = map[string][]Linter{
 		DefaultGroup: DefaultLinters,
 		AllGroup:     AllLinters,
+		AIPGroup: append(
+			copyLintersWithout(
+				DefaultLinters,
+				requestResponseTypesInSameFileLinter,
+				requestResponseTypesUniqueLinter,
+			),
+			AIPLinters...,
+		),
+		GatewayGroup:  append(copyLintersWithout(DefaultLinters), GatewayLinters...),
+		OptionsGroup:  append(copyLintersWithout(DefaultLinters), OptionsLinters...),
+		ValidateGroup: append(copyLintersWithout(DefaultLinters), ValidateLinters...),
 	}
 )
 
@@ -137,6 +252,39 @@ func RunnerWithLogger(logger *zap.Logger) RunnerOption {
 	}
 }
 
+// RunnerWithTimer returns a RunnerOption that records the time spent
+// parsing and running each linter with the given timer.
+//
+// The default is to not record anything.
+func RunnerWithTimer(timer timing.Timer) RunnerOption {
+	return func(runner *runner) {
+		runner.timer = timer
+	}
+}
+
+// RunnerWithCachePath returns a RunnerOption that caches lint results
+// in the given directory, so that directories with no changes to their
+// files, the config, or the linters are not checked again.
+//
+// The default is to not cache lint results.
+func RunnerWithCachePath(cachePath string) RunnerOption {
+	return func(runner *runner) {
+		runner.cachePath = cachePath
+	}
+}
+
+// RunnerWithDescriptors returns a RunnerOption that uses the given
+// descriptors for the files with the given paths instead of parsing the
+// files, so that files already parsed by another stage are not parsed again.
+//
+// The descriptors must have been parsed with syntax.Parse using the display
+// paths of the files, and must not have any syntax errors.
+func RunnerWithDescriptors(filePathToDescriptor map[string]*proto.Proto) RunnerOption {
+	return func(runner *runner) {
+		runner.filePathToDescriptor = filePathToDescriptor
+	}
+}
+
 // NewRunner returns a new Runner.
 func NewRunner(options ...RunnerOption) Runner {
 	return newRunner(options...)
@@ -169,14 +317,223 @@ func NewLinter(id string, purpose string, addCheck func(func(*text.Failure), str
 	return newBaseLinter(id, purpose, addCheck)
 }
 
+// EnumNaming is how enum values are expected to be named.
+type EnumNaming struct {
+	// ValuePrefix is one of protostrs.EnumValuePrefixes.
+	ValuePrefix string
+	// ZeroValueSuffix is the UPPER_SNAKE_CASE suffix of enum zero values.
+	ZeroValueSuffix string
+}
+
+// GetEnumNaming returns the EnumNaming for the LintConfig.
+//
+// Values that are not set in the config default to the values for the
+// lint group in GroupToEnumNaming, and then to protostrs.DefaultEnumValuePrefix
+// and protostrs.DefaultEnumZeroValueSuffix.
+func GetEnumNaming(config settings.LintConfig) EnumNaming {
+	enumNaming, ok := GroupToEnumNaming[config.Group]
+	if !ok {
+		enumNaming = EnumNaming{
+			ValuePrefix:     protostrs.DefaultEnumValuePrefix,
+			ZeroValueSuffix: protostrs.DefaultEnumZeroValueSuffix,
+		}
+	}
+	if config.EnumValuePrefix != "" {
+		enumNaming.ValuePrefix = config.EnumValuePrefix
+	}
+	if config.EnumZeroValueSuffix != "" {
+		enumNaming.ZeroValueSuffix = config.EnumZeroValueSuffix
+	}
+	return enumNaming
+}
+
+// Explanation is the documentation of a linter.
+type Explanation struct {
+	ID      string
+	Purpose string
+	// Why the rule exists.
+	Rationale string
+	// Protobuf that fails the rule.
+	Bad string
+	// Protobuf that passes the rule.
+	Good string
+	// The sorted lint groups that contain the linter.
+	Groups []string
+}
+
+// Explain returns the Explanation for the linter with the ID.
+//
+// The purpose is that of the linter configured for the LintConfig, for
+// example with the configured enum naming or service name suffixes.
+func Explain(id string, config settings.LintConfig) (*Explanation, error) {
+	id = strings.ToUpper(id)
+	var linter Linter
+	for _, iLinter := range AllLinters {
+		if iLinter.ID() == id {
+			linter = iLinter
+			break
+		}
+	}
+	if linter == nil {
+		return nil, fmt.Errorf("unknown lint ID: %s", id)
+	}
+	doc := idToLinterDoc[id]
+	explanation := &Explanation{
+		ID:        id,
+		Purpose:   configureLinter(linter, config).Purpose(),
+		Rationale: doc.rationale,
+		Bad:       doc.bad,
+		Good:      doc.good,
+	}
+	explanation.Groups = getGroups(linter)
+	return explanation, nil
+}
+
+// Metadata is the metadata of a linter, for generating documentation and
+// config UIs.
+type Metadata struct {
+	ID      string `json:"id,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+	// The sorted lint groups that contain the linter.
+	Groups []string `json:"groups,omitempty"`
+	// The command that fixes failures of the linter, or empty if failures
+	// are fixed by hand.
+	Fix string `json:"fix,omitempty"`
+	// The config keys that configure the linter, if any.
+	Settings []string `json:"settings,omitempty"`
+}
+
+// AllMetadata returns the Metadata of AllLinters, sorted by ID.
+//
+// The purposes are those of the unconfigured linters.
+func AllMetadata() []*Metadata {
+	metadatas := make([]*Metadata, 0, len(AllLinters))
+	for _, linter := range AllLinters {
+		metadatas = append(metadatas, &Metadata{
+			ID:       linter.ID(),
+			Purpose:  linter.Purpose(),
+			Groups:   getGroups(linter),
+			Fix:      idToFix[linter.ID()],
+			Settings: idToSettings[linter.ID()],
+		})
+	}
+	sort.Slice(metadatas, func(i int, j int) bool { return metadatas[i].ID < metadatas[j].ID })
+	return metadatas
+}
+
+// getGroups returns the sorted lint groups that contain the linter.
+func getGroups(linter Linter) []string {
+	var groups []string
+	for group, linters := range GroupToLinters {
+		for _, groupLinter := range linters {
+			if groupLinter == linter {
+				groups = append(groups, group)
+				break
+			}
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
 // GetLinters returns the Linters for the LintConfig.
 //
 // The config is expected to be valid, ie slices deduped, all upper-case,
 // and only either IDs or Group/IncludeIDs/ExcludeIDs, with no overlap between
 // IncludeIDs and ExcludeIDs.
 //
+// The enum linters check the EnumNaming for the config, and the service, RPC,
+// request, and response naming linters check the naming in the config.
+//
+// If languages are set, the file option linters of other languages are not
+// returned, and FILE_OPTIONS_REQUIRED_FOR_LANGUAGES replaces the linters that
+// each require one file option.
+//
 // If the config came from the settings package, this is already validated.
 func GetLinters(config settings.LintConfig) ([]Linter, error) {
+	linters, err := getLinters(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.Languages) > 0 {
+		linters = getLanguageLinters(linters, config.Languages)
+	}
+	configuredLinters := make([]Linter, len(linters))
+	for i, linter := range linters {
+		configuredLinters[i] = configureLinter(linter, config)
+	}
+	return configuredLinters, nil
+}
+
+// configureLinter returns the linter for the LintConfig, if the config changes
+// what the linter checks, otherwise it returns the linter.
+func configureLinter(linter Linter, config settings.LintConfig) Linter {
+	switch linter {
+	case customOptionsNumbersInRangeLinter:
+		if len(config.CustomOptionNumberRanges) > 0 {
+			return newCustomOptionsNumbersInRangeLinter(config.CustomOptionNumberRanges)
+		}
+	case enumFieldPrefixesLinter:
+		if enumNaming := GetEnumNaming(config); enumNaming.ValuePrefix != protostrs.DefaultEnumValuePrefix {
+			return newEnumFieldPrefixesLinter(enumNaming.ValuePrefix)
+		}
+	case enumZeroValuesInvalidLinter:
+		if enumNaming := GetEnumNaming(config); enumNaming.ValuePrefix != protostrs.DefaultEnumValuePrefix || enumNaming.ZeroValueSuffix != protostrs.DefaultEnumZeroValueSuffix {
+			return newEnumZeroValuesInvalidLinter(enumNaming.ValuePrefix, enumNaming.ZeroValueSuffix)
+		}
+	case fieldNumbersLowForHotFieldsLinter:
+		if len(config.HotFields) > 0 {
+			return newFieldNumbersLowForHotFieldsLinter(config.HotFields)
+		}
+	case fileOptionsRequiredForLanguagesLinter:
+		if len(config.Languages) > 0 {
+			return newFileOptionsRequiredForLanguagesLinter(config.Languages)
+		}
+	case importsAndTypesNotForbiddenLinter:
+		if len(config.ForbiddenImports) > 0 || len(config.ForbiddenTypes) > 0 {
+			return newImportsAndTypesNotForbiddenLinter(config.ForbiddenImports, config.ForbiddenTypes)
+		}
+	case logExemptTypesNotInLogPackagesLinter:
+		if len(config.LogExemptTypes) > 0 || len(config.LogPackages) > 0 {
+			return newLogExemptTypesNotInLogPackagesLinter(config.LogExemptTypes, config.LogPackages)
+		}
+	case messageFieldsMaxCountLinter:
+		if config.MaxFieldsPerMessage > 0 {
+			return newMessageFieldsMaxCountLinter(config.MaxFieldsPerMessage)
+		}
+	case packageHasVersionSuffixLinter:
+		if config.StablePackageVersionsOnly {
+			return newPackageHasVersionSuffixLinter(true)
+		}
+	case requestResponseNamesMatchRPCLinter:
+		if config.RequestNameTemplate != "" || config.ResponseNameTemplate != "" {
+			requestNameTemplate := protostrs.DefaultRequestNameTemplate
+			if config.RequestNameTemplate != "" {
+				requestNameTemplate = config.RequestNameTemplate
+			}
+			responseNameTemplate := protostrs.DefaultResponseNameTemplate
+			if config.ResponseNameTemplate != "" {
+				responseNameTemplate = config.ResponseNameTemplate
+			}
+			return newRequestResponseNamesMatchRPCLinter(requestNameTemplate, responseNameTemplate)
+		}
+	case rpcNamesHavePrefixLinter:
+		if len(config.RPCNamePrefixes) > 0 {
+			return newRPCNamesHavePrefixLinter(config.RPCNamePrefixes)
+		}
+	case sensitiveFieldsAnnotatedLinter:
+		if config.SensitiveOption != "" {
+			return newSensitiveFieldsAnnotatedLinter(config.SensitiveOption, config.SensitiveFieldPatterns)
+		}
+	case serviceNamesHaveSuffixLinter:
+		if len(config.ServiceNameSuffixes) > 0 {
+			return newServiceNamesHaveSuffixLinter(config.ServiceNameSuffixes)
+		}
+	}
+	return linter
+}
+
+func getLinters(config settings.LintConfig) ([]Linter, error) {
 	if len(config.IDs) == 0 && (len(config.Group) == 0 || config.Group == DefaultGroup) && len(config.IncludeIDs) == 0 && len(config.ExcludeIDs) == 0 {
 		return DefaultLinters, nil
 	}
@@ -225,37 +582,105 @@ func GetLinters(config settings.LintConfig) ([]Linter, error) {
 	return linters, nil
 }
 
+// getOverrideLinters returns the configured linters that the overrides of
+// the LintConfig include that are not in linters.
+func getOverrideLinters(config settings.LintConfig, linters []Linter) ([]Linter, error) {
+	ids := make(map[string]struct{}, len(linters))
+	for _, linter := range linters {
+		ids[linter.ID()] = struct{}{}
+	}
+	var overrideLinters []Linter
+	for _, override := range config.Overrides {
+		for _, id := range append(append([]string{}, override.IncludeIDs...), override.ExcludeIDs...) {
+			var overrideLinter Linter
+			for _, linter := range AllLinters {
+				if linter.ID() == id {
+					overrideLinter = linter
+					break
+				}
+			}
+			if overrideLinter == nil {
+				return nil, fmt.Errorf("unknown lint ID in overrides for path %s: %s", override.Pattern, id)
+			}
+			if _, ok := ids[id]; ok || !containsString(override.IncludeIDs, id) {
+				continue
+			}
+			ids[id] = struct{}{}
+			overrideLinters = append(overrideLinters, configureLinter(overrideLinter, config))
+		}
+	}
+	return overrideLinters, nil
+}
+
 // GetDirPathToDescriptors is a convenience function that gets the
 // descriptors for the given ProtoSet.
+//
+// If any file has syntax errors, an error with all of the syntax errors
+// is returned.
 func GetDirPathToDescriptors(protoSet *file.ProtoSet) (map[string][]*proto.Proto, error) {
+	dirPathToDescriptors, failures, err := getDirPathToDescriptors(protoSet, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(failures) > 0 {
+		text.SortFailures(failures)
+		lines := make([]string, len(failures))
+		for i, failure := range failures {
+			lines[i] = failure.String()
+		}
+		return nil, errors.New(strings.Join(lines, "\n"))
+	}
+	return dirPathToDescriptors, nil
+}
+
+// getDirPathToDescriptors gets the descriptors for the given ProtoSet,
+// and the syntax errors of all of the files as failures. Files with
+// syntax errors do not have descriptors.
+//
+// Files in filePathToDescriptor are not parsed again.
+func getDirPathToDescriptors(protoSet *file.ProtoSet, filePathToDescriptor map[string]*proto.Proto) (map[string][]*proto.Proto, []*text.Failure, error) {
 	dirPathToDescriptors := make(map[string][]*proto.Proto, len(protoSet.DirPathToFiles))
+	var failures []*text.Failure
 	for dirPath, protoFiles := range protoSet.DirPathToFiles {
-		descriptors := make([]*proto.Proto, len(protoFiles))
-		for i, protoFile := range protoFiles {
+		descriptors := make([]*proto.Proto, 0, len(protoFiles))
+		for _, protoFile := range protoFiles {
+			if descriptor, ok := filePathToDescriptor[protoFile.Path]; ok {
+				descriptors = append(descriptors, descriptor)
+				continue
+			}
 			file, err := os.Open(protoFile.Path)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
-			parser := proto.NewParser(file)
-			parser.Filename(protoFile.DisplayPath)
-			descriptor, err := parser.Parse()
+			descriptor, fileFailures, err := syntax.Parse(file, protoFile.DisplayPath)
 			_ = file.Close()
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
-			descriptors[i] = descriptor
+			if len(fileFailures) > 0 {
+				failures = append(failures, fileFailures...)
+				continue
+			}
+			descriptors = append(descriptors, descriptor)
 		}
 		dirPathToDescriptors[dirPath] = descriptors
 	}
-	return dirPathToDescriptors, nil
+	return dirPathToDescriptors, failures, nil
 }
 
 // CheckMultiple is a convenience function that checks multiple linters and multiple descriptors.
 func CheckMultiple(linters []Linter, dirPathToDescriptors map[string][]*proto.Proto, ignoreIDToFilePaths map[string][]string) ([]*text.Failure, error) {
+	return checkMultiple(linters, dirPathToDescriptors, &fileFilter{ignoreIDToFilePaths: ignoreIDToFilePaths}, timing.NewNopTimer())
+}
+
+// checkMultiple records the time spent in each linter as the phase "lint ID".
+func checkMultiple(linters []Linter, dirPathToDescriptors map[string][]*proto.Proto, filter *fileFilter, timer timing.Timer) ([]*text.Failure, error) {
 	var allFailures []*text.Failure
 	for dirPath, descriptors := range dirPathToDescriptors {
 		for _, linter := range linters {
-			failures, err := checkOne(linter, dirPath, descriptors, ignoreIDToFilePaths)
+			stop := timer.Start("lint " + linter.ID())
+			failures, err := checkOne(linter, dirPath, descriptors, filter)
+			stop()
 			if err != nil {
 				return nil, err
 			}
@@ -266,18 +691,18 @@ func CheckMultiple(linters []Linter, dirPathToDescriptors map[string][]*proto.Pr
 	return allFailures, nil
 }
 
-func checkOne(linter Linter, dirPath string, descriptors []*proto.Proto, ignoreIDToFilePaths map[string][]string) ([]*text.Failure, error) {
-	filteredDescriptors, err := filterIgnores(linter, descriptors, ignoreIDToFilePaths)
+func checkOne(linter Linter, dirPath string, descriptors []*proto.Proto, filter *fileFilter) ([]*text.Failure, error) {
+	filteredDescriptors, err := filterIgnores(linter, descriptors, filter)
 	if err != nil {
 		return nil, err
 	}
 	return linter.Check(dirPath, filteredDescriptors)
 }
 
-func filterIgnores(linter Linter, descriptors []*proto.Proto, ignoreIDToFilePaths map[string][]string) ([]*proto.Proto, error) {
+func filterIgnores(linter Linter, descriptors []*proto.Proto, filter *fileFilter) ([]*proto.Proto, error) {
 	var filteredDescriptors []*proto.Proto
 	for _, descriptor := range descriptors {
-		ignore, err := shouldIgnore(linter, descriptor, ignoreIDToFilePaths)
+		ignore, err := filter.shouldIgnore(linter, descriptor)
 		if err != nil {
 			return nil, err
 		}
@@ -288,7 +713,34 @@ func filterIgnores(linter Linter, descriptors []*proto.Proto, ignoreIDToFilePath
 	return filteredDescriptors, nil
 }
 
-func shouldIgnore(linter Linter, descriptor *proto.Proto, ignoreIDToFilePaths map[string][]string) (bool, error) {
+// fileFilter says which files each linter checks.
+type fileFilter struct {
+	ignoreIDToFilePaths map[string][]string
+	// configDirPath is the directory that the patterns of the overrides
+	// are relative to.
+	configDirPath string
+	overrides     []settings.LintOverride
+	// ids are the IDs of the linters that check the files that no
+	// override matches.
+	ids map[string]struct{}
+}
+
+// newFileFilter returns the fileFilter for the config, where linters are
+// the linters of the LintConfig before overrides.
+func newFileFilter(config settings.Config, linters []Linter) *fileFilter {
+	ids := make(map[string]struct{}, len(linters))
+	for _, linter := range linters {
+		ids[linter.ID()] = struct{}{}
+	}
+	return &fileFilter{
+		ignoreIDToFilePaths: config.Lint.IgnoreIDToFilePaths,
+		configDirPath:       config.DirPath,
+		overrides:           config.Lint.Overrides,
+		ids:                 ids,
+	}
+}
+
+func (f *fileFilter) shouldIgnore(linter Linter, descriptor *proto.Proto) (bool, error) {
 	filePath := descriptor.Filename
 	var err error
 	if !filepath.IsAbs(filePath) {
@@ -297,16 +749,40 @@ func shouldIgnore(linter Linter, descriptor *proto.Proto, ignoreIDToFilePaths ma
 			return false, err
 		}
 	}
-	ignoreFilePaths, ok := ignoreIDToFilePaths[linter.ID()]
-	if !ok {
-		return false, nil
-	}
-	for _, ignoreFilePath := range ignoreFilePaths {
+	for _, ignoreFilePath := range f.ignoreIDToFilePaths[linter.ID()] {
 		if filePath == ignoreFilePath {
 			return true, nil
 		}
 	}
-	return false, nil
+	if len(f.overrides) == 0 {
+		return false, nil
+	}
+	_, use := f.ids[linter.ID()]
+	relFilePath, err := filepath.Rel(f.configDirPath, filePath)
+	if err != nil {
+		return false, err
+	}
+	relFilePath = filepath.ToSlash(relFilePath)
+	// overrides only match files within the config directory
+	if strings.HasPrefix(relFilePath, "../") {
+		return !use, nil
+	}
+	for _, override := range f.overrides {
+		matched, err := strs.MatchGlob(override.Pattern, relFilePath)
+		if err != nil {
+			return false, err
+		}
+		if !matched {
+			continue
+		}
+		if containsString(override.IncludeIDs, linter.ID()) {
+			use = true
+		}
+		if containsString(override.ExcludeIDs, linter.ID()) {
+			use = false
+		}
+	}
+	return !use, nil
 }
 
 func copyLintersWithout(linters []Linter, remove ...Linter) []Linter {
@@ -327,3 +803,12 @@ func linterIn(linter Linter, s []Linter) bool {
 	}
 	return false
 }
+
+func containsString(s []string, e string) bool {
+	for _, iE := range s {
+		if iE == e {
+			return true
+		}
+	}
+	return false
+}
@@ -21,18 +21,24 @@
 package lint
 
 import (
+	"github.com/emicklei/proto"
 	"github.com/uber/prototool/internal/file"
 	"github.com/uber/prototool/internal/text"
+	"github.com/uber/prototool/internal/timing"
 	"go.uber.org/zap"
 )
 
 type runner struct {
-	logger *zap.Logger
+	logger               *zap.Logger
+	timer                timing.Timer
+	cachePath            string
+	filePathToDescriptor map[string]*proto.Proto
 }
 
 func newRunner(options ...RunnerOption) *runner {
 	runner := &runner{
 		logger: zap.NewNop(),
+		timer:  timing.NewNopTimer(),
 	}
 	for _, option := range options {
 		option(runner)
@@ -45,9 +51,80 @@ func (r *runner) Run(protoSet *file.ProtoSet) ([]*text.Failure, error) {
 	if err != nil {
 		return nil, err
 	}
-	dirPathToDescriptors, err := GetDirPathToDescriptors(protoSet)
+	filter := newFileFilter(protoSet.Config, linters)
+	overrideLinters, err := getOverrideLinters(protoSet.Config.Lint, linters)
 	if err != nil {
 		return nil, err
 	}
-	return CheckMultiple(linters, dirPathToDescriptors, protoSet.Config.Lint.IgnoreIDToFilePaths)
+	linters = append(linters, overrideLinters...)
+	cache := r.newCache(protoSet, linters)
+	var failures []*text.Failure
+	for dirPath, protoFiles := range protoSet.DirPathToFiles {
+		dirFailures, err := r.runDir(protoSet, dirPath, protoFiles, linters, filter, cache)
+		if err != nil {
+			return nil, err
+		}
+		failures = append(failures, dirFailures...)
+	}
+	text.SortFailures(failures)
+	for _, failure := range failures {
+		if severity, ok := protoSet.Config.Lint.IDToSeverity[failure.ID]; ok {
+			failure.Severity = severity
+		}
+	}
+	return failures, nil
+}
+
+// runDir lints the files in one directory, using the cache if it is not nil.
+func (r *runner) runDir(protoSet *file.ProtoSet, dirPath string, protoFiles []*file.ProtoFile, linters []Linter, filter *fileFilter, cache *cache) ([]*text.Failure, error) {
+	var key string
+	if cache != nil {
+		var err error
+		key, err = cache.key(dirPath, protoFiles)
+		if err != nil {
+			return nil, err
+		}
+		if failures, ok := cache.get(key); ok {
+			r.logger.Debug("using cached lint results", zap.String("dirPath", dirPath))
+			return failures, nil
+		}
+	}
+	stopParse := r.timer.Start("parse")
+	dirPathToDescriptors, syntaxFailures, err := getDirPathToDescriptors(&file.ProtoSet{
+		WorkDirPath:    protoSet.WorkDirPath,
+		DirPath:        protoSet.DirPath,
+		DirPathToFiles: map[string][]*file.ProtoFile{dirPath: protoFiles},
+		Config:         protoSet.Config,
+	}, r.filePathToDescriptor)
+	stopParse()
+	if err != nil {
+		return nil, err
+	}
+	failures, err := checkMultiple(linters, dirPathToDescriptors, filter, r.timer)
+	if err != nil {
+		return nil, err
+	}
+	// files with syntax errors are not linted, and all of their
+	// syntax errors are reported
+	failures = append(failures, syntaxFailures...)
+	if cache != nil {
+		// the cache is best effort
+		if err := cache.put(key, failures); err != nil {
+			r.logger.Debug("could not cache lint results", zap.String("dirPath", dirPath), zap.Error(err))
+		}
+	}
+	return failures, nil
+}
+
+// newCache returns the cache to use, or nil if results should not be cached.
+func (r *runner) newCache(protoSet *file.ProtoSet, linters []Linter) *cache {
+	if r.cachePath == "" {
+		return nil
+	}
+	cache, err := newCache(r.cachePath, protoSet, linters)
+	if err != nil {
+		r.logger.Debug("not caching lint results", zap.Error(err))
+		return nil
+	}
+	return cache
 }
@@ -0,0 +1,257 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/syntax"
+)
+
+func TestRunnerCache(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	protoDirPath := filepath.Join(tmpDir, "proto")
+	cacheDirPath := filepath.Join(tmpDir, "cache")
+	require.NoError(t, os.MkdirAll(protoDirPath, 0755))
+	protoFilePath := filepath.Join(protoDirPath, "foo.proto")
+	require.NoError(t, ioutil.WriteFile(protoFilePath, []byte("syntax = \"proto3\";\n\npackage foo;\n\nmessage bar {}\n"), 0644))
+	protoSet := &file.ProtoSet{
+		WorkDirPath: tmpDir,
+		DirPath:     tmpDir,
+		DirPathToFiles: map[string][]*file.ProtoFile{
+			protoDirPath: {
+				{
+					Path:        protoFilePath,
+					DisplayPath: "proto/foo.proto",
+				},
+			},
+		},
+		Config: settings.Config{
+			DirPath: tmpDir,
+			Lint: settings.LintConfig{
+				IDs: []string{"MESSAGE_NAMES_CAPITALIZED"},
+			},
+		},
+	}
+	runner := newRunner(RunnerWithCachePath(cacheDirPath))
+
+	failures, err := runner.Run(protoSet)
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "MESSAGE_NAMES_CAPITALIZED", failures[0].ID)
+	cacheFileInfos, err := ioutil.ReadDir(cacheDirPath)
+	require.NoError(t, err)
+	assert.Len(t, cacheFileInfos, 1)
+
+	// the cached failures are returned even though the linters are not run
+	cachedFailures, err := runner.Run(protoSet)
+	require.NoError(t, err)
+	assert.Equal(t, failures, cachedFailures)
+	cacheFileInfos, err = ioutil.ReadDir(cacheDirPath)
+	require.NoError(t, err)
+	assert.Len(t, cacheFileInfos, 1)
+
+	// severities are applied after the cache
+	protoSet.Config.Lint.IDToSeverity = map[string]string{"MESSAGE_NAMES_CAPITALIZED": "warning"}
+	failures, err = runner.Run(protoSet)
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "warning", failures[0].Severity)
+	protoSet.Config.Lint.IDToSeverity = nil
+
+	require.NoError(t, ioutil.WriteFile(protoFilePath, []byte("syntax = \"proto3\";\n\npackage foo;\n\nmessage Bar {}\n"), 0644))
+	failures, err = runner.Run(protoSet)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+	cacheFileInfos, err = ioutil.ReadDir(cacheDirPath)
+	require.NoError(t, err)
+	assert.Len(t, cacheFileInfos, 3)
+}
+
+func TestRunnerOverrides(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	dirPathToFiles := make(map[string][]*file.ProtoFile)
+	for _, dirName := range []string{"api", "internal", "other"} {
+		dirPath := filepath.Join(tmpDir, dirName)
+		require.NoError(t, os.MkdirAll(dirPath, 0755))
+		filePath := filepath.Join(dirPath, "foo.proto")
+		require.NoError(t, ioutil.WriteFile(filePath, []byte("syntax = \"proto3\";\n\npackage foo;\n\nmessage bar {}\n"), 0644))
+		// the display paths are absolute as the test does not run in tmpDir
+		dirPathToFiles[dirPath] = []*file.ProtoFile{
+			{
+				Path:        filePath,
+				DisplayPath: filePath,
+			},
+		}
+	}
+	protoSet := &file.ProtoSet{
+		WorkDirPath:    tmpDir,
+		DirPath:        tmpDir,
+		DirPathToFiles: dirPathToFiles,
+		Config: settings.Config{
+			DirPath: tmpDir,
+			Lint: settings.LintConfig{
+				IDs: []string{"MESSAGE_NAMES_CAPITALIZED"},
+				Overrides: []settings.LintOverride{
+					{
+						Pattern:    "api/**",
+						IncludeIDs: []string{"MESSAGES_HAVE_COMMENTS"},
+					},
+					{
+						Pattern:    "internal/",
+						ExcludeIDs: []string{"MESSAGE_NAMES_CAPITALIZED"},
+					},
+					{
+						Pattern:    "api/foo.proto",
+						ExcludeIDs: []string{"MESSAGE_NAMES_CAPITALIZED"},
+					},
+				},
+			},
+		},
+	}
+
+	failures, err := newRunner().Run(protoSet)
+	require.NoError(t, err)
+	var fileNameAndIDs []string
+	for _, failure := range failures {
+		relFilePath, err := filepath.Rel(tmpDir, failure.Filename)
+		require.NoError(t, err)
+		fileNameAndIDs = append(fileNameAndIDs, filepath.ToSlash(relFilePath)+":"+failure.ID)
+	}
+	assert.Equal(
+		t,
+		[]string{
+			"api/foo.proto:MESSAGES_HAVE_COMMENTS",
+			"other/foo.proto:MESSAGE_NAMES_CAPITALIZED",
+		},
+		fileNameAndIDs,
+	)
+
+	protoSet.Config.Lint.Overrides = []settings.LintOverride{
+		{
+			Pattern:    "api/",
+			IncludeIDs: []string{"NOT_A_LINTER"},
+		},
+	}
+	_, err = newRunner().Run(protoSet)
+	assert.Error(t, err)
+}
+
+func TestRunnerSyntaxErrors(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	badFilePath := filepath.Join(tmpDir, "bad.proto")
+	require.NoError(t, ioutil.WriteFile(badFilePath, []byte("syntax = \"proto3\";\n\npackage foo;\n\nmessage Foo {\n  int32 bar = ;\n}\n\nmessage 1Baz {}\n"), 0644))
+	goodFilePath := filepath.Join(tmpDir, "good.proto")
+	require.NoError(t, ioutil.WriteFile(goodFilePath, []byte("syntax = \"proto3\";\n\npackage foo;\n\nmessage bar {}\n"), 0644))
+	protoSet := &file.ProtoSet{
+		WorkDirPath: tmpDir,
+		DirPath:     tmpDir,
+		DirPathToFiles: map[string][]*file.ProtoFile{
+			tmpDir: {
+				{
+					Path:        badFilePath,
+					DisplayPath: "bad.proto",
+				},
+				{
+					Path:        goodFilePath,
+					DisplayPath: "good.proto",
+				},
+			},
+		},
+		Config: settings.Config{
+			DirPath: tmpDir,
+			Lint: settings.LintConfig{
+				IDs: []string{"MESSAGE_NAMES_CAPITALIZED"},
+			},
+		},
+	}
+
+	failures, err := newRunner().Run(protoSet)
+	require.NoError(t, err)
+	var fileNameLineAndIDs []string
+	for _, failure := range failures {
+		fileNameLineAndIDs = append(fileNameLineAndIDs, fmt.Sprintf("%s:%d:%s", failure.Filename, failure.Line, failure.ID))
+	}
+	// all syntax errors of a file are reported, and other files are linted
+	assert.Equal(
+		t,
+		[]string{
+			"bad.proto:6:",
+			"bad.proto:9:",
+			"good.proto:5:MESSAGE_NAMES_CAPITALIZED",
+		},
+		fileNameLineAndIDs,
+	)
+}
+
+func TestRunnerDescriptors(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	protoFilePath := filepath.Join(tmpDir, "foo.proto")
+	require.NoError(t, ioutil.WriteFile(protoFilePath, []byte("syntax = \"proto3\";\n\npackage foo;\n\nmessage bar {}\n"), 0644))
+	protoSet := &file.ProtoSet{
+		WorkDirPath: tmpDir,
+		DirPath:     tmpDir,
+		DirPathToFiles: map[string][]*file.ProtoFile{
+			tmpDir: {
+				{
+					Path:        protoFilePath,
+					DisplayPath: "foo.proto",
+				},
+			},
+		},
+		Config: settings.Config{
+			DirPath: tmpDir,
+			Lint: settings.LintConfig{
+				IDs: []string{"MESSAGE_NAMES_CAPITALIZED"},
+			},
+		},
+	}
+	// the given descriptor is linted instead of the file
+	descriptor, syntaxFailures, err := syntax.Parse(strings.NewReader("syntax = \"proto3\";\n\npackage foo;\n\nmessage Bar {}\n"), "foo.proto")
+	require.NoError(t, err)
+	require.Empty(t, syntaxFailures)
+
+	failures, err := newRunner(RunnerWithDescriptors(map[string]*proto.Proto{protoFilePath: descriptor})).Run(protoSet)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+	failures, err = newRunner().Run(protoSet)
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "MESSAGE_NAMES_CAPITALIZED", failures[0].ID)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package metrics emits the duration and failure counts of commands to
+// statsd or a Prometheus Pushgateway, so that the health of Protobuf
+// toolchains can be tracked across CI pipelines.
+//
+// Nothing is emitted unless a Recorder is created with a URL.
+package metrics
+
+import (
+	"time"
+
+	"github.com/uber/prototool/internal/text"
+	"go.uber.org/zap"
+)
+
+// DefaultTimeout is the default timeout for emitting metrics.
+const DefaultTimeout = 5 * time.Second
+
+// Recorder records the failures found by a command and emits metrics
+// for the command when it is done.
+//
+// Recorders are safe for concurrent use.
+type Recorder interface {
+	// AddFailures adds the failures to the failures found by the command.
+	AddFailures(failures ...*text.Failure)
+	// Emit emits the duration since the Recorder was created, the number
+	// of errors and warnings added, and the exit code of the command.
+	//
+	// The command is the command path without the binary name, for
+	// example lint or break check.
+	Emit(command string, exitCode int) error
+}
+
+// RecorderOption is an option for a new Recorder.
+type RecorderOption func(*recorder)
+
+// RecorderWithLogger returns a RecorderOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func RecorderWithLogger(logger *zap.Logger) RecorderOption {
+	return func(recorder *recorder) {
+		recorder.logger = logger
+	}
+}
+
+// RecorderWithTimeout returns a RecorderOption that uses the given
+// timeout for emitting metrics.
+//
+// The default is to use DefaultTimeout.
+func RecorderWithTimeout(timeout time.Duration) RecorderOption {
+	return func(recorder *recorder) {
+		recorder.timeout = timeout
+	}
+}
+
+// NewRecorder returns a new Recorder that emits to the given URL.
+//
+// URLs of the form statsd://host:port/prefix emit statsd metrics over UDP
+// named prefix.command.name, where the prefix defaults to prototool.
+// URLs of the form http://host:port/path push Prometheus metrics to a
+// Pushgateway, grouped by the command under the path, which defaults to
+// /metrics/job/prototool.
+func NewRecorder(url string, options ...RecorderOption) (Recorder, error) {
+	return newRecorder(url, options...)
+}
+
+// NewNopRecorder returns a new Recorder that does not emit anything.
+func NewNopRecorder() Recorder {
+	return nopRecorder{}
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/prototool/internal/text"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultStatsdPrefix    = "prototool"
+	defaultPushgatewayPath = "/metrics/job/prototool"
+)
+
+// sample is the metrics for one run of a command.
+type sample struct {
+	// the command with spaces replaced by underscores, for example break_check
+	command  string
+	duration time.Duration
+	errors   int
+	warnings int
+	exitCode int
+}
+
+type recorder struct {
+	logger  *zap.Logger
+	timeout time.Duration
+
+	url      *url.URL
+	start    time.Time
+	errors   int
+	warnings int
+	lock     sync.Mutex
+}
+
+func newRecorder(rawURL string, options ...RecorderOption) (*recorder, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch parsedURL.Scheme {
+	case "statsd", "http", "https":
+	default:
+		return nil, fmt.Errorf("metrics URL must start with statsd://, http://, or https:// but was %q", rawURL)
+	}
+	if parsedURL.Host == "" {
+		return nil, fmt.Errorf("metrics URL must have a host but was %q", rawURL)
+	}
+	recorder := &recorder{
+		logger:  zap.NewNop(),
+		timeout: DefaultTimeout,
+		url:     parsedURL,
+		start:   time.Now(),
+	}
+	for _, option := range options {
+		option(recorder)
+	}
+	return recorder, nil
+}
+
+func (r *recorder) AddFailures(failures ...*text.Failure) {
+	warnings := text.CountWarnings(failures...)
+	errors := 0
+	for _, failure := range failures {
+		if failure.Severity == "" || failure.Severity == text.SeverityError {
+			errors++
+		}
+	}
+	r.lock.Lock()
+	r.errors += errors
+	r.warnings += warnings
+	r.lock.Unlock()
+}
+
+func (r *recorder) Emit(command string, exitCode int) error {
+	r.lock.Lock()
+	sample := &sample{
+		command:  strings.Replace(command, " ", "_", -1),
+		duration: time.Since(r.start),
+		errors:   r.errors,
+		warnings: r.warnings,
+		exitCode: exitCode,
+	}
+	r.lock.Unlock()
+	r.logger.Debug("emitting metrics", zap.String("command", sample.command), zap.String("scheme", r.url.Scheme))
+	if r.url.Scheme == "statsd" {
+		return r.emitStatsd(sample)
+	}
+	return r.emitPushgateway(sample)
+}
+
+func (r *recorder) emitStatsd(sample *sample) (retErr error) {
+	prefix := strings.Trim(r.url.Path, "/")
+	if prefix == "" {
+		prefix = defaultStatsdPrefix
+	}
+	conn, err := net.DialTimeout("udp", r.url.Host, r.timeout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, conn.Close())
+	}()
+	_, err = conn.Write(getStatsdData(prefix, sample))
+	return err
+}
+
+func (r *recorder) emitPushgateway(sample *sample) (retErr error) {
+	path := strings.TrimSuffix(r.url.Path, "/")
+	if path == "" {
+		path = defaultPushgatewayPath
+	}
+	pushURL := *r.url
+	pushURL.Path = path + "/command/" + sample.command
+	pushURL.RawPath = ""
+	httpRequest, err := http.NewRequest(http.MethodPut, pushURL.String(), bytes.NewReader(getPrometheusData(sample)))
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	httpResponse, err := (&http.Client{Timeout: r.timeout}).Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, httpResponse.Body.Close())
+	}()
+	// read the body so that the connection can be reused
+	if _, err := io.Copy(ioutil.Discard, httpResponse.Body); err != nil {
+		return err
+	}
+	if httpResponse.StatusCode < 200 || httpResponse.StatusCode >= 300 {
+		return fmt.Errorf("pushgateway returned %s", httpResponse.Status)
+	}
+	return nil
+}
+
+// getStatsdData returns the statsd lines for the sample, sent in one packet.
+func getStatsdData(prefix string, sample *sample) []byte {
+	buffer := bytes.NewBuffer(nil)
+	name := prefix + "." + sample.command
+	fmt.Fprintf(buffer, "%s.runs:1|c\n", name)
+	if sample.exitCode != 0 {
+		fmt.Fprintf(buffer, "%s.failed_runs:1|c\n", name)
+	}
+	fmt.Fprintf(buffer, "%s.duration:%d|ms\n", name, sample.duration/time.Millisecond)
+	fmt.Fprintf(buffer, "%s.errors:%d|g\n", name, sample.errors)
+	fmt.Fprintf(buffer, "%s.warnings:%d|g\n", name, sample.warnings)
+	return buffer.Bytes()
+}
+
+// getPrometheusData returns the Prometheus text exposition format for the
+// sample, without labels as the command is in the grouping key.
+func getPrometheusData(sample *sample) []byte {
+	buffer := bytes.NewBuffer(nil)
+	for _, metric := range []struct {
+		name  string
+		help  string
+		value string
+	}{
+		{"prototool_command_duration_seconds", "The wall time of the command.", fmt.Sprintf("%g", sample.duration.Seconds())},
+		{"prototool_command_errors", "The number of failures that are errors.", fmt.Sprintf("%d", sample.errors)},
+		{"prototool_command_warnings", "The number of failures that are warnings.", fmt.Sprintf("%d", sample.warnings)},
+		{"prototool_command_exit_code", "The exit code of the command.", fmt.Sprintf("%d", sample.exitCode)},
+	} {
+		fmt.Fprintf(buffer, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", metric.name, metric.help, metric.name, metric.name, metric.value)
+	}
+	return buffer.Bytes()
+}
+
+type nopRecorder struct{}
+
+func (nopRecorder) AddFailures(...*text.Failure) {}
+
+func (nopRecorder) Emit(string, int) error {
+	return nil
+}
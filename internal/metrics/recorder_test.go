@@ -0,0 +1,92 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/text"
+)
+
+func TestRecorderStatsd(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, conn.Close()) }()
+
+	recorder, err := NewRecorder("statsd://" + conn.LocalAddr().String() + "/ci.proto")
+	require.NoError(t, err)
+	recorder.AddFailures(&text.Failure{ID: "FOO"}, &text.Failure{ID: "BAR", Severity: text.SeverityWarning})
+	recorder.AddFailures(&text.Failure{ID: "BAZ", Severity: text.SeverityError})
+	require.NoError(t, recorder.Emit("break check", 255))
+
+	buffer := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buffer)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(buffer[:n])), "\n")
+	require.Len(t, lines, 5)
+	assert.Equal(t, "ci.proto.break_check.runs:1|c", lines[0])
+	assert.Equal(t, "ci.proto.break_check.failed_runs:1|c", lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "ci.proto.break_check.duration:"))
+	assert.Equal(t, "ci.proto.break_check.errors:2|g", lines[3])
+	assert.Equal(t, "ci.proto.break_check.warnings:1|g", lines[4])
+}
+
+func TestRecorderPushgateway(t *testing.T) {
+	var paths []string
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		data, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		paths = append(paths, r.URL.Path)
+		bodies = append(bodies, string(data))
+	}))
+	defer server.Close()
+
+	recorder, err := NewRecorder(server.URL)
+	require.NoError(t, err)
+	recorder.AddFailures(&text.Failure{ID: "FOO", Severity: text.SeverityWarning})
+	require.NoError(t, recorder.Emit("lint", 0))
+	recorder, err = NewRecorder(server.URL + "/metrics/job/ci/pipeline/protos/")
+	require.NoError(t, err)
+	require.NoError(t, recorder.Emit("compile", 0))
+
+	assert.Equal(t, []string{"/metrics/job/prototool/command/lint", "/metrics/job/ci/pipeline/protos/command/compile"}, paths)
+	require.Len(t, bodies, 2)
+	assert.Contains(t, bodies[0], "# TYPE prototool_command_duration_seconds gauge\nprototool_command_duration_seconds ")
+	assert.Contains(t, bodies[0], "\nprototool_command_errors 0\n")
+	assert.Contains(t, bodies[0], "\nprototool_command_warnings 1\n")
+	assert.Contains(t, bodies[0], "\nprototool_command_exit_code 0\n")
+}
+
+func TestNewRecorderErrors(t *testing.T) {
+	_, err := NewRecorder("udp://localhost:8125")
+	assert.Error(t, err)
+	_, err = NewRecorder("statsd:///prefix")
+	assert.Error(t, err)
+}
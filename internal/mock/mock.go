@@ -0,0 +1,86 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package mock serves mock implementations of gRPC services using only
+// the FileDescriptorSets of the services.
+package mock
+
+import (
+	"net"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"go.uber.org/zap"
+)
+
+// DefaultStreamResponseCount is the default number of responses sent
+// for server streaming methods without fixtures.
+const DefaultStreamResponseCount = 3
+
+// Server is a mock gRPC server.
+//
+// Unless there is a fixture for a method, responses are randomly generated.
+// Unary and client streaming methods send one response after all
+// requests have been received, server streaming methods send all responses
+// after the request has been received, and bidirectional streaming methods
+// send one response per request received.
+type Server interface {
+	// Serve serves every service in the FileDescriptorSets on the listener
+	// until the listener is closed or an error occurs.
+	//
+	// Server reflection is enabled for all services.
+	Serve(fileDescriptorSets []*descriptor.FileDescriptorSet, listener net.Listener) error
+}
+
+// ServerOption is an option for a new Server.
+type ServerOption func(*server)
+
+// ServerWithLogger returns a ServerOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func ServerWithLogger(logger *zap.Logger) ServerOption {
+	return func(server *server) {
+		server.logger = logger
+	}
+}
+
+// ServerWithFixtureData returns a ServerOption that uses the given YAML
+// fixture data for responses instead of randomly generated responses.
+//
+// The keys are methods of the form package.Service/Method, and the values
+// are either a single response, or a list of responses in the JSON mapping.
+// Lists with more than one response are only allowed for server and
+// bidirectional streaming methods, and bidirectional streaming methods
+// cycle through the list.
+//
+//	foo.ExcitedService/Exclamation:
+//	  value: hello!
+//	foo.ExcitedService/ExclamationServerStream:
+//	  - value: h
+//	  - value: i
+func ServerWithFixtureData(fixtureData []byte) ServerOption {
+	return func(server *server) {
+		server.fixtureData = fixtureData
+	}
+}
+
+// NewServer returns a new Server.
+func NewServer(options ...ServerOption) Server {
+	return newServer(options...)
+}
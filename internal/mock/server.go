@@ -0,0 +1,241 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mock
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/uber/prototool/internal/datagen"
+	intdesc "github.com/uber/prototool/internal/desc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+type server struct {
+	logger      *zap.Logger
+	fixtureData []byte
+
+	generator datagen.Generator
+}
+
+func newServer(options ...ServerOption) *server {
+	server := &server{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(server)
+	}
+	// TODO(pedge): composition
+	server.generator = datagen.NewGenerator(
+		datagen.GeneratorWithLogger(server.logger),
+	)
+	return server
+}
+
+func (s *server) Serve(fileDescriptorSets []*descriptor.FileDescriptorSet, listener net.Listener) error {
+	fileDescriptors, err := intdesc.CreateFileDescriptors(fileDescriptorSets...)
+	if err != nil {
+		return err
+	}
+	methodToFixtures, err := s.getMethodToFixtures(fileDescriptors)
+	if err != nil {
+		return err
+	}
+	grpcServer := grpc.NewServer()
+	for _, fileDescriptor := range fileDescriptors {
+		if err := registerFileDescriptor(fileDescriptor); err != nil {
+			return err
+		}
+		for _, serviceDescriptor := range fileDescriptor.GetServices() {
+			grpcServer.RegisterService(s.newServiceDesc(serviceDescriptor, methodToFixtures), struct{}{})
+			s.logger.Debug("registered service", zap.String("service", serviceDescriptor.GetFullyQualifiedName()))
+		}
+	}
+	reflection.Register(grpcServer)
+	return grpcServer.Serve(listener)
+}
+
+func (s *server) newServiceDesc(serviceDescriptor *desc.ServiceDescriptor, methodToFixtures map[string][]*dynamic.Message) *grpc.ServiceDesc {
+	serviceDesc := &grpc.ServiceDesc{
+		ServiceName: serviceDescriptor.GetFullyQualifiedName(),
+		HandlerType: (*interface{})(nil),
+		// the reflection service uses this to find the file for the service
+		Metadata: serviceDescriptor.GetFile().GetName(),
+	}
+	for _, methodDescriptor := range serviceDescriptor.GetMethods() {
+		// all methods are registered as streams, which is equivalent
+		// on the wire for unary methods
+		serviceDesc.Streams = append(serviceDesc.Streams, grpc.StreamDesc{
+			StreamName:    methodDescriptor.GetName(),
+			Handler:       s.newStreamHandler(methodDescriptor, methodToFixtures[getMethodName(methodDescriptor)]),
+			ServerStreams: methodDescriptor.IsServerStreaming(),
+			ClientStreams: methodDescriptor.IsClientStreaming(),
+		})
+	}
+	return serviceDesc
+}
+
+func (s *server) newStreamHandler(methodDescriptor *desc.MethodDescriptor, fixtures []*dynamic.Message) grpc.StreamHandler {
+	methodName := getMethodName(methodDescriptor)
+	return func(_ interface{}, stream grpc.ServerStream) error {
+		s.logger.Debug("call", zap.String("method", methodName))
+		numResponses := 0
+		sendResponse := func() error {
+			var response proto.Message
+			if len(fixtures) > 0 {
+				response = fixtures[numResponses%len(fixtures)]
+			} else {
+				generated, err := s.generator.Generate(methodDescriptor.GetOutputType())
+				if err != nil {
+					return err
+				}
+				response = generated
+			}
+			numResponses++
+			return stream.SendMsg(response)
+		}
+		for {
+			request := dynamic.NewMessage(methodDescriptor.GetInputType())
+			if err := stream.RecvMsg(request); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return err
+			}
+			s.logger.Debug("request", zap.String("method", methodName), zap.Stringer("request", request))
+			if methodDescriptor.IsClientStreaming() && methodDescriptor.IsServerStreaming() {
+				if err := sendResponse(); err != nil {
+					return err
+				}
+			}
+			if !methodDescriptor.IsClientStreaming() {
+				break
+			}
+		}
+		switch {
+		case methodDescriptor.IsClientStreaming() && methodDescriptor.IsServerStreaming():
+			return nil
+		case methodDescriptor.IsServerStreaming():
+			count := len(fixtures)
+			if count == 0 {
+				count = DefaultStreamResponseCount
+			}
+			for i := 0; i < count; i++ {
+				if err := sendResponse(); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			return sendResponse()
+		}
+	}
+}
+
+// getMethodToFixtures parses the fixture data, and verifies that every
+// fixture is for a method in the FileDescriptors and is valid for the
+// method's response type.
+func (s *server) getMethodToFixtures(fileDescriptors []*desc.FileDescriptor) (map[string][]*dynamic.Message, error) {
+	methodToFixtures := make(map[string][]*dynamic.Message)
+	if len(s.fixtureData) == 0 {
+		return methodToFixtures, nil
+	}
+	jsonData, err := yaml.YAMLToJSON(s.fixtureData)
+	if err != nil {
+		return nil, err
+	}
+	methodToRawFixtures := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(jsonData, &methodToRawFixtures); err != nil {
+		return nil, fmt.Errorf("fixtures must be a map from method to responses: %v", err)
+	}
+	methodDescriptors := make(map[string]*desc.MethodDescriptor)
+	for _, fileDescriptor := range fileDescriptors {
+		for _, serviceDescriptor := range fileDescriptor.GetServices() {
+			for _, methodDescriptor := range serviceDescriptor.GetMethods() {
+				methodDescriptors[getMethodName(methodDescriptor)] = methodDescriptor
+			}
+		}
+	}
+	for method, rawFixtures := range methodToRawFixtures {
+		method = strings.TrimPrefix(method, "/")
+		methodDescriptor, ok := methodDescriptors[method]
+		if !ok {
+			return nil, fmt.Errorf("fixtures for unknown method %s", method)
+		}
+		var rawResponses []json.RawMessage
+		if trimmed := bytes.TrimSpace(rawFixtures); len(trimmed) > 0 && trimmed[0] == '[' {
+			if err := json.Unmarshal(trimmed, &rawResponses); err != nil {
+				return nil, fmt.Errorf("invalid fixtures for method %s: %v", method, err)
+			}
+		} else {
+			rawResponses = []json.RawMessage{rawFixtures}
+		}
+		if len(rawResponses) > 1 && !methodDescriptor.IsServerStreaming() {
+			return nil, fmt.Errorf("method %s is not server streaming but has %d fixtures", method, len(rawResponses))
+		}
+		for _, rawResponse := range rawResponses {
+			response := dynamic.NewMessage(methodDescriptor.GetOutputType())
+			if err := response.UnmarshalJSON(rawResponse); err != nil {
+				return nil, fmt.Errorf("invalid fixture for method %s: %v", method, err)
+			}
+			methodToFixtures[method] = append(methodToFixtures[method], response)
+		}
+	}
+	return methodToFixtures, nil
+}
+
+// registerFileDescriptor registers the gzipped FileDescriptorProto with
+// github.com/golang/protobuf so that it can be served by the reflection service.
+func registerFileDescriptor(fileDescriptor *desc.FileDescriptor) error {
+	if proto.FileDescriptor(fileDescriptor.GetName()) != nil {
+		return nil
+	}
+	data, err := proto.Marshal(fileDescriptor.AsFileDescriptorProto())
+	if err != nil {
+		return err
+	}
+	buffer := bytes.NewBuffer(nil)
+	gzipWriter := gzip.NewWriter(buffer)
+	if _, err := gzipWriter.Write(data); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+	proto.RegisterFile(fileDescriptor.GetName(), buffer.Bytes())
+	return nil
+}
+
+func getMethodName(methodDescriptor *desc.MethodDescriptor) string {
+	return methodDescriptor.GetService().GetFullyQualifiedName() + "/" + methodDescriptor.GetName()
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mock
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+const testSource = `syntax = "proto3";
+
+package mocktest;
+
+message Value {
+  string value = 1;
+  int64 count = 2;
+}
+
+service EchoAPI {
+  rpc Echo(Value) returns (Value);
+  rpc EchoServerStream(Value) returns (stream Value);
+  rpc EchoClientStream(stream Value) returns (Value);
+  rpc EchoBidiStream(stream Value) returns (stream Value);
+}
+`
+
+func TestServerFixtures(t *testing.T) {
+	fileDescriptor := newTestFileDescriptor(t)
+	clientConn := startTestServer(
+		t,
+		fileDescriptor,
+		newServer(
+			ServerWithFixtureData([]byte(`mocktest.EchoAPI/Echo:
+  value: pong
+mocktest.EchoAPI/EchoServerStream:
+  - value: a
+  - value: b
+/mocktest.EchoAPI/EchoBidiStream:
+  - value: c
+  - value: d
+`)),
+		),
+	)
+	defer func() { _ = clientConn.Close() }()
+
+	assert.Equal(t, []string{`value:"pong"`}, callTestServer(t, clientConn, fileDescriptor, "Echo", 1))
+	assert.Equal(t, []string{`value:"a"`, `value:"b"`}, callTestServer(t, clientConn, fileDescriptor, "EchoServerStream", 1))
+	// bidirectional streaming methods cycle through the fixtures
+	assert.Equal(t, []string{`value:"c"`, `value:"d"`, `value:"c"`}, callTestServer(t, clientConn, fileDescriptor, "EchoBidiStream", 3))
+}
+
+func TestServerGenerated(t *testing.T) {
+	fileDescriptor := newTestFileDescriptor(t)
+	clientConn := startTestServer(t, fileDescriptor, newServer())
+	defer func() { _ = clientConn.Close() }()
+
+	assert.Len(t, callTestServer(t, clientConn, fileDescriptor, "Echo", 1), 1)
+	assert.Len(t, callTestServer(t, clientConn, fileDescriptor, "EchoServerStream", 1), DefaultStreamResponseCount)
+	assert.Len(t, callTestServer(t, clientConn, fileDescriptor, "EchoClientStream", 3), 1)
+	assert.Len(t, callTestServer(t, clientConn, fileDescriptor, "EchoBidiStream", 2), 2)
+}
+
+func TestServerReflection(t *testing.T) {
+	fileDescriptor := newTestFileDescriptor(t)
+	clientConn := startTestServer(t, fileDescriptor, newServer())
+	defer func() { _ = clientConn.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := grpcreflect.NewClient(ctx, reflectionpb.NewServerReflectionClient(clientConn))
+	defer client.Reset()
+	services, err := client.ListServices()
+	require.NoError(t, err)
+	assert.Contains(t, services, "mocktest.EchoAPI")
+	serviceDescriptor, err := client.ResolveService("mocktest.EchoAPI")
+	require.NoError(t, err)
+	assert.Len(t, serviceDescriptor.GetMethods(), 4)
+}
+
+func TestServerInvalidFixtures(t *testing.T) {
+	fileDescriptorSets := []*descriptor.FileDescriptorSet{
+		{
+			File: []*descriptor.FileDescriptorProto{newTestFileDescriptor(t).AsFileDescriptorProto()},
+		},
+	}
+	for fixtureData, expectedError := range map[string]string{
+		"mocktest.EchoAPI/Unknown:\n  value: a\n":              "fixtures for unknown method mocktest.EchoAPI/Unknown",
+		"mocktest.EchoAPI/Echo:\n  - value: a\n  - value: b\n": "method mocktest.EchoAPI/Echo is not server streaming but has 2 fixtures",
+		"mocktest.EchoAPI/Echo:\n  unknown: a\n":               "invalid fixture for method mocktest.EchoAPI/Echo",
+	} {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		err = newServer(ServerWithFixtureData([]byte(fixtureData))).Serve(fileDescriptorSets, listener)
+		_ = listener.Close()
+		require.Error(t, err)
+		assert.True(t, strings.HasPrefix(err.Error(), expectedError), err.Error())
+	}
+}
+
+func startTestServer(t *testing.T, fileDescriptor *desc.FileDescriptor, server *server) *grpc.ClientConn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	fileDescriptorSets := []*descriptor.FileDescriptorSet{
+		{
+			File: []*descriptor.FileDescriptorProto{fileDescriptor.AsFileDescriptorProto()},
+		},
+	}
+	go func() { _ = server.Serve(fileDescriptorSets, listener) }()
+	clientConn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	return clientConn
+}
+
+// callTestServer sends numRequests requests, or one request for methods that
+// are not client streaming, and returns the responses in the text format.
+func callTestServer(t *testing.T, clientConn *grpc.ClientConn, fileDescriptor *desc.FileDescriptor, methodName string, numRequests int) []string {
+	methodDescriptor := fileDescriptor.FindService("mocktest.EchoAPI").FindMethodByName(methodName)
+	require.NotNil(t, methodDescriptor)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := clientConn.NewStream(
+		ctx,
+		&grpc.StreamDesc{
+			StreamName:    methodName,
+			ServerStreams: methodDescriptor.IsServerStreaming(),
+			ClientStreams: methodDescriptor.IsClientStreaming(),
+		},
+		"/mocktest.EchoAPI/"+methodName,
+	)
+	require.NoError(t, err)
+	for i := 0; i < numRequests; i++ {
+		request := dynamic.NewMessage(methodDescriptor.GetInputType())
+		request.SetFieldByName("value", fmt.Sprintf("request%d", i))
+		require.NoError(t, stream.SendMsg(request))
+	}
+	require.NoError(t, stream.CloseSend())
+	var responses []string
+	for {
+		response := dynamic.NewMessage(methodDescriptor.GetOutputType())
+		if err := stream.RecvMsg(response); err != nil {
+			require.Equal(t, io.EOF, err)
+			break
+		}
+		responses = append(responses, response.String())
+	}
+	return responses
+}
+
+func newTestFileDescriptor(t *testing.T) *desc.FileDescriptor {
+	parser := protoparse.Parser{
+		Accessor: func(filename string) (io.ReadCloser, error) {
+			if filename == "mocktest/echo.proto" {
+				return ioutil.NopCloser(strings.NewReader(testSource)), nil
+			}
+			return nil, fmt.Errorf("unknown file %s", filename)
+		},
+	}
+	fileDescriptors, err := parser.ParseFiles("mocktest/echo.proto")
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 1)
+	return fileDescriptors[0]
+}
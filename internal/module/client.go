@@ -0,0 +1,143 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+type client struct {
+	logger  *zap.Logger
+	timeout time.Duration
+
+	registryURL string
+	httpClient  *http.Client
+}
+
+func newClient(registryURL string, options ...ClientOption) *client {
+	client := &client{
+		logger:      zap.NewNop(),
+		timeout:     DefaultTimeout,
+		registryURL: strings.TrimSuffix(registryURL, "/"),
+	}
+	for _, option := range options {
+		option(client)
+	}
+	client.httpClient = &http.Client{
+		Timeout: client.timeout,
+	}
+	return client
+}
+
+func (c *client) Push(fileDescriptorSet *descriptor.FileDescriptorSet, metadata *Metadata) error {
+	if metadata.Name == "" || metadata.Version == "" {
+		return fmt.Errorf("name and version required to push a module")
+	}
+	// the metadata is pushed last, so it is used to check if the version exists
+	_, exists, err := c.do(http.MethodGet, c.getURL(metadata.Name, metadata.Version, MetadataFilename), nil)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("module %s version %s already exists", metadata.Name, metadata.Version)
+	}
+	descriptorSetData, err := proto.Marshal(fileDescriptorSet)
+	if err != nil {
+		return err
+	}
+	metadataData, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, _, err := c.do(http.MethodPut, c.getURL(metadata.Name, metadata.Version, DescriptorSetFilename), descriptorSetData); err != nil {
+		return err
+	}
+	_, _, err = c.do(http.MethodPut, c.getURL(metadata.Name, metadata.Version, MetadataFilename), metadataData)
+	return err
+}
+
+func (c *client) Fetch(name string, version string) (*descriptor.FileDescriptorSet, *Metadata, error) {
+	metadataData, exists, err := c.do(http.MethodGet, c.getURL(name, version, MetadataFilename), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exists {
+		return nil, nil, fmt.Errorf("module %s version %s does not exist", name, version)
+	}
+	metadata := &Metadata{}
+	if err := json.Unmarshal(metadataData, metadata); err != nil {
+		return nil, nil, fmt.Errorf("invalid metadata for module %s version %s: %v", name, version, err)
+	}
+	descriptorSetData, exists, err := c.do(http.MethodGet, c.getURL(name, version, DescriptorSetFilename), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exists {
+		return nil, nil, fmt.Errorf("module %s version %s has no %s", name, version, DescriptorSetFilename)
+	}
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	if err := proto.Unmarshal(descriptorSetData, fileDescriptorSet); err != nil {
+		return nil, nil, fmt.Errorf("invalid FileDescriptorSet for module %s version %s: %v", name, version, err)
+	}
+	return fileDescriptorSet, metadata, nil
+}
+
+// do returns the response body, and false if the response was a 404.
+func (c *client) do(method string, url string, data []byte) (_ []byte, _ bool, retErr error) {
+	request, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, false, err
+	}
+	c.logger.Debug("module registry request", zap.String("method", method), zap.String("url", url))
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, false, fmt.Errorf("module registry returned %s for %s %s", response.Status, method, url)
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	return body, true, nil
+}
+
+func (c *client) getURL(name string, version string, filename string) string {
+	return strings.Join([]string{c.registryURL, name, version, filename}, "/")
+}
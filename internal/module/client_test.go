@@ -0,0 +1,206 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package module
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushFetch(t *testing.T) {
+	registry := newTestRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	client := newClient(server.URL + "/")
+
+	fileDescriptorSet := newTestFileDescriptorSet()
+	metadata := &Metadata{
+		Name:          "foo/bar",
+		Version:       "v1.0.0",
+		Files:         []string{"foo/foo.proto"},
+		ProtocVersion: "3.11.0",
+		CreateTime:    time.Date(2018, 6, 22, 0, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, client.Push(fileDescriptorSet, metadata))
+	assert.Equal(
+		t,
+		[]string{
+			"GET /foo/bar/v1.0.0/metadata.json",
+			"PUT /foo/bar/v1.0.0/descriptor_set.bin",
+			"PUT /foo/bar/v1.0.0/metadata.json",
+		},
+		registry.getRequests(),
+	)
+
+	fetchedFileDescriptorSet, fetchedMetadata, err := client.Fetch("foo/bar", "v1.0.0")
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(fileDescriptorSet, fetchedFileDescriptorSet))
+	assert.Equal(t, metadata, fetchedMetadata)
+
+	_, _, err = client.Fetch("foo/bar", "v2.0.0")
+	assert.EqualError(t, err, "module foo/bar version v2.0.0 does not exist")
+}
+
+func TestPushExists(t *testing.T) {
+	registry := newTestRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	client := newClient(server.URL)
+
+	metadata := &Metadata{Name: "foo", Version: "v1.0.0"}
+	require.NoError(t, client.Push(newTestFileDescriptorSet(), metadata))
+	registry.resetRequests()
+	assert.EqualError(t, client.Push(newTestFileDescriptorSet(), metadata), "module foo version v1.0.0 already exists")
+	// nothing is written when the version exists
+	assert.Equal(t, []string{"GET /foo/v1.0.0/metadata.json"}, registry.getRequests())
+
+	assert.EqualError(t, client.Push(newTestFileDescriptorSet(), &Metadata{Name: "foo"}), "name and version required to push a module")
+}
+
+func TestPushRejected(t *testing.T) {
+	registry := newTestRegistry()
+	registry.putStatus = http.StatusForbidden
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	client := newClient(server.URL)
+
+	err := client.Push(newTestFileDescriptorSet(), &Metadata{Name: "foo", Version: "v1.0.0"})
+	assert.EqualError(t, err, "module registry returned 403 Forbidden for PUT "+server.URL+"/foo/v1.0.0/descriptor_set.bin")
+	// the metadata is not pushed if the FileDescriptorSet is rejected
+	assert.Equal(
+		t,
+		[]string{
+			"GET /foo/v1.0.0/metadata.json",
+			"PUT /foo/v1.0.0/descriptor_set.bin",
+		},
+		registry.getRequests(),
+	)
+	_, _, err = client.Fetch("foo", "v1.0.0")
+	assert.EqualError(t, err, "module foo version v1.0.0 does not exist")
+}
+
+func TestFetchInvalid(t *testing.T) {
+	registry := newTestRegistry()
+	registry.files["/foo/v1.0.0/metadata.json"] = []byte("{")
+	registry.files["/bar/v1.0.0/metadata.json"] = []byte(`{"name":"bar","version":"v1.0.0"}`)
+	registry.files["/baz/v1.0.0/metadata.json"] = []byte(`{"name":"baz","version":"v1.0.0"}`)
+	registry.files["/baz/v1.0.0/descriptor_set.bin"] = []byte("invalid")
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	client := newClient(server.URL)
+
+	_, _, err := client.Fetch("foo", "v1.0.0")
+	assert.Error(t, err)
+	_, _, err = client.Fetch("bar", "v1.0.0")
+	assert.EqualError(t, err, "module bar version v1.0.0 has no descriptor_set.bin")
+	_, _, err = client.Fetch("baz", "v1.0.0")
+	assert.Error(t, err)
+
+	registry.getStatus = http.StatusInternalServerError
+	_, _, err = client.Fetch("foo", "v1.0.0")
+	assert.EqualError(t, err, "module registry returned 500 Internal Server Error for GET "+server.URL+"/foo/v1.0.0/metadata.json")
+}
+
+// testRegistry is an in-memory HTTP artifact store.
+type testRegistry struct {
+	lock      sync.Mutex
+	files     map[string][]byte
+	requests  []string
+	getStatus int
+	putStatus int
+}
+
+func newTestRegistry() *testRegistry {
+	return &testRegistry{
+		files: make(map[string][]byte),
+	}
+}
+
+func (r *testRegistry) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.requests = append(r.requests, request.Method+" "+request.URL.Path)
+	switch request.Method {
+	case http.MethodGet:
+		if r.getStatus != 0 {
+			responseWriter.WriteHeader(r.getStatus)
+			return
+		}
+		data, ok := r.files[request.URL.Path]
+		if !ok {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = responseWriter.Write(data)
+	case http.MethodPut:
+		if r.putStatus != 0 {
+			responseWriter.WriteHeader(r.putStatus)
+			return
+		}
+		data, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			responseWriter.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		r.files[request.URL.Path] = data
+		responseWriter.WriteHeader(http.StatusCreated)
+	default:
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *testRegistry) getRequests() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.requests
+}
+
+func (r *testRegistry) resetRequests() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.requests = nil
+}
+
+func newTestFileDescriptorSet() *descriptor.FileDescriptorSet {
+	return &descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("foo/foo.proto"),
+				Package: proto.String("foo"),
+				Syntax:  proto.String("proto3"),
+				MessageType: []*descriptor.DescriptorProto{
+					{
+						Name: proto.String("Foo"),
+					},
+				},
+			},
+		},
+	}
+}
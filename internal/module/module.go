@@ -0,0 +1,105 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package module publishes and fetches modules, which are versioned
+// FileDescriptorSets stored in an HTTP artifact store.
+//
+// A module with a given name and version is stored at
+// REGISTRY_URL/NAME/VERSION/descriptor_set.bin, with its metadata stored
+// as JSON at REGISTRY_URL/NAME/VERSION/metadata.json. Pushing is done with
+// HTTP PUT, and fetching with HTTP GET.
+package module
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultTimeout is the default timeout for requests.
+	DefaultTimeout = 30 * time.Second
+
+	// DescriptorSetFilename is the name of the FileDescriptorSet file for a module.
+	DescriptorSetFilename = "descriptor_set.bin"
+	// MetadataFilename is the name of the metadata file for a module.
+	MetadataFilename = "metadata.json"
+)
+
+// Metadata is the metadata for a module.
+type Metadata struct {
+	// The name of the module.
+	Name string `json:"name"`
+	// The version of the module.
+	Version string `json:"version"`
+	// The names of the files in the module, not including imports
+	// from other modules or the Well-Known Types.
+	Files []string `json:"files"`
+	// The version of protoc used to compile the module.
+	ProtocVersion string `json:"protoc_version,omitempty"`
+	// The time the module was pushed.
+	CreateTime time.Time `json:"create_time"`
+}
+
+// Client is a client for a module registry.
+type Client interface {
+	// Push pushes the FileDescriptorSet and Metadata using the name and version
+	// from the Metadata.
+	//
+	// Versions are immutable, so this returns an error if the version already exists.
+	Push(fileDescriptorSet *descriptor.FileDescriptorSet, metadata *Metadata) error
+	// Fetch fetches the FileDescriptorSet and Metadata for the name and version.
+	Fetch(name string, version string) (*descriptor.FileDescriptorSet, *Metadata, error)
+}
+
+// ClientOption is an option for a new Client.
+type ClientOption func(*client)
+
+// ClientWithLogger returns a ClientOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func ClientWithLogger(logger *zap.Logger) ClientOption {
+	return func(client *client) {
+		client.logger = logger
+	}
+}
+
+// ClientWithTimeout returns a ClientOption that uses the given timeout
+// for requests.
+//
+// The default is to use DefaultTimeout.
+func ClientWithTimeout(timeout time.Duration) ClientOption {
+	return func(client *client) {
+		client.timeout = timeout
+	}
+}
+
+// NewClient returns a new Client for the registry at the given URL.
+func NewClient(registryURL string, options ...ClientOption) Client {
+	return newClient(registryURL, options...)
+}
+
+// DescriptorSetFilePath returns the path that the FileDescriptorSet for
+// a fetched module is written to for the config in the given directory.
+func DescriptorSetFilePath(configDirPath string, name string, version string) string {
+	return filepath.Join(configDirPath, ".prototool", "modules", filepath.FromSlash(name), version, DescriptorSetFilename)
+}
@@ -0,0 +1,371 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package parsecheck
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/syntax"
+	"github.com/uber/prototool/internal/text"
+	"go.uber.org/zap"
+)
+
+const (
+	symbolKindPackage symbolKind = iota + 1
+	symbolKindMessage
+	symbolKindEnum
+)
+
+var (
+	scalarTypes = map[string]struct{}{
+		"double":   {},
+		"float":    {},
+		"int32":    {},
+		"int64":    {},
+		"uint32":   {},
+		"uint64":   {},
+		"sint32":   {},
+		"sint64":   {},
+		"fixed32":  {},
+		"fixed64":  {},
+		"sfixed32": {},
+		"sfixed64": {},
+		"bool":     {},
+		"string":   {},
+		"bytes":    {},
+	}
+
+	// the import prefixes of files that are downloaded by Prototool
+	// and are not on the include paths of the config
+	downloadedImportPrefixes = []string{
+		"google/api/",
+		"google/protobuf/",
+		"gogoproto/",
+		"protoc-gen-openapiv2/",
+		"validate/",
+	}
+)
+
+type symbolKind int
+
+type checker struct {
+	logger *zap.Logger
+}
+
+func newChecker(options ...CheckerOption) *checker {
+	checker := &checker{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(checker)
+	}
+	return checker
+}
+
+func (c *checker) Check(protoSet *file.ProtoSet) ([]*text.Failure, error) {
+	state := &checkState{
+		includePaths:    file.GetIncludePaths(protoSet),
+		pathToFile:      make(map[string]*parsedFile),
+		reportedImports: make(map[string]struct{}),
+	}
+	var protoFiles []*file.ProtoFile
+	for _, dirProtoFiles := range protoSet.DirPathToFiles {
+		protoFiles = append(protoFiles, dirProtoFiles...)
+	}
+	sort.Slice(protoFiles, func(i int, j int) bool { return protoFiles[i].Path < protoFiles[j].Path })
+	var parsedFiles []*parsedFile
+	for _, protoFile := range protoFiles {
+		parsedFile, err := state.parse(protoFile.Path, protoFile.DisplayPath)
+		if err != nil {
+			return nil, err
+		}
+		if parsedFile.descriptor != nil {
+			parsedFiles = append(parsedFiles, parsedFile)
+		}
+	}
+	for _, parsedFile := range parsedFiles {
+		c.logger.Debug("checking references", zap.String("file", parsedFile.displayPath))
+		if err := state.checkReferences(parsedFile); err != nil {
+			return nil, err
+		}
+	}
+	text.SortFailures(state.failures)
+	return state.failures, nil
+}
+
+type checkState struct {
+	includePaths []string
+	// absolute path to parsed file, including files that failed to parse
+	pathToFile map[string]*parsedFile
+	// the import filenames that were already reported as not found
+	reportedImports map[string]struct{}
+	failures        []*text.Failure
+}
+
+type parsedFile struct {
+	displayPath string
+	// nil if the file failed to parse
+	descriptor *proto.Proto
+	pkg        string
+	// fully-qualified name to kind for the package and the types in the file
+	symbols map[string]symbolKind
+}
+
+// parse parses the file at the path, and adds a failure if it does not parse.
+//
+// Each file is only parsed once.
+func (s *checkState) parse(path string, displayPath string) (*parsedFile, error) {
+	if parsedFile, ok := s.pathToFile[path]; ok {
+		return parsedFile, nil
+	}
+	parsedFile := &parsedFile{
+		displayPath: displayPath,
+	}
+	s.pathToFile[path] = parsedFile
+	osFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	descriptor, failures, err := syntax.Parse(osFile, displayPath)
+	_ = osFile.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(failures) > 0 {
+		s.failures = append(s.failures, failures...)
+		return parsedFile, nil
+	}
+	parsedFile.descriptor = descriptor
+	parsedFile.symbols = make(map[string]symbolKind)
+	for _, element := range descriptor.Elements {
+		if pkg, ok := element.(*proto.Package); ok {
+			parsedFile.pkg = pkg.Name
+			prefix := ""
+			for _, part := range strings.Split(pkg.Name, ".") {
+				prefix = qualify(prefix, part)
+				parsedFile.symbols[prefix] = symbolKindPackage
+			}
+		}
+	}
+	addSymbols(parsedFile.symbols, parsedFile.pkg, descriptor.Elements)
+	return parsedFile, nil
+}
+
+// checkReferences adds failures for imports that are not found and
+// type references that are not defined in the file or its imports.
+func (s *checkState) checkReferences(checkedFile *parsedFile) error {
+	symbols := make(map[string]symbolKind)
+	// if any import is not parsed, references that are not
+	// found may be defined in it, so they are not reported
+	complete := true
+	visibleFiles, err := s.getVisibleFiles(checkedFile, true, make(map[*parsedFile]struct{}))
+	if err != nil {
+		return err
+	}
+	for _, visibleFile := range visibleFiles {
+		if visibleFile == nil || visibleFile.descriptor == nil {
+			complete = false
+			continue
+		}
+		for name, kind := range visibleFile.symbols {
+			symbols[name] = kind
+		}
+	}
+	if !complete {
+		return nil
+	}
+	checkReferences(symbols, checkedFile.pkg, checkedFile.descriptor.Elements, func(position scanner.Position, format string, args ...interface{}) {
+		s.failures = append(s.failures, text.NewFailuref(position, "", format, args...))
+	})
+	return nil
+}
+
+// getVisibleFiles returns the file, its imports, and the public imports
+// of its imports, recursively. A nil file is returned for an import
+// that is not on the include paths.
+func (s *checkState) getVisibleFiles(importingFile *parsedFile, direct bool, seen map[*parsedFile]struct{}) ([]*parsedFile, error) {
+	if _, ok := seen[importingFile]; ok {
+		return nil, nil
+	}
+	seen[importingFile] = struct{}{}
+	visibleFiles := []*parsedFile{importingFile}
+	if importingFile.descriptor == nil {
+		return visibleFiles, nil
+	}
+	for _, element := range importingFile.descriptor.Elements {
+		protoImport, ok := element.(*proto.Import)
+		if !ok || (!direct && protoImport.Kind != "public") {
+			continue
+		}
+		importFile, err := s.getImport(protoImport)
+		if err != nil {
+			return nil, err
+		}
+		if importFile == nil {
+			visibleFiles = append(visibleFiles, nil)
+			continue
+		}
+		importVisibleFiles, err := s.getVisibleFiles(importFile, false, seen)
+		if err != nil {
+			return nil, err
+		}
+		visibleFiles = append(visibleFiles, importVisibleFiles...)
+	}
+	return visibleFiles, nil
+}
+
+// getImport returns the parsed imported file, or nil if the file is not
+// on the include paths, in which case a failure is added unless the file
+// is downloaded by Prototool.
+func (s *checkState) getImport(protoImport *proto.Import) (*parsedFile, error) {
+	for _, includePath := range s.includePaths {
+		path := filepath.Join(includePath, filepath.FromSlash(protoImport.Filename))
+		if fileInfo, err := os.Stat(path); err == nil && !fileInfo.IsDir() {
+			return s.parse(path, path)
+		}
+	}
+	for _, prefix := range downloadedImportPrefixes {
+		if strings.HasPrefix(protoImport.Filename, prefix) {
+			return nil, nil
+		}
+	}
+	key := protoImport.Position.Filename + ":" + protoImport.Filename
+	if _, ok := s.reportedImports[key]; !ok {
+		s.reportedImports[key] = struct{}{}
+		s.failures = append(s.failures, text.NewFailuref(protoImport.Position, "", `Import "%s" was not found.`, protoImport.Filename))
+	}
+	return nil, nil
+}
+
+// addSymbols adds the messages and enums in the elements, and the
+// messages and enums nested within them, to the symbols.
+func addSymbols(symbols map[string]symbolKind, scope string, elements []proto.Visitee) {
+	for _, element := range elements {
+		switch element := element.(type) {
+		case *proto.Message:
+			if element.IsExtend {
+				continue
+			}
+			name := qualify(scope, element.Name)
+			symbols[name] = symbolKindMessage
+			addSymbols(symbols, name, element.Elements)
+		case *proto.Group:
+			name := qualify(scope, element.Name)
+			symbols[name] = symbolKindMessage
+			addSymbols(symbols, name, element.Elements)
+		case *proto.Enum:
+			symbols[qualify(scope, element.Name)] = symbolKindEnum
+		}
+	}
+}
+
+// checkReferences calls addFailuref for each type referenced in the elements
+// that is not in the symbols, or is not a message where a message is required.
+func checkReferences(
+	symbols map[string]symbolKind,
+	scope string,
+	elements []proto.Visitee,
+	addFailuref func(scanner.Position, string, ...interface{}),
+) {
+	checkType := func(position scanner.Position, typeName string, messageOnly bool) {
+		if _, ok := scalarTypes[typeName]; ok && !messageOnly {
+			return
+		}
+		kind := resolve(symbols, scope, typeName)
+		switch {
+		case kind == 0 || kind == symbolKindPackage:
+			addFailuref(position, `"%s" is not defined.`, typeName)
+		case messageOnly && kind != symbolKindMessage:
+			addFailuref(position, `"%s" is not a message type.`, typeName)
+		}
+	}
+	for _, element := range elements {
+		switch element := element.(type) {
+		case *proto.Message:
+			if element.IsExtend {
+				checkType(element.Position, element.Name, true)
+				// the fields of an extend are in the scope of the extend
+				checkReferences(symbols, scope, element.Elements, addFailuref)
+				continue
+			}
+			checkReferences(symbols, qualify(scope, element.Name), element.Elements, addFailuref)
+		case *proto.Group:
+			checkReferences(symbols, qualify(scope, element.Name), element.Elements, addFailuref)
+		case *proto.NormalField:
+			checkType(element.Position, element.Type, false)
+		case *proto.MapField:
+			checkType(element.Position, element.Type, false)
+		case *proto.Oneof:
+			checkReferences(symbols, scope, element.Elements, addFailuref)
+		case *proto.OneOfField:
+			checkType(element.Position, element.Type, false)
+		case *proto.Service:
+			for _, serviceElement := range element.Elements {
+				if rpc, ok := serviceElement.(*proto.RPC); ok {
+					checkType(rpc.Position, rpc.RequestType, true)
+					checkType(rpc.Position, rpc.ReturnsType, true)
+				}
+			}
+		}
+	}
+}
+
+// resolve returns the kind of the type name referenced from the scope,
+// or 0 if it is not defined.
+//
+// As with protoc, the first part of the name is looked up in the scope
+// and then each enclosing scope, and the rest of the name must be
+// within the first match.
+func resolve(symbols map[string]symbolKind, scope string, typeName string) symbolKind {
+	if strings.HasPrefix(typeName, ".") {
+		return symbols[typeName[1:]]
+	}
+	firstPart := typeName
+	if i := strings.IndexByte(typeName, '.'); i >= 0 {
+		firstPart = typeName[:i]
+	}
+	for {
+		if _, ok := symbols[qualify(scope, firstPart)]; ok {
+			return symbols[qualify(scope, typeName)]
+		}
+		if scope == "" {
+			return 0
+		}
+		if i := strings.LastIndexByte(scope, '.'); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+func qualify(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
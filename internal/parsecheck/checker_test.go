@@ -0,0 +1,124 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package parsecheck
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/file"
+)
+
+func TestCheck(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	writeFile(t, tmpDir, "prototool.yaml", "")
+	writeFile(t, tmpDir, "a/a.proto", `syntax = "proto3";
+
+package a;
+
+import public "b/b.proto";
+
+message A {
+  message Nested {}
+  Nested nested = 1;
+  b.B b = 2;
+  b.C c = 3;
+  Missing missing = 4;
+}
+
+service AService {
+  rpc Get(A) returns (b.BEnum);
+}
+`)
+	writeFile(t, tmpDir, "b/b.proto", `syntax = "proto3";
+
+package b;
+
+message B {}
+
+enum BEnum {
+  B_ENUM_INVALID = 0;
+}
+`)
+	writeFile(t, tmpDir, "c/c.proto", `syntax = "proto3";
+
+package c;
+
+import "google/protobuf/timestamp.proto";
+import "missing.proto";
+
+message C {
+  google.protobuf.Timestamp timestamp = 1;
+  Missing missing = 2;
+}
+`)
+	writeFile(t, tmpDir, "d/d.proto", `syntax = "proto3";
+
+message D {
+`)
+	protoSet, err := file.NewProtoSetProvider().GetForDir(tmpDir, tmpDir)
+	require.NoError(t, err)
+	failures, err := NewChecker().Check(protoSet)
+	require.NoError(t, err)
+	var messages []string
+	for _, failure := range failures {
+		messages = append(messages, failure.Message)
+	}
+	assert.Equal(
+		t,
+		[]string{
+			`"b.C" is not defined.`,
+			`"Missing" is not defined.`,
+			`"b.BEnum" is not a message type.`,
+			`Import "missing.proto" was not found.`,
+		},
+		messages[:4],
+	)
+	require.Len(t, failures, 5)
+	assert.Equal(t, 4, failures[4].Line)
+}
+
+func TestResolve(t *testing.T) {
+	symbols := map[string]symbolKind{
+		"foo":         symbolKindPackage,
+		"foo.bar":     symbolKindPackage,
+		"foo.bar.Baz": symbolKindMessage,
+		"foo.Baz":     symbolKindEnum,
+	}
+	assert.Equal(t, symbolKindMessage, resolve(symbols, "foo.bar", "Baz"))
+	assert.Equal(t, symbolKindEnum, resolve(symbols, "foo", "Baz"))
+	assert.Equal(t, symbolKindEnum, resolve(symbols, "foo.bar", ".foo.Baz"))
+	assert.Equal(t, symbolKindMessage, resolve(symbols, "", "foo.bar.Baz"))
+	// bar is found in foo.bar, so bar.Qux is not looked up in other scopes
+	assert.Equal(t, symbolKind(0), resolve(symbols, "foo.bar", "bar.Qux"))
+}
+
+func writeFile(t *testing.T, dirPath string, filePath string, data string) {
+	path := filepath.Join(dirPath, filepath.FromSlash(filePath))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(data), 0644))
+}
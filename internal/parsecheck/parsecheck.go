@@ -0,0 +1,61 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package parsecheck checks Protobuf files for syntax errors and
+// unresolved references with the internal parser, without protoc.
+//
+// This is a fast approximation of compiling with protoc. It does not
+// check everything that protoc checks, such as options, field numbers,
+// and default values, so files that pass may still fail to compile.
+package parsecheck
+
+import (
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/text"
+	"go.uber.org/zap"
+)
+
+// Checker checks Protobuf files.
+type Checker interface {
+	// Check checks the files in the ProtoSet.
+	//
+	// Imports are looked up on the include paths of the config, and are
+	// also checked for syntax errors. References to types in imports that
+	// are downloaded by Prototool, such as the Well-Known Types, are not
+	// checked.
+	Check(protoSet *file.ProtoSet) ([]*text.Failure, error)
+}
+
+// CheckerOption is an option for a new Checker.
+type CheckerOption func(*checker)
+
+// CheckerWithLogger returns a CheckerOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func CheckerWithLogger(logger *zap.Logger) CheckerOption {
+	return func(checker *checker) {
+		checker.logger = logger
+	}
+}
+
+// NewChecker returns a new Checker.
+func NewChecker(options ...CheckerOption) Checker {
+	return newChecker(options...)
+}
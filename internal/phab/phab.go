@@ -77,6 +77,12 @@ func TextFailureToHarbormasterLintResult(textFailure *text.Failure) (*Harbormast
 		Char:        textFailure.Column,
 		Description: textFailure.Message,
 	}
+	switch textFailure.Severity {
+	case text.SeverityWarning:
+		harbormasterLintResult.Severity = "warning"
+	case text.SeverityInfo:
+		harbormasterLintResult.Severity = "advice"
+	}
 	if harbormasterLintResult.Code == "" {
 		harbormasterLintResult.Code = DefaultHarbormasterLintResultCode
 	}
@@ -0,0 +1,100 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package progress reports the progress of long operations, such as
+// downloads and compiling many directories.
+package progress
+
+import (
+	"io"
+	"time"
+)
+
+// DefaultInterval is the default interval between the lines printed for
+// an operation when not writing to a terminal.
+const DefaultInterval = 10 * time.Second
+
+// Reporter reports the progress of operations.
+//
+// Reporters are safe for concurrent use.
+type Reporter interface {
+	// Start starts reporting the progress of an operation towards the given
+	// total, or 0 if the total is unknown.
+	Start(name string, total int64, options ...TaskOption) Task
+	// Printf prints a line, such as the start or end of a download.
+	Printf(format string, args ...interface{})
+}
+
+// Task is an operation started with a Reporter.
+type Task interface {
+	// Add adds n to the progress of the operation.
+	Add(n int64)
+	// Done stops reporting the progress of the operation.
+	Done()
+}
+
+// ReporterOption is an option for a new Reporter.
+type ReporterOption func(*reporter)
+
+// ReporterWithTerminal returns a ReporterOption that draws a spinner with
+// the progress of the running operations on a single line that is redrawn
+// in place, for when the writer is a terminal.
+//
+// The default is to print a line with the progress of each operation
+// at most once per interval.
+func ReporterWithTerminal() ReporterOption {
+	return func(reporter *reporter) {
+		reporter.terminal = true
+	}
+}
+
+// ReporterWithInterval returns a ReporterOption that prints a line with
+// the progress of each operation at most once per the given interval
+// when not writing to a terminal.
+//
+// The default is to use DefaultInterval.
+func ReporterWithInterval(interval time.Duration) ReporterOption {
+	return func(reporter *reporter) {
+		reporter.interval = interval
+	}
+}
+
+// TaskOption is an option for a new Task.
+type TaskOption func(*task)
+
+// TaskWithBytes returns a TaskOption that prints the progress and total
+// as a number of bytes.
+//
+// The default is to print them as counts.
+func TaskWithBytes() TaskOption {
+	return func(task *task) {
+		task.bytes = true
+	}
+}
+
+// NewReporter returns a new Reporter that writes to the given writer.
+func NewReporter(writer io.Writer, options ...ReporterOption) Reporter {
+	return newReporter(writer, options...)
+}
+
+// NewNopReporter returns a new Reporter that does nothing.
+func NewNopReporter() Reporter {
+	return nopReporter{}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package progress
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReporterLines(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	reporter := NewReporter(buffer, ReporterWithInterval(0))
+	task := reporter.Start("Compiling", 2)
+	task.Add(1)
+	reporter.Printf("Downloading %s", "protoc.zip")
+	downloadTask := reporter.Start("Downloading protoc.zip", 2048, TaskWithBytes())
+	downloadTask.Add(1024)
+	downloadTask.Done()
+	task.Add(1)
+	task.Done()
+	unknownTask := reporter.Start("Generating", 0)
+	unknownTask.Add(3)
+	unknownTask.Done()
+	assert.Equal(
+		t,
+		`Compiling: 1/2 (50%)
+Downloading protoc.zip
+Downloading protoc.zip: 50% (1.0 KB of 2.0 KB)
+Compiling: 2/2 (100%)
+Generating: 3
+`,
+		buffer.String(),
+	)
+}
+
+func TestReporterInterval(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	task := NewReporter(buffer).Start("Compiling", 2)
+	task.Add(1)
+	task.Add(1)
+	task.Done()
+	// operations that are done within the interval print nothing
+	assert.Empty(t, buffer.String())
+}
+
+func TestReporterTerminal(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	reporter := newReporter(buffer, ReporterWithTerminal(), ReporterWithInterval(0))
+	task := reporter.Start("Compiling", 4)
+	task.Add(1)
+	reporter.lock.Lock()
+	reporter.draw()
+	reporter.lock.Unlock()
+	assert.Contains(t, buffer.String(), "\r\033[K| Compiling: 1/4 (25%)")
+	// nothing is printed per step on a terminal
+	assert.NotContains(t, buffer.String(), "\n")
+	reporter.Printf("Downloaded protoc.zip")
+	assert.Contains(t, buffer.String(), "\r\033[KDownloaded protoc.zip\n")
+	task.Done()
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+	assert.False(t, reporter.drawn)
+	assert.Nil(t, reporter.stop)
+}
+
+func TestNopReporter(t *testing.T) {
+	reporter := NewNopReporter()
+	task := reporter.Start("Compiling", 1)
+	task.Add(1)
+	task.Done()
+	reporter.Printf("Downloaded %s", "protoc.zip")
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// terminalInterval is the interval between redraws on a terminal, so that
+// operations that are done within it are never drawn.
+const terminalInterval = 100 * time.Millisecond
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+type reporter struct {
+	writer   io.Writer
+	terminal bool
+	interval time.Duration
+
+	lock  sync.Mutex
+	tasks []*task
+	// whether the line of the terminal has the progress drawn on it
+	drawn bool
+	frame int
+	// closed to stop redrawing when there are no running operations
+	stop chan struct{}
+}
+
+func newReporter(writer io.Writer, options ...ReporterOption) *reporter {
+	reporter := &reporter{
+		writer:   writer,
+		interval: DefaultInterval,
+	}
+	for _, option := range options {
+		option(reporter)
+	}
+	return reporter
+}
+
+func (r *reporter) Start(name string, total int64, options ...TaskOption) Task {
+	now := time.Now()
+	task := &task{
+		reporter:  r,
+		name:      name,
+		total:     total,
+		lastPrint: now,
+	}
+	for _, option := range options {
+		option(task)
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.tasks = append(r.tasks, task)
+	if r.terminal && r.stop == nil {
+		r.stop = make(chan struct{})
+		go r.redraw(r.stop)
+	}
+	return task
+}
+
+func (r *reporter) Printf(format string, args ...interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	// the progress is drawn again on the next redraw
+	r.clear()
+	_, _ = fmt.Fprintf(r.writer, format+"\n", args...)
+}
+
+func (r *reporter) redraw(stop <-chan struct{}) {
+	ticker := time.NewTicker(terminalInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.lock.Lock()
+			r.draw()
+			r.lock.Unlock()
+		}
+	}
+}
+
+// draw draws the progress of the running operations on the line of the
+// terminal, and is called with the lock held.
+func (r *reporter) draw() {
+	if len(r.tasks) == 0 {
+		return
+	}
+	statuses := make([]string, len(r.tasks))
+	for i, task := range r.tasks {
+		statuses[i] = task.status()
+	}
+	_, _ = fmt.Fprintf(r.writer, "\r\033[K%s %s", spinnerFrames[r.frame%len(spinnerFrames)], strings.Join(statuses, ", "))
+	r.frame++
+	r.drawn = true
+}
+
+// clear clears the line of the terminal if the progress is drawn on it,
+// and is called with the lock held.
+func (r *reporter) clear() {
+	if r.drawn {
+		_, _ = fmt.Fprint(r.writer, "\r\033[K")
+		r.drawn = false
+	}
+}
+
+func (r *reporter) done(task *task) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i, iTask := range r.tasks {
+		if iTask == task {
+			r.tasks = append(r.tasks[:i], r.tasks[i+1:]...)
+			break
+		}
+	}
+	if len(r.tasks) > 0 {
+		return
+	}
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	r.clear()
+}
+
+type task struct {
+	reporter  *reporter
+	name      string
+	total     int64
+	current   int64
+	bytes     bool
+	lastPrint time.Time
+}
+
+func (t *task) Add(n int64) {
+	t.reporter.lock.Lock()
+	defer t.reporter.lock.Unlock()
+	t.current += n
+	if t.reporter.terminal {
+		return
+	}
+	if now := time.Now(); now.Sub(t.lastPrint) >= t.reporter.interval {
+		t.lastPrint = now
+		_, _ = fmt.Fprintln(t.reporter.writer, t.status())
+	}
+}
+
+func (t *task) Done() {
+	t.reporter.done(t)
+}
+
+// status returns the name of the operation with its progress.
+func (t *task) status() string {
+	format := func(n int64) string { return fmt.Sprintf("%d", n) }
+	if t.bytes {
+		format = formatBytes
+	}
+	switch {
+	case t.total <= 0:
+		return fmt.Sprintf("%s: %s", t.name, format(t.current))
+	case t.bytes:
+		return fmt.Sprintf("%s: %d%% (%s of %s)", t.name, 100*t.current/t.total, format(t.current), format(t.total))
+	default:
+		return fmt.Sprintf("%s: %s/%s (%d%%)", t.name, format(t.current), format(t.total), 100*t.current/t.total)
+	}
+}
+
+func formatBytes(n int64) string {
+	if n < 1024*1024 {
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+}
+
+type nopReporter struct{}
+
+func (nopReporter) Start(string, int64, ...TaskOption) Task { return nopTask{} }
+
+func (nopReporter) Printf(string, ...interface{}) {}
+
+type nopTask struct{}
+
+func (nopTask) Add(int64) {}
+
+func (nopTask) Done() {}
@@ -0,0 +1,251 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package proto3 migrates proto2 files to proto3.
+package proto3
+
+import (
+	"fmt"
+	"strings"
+	"text/scanner"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/strs"
+	"github.com/uber/prototool/internal/text"
+)
+
+// FailureID is the ID of the failures for constructs that cannot be migrated.
+const FailureID = "MIGRATE_PROTO3"
+
+var (
+	scalarTypes = map[string]struct{}{
+		"double":   struct{}{},
+		"float":    struct{}{},
+		"int32":    struct{}{},
+		"int64":    struct{}{},
+		"uint32":   struct{}{},
+		"uint64":   struct{}{},
+		"sint32":   struct{}{},
+		"sint64":   struct{}{},
+		"fixed32":  struct{}{},
+		"fixed64":  struct{}{},
+		"sfixed32": struct{}{},
+		"sfixed64": struct{}{},
+		"bool":     struct{}{},
+		"string":   struct{}{},
+		"bytes":    struct{}{},
+	}
+	// scalar types that are not packable
+	lengthDelimitedTypes = map[string]struct{}{
+		"string": struct{}{},
+		"bytes":  struct{}{},
+	}
+)
+
+// Migrate converts the proto2 file to proto3 in place, keeping the
+// wire format and the presence of fields.
+//
+// Optional and required fields of scalar and enum types become optional
+// fields, and the label is dropped for fields of message types declared
+// in the file or in google.protobuf, as these always have presence.
+// Default values are removed and noted in the comments of the fields.
+// Repeated fields of packable types get packed = false, as these are
+// packed by default in proto3.
+//
+// Groups, extension ranges, extensions of types other than the
+// google.protobuf options, and enums whose first value is not zero
+// cannot be migrated, and are returned as failures. If there are
+// failures, the file should not be used.
+//
+// Files that are already proto3 are left unchanged.
+func Migrate(descriptor *proto.Proto) ([]*text.Failure, error) {
+	var syntax *proto.Syntax
+	for _, element := range descriptor.Elements {
+		if s, ok := element.(*proto.Syntax); ok {
+			syntax = s
+		}
+	}
+	if syntax != nil && syntax.Value == "proto3" {
+		return nil, nil
+	}
+	if syntax != nil && syntax.Value != "proto2" {
+		return nil, fmt.Errorf("%s: syntax is %q, only proto2 files can be migrated to proto3", descriptor.Filename, syntax.Value)
+	}
+	if syntax == nil {
+		// files without a syntax declaration are proto2
+		syntax = &proto.Syntax{
+			Parent: descriptor,
+		}
+		descriptor.Elements = append([]proto.Visitee{syntax}, descriptor.Elements...)
+	}
+	syntax.Value = "proto3"
+
+	m := &migrator{
+		enumNames:    make(map[string]struct{}),
+		messageNames: make(map[string]struct{}),
+	}
+	m.collectNames(descriptor.Elements)
+	m.migrateElements(descriptor.Elements)
+	text.SortFailures(m.failures)
+	return m.failures, nil
+}
+
+type migrator struct {
+	// the names of the enums and messages declared in the file,
+	// with and without their parent messages
+	enumNames    map[string]struct{}
+	messageNames map[string]struct{}
+	failures     []*text.Failure
+}
+
+func (m *migrator) collectNames(elements []proto.Visitee) {
+	for _, element := range elements {
+		switch e := element.(type) {
+		case *proto.Message:
+			if !e.IsExtend {
+				m.messageNames[e.Name] = struct{}{}
+				m.collectNames(e.Elements)
+			}
+		case *proto.Enum:
+			m.enumNames[e.Name] = struct{}{}
+		}
+	}
+}
+
+func (m *migrator) migrateElements(elements []proto.Visitee) {
+	for _, element := range elements {
+		switch e := element.(type) {
+		case *proto.Message:
+			if e.IsExtend {
+				if !isOptionsType(e.Name) {
+					m.addFailuref(e.Position, "Extensions of %s are not allowed in proto3, only extensions of the google.protobuf options are.", e.Name)
+				}
+				continue
+			}
+			m.migrateElements(e.Elements)
+		case *proto.Oneof:
+			m.migrateElements(e.Elements)
+		case *proto.NormalField:
+			m.migrateField(e)
+		case *proto.OneOfField:
+			m.migrateDefault(e.Field)
+		case *proto.Group:
+			m.addFailuref(e.Position, "Groups are not allowed in proto3, replace group %s with a message %s and a field of that type with number %d.", e.Name, e.Name, e.Sequence)
+		case *proto.Extensions:
+			m.addFailuref(e.Position, "Extension ranges are not allowed in proto3, consider a field of type google.protobuf.Any instead.")
+		case *proto.Enum:
+			m.migrateEnum(e)
+		}
+	}
+}
+
+func (m *migrator) migrateField(field *proto.NormalField) {
+	if field.Required {
+		field.Required = false
+		field.Optional = true
+	}
+	if field.Optional && m.isMessageType(field.Type) {
+		field.Optional = false
+	}
+	if field.Repeated && m.isPackableType(field.Type) && !hasOption(field.Options, "packed") {
+		field.Options = append(field.Options, &proto.Option{
+			Name:     "packed",
+			Constant: proto.Literal{Source: "false"},
+			Parent:   field,
+		})
+	}
+	m.migrateDefault(field.Field)
+}
+
+func (m *migrator) migrateDefault(field *proto.Field) {
+	options := make([]*proto.Option, 0, len(field.Options))
+	for _, option := range field.Options {
+		if option.Name != "default" {
+			options = append(options, option)
+			continue
+		}
+		value := option.Constant.SourceRepresentation()
+		if field.Comment == nil {
+			field.Comment = &proto.Comment{
+				Position: field.Position,
+			}
+		}
+		field.Comment.Lines = append(field.Comment.Lines, fmt.Sprintf(" The default value in proto2 was %s.", value))
+	}
+	field.Options = options
+}
+
+func (m *migrator) migrateEnum(enum *proto.Enum) {
+	for _, element := range enum.Elements {
+		if enumField, ok := element.(*proto.EnumField); ok {
+			if enumField.Integer != 0 {
+				m.addFailuref(enumField.Position, "The first value of enum %s must be zero in proto3, add a value such as %s_UNSPECIFIED = 0 before %s.", enum.Name, strs.ToUpperSnakeCase(enum.Name), enumField.Name)
+			}
+			return
+		}
+	}
+}
+
+// isMessageType returns true if the type is a message declared in the
+// file or a google.protobuf message. Types from other files are not known,
+// so they keep optional to be safe.
+func (m *migrator) isMessageType(fieldType string) bool {
+	if strings.HasPrefix(strings.TrimPrefix(fieldType, "."), "google.protobuf.") {
+		return fieldType != "google.protobuf.NullValue" && fieldType != ".google.protobuf.NullValue"
+	}
+	name := lastComponent(fieldType)
+	if _, ok := m.enumNames[name]; ok {
+		return false
+	}
+	_, ok := m.messageNames[name]
+	return ok
+}
+
+func (m *migrator) isPackableType(fieldType string) bool {
+	if _, ok := scalarTypes[fieldType]; ok {
+		_, ok := lengthDelimitedTypes[fieldType]
+		return !ok
+	}
+	_, ok := m.enumNames[lastComponent(fieldType)]
+	return ok
+}
+
+func (m *migrator) addFailuref(position scanner.Position, format string, args ...interface{}) {
+	m.failures = append(m.failures, text.NewFailuref(position, FailureID, format, args...))
+}
+
+func isOptionsType(name string) bool {
+	name = strings.TrimPrefix(name, ".")
+	return strings.HasPrefix(name, "google.protobuf.") && strings.HasSuffix(name, "Options")
+}
+
+func hasOption(options []*proto.Option, name string) bool {
+	for _, option := range options {
+		if option.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func lastComponent(fieldType string) string {
+	split := strings.Split(fieldType, ".")
+	return split[len(split)-1]
+}
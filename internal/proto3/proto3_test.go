@@ -0,0 +1,137 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package proto3
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emicklei/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	descriptor := parse(t, `package foo;
+
+message Foo {
+  enum Kind {
+    KIND_INVALID = 0;
+  }
+  required int64 one = 1 [default = 5];
+  optional Foo two = 2;
+  optional Kind three = 3;
+  repeated int32 four = 4;
+  repeated string five = 5;
+  optional bar.Baz six = 6;
+  optional google.protobuf.Timestamp seven = 7;
+}
+`)
+	failures, err := Migrate(descriptor)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+	syntax := descriptor.Elements[0].(*proto.Syntax)
+	assert.Equal(t, "proto3", syntax.Value)
+	fields := getFields(descriptor.Elements[2].(*proto.Message))
+	require.Len(t, fields, 7)
+
+	assert.True(t, fields[0].Optional)
+	assert.False(t, fields[0].Required)
+	assert.Empty(t, fields[0].Options)
+	assert.Equal(t, []string{" The default value in proto2 was 5."}, fields[0].Comment.Lines)
+	assert.False(t, fields[1].Optional)
+	assert.True(t, fields[2].Optional)
+	require.Len(t, fields[3].Options, 1)
+	assert.Equal(t, "packed", fields[3].Options[0].Name)
+	assert.Equal(t, "false", fields[3].Options[0].Constant.Source)
+	assert.Empty(t, fields[4].Options)
+	assert.True(t, fields[5].Optional)
+	assert.False(t, fields[6].Optional)
+}
+
+func TestMigrateFailures(t *testing.T) {
+	descriptor := parse(t, `syntax = "proto2";
+
+package foo;
+
+message Foo {
+  optional group Bar = 1 {
+    optional int64 one = 2;
+  }
+  extensions 100 to 200;
+}
+
+enum Baz {
+  BAZ_ONE = 1;
+}
+
+extend Foo {
+  optional int64 two = 100;
+}
+
+extend google.protobuf.FieldOptions {
+  optional int64 three = 50000;
+}
+`)
+	failures, err := Migrate(descriptor)
+	require.NoError(t, err)
+	require.Len(t, failures, 4)
+	for _, failure := range failures {
+		assert.Equal(t, FailureID, failure.ID)
+	}
+	assert.Equal(t, 6, failures[0].Line)
+	assert.Equal(t, 9, failures[1].Line)
+	assert.Equal(t, 13, failures[2].Line)
+	assert.Contains(t, failures[2].Message, "BAZ_UNSPECIFIED = 0")
+	assert.Equal(t, 16, failures[3].Line)
+}
+
+func TestMigrateSyntax(t *testing.T) {
+	descriptor := parse(t, `syntax = "proto3";
+
+message Foo {
+  optional int64 one = 1;
+}
+`)
+	failures, err := Migrate(descriptor)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+	assert.True(t, getFields(descriptor.Elements[1].(*proto.Message))[0].Optional)
+
+	_, err = Migrate(parse(t, `syntax = "proto4";`))
+	assert.Error(t, err)
+}
+
+func getFields(message *proto.Message) []*proto.NormalField {
+	var fields []*proto.NormalField
+	for _, element := range message.Elements {
+		if field, ok := element.(*proto.NormalField); ok {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func parse(t *testing.T, data string) *proto.Proto {
+	descriptor, err := proto.NewParser(strings.NewReader(data)).Parse()
+	require.NoError(t, err)
+	return descriptor
+}
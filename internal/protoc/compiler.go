@@ -21,7 +21,6 @@
 package protoc
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -30,15 +29,21 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/uber/prototool/internal/exectrace"
 	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/module"
+	"github.com/uber/prototool/internal/progress"
 	"github.com/uber/prototool/internal/settings"
 	"github.com/uber/prototool/internal/text"
+	"github.com/uber/prototool/internal/timing"
 	"github.com/uber/prototool/internal/wkt"
 	"go.uber.org/zap"
 )
@@ -61,23 +66,58 @@ var (
 	optionValueRegexp                 = regexp.MustCompile("^(.*): Error while parsing option value for (.*)$")
 	programNotFoundRegexp             = regexp.MustCompile("protoc-gen-(.*): program not found or is not executable$")
 	firstEnumValueZeroRegexp          = regexp.MustCompile("^(.*): The first enum value must be zero in proto3.$")
+	// any other warning, optionally with a line and column
+	warningRegexp = regexp.MustCompile("^(.*?)(?::([0-9]+):([0-9]+))?: warning: (.*)$")
+
+	// the generators built into protoc, which are not looked up in plugin_dirs
+	builtinPluginNames = map[string]struct{}{
+		"cpp":    {},
+		"csharp": {},
+		"java":   {},
+		"js":     {},
+		"kotlin": {},
+		"objc":   {},
+		"php":    {},
+		"pyi":    {},
+		"python": {},
+		"ruby":   {},
+	}
 )
 
 type compiler struct {
 	logger              *zap.Logger
 	cachePath           string
 	protocURL           string
+	protocBinPath       string
+	protocWKTPath       string
 	doGen               bool
 	doFileDescriptorSet bool
+	warningsAsErrors    bool
+	jobs                int
+	timer               timing.Timer
+	execTracer          exectrace.Tracer
+	progressReporter    progress.Reporter
+	remoteExecutionURL  string
+	executor            executor
 }
 
 func newCompiler(options ...CompilerOption) *compiler {
 	compiler := &compiler{
-		logger: zap.NewNop(),
+		logger:           zap.NewNop(),
+		timer:            timing.NewNopTimer(),
+		execTracer:       exectrace.NewNopTracer(),
+		progressReporter: progress.NewNopReporter(),
 	}
 	for _, option := range options {
 		option(compiler)
 	}
+	if compiler.jobs < 1 {
+		compiler.jobs = runtime.NumCPU()
+	}
+	compiler.executor = localExecutor{execTracer: compiler.execTracer}
+	if compiler.remoteExecutionURL != "" {
+		compiler.executor = newRemoteExecutor(compiler.logger, compiler.remoteExecutionURL)
+	}
 	return compiler
 }
 
@@ -108,12 +148,23 @@ func (c *compiler) Compile(protoSet *file.ProtoSet) (*CompileResult, error) {
 	var errs []error
 	var lock sync.Mutex
 	var wg sync.WaitGroup
+	// the protoc invocations, including one per plugin, are independent
+	// so we run them concurrently, bounded by the number of jobs
+	semaphore := make(chan struct{}, c.jobs)
+	taskName := "Compiling"
+	if c.doGen {
+		taskName = "Generating"
+	}
+	task := c.progressReporter.Start(taskName, int64(len(cmdMetas)))
 	for _, cmdMeta := range cmdMetas {
 		cmdMeta := cmdMeta
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
 			iFailures, iErr := c.runCmdMeta(cmdMeta)
+			task.Add(1)
 			lock.Lock()
 			failures = append(failures, iFailures...)
 			if iErr != nil {
@@ -123,6 +174,7 @@ func (c *compiler) Compile(protoSet *file.ProtoSet) (*CompileResult, error) {
 		}()
 	}
 	wg.Wait()
+	task.Done()
 	// errors are not text.Failures, these are actual unhandled
 	// system errors from calling protoc, so we short circuit
 	if len(errs) > 0 {
@@ -137,11 +189,11 @@ func (c *compiler) Compile(protoSet *file.ProtoSet) (*CompileResult, error) {
 		}
 		return nil, errors.New(strings.Join(errStrings, "\n"))
 	}
-	// if we have failures, it does not matter if we have file descriptor sets
+	// if we have errors, it does not matter if we have file descriptor sets
 	// as we should error out, so we do not do any parsing of file descriptor sets
 	// this decision could be revisited
-	if len(failures) > 0 {
-		text.SortFailures(failures)
+	text.SortFailures(failures)
+	if text.ContainsError(failures...) {
 		return &CompileResult{
 			Failures: failures,
 		}, nil
@@ -160,6 +212,7 @@ func (c *compiler) Compile(protoSet *file.ProtoSet) (*CompileResult, error) {
 		}
 	}
 	return &CompileResult{
+		Failures:           failures,
 		FileDescriptorSets: fileDescriptorSets,
 	}, nil
 }
@@ -181,6 +234,22 @@ func (c *compiler) ProtocCommands(protoSet *file.ProtoSet) ([]string, error) {
 	return cmdMetaStrings, nil
 }
 
+func (c *compiler) ProtocPlan(protoSet *file.ProtoSet) ([]*ProtocCommand, error) {
+	// see ProtocCommands for why we clean up
+	cmdMetas, err := c.getCmdMetas(protoSet)
+	if err != nil {
+		return nil, err
+	}
+	protocCommands := make([]*ProtocCommand, 0, len(cmdMetas))
+	for _, cmdMeta := range cmdMetas {
+		protocCommands = append(protocCommands, cmdMeta.ProtocCommand())
+	}
+	cleanCmdMetas(cmdMetas)
+	// stable so that the plugins stay in the order of the config
+	sort.SliceStable(protocCommands, func(i int, j int) bool { return protocCommands[i].DirPath < protocCommands[j].DirPath })
+	return protocCommands, nil
+}
+
 func (c *compiler) makeGenDirs(protoSet *file.ProtoSet) error {
 	genDirs := make(map[string]struct{})
 	for _, genPlugin := range protoSet.Config.Gen.Plugins {
@@ -201,21 +270,24 @@ func (c *compiler) makeGenDirs(protoSet *file.ProtoSet) error {
 
 func (c *compiler) runCmdMeta(cmdMeta *cmdMeta) ([]*text.Failure, error) {
 	c.logger.Debug("running protoc", zap.String("command", cmdMeta.String()))
-	buffer := bytes.NewBuffer(nil)
-	cmdMeta.execCmd.Stderr = buffer
-	// we only need stderr to parse errors
-	// you have to explicitly set to ioutil.Discard, otherwise if there
-	// is a stdout, it will be printed to os.Stdout
-	cmdMeta.execCmd.Stdout = ioutil.Discard
-	runErr := cmdMeta.execCmd.Run()
-	if runErr != nil {
-		// exit errors are ok, we can probably parse them into text.Failures
-		// if not an exec.ExitError, short circuit
-		if _, ok := runErr.(*exec.ExitError); !ok {
-			return nil, runErr
-		}
-	}
-	output := strings.TrimSpace(buffer.String())
+	defer c.timer.Start(cmdMeta.phase)()
+	start := time.Now()
+	output, runErr := c.executor.execute(cmdMeta)
+	c.logger.Debug(
+		"ran protoc",
+		zap.String("command", cmdMeta.String()),
+		zap.Duration("duration", time.Since(start)),
+		zap.Bool("success", runErr == nil),
+	)
+	if killedErr, ok := runErr.(*killedError); ok {
+		return nil, fmt.Errorf("%s killed: %v", cmdMeta.phase, killedErr)
+	}
+	// exit errors are ok, we can probably parse them into text.Failures
+	// if not an exit error, short circuit
+	if runErr != nil && !isExitError(runErr) {
+		return nil, runErr
+	}
+	output = strings.TrimSpace(output)
 	if output != "" {
 		c.logger.Debug("protoc output", zap.String("output", output))
 	}
@@ -244,10 +316,17 @@ func (c *compiler) getCmdMetas(protoSet *file.ProtoSet) (cmdMetas []*cmdMeta, re
 			cmdMetas = nil
 		}
 	}()
+	protoSet, err := c.withProtobufVersion(protoSet)
+	if err != nil {
+		return nil, err
+	}
 	// you need a new downloader for every ProtoSet as each prototool.yaml could
 	// have a different protoc_version value
 	downloader := c.newDownloader(protoSet.Config)
-	if _, err := downloader.Download(); err != nil {
+	stopDownload := c.timer.Start("protoc download")
+	_, err = downloader.Download()
+	stopDownload()
+	if err != nil {
 		return cmdMetas, err
 	}
 	for dirPath, protoFiles := range protoSet.DirPathToFiles {
@@ -266,6 +345,10 @@ func (c *compiler) getCmdMetas(protoSet *file.ProtoSet) (cmdMetas []*cmdMeta, re
 		if configDirPath == "" {
 			configDirPath = protoSet.WorkDirPath
 		}
+		relDirPath, err := filepath.Rel(protoSet.WorkDirPath, dirPath)
+		if err != nil {
+			relDirPath = dirPath
+		}
 		includes, err := getIncludes(downloader, protoSet.Config, dirPath, configDirPath)
 		if err != nil {
 			return cmdMetas, err
@@ -274,6 +357,17 @@ func (c *compiler) getCmdMetas(protoSet *file.ProtoSet) (cmdMetas []*cmdMeta, re
 		for _, include := range includes {
 			args = append(args, "-I", include)
 		}
+		descriptorSetInArg, err := getDescriptorSetInArg(protoSet.Config)
+		if err != nil {
+			return cmdMetas, err
+		}
+		if descriptorSetInArg != "" {
+			args = append(args, descriptorSetInArg)
+		}
+		if needsExperimentalAllowProto3Optional(protoSet.Config) {
+			args = append(args, "--experimental_allow_proto3_optional")
+		}
+		args = append(args, protoSet.Config.Compile.ExtraArgs...)
 		protocPath, err := downloader.ProtocPath()
 		if err != nil {
 			return cmdMetas, err
@@ -308,24 +402,32 @@ func (c *compiler) getCmdMetas(protoSet *file.ProtoSet) (cmdMetas []*cmdMeta, re
 			cmdMetas = append(cmdMetas, &cmdMeta{
 				execCmd:    exec.Command(protocPath, iArgs...),
 				protoSet:   protoSet,
+				dirPath:    dirPath,
+				includes:   includes,
 				protoFiles: protoFiles,
+				phase:      "protoc " + relDirPath,
 				// used for cleaning up the cmdMeta after everything is done
 				descriptorSetTempFilePath: descriptorSetTempFilePath,
 			})
 		}
-		pluginFlagSets, err := c.getPluginFlagSets(protoSet, dirPath)
+		pluginFlagSets, err := c.getPluginFlagSets(downloader, protoSet, dirPath)
 		if err != nil {
 			return cmdMetas, err
 		}
-		for _, pluginFlagSet := range pluginFlagSets {
+		for i, pluginFlagSet := range pluginFlagSets {
 			iArgs := append(args, pluginFlagSet...)
 			for _, protoFile := range protoFiles {
 				iArgs = append(iArgs, protoFile.Path)
 			}
 			cmdMetas = append(cmdMetas, &cmdMeta{
-				execCmd:    exec.Command(protocPath, iArgs...),
-				protoSet:   protoSet,
-				protoFiles: protoFiles,
+				execCmd:       exec.Command(protocPath, iArgs...),
+				protoSet:      protoSet,
+				dirPath:       dirPath,
+				includes:      includes,
+				protoFiles:    protoFiles,
+				genPlugin:     &protoSet.Config.Gen.Plugins[i],
+				pluginFlagSet: pluginFlagSet,
+				phase:         "plugin " + getPluginName(pluginFlagSet) + " " + relDirPath,
 			})
 		}
 	}
@@ -335,6 +437,7 @@ func (c *compiler) getCmdMetas(protoSet *file.ProtoSet) (cmdMetas []*cmdMeta, re
 func (c *compiler) newDownloader(config settings.Config) Downloader {
 	downloaderOptions := []DownloaderOption{
 		DownloaderWithLogger(c.logger),
+		DownloaderWithExecTracer(c.execTracer),
 	}
 	if c.cachePath != "" {
 		downloaderOptions = append(
@@ -348,6 +451,22 @@ func (c *compiler) newDownloader(config settings.Config) Downloader {
 			DownloaderWithProtocURL(c.protocURL),
 		)
 	}
+	if c.protocBinPath != "" {
+		downloaderOptions = append(
+			downloaderOptions,
+			DownloaderWithProtocBinPath(c.protocBinPath),
+		)
+	}
+	if c.protocWKTPath != "" {
+		downloaderOptions = append(
+			downloaderOptions,
+			DownloaderWithProtocWKTPath(c.protocWKTPath),
+		)
+	}
+	downloaderOptions = append(
+		downloaderOptions,
+		DownloaderWithProgress(c.progressReporter),
+	)
 	return NewDownloader(config, downloaderOptions...)
 }
 
@@ -371,13 +490,50 @@ func (c *compiler) getDescriptorSetFilePath(protoSet *file.ProtoSet) (string, bo
 // examples:
 // []string{"--go_out=plugins=grpc:."}
 // []string{"--grpc-cpp_out=.", "--plugin=protoc-gen-grpc-cpp=/path/to/foo"}
-func (c *compiler) getPluginFlagSets(protoSet *file.ProtoSet, dirPath string) ([][]string, error) {
+func (c *compiler) getPluginFlagSets(downloader Downloader, protoSet *file.ProtoSet, dirPath string) ([][]string, error) {
 	// if not generating, or there are no plugins, nothing to do
 	if !c.doGen || len(protoSet.Config.Gen.Plugins) == 0 {
 		return nil, nil
 	}
 	pluginFlagSets := make([][]string, 0, len(protoSet.Config.Gen.Plugins))
 	for _, genPlugin := range protoSet.Config.Gen.Plugins {
+		// use the downloaded protoc-gen-validate unless there is an override
+		if genPlugin.Name == "validate" && genPlugin.Path == "" && protoSet.Config.Compile.ValidateVersion != "" {
+			validatePluginPath, err := downloader.ValidatePluginPath()
+			if err != nil {
+				return nil, err
+			}
+			genPlugin.Path = validatePluginPath
+		}
+		// use the downloaded grpc-gateway plugins for their presets unless there is an override
+		if _, ok := grpcGatewayPresets[genPlugin.Preset]; ok && genPlugin.Path == "" {
+			grpcGatewayPluginPath, err := downloader.GRPCGatewayPluginPath(genPlugin.Preset)
+			if err != nil {
+				return nil, err
+			}
+			genPlugin.Path = grpcGatewayPluginPath
+		}
+		if genPlugin.Path == "" {
+			switch genPlugin.Preset {
+			case "js":
+				protobufJavascriptPluginPath, err := downloader.ProtobufJavascriptPluginPath()
+				if err != nil {
+					return nil, err
+				}
+				genPlugin.Path = protobufJavascriptPluginPath
+			case "ts":
+				// if not installed with npm, protoc looks for protoc-gen-ts on the PATH
+				genPlugin.Path = getNodeModulesPluginPath(protoSet.Config.DirPath, tsPluginName)
+			}
+		}
+		// do not let protoc look up the plugin in PATH if plugin_dirs is set
+		if _, ok := builtinPluginNames[genPlugin.Name]; !ok && genPlugin.Path == "" && len(protoSet.Config.Gen.PluginDirPaths) > 0 {
+			pluginPath, err := getPluginDirsPluginPath(protoSet.Config.Gen.PluginDirPaths, genPlugin.Name)
+			if err != nil {
+				return nil, err
+			}
+			genPlugin.Path = pluginPath
+		}
 		pluginFlagSet, err := getPluginFlagSet(protoSet, dirPath, genPlugin)
 		if err != nil {
 			return nil, err
@@ -387,6 +543,28 @@ func (c *compiler) getPluginFlagSets(protoSet *file.ProtoSet, dirPath string) ([
 	return pluginFlagSets, nil
 }
 
+// getPluginDirsPluginPath returns the path to protoc-gen-NAME in the first
+// of the directories that has it.
+func getPluginDirsPluginPath(pluginDirPaths []string, name string) (string, error) {
+	pluginFileName := getExecutableName(runtime.GOOS, "protoc-gen-"+name)
+	for _, pluginDirPath := range pluginDirPaths {
+		pluginPath := filepath.Join(pluginDirPath, pluginFileName)
+		if fileInfo, err := os.Stat(pluginPath); err == nil && !fileInfo.IsDir() {
+			return pluginPath, nil
+		}
+	}
+	return "", fmt.Errorf("could not find %s for plugin %s in plugin_dirs %s", pluginFileName, name, strings.Join(pluginDirPaths, ", "))
+}
+
+// getPluginName returns the plugin name from the --NAME_out flag
+// of the plugin flag set.
+func getPluginName(pluginFlagSet []string) string {
+	if len(pluginFlagSet) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(strings.SplitN(pluginFlagSet[0], "=", 2)[0], "--"), "_out")
+}
+
 func getPluginFlagSet(protoSet *file.ProtoSet, dirPath string, genPlugin settings.GenPlugin) ([]string, error) {
 	protoFlags, err := getPluginFlagSetProtoFlags(protoSet, dirPath, genPlugin)
 	if err != nil {
@@ -399,7 +577,21 @@ func getPluginFlagSet(protoSet *file.ProtoSet, dirPath string, genPlugin setting
 	if genPlugin.Path != "" {
 		flagSet = append(flagSet, fmt.Sprintf("--plugin=protoc-gen-%s=%s", genPlugin.Name, genPlugin.Path))
 	}
-	return flagSet, nil
+	return append(flagSet, genPlugin.ProtocArgs...), nil
+}
+
+// appendModifierFlags appends the Mfile=package flags sorted by file so
+// that the protoc commands are the same on every run.
+func appendModifierFlags(goFlags []string, modifiers map[string]string) []string {
+	keys := make([]string, 0, len(modifiers))
+	for key := range modifiers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		goFlags = append(goFlags, fmt.Sprintf("M%s=%s", key, modifiers[key]))
+	}
+	return goFlags
 }
 
 // the return value corresponds to CodeGeneratorRequest.Parameter
@@ -427,28 +619,40 @@ func getPluginFlagSetProtoFlags(protoSet *file.ProtoSet, dirPath string, genPlug
 			// these packages in as imports
 			if subDirPath != dirPath {
 				for _, protoFile := range protoFiles {
-					path, err := filepath.Rel(protoSet.Config.DirPath, protoFile.Path)
+					path, err := file.ImportPath(protoSet, protoFile.Path)
 					if err != nil {
 						// TODO: best effort, maybe error
 						path = protoFile.Path
 					}
 					// TODO: if relative path in OutputPath.RelPath jumps out of import path context, this will be wrong
-					modifiers[path] = filepath.Clean(filepath.Join(genGoPluginOptions.ImportPath, genPlugin.OutputPath.RelPath, filepath.Dir(path)))
+					// Go packages are always slash-separated, including on Windows
+					modifiers[path] = filepath.ToSlash(filepath.Clean(filepath.Join(genGoPluginOptions.ImportPath, genPlugin.OutputPath.RelPath, filepath.Dir(filepath.FromSlash(path)))))
 				}
 			}
 		}
-		for key, value := range modifiers {
-			goFlags = append(goFlags, fmt.Sprintf("M%s=%s", key, value))
-		}
-		if protoSet.Config.Compile.IncludeWellKnownTypes {
+		goFlags = appendModifierFlags(goFlags, modifiers)
+		// presets always map the Well-Known Types as configuring this by hand is error-prone
+		if protoSet.Config.Compile.IncludeWellKnownTypes || genPlugin.Preset != "" {
 			// one of these two must be true, we validate this above
 			if genPlugin.Type.IsGo() {
 				modifiers = wkt.FilenameToGoModifierMap
 			} else if genPlugin.Type.IsGogo() {
 				modifiers = wkt.FilenameToGogoModifierMap
 			}
-			for key, value := range modifiers {
-				goFlags = append(goFlags, fmt.Sprintf("M%s=%s", key, value))
+			goFlags = appendModifierFlags(goFlags, modifiers)
+		}
+		if protoSet.Config.Compile.ValidateVersion != "" {
+			goFlags = append(goFlags, fmt.Sprintf("M%s=%s", validateIncludeFile, validateGoPackage))
+		}
+		if protoSet.Config.Compile.GogoProtobufVersion != "" {
+			goFlags = append(goFlags, fmt.Sprintf("M%s=%s", gogoIncludeFile, gogoGoPackage))
+		}
+		if protoSet.Config.Compile.GRPCGatewayVersion != "" {
+			for _, includeFile := range grpcGatewayGoogleapisIncludeFiles {
+				goFlags = append(goFlags, fmt.Sprintf("M%s=%s", includeFile, googleapisAPIGoPackage))
+			}
+			for _, includeFile := range grpcGatewayOpenAPIIncludeFiles {
+				goFlags = append(goFlags, fmt.Sprintf("M%s=%s", includeFile, grpcGatewayOpenAPIGoPackage))
 			}
 		}
 	}
@@ -462,6 +666,20 @@ func getIncludes(downloader Downloader, config settings.Config, dirPath string,
 	var includes []string
 	fileInIncludePath := false
 	includedConfigDirPath := false
+	// in a multi-root workspace, the root of the file comes first so that
+	// the file is always compiled relative to its root, and the other roots
+	// follow so that files can import files in other roots
+	fileRoot, fileInRoot := file.GetRoot(config, dirPath)
+	if fileInRoot {
+		includes = append(includes, fileRoot.DirPath)
+		includes = append(includes, fileRoot.IncludePaths...)
+		fileInIncludePath = true
+	}
+	for _, root := range config.Compile.Roots {
+		if !fileInRoot || root.DirPath != fileRoot.DirPath {
+			includes = append(includes, root.DirPath)
+		}
+	}
 	for _, includePath := range config.Compile.IncludePaths {
 		includes = append(includes, includePath)
 		// TODO: not exactly platform independent
@@ -483,6 +701,34 @@ func getIncludes(downloader Downloader, config settings.Config, dirPath string,
 			fileInIncludePath = true
 		}
 	}
+	if config.Compile.ValidateVersion != "" {
+		validateIncludePath, err := downloader.ValidateIncludePath()
+		if err != nil {
+			return nil, err
+		}
+		includes = append(includes, validateIncludePath)
+	}
+	if config.Compile.GogoProtobufVersion != "" {
+		gogoIncludePath, err := downloader.GogoIncludePath()
+		if err != nil {
+			return nil, err
+		}
+		includes = append(includes, gogoIncludePath)
+	}
+	if config.Compile.GRPCGatewayVersion != "" {
+		grpcGatewayIncludePath, err := downloader.GRPCGatewayIncludePath()
+		if err != nil {
+			return nil, err
+		}
+		includes = append(includes, grpcGatewayIncludePath)
+	}
+	if config.Compile.GoogleapisVersion != "" {
+		googleapisIncludePath, err := downloader.GoogleapisIncludePath()
+		if err != nil {
+			return nil, err
+		}
+		includes = append(includes, googleapisIncludePath)
+	}
 	// you want your proto files to be in at least one of the -I directories
 	// or otherwise things can get weird
 	// if the file is not in one of the -I directories and we haven't included
@@ -494,6 +740,50 @@ func getIncludes(downloader Downloader, config settings.Config, dirPath string,
 	return includes, nil
 }
 
+// needsExperimentalAllowProto3Optional returns true if the protoc version of
+// the config only allows optional fields in proto3 files with the flag
+// --experimental_allow_proto3_optional, which is true for 3.12 to 3.14.
+// Earlier versions do not know about the flag, and later versions allow
+// optional fields in proto3 files without it.
+func needsExperimentalAllowProto3Optional(config settings.Config) bool {
+	if config.Compile.ProtobufVersion == "" {
+		return false
+	}
+	split := strings.SplitN(config.Compile.ProtobufVersion, ".", 3)
+	if len(split) < 2 {
+		return false
+	}
+	major, err := strconv.Atoi(split[0])
+	if err != nil {
+		return false
+	}
+	minor, err := strconv.Atoi(split[1])
+	if err != nil {
+		return false
+	}
+	return major == 3 && minor >= 12 && minor <= 14
+}
+
+// getDescriptorSetInArg returns the --descriptor_set_in flag for the
+// fetched module deps of the config, or an empty string if there are none.
+func getDescriptorSetInArg(config settings.Config) (string, error) {
+	if len(config.Modules.Deps) == 0 {
+		return "", nil
+	}
+	filePaths := make([]string, 0, len(config.Modules.Deps))
+	for _, dep := range config.Modules.Deps {
+		filePath := module.DescriptorSetFilePath(config.DirPath, dep.Name, dep.Version)
+		if _, err := os.Stat(filePath); err != nil {
+			if os.IsNotExist(err) {
+				return "", fmt.Errorf("module %s version %s has not been fetched, run prototool module fetch", dep.Name, dep.Version)
+			}
+			return "", err
+		}
+		filePaths = append(filePaths, filePath)
+	}
+	return "--descriptor_set_in=" + strings.Join(filePaths, string(os.PathListSeparator)), nil
+}
+
 // we try to handle all protoc errors to convert them into text.Failures
 // so we can output failures in the standard filename:line:column:message format
 func (c *compiler) parseProtocOutput(cmdMeta *cmdMeta, output string) []*text.Failure {
@@ -520,7 +810,7 @@ func (c *compiler) parseProtocLine(cmdMeta *cmdMeta, protocLine string) *text.Fa
 			Message: fmt.Sprintf("protoc-gen-%s: %s", matches[1], matches[2]),
 		}
 	}
-	split := strings.Split(protocLine, ":")
+	split := splitProtocLine(protocLine)
 	if len(split) != 4 {
 		if matches := noSyntaxSpecifiedRegexp.FindStringSubmatch(protocLine); len(matches) > 1 {
 			return &text.Failure{
@@ -529,12 +819,25 @@ func (c *compiler) parseProtocLine(cmdMeta *cmdMeta, protocLine string) *text.Fa
 			}
 		}
 		if matches := extraImportRegexp.FindStringSubmatch(protocLine); len(matches) > 2 {
+			failure := &text.Failure{
+				Filename: bestFilePath(cmdMeta, matches[1]),
+				Message:  fmt.Sprintf(`Import "%s" was not used.`, matches[2]),
+			}
 			if cmdMeta.protoSet.Config.Compile.AllowUnusedImports {
-				return nil
+				failure.Severity = c.getWarningSeverity(cmdMeta)
 			}
+			return failure
+		}
+		if matches := warningRegexp.FindStringSubmatch(protocLine); len(matches) > 4 {
+			// the line and column will be 0 if not matched
+			line, _ := strconv.Atoi(matches[2])
+			column, _ := strconv.Atoi(matches[3])
 			return &text.Failure{
 				Filename: bestFilePath(cmdMeta, matches[1]),
-				Message:  fmt.Sprintf(`Import "%s" was not used.`, matches[2]),
+				Line:     line,
+				Column:   column,
+				Message:  matches[4],
+				Severity: c.getWarningSeverity(cmdMeta),
 			}
 		}
 		if matches := fileNotFoundRegexp.FindStringSubmatch(protocLine); len(matches) > 1 {
@@ -617,6 +920,28 @@ func (c *compiler) parseProtocLine(cmdMeta *cmdMeta, protocLine string) *text.Fa
 	}
 }
 
+// splitProtocLine splits a protoc output line of the form file:line:column:message
+// on colons, keeping a leading Windows drive letter such as C: with the file.
+func splitProtocLine(protocLine string) []string {
+	if len(protocLine) > 2 && protocLine[1] == ':' && (protocLine[2] == '\\' || protocLine[2] == '/') {
+		if c := protocLine[0]; ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') {
+			split := strings.Split(protocLine[2:], ":")
+			split[0] = protocLine[:2] + split[0]
+			return split
+		}
+	}
+	return strings.Split(protocLine, ":")
+}
+
+// getWarningSeverity returns the severity to use for protoc warnings,
+// which is an error if warnings are treated as errors.
+func (c *compiler) getWarningSeverity(cmdMeta *cmdMeta) string {
+	if c.warningsAsErrors || cmdMeta.protoSet.Config.Compile.WarningsAsErrors {
+		return text.SeverityError
+	}
+	return text.SeverityWarning
+}
+
 func (c *compiler) handleUninterpretedProtocLine(protocLine string) *text.Failure {
 	c.logger.Warn("protoc returned a line we do not understand, please file this as an issue "+
 		"at https://github.com/uber/prototool/issues/new", zap.String("protocLine", protocLine))
@@ -707,14 +1032,70 @@ func cleanCmdMetas(cmdMetas []*cmdMeta) {
 type cmdMeta struct {
 	execCmd                   *exec.Cmd
 	protoSet                  *file.ProtoSet
+	dirPath                   string
+	includes                  []string
 	protoFiles                []*file.ProtoFile
 	descriptorSetTempFilePath string
+	// only set for plugin commands
+	genPlugin     *settings.GenPlugin
+	pluginFlagSet []string
+	// the name of the timing phase for this command
+	phase string
+}
+
+// timeout returns the timeout of the command, preferring the
+// timeout of the plugin if set.
+func (c *cmdMeta) timeout() time.Duration {
+	if c.genPlugin != nil && c.genPlugin.Timeout != 0 {
+		return c.genPlugin.Timeout
+	}
+	return c.protoSet.Config.Compile.Timeout
 }
 
 func (c *cmdMeta) String() string {
 	return strings.Join(c.execCmd.Args, " ")
 }
 
+func (c *cmdMeta) ProtocCommand() *ProtocCommand {
+	protocCommand := &ProtocCommand{
+		ProtocPath:   c.execCmd.Args[0],
+		Args:         c.execCmd.Args[1:],
+		DirPath:      c.dirPath,
+		IncludePaths: c.includes,
+		FilePaths:    make([]string, 0, len(c.protoFiles)),
+	}
+	for _, protoFile := range c.protoFiles {
+		protocCommand.FilePaths = append(protocCommand.FilePaths, protoFile.Path)
+	}
+	if c.genPlugin != nil {
+		protocCommand.Plugin = getProtocCommandPlugin(*c.genPlugin, c.pluginFlagSet)
+	}
+	return protocCommand
+}
+
+// getProtocCommandPlugin gets the plugin from the flag set returned by
+// getPluginFlagSet, as the path and flags are only resolved there.
+func getProtocCommandPlugin(genPlugin settings.GenPlugin, pluginFlagSet []string) *ProtocCommandPlugin {
+	protocCommandPlugin := &ProtocCommandPlugin{
+		Name:       genPlugin.Name,
+		OutputPath: genPlugin.OutputPath.AbsPath,
+		ProtocArgs: genPlugin.ProtocArgs,
+	}
+	outFlagPrefix := fmt.Sprintf("--%s_out=", genPlugin.Name)
+	pluginFlagPrefix := fmt.Sprintf("--plugin=protoc-gen-%s=", genPlugin.Name)
+	for _, flag := range pluginFlagSet[:len(pluginFlagSet)-len(genPlugin.ProtocArgs)] {
+		switch {
+		case strings.HasPrefix(flag, outFlagPrefix):
+			if value := strings.TrimPrefix(flag, outFlagPrefix); value != genPlugin.OutputPath.AbsPath {
+				protocCommandPlugin.Flags = strings.TrimSuffix(value, ":"+genPlugin.OutputPath.AbsPath)
+			}
+		case strings.HasPrefix(flag, pluginFlagPrefix):
+			protocCommandPlugin.Path = strings.TrimPrefix(flag, pluginFlagPrefix)
+		}
+	}
+	return protocCommandPlugin
+}
+
 func (c *cmdMeta) Clean() {
 	tryRemoveTempFile(c.descriptorSetTempFilePath)
 }
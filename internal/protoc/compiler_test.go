@@ -0,0 +1,444 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
+)
+
+func TestNeedsExperimentalAllowProto3Optional(t *testing.T) {
+	for version, expected := range map[string]bool{
+		"":       false,
+		"3.5.1":  false,
+		"3.11.4": false,
+		"3.12.0": true,
+		"3.14.0": true,
+		"3.15.8": false,
+		"4.0.0":  false,
+		"foo":    false,
+	} {
+		config := settings.Config{
+			Compile: settings.CompileConfig{
+				ProtobufVersion: version,
+			},
+		}
+		assert.Equal(t, expected, needsExperimentalAllowProto3Optional(config), version)
+	}
+}
+
+func TestGetPluginFlagSetProtoFlagsGogoPreset(t *testing.T) {
+	protoSet := &file.ProtoSet{
+		Config: settings.Config{
+			Compile: settings.CompileConfig{
+				GogoProtobufVersion: "1.3.2",
+			},
+			Gen: settings.GenConfig{
+				GoPluginOptions: settings.GenGoPluginOptions{
+					ImportPath: "github.com/foo/bar",
+				},
+			},
+		},
+	}
+	genPlugin := settings.GenPlugin{
+		Name:   "gogofaster",
+		Type:   settings.GenPluginTypeGogo,
+		Flags:  "plugins=grpc",
+		Preset: "gogofaster",
+	}
+	protoFlags, err := getPluginFlagSetProtoFlags(protoSet, "/tmp", genPlugin)
+	require.NoError(t, err)
+	flags := strings.Split(protoFlags, ",")
+	assert.Equal(t, "plugins=grpc", flags[0])
+	assert.Contains(t, flags, "Mgoogle/protobuf/any.proto=github.com/gogo/protobuf/types")
+	assert.Contains(t, flags, "Mgogoproto/gogo.proto=github.com/gogo/protobuf/gogoproto")
+
+	genPlugin.Preset = ""
+	protoFlags, err = getPluginFlagSetProtoFlags(protoSet, "/tmp", genPlugin)
+	require.NoError(t, err)
+	flags = strings.Split(protoFlags, ",")
+	assert.NotContains(t, flags, "Mgoogle/protobuf/any.proto=github.com/gogo/protobuf/types")
+	assert.Contains(t, flags, "Mgogoproto/gogo.proto=github.com/gogo/protobuf/gogoproto")
+}
+
+func TestGetPluginFlagSetProtoFlagsGRPCGatewayPreset(t *testing.T) {
+	protoSet := &file.ProtoSet{
+		Config: settings.Config{
+			Compile: settings.CompileConfig{
+				GRPCGatewayVersion: "2.16.0",
+			},
+			Gen: settings.GenConfig{
+				GoPluginOptions: settings.GenGoPluginOptions{
+					ImportPath: "github.com/foo/bar",
+				},
+			},
+		},
+	}
+	genPlugin := settings.GenPlugin{
+		Name:   "grpc-gateway",
+		Type:   settings.GenPluginTypeGo,
+		Preset: "grpc-gateway",
+	}
+	protoFlags, err := getPluginFlagSetProtoFlags(protoSet, "/tmp", genPlugin)
+	require.NoError(t, err)
+	flags := strings.Split(protoFlags, ",")
+	assert.Contains(t, flags, "Mgoogle/protobuf/any.proto=github.com/golang/protobuf/ptypes/any")
+	assert.Contains(t, flags, "Mgoogle/api/annotations.proto=google.golang.org/genproto/googleapis/api/annotations")
+	assert.Contains(t, flags, "Mprotoc-gen-openapiv2/options/annotations.proto=github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2/options")
+}
+
+func TestGetNodeModulesPluginPath(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	binDirPath := filepath.Join(tmpDir, "node_modules", ".bin")
+	require.NoError(t, os.MkdirAll(binDirPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(binDirPath, tsPluginName), nil, 0755))
+	dirPath := filepath.Join(tmpDir, "foo", "bar")
+	require.NoError(t, os.MkdirAll(dirPath, 0755))
+
+	assert.Equal(t, filepath.Join(binDirPath, tsPluginName), getNodeModulesPluginPath(dirPath, tsPluginName))
+	assert.Equal(t, "", getNodeModulesPluginPath(dirPath, jsPluginName))
+}
+
+func TestGetPluginFlagSetsPluginDirs(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	firstDirPath := filepath.Join(tmpDir, "first")
+	secondDirPath := filepath.Join(tmpDir, "second")
+	require.NoError(t, os.MkdirAll(firstDirPath, 0755))
+	require.NoError(t, os.MkdirAll(secondDirPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(firstDirPath, "protoc-gen-go"), nil, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(secondDirPath, "protoc-gen-go"), nil, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(secondDirPath, "protoc-gen-foo"), nil, 0755))
+
+	protoSet := &file.ProtoSet{
+		Config: settings.Config{
+			DirPath: tmpDir,
+			Gen: settings.GenConfig{
+				Plugins: []settings.GenPlugin{
+					{
+						Name:       "foo",
+						OutputPath: settings.OutputPath{AbsPath: "/out"},
+					},
+					{
+						Name:       "go",
+						OutputPath: settings.OutputPath{AbsPath: "/out"},
+					},
+					{
+						Name:       "java",
+						OutputPath: settings.OutputPath{AbsPath: "/out"},
+					},
+					{
+						Name:       "bar",
+						Path:       "/usr/local/bin/protoc-gen-bar",
+						OutputPath: settings.OutputPath{AbsPath: "/out"},
+					},
+				},
+				PluginDirPaths: []string{firstDirPath, secondDirPath},
+			},
+		},
+	}
+	pluginFlagSets, err := newCompiler(CompilerWithGen()).getPluginFlagSets(nil, protoSet, tmpDir)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[][]string{
+			{"--foo_out=/out", "--plugin=protoc-gen-foo=" + filepath.Join(secondDirPath, "protoc-gen-foo")},
+			{"--go_out=/out", "--plugin=protoc-gen-go=" + filepath.Join(firstDirPath, "protoc-gen-go")},
+			{"--java_out=/out"},
+			{"--bar_out=/out", "--plugin=protoc-gen-bar=/usr/local/bin/protoc-gen-bar"},
+		},
+		pluginFlagSets,
+	)
+
+	protoSet.Config.Gen.Plugins = append(protoSet.Config.Gen.Plugins, settings.GenPlugin{
+		Name:       "baz",
+		OutputPath: settings.OutputPath{AbsPath: "/out"},
+	})
+	_, err = newCompiler(CompilerWithGen()).getPluginFlagSets(nil, protoSet, tmpDir)
+	assert.Error(t, err)
+}
+
+func TestGetPluginFlagSetProtocArgs(t *testing.T) {
+	protoSet := &file.ProtoSet{
+		Config: settings.Config{
+			DirPath: "/tmp/foo",
+		},
+	}
+	flagSet, err := getPluginFlagSet(
+		protoSet,
+		"/tmp/foo",
+		settings.GenPlugin{
+			Name:       "foo",
+			Path:       "/usr/local/bin/protoc-gen-foo",
+			OutputPath: settings.OutputPath{AbsPath: "/out"},
+			ProtocArgs: []string{"--foo_opt=bar", "--experimental_editions"},
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			"--foo_out=/out",
+			"--plugin=protoc-gen-foo=/usr/local/bin/protoc-gen-foo",
+			"--foo_opt=bar",
+			"--experimental_editions",
+		},
+		flagSet,
+	)
+	assert.Equal(t, "foo", getPluginName(flagSet))
+}
+
+func TestCmdMetaProtocCommand(t *testing.T) {
+	genPlugin := settings.GenPlugin{
+		Name:       "foo",
+		Path:       "/usr/local/bin/protoc-gen-foo",
+		Flags:      "plugins=grpc",
+		OutputPath: settings.OutputPath{AbsPath: "/out"},
+		ProtocArgs: []string{"--foo_opt=bar"},
+	}
+	protoSet := &file.ProtoSet{
+		Config: settings.Config{
+			DirPath: "/tmp/foo",
+		},
+	}
+	pluginFlagSet, err := getPluginFlagSet(protoSet, "/tmp/foo", genPlugin)
+	require.NoError(t, err)
+	cmdMeta := &cmdMeta{
+		execCmd:       exec.Command("protoc", append(append([]string{"-I", "/tmp/foo"}, pluginFlagSet...), "/tmp/foo/a.proto")...),
+		protoSet:      protoSet,
+		dirPath:       "/tmp/foo",
+		includes:      []string{"/tmp/foo"},
+		protoFiles:    []*file.ProtoFile{{Path: "/tmp/foo/a.proto"}},
+		genPlugin:     &genPlugin,
+		pluginFlagSet: pluginFlagSet,
+	}
+	assert.Equal(
+		t,
+		&ProtocCommand{
+			ProtocPath: "protoc",
+			Args: []string{
+				"-I",
+				"/tmp/foo",
+				"--foo_out=plugins=grpc:/out",
+				"--plugin=protoc-gen-foo=/usr/local/bin/protoc-gen-foo",
+				"--foo_opt=bar",
+				"/tmp/foo/a.proto",
+			},
+			DirPath:      "/tmp/foo",
+			IncludePaths: []string{"/tmp/foo"},
+			FilePaths:    []string{"/tmp/foo/a.proto"},
+			Plugin: &ProtocCommandPlugin{
+				Name:       "foo",
+				Path:       "/usr/local/bin/protoc-gen-foo",
+				Flags:      "plugins=grpc",
+				OutputPath: "/out",
+				ProtocArgs: []string{"--foo_opt=bar"},
+			},
+		},
+		cmdMeta.ProtocCommand(),
+	)
+}
+
+func TestSplitProtocLine(t *testing.T) {
+	for protocLine, expected := range map[string][]string{
+		"foo/bar.proto:1:2: Expected a message.":     {"foo/bar.proto", "1", "2", " Expected a message."},
+		`C:\foo\bar.proto:1:2: Expected a message.`:  {`C:\foo\bar.proto`, "1", "2", " Expected a message."},
+		"c:/foo/bar.proto:1:2: Expected a message.":  {"c:/foo/bar.proto", "1", "2", " Expected a message."},
+		"foo.proto: Import bar.proto was not found.": {"foo.proto", " Import bar.proto was not found."},
+		"--go_out: protoc-gen-go: Plugin failed.":    {"--go_out", " protoc-gen-go", " Plugin failed."},
+	} {
+		assert.Equal(t, expected, splitProtocLine(protocLine), protocLine)
+	}
+}
+
+func TestCompileWarnings(t *testing.T) {
+	protoSet, protocBinPath, protocWKTPath := newTestCompileEnv(t, `>&2 echo "foo.proto:3:1: warning: Message name should be in UpperCamelCase."
+>&2 echo "foo.proto: warning: Import bar.proto but not used."
+`)
+	defer func() { _ = os.RemoveAll(protoSet.WorkDirPath) }()
+	protoSet.Config.Compile.AllowUnusedImports = true
+
+	compileResult, err := NewCompiler(
+		CompilerWithProtocBinPath(protocBinPath),
+		CompilerWithProtocWKTPath(protocWKTPath),
+	).Compile(protoSet)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*text.Failure{
+			{
+				Filename: "foo.proto",
+				Message:  `Import "bar.proto" was not used.`,
+				Severity: text.SeverityWarning,
+			},
+			{
+				Filename: "foo.proto",
+				Line:     3,
+				Column:   1,
+				Message:  "Message name should be in UpperCamelCase.",
+				Severity: text.SeverityWarning,
+			},
+		},
+		compileResult.Failures,
+	)
+	assert.False(t, text.ContainsError(compileResult.Failures...))
+	buffer := bytes.NewBuffer(nil)
+	for _, failure := range compileResult.Failures {
+		require.NoError(t, failure.Fprintln(buffer, text.DefaultFailureFields...))
+	}
+	assert.Equal(
+		t,
+		`foo.proto:1:1:warning: Import "bar.proto" was not used.
+foo.proto:3:1:warning: Message name should be in UpperCamelCase.
+`,
+		buffer.String(),
+	)
+
+	compileResult, err = NewCompiler(
+		CompilerWithProtocBinPath(protocBinPath),
+		CompilerWithProtocWKTPath(protocWKTPath),
+		CompilerWithWarningsAsErrors(),
+	).Compile(protoSet)
+	require.NoError(t, err)
+	require.Len(t, compileResult.Failures, 2)
+	for _, failure := range compileResult.Failures {
+		assert.Equal(t, text.SeverityError, failure.Severity)
+	}
+	assert.True(t, text.ContainsError(compileResult.Failures...))
+}
+
+func TestCompileJobs(t *testing.T) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool-compiler-jobs")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	runningDirPath := filepath.Join(tmpDirPath, "running")
+	require.NoError(t, os.MkdirAll(runningDirPath, 0755))
+	logFilePath := filepath.Join(tmpDirPath, "log")
+	// every invocation records how many invocations are running at once
+	protoSet, protocBinPath, protocWKTPath := newTestCompileEnv(t, fmt.Sprintf(`touch %s/$$
+sleep 0.1
+ls %s | wc -l >> %s
+rm %s/$$
+`, runningDirPath, runningDirPath, logFilePath, runningDirPath))
+	defer func() { _ = os.RemoveAll(protoSet.WorkDirPath) }()
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		dirPath := filepath.Join(protoSet.DirPath, name)
+		require.NoError(t, os.MkdirAll(dirPath, 0755))
+		filePath := filepath.Join(dirPath, "foo.proto")
+		require.NoError(t, ioutil.WriteFile(filePath, []byte("syntax = \"proto3\";\n\npackage "+name+";\n"), 0644))
+		protoSet.DirPathToFiles[dirPath] = []*file.ProtoFile{
+			{
+				Path:        filePath,
+				DisplayPath: filepath.Join(name, "foo.proto"),
+			},
+		}
+	}
+
+	for _, jobs := range []int{1, 2, 3} {
+		require.NoError(t, ioutil.WriteFile(logFilePath, nil, 0644))
+		compileResult, err := NewCompiler(
+			CompilerWithProtocBinPath(protocBinPath),
+			CompilerWithProtocWKTPath(protocWKTPath),
+			CompilerWithJobs(jobs),
+		).Compile(protoSet)
+		require.NoError(t, err)
+		assert.Empty(t, compileResult.Failures)
+		data, err := ioutil.ReadFile(logFilePath)
+		require.NoError(t, err)
+		counts := strings.Fields(string(data))
+		require.Len(t, counts, 7, "jobs %d", jobs)
+		for _, count := range counts {
+			running, err := strconv.Atoi(count)
+			require.NoError(t, err)
+			assert.True(t, running >= 1 && running <= jobs, "jobs %d ran %d at once", jobs, running)
+		}
+	}
+}
+
+func TestNewCompilerJobs(t *testing.T) {
+	for jobs, expected := range map[int]int{
+		-1: runtime.NumCPU(),
+		0:  runtime.NumCPU(),
+		1:  1,
+		4:  4,
+	} {
+		assert.Equal(t, expected, newCompiler(CompilerWithJobs(jobs)).jobs, "jobs %d", jobs)
+	}
+	assert.Equal(t, runtime.NumCPU(), newCompiler().jobs)
+}
+
+// newTestCompileEnv creates a temporary directory with a single foo.proto
+// and a fake protoc that runs the given shell script for every invocation
+// other than --version, and returns the ProtoSet and the protoc bin and wkt paths.
+//
+// The caller is responsible for removing protoSet.WorkDirPath.
+func newTestCompileEnv(t *testing.T, script string) (*file.ProtoSet, string, string) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool-compiler")
+	require.NoError(t, err)
+	protoDirPath := filepath.Join(tmpDirPath, "proto")
+	binDirPath := filepath.Join(tmpDirPath, "protoc", "bin")
+	wktDirPath := filepath.Join(tmpDirPath, "protoc", "include")
+	for _, dirPath := range []string{protoDirPath, binDirPath, filepath.Join(wktDirPath, "google", "protobuf")} {
+		require.NoError(t, os.MkdirAll(dirPath, 0755))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(wktDirPath, "google", "protobuf", "descriptor.proto"), []byte(`syntax = "proto2";`), 0644))
+	protoFilePath := filepath.Join(protoDirPath, "foo.proto")
+	require.NoError(t, ioutil.WriteFile(protoFilePath, []byte("syntax = \"proto3\";\n\npackage foo;\n"), 0644))
+	protocBinPath := filepath.Join(binDirPath, "protoc")
+	require.NoError(t, ioutil.WriteFile(protocBinPath, []byte(`#!/bin/sh
+if [ "$1" = "--version" ]; then
+  echo "libprotoc 3.11.0"
+  exit 0
+fi
+`+script), 0755))
+	return &file.ProtoSet{
+		WorkDirPath: protoDirPath,
+		DirPath:     protoDirPath,
+		DirPathToFiles: map[string][]*file.ProtoFile{
+			protoDirPath: {
+				{
+					Path:        protoFilePath,
+					DisplayPath: "foo.proto",
+				},
+			},
+		},
+		Config: settings.Config{
+			DirPath: protoDirPath,
+		},
+	}, protocBinPath, wktDirPath
+}
@@ -27,14 +27,16 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/uber/prototool/internal/exectrace"
+	"github.com/uber/prototool/internal/progress"
 	"github.com/uber/prototool/internal/settings"
 	"github.com/uber/prototool/internal/vars"
 	"go.uber.org/multierr"
@@ -45,17 +47,49 @@ type downloader struct {
 	logger    *zap.Logger
 	cachePath string
 	protocURL string
-	config    settings.Config
+	// the path or name of a protoc binary to use instead of downloading
+	protocBinPath string
+	// the path to include for the well-known types if protocBinPath is set
+	protocWKTPath string
+	execTracer    exectrace.Tracer
+	config        settings.Config
 
+	progressReporter progress.Reporter
+
+	// each artifact has its own lock so that they can be downloaded concurrently
 	lock sync.RWMutex
 	// the looked-up and verified to exist base path
 	cachedBasePath string
+	// the looked-up and verified to run path to protoc if protocBinPath is set
+	cachedProtocBinPath string
+
+	validateLock sync.Mutex
+	// the looked-up and verified to exist base path for protoc-gen-validate
+	cachedValidateBasePath string
+
+	gogoLock sync.Mutex
+	// the looked-up and verified to exist base path for gogo.proto
+	cachedGogoBasePath string
+
+	grpcGatewayLock sync.Mutex
+	// the looked-up and verified to exist base path for grpc-gateway
+	cachedGRPCGatewayBasePath string
+
+	protobufJavascriptLock sync.Mutex
+	// the looked-up and verified to exist base path for protobuf-javascript
+	cachedProtobufJavascriptBasePath string
+
+	googleapisLock sync.Mutex
+	// the looked-up and verified to exist base path for googleapis
+	cachedGoogleapisBasePath string
 }
 
 func newDownloader(config settings.Config, options ...DownloaderOption) *downloader {
 	downloader := &downloader{
-		config: config,
-		logger: zap.NewNop(),
+		config:           config,
+		logger:           zap.NewNop(),
+		execTracer:       exectrace.NewNopTracer(),
+		progressReporter: progress.NewNopReporter(),
 	}
 	for _, option := range options {
 		option(downloader)
@@ -63,28 +97,110 @@ func newDownloader(config settings.Config, options ...DownloaderOption) *downloa
 	if downloader.config.Compile.ProtobufVersion == "" {
 		downloader.config.Compile.ProtobufVersion = vars.DefaultProtocVersion
 	}
+	if downloader.protocBinPath == "" {
+		downloader.protocBinPath = downloader.config.Compile.ProtocBinPath
+	}
+	if downloader.protocWKTPath == "" {
+		downloader.protocWKTPath = downloader.config.Compile.ProtocWKTPath
+	}
 	return downloader
 }
 
 func (d *downloader) Download() (string, error) {
+	if d.protocBinPath != "" {
+		return d.getProtocBinPath()
+	}
 	d.lock.RLock()
 	cachedBasePath := d.cachedBasePath
 	d.lock.RUnlock()
 	if cachedBasePath != "" {
 		return cachedBasePath, nil
 	}
-	return d.cache()
+	// protoc and the configured plugins and include files are independent
+	// so we download them concurrently
+	downloadFuncs := []func() error{
+		func() error {
+			var err error
+			cachedBasePath, err = d.cache()
+			return err
+		},
+	}
+	if d.config.Compile.ValidateVersion != "" {
+		downloadFuncs = append(downloadFuncs, func() error {
+			_, err := d.downloadValidate()
+			return err
+		})
+	}
+	if d.config.Compile.GogoProtobufVersion != "" {
+		downloadFuncs = append(downloadFuncs, func() error {
+			_, err := d.downloadGogo()
+			return err
+		})
+	}
+	if d.config.Compile.GRPCGatewayVersion != "" {
+		downloadFuncs = append(downloadFuncs, func() error {
+			_, err := d.downloadGRPCGateway()
+			return err
+		})
+	}
+	if d.config.Compile.ProtobufJavascriptVersion != "" {
+		downloadFuncs = append(downloadFuncs, func() error {
+			_, err := d.ProtobufJavascriptPluginPath()
+			return err
+		})
+	}
+	if d.config.Compile.GoogleapisVersion != "" {
+		downloadFuncs = append(downloadFuncs, func() error {
+			_, err := d.downloadGoogleapis()
+			return err
+		})
+	}
+	errs := make([]error, len(downloadFuncs))
+	var wg sync.WaitGroup
+	for i, downloadFunc := range downloadFuncs {
+		i := i
+		downloadFunc := downloadFunc
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = downloadFunc()
+		}()
+	}
+	wg.Wait()
+	if err := multierr.Combine(errs...); err != nil {
+		return "", err
+	}
+	return cachedBasePath, nil
 }
 
 func (d *downloader) ProtocPath() (string, error) {
+	if d.protocBinPath != "" {
+		return d.getProtocBinPath()
+	}
 	basePath, err := d.Download()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(basePath, "bin", "protoc"), nil
+	return filepath.Join(basePath, "bin", getExecutableName(runtime.GOOS, "protoc")), nil
 }
 
 func (d *downloader) WellKnownTypesIncludePath() (string, error) {
+	if d.protocWKTPath != "" {
+		return d.protocWKTPath, nil
+	}
+	if d.protocBinPath != "" {
+		protocBinPath, err := d.getProtocBinPath()
+		if err != nil {
+			return "", err
+		}
+		// installations such as /usr/bin/protoc and /usr/include
+		// have the well-known types in the sibling include directory
+		includePath := filepath.Join(filepath.Dir(filepath.Dir(protocBinPath)), "include")
+		if _, err := os.Stat(filepath.Join(includePath, "google", "protobuf", "descriptor.proto")); err != nil {
+			return "", fmt.Errorf("could not find the well-known types for %s in %s, set the protoc wkt_path", protocBinPath, includePath)
+		}
+		return includePath, nil
+	}
 	basePath, err := d.Download()
 	if err != nil {
 		return "", err
@@ -97,9 +213,54 @@ func (d *downloader) Delete() error {
 	if err != nil {
 		return err
 	}
+	validateBasePath, err := d.getValidateBasePathNoVersion()
+	if err != nil {
+		return err
+	}
+	gogoBasePath, err := d.getGogoBasePathNoVersion()
+	if err != nil {
+		return err
+	}
+	grpcGatewayBasePath, err := d.getGRPCGatewayBasePathNoVersion()
+	if err != nil {
+		return err
+	}
+	protobufJavascriptBasePath, err := d.getProtobufJavascriptBasePathNoVersion()
+	if err != nil {
+		return err
+	}
+	googleapisBasePath, err := d.getGoogleapisBasePathNoVersion()
+	if err != nil {
+		return err
+	}
 	d.cachedBasePath = ""
+	d.cachedValidateBasePath = ""
+	d.cachedGogoBasePath = ""
+	d.cachedGRPCGatewayBasePath = ""
+	d.cachedProtobufJavascriptBasePath = ""
+	d.cachedGoogleapisBasePath = ""
 	d.logger.Debug("deleting", zap.String("path", basePath))
-	return os.RemoveAll(basePath)
+	if err := os.RemoveAll(basePath); err != nil {
+		return err
+	}
+	d.logger.Debug("deleting", zap.String("path", validateBasePath))
+	if err := os.RemoveAll(validateBasePath); err != nil {
+		return err
+	}
+	d.logger.Debug("deleting", zap.String("path", gogoBasePath))
+	if err := os.RemoveAll(gogoBasePath); err != nil {
+		return err
+	}
+	d.logger.Debug("deleting", zap.String("path", grpcGatewayBasePath))
+	if err := os.RemoveAll(grpcGatewayBasePath); err != nil {
+		return err
+	}
+	d.logger.Debug("deleting", zap.String("path", protobufJavascriptBasePath))
+	if err := os.RemoveAll(protobufJavascriptBasePath); err != nil {
+		return err
+	}
+	d.logger.Debug("deleting", zap.String("path", googleapisBasePath))
+	return os.RemoveAll(googleapisBasePath)
 }
 
 func (d *downloader) cache() (string, error) {
@@ -111,13 +272,18 @@ func (d *downloader) cache() (string, error) {
 		return "", err
 	}
 	if err := d.checkDownloaded(basePath); err != nil {
+		start := time.Now()
 		if err := d.download(basePath); err != nil {
 			return "", err
 		}
 		if err := d.checkDownloaded(basePath); err != nil {
+			if runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" {
+				// we may have fallen back to the x86_64 protoc
+				return "", fmt.Errorf("%v\nIf this protoc version has no Apple Silicon release, install Rosetta with softwareupdate --install-rosetta", err)
+			}
 			return "", err
 		}
-		d.logger.Debug("protobuf downloaded", zap.String("path", basePath))
+		d.logger.Debug("protobuf downloaded", zap.String("path", basePath), zap.Duration("duration", time.Since(start)))
 	} else {
 		d.logger.Debug("protobuf already downloaded", zap.String("path", basePath))
 	}
@@ -126,11 +292,47 @@ func (d *downloader) cache() (string, error) {
 	return basePath, nil
 }
 
+// getProtocBinPath resolves protocBinPath, looking it up in PATH if it is
+// not a path, and checks that it runs.
+//
+// The version of protoc is not checked, as it is managed outside of Prototool.
+func (d *downloader) getProtocBinPath() (string, error) {
+	d.lock.RLock()
+	cachedProtocBinPath := d.cachedProtocBinPath
+	d.lock.RUnlock()
+	if cachedProtocBinPath != "" {
+		return cachedProtocBinPath, nil
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	protocBinPath, err := exec.LookPath(d.protocBinPath)
+	if err != nil {
+		return "", fmt.Errorf("could not find protoc binary %s: %v", d.protocBinPath, err)
+	}
+	protocBinPath, err = absClean(protocBinPath)
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command(protocBinPath, "--version")
+	finish := d.execTracer.Start(cmd)
+	output, err := cmd.Output()
+	finish(err)
+	if err != nil {
+		return "", fmt.Errorf("could not run %s --version: %v", protocBinPath, err)
+	}
+	d.logger.Debug("using protoc binary", zap.String("path", protocBinPath), zap.String("version", strings.TrimSpace(string(output))))
+	d.cachedProtocBinPath = protocBinPath
+	return protocBinPath, nil
+}
+
 func (d *downloader) checkDownloaded(basePath string) error {
 	buffer := bytes.NewBuffer(nil)
-	cmd := exec.Command(filepath.Join(basePath, "bin", "protoc"), "--version")
+	cmd := exec.Command(filepath.Join(basePath, "bin", getExecutableName(runtime.GOOS, "protoc")), "--version")
 	cmd.Stdout = buffer
-	if err := cmd.Run(); err != nil {
+	finish := d.execTracer.Start(cmd)
+	err := cmd.Run()
+	finish(err)
+	if err != nil {
 		return err
 	}
 	if d.protocURL != "" {
@@ -140,7 +342,8 @@ func (d *downloader) checkDownloaded(basePath string) error {
 	output := strings.TrimSpace(buffer.String())
 	d.logger.Debug("output from protoc --version", zap.String("output", output))
 	expected := fmt.Sprintf("libprotoc %s", d.config.Compile.ProtobufVersion)
-	if output != expected {
+	// protoc 21.x releases print their version as 3.21.x
+	if output != expected && output != fmt.Sprintf("libprotoc 3.%s", d.config.Compile.ProtobufVersion) {
 		return fmt.Errorf("expected %s from protoc --version, got %s", expected, output)
 	}
 	return nil
@@ -151,36 +354,40 @@ func (d *downloader) download(basePath string) (retErr error) {
 }
 
 func (d *downloader) downloadInternal(basePath string, goos string, goarch string) (retErr error) {
-	url, err := d.getProtocURL(goos, goarch)
+	urls, err := d.getProtocURLs(goos, goarch)
 	if err != nil {
 		return err
 	}
-	response, err := http.Get(url)
-	if err != nil || response.StatusCode != http.StatusOK {
+	var url string
+	var fetchedFile *fetchedFile
+	for _, url = range urls {
+		fetchedFile, err = d.fetch(url)
+		// try the next url if this release does not have this asset, as
+		// older releases only have the win32 zip file for Windows, and
+		// only have the x86_64 zip file for Darwin
+		if isNotFound(err) && url != urls[len(urls)-1] {
+			continue
+		}
+		break
+	}
+	if err != nil {
 		// if there is not given protocURL, we tried to
 		// download this from GitHub Releases, so add
 		// extra context to the error message
 		if d.protocURL == "" {
-			return fmt.Errorf("error downloading %s: %v\nMake sure GitHub Releases has a proper protoc zip file of the form protoc-VERSION-OS-ARCH.zip at https://github.com/google/protobuf/releases/v%s\nNote that many micro versions do not have this, and no version before 3.0.0-beta-2 has this", url, err, d.config.Compile.ProtobufVersion)
+			return fmt.Errorf("%v\nMake sure GitHub Releases has a proper protoc zip file of the form protoc-VERSION-OS-ARCH.zip at https://github.com/google/protobuf/releases/v%s\nNote that many micro versions do not have this, and no version before 3.0.0-beta-2 has this", err, d.config.Compile.ProtobufVersion)
 		}
 		return err
 	}
 	d.logger.Debug("downloaded protobuf zip file", zap.String("url", url))
 	defer func() {
-		if response.Body != nil {
-			retErr = multierr.Append(retErr, response.Body.Close())
-		}
+		retErr = multierr.Append(retErr, fetchedFile.Close())
 	}()
-	data, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return err
-	}
 
 	// this is a working but hacky unzip
 	// there must be a library for this
 	// we don't properly copy directories, modification times, etc
-	readerAt := bytes.NewReader(data)
-	zipReader, err := zip.NewReader(readerAt, int64(len(data)))
+	zipReader, err := zip.NewReader(fetchedFile, fetchedFile.size)
 	if err != nil {
 		return err
 	}
@@ -220,25 +427,36 @@ func (d *downloader) downloadInternal(basePath string, goos string, goarch strin
 	return nil
 }
 
-func (d *downloader) getProtocURL(goos string, goarch string) (string, error) {
+// getProtocURLs returns the urls to try to download protoc from, in order.
+func (d *downloader) getProtocURLs(goos string, goarch string) ([]string, error) {
 	if d.protocURL != "" {
-		return d.protocURL, nil
+		return []string{d.protocURL}, nil
 	}
-	_, unameM, err := getUnameSUnameMPaths(goos, goarch)
+	protocM, err := getProtocMPath(goarch)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	protocS, err := getProtocSPath(goos)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	version := d.config.Compile.ProtobufVersion
+	getURL := func(suffix string) string {
+		return fmt.Sprintf("https://github.com/google/protobuf/releases/download/v%s/protoc-%s-%s.zip", version, version, suffix)
+	}
+	switch {
+	case goos == "windows":
+		// the Windows zip files are named by bitness instead of by
+		// architecture, and the 32-bit protoc also runs on 64-bit Windows
+		// as well as on ARM with emulation
+		return []string{getURL(protocS + "64"), getURL(protocS + "32")}, nil
+	case goos == "darwin" && goarch == "arm64":
+		// releases before 3.20 do not have an Apple Silicon protoc, but
+		// the x86_64 protoc runs with Rosetta
+		return []string{getURL(protocS + "-" + protocM), getURL(protocS + "-x86_64")}, nil
+	default:
+		return []string{getURL(protocS + "-" + protocM)}, nil
 	}
-	return fmt.Sprintf(
-		"https://github.com/google/protobuf/releases/download/v%s/protoc-%s-%s-%s.zip",
-		d.config.Compile.ProtobufVersion,
-		d.config.Compile.ProtobufVersion,
-		protocS,
-		unameM,
-	), nil
 }
 
 func (d *downloader) getBasePath() (string, error) {
@@ -292,6 +510,13 @@ func getDefaultBasePathInternal(goos string, goarch string, getenvFunc func(stri
 	if xdgCacheHome != "" {
 		return filepath.Join(xdgCacheHome, "prototool", unameS, unameM), nil
 	}
+	if unameS == "Windows" {
+		localAppData := getenvFunc("LOCALAPPDATA")
+		if localAppData == "" {
+			return "", fmt.Errorf("LOCALAPPDATA is not set")
+		}
+		return filepath.Join(localAppData, "prototool", unameS, unameM), nil
+	}
 	home := getenvFunc("HOME")
 	if home == "" {
 		return "", fmt.Errorf("HOME is not set")
@@ -312,6 +537,8 @@ func getProtocSPath(goos string) (string, error) {
 		return "osx", nil
 	case "linux":
 		return "linux", nil
+	case "windows":
+		return "win", nil
 	default:
 		return "", fmt.Errorf("unsupported value for runtime.GOOS: %v", goos)
 	}
@@ -324,6 +551,8 @@ func getUnameSUnameMPaths(goos string, goarch string) (string, string, error) {
 		unameS = "Darwin"
 	case "linux":
 		unameS = "Linux"
+	case "windows":
+		unameS = "Windows"
 	default:
 		return "", "", fmt.Errorf("unsupported value for runtime.GOOS: %v", goos)
 	}
@@ -331,8 +560,37 @@ func getUnameSUnameMPaths(goos string, goarch string) (string, string, error) {
 	switch goarch {
 	case "amd64":
 		unameM = "x86_64"
+	case "arm64":
+		// uname -m is aarch64 on Linux and arm64 elsewhere
+		if goos == "linux" {
+			unameM = "aarch64"
+		} else {
+			unameM = "arm64"
+		}
 	default:
 		return "", "", fmt.Errorf("unsupported value for runtime.GOARCH: %v", goarch)
 	}
 	return unameS, unameM, nil
 }
+
+// getProtocMPath returns the architecture part of the names of the
+// protoc release files, which is also used by protobuf-javascript.
+func getProtocMPath(goarch string) (string, error) {
+	switch goarch {
+	case "amd64":
+		return "x86_64", nil
+	case "arm64":
+		return "aarch_64", nil
+	default:
+		return "", fmt.Errorf("unsupported value for runtime.GOARCH: %v", goarch)
+	}
+}
+
+// getExecutableName returns the file name of the named executable for
+// goos, which has the .exe extension on Windows.
+func getExecutableName(goos string, name string) string {
+	if goos == "windows" {
+		return name + ".exe"
+	}
+	return name
+}
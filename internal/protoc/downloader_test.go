@@ -21,10 +21,25 @@
 package protoc
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/progress"
+	"github.com/uber/prototool/internal/settings"
+	"go.uber.org/zap"
 )
 
 func TestGetDefaultBasePath(t *testing.T) {
@@ -62,6 +77,18 @@ func TestGetDefaultBasePath(t *testing.T) {
 			home:             "/home/alice",
 			expectedBasePath: "/home/alice/.cache/prototool/Linux/x86_64",
 		},
+		{
+			goos:             "darwin",
+			goarch:           "arm64",
+			home:             "/Users/alice",
+			expectedBasePath: "/Users/alice/Library/Caches/prototool/Darwin/arm64",
+		},
+		{
+			goos:             "linux",
+			goarch:           "arm64",
+			home:             "/home/alice",
+			expectedBasePath: "/home/alice/.cache/prototool/Linux/aarch64",
+		},
 		{
 			goos:         "foo",
 			goarch:       "amd64",
@@ -103,3 +130,199 @@ func newTestGetenvFunc(xdgCacheHome string, home string) func(string) string {
 	}
 	return func(key string) string { return m[key] }
 }
+
+func TestDownloaderProtocBinPath(t *testing.T) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDirPath)) }()
+	protocBinPath := filepath.Join(tmpDirPath, "bin", "protoc")
+	require.NoError(t, os.MkdirAll(filepath.Dir(protocBinPath), 0755))
+	require.NoError(t, ioutil.WriteFile(protocBinPath, []byte("#!/bin/sh\necho libprotoc 3.6.1\n"), 0755))
+
+	downloader := newDownloader(settings.Config{}, DownloaderWithProtocBinPath(protocBinPath))
+	path, err := downloader.ProtocPath()
+	assert.NoError(t, err)
+	assert.Equal(t, protocBinPath, path)
+	path, err = downloader.Download()
+	assert.NoError(t, err)
+	assert.Equal(t, protocBinPath, path)
+	// no well-known types next to the bin directory
+	_, err = downloader.WellKnownTypesIncludePath()
+	assert.Error(t, err)
+
+	includePath := filepath.Join(tmpDirPath, "include")
+	require.NoError(t, os.MkdirAll(filepath.Join(includePath, "google", "protobuf"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(includePath, "google", "protobuf", "descriptor.proto"), nil, 0644))
+	path, err = downloader.WellKnownTypesIncludePath()
+	assert.NoError(t, err)
+	assert.Equal(t, includePath, path)
+
+	// the option overrides the config
+	downloader = newDownloader(
+		settings.Config{
+			Compile: settings.CompileConfig{
+				ProtocBinPath: filepath.Join(tmpDirPath, "missing"),
+				ProtocWKTPath: "/usr/include",
+			},
+		},
+		DownloaderWithProtocBinPath(protocBinPath),
+		DownloaderWithProtocWKTPath("/foo/include"),
+	)
+	path, err = downloader.ProtocPath()
+	assert.NoError(t, err)
+	assert.Equal(t, protocBinPath, path)
+	path, err = downloader.WellKnownTypesIncludePath()
+	assert.NoError(t, err)
+	assert.Equal(t, "/foo/include", path)
+
+	downloader = newDownloader(
+		settings.Config{
+			Compile: settings.CompileConfig{
+				ProtocBinPath: filepath.Join(tmpDirPath, "missing"),
+			},
+		},
+	)
+	_, err = downloader.ProtocPath()
+	assert.Error(t, err)
+}
+
+func TestGetArchiveProtoFileName(t *testing.T) {
+	for archiveName, expected := range map[string]string{
+		"googleapis-abc/google/type/date.proto":     "google/type/date.proto",
+		"googleapis-abc/google/api/BUILD.bazel":     "",
+		"googleapis-abc/grafeas/v1/grafeas.proto":   "",
+		"googleapis-abc/google/../../foo.proto":     "",
+		"googleapis-abc/google/api/./http.proto":    "",
+		"google/type/date.proto":                    "",
+		"googleapis-abc/google/rpc/status.proto":    "google/rpc/status.proto",
+		"googleapis-abc/google/type/latlng.proto.1": "",
+	} {
+		name, ok := getArchiveProtoFileName(archiveName, "google/")
+		assert.Equal(t, expected != "", ok, archiveName)
+		assert.Equal(t, expected, name, archiveName)
+	}
+}
+
+func TestDownloadTarGzProtoFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		gzipWriter := gzip.NewWriter(responseWriter)
+		tarWriter := tar.NewWriter(gzipWriter)
+		for name, content := range map[string]string{
+			"googleapis-abc/google/type/date.proto": "syntax = \"proto3\";",
+			"googleapis-abc/google/api/BUILD.bazel": "",
+			"googleapis-abc/README.md":              "",
+		} {
+			require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+			_, err := tarWriter.Write([]byte(content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tarWriter.Close())
+		require.NoError(t, gzipWriter.Close())
+	}))
+	defer server.Close()
+	tmpDir, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+
+	downloader := newDownloader(settings.Config{}, DownloaderWithLogger(zap.NewNop()), DownloaderWithCachePath(tmpDir))
+	require.NoError(t, downloader.downloadTarGzProtoFiles(server.URL, "google/", tmpDir))
+	data, err := ioutil.ReadFile(filepath.Join(tmpDir, "google", "type", "date.proto"))
+	require.NoError(t, err)
+	assert.Equal(t, `syntax = "proto3";`, string(data))
+	_, err = os.Stat(filepath.Join(tmpDir, "google", "api", "BUILD.bazel"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Error(t, downloader.downloadTarGzProtoFiles(server.URL, "grafeas/", tmpDir))
+}
+
+func TestFetchResume(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/protoc.zip" {
+			http.NotFound(responseWriter, request)
+			return
+		}
+		ranges = append(ranges, request.Header.Get("Range"))
+		http.ServeContent(responseWriter, request, "protoc.zip", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+	tmpDir, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+
+	progressBuffer := bytes.NewBuffer(nil)
+	downloader := newDownloader(settings.Config{}, DownloaderWithCachePath(tmpDir), DownloaderWithProgress(progress.NewReporter(progressBuffer)))
+	downloadsDirPath, err := downloader.getDownloadsDirPath()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(downloadsDirPath, 0755))
+	url := server.URL + "/protoc.zip"
+	hash := sha256.Sum256([]byte(url))
+	// an interrupted download of the first 300 bytes
+	require.NoError(t, ioutil.WriteFile(filepath.Join(downloadsDirPath, hex.EncodeToString(hash[:])+".part"), []byte(content[:300]), 0644))
+
+	fetchedFile, err := downloader.fetch(url)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(fetchedFile)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	assert.Equal(t, int64(len(content)), fetchedFile.size)
+	require.NoError(t, fetchedFile.Close())
+	assert.Equal(t, []string{"bytes=300-"}, ranges)
+	assert.Contains(t, progressBuffer.String(), "Resuming download of protoc.zip at 0.3 KB")
+	assert.Contains(t, progressBuffer.String(), "Downloaded protoc.zip (0.7 KB in ")
+	fileInfos, err := ioutil.ReadDir(downloadsDirPath)
+	require.NoError(t, err)
+	assert.Empty(t, fileInfos)
+
+	_, err = downloader.fetch(server.URL + "/missing.zip")
+	assert.True(t, isNotFound(err))
+}
+
+func TestGetDefaultBasePathWindows(t *testing.T) {
+	getenvFunc := func(key string) string {
+		if key == "LOCALAPPDATA" {
+			return `C:\Users\alice\AppData\Local`
+		}
+		return ""
+	}
+	basePath, err := getDefaultBasePathInternal("windows", "amd64", getenvFunc)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(`C:\Users\alice\AppData\Local`, "prototool", "Windows", "x86_64"), basePath)
+	_, err = getDefaultBasePathInternal("windows", "amd64", newTestGetenvFunc("", `C:\Users\alice`))
+	assert.Error(t, err)
+}
+
+func TestGetProtocURLs(t *testing.T) {
+	downloader := newDownloader(settings.Config{Compile: settings.CompileConfig{ProtobufVersion: "3.5.1"}})
+	urls, err := downloader.getProtocURLs("linux", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://github.com/google/protobuf/releases/download/v3.5.1/protoc-3.5.1-linux-x86_64.zip"}, urls)
+	urls, err = downloader.getProtocURLs("windows", "amd64")
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			"https://github.com/google/protobuf/releases/download/v3.5.1/protoc-3.5.1-win64.zip",
+			"https://github.com/google/protobuf/releases/download/v3.5.1/protoc-3.5.1-win32.zip",
+		},
+		urls,
+	)
+	urls, err = downloader.getProtocURLs("darwin", "arm64")
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			"https://github.com/google/protobuf/releases/download/v3.5.1/protoc-3.5.1-osx-aarch_64.zip",
+			"https://github.com/google/protobuf/releases/download/v3.5.1/protoc-3.5.1-osx-x86_64.zip",
+		},
+		urls,
+	)
+	urls, err = downloader.getProtocURLs("linux", "arm64")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://github.com/google/protobuf/releases/download/v3.5.1/protoc-3.5.1-linux-aarch_64.zip"}, urls)
+	_, err = downloader.getProtocURLs("linux", "386")
+	assert.Error(t, err)
+	assert.Equal(t, "protoc.exe", getExecutableName("windows", "protoc"))
+	assert.Equal(t, "protoc", getExecutableName("darwin", "protoc"))
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/uber/prototool/internal/exectrace"
+)
+
+// executor runs the protoc commands of a compiler.
+type executor interface {
+	// execute runs the protoc command and returns what protoc wrote to stderr.
+	//
+	// If protoc ran but exited with a non-zero status, the error is an
+	// exit error and the output can be parsed into failures.
+	execute(cmdMeta *cmdMeta) (string, error)
+}
+
+// isExitError returns true if the error returned from an executor
+// says that protoc ran but exited with a non-zero status.
+func isExitError(err error) bool {
+	switch err.(type) {
+	case *exec.ExitError, *remoteExitError:
+		return true
+	default:
+		return false
+	}
+}
+
+// localExecutor runs protoc on this machine.
+type localExecutor struct {
+	execTracer exectrace.Tracer
+}
+
+func (l localExecutor) execute(cmdMeta *cmdMeta) (string, error) {
+	compileConfig := cmdMeta.protoSet.Config.Compile
+	if compileConfig.Sandbox {
+		// all paths passed to protoc are absolute, so plugins that write
+		// to their working directory only see an empty temporary directory
+		sandboxDirPath, err := ioutil.TempDir("", "prototool")
+		if err != nil {
+			return "", err
+		}
+		defer func() {
+			_ = os.RemoveAll(sandboxDirPath)
+		}()
+		cmdMeta.execCmd.Dir = sandboxDirPath
+		cmdMeta.execCmd.Env = getSandboxEnv(os.Environ(), compileConfig.SandboxEnv)
+	}
+	buffer := bytes.NewBuffer(nil)
+	cmdMeta.execCmd.Stderr = buffer
+	var exceeded <-chan struct{}
+	if compileConfig.MaxOutputBytes > 0 {
+		stderr := newLimitWriter(buffer, compileConfig.MaxOutputBytes)
+		cmdMeta.execCmd.Stderr = stderr
+		exceeded = stderr.exceeded
+	}
+	// we only need stderr to parse errors
+	// you have to explicitly set to ioutil.Discard, otherwise if there
+	// is a stdout, it will be printed to os.Stdout
+	cmdMeta.execCmd.Stdout = ioutil.Discard
+	finish := l.execTracer.Start(cmdMeta.execCmd)
+	err := runExecCmd(cmdMeta.execCmd, cmdMeta.timeout(), compileConfig.MaxOutputBytes, exceeded)
+	finish(err)
+	if err != nil {
+		if _, ok := err.(*killedError); ok {
+			// protoc may still be writing to the buffer
+			return "", err
+		}
+		return buffer.String(), err
+	}
+	return buffer.String(), nil
+}
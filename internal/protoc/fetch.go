@@ -0,0 +1,199 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/uber/prototool/internal/progress"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// statusError is returned from fetch if the server did not return the file.
+type statusError struct {
+	url        string
+	status     string
+	statusCode int
+}
+
+func (s *statusError) Error() string {
+	return fmt.Sprintf("error downloading %s: %s", s.url, s.status)
+}
+
+// isNotFound returns true if the error is a statusError for a missing file.
+func isNotFound(err error) bool {
+	statusErr, ok := err.(*statusError)
+	return ok && statusErr.statusCode == http.StatusNotFound
+}
+
+// fetchedFile is a downloaded file that is removed when closed.
+type fetchedFile struct {
+	*os.File
+	size int64
+}
+
+func (f *fetchedFile) Close() error {
+	return multierr.Append(f.File.Close(), os.Remove(f.Name()))
+}
+
+// fetch downloads the file at url to the downloads directory of the cache,
+// and returns it opened for reading.
+//
+// The file is downloaded to a .part file first. If a previous download of
+// the same url was interrupted, the download is resumed from where it left
+// off if the server supports range requests.
+func (d *downloader) fetch(url string) (*fetchedFile, error) {
+	downloadsDirPath, err := d.getDownloadsDirPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(downloadsDirPath, 0755); err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256([]byte(url))
+	filePath := filepath.Join(downloadsDirPath, hex.EncodeToString(hash[:]))
+	partFilePath := filePath + ".part"
+	if err := d.fetchPart(url, partFilePath); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(partFilePath, filePath); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &fetchedFile{File: file, size: fileInfo.Size()}, nil
+}
+
+// fetchPart downloads the file at url to partFilePath, resuming the
+// download if partFilePath already exists.
+//
+// partFilePath is kept if the download fails after the response started
+// so that the next fetch can resume it.
+func (d *downloader) fetchPart(url string, partFilePath string) (retErr error) {
+	var offset int64
+	if fileInfo, err := os.Stat(partFilePath); err == nil {
+		offset = fileInfo.Size()
+	}
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		request.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("error downloading %s: %v", url, err)
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	switch response.StatusCode {
+	case http.StatusOK:
+		// the server ignored the range, so we start over
+		offset = 0
+	case http.StatusPartialContent:
+		flag = os.O_WRONLY | os.O_APPEND
+		d.logger.Debug("resuming download", zap.String("url", url), zap.Int64("offset", offset))
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the part file is not a prefix of the file, so we start over
+		if err := os.Remove(partFilePath); err != nil {
+			return err
+		}
+		return d.fetchPart(url, partFilePath)
+	default:
+		return &statusError{url: url, status: response.Status, statusCode: response.StatusCode}
+	}
+	file, err := os.OpenFile(partFilePath, flag, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, file.Close())
+	}()
+	var total int64 = -1
+	if response.ContentLength >= 0 {
+		total = offset + response.ContentLength
+	}
+	name := path.Base(request.URL.Path)
+	if offset > 0 {
+		d.progressReporter.Printf("Resuming download of %s at %s", name, formatBytes(offset))
+	} else {
+		d.progressReporter.Printf("Downloading %s", name)
+	}
+	start := time.Now()
+	task := d.progressReporter.Start("Downloading "+name, total, progress.TaskWithBytes())
+	task.Add(offset)
+	written, err := io.Copy(file, io.TeeReader(response.Body, taskWriter{task: task}))
+	task.Done()
+	if err != nil {
+		return fmt.Errorf("error downloading %s: %v", url, err)
+	}
+	if response.ContentLength >= 0 && written != response.ContentLength {
+		return fmt.Errorf("error downloading %s: expected %d bytes but got %d", url, response.ContentLength, written)
+	}
+	d.progressReporter.Printf("Downloaded %s (%s in %v)", name, formatBytes(written), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func (d *downloader) getDownloadsDirPath() (string, error) {
+	// this is a sibling of the protobuf directory
+	basePath, err := d.getBasePathNoVersion()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(basePath), "downloads"), nil
+}
+
+// taskWriter adds the number of bytes written to the progress of a task.
+type taskWriter struct {
+	task progress.Task
+}
+
+func (w taskWriter) Write(data []byte) (int, error) {
+	w.task.Add(int64(len(data)))
+	return len(data), nil
+}
+
+func formatBytes(n int64) string {
+	if n < 1024*1024 {
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+const (
+	grpcGatewayName = "grpc-gateway"
+	// the google/api files are not in the v2 source archive, so we take them
+	// from the third_party/googleapis directory of the last v1 source archive
+	grpcGatewayGoogleapisVersion = "1.16.0"
+	grpcGatewayOpenAPIGoPackage  = "github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2/options"
+	googleapisAPIGoPackage       = "google.golang.org/genproto/googleapis/api/annotations"
+)
+
+var (
+	// the presets that use a downloaded plugin of the same name
+	grpcGatewayPresets = map[string]struct{}{
+		"grpc-gateway": struct{}{},
+		"openapiv2":    struct{}{},
+	}
+
+	grpcGatewayGoogleapisIncludeFiles = []string{
+		"google/api/annotations.proto",
+		"google/api/http.proto",
+		"google/api/httpbody.proto",
+	}
+	grpcGatewayOpenAPIIncludeFiles = []string{
+		"protoc-gen-openapiv2/options/annotations.proto",
+		"protoc-gen-openapiv2/options/openapiv2.proto",
+	}
+)
+
+func (d *downloader) GRPCGatewayPluginPath(name string) (string, error) {
+	if _, ok := grpcGatewayPresets[name]; !ok {
+		return "", fmt.Errorf("unknown grpc-gateway plugin: %s", name)
+	}
+	basePath, err := d.downloadGRPCGateway()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePath, "bin", getExecutableName(runtime.GOOS, "protoc-gen-"+name)), nil
+}
+
+func (d *downloader) GRPCGatewayIncludePath() (string, error) {
+	basePath, err := d.downloadGRPCGateway()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePath, "include"), nil
+}
+
+func (d *downloader) downloadGRPCGateway() (string, error) {
+	if d.config.Compile.GRPCGatewayVersion == "" {
+		return "", fmt.Errorf("grpc_gateway_version must be set in the config file to use grpc-gateway")
+	}
+	d.grpcGatewayLock.Lock()
+	defer d.grpcGatewayLock.Unlock()
+	if d.cachedGRPCGatewayBasePath != "" {
+		return d.cachedGRPCGatewayBasePath, nil
+	}
+
+	basePath, err := d.getGRPCGatewayBasePath()
+	if err != nil {
+		return "", err
+	}
+	if err := checkGRPCGatewayDownloaded(basePath); err != nil {
+		if err := d.downloadGRPCGatewayInternal(basePath, runtime.GOOS, runtime.GOARCH); err != nil {
+			return "", err
+		}
+		if err := checkGRPCGatewayDownloaded(basePath); err != nil {
+			return "", err
+		}
+		d.logger.Debug("grpc-gateway downloaded", zap.String("path", basePath))
+	} else {
+		d.logger.Debug("grpc-gateway already downloaded", zap.String("path", basePath))
+	}
+
+	d.cachedGRPCGatewayBasePath = basePath
+	return basePath, nil
+}
+
+func (d *downloader) downloadGRPCGatewayInternal(basePath string, goos string, goarch string) error {
+	version := d.config.Compile.GRPCGatewayVersion
+	if _, _, err := getUnameSUnameMPaths(goos, goarch); err != nil {
+		return err
+	}
+	// the release binaries are named with x86_64 for amd64, and
+	// with the GOARCH otherwise, such as arm64 for both Darwin and Linux
+	gatewayM := goarch
+	if goarch == "amd64" {
+		gatewayM = "x86_64"
+	}
+	for name := range grpcGatewayPresets {
+		// the Windows release binaries have the .exe extension
+		if err := d.downloadFile(
+			getExecutableName(goos, fmt.Sprintf(
+				"https://github.com/grpc-ecosystem/grpc-gateway/releases/download/v%s/protoc-gen-%s-v%s-%s-%s",
+				version,
+				name,
+				version,
+				goos,
+				gatewayM,
+			)),
+			filepath.Join(basePath, "bin", getExecutableName(goos, "protoc-gen-"+name)),
+			0755,
+		); err != nil {
+			return err
+		}
+	}
+	// the source archives have a top-level directory grpc-gateway-VERSION
+	if err := d.downloadTarGzFiles(
+		fmt.Sprintf("https://github.com/grpc-ecosystem/grpc-gateway/archive/v%s.tar.gz", grpcGatewayGoogleapisVersion),
+		getGRPCGatewayArchiveNameToWriteFilePath(basePath, grpcGatewayGoogleapisVersion, "third_party/googleapis/", grpcGatewayGoogleapisIncludeFiles),
+		0644,
+	); err != nil {
+		return err
+	}
+	return d.downloadTarGzFiles(
+		fmt.Sprintf("https://github.com/grpc-ecosystem/grpc-gateway/archive/v%s.tar.gz", version),
+		getGRPCGatewayArchiveNameToWriteFilePath(basePath, version, "", grpcGatewayOpenAPIIncludeFiles),
+		0644,
+	)
+}
+
+// downloadFile downloads the file at url and writes it to writeFilePath.
+func (d *downloader) downloadFile(url string, writeFilePath string, fileMode os.FileMode) (retErr error) {
+	fetchedFile, err := d.fetch(url)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, fetchedFile.Close())
+	}()
+	d.logger.Debug("downloaded file", zap.String("url", url))
+	return writeFileFromReader(writeFilePath, fetchedFile, fileMode)
+}
+
+func (d *downloader) getGRPCGatewayBasePath() (string, error) {
+	basePathNoVersion, err := d.getGRPCGatewayBasePathNoVersion()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePathNoVersion, d.config.Compile.GRPCGatewayVersion), nil
+}
+
+func (d *downloader) getGRPCGatewayBasePathNoVersion() (string, error) {
+	// this is a sibling of the protobuf directory
+	basePath, err := d.getBasePathNoVersion()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(basePath), grpcGatewayName), nil
+}
+
+func getGRPCGatewayArchiveNameToWriteFilePath(basePath string, version string, archivePrefix string, includeFiles []string) map[string]string {
+	archiveNameToWriteFilePath := make(map[string]string, len(includeFiles))
+	for _, includeFile := range includeFiles {
+		archiveNameToWriteFilePath[grpcGatewayName+"-"+version+"/"+archivePrefix+includeFile] = filepath.Join(basePath, "include", filepath.FromSlash(includeFile))
+	}
+	return archiveNameToWriteFilePath
+}
+
+func checkGRPCGatewayDownloaded(basePath string) error {
+	var filePaths []string
+	for name := range grpcGatewayPresets {
+		filePaths = append(filePaths, filepath.Join(basePath, "bin", getExecutableName(runtime.GOOS, "protoc-gen-"+name)))
+	}
+	for _, includeFile := range append(grpcGatewayGoogleapisIncludeFiles, grpcGatewayOpenAPIIncludeFiles...) {
+		filePaths = append(filePaths, filepath.Join(basePath, "include", filepath.FromSlash(includeFile)))
+	}
+	for _, filePath := range filePaths {
+		if _, err := os.Stat(filePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
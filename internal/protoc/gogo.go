@@ -0,0 +1,100 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+const (
+	gogoName        = "gogo-protobuf"
+	gogoIncludeFile = "gogoproto/gogo.proto"
+	gogoGoPackage   = "github.com/gogo/protobuf/gogoproto"
+)
+
+func (d *downloader) GogoIncludePath() (string, error) {
+	basePath, err := d.downloadGogo()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePath, "include"), nil
+}
+
+func (d *downloader) downloadGogo() (string, error) {
+	if d.config.Compile.GogoProtobufVersion == "" {
+		return "", fmt.Errorf("gogo_protobuf_version must be set in the config file to include gogoproto/gogo.proto")
+	}
+	d.gogoLock.Lock()
+	defer d.gogoLock.Unlock()
+	if d.cachedGogoBasePath != "" {
+		return d.cachedGogoBasePath, nil
+	}
+
+	basePath, err := d.getGogoBasePath()
+	if err != nil {
+		return "", err
+	}
+	includeFilePath := filepath.Join(basePath, "include", filepath.FromSlash(gogoIncludeFile))
+	if _, err := os.Stat(includeFilePath); err != nil {
+		version := d.config.Compile.GogoProtobufVersion
+		if err := d.downloadTarGzFile(
+			fmt.Sprintf("https://github.com/gogo/protobuf/archive/v%s.tar.gz", version),
+			func(name string) bool {
+				// the source archive has a top-level directory protobuf-VERSION
+				return name == "protobuf-"+version+"/"+gogoIncludeFile
+			},
+			includeFilePath,
+			0644,
+		); err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(includeFilePath); err != nil {
+			return "", err
+		}
+		d.logger.Debug("gogo.proto downloaded", zap.String("path", basePath))
+	} else {
+		d.logger.Debug("gogo.proto already downloaded", zap.String("path", basePath))
+	}
+
+	d.cachedGogoBasePath = basePath
+	return basePath, nil
+}
+
+func (d *downloader) getGogoBasePath() (string, error) {
+	basePathNoVersion, err := d.getGogoBasePathNoVersion()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePathNoVersion, d.config.Compile.GogoProtobufVersion), nil
+}
+
+func (d *downloader) getGogoBasePathNoVersion() (string, error) {
+	// this is a sibling of the protobuf directory
+	basePath, err := d.getBasePathNoVersion()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(basePath), gogoName), nil
+}
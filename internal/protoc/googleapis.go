@@ -0,0 +1,170 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+const (
+	googleapisName = "googleapis"
+	// written after all files are extracted so that a partial download
+	// is not mistaken for a complete one
+	googleapisDownloadedFileName = ".downloaded"
+)
+
+func (d *downloader) GoogleapisIncludePath() (string, error) {
+	basePath, err := d.downloadGoogleapis()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePath, "include"), nil
+}
+
+func (d *downloader) downloadGoogleapis() (string, error) {
+	if d.config.Compile.GoogleapisVersion == "" {
+		return "", fmt.Errorf("googleapis_version must be set in the config file to include googleapis")
+	}
+	d.googleapisLock.Lock()
+	defer d.googleapisLock.Unlock()
+	if d.cachedGoogleapisBasePath != "" {
+		return d.cachedGoogleapisBasePath, nil
+	}
+
+	basePath, err := d.getGoogleapisBasePath()
+	if err != nil {
+		return "", err
+	}
+	downloadedFilePath := filepath.Join(basePath, googleapisDownloadedFileName)
+	if _, err := os.Stat(downloadedFilePath); err != nil {
+		// remove anything left over from an interrupted download
+		if err := os.RemoveAll(basePath); err != nil {
+			return "", err
+		}
+		if err := d.downloadTarGzProtoFiles(
+			fmt.Sprintf("https://github.com/googleapis/googleapis/archive/%s.tar.gz", d.config.Compile.GoogleapisVersion),
+			"google/",
+			filepath.Join(basePath, "include"),
+		); err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(downloadedFilePath, nil, 0644); err != nil {
+			return "", err
+		}
+		d.logger.Debug("googleapis downloaded", zap.String("path", basePath))
+	} else {
+		d.logger.Debug("googleapis already downloaded", zap.String("path", basePath))
+	}
+
+	d.cachedGoogleapisBasePath = basePath
+	return basePath, nil
+}
+
+// downloadTarGzProtoFiles downloads the .tar.gz file at url, and writes every
+// .proto file in it under the given prefix to includePath.
+//
+// The top-level directory of the archive is stripped, as source archives
+// have a top-level directory named after the repository and version.
+// Returns an error if no file is found.
+func (d *downloader) downloadTarGzProtoFiles(url string, prefix string, includePath string) (retErr error) {
+	fetchedFile, err := d.fetch(url)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, fetchedFile.Close())
+	}()
+	d.logger.Debug("downloaded tar.gz file", zap.String("url", url))
+	gzipReader, err := gzip.NewReader(fetchedFile)
+	if err != nil {
+		return err
+	}
+	tarReader := tar.NewReader(gzipReader)
+	count := 0
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name, ok := getArchiveProtoFileName(header.Name, prefix)
+		if header.Typeflag != tar.TypeReg || !ok {
+			continue
+		}
+		if err := writeFileFromReader(filepath.Join(includePath, filepath.FromSlash(name)), tarReader, 0644); err != nil {
+			return err
+		}
+		count++
+	}
+	if count == 0 {
+		return fmt.Errorf("no .proto files under %s found in %s", prefix, url)
+	}
+	d.logger.Debug("wrote files", zap.String("path", includePath), zap.Int("count", count))
+	return nil
+}
+
+func (d *downloader) getGoogleapisBasePath() (string, error) {
+	basePathNoVersion, err := d.getGoogleapisBasePathNoVersion()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePathNoVersion, d.config.Compile.GoogleapisVersion), nil
+}
+
+func (d *downloader) getGoogleapisBasePathNoVersion() (string, error) {
+	// this is a sibling of the protobuf directory
+	basePath, err := d.getBasePathNoVersion()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(basePath), googleapisName), nil
+}
+
+// getArchiveProtoFileName returns the name of the archive file without the
+// top-level directory, and true if it is a .proto file under prefix.
+func getArchiveProtoFileName(archiveName string, prefix string) (string, bool) {
+	split := strings.SplitN(archiveName, "/", 2)
+	if len(split) != 2 {
+		return "", false
+	}
+	name := split[1]
+	if !strings.HasPrefix(name, prefix) || filepath.Ext(name) != ".proto" {
+		return "", false
+	}
+	// do not let a malformed archive write outside of the include path
+	if path.Clean(name) != name || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+
+	"go.uber.org/zap"
+)
+
+const (
+	protobufJavascriptName = "protobuf-javascript"
+	jsPluginName           = "protoc-gen-js"
+	tsPluginName           = "protoc-gen-ts"
+)
+
+func (d *downloader) ProtobufJavascriptPluginPath() (string, error) {
+	if d.config.Compile.ProtobufJavascriptVersion == "" {
+		return "", fmt.Errorf("protobuf_javascript_version must be set in the config file to use protoc-gen-js")
+	}
+	d.protobufJavascriptLock.Lock()
+	defer d.protobufJavascriptLock.Unlock()
+	if d.cachedProtobufJavascriptBasePath != "" {
+		return filepath.Join(d.cachedProtobufJavascriptBasePath, "bin", getExecutableName(runtime.GOOS, jsPluginName)), nil
+	}
+
+	basePath, err := d.getProtobufJavascriptBasePath()
+	if err != nil {
+		return "", err
+	}
+	pluginPath := filepath.Join(basePath, "bin", getExecutableName(runtime.GOOS, jsPluginName))
+	if _, err := os.Stat(pluginPath); err != nil {
+		if err := d.downloadProtobufJavascriptInternal(pluginPath, runtime.GOOS, runtime.GOARCH); err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(pluginPath); err != nil {
+			return "", err
+		}
+		d.logger.Debug("protoc-gen-js downloaded", zap.String("path", basePath))
+	} else {
+		d.logger.Debug("protoc-gen-js already downloaded", zap.String("path", basePath))
+	}
+
+	d.cachedProtobufJavascriptBasePath = basePath
+	return pluginPath, nil
+}
+
+func (d *downloader) downloadProtobufJavascriptInternal(pluginPath string, goos string, goarch string) error {
+	version := d.config.Compile.ProtobufJavascriptVersion
+	protocM, err := getProtocMPath(goarch)
+	if err != nil {
+		return err
+	}
+	protocS, err := getProtocSPath(goos)
+	if err != nil {
+		return err
+	}
+	match := func(name string) bool {
+		return path.Base(name) == getExecutableName(goos, jsPluginName)
+	}
+	if goos == "windows" {
+		// the Windows release is a zip file named by bitness instead of by architecture
+		return d.downloadZipFile(
+			fmt.Sprintf(
+				"https://github.com/protocolbuffers/protobuf-javascript/releases/download/v%s/%s-%s-%s64.zip",
+				version,
+				protobufJavascriptName,
+				version,
+				protocS,
+			),
+			match,
+			pluginPath,
+			0755,
+		)
+	}
+	return d.downloadTarGzFile(
+		fmt.Sprintf(
+			"https://github.com/protocolbuffers/protobuf-javascript/releases/download/v%s/%s-%s-%s-%s.tar.gz",
+			version,
+			protobufJavascriptName,
+			version,
+			protocS,
+			protocM,
+		),
+		match,
+		pluginPath,
+		0755,
+	)
+}
+
+func (d *downloader) getProtobufJavascriptBasePath() (string, error) {
+	basePathNoVersion, err := d.getProtobufJavascriptBasePathNoVersion()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePathNoVersion, d.config.Compile.ProtobufJavascriptVersion), nil
+}
+
+func (d *downloader) getProtobufJavascriptBasePathNoVersion() (string, error) {
+	// this is a sibling of the protobuf directory
+	basePath, err := d.getBasePathNoVersion()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(basePath), protobufJavascriptName), nil
+}
+
+// getNodeModulesPluginPath returns the path to the plugin in the
+// node_modules/.bin directory of dirPath or the closest parent
+// directory that has it, or empty if there is no such directory,
+// which is where npm installs plugins such as protoc-gen-ts.
+func getNodeModulesPluginPath(dirPath string, pluginName string) string {
+	// npm installs a .cmd script to run the plugin on Windows
+	if runtime.GOOS == "windows" {
+		pluginName += ".cmd"
+	}
+	for {
+		pluginPath := filepath.Join(dirPath, "node_modules", ".bin", pluginName)
+		if fileInfo, err := os.Stat(pluginPath); err == nil && !fileInfo.IsDir() {
+			return pluginPath
+		}
+		parentDirPath := filepath.Dir(dirPath)
+		if parentDirPath == dirPath {
+			return ""
+		}
+		dirPath = parentDirPath
+	}
+}
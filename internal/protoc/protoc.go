@@ -25,12 +25,45 @@ import (
 	"path/filepath"
 
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/uber/prototool/internal/exectrace"
 	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/progress"
 	"github.com/uber/prototool/internal/settings"
 	"github.com/uber/prototool/internal/text"
+	"github.com/uber/prototool/internal/timing"
 	"go.uber.org/zap"
 )
 
+// VersionRequirement is the minimum protoc version needed for a feature
+// used by a file, such as proto3 optional fields or editions.
+type VersionRequirement struct {
+	// The minimum protoc version, for example 3.12.0 or 27.0.
+	Version string
+	// The feature that needs the version.
+	Feature string
+	// The display path of the file that uses the feature.
+	FilePath string
+}
+
+// GetVersionRequirement returns the highest minimum protoc version needed
+// for the features used by the files of the ProtoSet, or nil if the files
+// do not use any feature that needs a newer protoc.
+//
+// Files that cannot be parsed are skipped, as protoc reports the errors.
+func GetVersionRequirement(protoSet *file.ProtoSet) (*VersionRequirement, error) {
+	return getVersionRequirement(protoSet)
+}
+
+// GetProtobufVersion returns the protoc version used for the ProtoSet.
+//
+// This is the version in the config, or vars.DefaultProtocVersion if not set.
+// If the config sets the version to settings.AutoProtocVersion, this is the
+// version from GetVersionRequirement, or vars.DefaultProtocVersion if that
+// is newer.
+func GetProtobufVersion(protoSet *file.ProtoSet) (string, error) {
+	return getProtobufVersion(protoSet)
+}
+
 // Downloader downloads and caches protobuf.
 type Downloader interface {
 	// Download protobuf.
@@ -39,11 +72,18 @@ type Downloader interface {
 	// This will download to ${XDG_CACHE_HOME}/prototool/$(uname -s)/$(uname -m)
 	// unless overridden by a DownloaderOption.
 	// If ${XDG_CACHE_HOME} is not set, it defaults to ${HOME}/Library/Caches on
-	// Darwin, and ${HOME}/.cache on Linux.
+	// Darwin, ${HOME}/.cache on Linux, and %LOCALAPPDATA% on Windows.
 	// If ${HOME} is not set, an error will be returned.
 	//
 	// Returns the path to the downloaded protobuf artifacts.
 	//
+	// If a protoc binary path is set with DownloaderWithProtocBinPath or the
+	// config, nothing is downloaded and the path to protoc is returned.
+	//
+	// The plugins and include files that have a version in the config,
+	// such as protoc-gen-validate and googleapis, are downloaded
+	// concurrently with protobuf. Interrupted downloads are resumed.
+	//
 	// ProtocPath and WellKnownTypesIncludePath implicitly call this.
 	Download() (string, error)
 
@@ -59,6 +99,67 @@ type Downloader interface {
 	// If not downloaded, this downloads and caches protobuf. This is thread-safe.
 	WellKnownTypesIncludePath() (string, error)
 
+	// Get the path to protoc-gen-validate.
+	//
+	// If not downloaded, this downloads and caches protoc-gen-validate.
+	// This is thread-safe. Returns an error if the config does not have
+	// a protoc-gen-validate version.
+	ValidatePluginPath() (string, error)
+
+	// Get the path to include for validate/validate.proto.
+	//
+	// Inside this directory will be the subdirectory validate.
+	//
+	// If not downloaded, this downloads and caches protoc-gen-validate.
+	// This is thread-safe. Returns an error if the config does not have
+	// a protoc-gen-validate version.
+	ValidateIncludePath() (string, error)
+
+	// Get the path to include for gogoproto/gogo.proto.
+	//
+	// Inside this directory will be the subdirectory gogoproto.
+	//
+	// If not downloaded, this downloads and caches gogo.proto from
+	// github.com/gogo/protobuf. This is thread-safe. Returns an error
+	// if the config does not have a gogo protobuf version.
+	GogoIncludePath() (string, error)
+
+	// Get the path to protoc-gen-grpc-gateway or protoc-gen-openapiv2
+	// for the given plugin name, either grpc-gateway or openapiv2.
+	//
+	// If not downloaded, this downloads and caches grpc-gateway.
+	// This is thread-safe. Returns an error if the config does not have
+	// a grpc-gateway version.
+	GRPCGatewayPluginPath(name string) (string, error)
+
+	// Get the path to include for google/api/annotations.proto,
+	// google/api/http.proto, and the protoc-gen-openapiv2 options.
+	//
+	// Inside this directory will be the subdirectories google and
+	// protoc-gen-openapiv2.
+	//
+	// If not downloaded, this downloads and caches grpc-gateway.
+	// This is thread-safe. Returns an error if the config does not have
+	// a grpc-gateway version.
+	GRPCGatewayIncludePath() (string, error)
+
+	// Get the path to protoc-gen-js.
+	//
+	// If not downloaded, this downloads and caches protobuf-javascript.
+	// This is thread-safe. Returns an error if the config does not have
+	// a protobuf-javascript version.
+	ProtobufJavascriptPluginPath() (string, error)
+
+	// Get the path to include for the googleapis protos, such as
+	// google/api/annotations.proto and google/type/date.proto.
+	//
+	// Inside this directory will be the subdirectory google.
+	//
+	// If not downloaded, this downloads and caches the .proto files of
+	// github.com/googleapis/googleapis. This is thread-safe. Returns an
+	// error if the config does not have a googleapis version.
+	GoogleapisIncludePath() (string, error)
+
 	// Delete any downloaded artifacts.
 	//
 	// This is not thread-safe and no calls to other functions can be reliably
@@ -96,6 +197,48 @@ func DownloaderWithProtocURL(protocURL string) DownloaderOption {
 	}
 }
 
+// DownloaderWithProtocBinPath returns a DownloaderOption that uses the given
+// protoc binary instead of downloading protoc. If the path has no path
+// separators, it is looked up in PATH.
+//
+// The default is to use the protoc bin path in the config, and if that
+// is not set, to download protoc.
+func DownloaderWithProtocBinPath(protocBinPath string) DownloaderOption {
+	return func(downloader *downloader) {
+		downloader.protocBinPath = protocBinPath
+	}
+}
+
+// DownloaderWithProtocWKTPath returns a DownloaderOption that uses the given
+// path to include for the well-known types.
+//
+// The default is to use the protoc wkt path in the config, and if that is
+// not set, the include directory next to the bin directory of the protoc
+// binary if a protoc bin path is set, otherwise the downloaded include directory.
+func DownloaderWithProtocWKTPath(protocWKTPath string) DownloaderOption {
+	return func(downloader *downloader) {
+		downloader.protocWKTPath = protocWKTPath
+	}
+}
+
+// DownloaderWithProgress returns a DownloaderOption that reports the
+// progress of each download to the given Reporter.
+//
+// The default is to not report anything.
+func DownloaderWithProgress(progressReporter progress.Reporter) DownloaderOption {
+	return func(downloader *downloader) {
+		downloader.progressReporter = progressReporter
+	}
+}
+
+// DownloaderWithExecTracer returns a DownloaderOption that traces the
+// protoc processes run to check the protoc version.
+func DownloaderWithExecTracer(execTracer exectrace.Tracer) DownloaderOption {
+	return func(downloader *downloader) {
+		downloader.execTracer = execTracer
+	}
+}
+
 // NewDownloader returns a new Downloader for the given config and DownloaderOptions.
 func NewDownloader(config settings.Config, options ...DownloaderOption) Downloader {
 	return newDownloader(config, options...)
@@ -104,8 +247,10 @@ func NewDownloader(config settings.Config, options ...DownloaderOption) Download
 // CompileResult is the result of a compile
 type CompileResult struct {
 	// The failures from all calls.
+	//
+	// If all failures are warnings, FileDescriptorSets will still be set.
 	Failures []*text.Failure
-	// Will not be set if there are any failures.
+	// Will not be set if there are any failures that are not warnings.
 	//
 	// Will only be set if the CompilerWithFileDescriptorSet
 	// option is used.
@@ -126,6 +271,42 @@ type Compiler interface {
 	//
 	// This will ignore the CompilerWithFileDescriptorSet option.
 	ProtocCommands(*file.ProtoSet) ([]string, error)
+
+	// Return the protoc commands that would be run on Compile as
+	// structured values, sorted by directory.
+	//
+	// This will ignore the CompilerWithFileDescriptorSet option.
+	ProtocPlan(*file.ProtoSet) ([]*ProtocCommand, error)
+}
+
+// ProtocCommand is a protoc command that would be run on Compile.
+type ProtocCommand struct {
+	// The path to protoc.
+	ProtocPath string `json:"protoc_path"`
+	// The arguments to protoc, not including the path to protoc.
+	Args []string `json:"args"`
+	// The directory of the files being compiled.
+	DirPath string `json:"dir_path"`
+	// The include paths passed with -I, in order.
+	IncludePaths []string `json:"include_paths"`
+	// The paths of the files being compiled.
+	FilePaths []string `json:"file_paths"`
+	// Only set if the command generates with a plugin.
+	Plugin *ProtocCommandPlugin `json:"plugin,omitempty"`
+}
+
+// ProtocCommandPlugin is the plugin of a ProtocCommand.
+type ProtocCommandPlugin struct {
+	// The name of the plugin, as in --NAME_out.
+	Name string `json:"name"`
+	// The path to the plugin, only set if passed with --plugin.
+	Path string `json:"path,omitempty"`
+	// The parameter passed to the plugin, including any Mfile=package modifiers.
+	Flags string `json:"flags,omitempty"`
+	// The directory that the plugin outputs to.
+	OutputPath string `json:"output_path"`
+	// The extra arguments passed to protoc for this plugin.
+	ProtocArgs []string `json:"protoc_args,omitempty"`
 }
 
 // CompilerOption is an option for a new Compiler.
@@ -158,6 +339,49 @@ func CompilerWithProtocURL(protocURL string) CompilerOption {
 	}
 }
 
+// CompilerWithProtocBinPath returns a CompilerOption that uses the given
+// protoc binary instead of downloading protoc.
+//
+// See DownloaderWithProtocBinPath for more details.
+func CompilerWithProtocBinPath(protocBinPath string) CompilerOption {
+	return func(compiler *compiler) {
+		compiler.protocBinPath = protocBinPath
+	}
+}
+
+// CompilerWithProtocWKTPath returns a CompilerOption that uses the given
+// path to include for the well-known types.
+//
+// See DownloaderWithProtocWKTPath for more details.
+func CompilerWithProtocWKTPath(protocWKTPath string) CompilerOption {
+	return func(compiler *compiler) {
+		compiler.protocWKTPath = protocWKTPath
+	}
+}
+
+// CompilerWithJobs returns a CompilerOption that runs at most the given
+// number of protoc invocations at once. Each plugin for each directory
+// is a separate protoc invocation.
+//
+// The default is runtime.NumCPU().
+func CompilerWithJobs(jobs int) CompilerOption {
+	return func(compiler *compiler) {
+		compiler.jobs = jobs
+	}
+}
+
+// CompilerWithRemoteExecutionURL returns a CompilerOption that runs protoc
+// on the remote execution worker at the given HTTP URL instead of locally.
+//
+// The worker receives a JSON POST request with the protoc args and the
+// input files, and is expected to have protoc and the plugins installed.
+// The default is to run protoc locally.
+func CompilerWithRemoteExecutionURL(remoteExecutionURL string) CompilerOption {
+	return func(compiler *compiler) {
+		compiler.remoteExecutionURL = remoteExecutionURL
+	}
+}
+
 // CompilerWithGen says to also generate the code.
 func CompilerWithGen() CompilerOption {
 	return func(compiler *compiler) {
@@ -172,6 +396,46 @@ func CompilerWithFileDescriptorSet() CompilerOption {
 	}
 }
 
+// CompilerWithProgress returns a CompilerOption that reports the progress
+// of downloading protoc and the plugins, and of the protoc invocations for
+// the directories, to the given Reporter.
+//
+// The default is to not report anything.
+func CompilerWithProgress(progressReporter progress.Reporter) CompilerOption {
+	return func(compiler *compiler) {
+		compiler.progressReporter = progressReporter
+	}
+}
+
+// CompilerWithExecTracer returns a CompilerOption that traces every
+// protoc process run locally with the given tracer.
+//
+// The default is to not trace anything.
+func CompilerWithExecTracer(execTracer exectrace.Tracer) CompilerOption {
+	return func(compiler *compiler) {
+		compiler.execTracer = execTracer
+	}
+}
+
+// CompilerWithTimer returns a CompilerOption that records the time spent
+// downloading protoc and running each protoc command with the given timer.
+//
+// The default is to not record anything.
+func CompilerWithTimer(timer timing.Timer) CompilerOption {
+	return func(compiler *compiler) {
+		compiler.timer = timer
+	}
+}
+
+// CompilerWithWarningsAsErrors says to treat protoc warnings as errors.
+//
+// The default is to use the warnings_as_errors value of the config.
+func CompilerWithWarningsAsErrors() CompilerOption {
+	return func(compiler *compiler) {
+		compiler.warningsAsErrors = true
+	}
+}
+
 // NewCompiler returns a new Compiler.
 func NewCompiler(options ...CompilerOption) Compiler {
 	return newCompiler(options...)
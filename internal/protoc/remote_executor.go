@@ -0,0 +1,358 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// remoteDescriptorSetPath is the path of the -o file in remote requests.
+const remoteDescriptorSetPath = "out/descriptor_set.pb"
+
+var importRegexp = regexp.MustCompile(`^\s*import\s+(?:public\s+|weak\s+)?"([^"]+)"\s*;`)
+
+// remoteRequest is the body of the POST request to a remote execution worker.
+//
+// All paths are relative with / separators. The worker writes the files,
+// runs protoc with the args in the directory it wrote the files to, and
+// responds with a remoteResponse containing the files under out.
+type remoteRequest struct {
+	Args  []string          `json:"args"`
+	Files map[string][]byte `json:"files"`
+}
+
+// remoteResponse is the body of the response of a remote execution worker.
+type remoteResponse struct {
+	ExitCode int               `json:"exit_code"`
+	Stderr   string            `json:"stderr"`
+	Files    map[string][]byte `json:"files"`
+}
+
+// remoteExitError says that protoc exited with a non-zero status on
+// the remote execution worker.
+type remoteExitError struct {
+	exitCode int
+}
+
+func (r *remoteExitError) Error() string {
+	return fmt.Sprintf("remote protoc exited with status %d", r.exitCode)
+}
+
+// remoteExecutor runs protoc by sending the args and input files to a
+// remote execution worker over HTTP.
+type remoteExecutor struct {
+	logger     *zap.Logger
+	url        string
+	httpClient *http.Client
+}
+
+func newRemoteExecutor(logger *zap.Logger, url string) *remoteExecutor {
+	return &remoteExecutor{
+		logger:     logger,
+		url:        url,
+		httpClient: &http.Client{},
+	}
+}
+
+func (r *remoteExecutor) execute(cmdMeta *cmdMeta) (retOutput string, retErr error) {
+	remoteArgs, err := newRemoteArgs(cmdMeta.execCmd.Args[1:], cmdMeta.includes)
+	if err != nil {
+		return "", err
+	}
+	request, err := remoteArgs.newRequest()
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	ctx := context.Background()
+	if timeout := cmdMeta.timeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	httpRequest, err := http.NewRequest(http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	httpRequest = httpRequest.WithContext(ctx)
+	httpRequest.Header.Set("Content-Type", "application/json")
+	r.logger.Debug("remote execution request", zap.Strings("args", request.Args), zap.Int("files", len(request.Files)))
+	httpResponse, err := r.httpClient.Do(httpRequest)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", &killedError{fmt.Sprintf("timed out after %v", cmdMeta.timeout())}
+		}
+		return "", err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, httpResponse.Body.Close())
+	}()
+	data, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return "", err
+	}
+	if httpResponse.StatusCode < 200 || httpResponse.StatusCode >= 300 {
+		return "", fmt.Errorf("remote execution returned %s: %s", httpResponse.Status, strings.TrimSpace(string(data)))
+	}
+	response := &remoteResponse{}
+	if err := json.Unmarshal(data, response); err != nil {
+		return "", fmt.Errorf("could not parse remote execution response: %v", err)
+	}
+	if err := remoteArgs.writeFiles(response.Files); err != nil {
+		return "", err
+	}
+	output := remoteArgs.localizeOutput(response.Stderr)
+	if response.ExitCode != 0 {
+		return output, &remoteExitError{exitCode: response.ExitCode}
+	}
+	return output, nil
+}
+
+// remoteArgs are the args of a protoc command rewritten to the paths
+// of a remote request.
+type remoteArgs struct {
+	args []string
+	// the local directory for each remote directory, such as include/0
+	localDirPaths map[string]string
+	// the local file for each remote file outside of the include directories
+	localFilePaths map[string]string
+	// the import paths of the files to compile
+	importPaths []string
+	// the local path of the -o file, if any
+	descriptorSetFilePath string
+}
+
+// newRemoteArgs rewrites the local paths in the args to remote paths.
+//
+// The includes are mapped to include/N, the --*_out directories to out/N,
+// the --descriptor_set_in files to in/N, and the -o file to
+// out/descriptor_set.pb. --plugin flags are dropped, so plugins are
+// looked up by name on the remote execution worker.
+func newRemoteArgs(args []string, includes []string) (*remoteArgs, error) {
+	r := &remoteArgs{
+		localDirPaths:  make(map[string]string),
+		localFilePaths: make(map[string]string),
+	}
+	includeToRemote := make(map[string]string, len(includes))
+	for i, include := range includes {
+		remoteDirPath := "include/" + strconv.Itoa(i)
+		includeToRemote[include] = remoteDirPath
+		r.localDirPaths[remoteDirPath] = include
+	}
+	numOuts := 0
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-I" && i+1 < len(args):
+			i++
+			remoteDirPath, ok := includeToRemote[args[i]]
+			if !ok {
+				return nil, fmt.Errorf("unknown include for remote execution: %s", args[i])
+			}
+			r.args = append(r.args, "-I", remoteDirPath)
+		case arg == "-o" && i+1 < len(args):
+			i++
+			r.descriptorSetFilePath = args[i]
+			r.args = append(r.args, "-o", remoteDescriptorSetPath)
+		case strings.HasPrefix(arg, "--descriptor_set_in="):
+			localFilePaths := strings.Split(strings.TrimPrefix(arg, "--descriptor_set_in="), string(os.PathListSeparator))
+			remoteFilePaths := make([]string, len(localFilePaths))
+			for j, localFilePath := range localFilePaths {
+				remoteFilePaths[j] = "in/" + strconv.Itoa(j)
+				r.localFilePaths[remoteFilePaths[j]] = localFilePath
+			}
+			// the worker is expected to run on a system that uses : as the separator
+			r.args = append(r.args, "--descriptor_set_in="+strings.Join(remoteFilePaths, ":"))
+		case strings.HasPrefix(arg, "--plugin="):
+		case strings.HasPrefix(arg, "--") && strings.Contains(arg, "_out="):
+			split := strings.SplitN(arg, "=", 2)
+			value := split[1]
+			prefix := ""
+			if index := strings.LastIndex(value, ":"); index >= 0 && filepath.IsAbs(value[index+1:]) {
+				prefix = value[:index+1]
+				value = value[index+1:]
+			}
+			remoteDirPath := "out/" + strconv.Itoa(numOuts)
+			numOuts++
+			r.localDirPaths[remoteDirPath] = value
+			r.args = append(r.args, split[0]+"="+prefix+remoteDirPath)
+		case !strings.HasPrefix(arg, "-") && strings.HasSuffix(arg, ".proto"):
+			remoteFilePath, importPath, err := getRemoteProtoFilePath(arg, includes, includeToRemote)
+			if err != nil {
+				return nil, err
+			}
+			r.importPaths = append(r.importPaths, importPath)
+			r.args = append(r.args, remoteFilePath)
+		default:
+			r.args = append(r.args, arg)
+		}
+	}
+	return r, nil
+}
+
+// getRemoteProtoFilePath returns the remote path and the import path of
+// the proto file in the first include that contains it.
+func getRemoteProtoFilePath(filePath string, includes []string, includeToRemote map[string]string) (string, string, error) {
+	for _, include := range includes {
+		relFilePath, err := filepath.Rel(include, filePath)
+		if err != nil || relFilePath == ".." || strings.HasPrefix(relFilePath, ".."+string(filepath.Separator)) {
+			continue
+		}
+		importPath := filepath.ToSlash(relFilePath)
+		return path.Join(includeToRemote[include], importPath), importPath, nil
+	}
+	return "", "", fmt.Errorf("%s is not in any include for remote execution", filePath)
+}
+
+// newRequest returns the request with the files to compile, the files
+// they transitively import, and the --descriptor_set_in files.
+func (r *remoteArgs) newRequest() (*remoteRequest, error) {
+	request := &remoteRequest{
+		Args:  r.args,
+		Files: make(map[string][]byte),
+	}
+	for remoteFilePath, localFilePath := range r.localFilePaths {
+		data, err := ioutil.ReadFile(localFilePath)
+		if err != nil {
+			return nil, err
+		}
+		request.Files[remoteFilePath] = data
+	}
+	seen := make(map[string]struct{})
+	importPaths := r.importPaths
+	for len(importPaths) > 0 {
+		importPath := importPaths[0]
+		importPaths = importPaths[1:]
+		if _, ok := seen[importPath]; ok {
+			continue
+		}
+		seen[importPath] = struct{}{}
+		remoteFilePath, data, err := r.readImport(importPath)
+		if err != nil {
+			return nil, err
+		}
+		// imports that are not in any include are left to protoc to report,
+		// or are in the --descriptor_set_in files
+		if data == nil {
+			continue
+		}
+		request.Files[remoteFilePath] = data
+		importPaths = append(importPaths, getImportPaths(data)...)
+	}
+	return request, nil
+}
+
+// readImport reads the file for the import path from the first
+// include that contains it, returning nil data if none do.
+func (r *remoteArgs) readImport(importPath string) (string, []byte, error) {
+	for i := 0; ; i++ {
+		remoteDirPath := "include/" + strconv.Itoa(i)
+		include, ok := r.localDirPaths[remoteDirPath]
+		if !ok {
+			return "", nil, nil
+		}
+		data, err := ioutil.ReadFile(filepath.Join(include, filepath.FromSlash(importPath)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", nil, err
+		}
+		return path.Join(remoteDirPath, importPath), data, nil
+	}
+}
+
+// getImportPaths returns the import paths of the proto file.
+func getImportPaths(data []byte) []string {
+	var importPaths []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if matches := importRegexp.FindStringSubmatch(scanner.Text()); len(matches) == 2 {
+			importPaths = append(importPaths, matches[1])
+		}
+	}
+	return importPaths
+}
+
+// writeFiles writes the files of a response to the local directories
+// that their remote directories were mapped from.
+func (r *remoteArgs) writeFiles(files map[string][]byte) error {
+	for remoteFilePath, data := range files {
+		localFilePath, err := r.getLocalFilePath(remoteFilePath)
+		if err != nil {
+			return err
+		}
+		if localFilePath == "" || localFilePath == os.DevNull {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(localFilePath), 0744); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(localFilePath, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getLocalFilePath returns the local path of a file in a response, or
+// an error if the file is not in an output directory.
+func (r *remoteArgs) getLocalFilePath(remoteFilePath string) (string, error) {
+	remoteFilePath = path.Clean(remoteFilePath)
+	if remoteFilePath == remoteDescriptorSetPath {
+		return r.descriptorSetFilePath, nil
+	}
+	split := strings.SplitN(remoteFilePath, "/", 3)
+	if len(split) == 3 && split[0] == "out" {
+		if localDirPath, ok := r.localDirPaths[split[0]+"/"+split[1]]; ok && !strings.HasPrefix(split[2], "../") {
+			return filepath.Join(localDirPath, filepath.FromSlash(split[2])), nil
+		}
+	}
+	return "", fmt.Errorf("remote execution returned a file outside of the output directories: %s", remoteFilePath)
+}
+
+// localizeOutput replaces the remote directories in the output of
+// protoc with the local directories they were mapped from.
+func (r *remoteArgs) localizeOutput(output string) string {
+	oldnew := make([]string, 0, 2*len(r.localDirPaths))
+	for remoteDirPath, localDirPath := range r.localDirPaths {
+		oldnew = append(oldnew, remoteDirPath+"/", localDirPath+string(filepath.Separator))
+	}
+	return strings.NewReplacer(oldnew...).Replace(output)
+}
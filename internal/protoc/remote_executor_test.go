@@ -0,0 +1,141 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/file"
+	"go.uber.org/zap"
+)
+
+func TestRemoteExecutor(t *testing.T) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	includePath := filepath.Join(tmpDirPath, "proto")
+	wktPath := filepath.Join(tmpDirPath, "wkt")
+	outPath := filepath.Join(tmpDirPath, "gen")
+	writeTestFile(t, filepath.Join(includePath, "a", "a.proto"), `syntax = "proto3";
+import "b/b.proto";
+import public "google/protobuf/empty.proto";
+`)
+	writeTestFile(t, filepath.Join(includePath, "b", "b.proto"), `syntax = "proto3";
+import "missing.proto";
+`)
+	writeTestFile(t, filepath.Join(includePath, "c", "c.proto"), `syntax = "proto3";`)
+	writeTestFile(t, filepath.Join(wktPath, "google", "protobuf", "empty.proto"), `syntax = "proto3";`)
+
+	var request remoteRequest
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, httpRequest *http.Request) {
+		assert.NoError(t, json.NewDecoder(httpRequest.Body).Decode(&request))
+		assert.NoError(t, json.NewEncoder(responseWriter).Encode(&remoteResponse{
+			ExitCode: 1,
+			Stderr:   "include/0/a/a.proto:1:1: foo\n",
+			Files: map[string][]byte{
+				"out/0/a/a.pb.foo": []byte("generated"),
+			},
+		}))
+	}))
+	defer server.Close()
+
+	cmdMeta := &cmdMeta{
+		execCmd: exec.Command(
+			"protoc",
+			"-I", includePath,
+			"-I", wktPath,
+			"--foo_out=bar=baz:"+outPath,
+			"--plugin=protoc-gen-foo=/usr/local/bin/protoc-gen-foo",
+			filepath.Join(includePath, "a", "a.proto"),
+		),
+		protoSet: &file.ProtoSet{},
+		includes: []string{includePath, wktPath},
+	}
+	output, err := newRemoteExecutor(zap.NewNop(), server.URL).execute(cmdMeta)
+	assert.True(t, isExitError(err))
+	assert.Equal(t, filepath.Join(includePath, "a", "a.proto")+":1:1: foo\n", output)
+	assert.Equal(
+		t,
+		[]string{
+			"-I", "include/0",
+			"-I", "include/1",
+			"--foo_out=bar=baz:out/0",
+			"include/0/a/a.proto",
+		},
+		request.Args,
+	)
+	assert.Equal(
+		t,
+		[]string{
+			"include/0/a/a.proto",
+			"include/0/b/b.proto",
+			"include/1/google/protobuf/empty.proto",
+		},
+		sortedKeys(request.Files),
+	)
+	data, err := ioutil.ReadFile(filepath.Join(outPath, "a", "a.pb.foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "generated", string(data))
+}
+
+func TestRemoteArgsGetLocalFilePath(t *testing.T) {
+	remoteArgs, err := newRemoteArgs([]string{"--foo_out=/gen", "-o", "/tmp/foo.bin"}, nil)
+	require.NoError(t, err)
+	localFilePath, err := remoteArgs.getLocalFilePath("out/0/foo/bar.go")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/gen", "foo", "bar.go"), localFilePath)
+	localFilePath, err = remoteArgs.getLocalFilePath(remoteDescriptorSetPath)
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/foo.bin", localFilePath)
+	for _, remoteFilePath := range []string{
+		"out/0/../../etc/passwd",
+		"out/1/foo.go",
+		"include/0/foo.proto",
+		"out/0",
+	} {
+		_, err := remoteArgs.getLocalFilePath(remoteFilePath)
+		assert.Error(t, err, remoteFilePath)
+	}
+}
+
+func writeTestFile(t *testing.T, filePath string, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+}
+
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
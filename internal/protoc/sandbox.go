@@ -0,0 +1,133 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sandboxEnvNames are the environment variables always kept in the sandbox.
+var sandboxEnvNames = []string{"PATH"}
+
+// getSandboxEnv returns the entries of environ whose names are in
+// sandboxEnvNames or names.
+func getSandboxEnv(environ []string, names []string) []string {
+	keep := make(map[string]struct{}, len(sandboxEnvNames)+len(names))
+	for _, name := range sandboxEnvNames {
+		keep[name] = struct{}{}
+	}
+	for _, name := range names {
+		keep[name] = struct{}{}
+	}
+	env := make([]string, 0, len(keep))
+	for _, entry := range environ {
+		if _, ok := keep[strings.SplitN(entry, "=", 2)[0]]; ok {
+			env = append(env, entry)
+		}
+	}
+	return env
+}
+
+// limitWriter writes to a writer until a maximum number of bytes
+// is reached, after which it drops all writes and closes exceeded.
+type limitWriter struct {
+	writer    io.Writer
+	remaining int
+	exceeded  chan struct{}
+	lock      sync.Mutex
+}
+
+func newLimitWriter(writer io.Writer, maxBytes int) *limitWriter {
+	return &limitWriter{
+		writer:    writer,
+		remaining: maxBytes,
+		exceeded:  make(chan struct{}),
+	}
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.remaining < 0 {
+		return len(p), nil
+	}
+	if len(p) > l.remaining {
+		if _, err := l.writer.Write(p[:l.remaining]); err != nil {
+			return 0, err
+		}
+		l.remaining = -1
+		close(l.exceeded)
+		return len(p), nil
+	}
+	l.remaining -= len(p)
+	return l.writer.Write(p)
+}
+
+// runExecCmd runs the command, killing it if it does not finish within
+// timeout or if exceeded is closed. A timeout of 0 means no timeout,
+// and a nil exceeded is never closed.
+//
+// Plugins started by protoc may keep stderr open after protoc is killed,
+// so runExecCmd does not wait for the command to finish after killing it.
+func runExecCmd(execCmd *exec.Cmd, timeout time.Duration, maxBytes int, exceeded <-chan struct{}) error {
+	if err := execCmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- execCmd.Wait()
+	}()
+	var timeoutC <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+	select {
+	case err := <-done:
+		select {
+		case <-exceeded:
+			return &killedError{fmt.Sprintf("wrote more than %d bytes of output", maxBytes)}
+		default:
+			return err
+		}
+	case <-timeoutC:
+		_ = execCmd.Process.Kill()
+		return &killedError{fmt.Sprintf("timed out after %v", timeout)}
+	case <-exceeded:
+		_ = execCmd.Process.Kill()
+		return &killedError{fmt.Sprintf("wrote more than %d bytes of output", maxBytes)}
+	}
+}
+
+// killedError is returned by runExecCmd when it killed the command.
+type killedError struct {
+	reason string
+}
+
+func (k *killedError) Error() string {
+	return k.reason
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSandboxEnv(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{"PATH=/bin", "GOPATH=/go"},
+		getSandboxEnv(
+			[]string{"PATH=/bin", "AWS_SECRET_ACCESS_KEY=secret", "GOPATH=/go", "GOPATHX=/gox"},
+			[]string{"GOPATH"},
+		),
+	)
+	assert.Equal(t, []string{}, getSandboxEnv([]string{"HOME=/root"}, nil))
+}
+
+func TestLimitWriter(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	writer := newLimitWriter(buffer, 5)
+	n, err := writer.Write([]byte("abc"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	select {
+	case <-writer.exceeded:
+		t.Fatal("exceeded closed before the maximum was reached")
+	default:
+	}
+	n, err = writer.Write([]byte("defgh"))
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	n, err = writer.Write([]byte("ijk"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, "abcde", buffer.String())
+	<-writer.exceeded
+}
+
+func TestRunExecCmdTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not installed")
+	}
+	err := runExecCmd(exec.Command("sleep", "10"), 100*time.Millisecond, 0, nil)
+	require.Error(t, err)
+	assert.Equal(t, "timed out after 100ms", err.Error())
+	assert.NoError(t, runExecCmd(exec.Command("sleep", "0"), 10*time.Second, 0, nil))
+}
+
+func TestRunExecCmdMaxOutputBytes(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+	execCmd := exec.Command("sh", "-c", "while true; do echo foo >&2; done")
+	stderr := newLimitWriter(bytes.NewBuffer(nil), 100)
+	execCmd.Stderr = stderr
+	err := runExecCmd(execCmd, 10*time.Second, 100, stderr.exceeded)
+	require.Error(t, err)
+	assert.Equal(t, "wrote more than 100 bytes of output", err.Error())
+}
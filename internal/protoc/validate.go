@@ -0,0 +1,280 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+const (
+	validateName        = "protoc-gen-validate"
+	validateIncludeFile = "validate/validate.proto"
+	validateGoPackage   = "github.com/envoyproxy/protoc-gen-validate/validate"
+)
+
+func (d *downloader) ValidatePluginPath() (string, error) {
+	basePath, err := d.downloadValidate()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePath, "bin", getExecutableName(runtime.GOOS, validateName)), nil
+}
+
+func (d *downloader) ValidateIncludePath() (string, error) {
+	basePath, err := d.downloadValidate()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePath, "include"), nil
+}
+
+func (d *downloader) downloadValidate() (string, error) {
+	if d.config.Compile.ValidateVersion == "" {
+		return "", fmt.Errorf("protoc_gen_validate_version must be set in the config file to use protoc-gen-validate")
+	}
+	d.validateLock.Lock()
+	defer d.validateLock.Unlock()
+	if d.cachedValidateBasePath != "" {
+		return d.cachedValidateBasePath, nil
+	}
+
+	basePath, err := d.getValidateBasePath()
+	if err != nil {
+		return "", err
+	}
+	if err := checkValidateDownloaded(basePath); err != nil {
+		if err := d.downloadValidateInternal(basePath, runtime.GOOS, runtime.GOARCH); err != nil {
+			return "", err
+		}
+		if err := checkValidateDownloaded(basePath); err != nil {
+			return "", err
+		}
+		d.logger.Debug("protoc-gen-validate downloaded", zap.String("path", basePath))
+	} else {
+		d.logger.Debug("protoc-gen-validate already downloaded", zap.String("path", basePath))
+	}
+
+	d.cachedValidateBasePath = basePath
+	return basePath, nil
+}
+
+func (d *downloader) downloadValidateInternal(basePath string, goos string, goarch string) error {
+	version := d.config.Compile.ValidateVersion
+	// the release binaries do not contain validate.proto, so we get it from the source archive
+	if err := d.downloadTarGzFile(
+		fmt.Sprintf("https://github.com/envoyproxy/protoc-gen-validate/archive/v%s.tar.gz", version),
+		func(name string) bool {
+			// the source archive has a top-level directory protoc-gen-validate-VERSION
+			return name == validateName+"-"+version+"/"+validateIncludeFile
+		},
+		filepath.Join(basePath, "include", filepath.FromSlash(validateIncludeFile)),
+		0644,
+	); err != nil {
+		return err
+	}
+	return d.downloadTarGzFile(
+		fmt.Sprintf(
+			"https://github.com/envoyproxy/protoc-gen-validate/releases/download/v%s/%s_%s_%s_%s.tar.gz",
+			version,
+			validateName,
+			version,
+			goos,
+			goarch,
+		),
+		func(name string) bool {
+			return path.Base(name) == getExecutableName(goos, validateName)
+		},
+		filepath.Join(basePath, "bin", getExecutableName(goos, validateName)),
+		0755,
+	)
+}
+
+// downloadTarGzFile downloads the .tar.gz file at url, and writes the first file
+// in it that matches to writeFilePath.
+func (d *downloader) downloadTarGzFile(url string, match func(string) bool, writeFilePath string, fileMode os.FileMode) (retErr error) {
+	fetchedFile, err := d.fetch(url)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, fetchedFile.Close())
+	}()
+	d.logger.Debug("downloaded tar.gz file", zap.String("url", url))
+	gzipReader, err := gzip.NewReader(fetchedFile)
+	if err != nil {
+		return err
+	}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return fmt.Errorf("no matching file for %s found in %s", filepath.Base(writeFilePath), url)
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg || !match(header.Name) {
+			continue
+		}
+		if err := writeFileFromReader(writeFilePath, tarReader, fileMode); err != nil {
+			return err
+		}
+		d.logger.Debug("wrote file", zap.String("path", writeFilePath))
+		return nil
+	}
+}
+
+// downloadZipFile downloads the .zip file at url, and writes the first file
+// in it that matches to writeFilePath.
+func (d *downloader) downloadZipFile(url string, match func(string) bool, writeFilePath string, fileMode os.FileMode) (retErr error) {
+	fetchedFile, err := d.fetch(url)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, fetchedFile.Close())
+	}()
+	d.logger.Debug("downloaded zip file", zap.String("url", url))
+	zipReader, err := zip.NewReader(fetchedFile, fetchedFile.size)
+	if err != nil {
+		return err
+	}
+	for _, file := range zipReader.File {
+		if file.Mode().IsDir() || !match(file.Name) {
+			continue
+		}
+		readCloser, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			retErr = multierr.Append(retErr, readCloser.Close())
+		}()
+		if err := writeFileFromReader(writeFilePath, readCloser, fileMode); err != nil {
+			return err
+		}
+		d.logger.Debug("wrote file", zap.String("path", writeFilePath))
+		return nil
+	}
+	return fmt.Errorf("no matching file for %s found in %s", filepath.Base(writeFilePath), url)
+}
+
+// downloadTarGzFiles downloads the .tar.gz file at url, and writes each file
+// in it that is a key of archiveNameToWriteFilePath to the corresponding value.
+//
+// Returns an error if any file is not found.
+func (d *downloader) downloadTarGzFiles(url string, archiveNameToWriteFilePath map[string]string, fileMode os.FileMode) (retErr error) {
+	fetchedFile, err := d.fetch(url)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, fetchedFile.Close())
+	}()
+	d.logger.Debug("downloaded tar.gz file", zap.String("url", url))
+	gzipReader, err := gzip.NewReader(fetchedFile)
+	if err != nil {
+		return err
+	}
+	tarReader := tar.NewReader(gzipReader)
+	remaining := make(map[string]string, len(archiveNameToWriteFilePath))
+	for archiveName, writeFilePath := range archiveNameToWriteFilePath {
+		remaining[archiveName] = writeFilePath
+	}
+	for len(remaining) > 0 {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			for archiveName := range remaining {
+				return fmt.Errorf("no file %s found in %s", archiveName, url)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		writeFilePath, ok := remaining[header.Name]
+		if header.Typeflag != tar.TypeReg || !ok {
+			continue
+		}
+		if err := writeFileFromReader(writeFilePath, tarReader, fileMode); err != nil {
+			return err
+		}
+		d.logger.Debug("wrote file", zap.String("path", writeFilePath))
+		delete(remaining, header.Name)
+	}
+	return nil
+}
+
+// writeFileFromReader writes the contents of reader to writeFilePath,
+// creating the parent directory if it does not exist.
+func writeFileFromReader(writeFilePath string, reader io.Reader, fileMode os.FileMode) (retErr error) {
+	if err := os.MkdirAll(filepath.Dir(writeFilePath), 0755); err != nil {
+		return err
+	}
+	writeFile, err := os.OpenFile(writeFilePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, fileMode)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, writeFile.Close())
+	}()
+	_, err = io.Copy(writeFile, reader)
+	return err
+}
+
+func (d *downloader) getValidateBasePath() (string, error) {
+	basePathNoVersion, err := d.getValidateBasePathNoVersion()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePathNoVersion, d.config.Compile.ValidateVersion), nil
+}
+
+func (d *downloader) getValidateBasePathNoVersion() (string, error) {
+	// this is a sibling of the protobuf directory
+	basePath, err := d.getBasePathNoVersion()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(basePath), validateName), nil
+}
+
+func checkValidateDownloaded(basePath string) error {
+	for _, filePath := range []string{
+		filepath.Join(basePath, "bin", getExecutableName(runtime.GOOS, validateName)),
+		filepath.Join(basePath, "include", filepath.FromSlash(validateIncludeFile)),
+	} {
+		if _, err := os.Stat(filePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
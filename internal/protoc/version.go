@@ -0,0 +1,226 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/editions"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/vars"
+	"go.uber.org/zap"
+)
+
+const (
+	// protoc 3.12 to 3.14 allow optional fields in proto3 files with
+	// --experimental_allow_proto3_optional, which is added automatically
+	proto3OptionalProtobufVersion = "3.12.0"
+	editionsProtobufVersion       = "27.0"
+)
+
+// the well-known types that were not included with the first protoc
+// releases, keyed by import path
+var importToProtobufVersion = map[string]string{
+	"google/protobuf/cpp_features.proto":  "27.0",
+	"google/protobuf/java_features.proto": "27.0",
+}
+
+func (c *compiler) withProtobufVersion(protoSet *file.ProtoSet) (*file.ProtoSet, error) {
+	config := protoSet.Config
+	if c.protocURL != "" || c.protocBinPath != "" || config.Compile.ProtocBinPath != "" {
+		// we do not know the version of protoc
+		return protoSet, nil
+	}
+	if !config.Compile.AutoProtobufVersion && config.Compile.ProtobufVersion == "" {
+		return protoSet, nil
+	}
+	requirement, err := getVersionRequirement(protoSet)
+	if err != nil {
+		return nil, err
+	}
+	if requirement == nil {
+		return protoSet, nil
+	}
+	if !config.Compile.AutoProtobufVersion {
+		if compareVersions(config.Compile.ProtobufVersion, requirement.Version) < 0 {
+			c.logger.Warn(
+				"protoc_version is too old for the features in use, set it to at least the required version or to "+settings.AutoProtocVersion,
+				zap.String("protoc_version", config.Compile.ProtobufVersion),
+				zap.String("required", requirement.Version),
+				zap.String("feature", requirement.Feature),
+				zap.String("file", requirement.FilePath),
+			)
+		}
+		return protoSet, nil
+	}
+	version := selectProtobufVersion(requirement)
+	c.logger.Debug(
+		"selected protoc version",
+		zap.String("version", version),
+		zap.String("required", requirement.Version),
+		zap.String("feature", requirement.Feature),
+		zap.String("file", requirement.FilePath),
+	)
+	newProtoSet := *protoSet
+	newProtoSet.Config.Compile.ProtobufVersion = version
+	newProtoSet.Config.Compile.AutoProtobufVersion = false
+	return &newProtoSet, nil
+}
+
+func getProtobufVersion(protoSet *file.ProtoSet) (string, error) {
+	if !protoSet.Config.Compile.AutoProtobufVersion {
+		if protoSet.Config.Compile.ProtobufVersion == "" {
+			return vars.DefaultProtocVersion, nil
+		}
+		return protoSet.Config.Compile.ProtobufVersion, nil
+	}
+	requirement, err := getVersionRequirement(protoSet)
+	if err != nil {
+		return "", err
+	}
+	return selectProtobufVersion(requirement), nil
+}
+
+// selectProtobufVersion returns the required version, or the default
+// version if that is newer, as the default version is the best tested.
+func selectProtobufVersion(requirement *VersionRequirement) string {
+	if requirement == nil || compareVersions(vars.DefaultProtocVersion, requirement.Version) >= 0 {
+		return vars.DefaultProtocVersion
+	}
+	return requirement.Version
+}
+
+func getFileVersionRequirement(protoFile *file.ProtoFile) (*VersionRequirement, error) {
+	osFile, err := os.Open(protoFile.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = osFile.Close() }()
+	parser, err := editions.NewParser(osFile)
+	if err != nil {
+		return nil, err
+	}
+	descriptor, err := parser.Parse()
+	if err != nil {
+		// protoc reports the error
+		return nil, nil
+	}
+	if editions.Edition(descriptor) != "" {
+		return &VersionRequirement{
+			Version:  editionsProtobufVersion,
+			Feature:  "editions",
+			FilePath: protoFile.DisplayPath,
+		}, nil
+	}
+	var requirement *VersionRequirement
+	update := func(version string, feature string) {
+		if requirement == nil || compareVersions(version, requirement.Version) > 0 {
+			requirement = &VersionRequirement{
+				Version:  version,
+				Feature:  feature,
+				FilePath: protoFile.DisplayPath,
+			}
+		}
+	}
+	isProto3 := false
+	proto.Walk(
+		descriptor,
+		func(visitee proto.Visitee) {
+			switch element := visitee.(type) {
+			case *proto.Syntax:
+				isProto3 = element.Value == "proto3"
+			case *proto.Import:
+				if version, ok := importToProtobufVersion[element.Filename]; ok {
+					update(version, "import of "+element.Filename)
+				}
+			case *proto.NormalField:
+				if isProto3 && element.Optional {
+					update(proto3OptionalProtobufVersion, "proto3 optional fields")
+				}
+			}
+		},
+	)
+	return requirement, nil
+}
+
+func getVersionRequirement(protoSet *file.ProtoSet) (*VersionRequirement, error) {
+	dirPaths := make([]string, 0, len(protoSet.DirPathToFiles))
+	for dirPath := range protoSet.DirPathToFiles {
+		dirPaths = append(dirPaths, dirPath)
+	}
+	sort.Strings(dirPaths)
+	var requirement *VersionRequirement
+	for _, dirPath := range dirPaths {
+		for _, protoFile := range protoSet.DirPathToFiles[dirPath] {
+			fileRequirement, err := getFileVersionRequirement(protoFile)
+			if err != nil {
+				return nil, err
+			}
+			if fileRequirement != nil && (requirement == nil || compareVersions(fileRequirement.Version, requirement.Version) > 0) {
+				requirement = fileRequirement
+			}
+		}
+	}
+	return requirement, nil
+}
+
+func compareVersions(one string, two string) int {
+	oneParts := parseVersion(one)
+	twoParts := parseVersion(two)
+	for i := range oneParts {
+		switch {
+		case oneParts[i] < twoParts[i]:
+			return -1
+		case oneParts[i] > twoParts[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseVersion returns the major, minor and patch numbers of the version.
+// Releases since 21.x are numbered without the leading 3, so 21.12 is
+// returned as 3.21.12. Pre-release suffixes such as -rc1 are ignored.
+func parseVersion(version string) []int {
+	if i := strings.IndexByte(version, '-'); i >= 0 {
+		version = version[:i]
+	}
+	parts := make([]int, 0, 3)
+	for _, s := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	if len(parts) > 0 && parts[0] >= 21 {
+		parts = append([]int{3}, parts...)
+	}
+	for len(parts) < 3 {
+		parts = append(parts, 0)
+	}
+	return parts[:3]
+}
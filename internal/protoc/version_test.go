@@ -0,0 +1,150 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/vars"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, testCase := range []struct {
+		one      string
+		two      string
+		expected int
+	}{
+		{"3.5.1", "3.5.1", 0},
+		{"3.5.1", "3.12.0", -1},
+		{"3.15.8", "3.12.0", 1},
+		{"3.21.12", "21.12", 0},
+		{"21.12", "27.0", -1},
+		{"27.1", "27.0", 1},
+		{"3.20.3", "21.0", -1},
+		{"3.0.0-beta-2", "3.0.0", 0},
+	} {
+		assert.Equal(t, testCase.expected, compareVersions(testCase.one, testCase.two), "%s %s", testCase.one, testCase.two)
+	}
+}
+
+func TestGetVersionRequirement(t *testing.T) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+
+	newProtoSet := func(fileNameToData map[string]string) *file.ProtoSet {
+		var protoFiles []*file.ProtoFile
+		for fileName, data := range fileNameToData {
+			filePath := filepath.Join(tmpDirPath, fileName)
+			require.NoError(t, ioutil.WriteFile(filePath, []byte(data), 0644))
+			protoFiles = append(protoFiles, &file.ProtoFile{
+				Path:        filePath,
+				DisplayPath: fileName,
+			})
+		}
+		return &file.ProtoSet{
+			DirPathToFiles: map[string][]*file.ProtoFile{
+				tmpDirPath: protoFiles,
+			},
+		}
+	}
+
+	requirement, err := GetVersionRequirement(newProtoSet(map[string]string{
+		"plain.proto":  `syntax = "proto3"; message Foo { string bar = 1; }`,
+		"proto2.proto": `syntax = "proto2"; message Baz { optional string bar = 1; }`,
+	}))
+	require.NoError(t, err)
+	assert.Nil(t, requirement)
+
+	requirement, err = GetVersionRequirement(newProtoSet(map[string]string{
+		"optional.proto": `syntax = "proto3"; message Foo { message Bar { optional string baz = 1; } }`,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, &VersionRequirement{
+		Version:  "3.12.0",
+		Feature:  "proto3 optional fields",
+		FilePath: "optional.proto",
+	}, requirement)
+
+	requirement, err = GetVersionRequirement(newProtoSet(map[string]string{
+		"optional.proto": `syntax = "proto3"; message Foo { optional string bar = 1; }`,
+		"edition.proto":  `edition = "2023"; message Bar { string baz = 1; }`,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, &VersionRequirement{
+		Version:  "27.0",
+		Feature:  "editions",
+		FilePath: "edition.proto",
+	}, requirement)
+
+	requirement, err = GetVersionRequirement(newProtoSet(map[string]string{
+		"features.proto": `syntax = "proto2"; import "google/protobuf/java_features.proto";`,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "27.0", requirement.Version)
+	assert.Equal(t, "features.proto", requirement.FilePath)
+}
+
+func TestGetProtobufVersion(t *testing.T) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	filePath := filepath.Join(tmpDirPath, "foo.proto")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(`edition = "2023"; message Foo {}`), 0644))
+	protoSet := &file.ProtoSet{
+		DirPathToFiles: map[string][]*file.ProtoFile{
+			tmpDirPath: {
+				{
+					Path:        filePath,
+					DisplayPath: "foo.proto",
+				},
+			},
+		},
+	}
+
+	version, err := GetProtobufVersion(protoSet)
+	require.NoError(t, err)
+	assert.Equal(t, vars.DefaultProtocVersion, version)
+
+	protoSet.Config.Compile.ProtobufVersion = "3.11.4"
+	version, err = GetProtobufVersion(protoSet)
+	require.NoError(t, err)
+	assert.Equal(t, "3.11.4", version)
+
+	protoSet.Config.Compile = settings.CompileConfig{
+		AutoProtobufVersion: true,
+	}
+	version, err = GetProtobufVersion(protoSet)
+	require.NoError(t, err)
+	assert.Equal(t, "27.0", version)
+
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(`syntax = "proto3"; message Foo {}`), 0644))
+	version, err = GetProtobufVersion(protoSet)
+	require.NoError(t, err)
+	assert.Equal(t, vars.DefaultProtocVersion, version)
+}
@@ -26,8 +26,11 @@
 package protostrs
 
 import (
+	"bytes"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"text/template"
 
 	"github.com/uber/prototool/internal/strs"
 )
@@ -67,3 +70,275 @@ func JavaPackage(packageName string) string {
 	}
 	return "com." + packageName
 }
+
+// packageVersionRegexp matches package version components such as v1,
+// v2alpha1, and v1beta1.
+var packageVersionRegexp = regexp.MustCompile(`^v[1-9][0-9]*((alpha|beta)[1-9][0-9]*)?$`)
+
+// IsPackageVersion returns true if the given package component is a version
+// such as v1, v2alpha1, or v1beta1.
+func IsPackageVersion(component string) bool {
+	return packageVersionRegexp.MatchString(component)
+}
+
+// IsStablePackageVersion returns true if the given package component is a
+// version without an alpha or beta suffix, such as v1.
+func IsStablePackageVersion(component string) bool {
+	return IsPackageVersion(component) && !strings.ContainsAny(component, "ab")
+}
+
+// PackageVersion returns the last component of the package name if it is a
+// version per IsPackageVersion, otherwise it returns an empty string.
+func PackageVersion(packageName string) string {
+	split := strings.Split(packageName, ".")
+	if version := split[len(split)-1]; IsPackageVersion(version) {
+		return version
+	}
+	return ""
+}
+
+// FileOptionTemplateNames are the names of the file options that can be
+// set from templates when formatting with rewrite.
+var FileOptionTemplateNames = []string{
+	"csharp_namespace",
+	"go_package",
+	"java_multiple_files",
+	"java_outer_classname",
+	"java_package",
+	"objc_class_prefix",
+}
+
+// DefaultFileOptionTemplates are the default templates for file options.
+// These match GoPackage, JavaOuterClassname, and JavaPackage.
+var DefaultFileOptionTemplates = map[string]string{
+	"go_package":           "{{.PackageLast}}pb",
+	"java_multiple_files":  "true",
+	"java_outer_classname": "{{.FileUpperCamelCase}}Proto",
+	"java_package":         "com.{{.Package}}",
+}
+
+// Languages are the languages that can be targeted with the languages
+// setting, sorted by name.
+var Languages = []string{
+	"csharp",
+	"go",
+	"java",
+	"objc",
+}
+
+// LanguageToFileOptionNames is the map from language to the names of the
+// file options that must be set to generate code for the language.
+var LanguageToFileOptionNames = map[string][]string{
+	"csharp": {"csharp_namespace"},
+	"go":     {"go_package"},
+	"java":   {"java_multiple_files", "java_outer_classname", "java_package"},
+	"objc":   {"objc_class_prefix"},
+}
+
+// LanguageFileOptionTemplates returns the default templates for the file
+// options of the given languages, which are expected to be in Languages.
+func LanguageFileOptionTemplates(languages []string) map[string]string {
+	templates := make(map[string]string)
+	for _, language := range languages {
+		for _, name := range LanguageToFileOptionNames[language] {
+			if templateText, ok := DefaultFileOptionTemplates[name]; ok {
+				templates[name] = templateText
+			} else {
+				templates[name] = languageFileOptionTemplates[name]
+			}
+		}
+	}
+	return templates
+}
+
+// languageFileOptionTemplates are the templates for the file options
+// of languages that are not in DefaultFileOptionTemplates.
+var languageFileOptionTemplates = map[string]string{
+	"csharp_namespace":  "{{.PackageUpperCamelCase}}",
+	"objc_class_prefix": "{{.PackageInitials}}",
+}
+
+// FileOptionTemplateData is the data available to file option templates.
+type FileOptionTemplateData struct {
+	// The package, for example foo.bar.v1.
+	Package string
+	// The last component of the package, for example v1.
+	PackageLast string
+	// The package with each "." replaced by "/", for example foo/bar/v1.
+	PackagePath string
+	// The package with each component UpperCamelCased, for example Foo.Bar.V1.
+	PackageUpperCamelCase string
+	// The first letter of each component of the package upper-cased,
+	// for example FBV.
+	PackageInitials string
+	// The basename of the file without the extension UpperCamelCased,
+	// for example FooBar for a/foo_bar.proto.
+	FileUpperCamelCase string
+}
+
+// FileOptionValue returns the value for a file option given its template,
+// a package name, and a file name.
+func FileOptionValue(templateText string, packageName string, filename string) (string, error) {
+	tmpl, err := template.New("").Parse(templateText)
+	if err != nil {
+		return "", err
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := tmpl.Execute(buffer, newFileOptionTemplateData(packageName, filename)); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// DefaultRequestNameTemplate is the default template for the names of
+// request types, which is the RPC name followed by "Request".
+const DefaultRequestNameTemplate = "{{.RPC}}Request"
+
+// DefaultResponseNameTemplate is the default template for the names of
+// response types, which is the RPC name followed by "Response".
+const DefaultResponseNameTemplate = "{{.RPC}}Response"
+
+// RPCTypeTemplateData is the data available to request and response
+// type name templates.
+type RPCTypeTemplateData struct {
+	// The name of the service, for example FooService.
+	Service string
+	// The name of the RPC, for example GetBar.
+	RPC string
+}
+
+// RPCTypeName returns the name of a request or response type given
+// its template, a service name, and an RPC name.
+func RPCTypeName(templateText string, serviceName string, rpcName string) (string, error) {
+	tmpl, err := template.New("").Parse(templateText)
+	if err != nil {
+		return "", err
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := tmpl.Execute(buffer, &RPCTypeTemplateData{
+		Service: serviceName,
+		RPC:     rpcName,
+	}); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// FileOptionIsString returns true if the file option with the given name
+// has a string value.
+func FileOptionIsString(name string) bool {
+	return name != "java_multiple_files"
+}
+
+// ImportLess returns true if the import with the first kind and filename
+// comes before the import with the second kind and filename in the canonical
+// order. Regular imports come first, followed by public imports, followed by
+// weak imports, each sorted by filename.
+func ImportLess(kind1 string, filename1 string, kind2 string, filename2 string) bool {
+	if rank1, rank2 := importKindRank(kind1), importKindRank(kind2); rank1 != rank2 {
+		return rank1 < rank2
+	}
+	return filename1 < filename2
+}
+
+// FileOptionLess returns true if the file option with the first name comes
+// before the file option with the second name in the canonical order.
+// Built-in options come first, followed by custom options, each sorted by name.
+func FileOptionLess(name1 string, name2 string) bool {
+	if custom1, custom2 := strings.HasPrefix(name1, "("), strings.HasPrefix(name2, "("); custom1 != custom2 {
+		return custom2
+	}
+	return name1 < name2
+}
+
+const (
+	// EnumValuePrefixNested says that enum values are prefixed with the
+	// UPPER_SNAKE_CASE names of the messages the enum is nested in and
+	// the enum, for example FOO_BAR_ for enum Bar nested in message Foo.
+	EnumValuePrefixNested = "nested"
+	// EnumValuePrefixEnum says that enum values are prefixed with the
+	// UPPER_SNAKE_CASE name of the enum only, for example BAR_.
+	EnumValuePrefixEnum = "enum"
+	// EnumValuePrefixNone says that enum values do not need a prefix.
+	EnumValuePrefixNone = "none"
+
+	// DefaultEnumValuePrefix is the default enum value prefix.
+	DefaultEnumValuePrefix = EnumValuePrefixNested
+	// DefaultEnumZeroValueSuffix is the default suffix of enum zero values.
+	DefaultEnumZeroValueSuffix = "INVALID"
+)
+
+// EnumValuePrefixes are the valid enum value prefixes.
+var EnumValuePrefixes = []string{
+	EnumValuePrefixNested,
+	EnumValuePrefixEnum,
+	EnumValuePrefixNone,
+}
+
+// EnumValuePrefix returns the prefix that the values of the enum are expected
+// to have for the given enum value prefix, given the names of the messages
+// the enum is nested in from outermost to innermost and the enum name.
+//
+// If enumValuePrefix is EnumValuePrefixNone, this will return an empty string.
+func EnumValuePrefix(enumValuePrefix string, messageNames []string, enumName string) string {
+	switch enumValuePrefix {
+	case EnumValuePrefixNone:
+		return ""
+	case EnumValuePrefixEnum:
+		return strs.ToUpperSnakeCase(enumName) + "_"
+	default:
+		names := make([]string, 0, len(messageNames)+1)
+		for _, messageName := range messageNames {
+			names = append(names, strs.ToUpperSnakeCase(messageName))
+		}
+		return strings.Join(append(names, strs.ToUpperSnakeCase(enumName)), "_") + "_"
+	}
+}
+
+// EnumZeroValueName returns the name that the zero value of the enum is
+// expected to have for the given enum value prefix and zero value suffix,
+// given the names of the messages the enum is nested in from outermost
+// to innermost and the enum name.
+//
+// As enum values are in the same scope as the enum, the zero value is
+// prefixed with the enum name for EnumValuePrefixNone so that it does not
+// conflict with the zero values of other enums.
+func EnumZeroValueName(enumValuePrefix string, enumZeroValueSuffix string, messageNames []string, enumName string) string {
+	if enumValuePrefix == EnumValuePrefixNone {
+		enumValuePrefix = EnumValuePrefixEnum
+	}
+	return EnumValuePrefix(enumValuePrefix, messageNames, enumName) + enumZeroValueSuffix
+}
+
+func importKindRank(kind string) int {
+	switch kind {
+	case "public":
+		return 1
+	case "weak":
+		return 2
+	default:
+		return 0
+	}
+}
+
+func newFileOptionTemplateData(packageName string, filename string) *FileOptionTemplateData {
+	split := strings.Split(packageName, ".")
+	upperCamelCaseSplit := make([]string, len(split))
+	var initials string
+	for i, e := range split {
+		upperCamelCaseSplit[i] = strs.ToUpperCamelCase(e)
+		if e != "" {
+			initials += strings.ToUpper(e[:1])
+		}
+	}
+	filename = filepath.Base(filename)
+	filename = strings.TrimSuffix(filename, filepath.Ext(filename))
+	return &FileOptionTemplateData{
+		Package:               packageName,
+		PackageLast:           split[len(split)-1],
+		PackagePath:           strings.Join(split, "/"),
+		PackageUpperCamelCase: strings.Join(upperCamelCaseSplit, "."),
+		PackageInitials:       initials,
+		FileUpperCamelCase:    strs.ToUpperCamelCase(filename),
+	}
+}
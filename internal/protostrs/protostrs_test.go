@@ -50,3 +50,101 @@ func TestJavaPackage(t *testing.T) {
 	assert.Equal(t, "com.foo", JavaPackage("foo"))
 	assert.Equal(t, "com.foo.bar", JavaPackage("foo.bar"))
 }
+
+func TestPackageVersion(t *testing.T) {
+	assert.Equal(t, "", PackageVersion(""))
+	assert.Equal(t, "", PackageVersion("foo.bar"))
+	assert.Equal(t, "v1", PackageVersion("foo.v1"))
+	assert.Equal(t, "v2alpha1", PackageVersion("foo.v2alpha1"))
+	assert.Equal(t, "v1beta1", PackageVersion("foo.bar.v1beta1"))
+	assert.Equal(t, "", PackageVersion("foo.v0"))
+	assert.Equal(t, "", PackageVersion("foo.v1beta"))
+	assert.Equal(t, "", PackageVersion("foo.v1.bar"))
+	assert.True(t, IsStablePackageVersion("v12"))
+	assert.False(t, IsStablePackageVersion("v1beta1"))
+	assert.False(t, IsStablePackageVersion("v1alpha2"))
+	assert.False(t, IsStablePackageVersion("foo"))
+}
+
+func TestImportLess(t *testing.T) {
+	assert.True(t, ImportLess("", "b.proto", "public", "a.proto"))
+	assert.True(t, ImportLess("public", "b.proto", "weak", "a.proto"))
+	assert.True(t, ImportLess("", "a.proto", "", "b.proto"))
+	assert.False(t, ImportLess("weak", "a.proto", "", "b.proto"))
+	assert.False(t, ImportLess("", "a.proto", "", "a.proto"))
+}
+
+func TestFileOptionLess(t *testing.T) {
+	assert.True(t, FileOptionLess("java_package", "(foo.bar)"))
+	assert.True(t, FileOptionLess("go_package", "java_package"))
+	assert.True(t, FileOptionLess("(bar.baz)", "(foo.bar)"))
+	assert.False(t, FileOptionLess("(foo.bar)", "go_package"))
+	assert.False(t, FileOptionLess("go_package", "go_package"))
+}
+
+func TestEnumValuePrefix(t *testing.T) {
+	assert.Equal(t, "FOO_BAR_BAZ_", EnumValuePrefix(EnumValuePrefixNested, []string{"Foo", "Bar"}, "Baz"))
+	assert.Equal(t, "BAZ_", EnumValuePrefix(EnumValuePrefixNested, nil, "Baz"))
+	assert.Equal(t, "BAZ_", EnumValuePrefix(EnumValuePrefixEnum, []string{"Foo", "Bar"}, "Baz"))
+	assert.Equal(t, "", EnumValuePrefix(EnumValuePrefixNone, []string{"Foo", "Bar"}, "Baz"))
+}
+
+func TestEnumZeroValueName(t *testing.T) {
+	assert.Equal(t, "FOO_BAZ_INVALID", EnumZeroValueName(EnumValuePrefixNested, "INVALID", []string{"Foo"}, "Baz"))
+	assert.Equal(t, "BAZ_UNSPECIFIED", EnumZeroValueName(EnumValuePrefixEnum, "UNSPECIFIED", []string{"Foo"}, "Baz"))
+	assert.Equal(t, "BAZ_NONE", EnumZeroValueName(EnumValuePrefixNone, "NONE", []string{"Foo"}, "Baz"))
+}
+
+func TestFileOptionValue(t *testing.T) {
+	for name, expected := range map[string]string{
+		"go_package":           GoPackage("foo.bar.v1"),
+		"java_multiple_files":  "true",
+		"java_outer_classname": JavaOuterClassname("a/b/file_one.proto"),
+		"java_package":         JavaPackage("foo.bar.v1"),
+	} {
+		value, err := FileOptionValue(DefaultFileOptionTemplates[name], "foo.bar.v1", "a/b/file_one.proto")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value, name)
+	}
+	value, err := FileOptionValue("{{.PackageUpperCamelCase}}", "foo.bar_baz.v1", "a/b/file_one.proto")
+	assert.NoError(t, err)
+	assert.Equal(t, "Foo.BarBaz.V1", value)
+	value, err = FileOptionValue("example.com/{{.PackagePath}}", "foo.bar.v1", "file.proto")
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com/foo/bar/v1", value)
+	value, err = FileOptionValue("{{.PackageInitials}}", "foo.bar_baz.v1", "file.proto")
+	assert.NoError(t, err)
+	assert.Equal(t, "FBV", value)
+	_, err = FileOptionValue("{{.Unknown}}", "foo.v1", "file.proto")
+	assert.Error(t, err)
+	_, err = FileOptionValue("{{.Package", "foo.v1", "file.proto")
+	assert.Error(t, err)
+}
+
+func TestRPCTypeName(t *testing.T) {
+	name, err := RPCTypeName(DefaultRequestNameTemplate, "FooService", "GetBar")
+	assert.NoError(t, err)
+	assert.Equal(t, "GetBarRequest", name)
+	name, err = RPCTypeName("{{.Service}}{{.RPC}}Response", "FooService", "GetBar")
+	assert.NoError(t, err)
+	assert.Equal(t, "FooServiceGetBarResponse", name)
+	_, err = RPCTypeName("{{.Method}}Request", "FooService", "GetBar")
+	assert.Error(t, err)
+}
+
+func TestLanguageFileOptionTemplates(t *testing.T) {
+	assert.Equal(
+		t,
+		map[string]string{
+			"csharp_namespace": "{{.PackageUpperCamelCase}}",
+			"go_package":       DefaultFileOptionTemplates["go_package"],
+		},
+		LanguageFileOptionTemplates([]string{"csharp", "go"}),
+	)
+	for _, language := range Languages {
+		for name, templateText := range LanguageFileOptionTemplates([]string{language}) {
+			assert.NotEmpty(t, templateText, name)
+			assert.Contains(t, FileOptionTemplateNames, name)
+		}
+	}
+}
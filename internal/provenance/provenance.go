@@ -0,0 +1,64 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package provenance stamps generated files with a header that traces
+// them back to the tool versions and the .proto files they were
+// generated from.
+package provenance
+
+import (
+	"time"
+
+	"github.com/uber/prototool/internal/file"
+	"go.uber.org/zap"
+)
+
+// Stamper stamps generated files.
+type Stamper interface {
+	// Stamp adds a header to the files in the plugin output paths and the
+	// Go stubs output path of the gen config of the ProtoSet that were
+	// modified at or after since, which are the files that were just
+	// generated, using the provenance config of the gen config.
+	//
+	// The protoc version is the version the files were generated with,
+	// or empty if not known. Files with extensions that do not have a
+	// known comment syntax are not stamped.
+	//
+	// Returns the paths of the stamped files, or nil if the provenance
+	// config is not enabled.
+	Stamp(protoSet *file.ProtoSet, protocVersion string, since time.Time) ([]string, error)
+}
+
+// StamperOption is an option for a new Stamper.
+type StamperOption func(*stamper)
+
+// StamperWithLogger returns a StamperOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func StamperWithLogger(logger *zap.Logger) StamperOption {
+	return func(stamper *stamper) {
+		stamper.logger = logger
+	}
+}
+
+// NewStamper returns a new Stamper.
+func NewStamper(options ...StamperOption) Stamper {
+	return newStamper(options...)
+}
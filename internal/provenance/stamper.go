@@ -0,0 +1,329 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package provenance
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/vars"
+	"go.uber.org/zap"
+)
+
+const goStubsName = "go_stubs"
+
+var (
+	extToCommentPrefix = map[string]string{
+		".c":     "//",
+		".cc":    "//",
+		".cpp":   "//",
+		".cs":    "//",
+		".cxx":   "//",
+		".dart":  "//",
+		".go":    "//",
+		".h":     "//",
+		".hpp":   "//",
+		".java":  "//",
+		".js":    "//",
+		".kt":    "//",
+		".m":     "//",
+		".mjs":   "//",
+		".mm":    "//",
+		".py":    "#",
+		".pyi":   "#",
+		".rb":    "#",
+		".rs":    "//",
+		".scala": "//",
+		".swift": "//",
+		".ts":    "//",
+		".yaml":  "#",
+		".yml":   "#",
+	}
+
+	// matches a Python or Ruby encoding declaration, which has to be on
+	// the first or second line
+	encodingRegexp = regexp.MustCompile(`^#.*coding[:=]`)
+)
+
+type stamper struct {
+	logger *zap.Logger
+}
+
+func newStamper(options ...StamperOption) *stamper {
+	stamper := &stamper{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(stamper)
+	}
+	return stamper
+}
+
+func (s *stamper) Stamp(protoSet *file.ProtoSet, protocVersion string, since time.Time) ([]string, error) {
+	config := protoSet.Config
+	if !config.Gen.Provenance.Enabled {
+		return nil, nil
+	}
+	timestamp, err := getTimestamp(config.Gen.Provenance.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := getSources(protoSet)
+	if err != nil {
+		return nil, err
+	}
+	outputPathToNames := make(map[string][]string)
+	for _, genPlugin := range config.Gen.Plugins {
+		outputPathToNames[genPlugin.OutputPath.AbsPath] = append(outputPathToNames[genPlugin.OutputPath.AbsPath], genPlugin.Name)
+	}
+	if config.Gen.GoStubsOutputPath != "" {
+		outputPathToNames[config.Gen.GoStubsOutputPath] = append(outputPathToNames[config.Gen.GoStubsOutputPath], goStubsName)
+	}
+	// file systems may only store modification times in seconds
+	since = since.Truncate(time.Second)
+	// a file is attributed to the deepest output path that contains it,
+	// as output paths can be nested
+	filePathToOutputPath := make(map[string]string)
+	for outputPath := range outputPathToNames {
+		if err := filepath.Walk(outputPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if !fileInfo.Mode().IsRegular() || fileInfo.ModTime().Before(since) {
+				return nil
+			}
+			if _, ok := extToCommentPrefix[filepath.Ext(filePath)]; !ok {
+				return nil
+			}
+			if existing, ok := filePathToOutputPath[filePath]; !ok || len(outputPath) > len(existing) {
+				filePathToOutputPath[filePath] = outputPath
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	filePaths := make([]string, 0, len(filePathToOutputPath))
+	for filePath := range filePathToOutputPath {
+		filePaths = append(filePaths, filePath)
+	}
+	sort.Strings(filePaths)
+	for _, filePath := range filePaths {
+		outputPath := filePathToOutputPath[filePath]
+		rel, err := filepath.Rel(outputPath, filePath)
+		if err != nil {
+			return nil, err
+		}
+		header := getHeader(
+			extToCommentPrefix[filepath.Ext(filePath)],
+			config.Gen.Provenance.License,
+			protocVersion,
+			outputPathToNames[outputPath],
+			getSourceLine(sources, filepath.ToSlash(rel)),
+			timestamp,
+		)
+		s.logger.Debug("stamping generated file", zap.String("path", filePath))
+		if err := stampFile(filePath, header); err != nil {
+			return nil, err
+		}
+	}
+	return filePaths, nil
+}
+
+type source struct {
+	// the path relative to the config directory, or the display path if
+	// the file is not in the config directory, with forward slashes
+	path string
+	// the file name without the .proto extension
+	stem string
+	hash string
+}
+
+func getSources(protoSet *file.ProtoSet) ([]*source, error) {
+	var sources []*source
+	for _, protoFiles := range protoSet.DirPathToFiles {
+		for _, protoFile := range protoFiles {
+			data, err := ioutil.ReadFile(protoFile.Path)
+			if err != nil {
+				return nil, err
+			}
+			sum := sha256.Sum256(data)
+			sourcePath := filepath.ToSlash(protoFile.DisplayPath)
+			if protoSet.Config.DirPath != "" {
+				if rel, err := filepath.Rel(protoSet.Config.DirPath, protoFile.Path); err == nil && !strings.HasPrefix(rel, "..") {
+					sourcePath = filepath.ToSlash(rel)
+				}
+			}
+			sources = append(sources, &source{
+				path: sourcePath,
+				stem: strings.TrimSuffix(path.Base(sourcePath), ".proto"),
+				hash: hex.EncodeToString(sum[:]),
+			})
+		}
+	}
+	sort.Slice(sources, func(i int, j int) bool { return sources[i].path < sources[j].path })
+	return sources, nil
+}
+
+// getSourceLine returns the line of the header that says what the file at
+// the path relative to its output path was generated from.
+//
+// Plugins name their files after the .proto files, for example foo.pb.go
+// or foo_grpc.pb.go for foo.proto, so a file is traced back to the .proto
+// file with the longest name that the file name starts with, preferring
+// the ones in a directory that the directory of the file ends with. If no
+// single .proto file is found, the hash of all of the .proto files is used.
+func getSourceLine(sources []*source, rel string) string {
+	base := path.Base(rel)
+	dir := path.Dir(rel)
+	var candidates []*source
+	for _, source := range sources {
+		if !strings.HasPrefix(base, source.stem+".") && !strings.HasPrefix(base, source.stem+"_") {
+			continue
+		}
+		if len(candidates) > 0 && len(source.stem) < len(candidates[0].stem) {
+			continue
+		}
+		if len(candidates) > 0 && len(source.stem) > len(candidates[0].stem) {
+			candidates = nil
+		}
+		candidates = append(candidates, source)
+	}
+	var dirCandidates []*source
+	for _, candidate := range candidates {
+		sourceDir := path.Dir(candidate.path)
+		if sourceDir == dir || strings.HasSuffix(dir, "/"+sourceDir) {
+			dirCandidates = append(dirCandidates, candidate)
+		}
+	}
+	switch {
+	case len(dirCandidates) == 1:
+		return fmt.Sprintf("Source: %s (sha256:%s)", dirCandidates[0].path, dirCandidates[0].hash)
+	case len(candidates) == 1:
+		return fmt.Sprintf("Source: %s (sha256:%s)", candidates[0].path, candidates[0].hash)
+	}
+	// the same format as sha256sum so the hash can be checked by hand
+	buffer := bytes.NewBuffer(nil)
+	for _, source := range sources {
+		_, _ = fmt.Fprintf(buffer, "%s  %s\n", source.hash, source.path)
+	}
+	sum := sha256.Sum256(buffer.Bytes())
+	return fmt.Sprintf("Sources: %d files (sha256:%s)", len(sources), hex.EncodeToString(sum[:]))
+}
+
+func getHeader(commentPrefix string, license string, protocVersion string, names []string, sourceLine string, timestamp string) []byte {
+	var lines []string
+	if license != "" {
+		lines = append(lines, strings.Split(license, "\n")...)
+		lines = append(lines, "")
+	}
+	tools := []string{"prototool " + vars.Version}
+	if protocVersion != "" {
+		tools = append(tools, "protoc "+protocVersion)
+	} else {
+		tools = append(tools, "protoc")
+	}
+	if len(names) == 1 {
+		tools = append(tools, "plugin "+names[0])
+	} else {
+		tools = append(tools, "plugins "+strings.Join(names, ", "))
+	}
+	lines = append(lines, "Generated by "+strings.Join(tools, ", ")+".", sourceLine)
+	if timestamp != "" {
+		lines = append(lines, "Timestamp: "+timestamp)
+	}
+	buffer := bytes.NewBuffer(nil)
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			buffer.WriteString(commentPrefix + "\n")
+		} else {
+			buffer.WriteString(commentPrefix + " " + line + "\n")
+		}
+	}
+	buffer.WriteString("\n")
+	return buffer.Bytes()
+}
+
+func getTimestamp(policy string) (string, error) {
+	switch policy {
+	case settings.ProvenanceTimestampNow:
+		return time.Now().UTC().Format(time.RFC3339), nil
+	case settings.ProvenanceTimestampSourceDateEpoch:
+		value := os.Getenv("SOURCE_DATE_EPOCH")
+		if value == "" {
+			return "", fmt.Errorf("gen provenance timestamp is %s but SOURCE_DATE_EPOCH is not set", policy)
+		}
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %v", value, err)
+		}
+		return time.Unix(seconds, 0).UTC().Format(time.RFC3339), nil
+	default:
+		return "", nil
+	}
+}
+
+// stampFile inserts the header at the top of the file, after a shebang
+// line and an encoding declaration if present.
+func stampFile(filePath string, header []byte) error {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	offset := 0
+	for i := 0; i < 2 && offset < len(data); i++ {
+		end := bytes.IndexByte(data[offset:], '\n')
+		if end < 0 {
+			break
+		}
+		line := data[offset : offset+end]
+		if !(i == 0 && bytes.HasPrefix(line, []byte("#!"))) && !encodingRegexp.Match(line) {
+			break
+		}
+		offset += end + 1
+	}
+	stamped := make([]byte, 0, len(data)+len(header))
+	stamped = append(stamped, data[:offset]...)
+	stamped = append(stamped, header...)
+	stamped = append(stamped, data[offset:]...)
+	return ioutil.WriteFile(filePath, stamped, fileInfo.Mode().Perm())
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package provenance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/vars"
+)
+
+func TestStamp(t *testing.T) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+
+	writeFile := func(rel string, data string) string {
+		filePath := filepath.Join(tmpDirPath, filepath.FromSlash(rel))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(data), 0644))
+		return filePath
+	}
+	readFile := func(rel string) string {
+		data, err := ioutil.ReadFile(filepath.Join(tmpDirPath, filepath.FromSlash(rel)))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	fooData := "syntax = \"proto3\";\npackage foo.v1;\n"
+	fooPath := writeFile("proto/foo/v1/foo.proto", fooData)
+	barPath := writeFile("proto/foo/v1/bar.proto", "syntax = \"proto3\";\npackage foo.v1;\n")
+	oldPath := writeFile("gen/go/foo/v1/old.pb.go", "package foov1\n")
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(oldPath, old, old))
+	since := time.Now().Add(-time.Minute)
+	writeFile("gen/go/foo/v1/foo.pb.go", "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage foov1\n")
+	writeFile("gen/go/foo/v1/foo_grpc.pb.go", "package foov1\n")
+	writeFile("gen/go/foo/v1/foo.pb.json", "{}\n")
+	writeFile("gen/python/foo/v1/foo_pb2.py", "#!/usr/bin/env python\n# -*- coding: utf-8 -*-\nimport sys\n")
+	writeFile("gen/java/foo/v1/FooProto.java", "package foo.v1;\n")
+
+	protoSet := &file.ProtoSet{
+		DirPathToFiles: map[string][]*file.ProtoFile{
+			filepath.Join(tmpDirPath, "proto", "foo", "v1"): {
+				{
+					Path:        barPath,
+					DisplayPath: "proto/foo/v1/bar.proto",
+				},
+				{
+					Path:        fooPath,
+					DisplayPath: "proto/foo/v1/foo.proto",
+				},
+			},
+		},
+		Config: settings.Config{
+			DirPath: filepath.Join(tmpDirPath, "proto"),
+			Gen: settings.GenConfig{
+				Plugins: []settings.GenPlugin{
+					{
+						Name:       "go",
+						OutputPath: settings.OutputPath{AbsPath: filepath.Join(tmpDirPath, "gen", "go")},
+					},
+					{
+						Name:       "grpc",
+						OutputPath: settings.OutputPath{AbsPath: filepath.Join(tmpDirPath, "gen", "go")},
+					},
+					{
+						Name:       "python",
+						OutputPath: settings.OutputPath{AbsPath: filepath.Join(tmpDirPath, "gen", "python")},
+					},
+					{
+						Name:       "java",
+						OutputPath: settings.OutputPath{AbsPath: filepath.Join(tmpDirPath, "gen", "java")},
+					},
+				},
+			},
+		},
+	}
+
+	filePaths, err := NewStamper().Stamp(protoSet, "3.15.8", since)
+	require.NoError(t, err)
+	assert.Nil(t, filePaths)
+
+	protoSet.Config.Gen.Provenance = settings.GenProvenanceConfig{
+		Enabled: true,
+		License: "Copyright (c) Foo Inc.\nAll rights reserved.",
+	}
+	filePaths, err = NewStamper().Stamp(protoSet, "3.15.8", since)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			filepath.Join(tmpDirPath, "gen", "go", "foo", "v1", "foo.pb.go"),
+			filepath.Join(tmpDirPath, "gen", "go", "foo", "v1", "foo_grpc.pb.go"),
+			filepath.Join(tmpDirPath, "gen", "java", "foo", "v1", "FooProto.java"),
+			filepath.Join(tmpDirPath, "gen", "python", "foo", "v1", "foo_pb2.py"),
+		},
+		filePaths,
+	)
+
+	fooSum := sha256.Sum256([]byte(fooData))
+	fooHash := hex.EncodeToString(fooSum[:])
+	assert.Equal(
+		t,
+		"// Copyright (c) Foo Inc.\n"+
+			"// All rights reserved.\n"+
+			"//\n"+
+			"// Generated by prototool "+vars.Version+", protoc 3.15.8, plugins go, grpc.\n"+
+			"// Source: foo/v1/foo.proto (sha256:"+fooHash+")\n"+
+			"\n"+
+			"// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage foov1\n",
+		readFile("gen/go/foo/v1/foo.pb.go"),
+	)
+	assert.Contains(t, readFile("gen/go/foo/v1/foo_grpc.pb.go"), "// Source: foo/v1/foo.proto (sha256:"+fooHash+")\n")
+	assert.Contains(t, readFile("gen/java/foo/v1/FooProto.java"), "// Sources: 2 files (sha256:")
+	assert.Equal(
+		t,
+		"#!/usr/bin/env python\n"+
+			"# -*- coding: utf-8 -*-\n"+
+			"# Copyright (c) Foo Inc.\n"+
+			"# All rights reserved.\n"+
+			"#\n"+
+			"# Generated by prototool "+vars.Version+", protoc 3.15.8, plugin python.\n"+
+			"# Source: foo/v1/foo.proto (sha256:"+fooHash+")\n"+
+			"\n"+
+			"import sys\n",
+		readFile("gen/python/foo/v1/foo_pb2.py"),
+	)
+	assert.Equal(t, "{}\n", readFile("gen/go/foo/v1/foo.pb.json"))
+	assert.Equal(t, "package foov1\n", readFile("gen/go/foo/v1/old.pb.go"))
+}
+
+func TestGetTimestamp(t *testing.T) {
+	timestamp, err := getTimestamp("")
+	require.NoError(t, err)
+	assert.Empty(t, timestamp)
+	timestamp, err = getTimestamp(settings.ProvenanceTimestampNone)
+	require.NoError(t, err)
+	assert.Empty(t, timestamp)
+	timestamp, err = getTimestamp(settings.ProvenanceTimestampNow)
+	require.NoError(t, err)
+	assert.NotEmpty(t, timestamp)
+
+	value, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	defer func() {
+		if ok {
+			_ = os.Setenv("SOURCE_DATE_EPOCH", value)
+		} else {
+			_ = os.Unsetenv("SOURCE_DATE_EPOCH")
+		}
+	}()
+	require.NoError(t, os.Setenv("SOURCE_DATE_EPOCH", "1700000000"))
+	timestamp, err = getTimestamp(settings.ProvenanceTimestampSourceDateEpoch)
+	require.NoError(t, err)
+	assert.Equal(t, "2023-11-14T22:13:20Z", timestamp)
+	require.NoError(t, os.Unsetenv("SOURCE_DATE_EPOCH"))
+	_, err = getTimestamp(settings.ProvenanceTimestampSourceDateEpoch)
+	assert.Error(t, err)
+}
@@ -23,6 +23,8 @@ package reflect
 import (
 	"fmt"
 
+	"github.com/ghodss/yaml"
+	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/dynamic"
@@ -32,14 +34,16 @@ import (
 )
 
 type handler struct {
-	logger *zap.Logger
+	logger        *zap.Logger
+	jsonMarshaler *jsonpb.Marshaler
 
 	getter extract.Getter
 }
 
 func newHandler(options ...HandlerOption) *handler {
 	handler := &handler{
-		logger: zap.NewNop(),
+		logger:        zap.NewNop(),
+		jsonMarshaler: &jsonpb.Marshaler{},
 	}
 	for _, option := range options {
 		option(handler)
@@ -59,7 +63,13 @@ func (h *handler) BinaryToJSON(fileDescriptorSets []*descriptor.FileDescriptorSe
 	if err := dynamicMessage.Unmarshal(binaryData); err != nil {
 		return nil, err
 	}
-	return dynamicMessage.MarshalJSON()
+	anyResolver, err := intdesc.NewAnyResolver(fileDescriptorSets...)
+	if err != nil {
+		return nil, err
+	}
+	jsonMarshaler := *h.jsonMarshaler
+	jsonMarshaler.AnyResolver = anyResolver
+	return dynamicMessage.MarshalJSONPB(&jsonMarshaler)
 }
 
 func (h *handler) JSONToBinary(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, jsonData []byte) ([]byte, error) {
@@ -67,12 +77,71 @@ func (h *handler) JSONToBinary(fileDescriptorSets []*descriptor.FileDescriptorSe
 	if err != nil {
 		return nil, err
 	}
-	if err := dynamicMessage.UnmarshalJSON(jsonData); err != nil {
+	if err := h.unmarshalJSON(fileDescriptorSets, dynamicMessage, jsonData); err != nil {
+		return nil, err
+	}
+	return dynamicMessage.Marshal()
+}
+
+func (h *handler) BinaryToText(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, binaryData []byte) ([]byte, error) {
+	dynamicMessage, err := h.getDynamicMessage(fileDescriptorSets, messagePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := dynamicMessage.Unmarshal(binaryData); err != nil {
+		return nil, err
+	}
+	return dynamicMessage.MarshalTextIndent()
+}
+
+func (h *handler) TextToBinary(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, textData []byte) ([]byte, error) {
+	dynamicMessage, err := h.getDynamicMessage(fileDescriptorSets, messagePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := dynamicMessage.UnmarshalText(textData); err != nil {
 		return nil, err
 	}
 	return dynamicMessage.Marshal()
 }
 
+func (h *handler) JSONToText(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, jsonData []byte) ([]byte, error) {
+	dynamicMessage, err := h.getDynamicMessage(fileDescriptorSets, messagePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.unmarshalJSON(fileDescriptorSets, dynamicMessage, jsonData); err != nil {
+		return nil, err
+	}
+	return dynamicMessage.MarshalTextIndent()
+}
+
+// BinaryToYAML goes through JSON so that the YAML field names and
+// value formats match the JSON mapping.
+func (h *handler) BinaryToYAML(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, binaryData []byte) ([]byte, error) {
+	jsonData, err := h.BinaryToJSON(fileDescriptorSets, messagePath, binaryData)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.JSONToYAML(jsonData)
+}
+
+func (h *handler) YAMLToBinary(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, yamlData []byte) ([]byte, error) {
+	jsonData, err := yaml.YAMLToJSON(yamlData)
+	if err != nil {
+		return nil, err
+	}
+	return h.JSONToBinary(fileDescriptorSets, messagePath, jsonData)
+}
+
+func (h *handler) unmarshalJSON(fileDescriptorSets []*descriptor.FileDescriptorSet, dynamicMessage *dynamic.Message, jsonData []byte) error {
+	anyResolver, err := intdesc.NewAnyResolver(fileDescriptorSets...)
+	if err != nil {
+		return err
+	}
+	return dynamicMessage.UnmarshalJSONPB(&jsonpb.Unmarshaler{AnyResolver: anyResolver}, jsonData)
+}
+
 func (h *handler) getDynamicMessage(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string) (*dynamic.Message, error) {
 	message, err := h.getter.GetMessage(fileDescriptorSets, messagePath)
 	if err != nil {
@@ -0,0 +1,210 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reflect
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSource = `syntax = "proto3";
+
+package foo;
+
+import "google/protobuf/any.proto";
+
+enum Color {
+  COLOR_INVALID = 0;
+  COLOR_RED = 1;
+}
+
+message Bar {
+  string bar_value = 1;
+}
+
+message Foo {
+  int64 hello = 1;
+  Color color = 2;
+  repeated string names = 3;
+  Bar bar = 4;
+  google.protobuf.Any any = 5;
+  string empty_value = 6;
+}
+`
+
+const testJSON = `{"hello":100,"color":"COLOR_RED","names":["a","b"],"bar":{"barValue":"baz"}}`
+
+func TestBinaryToText(t *testing.T) {
+	fileDescriptorSets := newTestFileDescriptorSets(t)
+	handler := newHandler()
+	binaryData, err := handler.JSONToBinary(fileDescriptorSets, "foo.Foo", []byte(testJSON))
+	require.NoError(t, err)
+	textData, err := handler.BinaryToText(fileDescriptorSets, "foo.Foo", binaryData)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`hello: 100
+color: COLOR_RED
+names: "a"
+names: "b"
+bar: <
+  bar_value: "baz"
+>`,
+		string(textData),
+	)
+	roundTripBinaryData, err := handler.TextToBinary(fileDescriptorSets, "foo.Foo", textData)
+	require.NoError(t, err)
+	jsonData, err := handler.BinaryToJSON(fileDescriptorSets, "foo.Foo", roundTripBinaryData)
+	require.NoError(t, err)
+	assert.Equal(t, testJSON, string(jsonData))
+	jsonTextData, err := handler.JSONToText(fileDescriptorSets, "foo.Foo", []byte(testJSON))
+	require.NoError(t, err)
+	assert.Equal(t, string(textData), string(jsonTextData))
+}
+
+func TestTextToBinaryError(t *testing.T) {
+	fileDescriptorSets := newTestFileDescriptorSets(t)
+	_, err := newHandler().TextToBinary(fileDescriptorSets, "foo.Foo", []byte(`unknown: 1`))
+	assert.Error(t, err)
+	_, err = newHandler().TextToBinary(fileDescriptorSets, "foo.Baz", []byte(`hello: 1`))
+	assert.Error(t, err)
+}
+
+func TestBinaryToYAML(t *testing.T) {
+	fileDescriptorSets := newTestFileDescriptorSets(t)
+	handler := newHandler()
+	binaryData, err := handler.JSONToBinary(fileDescriptorSets, "foo.Foo", []byte(testJSON))
+	require.NoError(t, err)
+	yamlData, err := handler.BinaryToYAML(fileDescriptorSets, "foo.Foo", binaryData)
+	require.NoError(t, err)
+	// field names and values follow the JSON mapping
+	assert.Equal(
+		t,
+		`bar:
+  barValue: baz
+color: COLOR_RED
+hello: 100
+names:
+- a
+- b
+`,
+		string(yamlData),
+	)
+	roundTripBinaryData, err := handler.YAMLToBinary(fileDescriptorSets, "foo.Foo", yamlData)
+	require.NoError(t, err)
+	jsonData, err := handler.BinaryToJSON(fileDescriptorSets, "foo.Foo", roundTripBinaryData)
+	require.NoError(t, err)
+	assert.Equal(t, testJSON, string(jsonData))
+	// the original field names are accepted as in the JSON mapping
+	binaryData, err = handler.YAMLToBinary(fileDescriptorSets, "foo.Foo", []byte("bar:\n  bar_value: baz\n"))
+	require.NoError(t, err)
+	jsonData, err = handler.BinaryToJSON(fileDescriptorSets, "foo.Foo", binaryData)
+	require.NoError(t, err)
+	assert.Equal(t, `{"bar":{"barValue":"baz"}}`, string(jsonData))
+	_, err = handler.YAMLToBinary(fileDescriptorSets, "foo.Foo", []byte("unknown: 1\n"))
+	assert.Error(t, err)
+}
+
+func TestBinaryToJSONMarshalerOptions(t *testing.T) {
+	fileDescriptorSets := newTestFileDescriptorSets(t)
+	binaryData, err := newHandler().JSONToBinary(fileDescriptorSets, "foo.Foo", []byte(`{"color":"COLOR_RED","names":["a"],"bar":{"barValue":"baz"}}`))
+	require.NoError(t, err)
+	handler := newHandler(
+		HandlerWithJSONMarshaler(
+			&jsonpb.Marshaler{
+				EmitDefaults: true,
+				OrigName:     true,
+				EnumsAsInts:  true,
+				Indent:       "  ",
+			},
+		),
+	)
+	jsonData, err := handler.BinaryToJSON(fileDescriptorSets, "foo.Foo", binaryData)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`{
+  "hello": 0,
+  "color": 1,
+  "names": [
+    "a"
+  ],
+  "bar": {
+    "bar_value": "baz"
+  },
+  "any": null,
+  "empty_value": ""
+}`,
+		string(jsonData),
+	)
+	// the options only apply to output
+	binaryData, err = handler.JSONToBinary(fileDescriptorSets, "foo.Foo", []byte(`{"color":1,"bar":{"bar_value":"baz"}}`))
+	require.NoError(t, err)
+	jsonData, err = newHandler().BinaryToJSON(fileDescriptorSets, "foo.Foo", binaryData)
+	require.NoError(t, err)
+	assert.Equal(t, `{"color":"COLOR_RED","bar":{"barValue":"baz"}}`, string(jsonData))
+}
+
+func TestAny(t *testing.T) {
+	fileDescriptorSets := newTestFileDescriptorSets(t)
+	handler := newHandler()
+	jsonData := `{"any":{"@type":"type.googleapis.com/foo.Bar","barValue":"packed"}}`
+	binaryData, err := handler.JSONToBinary(fileDescriptorSets, "foo.Foo", []byte(jsonData))
+	require.NoError(t, err)
+	roundTripJSONData, err := handler.BinaryToJSON(fileDescriptorSets, "foo.Foo", binaryData)
+	require.NoError(t, err)
+	assert.Equal(t, jsonData, string(roundTripJSONData))
+	yamlData, err := handler.BinaryToYAML(fileDescriptorSets, "foo.Foo", binaryData)
+	require.NoError(t, err)
+	assert.Equal(t, "any:\n  '@type': type.googleapis.com/foo.Bar\n  barValue: packed\n", string(yamlData))
+	// the packed message type must be in the FileDescriptorSets
+	_, err = handler.JSONToBinary(fileDescriptorSets, "foo.Foo", []byte(`{"any":{"@type":"type.googleapis.com/foo.Unknown","barValue":"packed"}}`))
+	assert.Error(t, err)
+}
+
+func newTestFileDescriptorSets(t *testing.T) []*descriptor.FileDescriptorSet {
+	parser := protoparse.Parser{
+		Accessor: func(filename string) (io.ReadCloser, error) {
+			if filename == "foo/foo.proto" {
+				return ioutil.NopCloser(strings.NewReader(testSource)), nil
+			}
+			return nil, fmt.Errorf("unknown file %s", filename)
+		},
+	}
+	fileDescriptors, err := parser.ParseFiles("foo/foo.proto")
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 1)
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	for _, dependency := range fileDescriptors[0].GetDependencies() {
+		fileDescriptorSet.File = append(fileDescriptorSet.File, dependency.AsFileDescriptorProto())
+	}
+	fileDescriptorSet.File = append(fileDescriptorSet.File, fileDescriptors[0].AsFileDescriptorProto())
+	return []*descriptor.FileDescriptorSet{fileDescriptorSet}
+}
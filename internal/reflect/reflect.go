@@ -21,6 +21,7 @@
 package reflect
 
 import (
+	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"go.uber.org/zap"
 )
@@ -29,6 +30,11 @@ import (
 type Handler interface {
 	BinaryToJSON(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, binaryData []byte) ([]byte, error)
 	JSONToBinary(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, jsonData []byte) ([]byte, error)
+	BinaryToText(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, binaryData []byte) ([]byte, error)
+	TextToBinary(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, textData []byte) ([]byte, error)
+	JSONToText(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, jsonData []byte) ([]byte, error)
+	BinaryToYAML(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, binaryData []byte) ([]byte, error)
+	YAMLToBinary(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, yamlData []byte) ([]byte, error)
 }
 
 // HandlerOption is an option for a new Handler.
@@ -43,6 +49,16 @@ func HandlerWithLogger(logger *zap.Logger) HandlerOption {
 	}
 }
 
+// HandlerWithJSONMarshaler returns a HandlerOption that uses the given
+// marshaler for JSON output.
+//
+// The default is to use a marshaler with no options set.
+func HandlerWithJSONMarshaler(jsonMarshaler *jsonpb.Marshaler) HandlerOption {
+	return func(handler *handler) {
+		handler.jsonMarshaler = jsonMarshaler
+	}
+}
+
 // NewHandler returns a new Handler.
 func NewHandler(options ...HandlerOption) Handler {
 	return newHandler(options...)
@@ -0,0 +1,90 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package scenario
+
+import (
+	"fmt"
+
+	"github.com/ghodss/yaml"
+	"github.com/uber/prototool/internal/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// AppendRecording appends a step for the recording to the YAML scenario
+// data and returns the new scenario data.
+//
+// The step expects the recorded responses and status code, so that running
+// the scenario replays the recorded calls and checks that the results
+// are the same. The scenario data can be empty, in which case a new
+// scenario is returned. Comments in the scenario data are not kept.
+func AppendRecording(scenarioData []byte, recording *grpc.Recording) ([]byte, error) {
+	f := &file{}
+	if err := yaml.Unmarshal(scenarioData, f, yaml.DisallowUnknownFields); err != nil {
+		return nil, fmt.Errorf("could not parse scenario file: %v", err)
+	}
+	step := &step{
+		Method: recording.Method,
+		Expect: &expect{
+			Responses: recording.Responses,
+		},
+	}
+	if len(recording.Requests) == 1 {
+		step.Data = recording.Requests[0]
+	} else {
+		step.Requests = recording.Requests
+	}
+	if recording.Code != codes.OK {
+		step.Expect.Code = recordingCodeString(recording.Code)
+	}
+	f.Steps = append(f.Steps, step)
+	return yaml.Marshal(f)
+}
+
+// codeNames are the names of the codes in google/rpc/code.proto,
+// indexed by code.
+var codeNames = []string{
+	"OK",
+	"CANCELLED",
+	"UNKNOWN",
+	"INVALID_ARGUMENT",
+	"DEADLINE_EXCEEDED",
+	"NOT_FOUND",
+	"ALREADY_EXISTS",
+	"PERMISSION_DENIED",
+	"RESOURCE_EXHAUSTED",
+	"FAILED_PRECONDITION",
+	"ABORTED",
+	"OUT_OF_RANGE",
+	"UNIMPLEMENTED",
+	"INTERNAL",
+	"UNAVAILABLE",
+	"DATA_LOSS",
+	"UNAUTHENTICATED",
+}
+
+// recordingCodeString returns the name of the code in google/rpc/code.proto,
+// such as NOT_FOUND.
+func recordingCodeString(code codes.Code) string {
+	if int(code) < len(codeNames) {
+		return codeNames[code]
+	}
+	return code.String()
+}
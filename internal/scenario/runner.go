@@ -0,0 +1,414 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/uber/prototool/internal/grpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	variableNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	variableRefRegexp  = regexp.MustCompile(`\$\{([^}]*)\}`)
+)
+
+type file struct {
+	Address  string            `json:"address,omitempty"`
+	Vars     map[string]string `json:"vars,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Setup    []*step           `json:"setup,omitempty"`
+	Steps    []*step           `json:"steps,omitempty"`
+	Teardown []*step           `json:"teardown,omitempty"`
+}
+
+type step struct {
+	Name     string            `json:"name,omitempty"`
+	Method   string            `json:"method,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Data     json.RawMessage   `json:"data,omitempty"`
+	Requests []json.RawMessage `json:"requests,omitempty"`
+	Capture  map[string]string `json:"capture,omitempty"`
+	Expect   *expect           `json:"expect,omitempty"`
+}
+
+type expect struct {
+	Code      string                     `json:"code,omitempty"`
+	Responses []json.RawMessage          `json:"responses,omitempty"`
+	Fields    map[string]json.RawMessage `json:"fields,omitempty"`
+}
+
+type runner struct {
+	logger         *zap.Logger
+	handlerOptions []grpc.HandlerOption
+}
+
+func newRunner(options ...RunnerOption) *runner {
+	runner := &runner{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(runner)
+	}
+	return runner
+}
+
+func (r *runner) Run(fileDescriptorSets []*descriptor.FileDescriptorSet, scenarioData []byte, address string, output io.Writer) (int, error) {
+	f, err := parseFile(scenarioData)
+	if err != nil {
+		return 0, err
+	}
+	if address == "" {
+		address = f.Address
+	}
+	if address == "" {
+		return 0, errors.New("address must be set in the scenario file or as a flag")
+	}
+	vars := make(map[string]string, len(f.Vars))
+	for name, value := range f.Vars {
+		vars[name] = value
+	}
+	stepRunner := &stepRunner{
+		runner:             r,
+		fileDescriptorSets: fileDescriptorSets,
+		address:            address,
+		headers:            f.Headers,
+		vars:               vars,
+		output:             output,
+	}
+	setupFailed := stepRunner.runSteps("setup", f.Setup, false)
+	stepRunner.runSteps("steps", f.Steps, setupFailed)
+	stepRunner.runSteps("teardown", f.Teardown, false)
+	if _, err := fmt.Fprintf(output, "%d passed, %d failed, %d skipped\n", stepRunner.numPassed, stepRunner.numFailed, stepRunner.numSkipped); err != nil {
+		return 0, err
+	}
+	return stepRunner.numFailed, stepRunner.writeErr
+}
+
+// stepRunner runs the steps of a single scenario.
+type stepRunner struct {
+	runner             *runner
+	fileDescriptorSets []*descriptor.FileDescriptorSet
+	address            string
+	headers            map[string]string
+	// vars are updated as variables are captured
+	vars       map[string]string
+	output     io.Writer
+	numPassed  int
+	numFailed  int
+	numSkipped int
+	// the first error writing to the output, if any
+	writeErr error
+}
+
+// runSteps runs the steps, or skips them if skip is true,
+// and returns true if any step failed.
+func (s *stepRunner) runSteps(phase string, steps []*step, skip bool) bool {
+	failed := false
+	for i, step := range steps {
+		name := fmt.Sprintf("%s: %s", phase, step.getName(i))
+		if skip || (failed && phase == "setup") {
+			s.numSkipped++
+			s.println("SKIP " + name)
+			continue
+		}
+		if err := s.runStep(step); err != nil {
+			failed = true
+			s.numFailed++
+			s.println("FAIL " + name)
+			for _, line := range strings.Split(err.Error(), "\n") {
+				s.println("    " + line)
+			}
+			continue
+		}
+		s.numPassed++
+		s.println("PASS " + name)
+	}
+	return failed
+}
+
+func (s *stepRunner) runStep(step *step) error {
+	input, err := step.getInput(s.vars)
+	if err != nil {
+		return err
+	}
+	expectations, err := step.getExpectations(s.vars)
+	if err != nil {
+		return err
+	}
+	handlerOptions := append([]grpc.HandlerOption{}, s.runner.handlerOptions...)
+	handlerOptions = append(handlerOptions, grpc.HandlerWithOutputFormat(grpc.OutputFormatJSON))
+	headers := make(map[string]string, len(s.headers)+len(step.Headers))
+	for key, value := range s.headers {
+		headers[key] = value
+	}
+	for key, value := range step.Headers {
+		headers[key] = value
+	}
+	for _, key := range sortedKeys(headers) {
+		value, err := substitute(headers[key], s.vars, nil)
+		if err != nil {
+			return err
+		}
+		handlerOptions = append(handlerOptions, grpc.HandlerWithHeader(key, value))
+	}
+	if expectations != nil {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithExpectations(expectations))
+	}
+	s.runner.logger.Debug("running step", zap.String("method", step.Method), zap.String("input", input))
+	buffer := bytes.NewBuffer(nil)
+	if err := grpc.NewHandler(handlerOptions...).Invoke(s.fileDescriptorSets, s.address, step.Method, strings.NewReader(input), buffer); err != nil {
+		return err
+	}
+	if len(step.Capture) == 0 {
+		return nil
+	}
+	responses, err := grpc.ParseJSONResponses(buffer)
+	if err != nil {
+		return err
+	}
+	if len(responses) == 0 {
+		return errors.New("no responses to capture variables from")
+	}
+	for _, name := range sortedKeys(step.Capture) {
+		path := step.Capture[name]
+		value, ok, err := grpc.GetJSONPathValue(responses[0], path)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("could not capture %s as %s is not set in the first response", name, path)
+		}
+		s.vars[name] = jsonValueString(value)
+	}
+	return nil
+}
+
+func (s *stepRunner) println(line string) {
+	if s.writeErr != nil {
+		return
+	}
+	_, s.writeErr = fmt.Fprintln(s.output, line)
+}
+
+func (s *step) getName(index int) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	if s.Method == "" {
+		return strconv.Itoa(index + 1)
+	}
+	return fmt.Sprintf("%d %s", index+1, s.Method)
+}
+
+// getInput returns the requests as JSON, one request per line.
+func (s *step) getInput(vars map[string]string) (string, error) {
+	requests := s.Requests
+	if len(s.Data) > 0 {
+		requests = []json.RawMessage{s.Data}
+	}
+	if len(requests) == 0 {
+		return "{}", nil
+	}
+	lines := make([]string, len(requests))
+	for i, request := range requests {
+		line, err := substitute(string(request), vars, jsonEscape)
+		if err != nil {
+			return "", err
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// getExpectations returns the expectations of the step, or nil if there are none.
+func (s *step) getExpectations(vars map[string]string) (*grpc.Expectations, error) {
+	if s.Expect == nil {
+		return nil, nil
+	}
+	expectations := &grpc.Expectations{}
+	if s.Expect.Code != "" {
+		code, err := parseCode(s.Expect.Code)
+		if err != nil {
+			return nil, err
+		}
+		expectations.Code = &code
+	}
+	if s.Expect.Responses != nil {
+		var responses []string
+		for _, response := range s.Expect.Responses {
+			substituted, err := substitute(string(response), vars, jsonEscape)
+			if err != nil {
+				return nil, err
+			}
+			responses = append(responses, substituted)
+		}
+		jsonResponses, err := grpc.ParseJSONResponses(strings.NewReader(strings.Join(responses, "\n")))
+		if err != nil {
+			return nil, err
+		}
+		expectations.JSONResponses = jsonResponses
+	}
+	for _, path := range sortedKeys(s.Expect.Fields) {
+		value, err := substitute(string(s.Expect.Fields[path]), vars, jsonEscape)
+		if err != nil {
+			return nil, err
+		}
+		fieldExpectation, err := grpc.ParseFieldExpectation(path + "=" + value)
+		if err != nil {
+			return nil, err
+		}
+		expectations.Fields = append(expectations.Fields, fieldExpectation)
+	}
+	return expectations, nil
+}
+
+func parseFile(scenarioData []byte) (*file, error) {
+	f := &file{}
+	if err := yaml.Unmarshal(scenarioData, f, yaml.DisallowUnknownFields); err != nil {
+		return nil, fmt.Errorf("could not parse scenario file: %v", err)
+	}
+	for name := range f.Vars {
+		if !variableNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("invalid variable name %q", name)
+		}
+	}
+	if len(f.Steps) == 0 {
+		return nil, errors.New("no steps in scenario file")
+	}
+	for _, steps := range [][]*step{f.Setup, f.Steps, f.Teardown} {
+		for i, step := range steps {
+			if err := step.validate(); err != nil {
+				return nil, fmt.Errorf("step %s: %v", step.getName(i), err)
+			}
+		}
+	}
+	return f, nil
+}
+
+func (s *step) validate() error {
+	if s.Method == "" {
+		return errors.New("method must be set")
+	}
+	if len(s.Data) > 0 && len(s.Requests) > 0 {
+		return errors.New("only one of data or requests can be set")
+	}
+	for name, path := range s.Capture {
+		if !variableNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid variable name %q", name)
+		}
+		if _, _, err := grpc.GetJSONPathValue(nil, path); err != nil {
+			return err
+		}
+	}
+	if s.Expect != nil {
+		if s.Expect.Code != "" {
+			if _, err := parseCode(s.Expect.Code); err != nil {
+				return err
+			}
+		}
+		for path := range s.Expect.Fields {
+			if _, _, err := grpc.GetJSONPathValue(nil, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// substitute substitutes the variables for ${name} in s, escaping
+// the values with escape if it is not nil.
+func substitute(s string, vars map[string]string, escape func(string) string) (string, error) {
+	var err error
+	result := variableRefRegexp.ReplaceAllStringFunc(s, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		value, ok := vars[name]
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("variable %s is not set", name)
+			}
+			return ref
+		}
+		if escape != nil {
+			return escape(value)
+		}
+		return value
+	})
+	return result, err
+}
+
+// jsonEscape escapes the string to be substituted within a JSON string.
+func jsonEscape(s string) string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return s
+	}
+	return string(data[1 : len(data)-1])
+}
+
+// jsonValueString returns the value unmarshalled from JSON as a string
+// to be used as a variable.
+func jsonValueString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func parseCode(s string) (codes.Code, error) {
+	var code codes.Code
+	if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(s)))); err != nil {
+		return code, fmt.Errorf("unknown code %q", s)
+	}
+	return code, nil
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch t := m.(type) {
+	case map[string]string:
+		for key := range t {
+			keys = append(keys, key)
+		}
+	case map[string]json.RawMessage:
+		for key := range t {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package scenario
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestParseFile(t *testing.T) {
+	f, err := parseFile([]byte(`
+address: localhost:8080
+vars:
+  name: foo
+steps:
+  - method: foo.v1.FooAPI/GetFoo
+    data:
+      id: ${id}
+    capture:
+      id: foo.id
+    expect:
+      code: not_found
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:8080", f.Address)
+	assert.Equal(t, map[string]string{"name": "foo"}, f.Vars)
+	require.Len(t, f.Steps, 1)
+	assert.Equal(t, `{"id":"${id}"}`, string(f.Steps[0].Data))
+
+	_, err = parseFile([]byte(`steps: []`))
+	assert.EqualError(t, err, "no steps in scenario file")
+	_, err = parseFile([]byte(`
+steps:
+  - method: foo.v1.FooAPI/GetFoo
+    unknown: true
+`))
+	assert.Error(t, err)
+	_, err = parseFile([]byte(`
+steps:
+  - data:
+      id: foo
+`))
+	assert.EqualError(t, err, "step 1: method must be set")
+	_, err = parseFile([]byte(`
+steps:
+  - name: get
+    method: foo.v1.FooAPI/GetFoo
+    capture:
+      foo-id: foo.id
+`))
+	assert.EqualError(t, err, `step get: invalid variable name "foo-id"`)
+	_, err = parseFile([]byte(`
+steps:
+  - name: get
+    method: foo.v1.FooAPI/GetFoo
+    expect:
+      code: FOO
+`))
+	assert.EqualError(t, err, `step get: unknown code "FOO"`)
+}
+
+func TestSubstitute(t *testing.T) {
+	vars := map[string]string{
+		"id":    "123",
+		"quote": `say "hi"`,
+	}
+	s, err := substitute(`{"id":"${id}","value":"${quote}"}`, vars, jsonEscape)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":"123","value":"say \"hi\""}`, s)
+	s, err = substitute(`Bearer ${quote}`, vars, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `Bearer say "hi"`, s)
+	_, err = substitute(`${id}/${unknown}`, vars, nil)
+	assert.EqualError(t, err, "variable unknown is not set")
+}
+
+func TestStepGetExpectations(t *testing.T) {
+	f, err := parseFile([]byte(`
+steps:
+  - method: foo.v1.FooAPI/GetFoo
+    expect:
+      responses:
+        - foo:
+            id: ${id}
+      fields:
+        foo.id: ${id}
+        foo.count: 2
+`))
+	require.NoError(t, err)
+	expectations, err := f.Steps[0].getExpectations(map[string]string{"id": "123"})
+	require.NoError(t, err)
+	assert.Nil(t, expectations.Code)
+	assert.Equal(t, []interface{}{map[string]interface{}{"foo": map[string]interface{}{"id": "123"}}}, expectations.JSONResponses)
+	require.Len(t, expectations.Fields, 2)
+	assert.Equal(t, "foo.count", expectations.Fields[0].Path)
+	assert.Equal(t, "foo.id", expectations.Fields[1].Path)
+	assert.Equal(t, "123", expectations.Fields[1].Value)
+}
+
+func TestAppendRecording(t *testing.T) {
+	scenarioData, err := AppendRecording(nil, &grpc.Recording{
+		Method:    "foo.ExcitedService/Exclamation",
+		Requests:  []json.RawMessage{json.RawMessage(`{"value":"hello"}`)},
+		Responses: []json.RawMessage{json.RawMessage(`{"value":"hello!"}`)},
+	})
+	require.NoError(t, err)
+	scenarioData, err = AppendRecording(scenarioData, &grpc.Recording{
+		Method:   "foo.ExcitedService/ExclamationClientStream",
+		Requests: []json.RawMessage{json.RawMessage(`{"value":"a"}`), json.RawMessage(`{"value":"b"}`)},
+		Code:     codes.NotFound,
+	})
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`steps:
+- data:
+    value: hello
+  expect:
+    responses:
+    - value: hello!
+  method: foo.ExcitedService/Exclamation
+- expect:
+    code: NOT_FOUND
+  method: foo.ExcitedService/ExclamationClientStream
+  requests:
+  - value: a
+  - value: b
+`,
+		string(scenarioData),
+	)
+	f, err := parseFile(scenarioData)
+	require.NoError(t, err)
+	require.Len(t, f.Steps, 2)
+	expectations, err := f.Steps[1].getExpectations(nil)
+	require.NoError(t, err)
+	require.NotNil(t, expectations.Code)
+	assert.Equal(t, codes.NotFound, *expectations.Code)
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package scenario runs sequences of gRPC calls described in YAML files,
+// for testing APIs with only their Protobuf definitions.
+package scenario
+
+import (
+	"io"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/uber/prototool/internal/grpc"
+	"go.uber.org/zap"
+)
+
+// Runner runs scenarios.
+//
+// A scenario file has setup steps, steps, and teardown steps, each of which
+// is a gRPC call. If a setup step fails, the remaining setup steps and the
+// steps are skipped. Teardown steps are always run.
+//
+//	address: 0.0.0.0:8080
+//	vars:
+//	  name: foo
+//	headers:
+//	  x-request-source: smoke-test
+//	setup:
+//	  - name: create
+//	    method: foo.v1.FooAPI/CreateFoo
+//	    data:
+//	      name: ${name}
+//	    capture:
+//	      id: foo.id
+//	steps:
+//	  - name: get
+//	    method: foo.v1.FooAPI/GetFoo
+//	    data:
+//	      id: ${id}
+//	    expect:
+//	      fields:
+//	        foo.name: ${name}
+//	teardown:
+//	  - method: foo.v1.FooAPI/DeleteFoo
+//	    data:
+//	      id: ${id}
+//
+// Variables are set with vars, or captured from the first response of a step
+// with a path as with grpc.FieldExpectation, and are substituted for ${name}
+// in data, requests, headers, and expectations as strings.
+type Runner interface {
+	// Run runs the scenario in the YAML scenario data against the address,
+	// writing the result of each step to the output, and returns the number
+	// of steps that failed.
+	//
+	// If the address is empty, the address in the scenario data is used.
+	Run(fileDescriptorSets []*descriptor.FileDescriptorSet, scenarioData []byte, address string, output io.Writer) (int, error)
+}
+
+// RunnerOption is an option for a new Runner.
+type RunnerOption func(*runner)
+
+// RunnerWithLogger returns a RunnerOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func RunnerWithLogger(logger *zap.Logger) RunnerOption {
+	return func(runner *runner) {
+		runner.logger = logger
+	}
+}
+
+// RunnerWithHandlerOptions returns a RunnerOption that uses the given
+// HandlerOptions for each call, such as for timeouts and headers.
+//
+// The output format of the Handler is always JSON.
+func RunnerWithHandlerOptions(handlerOptions ...grpc.HandlerOption) RunnerOption {
+	return func(runner *runner) {
+		runner.handlerOptions = append(runner.handlerOptions, handlerOptions...)
+	}
+}
+
+// NewRunner returns a new Runner.
+func NewRunner(options ...RunnerOption) Runner {
+	return newRunner(options...)
+}
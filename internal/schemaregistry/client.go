@@ -0,0 +1,197 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package schemaregistry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+const (
+	contentType         = "application/vnd.schemaregistry.v1+json"
+	protobufSchemaType  = "PROTOBUF"
+	subjectNotFoundCode = 40401
+	versionNotFoundCode = 40402
+)
+
+type client struct {
+	logger   *zap.Logger
+	username string
+	password string
+	timeout  time.Duration
+
+	url        string
+	httpClient *http.Client
+}
+
+func newClient(url string, options ...ClientOption) *client {
+	client := &client{
+		logger:  zap.NewNop(),
+		timeout: DefaultTimeout,
+		url:     strings.TrimSuffix(url, "/"),
+	}
+	for _, option := range options {
+		option(client)
+	}
+	client.httpClient = &http.Client{
+		Timeout: client.timeout,
+	}
+	return client
+}
+
+func (c *client) Register(subject string, schema string, references []*Reference) (*Schema, error) {
+	request := newSchemaRequest(schema, references)
+	if err := c.do(http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", request, nil); err != nil {
+		return nil, err
+	}
+	// registering only returns the ID, so look up the schema to get the version
+	response := &schemaResponse{}
+	if err := c.do(http.MethodPost, "/subjects/"+url.PathEscape(subject), request, response); err != nil {
+		return nil, err
+	}
+	return response.toSchema(subject), nil
+}
+
+func (c *client) IsCompatible(subject string, schema string, references []*Reference) (bool, error) {
+	response := &compatibilityResponse{}
+	err := c.do(http.MethodPost, "/compatibility/subjects/"+url.PathEscape(subject)+"/versions/latest", newSchemaRequest(schema, references), response)
+	if err != nil {
+		if errorResponse, ok := err.(*errorResponse); ok {
+			if errorResponse.ErrorCode == subjectNotFoundCode || errorResponse.ErrorCode == versionNotFoundCode {
+				return true, nil
+			}
+		}
+		return false, err
+	}
+	return response.IsCompatible, nil
+}
+
+func (c *client) Get(subject string, version string) (*Schema, error) {
+	response := &schemaResponse{}
+	if err := c.do(http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/"+url.PathEscape(version), nil, response); err != nil {
+		return nil, err
+	}
+	if response.SchemaType != "" && response.SchemaType != protobufSchemaType {
+		return nil, fmt.Errorf("subject %s version %s has schema type %s, not %s", subject, version, response.SchemaType, protobufSchemaType)
+	}
+	return response.toSchema(subject), nil
+}
+
+// do sends the request body as JSON if not nil, and unmarshals the
+// response body into response if not nil.
+func (c *client) do(method string, path string, request interface{}, response interface{}) (retErr error) {
+	body := bytes.NewReader(nil)
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	httpRequest, err := http.NewRequest(method, c.url+path, body)
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Accept", contentType)
+	if request != nil {
+		httpRequest.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" || c.password != "" {
+		httpRequest.SetBasicAuth(c.username, c.password)
+	}
+	c.logger.Debug("schema registry request", zap.String("method", method), zap.String("path", path))
+	httpResponse, err := c.httpClient.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, httpResponse.Body.Close())
+	}()
+	data, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return err
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		errorResponse := &errorResponse{}
+		if err := json.Unmarshal(data, errorResponse); err != nil || errorResponse.ErrorCode == 0 {
+			return fmt.Errorf("schema registry returned %s for %s %s", httpResponse.Status, method, path)
+		}
+		return errorResponse
+	}
+	if response == nil {
+		return nil
+	}
+	return json.Unmarshal(data, response)
+}
+
+type schemaRequest struct {
+	SchemaType string       `json:"schemaType"`
+	Schema     string       `json:"schema"`
+	References []*Reference `json:"references,omitempty"`
+}
+
+func newSchemaRequest(schema string, references []*Reference) *schemaRequest {
+	return &schemaRequest{
+		SchemaType: protobufSchemaType,
+		Schema:     schema,
+		References: references,
+	}
+}
+
+type schemaResponse struct {
+	ID         int          `json:"id"`
+	Version    int          `json:"version"`
+	SchemaType string       `json:"schemaType"`
+	Schema     string       `json:"schema"`
+	References []*Reference `json:"references"`
+}
+
+func (s *schemaResponse) toSchema(subject string) *Schema {
+	return &Schema{
+		Subject:    subject,
+		ID:         s.ID,
+		Version:    s.Version,
+		Schema:     s.Schema,
+		References: s.References,
+	}
+}
+
+type compatibilityResponse struct {
+	IsCompatible bool `json:"is_compatible"`
+}
+
+type errorResponse struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func (e *errorResponse) Error() string {
+	return fmt.Sprintf("schema registry error %d: %s", e.ErrorCode, e.Message)
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package schemaregistry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	var requests []*schemaRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, contentType, r.Header.Get("Accept"))
+		if r.Method == http.MethodPost {
+			request := &schemaRequest{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(request))
+			requests = append(requests, request)
+		}
+		switch r.URL.Path {
+		case "/compatibility/subjects/new-value/versions/latest":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error_code":40401,"message":"Subject not found."}`))
+		case "/compatibility/subjects/foo-value/versions/latest":
+			_, _ = w.Write([]byte(`{"is_compatible":false}`))
+		case "/subjects/foo-value/versions":
+			_, _ = w.Write([]byte(`{"id":3}`))
+		case "/subjects/foo-value":
+			_, _ = w.Write([]byte(`{"subject":"foo-value","id":3,"version":2,"schema":"syntax = \"proto3\";"}`))
+		case "/subjects/foo-value/versions/latest":
+			_, _ = w.Write([]byte(`{"subject":"foo-value","id":3,"version":2,"schemaType":"PROTOBUF","schema":"syntax = \"proto3\";","references":[{"name":"bar.proto","subject":"bar.proto","version":1}]}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	client := NewClient(server.URL + "/")
+
+	compatible, err := client.IsCompatible("new-value", `syntax = "proto3";`, nil)
+	assert.NoError(t, err)
+	assert.True(t, compatible)
+	compatible, err = client.IsCompatible("foo-value", `syntax = "proto3";`, nil)
+	assert.NoError(t, err)
+	assert.False(t, compatible)
+
+	references := []*Reference{{Name: "bar.proto", Subject: "bar.proto", Version: 1}}
+	schema, err := client.Register("foo-value", `syntax = "proto3";`, references)
+	require.NoError(t, err)
+	assert.Equal(t, &Schema{Subject: "foo-value", ID: 3, Version: 2, Schema: `syntax = "proto3";`}, schema)
+	require.Len(t, requests, 4)
+	assert.Equal(t, protobufSchemaType, requests[2].SchemaType)
+	assert.Equal(t, references, requests[2].References)
+
+	schema, err = client.Get("foo-value", "latest")
+	require.NoError(t, err)
+	assert.Equal(t, references, schema.References)
+
+	_, err = client.Get("bar-value", "latest")
+	assert.Error(t, err)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package schemaregistry is a client for the Confluent Schema Registry
+// that registers and retrieves schemas of the PROTOBUF schema type.
+//
+// https://docs.confluent.io/current/schema-registry/develop/api.html
+package schemaregistry
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultTimeout is the default timeout for requests.
+const DefaultTimeout = 10 * time.Second
+
+// Reference is a reference from a schema to another registered schema,
+// used for imports.
+type Reference struct {
+	// The import path, for example foo/bar.proto.
+	Name string `json:"name"`
+	// The subject the imported schema is registered under.
+	Subject string `json:"subject"`
+	// The version of the imported schema under the subject.
+	Version int `json:"version"`
+}
+
+// Schema is a registered schema.
+type Schema struct {
+	Subject    string
+	ID         int
+	Version    int
+	Schema     string
+	References []*Reference
+}
+
+// Client is a client for a Confluent Schema Registry.
+type Client interface {
+	// Register registers the .proto file contents under the subject and returns
+	// the registered schema.
+	//
+	// If the schema is already registered under the subject, the existing
+	// registered schema is returned.
+	Register(subject string, schema string, references []*Reference) (*Schema, error)
+	// IsCompatible returns true if the .proto file contents are compatible with
+	// the latest version registered under the subject, or if the subject does
+	// not exist yet.
+	IsCompatible(subject string, schema string, references []*Reference) (bool, error)
+	// Get gets the schema registered under the subject with the given
+	// version, which can be "latest".
+	Get(subject string, version string) (*Schema, error)
+}
+
+// ClientOption is an option for a new Client.
+type ClientOption func(*client)
+
+// ClientWithLogger returns a ClientOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func ClientWithLogger(logger *zap.Logger) ClientOption {
+	return func(client *client) {
+		client.logger = logger
+	}
+}
+
+// ClientWithBasicAuth returns a ClientOption that uses HTTP basic
+// authentication with the given username and password.
+func ClientWithBasicAuth(username string, password string) ClientOption {
+	return func(client *client) {
+		client.username = username
+		client.password = password
+	}
+}
+
+// ClientWithTimeout returns a ClientOption that uses the given timeout
+// for requests.
+//
+// The default is to use DefaultTimeout.
+func ClientWithTimeout(timeout time.Duration) ClientOption {
+	return func(client *client) {
+		client.timeout = timeout
+	}
+}
+
+// NewClient returns a new Client for the schema registry at the given URL.
+func NewClient(url string, options ...ClientOption) Client {
+	return newClient(url, options...)
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package secret
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/prototool/internal/exectrace"
+	"go.uber.org/zap"
+)
+
+type resolver struct {
+	logger     *zap.Logger
+	cmdTimeout time.Duration
+	execTracer exectrace.Tracer
+
+	lock    sync.Mutex
+	secrets map[string]string
+}
+
+func newResolver(options ...ResolverOption) *resolver {
+	resolver := &resolver{
+		logger:     zap.NewNop(),
+		cmdTimeout: DefaultCmdTimeout,
+		execTracer: exectrace.NewNopTracer(),
+		secrets:    make(map[string]string),
+	}
+	for _, option := range options {
+		option(resolver)
+	}
+	return resolver
+}
+
+func (r *resolver) Resolve(value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if secret, ok := r.secrets[value]; ok {
+		return secret, nil
+	}
+	secret, err := r.resolve(value)
+	if err != nil {
+		return "", err
+	}
+	r.secrets[value] = secret
+	return secret, nil
+}
+
+func (r *resolver) resolve(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, EnvPrefix):
+		name := strings.TrimPrefix(value, EnvPrefix)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("secret %s: environment variable %s is not set", value, name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, FilePrefix):
+		filePath := strings.TrimPrefix(value, FilePrefix)
+		data, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return "", fmt.Errorf("secret %s: %v", value, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	default:
+		return r.runCmd(value)
+	}
+}
+
+// runCmd runs the command of a cmd:// reference and returns its output.
+//
+// The output and the command are not logged, as the command may
+// contain secrets itself.
+func (r *resolver) runCmd(value string) (string, error) {
+	args := strings.Fields(strings.TrimPrefix(value, CmdPrefix))
+	if len(args) == 0 {
+		return "", fmt.Errorf("secret %s has no command", CmdPrefix)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.cmdTimeout)
+	defer cancel()
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	r.logger.Debug("running secret command", zap.String("name", args[0]))
+	finish := r.execTracer.Start(cmd)
+	err := cmd.Run()
+	finish(err)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("secret command %s timed out after %v", args[0], r.cmdTimeout)
+		}
+		return "", fmt.Errorf("secret command %s failed: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
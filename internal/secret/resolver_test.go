@@ -0,0 +1,86 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package secret
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	require.NoError(t, os.Setenv("PROTOTOOL_TEST_SECRET", "env-secret"))
+	defer func() { _ = os.Unsetenv("PROTOTOOL_TEST_SECRET") }()
+	tmpDir, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	filePath := filepath.Join(tmpDir, "secret")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("file-secret\n"), 0600))
+
+	resolver := NewResolver()
+	for value, expected := range map[string]string{
+		"plain":                        "plain",
+		"Bearer ${NOT_EXPANDED}":       "Bearer ${NOT_EXPANDED}",
+		"env://PROTOTOOL_TEST_SECRET":  "env-secret",
+		"file://" + filePath:           "file-secret",
+		"cmd://echo   cmd-secret  foo": "cmd-secret foo",
+	} {
+		secret, err := resolver.Resolve(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, secret, value)
+	}
+	for _, value := range []string{
+		"env://PROTOTOOL_TEST_SECRET_NOT_SET",
+		"file://" + filepath.Join(tmpDir, "missing"),
+		"cmd://",
+		"cmd://false",
+	} {
+		_, err := resolver.Resolve(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestResolveOnce(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	filePath := filepath.Join(tmpDir, "secret")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("first"), 0600))
+
+	resolver := NewResolver()
+	secret, err := resolver.Resolve("file://" + filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "first", secret)
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("second"), 0600))
+	secret, err = resolver.Resolve("file://" + filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "first", secret)
+}
+
+func TestResolveCmdTimeout(t *testing.T) {
+	_, err := NewResolver(ResolverWithCmdTimeout(10 * time.Millisecond)).Resolve("cmd://sleep 5")
+	assert.Error(t, err)
+}
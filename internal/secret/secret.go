@@ -0,0 +1,103 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package secret resolves references to secrets, so that tokens do not
+// need to be stored in plaintext in config files or passed on the command line.
+package secret
+
+import (
+	"strings"
+	"time"
+
+	"github.com/uber/prototool/internal/exectrace"
+	"go.uber.org/zap"
+)
+
+const (
+	// EnvPrefix is the prefix of references to the value of an
+	// environment variable, such as env://API_TOKEN.
+	EnvPrefix = "env://"
+	// FilePrefix is the prefix of references to the contents of a
+	// file, such as file:///run/secrets/api_token.
+	FilePrefix = "file://"
+	// CmdPrefix is the prefix of references to the output of a command,
+	// such as cmd://vault read -field=token secret/api.
+	//
+	// The command is split on whitespace and is not run in a shell.
+	CmdPrefix = "cmd://"
+
+	// DefaultCmdTimeout is the default timeout for commands.
+	DefaultCmdTimeout = time.Minute
+)
+
+// IsReference returns true if the value is a reference to a secret.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, EnvPrefix) ||
+		strings.HasPrefix(value, FilePrefix) ||
+		strings.HasPrefix(value, CmdPrefix)
+}
+
+// Resolver resolves references to secrets.
+type Resolver interface {
+	// Resolve returns the secret if the value is a reference to a secret,
+	// otherwise it returns the value.
+	//
+	// The contents of files and the output of commands are trimmed of
+	// leading and trailing whitespace. Each reference is only resolved
+	// once, so that commands are not run again for the same reference.
+	Resolve(value string) (string, error)
+}
+
+// ResolverOption is an option for a new Resolver.
+type ResolverOption func(*resolver)
+
+// ResolverWithLogger returns a ResolverOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func ResolverWithLogger(logger *zap.Logger) ResolverOption {
+	return func(resolver *resolver) {
+		resolver.logger = logger
+	}
+}
+
+// ResolverWithCmdTimeout returns a ResolverOption that uses the given
+// timeout for commands.
+//
+// The default is to use DefaultCmdTimeout.
+func ResolverWithCmdTimeout(cmdTimeout time.Duration) ResolverOption {
+	return func(resolver *resolver) {
+		resolver.cmdTimeout = cmdTimeout
+	}
+}
+
+// ResolverWithExecTracer returns a ResolverOption that traces the
+// commands run for cmd:// references with the given tracer.
+//
+// Only the command line is traced, never its output.
+func ResolverWithExecTracer(execTracer exectrace.Tracer) ResolverOption {
+	return func(resolver *resolver) {
+		resolver.execTracer = execTracer
+	}
+}
+
+// NewResolver returns a new Resolver.
+func NewResolver(options ...ResolverOption) Resolver {
+	return newResolver(options...)
+}
@@ -23,16 +23,28 @@ package settings
 import (
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/uber/prototool/internal/protostrs"
+	"github.com/uber/prototool/internal/secret"
 	"github.com/uber/prototool/internal/strs"
+	"github.com/uber/prototool/internal/vars"
+	"github.com/uber/prototool/internal/webhook"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v2"
 )
 
+// createLayoutVariableRegexp matches the <name> variables of create layouts.
+var createLayoutVariableRegexp = regexp.MustCompile(`<([a-zA-Z_][a-zA-Z0-9_]*)>`)
+
 type configProvider struct {
 	logger *zap.Logger
 }
@@ -98,10 +110,12 @@ func getFilePathForDir(dirPath string) (string, []string) {
 		if _, err := os.Stat(filePath); err == nil {
 			return filePath, dirPaths
 		}
-		if dirPath == "/" {
+		// the root directory is its own parent, such as / or C:\
+		parentDirPath := filepath.Dir(dirPath)
+		if parentDirPath == dirPath {
 			return "", dirPaths
 		}
-		dirPath = filepath.Dir(dirPath)
+		dirPath = parentDirPath
 	}
 }
 
@@ -138,6 +152,106 @@ func externalConfigToConfig(e ExternalConfig, dirPath string) (Config, error) {
 		includePaths = append(includePaths, includePath)
 		//}
 	}
+	protocBinPath := e.Protoc.BinPath
+	if strings.ContainsRune(protocBinPath, '/') || strings.ContainsRune(protocBinPath, filepath.Separator) {
+		if !filepath.IsAbs(protocBinPath) {
+			protocBinPath = filepath.Join(dirPath, protocBinPath)
+		}
+		protocBinPath = filepath.Clean(protocBinPath)
+	}
+	protocWKTPath := e.Protoc.WKTPath
+	if protocWKTPath != "" {
+		if !filepath.IsAbs(protocWKTPath) {
+			protocWKTPath = filepath.Join(dirPath, protocWKTPath)
+		}
+		protocWKTPath = filepath.Clean(protocWKTPath)
+	}
+	if strings.ContainsAny(e.GoogleapisVersion, `/\`) || strings.Contains(e.GoogleapisVersion, "..") {
+		return Config{}, fmt.Errorf("invalid googleapis_version: %s", e.GoogleapisVersion)
+	}
+	if err := validateProtocArgs(e.Protoc.ExtraArgs); err != nil {
+		return Config{}, fmt.Errorf("invalid protoc extra_args: %v", err)
+	}
+	protocTimeout, err := parseTimeout(e.Protoc.Timeout)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid protoc timeout: %v", err)
+	}
+	if e.Protoc.MaxOutputBytes < 0 {
+		return Config{}, fmt.Errorf("protoc max_output_bytes must not be negative: %d", e.Protoc.MaxOutputBytes)
+	}
+	if len(e.Protoc.SandboxEnv) > 0 && !e.Protoc.Sandbox {
+		return Config{}, fmt.Errorf("protoc sandbox_env can only be set with sandbox")
+	}
+	for _, name := range e.Protoc.SandboxEnv {
+		if name == "" || strings.ContainsRune(name, '=') {
+			return Config{}, fmt.Errorf("invalid protoc sandbox_env name: %q", name)
+		}
+	}
+	languages := nilIfEmpty(strs.DedupeSort(e.Languages, strings.ToLower))
+	for _, language := range languages {
+		if _, ok := protostrs.LanguageToFileOptionNames[language]; !ok {
+			return Config{}, fmt.Errorf("languages must be one of %v but was %q", protostrs.Languages, language)
+		}
+	}
+	var roots []Root
+	for _, protocRoot := range e.ProtocRoots {
+		if protocRoot.Path == "" {
+			return Config{}, fmt.Errorf("path required for protoc_roots")
+		}
+		if filepath.IsAbs(protocRoot.Path) {
+			return Config{}, fmt.Errorf("protoc_roots path must be relative: %s", protocRoot.Path)
+		}
+		rootDirPath := filepath.Clean(filepath.Join(dirPath, protocRoot.Path))
+		rootIncludePaths := make([]string, 0, len(protocRoot.Includes))
+		for _, includePath := range strs.DedupeSort(protocRoot.Includes, nil) {
+			if !filepath.IsAbs(includePath) {
+				includePath = filepath.Join(dirPath, includePath)
+			}
+			rootIncludePaths = append(rootIncludePaths, filepath.Clean(includePath))
+		}
+		roots = append(roots, Root{
+			DirPath:      rootDirPath,
+			IncludePaths: rootIncludePaths,
+		})
+	}
+	var workspaceRepoDirPaths []string
+	for _, repo := range e.Workspace.Repos {
+		if repo.Path == "" {
+			return Config{}, fmt.Errorf("path required for workspace repos")
+		}
+		if filepath.IsAbs(repo.Path) {
+			return Config{}, fmt.Errorf("workspace repo path must be relative: %s", repo.Path)
+		}
+		repoDirPath := filepath.Clean(filepath.Join(dirPath, repo.Path))
+		if repoDirPath == dirPath {
+			return Config{}, fmt.Errorf("workspace repo path must not be the directory of the config file: %s", repo.Path)
+		}
+		workspaceRepoDirPaths = append(workspaceRepoDirPaths, repoDirPath)
+		// the repository itself is the root unless roots are given
+		repoRoots := repo.Roots
+		if len(repoRoots) == 0 {
+			repoRoots = []string{"."}
+		}
+		for _, repoRoot := range repoRoots {
+			if filepath.IsAbs(repoRoot) {
+				return Config{}, fmt.Errorf("workspace repo root must be relative: %s", repoRoot)
+			}
+			roots = append(roots, Root{
+				DirPath: filepath.Clean(filepath.Join(repoDirPath, repoRoot)),
+			})
+		}
+	}
+	sort.Slice(roots, func(i int, j int) bool { return roots[i].DirPath < roots[j].DirPath })
+	for i, root := range roots {
+		for _, otherRoot := range roots[i+1:] {
+			if otherRoot.DirPath == root.DirPath {
+				return Config{}, fmt.Errorf("duplicate protoc_roots or workspace root path %s", root.DirPath)
+			}
+			if strings.HasPrefix(otherRoot.DirPath, root.DirPath+string(filepath.Separator)) {
+				return Config{}, fmt.Errorf("protoc_roots or workspace root path %s is within %s", otherRoot.DirPath, root.DirPath)
+			}
+		}
+	}
 	ignoreIDToFilePaths := make(map[string][]string)
 	for id, protoFilePaths := range e.Lint.IgnoreIDToFiles {
 		id = strings.ToUpper(id)
@@ -152,19 +266,210 @@ func externalConfigToConfig(e ExternalConfig, dirPath string) (Config, error) {
 			ignoreIDToFilePaths[id] = append(ignoreIDToFilePaths[id], protoFilePath)
 		}
 	}
+	var forbiddenImports []ForbiddenRule
+	for _, forbiddenImport := range e.Lint.Forbidden.Imports {
+		if forbiddenImport.Path == "" {
+			return Config{}, fmt.Errorf("lint forbidden imports must have a path")
+		}
+		if _, err := strs.MatchGlob(forbiddenImport.Path, ""); err != nil {
+			return Config{}, fmt.Errorf("lint forbidden import path %s is invalid: %v", forbiddenImport.Path, err)
+		}
+		allowPatterns, err := getForbiddenAllowPatterns(forbiddenImport.Allow)
+		if err != nil {
+			return Config{}, err
+		}
+		forbiddenImports = append(forbiddenImports, ForbiddenRule{
+			Pattern:       forbiddenImport.Path,
+			AllowDirPath:  dirPath,
+			AllowPatterns: allowPatterns,
+		})
+	}
+	var forbiddenTypes []ForbiddenRule
+	for _, forbiddenType := range e.Lint.Forbidden.Types {
+		name := strings.TrimPrefix(forbiddenType.Name, ".")
+		if name == "" || strings.Contains(strings.TrimSuffix(name, ".*"), "*") {
+			return Config{}, fmt.Errorf("lint forbidden type name must be a type or a package followed by .* but was %q", forbiddenType.Name)
+		}
+		allowPatterns, err := getForbiddenAllowPatterns(forbiddenType.Allow)
+		if err != nil {
+			return Config{}, err
+		}
+		forbiddenTypes = append(forbiddenTypes, ForbiddenRule{
+			Pattern:       name,
+			AllowDirPath:  dirPath,
+			AllowPatterns: allowPatterns,
+		})
+	}
+	sensitiveOption := strings.TrimPrefix(strings.TrimSuffix(strings.TrimPrefix(e.Lint.Sensitive.Option, "("), ")"), ".")
+	if strings.ContainsAny(sensitiveOption, "()* ") {
+		return Config{}, fmt.Errorf("lint sensitive option must be a fully-qualified option name but was %q", e.Lint.Sensitive.Option)
+	}
+	var sensitiveFieldPatterns []string
+	for _, fieldPattern := range e.Lint.Sensitive.FieldPatterns {
+		fieldPattern = strings.ToLower(fieldPattern)
+		if _, err := path.Match(fieldPattern, ""); fieldPattern == "" || err != nil {
+			return Config{}, fmt.Errorf("lint sensitive field pattern %q is invalid", fieldPattern)
+		}
+		sensitiveFieldPatterns = append(sensitiveFieldPatterns, fieldPattern)
+	}
+	var logExemptTypes []string
+	for _, logExemptType := range e.Lint.Sensitive.LogExemptTypes {
+		name := strings.TrimPrefix(logExemptType, ".")
+		if name == "" || strings.Contains(strings.TrimSuffix(name, ".*"), "*") {
+			return Config{}, fmt.Errorf("lint sensitive log_exempt_types must be types or packages followed by .* but was %q", logExemptType)
+		}
+		logExemptTypes = append(logExemptTypes, name)
+	}
+	var logPackages []string
+	for _, logPackage := range e.Lint.Sensitive.LogPackages {
+		name := strings.TrimPrefix(logPackage, ".")
+		if name == "" || strings.Contains(strings.TrimSuffix(name, ".*"), "*") {
+			return Config{}, fmt.Errorf("lint sensitive log_packages must be packages or packages followed by .* but was %q", logPackage)
+		}
+		logPackages = append(logPackages, name)
+	}
+	if len(logExemptTypes) > 0 && len(logPackages) == 0 {
+		return Config{}, fmt.Errorf("lint sensitive log_packages must be set if log_exempt_types is set")
+	}
+	var idToSeverity map[string]string
+	for id, severity := range e.Lint.IDToSeverity {
+		severity = strings.ToLower(severity)
+		switch severity {
+		case "error", "warning", "info":
+		default:
+			return Config{}, fmt.Errorf("lint severity for %s must be error, warning, or info but was %q", id, severity)
+		}
+		if idToSeverity == nil {
+			idToSeverity = make(map[string]string)
+		}
+		idToSeverity[strings.ToUpper(id)] = severity
+	}
+	enumZeroValueSuffix := strings.ToUpper(e.Lint.Enums.ZeroValueSuffix)
+	if enumZeroValueSuffix != "" && !strs.IsUpperSnakeCase(enumZeroValueSuffix) {
+		return Config{}, fmt.Errorf("lint enums zero_value_suffix must be UPPER_SNAKE_CASE but was %q", e.Lint.Enums.ZeroValueSuffix)
+	}
+	enumValuePrefix := strings.ToLower(e.Lint.Enums.ValuePrefix)
+	if enumValuePrefix != "" && !isValidEnumValuePrefix(enumValuePrefix) {
+		return Config{}, fmt.Errorf("lint enums value_prefix must be one of %v but was %q", protostrs.EnumValuePrefixes, e.Lint.Enums.ValuePrefix)
+	}
+	for _, serviceSuffix := range e.Lint.Naming.ServiceSuffixes {
+		if !strs.IsCamelCase(serviceSuffix) || !strs.IsCapitalized(serviceSuffix) {
+			return Config{}, fmt.Errorf("lint naming service_suffixes must be capitalized CamelCase but had %q", serviceSuffix)
+		}
+	}
+	for _, rpcPrefix := range e.Lint.Naming.RPCPrefixes {
+		if !strs.IsCamelCase(rpcPrefix) || !strs.IsCapitalized(rpcPrefix) {
+			return Config{}, fmt.Errorf("lint naming rpc_prefixes must be capitalized CamelCase but had %q", rpcPrefix)
+		}
+	}
+	for _, templateText := range []string{e.Lint.Naming.RequestTemplate, e.Lint.Naming.ResponseTemplate} {
+		if templateText == "" {
+			continue
+		}
+		if _, err := protostrs.RPCTypeName(templateText, "FooService", "GetBar"); err != nil {
+			return Config{}, fmt.Errorf("invalid lint naming template %q: %v", templateText, err)
+		}
+	}
+	var serviceNameSuffixes []string
+	if len(e.Lint.Naming.ServiceSuffixes) > 0 {
+		serviceNameSuffixes = strs.DedupeSort(e.Lint.Naming.ServiceSuffixes, nil)
+	}
+	var rpcNamePrefixes []string
+	if len(e.Lint.Naming.RPCPrefixes) > 0 {
+		rpcNamePrefixes = strs.DedupeSort(e.Lint.Naming.RPCPrefixes, nil)
+	}
+	var hotFields map[string][]string
+	for messageName, fieldNames := range e.Lint.Fields.HotFields {
+		if messageName == "" || strings.HasPrefix(messageName, ".") {
+			return Config{}, fmt.Errorf("lint fields hot_fields keys must be fully-qualified message names without a leading period: %q", messageName)
+		}
+		if len(fieldNames) == 0 {
+			continue
+		}
+		if hotFields == nil {
+			hotFields = make(map[string][]string)
+		}
+		hotFields[messageName] = strs.DedupeSort(fieldNames, nil)
+	}
+	if e.Lint.Fields.MaxPerMessage < 0 {
+		return Config{}, fmt.Errorf("lint fields max_per_message must be positive: %d", e.Lint.Fields.MaxPerMessage)
+	}
+	var customOptionNumberRanges []NumberRange
+	for _, value := range e.Lint.Options.NumberRanges {
+		numberRange, err := parseNumberRange(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid lint options number_ranges: %v", err)
+		}
+		customOptionNumberRanges = append(customOptionNumberRanges, numberRange)
+	}
 
+	protobufVersion := e.ProtocVersion
+	autoProtobufVersion := protobufVersion == AutoProtocVersion
+	if autoProtobufVersion {
+		protobufVersion = ""
+	}
+
+	gogoProtobufVersion := e.GogoProtobufVersion
+	grpcGatewayVersion := e.GRPCGatewayVersion
+	protobufJavascriptVersion := e.ProtobufJavascriptVersion
 	genPlugins := make([]GenPlugin, len(e.Gen.Plugins))
 	for i, plugin := range e.Gen.Plugins {
 		genPluginType, err := ParseGenPluginType(plugin.Type)
 		if err != nil {
 			return Config{}, err
 		}
+		preset := strings.ToLower(plugin.Preset)
+		if preset != "" {
+			genPluginPreset, ok := _genPluginPresets[preset]
+			if !ok {
+				return Config{}, fmt.Errorf("unknown preset %s for plugin %s", plugin.Preset, plugin.Name)
+			}
+			if plugin.Type != "" && genPluginType != genPluginPreset.genPluginType {
+				return Config{}, fmt.Errorf("plugin %s has preset %s which is of type %v but type %v was specified", plugin.Name, preset, genPluginPreset.genPluginType, genPluginType)
+			}
+			genPluginType = genPluginPreset.genPluginType
+			if plugin.Name == "" {
+				plugin.Name = preset
+			}
+			switch genPluginPreset.dependency {
+			case genPluginPresetDependencyGogoProtobuf:
+				if gogoProtobufVersion == "" {
+					gogoProtobufVersion = vars.DefaultGogoProtobufVersion
+				}
+			case genPluginPresetDependencyGRPCGateway:
+				if grpcGatewayVersion == "" {
+					grpcGatewayVersion = vars.DefaultGRPCGatewayVersion
+				}
+			case genPluginPresetDependencyProtobufJavascript:
+				if protobufJavascriptVersion == "" {
+					protobufJavascriptVersion = vars.DefaultProtobufJavascriptVersion
+				}
+			}
+			plugin.Flags = addDefaultPluginFlags(plugin.Flags, genPluginPreset.defaultFlags)
+		}
 		if plugin.Output == "" {
 			return Config{}, fmt.Errorf("output path required for plugin %s", plugin.Name)
 		}
+		if err := validateProtocArgs(plugin.ProtocArgs); err != nil {
+			return Config{}, fmt.Errorf("invalid protoc_args for plugin %s: %v", plugin.Name, err)
+		}
+		pluginTimeout, err := parseTimeout(plugin.Timeout)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid timeout for plugin %s: %v", plugin.Name, err)
+		}
 		path := ""
+		if plugin.Path != "" {
+			path = plugin.Path
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dirPath, path)
+			}
+			path = filepath.Clean(path)
+		}
 		if len(e.Gen.PluginOverrides) > 0 {
 			if override, ok := e.Gen.PluginOverrides[plugin.Name]; ok && override != "" {
+				if path != "" {
+					return Config{}, fmt.Errorf("plugin %s has both a path and a plugin_overrides path", plugin.Name)
+				}
 				path = override
 			}
 		}
@@ -188,9 +493,22 @@ func externalConfigToConfig(e ExternalConfig, dirPath string) (Config, error) {
 				RelPath: relPath,
 				AbsPath: absPath,
 			},
+			Preset:     preset,
+			ProtocArgs: nilIfEmpty(plugin.ProtocArgs),
+			Timeout:    pluginTimeout,
 		}
 	}
 	sort.Slice(genPlugins, func(i int, j int) bool { return genPlugins[i].Name < genPlugins[j].Name })
+	var genPluginDirPaths []string
+	for _, pluginDirPath := range e.Gen.PluginDirs {
+		if pluginDirPath == "" {
+			return Config{}, fmt.Errorf("gen plugin_dirs cannot contain empty paths")
+		}
+		if !filepath.IsAbs(pluginDirPath) {
+			pluginDirPath = filepath.Join(dirPath, pluginDirPath)
+		}
+		genPluginDirPaths = append(genPluginDirPaths, filepath.Clean(pluginDirPath))
+	}
 
 	createDirPathToBasePackage := make(map[string]string)
 	for relDirPath, basePackage := range e.Create.DirToBasePackage {
@@ -203,25 +521,298 @@ func externalConfigToConfig(e ExternalConfig, dirPath string) (Config, error) {
 	if len(createDirPathToBasePackage) == 0 {
 		createDirPathToBasePackage = nil
 	}
+	var createLayouts []CreateLayout
+	for _, layout := range e.Create.Layouts {
+		if layout.Path == "" || layout.Package == "" {
+			return Config{}, fmt.Errorf("create layouts must have a path and a package: %s", layout.Path)
+		}
+		if filepath.IsAbs(layout.Path) || path.IsAbs(layout.Path) {
+			return Config{}, fmt.Errorf("create layout path must be relative: %s", layout.Path)
+		}
+		pathVariables := make(map[string]struct{})
+		for _, match := range createLayoutVariableRegexp.FindAllStringSubmatch(layout.Path, -1) {
+			pathVariables[match[1]] = struct{}{}
+		}
+		templates := []string{layout.Package}
+		for _, value := range layout.Options {
+			templates = append(templates, value)
+		}
+		for _, template := range templates {
+			for _, match := range createLayoutVariableRegexp.FindAllStringSubmatch(template, -1) {
+				if _, ok := pathVariables[match[1]]; !ok {
+					return Config{}, fmt.Errorf("create layout %s uses <%s> which is not in the path", layout.Path, match[1])
+				}
+			}
+		}
+		createLayouts = append(createLayouts, CreateLayout{
+			DirPath: dirPath,
+			Path:    path.Clean(layout.Path),
+			Package: layout.Package,
+			Options: layout.Options,
+		})
+	}
+
+	moduleDeps := make([]ModuleDep, len(e.Modules.Deps))
+	moduleDepNames := make(map[string]struct{}, len(e.Modules.Deps))
+	for i, dep := range e.Modules.Deps {
+		if dep.Name == "" || dep.Version == "" {
+			return Config{}, fmt.Errorf("name and version required for module deps")
+		}
+		if _, ok := moduleDepNames[dep.Name]; ok {
+			return Config{}, fmt.Errorf("duplicate module dep %s", dep.Name)
+		}
+		moduleDepNames[dep.Name] = struct{}{}
+		moduleDeps[i] = ModuleDep{
+			Name:    dep.Name,
+			Version: dep.Version,
+		}
+	}
+	sort.Slice(moduleDeps, func(i int, j int) bool { return moduleDeps[i].Name < moduleDeps[j].Name })
+	// to make testing easier
+	if len(moduleDeps) == 0 {
+		moduleDeps = nil
+	}
+
+	genGoStubsOutputPath := ""
+	if e.Gen.GoStubs.Output != "" {
+		if filepath.IsAbs(e.Gen.GoStubs.Output) {
+			return Config{}, fmt.Errorf("gen go_stubs output must be relative: %s", e.Gen.GoStubs.Output)
+		}
+		genGoStubsOutputPath = filepath.Clean(filepath.Join(dirPath, e.Gen.GoStubs.Output))
+	}
+
+	provenanceTimestamp := strings.ToLower(e.Gen.Provenance.Timestamp)
+	switch provenanceTimestamp {
+	case "", ProvenanceTimestampNone, ProvenanceTimestampNow, ProvenanceTimestampSourceDateEpoch:
+	default:
+		return Config{}, fmt.Errorf("gen provenance timestamp must be one of %s, %s, %s: %s", ProvenanceTimestampNone, ProvenanceTimestampNow, ProvenanceTimestampSourceDateEpoch, e.Gen.Provenance.Timestamp)
+	}
+
+	docOutputPath := ""
+	if e.Doc.Output != "" {
+		if filepath.IsAbs(e.Doc.Output) {
+			return Config{}, fmt.Errorf("doc output must be relative: %s", e.Doc.Output)
+		}
+		docOutputPath = filepath.Clean(filepath.Join(dirPath, e.Doc.Output))
+	}
+	docFormat := strings.ToLower(e.Doc.Format)
+	switch docFormat {
+	case "", "markdown", "html":
+	default:
+		return Config{}, fmt.Errorf("doc format must be markdown or html: %s", e.Doc.Format)
+	}
+	if docFormat != "" && docOutputPath == "" {
+		return Config{}, fmt.Errorf("doc output must be set if doc format is set")
+	}
+
+	var disallowedImports map[string][]string
+	for pattern, disallowedPatterns := range e.Vet.DisallowedImports {
+		for _, p := range append([]string{pattern}, disallowedPatterns...) {
+			if p == "" || strings.Contains(strings.TrimSuffix(p, ".*"), "*") {
+				return Config{}, fmt.Errorf("vet disallowed_imports pattern must be a package optionally followed by .* but was %q", p)
+			}
+		}
+		if disallowedImports == nil {
+			disallowedImports = make(map[string][]string)
+		}
+		disallowedImports[pattern] = strs.DedupeSort(disallowedPatterns, nil)
+	}
+
+	formatIndent := ""
+	switch strings.ToLower(e.Format.Indent) {
+	case "":
+	case "2":
+		formatIndent = "  "
+	case "4":
+		formatIndent = "    "
+	case "tab":
+		formatIndent = "\t"
+	default:
+		return Config{}, fmt.Errorf("format indent must be 2, 4, or tab: %s", e.Format.Indent)
+	}
+	for name, templateText := range e.Format.FileOptionTemplates {
+		if !isFileOptionTemplateName(name) {
+			return Config{}, fmt.Errorf("format file_option_templates key must be one of %v: %s", protostrs.FileOptionTemplateNames, name)
+		}
+		if _, err := protostrs.FileOptionValue(templateText, "foo.v1", "foo.proto"); err != nil {
+			return Config{}, fmt.Errorf("format file_option_templates template for %s is invalid: %v", name, err)
+		}
+	}
+	if e.Format.TopLevelBlankLines < 0 || e.Format.TopLevelBlankLines > 3 {
+		return Config{}, fmt.Errorf("format top_level_blank_lines must be between 1 and 3: %d", e.Format.TopLevelBlankLines)
+	}
+
+	webhookURL := os.ExpandEnv(e.Webhook.URL)
+	if webhookURL != "" {
+		parsedURL, err := url.Parse(webhookURL)
+		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+			return Config{}, fmt.Errorf("webhook url must be an http or https URL: %s", e.Webhook.URL)
+		}
+	} else if e.Webhook.Template != "" || len(e.Webhook.Headers) > 0 {
+		return Config{}, fmt.Errorf("webhook url must be set if webhook template or headers are set")
+	}
+	if e.Webhook.Template != "" {
+		if _, err := webhook.ParseTemplate(e.Webhook.Template); err != nil {
+			return Config{}, fmt.Errorf("invalid webhook template: %v", err)
+		}
+	}
+	var webhookHeaders map[string]string
+	for key, value := range e.Webhook.Headers {
+		if webhookHeaders == nil {
+			webhookHeaders = make(map[string]string)
+		}
+		webhookHeaders[key] = getSecretReference(dirPath, os.ExpandEnv(value))
+	}
+
+	var grpcTargets map[string]GRPCTarget
+	for name, target := range e.GRPC.Targets {
+		if name == "" {
+			return Config{}, fmt.Errorf("grpc targets must have names")
+		}
+		if target.Address == "" {
+			return Config{}, fmt.Errorf("grpc target %s must have an address", name)
+		}
+		var headers map[string]string
+		for key, value := range target.Headers {
+			if headers == nil {
+				headers = make(map[string]string)
+			}
+			headers[key] = getSecretReference(dirPath, os.ExpandEnv(value))
+		}
+		var tlsConfig *GRPCTLSConfig
+		if target.TLS.Enabled {
+			if (target.TLS.Cert == "") != (target.TLS.Key == "") {
+				return Config{}, fmt.Errorf("grpc target %s must set both or neither of tls cert and key", name)
+			}
+			tlsConfig = &GRPCTLSConfig{
+				CACertPath:         getGRPCTLSFilePath(dirPath, target.TLS.CACert),
+				CertPath:           getGRPCTLSFilePath(dirPath, target.TLS.Cert),
+				KeyPath:            getGRPCTLSFilePath(dirPath, target.TLS.Key),
+				InsecureSkipVerify: target.TLS.InsecureSkipVerify,
+				ServerName:         target.TLS.ServerName,
+			}
+		} else if target.TLS.CACert != "" || target.TLS.Cert != "" || target.TLS.Key != "" || target.TLS.InsecureSkipVerify || target.TLS.ServerName != "" {
+			return Config{}, fmt.Errorf("grpc target %s must set tls enabled if other tls settings are set", name)
+		}
+		if grpcTargets == nil {
+			grpcTargets = make(map[string]GRPCTarget)
+		}
+		grpcTargets[name] = GRPCTarget{
+			Address:   getSecretReference(dirPath, os.ExpandEnv(target.Address)),
+			Headers:   headers,
+			AuthToken: getSecretReference(dirPath, os.ExpandEnv(target.AuthToken)),
+			TLS:       tlsConfig,
+		}
+	}
+
+	var breakIgnoreIDs []string
+	if len(e.Break.IgnoreIDs) > 0 {
+		breakIgnoreIDs = strs.DedupeSort(e.Break.IgnoreIDs, strings.ToUpper)
+	}
+	var breakIgnoreTypes []string
+	if len(e.Break.IgnoreTypes) > 0 {
+		breakIgnoreTypes = strs.DedupeSort(e.Break.IgnoreTypes, func(s string) string { return strings.TrimPrefix(s, ".") })
+	}
+	breakExceptionsFilePath := ""
+	if e.Break.ExceptionsFile != "" {
+		if filepath.IsAbs(e.Break.ExceptionsFile) {
+			return Config{}, fmt.Errorf("break exceptions_file must be relative: %s", e.Break.ExceptionsFile)
+		}
+		breakExceptionsFilePath = filepath.Clean(filepath.Join(dirPath, e.Break.ExceptionsFile))
+	}
+	var lintOverrides []LintOverride
+	for _, override := range e.Lint.Overrides {
+		if override.Path == "" {
+			return Config{}, fmt.Errorf("path required for lint overrides")
+		}
+		if _, err := strs.MatchGlob(override.Path, ""); err != nil {
+			return Config{}, fmt.Errorf("lint overrides path %s is invalid: %v", override.Path, err)
+		}
+		lintOverride := LintOverride{
+			Pattern:    override.Path,
+			IncludeIDs: strs.DedupeSort(override.IncludeIDs, strings.ToUpper),
+			ExcludeIDs: strs.DedupeSort(override.ExcludeIDs, strings.ToUpper),
+		}
+		if len(lintOverride.IncludeIDs) == 0 && len(lintOverride.ExcludeIDs) == 0 {
+			return Config{}, fmt.Errorf("include_ids or exclude_ids required for lint overrides path %s", override.Path)
+		}
+		if intersection := strs.Intersection(lintOverride.IncludeIDs, lintOverride.ExcludeIDs); len(intersection) > 0 {
+			return Config{}, fmt.Errorf("lint overrides path %s had intersection of %v between include_ids and exclude_ids", override.Path, intersection)
+		}
+		lintOverrides = append(lintOverrides, lintOverride)
+	}
+	var owners []Owner
+	for _, owner := range e.Owners {
+		if owner.Path == "" {
+			return Config{}, fmt.Errorf("path required for owners")
+		}
+		if owner.Team == "" {
+			return Config{}, fmt.Errorf("team required for owners path %s", owner.Path)
+		}
+		if _, err := strs.MatchGlob(owner.Path, ""); err != nil {
+			return Config{}, fmt.Errorf("owners path %s is invalid: %v", owner.Path, err)
+		}
+		owners = append(owners, Owner{
+			Pattern: owner.Path,
+			Team:    owner.Team,
+		})
+	}
 
 	config := Config{
 		DirPath:         dirPath,
 		ExcludePrefixes: excludePrefixes,
+		Languages:       languages,
 		Compile: CompileConfig{
-			ProtobufVersion:       e.ProtocVersion,
-			IncludePaths:          includePaths,
-			IncludeWellKnownTypes: e.ProtocIncludeWKT,
-			AllowUnusedImports:    e.AllowUnusedImports,
+			ProtobufVersion:           protobufVersion,
+			AutoProtobufVersion:       autoProtobufVersion,
+			ProtocBinPath:             protocBinPath,
+			ProtocWKTPath:             protocWKTPath,
+			IncludePaths:              includePaths,
+			Roots:                     roots,
+			IncludeWellKnownTypes:     e.ProtocIncludeWKT || e.GoogleapisVersion != "",
+			ExtraArgs:                 nilIfEmpty(e.Protoc.ExtraArgs),
+			AllowUnusedImports:        e.AllowUnusedImports,
+			WarningsAsErrors:          e.WarningsAsErrors,
+			Timeout:                   protocTimeout,
+			MaxOutputBytes:            e.Protoc.MaxOutputBytes,
+			Sandbox:                   e.Protoc.Sandbox,
+			SandboxEnv:                nilIfEmpty(strs.DedupeSort(e.Protoc.SandboxEnv, nil)),
+			ValidateVersion:           e.ProtocGenValidateVersion,
+			GogoProtobufVersion:       gogoProtobufVersion,
+			GRPCGatewayVersion:        grpcGatewayVersion,
+			ProtobufJavascriptVersion: protobufJavascriptVersion,
+			GoogleapisVersion:         e.GoogleapisVersion,
 		},
 		Create: CreateConfig{
 			DirPathToBasePackage: createDirPathToBasePackage,
+			Layouts:              createLayouts,
+			Header:               e.Create.Header,
 		},
 		Lint: LintConfig{
-			IDs:                 strs.DedupeSort(e.Lint.IDs, strings.ToUpper),
-			Group:               strings.ToLower(e.Lint.Group),
-			IncludeIDs:          strs.DedupeSort(e.Lint.IncludeIDs, strings.ToUpper),
-			ExcludeIDs:          strs.DedupeSort(e.Lint.ExcludeIDs, strings.ToUpper),
-			IgnoreIDToFilePaths: ignoreIDToFilePaths,
+			IDs:                       strs.DedupeSort(e.Lint.IDs, strings.ToUpper),
+			Group:                     strings.ToLower(e.Lint.Group),
+			IncludeIDs:                strs.DedupeSort(e.Lint.IncludeIDs, strings.ToUpper),
+			ExcludeIDs:                strs.DedupeSort(e.Lint.ExcludeIDs, strings.ToUpper),
+			IgnoreIDToFilePaths:       ignoreIDToFilePaths,
+			IDToSeverity:              idToSeverity,
+			EnumZeroValueSuffix:       enumZeroValueSuffix,
+			EnumValuePrefix:           enumValuePrefix,
+			ServiceNameSuffixes:       serviceNameSuffixes,
+			RPCNamePrefixes:           rpcNamePrefixes,
+			RequestNameTemplate:       e.Lint.Naming.RequestTemplate,
+			ResponseNameTemplate:      e.Lint.Naming.ResponseTemplate,
+			HotFields:                 hotFields,
+			MaxFieldsPerMessage:       e.Lint.Fields.MaxPerMessage,
+			StablePackageVersionsOnly: e.Lint.Packages.StableVersionsOnly,
+			ForbiddenImports:          forbiddenImports,
+			ForbiddenTypes:            forbiddenTypes,
+			Languages:                 languages,
+			CustomOptionNumberRanges:  customOptionNumberRanges,
+			SensitiveOption:           sensitiveOption,
+			SensitiveFieldPatterns:    sensitiveFieldPatterns,
+			LogExemptTypes:            logExemptTypes,
+			LogPackages:               logPackages,
+			Overrides:                 lintOverrides,
 		},
 		Gen: GenConfig{
 			GoPluginOptions: GenGoPluginOptions{
@@ -229,7 +820,57 @@ func externalConfigToConfig(e ExternalConfig, dirPath string) (Config, error) {
 				NoDefaultModifiers: e.Gen.GoOptions.NoDefaultModifiers,
 				ExtraModifiers:     e.Gen.GoOptions.ExtraModifiers,
 			},
-			Plugins: genPlugins,
+			Plugins:           genPlugins,
+			PluginDirPaths:    genPluginDirPaths,
+			GoStubsOutputPath: genGoStubsOutputPath,
+			Provenance: GenProvenanceConfig{
+				Enabled:   e.Gen.Provenance.Enabled,
+				License:   strings.TrimSpace(e.Gen.Provenance.License),
+				Timestamp: provenanceTimestamp,
+			},
+		},
+		JSON: JSONConfig{
+			EmitDefaults: e.JSON.EmitDefaults,
+			OrigName:     e.JSON.OrigName,
+			EnumsAsInts:  e.JSON.EnumsAsInts,
+			Indent:       e.JSON.Indent,
+		},
+		Modules: ModulesConfig{
+			RegistryURL: e.Modules.RegistryURL,
+			Name:        e.Modules.Name,
+			Deps:        moduleDeps,
+		},
+		Doc: DocConfig{
+			OutputPath: docOutputPath,
+			Format:     docFormat,
+		},
+		Vet: VetConfig{
+			DisallowedImports: disallowedImports,
+		},
+		Format: FormatConfig{
+			Indent:              formatIndent,
+			AlignFields:         e.Format.AlignFields,
+			AlignOptions:        e.Format.AlignOptions,
+			TopLevelBlankLines:  e.Format.TopLevelBlankLines,
+			CanonicalOrder:      e.Format.CanonicalOrder,
+			FileOptionTemplates: e.Format.FileOptionTemplates,
+		},
+		Break: BreakConfig{
+			IgnoreIDs:          breakIgnoreIDs,
+			IgnoreTypes:        breakIgnoreTypes,
+			ExceptionsFilePath: breakExceptionsFilePath,
+		},
+		Webhook: WebhookConfig{
+			URL:      webhookURL,
+			Template: e.Webhook.Template,
+			Headers:  webhookHeaders,
+		},
+		GRPC: GRPCConfig{
+			Targets: grpcTargets,
+		},
+		Owners: owners,
+		Workspace: WorkspaceConfig{
+			RepoDirPaths: workspaceRepoDirPaths,
 		},
 	}
 
@@ -303,3 +944,143 @@ func getExcludePrefixes(excludes []string, noDefaultExcludes bool, dirPath strin
 	}
 	return excludePrefixes, nil
 }
+
+// addDefaultPluginFlags adds each default flag of the form key=value or key
+// to the comma-separated flags if a flag with the same key is not set.
+func addDefaultPluginFlags(flags string, defaultFlags []string) string {
+	keys := make(map[string]struct{})
+	for _, flag := range strings.Split(flags, ",") {
+		keys[strings.SplitN(flag, "=", 2)[0]] = struct{}{}
+	}
+	for _, defaultFlag := range defaultFlags {
+		if _, ok := keys[strings.SplitN(defaultFlag, "=", 2)[0]]; ok {
+			continue
+		}
+		if flags != "" {
+			flags += ","
+		}
+		flags += defaultFlag
+	}
+	return flags
+}
+
+// parseTimeout parses a timeout from a config file, returning 0 if
+// the value is empty.
+func parseTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("must be positive but was %s", value)
+	}
+	return timeout, nil
+}
+
+// getSecretReference returns the value with the path of a file:// secret
+// reference made absolute, as paths are relative to the config file directory.
+func getSecretReference(dirPath string, value string) string {
+	if !strings.HasPrefix(value, secret.FilePrefix) {
+		return value
+	}
+	filePath := strings.TrimPrefix(value, secret.FilePrefix)
+	if filePath == "" || filepath.IsAbs(filePath) {
+		return value
+	}
+	return secret.FilePrefix + filepath.Join(dirPath, filePath)
+}
+
+// getGRPCTLSFilePath returns the absolute path of a grpc target TLS file,
+// which is relative to the config file directory if not absolute.
+func getGRPCTLSFilePath(dirPath string, filePath string) string {
+	if filePath == "" || filepath.IsAbs(filePath) {
+		return filePath
+	}
+	return filepath.Join(dirPath, filePath)
+}
+
+// maxFieldNumber is the largest Protobuf field number.
+const maxFieldNumber = 536870911
+
+// parseNumberRange parses a field number range of the form "start-end",
+// or a single field number.
+func parseNumberRange(value string) (NumberRange, error) {
+	startString, endString := value, value
+	if split := strings.SplitN(value, "-", 2); len(split) == 2 {
+		startString, endString = split[0], split[1]
+	}
+	start, err := strconv.Atoi(strings.TrimSpace(startString))
+	if err != nil {
+		return NumberRange{}, fmt.Errorf("%q is not a field number or a range of the form start-end", value)
+	}
+	end, err := strconv.Atoi(strings.TrimSpace(endString))
+	if err != nil {
+		return NumberRange{}, fmt.Errorf("%q is not a field number or a range of the form start-end", value)
+	}
+	if start < 1 || end > maxFieldNumber || start > end {
+		return NumberRange{}, fmt.Errorf("%q must be a range within 1 to %d", value, maxFieldNumber)
+	}
+	return NumberRange{Start: start, End: end}, nil
+}
+
+// validateProtocArgs makes sure the extra protoc args are flags, and that
+// they do not conflict with the flags prototool passes to protoc itself.
+func validateProtocArgs(args []string) error {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return fmt.Errorf("%q is not a flag", arg)
+		}
+		name := strings.SplitN(arg, "=", 2)[0]
+		switch {
+		case strings.HasPrefix(name, "-I"), name == "--proto_path":
+			return fmt.Errorf("%s is managed by prototool, use includes instead", name)
+		case strings.HasPrefix(name, "-o"), name == "--descriptor_set_out", name == "--include_imports":
+			return fmt.Errorf("%s is managed by prototool, use prototool descriptor-set instead", name)
+		case name == "--plugin", strings.HasSuffix(name, "_out"):
+			return fmt.Errorf("%s is managed by prototool, use gen plugins instead", name)
+		}
+	}
+	return nil
+}
+
+func nilIfEmpty(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
+
+func isFileOptionTemplateName(name string) bool {
+	for _, fileOptionTemplateName := range protostrs.FileOptionTemplateNames {
+		if name == fileOptionTemplateName {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidEnumValuePrefix(enumValuePrefix string) bool {
+	for _, validEnumValuePrefix := range protostrs.EnumValuePrefixes {
+		if enumValuePrefix == validEnumValuePrefix {
+			return true
+		}
+	}
+	return false
+}
+
+// getForbiddenAllowPatterns returns the allowed file patterns of a lint
+// forbidden import or type, which must be valid globs.
+func getForbiddenAllowPatterns(allowPatterns []string) ([]string, error) {
+	for _, allowPattern := range allowPatterns {
+		if _, err := strs.MatchGlob(allowPattern, ""); err != nil {
+			return nil, fmt.Errorf("lint forbidden allow path %s is invalid: %v", allowPattern, err)
+		}
+	}
+	if len(allowPatterns) == 0 {
+		return nil, nil
+	}
+	return strs.DedupeSort(allowPatterns, nil), nil
+}
@@ -24,10 +24,27 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 )
 
+// AutoProtocVersion is the protoc_version value that says to select
+// the protoc version from the features used by the files.
+const AutoProtocVersion = "auto"
+
+const (
+	// ProvenanceTimestampNone says to not put a timestamp in provenance
+	// headers, so that generated files are reproducible.
+	ProvenanceTimestampNone = "none"
+	// ProvenanceTimestampNow says to put the current time in provenance
+	// headers.
+	ProvenanceTimestampNow = "now"
+	// ProvenanceTimestampSourceDateEpoch says to put the time from the
+	// SOURCE_DATE_EPOCH environment variable in provenance headers.
+	ProvenanceTimestampSourceDateEpoch = "source_date_epoch"
+)
+
 const (
 	// DefaultConfigFilename is the default config filename.
 	DefaultConfigFilename = "prototool.yaml"
@@ -61,6 +78,37 @@ var (
 		"gogo": GenPluginTypeGogo,
 	}
 
+	_genPluginPresets = map[string]genPluginPreset{
+		"gogofast": {
+			genPluginType: GenPluginTypeGogo,
+			dependency:    genPluginPresetDependencyGogoProtobuf,
+		},
+		"gogofaster": {
+			genPluginType: GenPluginTypeGogo,
+			dependency:    genPluginPresetDependencyGogoProtobuf,
+		},
+		"gogoslick": {
+			genPluginType: GenPluginTypeGogo,
+			dependency:    genPluginPresetDependencyGogoProtobuf,
+		},
+		// the generated gateway code uses github.com/golang/protobuf
+		"grpc-gateway": {
+			genPluginType: GenPluginTypeGo,
+			dependency:    genPluginPresetDependencyGRPCGateway,
+		},
+		// merge all OpenAPI output into one file unless configured otherwise
+		"openapiv2": {
+			dependency:   genPluginPresetDependencyGRPCGateway,
+			defaultFlags: []string{"allow_merge=true"},
+		},
+		"js": {
+			dependency:   genPluginPresetDependencyProtobufJavascript,
+			defaultFlags: []string{"import_style=commonjs", "binary"},
+		},
+		// protoc-gen-ts is resolved from node_modules
+		"ts": {},
+	}
+
 	_genPluginTypeToIsGo = map[GenPluginType]bool{
 		GenPluginTypeNone: false,
 		GenPluginTypeGo:   true,
@@ -111,6 +159,23 @@ func ParseGenPluginType(s string) (GenPluginType, error) {
 	return genPluginType, nil
 }
 
+type genPluginPresetDependency int
+
+const (
+	genPluginPresetDependencyNone genPluginPresetDependency = iota
+	genPluginPresetDependencyGogoProtobuf
+	genPluginPresetDependencyGRPCGateway
+	genPluginPresetDependencyProtobufJavascript
+)
+
+type genPluginPreset struct {
+	genPluginType GenPluginType
+	// the downloaded dependency whose version is defaulted if the preset is used
+	dependency genPluginPresetDependency
+	// flags to add to the plugin flags if a flag with the same key is not set
+	defaultFlags []string
+}
+
 // Config is the main config.
 //
 // Configs are derived from ExternalConfigs, which represent the Config
@@ -131,6 +196,11 @@ type Config struct {
 	// Expected to be absolute paths.
 	// Expected to be unique.
 	ExcludePrefixes []string
+	// The languages that code is generated for, which are in
+	// protostrs.Languages. If set, only the file options for these languages
+	// are required by lint, set by format with rewrite, and set by create.
+	// Expected to be unique and sorted.
+	Languages []string
 	// The compile config.
 	Compile CompileConfig
 	// The create config.
@@ -141,6 +211,27 @@ type Config struct {
 	Lint LintConfig
 	// The gen config.
 	Gen GenConfig
+	// The JSON config.
+	JSON JSONConfig
+	// The modules config.
+	Modules ModulesConfig
+	// The doc config.
+	Doc DocConfig
+	// The vet config.
+	Vet VetConfig
+	// The format config.
+	Format FormatConfig
+	// The break config.
+	Break BreakConfig
+	// The webhook config.
+	Webhook WebhookConfig
+	// The grpc config.
+	GRPC GRPCConfig
+	// The owners of files, in the order given in the config file.
+	// If more than one Owner matches a file, the last one is used.
+	Owners []Owner
+	// The workspace config.
+	Workspace WorkspaceConfig
 }
 
 // CompileConfig is the compile config.
@@ -149,14 +240,87 @@ type CompileConfig struct {
 	// Must have a valid protoc zip file asset, so for example 3.5.0 is a valid version
 	// but 3.5.0.1 is not.
 	ProtobufVersion string
+	// AutoProtobufVersion says to select the minimum protoc version that
+	// supports the features used by the files, or the default version if
+	// that is newer, instead of using ProtobufVersion, which is empty.
+	AutoProtobufVersion bool
+	// ProtocBinPath is the protoc binary to use instead of downloading protoc,
+	// in which case ProtobufVersion is not used to download or check protoc.
+	// Expected to be absolute, or a name with no path separators to look up in PATH.
+	// If empty, protoc is downloaded.
+	ProtocBinPath string
+	// ProtocWKTPath is the path to include for the well-known types.
+	// Expected to be absolute.
+	// If empty, the well-known types from the downloaded protoc are used, or
+	// the include directory next to the bin directory of ProtocBinPath if set.
+	ProtocWKTPath string
 	// IncludePaths are the additional paths to include with -I to protoc.
 	// Expected to be absolute paths.
 	// Expected to be unique.
 	IncludePaths []string
+	// Roots are the proto roots of a multi-root workspace.
+	// If set, each root is passed to protoc with -I instead of the
+	// config directory, so files import each other relative to the
+	// root they are in. All roots are on the include path for every
+	// file so that files can import files in other roots.
+	// Expected to be sorted by DirPath.
+	Roots []Root
 	// IncludeWellKnownTypes says to add the Google well-known types with -I to protoc.
 	IncludeWellKnownTypes bool
+	// ExtraArgs are additional flags passed as-is to every protoc invocation,
+	// for protoc capabilities that prototool does not otherwise expose, such as
+	// experimental flags or --descriptor_set_in.
+	// Flags that prototool manages itself, such as -I and --*_out, are not allowed.
+	ExtraArgs []string
 	// AllowUnusedImports says to not error when an import is not used.
+	// Unused imports will be warnings instead.
 	AllowUnusedImports bool
+	// WarningsAsErrors says to treat protoc warnings as errors.
+	WarningsAsErrors bool
+	// Timeout is the maximum time each protoc invocation, including its
+	// plugins, can run before it is killed.
+	// If 0, there is no timeout.
+	Timeout time.Duration
+	// MaxOutputBytes is the maximum number of bytes protoc and plugins can
+	// write to stderr before protoc is killed.
+	// If 0, there is no maximum.
+	MaxOutputBytes int
+	// Sandbox says to run protoc and plugins in a temporary working directory
+	// with all environment variables cleared except PATH and SandboxEnv.
+	Sandbox bool
+	// SandboxEnv are the names of the environment variables to keep when
+	// Sandbox is set, in addition to PATH.
+	// Expected to be unique and sorted.
+	SandboxEnv []string
+	// The protoc-gen-validate version to use from
+	// https://github.com/envoyproxy/protoc-gen-validate/releases.
+	// If set, validate/validate.proto is added to the include path, and
+	// the plugin named validate uses the downloaded protoc-gen-validate
+	// unless a path is set with plugin_overrides.
+	ValidateVersion string
+	// The github.com/gogo/protobuf version to use gogoproto/gogo.proto from.
+	// If set, gogoproto/gogo.proto is added to the include path, and go
+	// and gogo plugins map it to github.com/gogo/protobuf/gogoproto.
+	// This is set to vars.DefaultGogoProtobufVersion if a plugin uses
+	// a gogo preset and no version is set.
+	GogoProtobufVersion string
+	// The github.com/grpc-ecosystem/grpc-gateway version to download
+	// protoc-gen-grpc-gateway and protoc-gen-openapiv2 for.
+	// If set, google/api/annotations.proto, google/api/http.proto, and the
+	// protoc-gen-openapiv2 options are added to the include path.
+	// This is set to vars.DefaultGRPCGatewayVersion if a plugin uses
+	// the grpc-gateway or openapiv2 preset and no version is set.
+	GRPCGatewayVersion string
+	// The github.com/protocolbuffers/protobuf-javascript version to download
+	// protoc-gen-js for.
+	// This is set to vars.DefaultProtobufJavascriptVersion if a plugin uses
+	// the js preset and no version is set.
+	ProtobufJavascriptVersion string
+	// The github.com/googleapis/googleapis commit to take the google/...
+	// .proto files from, such as google/type/date.proto.
+	// If set, the googleapis files and the well-known types they import
+	// are added to the include path.
+	GoogleapisVersion string
 }
 
 // CreateConfig is the create config.
@@ -164,6 +328,34 @@ type CreateConfig struct {
 	// The map from directory to the package to use as the base.
 	// Directories expected to be absolute paths.
 	DirPathToBasePackage map[string]string
+	// The layouts to match the directories of created files against, in
+	// order. The first matching layout takes precedence over
+	// DirPathToBasePackage.
+	Layouts []CreateLayout
+	// The header to add to created files as comments, where <name> is
+	// replaced with the value of the variable, such as <author> or <date>.
+	Header string
+}
+
+// CreateLayout maps directories that match a path pattern to the package
+// and file options of the files created in them.
+//
+// The path is a relative path with / separators where <name> matches any
+// part of a single directory name, for example idl/<team>/<name>/v<version>.
+// The package and option values are templates where <name> is replaced with
+// the matched value.
+type CreateLayout struct {
+	// The absolute path of the directory the path pattern is relative to,
+	// which is the directory of the config file.
+	DirPath string
+	// Expected to be relative with / separators.
+	Path string
+	// Expected to be set.
+	Package string
+	// The map from file option name to value, for example go_package.
+	// The go_package and java_package options replace the values inferred
+	// from the package.
+	Options map[string]string
 }
 
 // LintConfig is the lint config.
@@ -195,6 +387,287 @@ type LintConfig struct {
 	// IDs expected to be all upper-case.
 	// File paths expected to be absolute paths.
 	IgnoreIDToFilePaths map[string][]string
+	// IDToSeverity is the map of ID to the severity of its failures.
+	// IDs expected to be all upper-case.
+	// Severities expected to be one of error, warning, or info.
+	// IDs that are not set are errors.
+	IDToSeverity map[string]string
+	// EnumZeroValueSuffix is the suffix that enum zero values are expected to have.
+	// Expected to be all upper-case.
+	// If empty, the default for the lint group is used.
+	EnumZeroValueSuffix string
+	// EnumValuePrefix is the prefix that enum values are expected to have.
+	// Expected to be empty or one of protostrs.EnumValuePrefixes.
+	// If empty, the default for the lint group is used.
+	EnumValuePrefix string
+	// ServiceNameSuffixes are the suffixes that service names are expected
+	// to have one of.
+	// If empty, the defaults are used.
+	ServiceNameSuffixes []string
+	// RPCNamePrefixes are the prefixes that RPC names are expected to have one of.
+	// If empty, the defaults are used.
+	RPCNamePrefixes []string
+	// RequestNameTemplate is the template for the names of request types,
+	// executed with a protostrs.RPCTypeTemplateData.
+	// If empty, protostrs.DefaultRequestNameTemplate is used.
+	RequestNameTemplate string
+	// ResponseNameTemplate is the template for the names of response types,
+	// executed with a protostrs.RPCTypeTemplateData.
+	// If empty, protostrs.DefaultResponseNameTemplate is used.
+	ResponseNameTemplate string
+	// HotFields is the map of fully-qualified message name to the names of
+	// the fields of the message that are expected to use field numbers 1 to 15.
+	// Messages not in the map are not checked.
+	HotFields map[string][]string
+	// MaxFieldsPerMessage is the maximum number of fields a message can have.
+	// If 0, the default is used.
+	MaxFieldsPerMessage int
+	// StablePackageVersionsOnly says that package versions are expected to
+	// not have an alpha or beta suffix.
+	StablePackageVersionsOnly bool
+	// ForbiddenImports are the imports that files are not expected to have.
+	ForbiddenImports []ForbiddenRule
+	// ForbiddenTypes are the types that files are not expected to use for
+	// fields or as the request or response types of RPCs.
+	ForbiddenTypes []ForbiddenRule
+	// Languages are the languages from Config.Languages.
+	Languages []string
+	// CustomOptionNumberRanges are the ranges of field numbers that custom
+	// options are expected to use.
+	// If empty, the defaults are used.
+	CustomOptionNumberRanges []NumberRange
+	// SensitiveOption is the fully-qualified name of the option that
+	// sensitive fields are expected to set, without parentheses.
+	// If empty, sensitive fields are not checked.
+	SensitiveOption string
+	// SensitiveFieldPatterns are the globs of the lowercase names of
+	// sensitive fields, matched with path.Match.
+	// If empty, the defaults are used.
+	SensitiveFieldPatterns []string
+	// LogExemptTypes are the types that are exempt from logging, which
+	// are not expected to be used by fields or RPCs in LogPackages.
+	// These are fully-qualified type names without a leading dot, or a
+	// package followed by .* which matches all types in the package and
+	// the packages within it.
+	LogExemptTypes []string
+	// LogPackages are the packages whose messages are logged, as package
+	// names or a package followed by .* which matches the package and
+	// the packages within it.
+	LogPackages []string
+	// Overrides change the linters used for the files matching a pattern.
+	// If more than one Override matches a file, they are applied in order,
+	// so the last one that includes or excludes an ID is used.
+	Overrides []LintOverride
+}
+
+// LintOverride changes the linters used for the files matching a pattern.
+type LintOverride struct {
+	// The slash-separated glob pattern relative to Config.DirPath,
+	// matched with strs.MatchGlob.
+	Pattern string
+	// IncludeIDs are the linter IDs to use for the files in addition to
+	// the linters of the LintConfig.
+	// Expected to be all uppercase.
+	// Expected to be unique.
+	// Expected to have no overlap with ExcludeIDs.
+	IncludeIDs []string
+	// ExcludeIDs are the linter IDs to not use for the files.
+	// Expected to be all uppercase.
+	// Expected to be unique.
+	// Expected to have no overlap with IncludeIDs.
+	ExcludeIDs []string
+}
+
+// NumberRange is an inclusive range of field numbers.
+type NumberRange struct {
+	Start int
+	End   int
+}
+
+// ForbiddenRule forbids the imports or types that match a pattern in all
+// files except the allowed files.
+type ForbiddenRule struct {
+	// Pattern is the pattern of the forbidden imports or types.
+	//
+	// For imports, this is a glob matched against the import path with
+	// strs.MatchGlob. For types, this is a fully-qualified type name
+	// without a leading dot, or a package followed by .* which matches
+	// all types in the package and the packages within it.
+	Pattern string
+	// AllowDirPath is the absolute path of the directory that
+	// AllowPatterns are relative to, which is the directory of the
+	// config file.
+	AllowDirPath string
+	// AllowPatterns are the globs of the files that may use the imports
+	// or types, matched against the file path relative to AllowDirPath
+	// with strs.MatchGlob.
+	AllowPatterns []string
+}
+
+// JSONConfig is the config for JSON output of messages, such as for
+// binary-to-json and grpc.
+type JSONConfig struct {
+	// EmitDefaults says to output fields with default values.
+	EmitDefaults bool
+	// OrigName says to use the original proto field names instead of lowerCamelCase.
+	OrigName bool
+	// EnumsAsInts says to output enum values as integers instead of names.
+	EnumsAsInts bool
+	// Indent is the number of spaces to indent with.
+	// If 0, the default for the command is used, which is no indentation
+	// for conversions and two spaces for grpc. If negative, output is not indented.
+	Indent int
+}
+
+// ModulesConfig is the config for modules, which are versioned
+// FileDescriptorSets published to and fetched from an HTTP artifact store.
+type ModulesConfig struct {
+	// The base URL of the artifact store.
+	RegistryURL string
+	// The name to publish the files in this directory as, for example acme/users.
+	Name string
+	// The modules to depend on.
+	// These will be sorted by name if returned from this package.
+	Deps []ModuleDep
+}
+
+// ModuleDep is a module to depend on.
+type ModuleDep struct {
+	// The name of the module.
+	Name string
+	// The version of the module.
+	Version string
+}
+
+// DocConfig is the doc config.
+type DocConfig struct {
+	// The path to write the documentation to.
+	// Expected to be absolute.
+	// If empty, no documentation is generated.
+	OutputPath string
+	// The format, either markdown or html.
+	// If empty, markdown is used.
+	Format string
+}
+
+// VetConfig is the vet config.
+type VetConfig struct {
+	// DisallowedImports is the map of package pattern to the package
+	// patterns that matching packages may not import, directly or
+	// transitively. A pattern is either a package, or a package followed
+	// by .* which matches the package and all packages within it.
+	// If empty, there are no disallowed imports.
+	DisallowedImports map[string][]string
+}
+
+// FormatConfig is the format config.
+type FormatConfig struct {
+	// The string to indent with, either two spaces, four spaces, or a tab.
+	// If empty, two spaces are used.
+	Indent string
+	// AlignFields says to align the = signs of consecutive fields and enum values.
+	AlignFields bool
+	// AlignOptions says to align the opening brackets of the options of
+	// consecutive fields and enum values.
+	AlignOptions bool
+	// TopLevelBlankLines is the number of blank lines between top-level elements.
+	// If 0, one blank line is used.
+	TopLevelBlankLines int
+	// CanonicalOrder says to order imports with regular imports first, then public
+	// imports, then weak imports, and to order file options with built-in options
+	// before custom options.
+	CanonicalOrder bool
+	// FileOptionTemplates are the templates to compute the values of file options
+	// from when formatting with rewrite, overriding the defaults.
+	// The keys are file option names in protostrs.FileOptionTemplateNames, and
+	// the templates are executed with a protostrs.FileOptionTemplateData.
+	FileOptionTemplates map[string]string
+}
+
+// BreakConfig is the break config.
+type BreakConfig struct {
+	// IgnoreIDs are the IDs of the breaking change checks to not run.
+	// Expected to be all uppercase.
+	// Expected to be unique.
+	IgnoreIDs []string
+	// IgnoreTypes are the fully-qualified types, for example foo.v1.Bar,
+	// to not check for breaking changes, including the types nested within them.
+	// Expected to be unique.
+	IgnoreTypes []string
+	// ExceptionsFilePath is the path to the file of one-time exceptions
+	// for intentional breaking changes.
+	// Expected to be absolute.
+	// If empty, there are no exceptions.
+	ExceptionsFilePath string
+}
+
+// WebhookConfig is the config for the webhook that compile, lint, and
+// break check failures are posted to.
+type WebhookConfig struct {
+	// The URL to post failures to, with environment variables expanded.
+	// If empty, failures are not posted.
+	URL string
+	// The template for the request body, executed with a webhook.Payload.
+	// Expected to be valid per webhook.ParseTemplate.
+	// If empty, the webhook.Payload is posted as JSON.
+	Template string
+	// The headers to set on requests, with environment variables expanded.
+	// Values may be secret references per secret.IsReference.
+	Headers map[string]string
+}
+
+// GRPCConfig is the grpc config.
+type GRPCConfig struct {
+	// The map from target name to target, which the grpc command
+	// connects to instead of an address.
+	Targets map[string]GRPCTarget
+}
+
+// GRPCTarget is a named endpoint for the grpc command.
+type GRPCTarget struct {
+	// The address to connect to, with environment variables expanded.
+	// This may be a secret reference per secret.IsReference.
+	Address string
+	// The headers to set on calls, with environment variables expanded.
+	// Values may be secret references per secret.IsReference.
+	// Headers given on the command line take precedence.
+	Headers map[string]string
+	// The token to attach to calls as the header "authorization: Bearer token",
+	// with environment variables expanded, unless a token is given on the
+	// command line.
+	// This may be a secret reference per secret.IsReference.
+	AuthToken string
+	// The TLS config.
+	// If nil, connections do not use TLS.
+	TLS *GRPCTLSConfig
+}
+
+// GRPCTLSConfig is the TLS config of a GRPCTarget.
+type GRPCTLSConfig struct {
+	// The path to the PEM file of the certificate authorities to verify
+	// the server with.
+	// Expected to be absolute.
+	// If empty, the system certificate authorities are used.
+	CACertPath string
+	// The paths to the PEM files of the client certificate and key.
+	// Expected to be absolute.
+	// Either both or neither are set.
+	CertPath string
+	KeyPath  string
+	// Do not verify the server certificate.
+	InsecureSkipVerify bool
+	// The name to verify the server certificate with.
+	// If empty, the host of the address is used.
+	ServerName string
+}
+
+// Owner is the team that owns the files matching a pattern.
+type Owner struct {
+	// The slash-separated glob pattern relative to DirPath,
+	// matched with strs.MatchGlob.
+	Pattern string
+	// The name of the team.
+	Team string
 }
 
 // GenConfig is the gen config.
@@ -204,6 +677,32 @@ type GenConfig struct {
 	// The plugins.
 	// These will be sorted by name if returned from this package.
 	Plugins []GenPlugin
+	// PluginDirPaths are the directories to look for protoc-gen-NAME in, in order,
+	// for plugins that do not have a path and are not built into protoc.
+	// If set, plugins are not looked up in PATH.
+	// Expected to be absolute paths.
+	PluginDirPaths []string
+	// GoStubsOutputPath is the directory to write Go client interfaces and
+	// stub implementations for the services to, in the directory structure
+	// of the .proto files.
+	// Expected to be absolute.
+	// If empty, no stubs are generated.
+	GoStubsOutputPath string
+	// Provenance is the provenance header to add to generated files.
+	Provenance GenProvenanceConfig
+}
+
+// GenProvenanceConfig is the config for stamping generated files with
+// a header that traces them back to the inputs they were generated from.
+type GenProvenanceConfig struct {
+	// Enabled says to stamp generated files.
+	Enabled bool
+	// License is text to put at the top of the header, such as a
+	// copyright notice. May have multiple lines.
+	License string
+	// Timestamp is the timestamp policy, one of the ProvenanceTimestamp
+	// values. Empty is the same as ProvenanceTimestampNone.
+	Timestamp string
 }
 
 // GenGoPluginOptions are options for go plugins.
@@ -241,6 +740,17 @@ type GenPlugin struct {
 	// The path to output to.
 	// Must be relative in a config file.
 	OutputPath OutputPath
+	// The preset, if any, such as "gogofaster". Presets set the type,
+	// default the name to the preset, and always map the Well-Known Types
+	// to their packages for the plugin.
+	Preset string
+	// ProtocArgs are additional flags passed as-is to protoc only when
+	// generating with this plugin, for example --NAME_opt values.
+	// These are passed after CompileConfig.ExtraArgs.
+	ProtocArgs []string
+	// Timeout is the maximum time protoc can run when generating with
+	// this plugin, overriding CompileConfig.Timeout if set.
+	Timeout time.Duration
 }
 
 // OutputPath is an output path.
@@ -254,18 +764,66 @@ type OutputPath struct {
 	AbsPath string
 }
 
+// WorkspaceConfig is the config of a workspace of multiple repositories.
+type WorkspaceConfig struct {
+	// The directories of the repositories in the workspace. The files in
+	// these directories are compiled and linted together with the files in
+	// the directory of the config file using this config, and the roots of
+	// the repositories are in CompileConfig.Roots.
+	// Expected to be absolute.
+	RepoDirPaths []string
+}
+
+// Root is a proto root of a multi-root workspace.
+type Root struct {
+	// The path to the root.
+	// Expected to be absolute.
+	DirPath string
+	// IncludePaths are the additional paths to include with -I to protoc
+	// only when compiling files in this root.
+	// Expected to be absolute paths.
+	// Expected to be unique.
+	IncludePaths []string
+}
+
 // ExternalConfig is the external representation of Config.
 //
 // It is meant to be set by a YAML or JSON config file, or flags.
 type ExternalConfig struct {
-	Excludes           []string `json:"excludes,omitempty" yaml:"excludes,omitempty"`
-	NoDefaultExcludes  bool     `json:"no_default_excludes,omitempty" yaml:"no_default_excludes,omitempty"`
-	ProtocVersion      string   `json:"protoc_version,omitempty" yaml:"protoc_version,omitempty"`
-	ProtocIncludes     []string `json:"protoc_includes,omitempty" yaml:"protoc_includes,omitempty"`
-	ProtocIncludeWKT   bool     `json:"protoc_include_wkt,omitempty" yaml:"protoc_include_wkt,omitempty"`
-	AllowUnusedImports bool     `json:"allow_unused_imports,omitempty" yaml:"allow_unused_imports,omitempty"`
-	Create             struct {
+	Excludes                  []string `json:"excludes,omitempty" yaml:"excludes,omitempty"`
+	NoDefaultExcludes         bool     `json:"no_default_excludes,omitempty" yaml:"no_default_excludes,omitempty"`
+	ProtocVersion             string   `json:"protoc_version,omitempty" yaml:"protoc_version,omitempty"`
+	ProtocIncludes            []string `json:"protoc_includes,omitempty" yaml:"protoc_includes,omitempty"`
+	ProtocIncludeWKT          bool     `json:"protoc_include_wkt,omitempty" yaml:"protoc_include_wkt,omitempty"`
+	ProtocGenValidateVersion  string   `json:"protoc_gen_validate_version,omitempty" yaml:"protoc_gen_validate_version,omitempty"`
+	GogoProtobufVersion       string   `json:"gogo_protobuf_version,omitempty" yaml:"gogo_protobuf_version,omitempty"`
+	GRPCGatewayVersion        string   `json:"grpc_gateway_version,omitempty" yaml:"grpc_gateway_version,omitempty"`
+	ProtobufJavascriptVersion string   `json:"protobuf_javascript_version,omitempty" yaml:"protobuf_javascript_version,omitempty"`
+	GoogleapisVersion         string   `json:"googleapis_version,omitempty" yaml:"googleapis_version,omitempty"`
+	AllowUnusedImports        bool     `json:"allow_unused_imports,omitempty" yaml:"allow_unused_imports,omitempty"`
+	WarningsAsErrors          bool     `json:"warnings_as_errors,omitempty" yaml:"warnings_as_errors,omitempty"`
+	Languages                 []string `json:"languages,omitempty" yaml:"languages,omitempty"`
+	Protoc                    struct {
+		BinPath        string   `json:"bin_path,omitempty" yaml:"bin_path,omitempty"`
+		WKTPath        string   `json:"wkt_path,omitempty" yaml:"wkt_path,omitempty"`
+		ExtraArgs      []string `json:"extra_args,omitempty" yaml:"extra_args,omitempty"`
+		Timeout        string   `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+		MaxOutputBytes int      `json:"max_output_bytes,omitempty" yaml:"max_output_bytes,omitempty"`
+		Sandbox        bool     `json:"sandbox,omitempty" yaml:"sandbox,omitempty"`
+		SandboxEnv     []string `json:"sandbox_env,omitempty" yaml:"sandbox_env,omitempty"`
+	} `json:"protoc,omitempty" yaml:"protoc,omitempty"`
+	ProtocRoots []struct {
+		Path     string   `json:"path,omitempty" yaml:"path,omitempty"`
+		Includes []string `json:"includes,omitempty" yaml:"includes,omitempty"`
+	} `json:"protoc_roots,omitempty" yaml:"protoc_roots,omitempty"`
+	Create struct {
 		DirToBasePackage map[string]string `json:"dir_to_base_package,omitempty" yaml:"dir_to_base_package,omitempty"`
+		Layouts          []struct {
+			Path    string            `json:"path,omitempty" yaml:"path,omitempty"`
+			Package string            `json:"package,omitempty" yaml:"package,omitempty"`
+			Options map[string]string `json:"options,omitempty" yaml:"options,omitempty"`
+		} `json:"layouts,omitempty" yaml:"layouts,omitempty"`
+		Header string `json:"header,omitempty" yaml:"header,omitempty"`
 	} `json:"create,omitempty" yaml:"create,omitempty"`
 	Lint struct {
 		IDs             []string            `json:"ids,omitempty" yaml:"ids,omitempty"`
@@ -273,6 +831,48 @@ type ExternalConfig struct {
 		IncludeIDs      []string            `json:"include_ids,omitempty" yaml:"include_ids,omitempty"`
 		ExcludeIDs      []string            `json:"exclude_ids,omitempty" yaml:"exclude_ids,omitempty"`
 		IgnoreIDToFiles map[string][]string `json:"ignore_id_to_files,omitempty" yaml:"ignore_id_to_files,omitempty"`
+		IDToSeverity    map[string]string   `json:"id_to_severity,omitempty" yaml:"id_to_severity,omitempty"`
+		Enums           struct {
+			ZeroValueSuffix string `json:"zero_value_suffix,omitempty" yaml:"zero_value_suffix,omitempty"`
+			ValuePrefix     string `json:"value_prefix,omitempty" yaml:"value_prefix,omitempty"`
+		} `json:"enums,omitempty" yaml:"enums,omitempty"`
+		Naming struct {
+			ServiceSuffixes  []string `json:"service_suffixes,omitempty" yaml:"service_suffixes,omitempty"`
+			RPCPrefixes      []string `json:"rpc_prefixes,omitempty" yaml:"rpc_prefixes,omitempty"`
+			RequestTemplate  string   `json:"request_template,omitempty" yaml:"request_template,omitempty"`
+			ResponseTemplate string   `json:"response_template,omitempty" yaml:"response_template,omitempty"`
+		} `json:"naming,omitempty" yaml:"naming,omitempty"`
+		Fields struct {
+			HotFields     map[string][]string `json:"hot_fields,omitempty" yaml:"hot_fields,omitempty"`
+			MaxPerMessage int                 `json:"max_per_message,omitempty" yaml:"max_per_message,omitempty"`
+		} `json:"fields,omitempty" yaml:"fields,omitempty"`
+		Packages struct {
+			StableVersionsOnly bool `json:"stable_versions_only,omitempty" yaml:"stable_versions_only,omitempty"`
+		} `json:"packages,omitempty" yaml:"packages,omitempty"`
+		Options struct {
+			NumberRanges []string `json:"number_ranges,omitempty" yaml:"number_ranges,omitempty"`
+		} `json:"options,omitempty" yaml:"options,omitempty"`
+		Sensitive struct {
+			Option         string   `json:"option,omitempty" yaml:"option,omitempty"`
+			FieldPatterns  []string `json:"field_patterns,omitempty" yaml:"field_patterns,omitempty"`
+			LogExemptTypes []string `json:"log_exempt_types,omitempty" yaml:"log_exempt_types,omitempty"`
+			LogPackages    []string `json:"log_packages,omitempty" yaml:"log_packages,omitempty"`
+		} `json:"sensitive,omitempty" yaml:"sensitive,omitempty"`
+		Forbidden struct {
+			Imports []struct {
+				Path  string   `json:"path,omitempty" yaml:"path,omitempty"`
+				Allow []string `json:"allow,omitempty" yaml:"allow,omitempty"`
+			} `json:"imports,omitempty" yaml:"imports,omitempty"`
+			Types []struct {
+				Name  string   `json:"name,omitempty" yaml:"name,omitempty"`
+				Allow []string `json:"allow,omitempty" yaml:"allow,omitempty"`
+			} `json:"types,omitempty" yaml:"types,omitempty"`
+		} `json:"forbidden,omitempty" yaml:"forbidden,omitempty"`
+		Overrides []struct {
+			Path       string   `json:"path,omitempty" yaml:"path,omitempty"`
+			IncludeIDs []string `json:"include_ids,omitempty" yaml:"include_ids,omitempty"`
+			ExcludeIDs []string `json:"exclude_ids,omitempty" yaml:"exclude_ids,omitempty"`
+		} `json:"overrides,omitempty" yaml:"overrides,omitempty"`
 	} `json:"lint,omitempty" yaml:"lint,omitempty"`
 	Gen struct {
 		GoOptions struct {
@@ -280,14 +880,91 @@ type ExternalConfig struct {
 			NoDefaultModifiers bool              `json:"no_default_modifiers,omitempty" yaml:"no_default_modifiers,omitempty"`
 			ExtraModifiers     map[string]string `json:"extra_modifiers,omitempty" yaml:"extra_modifiers,omitempty"`
 		} `json:"go_options,omitempty" yaml:"go_options,omitempty"`
+		GoStubs struct {
+			Output string `json:"output,omitempty" yaml:"output,omitempty"`
+		} `json:"go_stubs,omitempty" yaml:"go_stubs,omitempty"`
+		Provenance struct {
+			Enabled   bool   `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+			License   string `json:"license,omitempty" yaml:"license,omitempty"`
+			Timestamp string `json:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+		} `json:"provenance,omitempty" yaml:"provenance,omitempty"`
 		PluginOverrides map[string]string `json:"plugin_overrides,omitempty" yaml:"plugin_overrides,omitempty"`
+		PluginDirs      []string          `json:"plugin_dirs,omitempty" yaml:"plugin_dirs,omitempty"`
 		Plugins         []struct {
-			Name   string `json:"name,omitempty" yaml:"name,omitempty"`
-			Type   string `json:"type,omitempty" yaml:"type,omitempty"`
-			Flags  string `json:"flags,omitempty" yaml:"flags,omitempty"`
-			Output string `json:"output,omitempty" yaml:"output,omitempty"`
+			Name       string   `json:"name,omitempty" yaml:"name,omitempty"`
+			Path       string   `json:"path,omitempty" yaml:"path,omitempty"`
+			Type       string   `json:"type,omitempty" yaml:"type,omitempty"`
+			Flags      string   `json:"flags,omitempty" yaml:"flags,omitempty"`
+			Output     string   `json:"output,omitempty" yaml:"output,omitempty"`
+			Preset     string   `json:"preset,omitempty" yaml:"preset,omitempty"`
+			ProtocArgs []string `json:"protoc_args,omitempty" yaml:"protoc_args,omitempty"`
+			Timeout    string   `json:"timeout,omitempty" yaml:"timeout,omitempty"`
 		} `json:"plugins,omitempty" yaml:"plugins,omitempty"`
 	} `json:"gen,omitempty" yaml:"gen,omitempty"`
+	JSON struct {
+		EmitDefaults bool `json:"emit_defaults,omitempty" yaml:"emit_defaults,omitempty"`
+		OrigName     bool `json:"orig_name,omitempty" yaml:"orig_name,omitempty"`
+		EnumsAsInts  bool `json:"enums_as_ints,omitempty" yaml:"enums_as_ints,omitempty"`
+		Indent       int  `json:"indent,omitempty" yaml:"indent,omitempty"`
+	} `json:"json,omitempty" yaml:"json,omitempty"`
+	Modules struct {
+		RegistryURL string `json:"registry_url,omitempty" yaml:"registry_url,omitempty"`
+		Name        string `json:"name,omitempty" yaml:"name,omitempty"`
+		Deps        []struct {
+			Name    string `json:"name,omitempty" yaml:"name,omitempty"`
+			Version string `json:"version,omitempty" yaml:"version,omitempty"`
+		} `json:"deps,omitempty" yaml:"deps,omitempty"`
+	} `json:"modules,omitempty" yaml:"modules,omitempty"`
+	Doc struct {
+		Output string `json:"output,omitempty" yaml:"output,omitempty"`
+		Format string `json:"format,omitempty" yaml:"format,omitempty"`
+	} `json:"doc,omitempty" yaml:"doc,omitempty"`
+	Vet struct {
+		DisallowedImports map[string][]string `json:"disallowed_imports,omitempty" yaml:"disallowed_imports,omitempty"`
+	} `json:"vet,omitempty" yaml:"vet,omitempty"`
+	Format struct {
+		Indent              string            `json:"indent,omitempty" yaml:"indent,omitempty"`
+		AlignFields         bool              `json:"align_fields,omitempty" yaml:"align_fields,omitempty"`
+		AlignOptions        bool              `json:"align_options,omitempty" yaml:"align_options,omitempty"`
+		TopLevelBlankLines  int               `json:"top_level_blank_lines,omitempty" yaml:"top_level_blank_lines,omitempty"`
+		CanonicalOrder      bool              `json:"canonical_order,omitempty" yaml:"canonical_order,omitempty"`
+		FileOptionTemplates map[string]string `json:"file_option_templates,omitempty" yaml:"file_option_templates,omitempty"`
+	} `json:"format,omitempty" yaml:"format,omitempty"`
+	Break struct {
+		IgnoreIDs      []string `json:"ignore_ids,omitempty" yaml:"ignore_ids,omitempty"`
+		IgnoreTypes    []string `json:"ignore_types,omitempty" yaml:"ignore_types,omitempty"`
+		ExceptionsFile string   `json:"exceptions_file,omitempty" yaml:"exceptions_file,omitempty"`
+	} `json:"break,omitempty" yaml:"break,omitempty"`
+	Webhook struct {
+		URL      string            `json:"url,omitempty" yaml:"url,omitempty"`
+		Template string            `json:"template,omitempty" yaml:"template,omitempty"`
+		Headers  map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	} `json:"webhook,omitempty" yaml:"webhook,omitempty"`
+	GRPC struct {
+		Targets map[string]struct {
+			Address   string            `json:"address,omitempty" yaml:"address,omitempty"`
+			Headers   map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+			AuthToken string            `json:"auth_token,omitempty" yaml:"auth_token,omitempty"`
+			TLS       struct {
+				Enabled            bool   `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+				CACert             string `json:"ca_cert,omitempty" yaml:"ca_cert,omitempty"`
+				Cert               string `json:"cert,omitempty" yaml:"cert,omitempty"`
+				Key                string `json:"key,omitempty" yaml:"key,omitempty"`
+				InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty" yaml:"insecure_skip_verify,omitempty"`
+				ServerName         string `json:"server_name,omitempty" yaml:"server_name,omitempty"`
+			} `json:"tls,omitempty" yaml:"tls,omitempty"`
+		} `json:"targets,omitempty" yaml:"targets,omitempty"`
+	} `json:"grpc,omitempty" yaml:"grpc,omitempty"`
+	Owners []struct {
+		Path string `json:"path,omitempty" yaml:"path,omitempty"`
+		Team string `json:"team,omitempty" yaml:"team,omitempty"`
+	} `json:"owners,omitempty" yaml:"owners,omitempty"`
+	Workspace struct {
+		Repos []struct {
+			Path  string   `json:"path,omitempty" yaml:"path,omitempty"`
+			Roots []string `json:"roots,omitempty" yaml:"roots,omitempty"`
+		} `json:"repos,omitempty" yaml:"repos,omitempty"`
+	} `json:"workspace,omitempty" yaml:"workspace,omitempty"`
 }
 
 // ConfigProvider provides Configs.
@@ -26,6 +26,7 @@
 package strs
 
 import (
+	"path"
 	"sort"
 	"strings"
 	"unicode"
@@ -167,6 +168,53 @@ func Intersection(one []string, two []string) []string {
 	return s
 }
 
+// MatchGlob returns true if the slash-separated name matches the pattern.
+//
+// Each element of the pattern is matched against an element of name with
+// path.Match, and an element of ** matches zero or more elements. As with
+// CODEOWNERS files, a pattern with no slash matches in any directory, a
+// pattern ending in a slash matches everything in the directory, and a
+// leading slash is ignored.
+//
+// Returns an error if the pattern is malformed.
+func MatchGlob(pattern string, name string) (bool, error) {
+	if !strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+		pattern = "**/" + pattern
+	}
+	if strings.HasSuffix(pattern, "/") {
+		pattern = pattern + "**"
+	}
+	patternElements := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	for _, patternElement := range patternElements {
+		if _, err := path.Match(patternElement, ""); err != nil {
+			return false, err
+		}
+	}
+	return matchGlobElements(patternElements, strings.Split(name, "/")), nil
+}
+
+// the pattern elements are expected to be valid
+func matchGlobElements(patternElements []string, nameElements []string) bool {
+	if len(patternElements) == 0 {
+		return len(nameElements) == 0
+	}
+	if patternElements[0] == "**" {
+		for i := 0; i <= len(nameElements); i++ {
+			if matchGlobElements(patternElements[1:], nameElements[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(nameElements) == 0 {
+		return false
+	}
+	if matched, _ := path.Match(patternElements[0], nameElements[0]); !matched {
+		return false
+	}
+	return matchGlobElements(patternElements[1:], nameElements[1:])
+}
+
 // IsLowercase returns true if s is not empty and is all lowercase.
 func IsLowercase(s string) bool {
 	if s == "" {
@@ -139,3 +139,34 @@ func TestIntersection(t *testing.T) {
 	assert.Equal(t, []string{"1", "2"}, Intersection([]string{"1", "2", "", "3"}, []string{"1", "5", "2"}))
 	assert.Equal(t, []string{"1", "2"}, Intersection([]string{"1", "2", "", "3"}, []string{"1", "5", "", "2"}))
 }
+
+func TestMatchGlob(t *testing.T) {
+	for _, testCase := range []struct {
+		pattern string
+		name    string
+		matched bool
+	}{
+		{"*.proto", "foo.proto", true},
+		{"*.proto", "a/b/foo.proto", true},
+		{"*.proto", "a/b/foo.txt", false},
+		{"a/*.proto", "a/foo.proto", true},
+		{"a/*.proto", "a/b/foo.proto", false},
+		{"/a/*.proto", "a/foo.proto", true},
+		{"a/**/*.proto", "a/foo.proto", true},
+		{"a/**/*.proto", "a/b/c/foo.proto", true},
+		{"a/**", "a/b/c/foo.proto", true},
+		{"a/", "a/b/c/foo.proto", true},
+		{"a/", "b/a/foo.proto", true},
+		{"/a/", "b/a/foo.proto", false},
+		{"/b/", "a/b/foo.proto", false},
+		{"**/b/*.proto", "a/b/foo.proto", true},
+	} {
+		matched, err := MatchGlob(testCase.pattern, testCase.name)
+		assert.NoError(t, err, testCase.pattern)
+		assert.Equal(t, testCase.matched, matched, "%s %s", testCase.pattern, testCase.name)
+	}
+	_, err := MatchGlob("a/[", "a/b")
+	assert.Error(t, err)
+	_, err = MatchGlob("a/b/[", "a")
+	assert.Error(t, err)
+}
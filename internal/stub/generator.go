@@ -0,0 +1,456 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package stub
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/wkt"
+	"go.uber.org/zap"
+)
+
+var stubTemplate = template.Must(template.New("stub").Parse(`// Code generated by prototool. DO NOT EDIT.
+// source: {{.Source}}
+
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+	{{.Alias}} {{.Path}}
+{{- end}}
+)
+{{range $service := .Services}}
+// {{.Name}}Client is the client API for the {{.FullName}} service.
+type {{.Name}}Client interface {
+{{- range .Methods}}
+	{{.Name}}{{.Signature}}
+{{- end}}
+}
+
+// {{.Name}}ClientStub is a {{.Name}}Client for tests.
+//
+// Each method calls the field of the same name with the suffix Func, or
+// returns an error with code Unimplemented if the field is not set.
+type {{.Name}}ClientStub struct {
+{{- range .Methods}}
+	{{.Name}}Func func{{.Signature}}
+{{- end}}
+}
+
+var _ {{.Name}}Client = (*{{.Name}}ClientStub)(nil)
+{{range .Methods}}
+// {{.Name}} calls {{.Name}}Func.
+func (s *{{$service.Name}}ClientStub) {{.Name}}{{.Signature}} {
+	if s.{{.Name}}Func == nil {
+		return nil, status.Error(codes.Unimplemented, "{{$service.Name}}ClientStub.{{.Name}}Func is not set")
+	}
+	return s.{{.Name}}Func({{.Args}})
+}
+{{end}}
+{{- end}}`))
+
+type stubFile struct {
+	Source   string
+	Package  string
+	Imports  []*stubImport
+	Services []*stubService
+}
+
+type stubImport struct {
+	Alias string
+	Path  string
+}
+
+type stubService struct {
+	Name     string
+	FullName string
+	Methods  []*stubMethod
+}
+
+type stubMethod struct {
+	Name      string
+	Signature string
+	Args      string
+}
+
+type generator struct {
+	logger *zap.Logger
+}
+
+func newGenerator(options ...GeneratorOption) *generator {
+	generator := &generator{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(generator)
+	}
+	return generator
+}
+
+func (g *generator) Generate(protoSet *file.ProtoSet, fileDescriptorSets []*descriptor.FileDescriptorSet) ([]*File, error) {
+	outputPath := protoSet.Config.Gen.GoStubsOutputPath
+	if outputPath == "" {
+		return nil, nil
+	}
+	var names []string
+	nameToInSet := make(map[string]struct{})
+	for _, protoFiles := range protoSet.DirPathToFiles {
+		for _, protoFile := range protoFiles {
+			name, err := file.ImportPath(protoSet, protoFile.Path)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+			nameToInSet[name] = struct{}{}
+		}
+	}
+	sort.Strings(names)
+	resolver := newResolver(protoSet.Config.Gen, nameToInSet, fileDescriptorSets)
+
+	var files []*File
+	for _, name := range names {
+		fileDescriptor, ok := resolver.nameToFileDescriptor[name]
+		if !ok {
+			return nil, fmt.Errorf("no FileDescriptorProto for %s", name)
+		}
+		if len(fileDescriptor.GetService()) == 0 {
+			continue
+		}
+		data, err := resolver.generate(fileDescriptor)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &File{
+			Path: filepath.Join(outputPath, filepath.FromSlash(strings.TrimSuffix(name, ".proto")+"_stubs.go")),
+			Data: data,
+		})
+	}
+	g.logger.Debug("generated stubs", zap.String("path", outputPath), zap.Int("numFiles", len(files)))
+	return files, nil
+}
+
+// resolver resolves the Go packages and names of the types in the
+// compiled files.
+type resolver struct {
+	genConfig            settings.GenConfig
+	genPlugin            *settings.GenPlugin
+	nameToInSet          map[string]struct{}
+	nameToFileDescriptor map[string]*descriptor.FileDescriptorProto
+	// fully-qualified message name without the leading dot to the file
+	// name and the Go name of the message
+	messageToFileName map[string]string
+	messageToGoName   map[string]string
+}
+
+func newResolver(genConfig settings.GenConfig, nameToInSet map[string]struct{}, fileDescriptorSets []*descriptor.FileDescriptorSet) *resolver {
+	resolver := &resolver{
+		genConfig:            genConfig,
+		nameToInSet:          nameToInSet,
+		nameToFileDescriptor: make(map[string]*descriptor.FileDescriptorProto),
+		messageToFileName:    make(map[string]string),
+		messageToGoName:      make(map[string]string),
+	}
+	// stubs use the Go package of the first Go plugin, which is the
+	// package the Mfile=package modifiers point to
+	for i, genPlugin := range genConfig.Plugins {
+		if genPlugin.Type.IsGo() || genPlugin.Type.IsGogo() {
+			resolver.genPlugin = &genConfig.Plugins[i]
+			break
+		}
+	}
+	for _, fileDescriptorSet := range fileDescriptorSets {
+		for _, fileDescriptor := range fileDescriptorSet.GetFile() {
+			if _, ok := resolver.nameToFileDescriptor[fileDescriptor.GetName()]; ok {
+				continue
+			}
+			resolver.nameToFileDescriptor[fileDescriptor.GetName()] = fileDescriptor
+			resolver.addMessages(fileDescriptor.GetName(), fileDescriptor.GetPackage(), nil, fileDescriptor.GetMessageType())
+		}
+	}
+	return resolver
+}
+
+func (r *resolver) addMessages(fileName string, scope string, goNames []string, messages []*descriptor.DescriptorProto) {
+	for _, message := range messages {
+		fullName := message.GetName()
+		if scope != "" {
+			fullName = scope + "." + fullName
+		}
+		messageGoNames := append(append([]string(nil), goNames...), message.GetName())
+		r.messageToFileName[fullName] = fileName
+		r.messageToGoName[fullName] = camelCase(strings.Join(messageGoNames, "_"))
+		r.addMessages(fileName, fullName, messageGoNames, message.GetNestedType())
+	}
+}
+
+// goImportPath returns the import path of the Go package generated for
+// the file, following the Mfile=package modifiers that the compiler
+// passes to Go plugins, and falling back to the go_package option.
+func (r *resolver) goImportPath(fileDescriptor *descriptor.FileDescriptorProto) (string, error) {
+	name := fileDescriptor.GetName()
+	if importPath, ok := r.genConfig.GoPluginOptions.ExtraModifiers[name]; ok {
+		return importPath, nil
+	}
+	if _, ok := wkt.Filenames[name]; ok {
+		if r.genPlugin != nil && r.genPlugin.Type.IsGogo() {
+			return wkt.FilenameToGogoModifierMap[name], nil
+		}
+		return wkt.FilenameToGoModifierMap[name], nil
+	}
+	if _, ok := r.nameToInSet[name]; ok && r.genPlugin != nil && !r.genConfig.GoPluginOptions.NoDefaultModifiers {
+		return path.Clean(path.Join(r.genConfig.GoPluginOptions.ImportPath, filepath.ToSlash(r.genPlugin.OutputPath.RelPath), path.Dir(name))), nil
+	}
+	goPackage := fileDescriptor.GetOptions().GetGoPackage()
+	if i := strings.Index(goPackage, ";"); i >= 0 {
+		goPackage = goPackage[:i]
+	}
+	if strings.Contains(goPackage, "/") {
+		return goPackage, nil
+	}
+	return "", fmt.Errorf("cannot determine the Go package of %s, configure a go plugin or set the go_package option to a full import path", name)
+}
+
+func (r *resolver) generate(fileDescriptor *descriptor.FileDescriptorProto) ([]byte, error) {
+	goImportPath, err := r.goImportPath(fileDescriptor)
+	if err != nil {
+		return nil, err
+	}
+	imports := newImports("context", "google.golang.org/grpc")
+	stubFile := &stubFile{
+		Source:  fileDescriptor.GetName(),
+		Package: goIdentifier(path.Base(goImportPath)) + "stubs",
+	}
+	for _, service := range fileDescriptor.GetService() {
+		stubService := &stubService{
+			Name:     camelCase(service.GetName()),
+			FullName: service.GetName(),
+		}
+		if fileDescriptor.GetPackage() != "" {
+			stubService.FullName = fileDescriptor.GetPackage() + "." + service.GetName()
+		}
+		for _, method := range service.GetMethod() {
+			stubMethod, err := r.newStubMethod(imports, goImportPath, stubService.Name, method)
+			if err != nil {
+				return nil, err
+			}
+			stubService.Methods = append(stubService.Methods, stubMethod)
+		}
+		if len(stubService.Methods) > 0 {
+			imports.add("google.golang.org/grpc/codes")
+			imports.add("google.golang.org/grpc/status")
+		}
+		stubFile.Services = append(stubFile.Services, stubService)
+	}
+	stubFile.Imports = imports.sorted()
+
+	buffer := bytes.NewBuffer(nil)
+	if err := stubTemplate.Execute(buffer, stubFile); err != nil {
+		return nil, err
+	}
+	data, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("could not format stubs for %s: %v", fileDescriptor.GetName(), err)
+	}
+	return data, nil
+}
+
+func (r *resolver) newStubMethod(imports *imports, goImportPath string, serviceName string, method *descriptor.MethodDescriptorProto) (*stubMethod, error) {
+	name := camelCase(method.GetName())
+	if method.GetClientStreaming() || method.GetServerStreaming() {
+		streamType := imports.add(goImportPath) + "." + serviceName + "_" + name + "Client"
+		if method.GetClientStreaming() {
+			return &stubMethod{
+				Name:      name,
+				Signature: fmt.Sprintf("(ctx context.Context, opts ...grpc.CallOption) (%s, error)", streamType),
+				Args:      "ctx, opts...",
+			}, nil
+		}
+		inputType, err := r.goType(imports, method.GetInputType())
+		if err != nil {
+			return nil, err
+		}
+		return &stubMethod{
+			Name:      name,
+			Signature: fmt.Sprintf("(ctx context.Context, in *%s, opts ...grpc.CallOption) (%s, error)", inputType, streamType),
+			Args:      "ctx, in, opts...",
+		}, nil
+	}
+	inputType, err := r.goType(imports, method.GetInputType())
+	if err != nil {
+		return nil, err
+	}
+	outputType, err := r.goType(imports, method.GetOutputType())
+	if err != nil {
+		return nil, err
+	}
+	return &stubMethod{
+		Name:      name,
+		Signature: fmt.Sprintf("(ctx context.Context, in *%s, opts ...grpc.CallOption) (*%s, error)", inputType, outputType),
+		Args:      "ctx, in, opts...",
+	}, nil
+}
+
+// goType returns the qualified Go type of the message with the given
+// fully-qualified name, adding the import of its package.
+func (r *resolver) goType(imports *imports, typeName string) (string, error) {
+	typeName = strings.TrimPrefix(typeName, ".")
+	fileName, ok := r.messageToFileName[typeName]
+	if !ok {
+		return "", fmt.Errorf("unknown message type: %s", typeName)
+	}
+	goImportPath, err := r.goImportPath(r.nameToFileDescriptor[fileName])
+	if err != nil {
+		return "", err
+	}
+	return imports.add(goImportPath) + "." + r.messageToGoName[typeName], nil
+}
+
+// imports are the imports of a generated file, with unique aliases.
+type imports struct {
+	pathToAlias map[string]string
+	aliases     map[string]struct{}
+	unaliased   map[string]struct{}
+}
+
+func newImports(paths ...string) *imports {
+	imports := &imports{
+		pathToAlias: make(map[string]string),
+		aliases:     make(map[string]struct{}),
+		unaliased:   make(map[string]struct{}),
+	}
+	// reserve the names the template uses unqualified
+	for _, alias := range []string{"codes", "context", "ctx", "grpc", "in", "opts", "s", "status"} {
+		imports.aliases[alias] = struct{}{}
+	}
+	for _, importPath := range paths {
+		imports.pathToAlias[importPath] = path.Base(importPath)
+		imports.unaliased[importPath] = struct{}{}
+	}
+	return imports
+}
+
+// add adds the import path if it is not already added, and returns its alias.
+func (i *imports) add(importPath string) string {
+	if alias, ok := i.pathToAlias[importPath]; ok {
+		return alias
+	}
+	alias := path.Base(importPath)
+	switch importPath {
+	case "google.golang.org/grpc/codes", "google.golang.org/grpc/status":
+		i.unaliased[importPath] = struct{}{}
+	default:
+		base := goIdentifier(alias)
+		alias = base
+		for n := 2; ; n++ {
+			if _, ok := i.aliases[alias]; !ok {
+				break
+			}
+			alias = base + strconv.Itoa(n)
+		}
+		i.aliases[alias] = struct{}{}
+	}
+	i.pathToAlias[importPath] = alias
+	return alias
+}
+
+func (i *imports) sorted() []*stubImport {
+	stubImports := make([]*stubImport, 0, len(i.pathToAlias))
+	for importPath, alias := range i.pathToAlias {
+		stubImport := &stubImport{
+			Path: strconv.Quote(importPath),
+		}
+		// the name of a generated package may differ from the last element
+		// of its import path, so these are always imported with an alias
+		if _, ok := i.unaliased[importPath]; !ok {
+			stubImport.Alias = alias
+		}
+		stubImports = append(stubImports, stubImport)
+	}
+	sort.Slice(stubImports, func(i int, j int) bool { return stubImports[i].Path < stubImports[j].Path })
+	return stubImports
+}
+
+// goIdentifier returns s with all characters that are not valid in a Go
+// identifier replaced with underscores.
+func goIdentifier(s string) string {
+	identifier := strings.Map(func(r rune) rune {
+		if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+	if identifier == "" || ('0' <= identifier[0] && identifier[0] <= '9') {
+		identifier = "_" + identifier
+	}
+	return identifier
+}
+
+// camelCase returns the Go name that protoc-gen-go generates for the
+// Protobuf name s.
+func camelCase(s string) string {
+	if s == "" {
+		return ""
+	}
+	t := make([]byte, 0, len(s)+1)
+	i := 0
+	if s[0] == '_' {
+		t = append(t, 'X')
+		i++
+	}
+	for ; i < len(s); i++ {
+		c := s[i]
+		if c == '_' && i+1 < len(s) && isLower(s[i+1]) {
+			continue
+		}
+		if isDigit(c) {
+			t = append(t, c)
+			continue
+		}
+		if isLower(c) {
+			c ^= ' '
+		}
+		t = append(t, c)
+		for i+1 < len(s) && isLower(s[i+1]) {
+			i++
+			t = append(t, s[i])
+		}
+	}
+	return string(t)
+}
+
+func isLower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package stub
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/settings"
+)
+
+func TestGenerate(t *testing.T) {
+	dirPath := filepath.FromSlash("/tmp/stubtest")
+	protoSet := &file.ProtoSet{
+		WorkDirPath: dirPath,
+		DirPath:     dirPath,
+		DirPathToFiles: map[string][]*file.ProtoFile{
+			filepath.Join(dirPath, "foo", "v1"): {
+				{Path: filepath.Join(dirPath, "foo", "v1", "foo.proto")},
+				{Path: filepath.Join(dirPath, "foo", "v1", "types.proto")},
+			},
+		},
+		Config: settings.Config{
+			DirPath: dirPath,
+			Gen: settings.GenConfig{
+				GoPluginOptions: settings.GenGoPluginOptions{
+					ImportPath: "github.com/acme/api",
+				},
+				Plugins: []settings.GenPlugin{
+					{
+						Name: "go",
+						Type: settings.GenPluginTypeGo,
+						OutputPath: settings.OutputPath{
+							RelPath: "gen/go",
+							AbsPath: filepath.Join(dirPath, "gen", "go"),
+						},
+					},
+				},
+				GoStubsOutputPath: filepath.Join(dirPath, "gen", "stubs"),
+			},
+		},
+	}
+	fileDescriptorSet := &descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("google/protobuf/empty.proto"),
+				Package: proto.String("google.protobuf"),
+				MessageType: []*descriptor.DescriptorProto{
+					{Name: proto.String("Empty")},
+				},
+			},
+			{
+				Name:    proto.String("foo/v1/types.proto"),
+				Package: proto.String("foo.v1"),
+				MessageType: []*descriptor.DescriptorProto{
+					{
+						Name: proto.String("Outer"),
+						NestedType: []*descriptor.DescriptorProto{
+							{Name: proto.String("Inner")},
+						},
+					},
+				},
+			},
+			{
+				Name:    proto.String("foo/v1/foo.proto"),
+				Package: proto.String("foo.v1"),
+				Service: []*descriptor.ServiceDescriptorProto{
+					{
+						Name: proto.String("hello_service"),
+						Method: []*descriptor.MethodDescriptorProto{
+							{
+								Name:       proto.String("SayHello"),
+								InputType:  proto.String(".foo.v1.Outer"),
+								OutputType: proto.String(".google.protobuf.Empty"),
+							},
+							{
+								Name:            proto.String("ListHellos"),
+								InputType:       proto.String(".foo.v1.Outer.Inner"),
+								OutputType:      proto.String(".foo.v1.Outer"),
+								ServerStreaming: proto.Bool(true),
+							},
+							{
+								Name:            proto.String("Chat"),
+								InputType:       proto.String(".foo.v1.Outer"),
+								OutputType:      proto.String(".foo.v1.Outer"),
+								ClientStreaming: proto.Bool(true),
+								ServerStreaming: proto.Bool(true),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	files, err := NewGenerator().Generate(protoSet, []*descriptor.FileDescriptorSet{fileDescriptorSet})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, filepath.Join(dirPath, "gen", "stubs", "foo", "v1", "foo_stubs.go"), files[0].Path)
+	data := string(files[0].Data)
+	for _, expected := range []string{
+		"package v1stubs",
+		`v1 "github.com/acme/api/gen/go/foo/v1"`,
+		`empty "github.com/golang/protobuf/ptypes/empty"`,
+		"type HelloServiceClient interface {",
+		"SayHello(ctx context.Context, in *v1.Outer, opts ...grpc.CallOption) (*empty.Empty, error)",
+		"ListHellos(ctx context.Context, in *v1.Outer_Inner, opts ...grpc.CallOption) (v1.HelloService_ListHellosClient, error)",
+		"Chat(ctx context.Context, opts ...grpc.CallOption) (v1.HelloService_ChatClient, error)",
+		"type HelloServiceClientStub struct {",
+		"var _ HelloServiceClient = (*HelloServiceClientStub)(nil)",
+		`return nil, status.Error(codes.Unimplemented, "HelloServiceClientStub.SayHelloFunc is not set")`,
+		"return s.ChatFunc(ctx, opts...)",
+	} {
+		assert.Contains(t, data, expected)
+	}
+
+	protoSet.Config.Gen.Plugins = nil
+	_, err = NewGenerator().Generate(protoSet, []*descriptor.FileDescriptorSet{fileDescriptorSet})
+	assert.Error(t, err)
+}
+
+func TestCamelCase(t *testing.T) {
+	assert.Equal(t, "HelloService", camelCase("hello_service"))
+	assert.Equal(t, "OuterInnerValue", camelCase("Outer_inner_value"))
+	assert.Equal(t, "XFoo", camelCase("_foo"))
+	assert.Equal(t, "Foo2Bar", camelCase("foo2_bar"))
+}
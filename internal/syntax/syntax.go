@@ -0,0 +1,228 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package syntax parses Protobuf files with the internal parser,
+// recovering from syntax errors so that all of the syntax errors in a
+// file are reported in one pass instead of one per run.
+package syntax
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/editions"
+	"github.com/uber/prototool/internal/text"
+)
+
+// MaxFailures is the maximum number of syntax errors reported per file.
+//
+// A syntax error can cause further errors after recovery, so stopping
+// early keeps the output useful for files with many errors.
+const MaxFailures = 20
+
+var parseErrorRegexp = regexp.MustCompile(`^(.*):([0-9]+):([0-9]+): (.*)$`)
+
+// Parse parses the Protobuf file read from the reader with the parser from
+// editions.NewParser, using the filename for positions.
+//
+// Instead of stopping at the first syntax error, the statement with the
+// error is blanked out and the file is parsed again, so that all of the
+// syntax errors in the file are returned as failures, up to MaxFailures.
+// A statement that opens a block, such as a message, is blanked out with
+// its block. Positions are not changed by blanking out statements.
+//
+// The descriptor is returned only if there are no failures. The error is
+// only returned if the file cannot be read.
+func Parse(reader io.Reader, filename string) (*proto.Proto, []*text.Failure, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	var failures []*text.Failure
+	for len(failures) < MaxFailures {
+		parser, err := editions.NewParser(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		parser.Filename(filename)
+		descriptor, err := parser.Parse()
+		if err == nil {
+			if len(failures) > 0 {
+				return nil, failures, nil
+			}
+			return descriptor, nil, nil
+		}
+		failure := NewFailure(filename, err)
+		failures = append(failures, failure)
+		if failure.Line == 0 {
+			break
+		}
+		var ok bool
+		if data, ok = blankStatement(data, getOffset(data, failure.Line, failure.Column)); !ok {
+			break
+		}
+	}
+	return nil, failures, nil
+}
+
+// NewFailure returns a Failure for the error from parsing the file.
+func NewFailure(filename string, err error) *text.Failure {
+	if matches := parseErrorRegexp.FindStringSubmatch(err.Error()); len(matches) > 4 {
+		line, _ := strconv.Atoi(matches[2])
+		column, _ := strconv.Atoi(matches[3])
+		return &text.Failure{
+			Filename: filename,
+			Line:     line,
+			Column:   column,
+			Message:  upperFirst(matches[4]) + ".",
+		}
+	}
+	return &text.Failure{
+		Filename: filename,
+		Message:  err.Error(),
+	}
+}
+
+// blankStatement replaces the statement at the offset with spaces, keeping
+// newlines, and returns false if there is nothing to blank out.
+//
+// The statement starts after the closest ;, {, or } before the offset, and
+// ends with the first ; after the offset, or with the block of the first {
+// after the offset, or before the first } after the offset, which closes
+// the enclosing block. A } at the offset is blanked out by itself.
+func blankStatement(data []byte, offset int) ([]byte, bool) {
+	isCode := getIsCode(data)
+	if offset >= len(data) {
+		offset = len(data)
+	}
+	start := offset
+	for start > 0 {
+		if isCode[start-1] && isBoundary(data[start-1]) {
+			break
+		}
+		start--
+	}
+	end := offset
+	if end < len(data) && isCode[end] && data[end] == '}' {
+		end++
+	} else {
+		depth := 0
+	Loop:
+		for ; end < len(data); end++ {
+			if !isCode[end] {
+				continue
+			}
+			switch data[end] {
+			case ';':
+				if depth == 0 {
+					end++
+					break Loop
+				}
+			case '{':
+				depth++
+			case '}':
+				if depth == 0 {
+					break Loop
+				}
+				depth--
+				if depth == 0 {
+					end++
+					break Loop
+				}
+			}
+		}
+	}
+	if len(bytes.TrimSpace(data[start:end])) == 0 {
+		return nil, false
+	}
+	blanked := make([]byte, len(data))
+	copy(blanked, data)
+	for i := start; i < end; i++ {
+		if blanked[i] != '\n' {
+			blanked[i] = ' '
+		}
+	}
+	return blanked, true
+}
+
+// getIsCode returns whether each byte of the data is outside of comments
+// and string literals.
+func getIsCode(data []byte) []bool {
+	isCode := make([]bool, len(data))
+	for i := 0; i < len(data); i++ {
+		switch {
+		case data[i] == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case data[i] == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i < len(data) && !(data[i] == '*' && i+1 < len(data) && data[i+1] == '/') {
+				i++
+			}
+			i++
+		case data[i] == '"' || data[i] == '\'':
+			quote := data[i]
+			for i++; i < len(data) && data[i] != quote && data[i] != '\n'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+		default:
+			isCode[i] = true
+		}
+	}
+	return isCode
+}
+
+// getOffset returns the offset of the 1-based line and column in the data.
+func getOffset(data []byte, line int, column int) int {
+	offset := 0
+	for i := 1; i < line; i++ {
+		newline := bytes.IndexByte(data[offset:], '\n')
+		if newline < 0 {
+			return len(data)
+		}
+		offset += newline + 1
+	}
+	// columns count characters, not bytes
+	for i := 1; i < column && offset < len(data) && data[offset] != '\n'; i++ {
+		_, size := utf8.DecodeRune(data[offset:])
+		offset += size
+	}
+	return offset
+}
+
+func isBoundary(c byte) bool {
+	return c == ';' || c == '{' || c == '}'
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package syntax
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/text"
+)
+
+func TestParse(t *testing.T) {
+	descriptor, failures, err := Parse(strings.NewReader(`syntax = "proto3";
+
+package foo;
+
+message Foo {
+  string bar = 1;
+}
+`), "foo.proto")
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+	require.NotNil(t, descriptor)
+	assert.Len(t, descriptor.Elements, 3)
+
+	descriptor, failures, err = Parse(strings.NewReader(`syntax = "proto3";
+
+package foo;
+
+message Foo {
+  string bar = 1;
+  // a comment with ; and { in it
+  int32 baz = ;
+  string qux = 3;
+}
+
+message 1Bar {
+  string bar = 1;
+}
+
+enum Baz {
+  BAZ_INVALID = 0;
+  BAZ_ONE = "one;";
+}
+`), "foo.proto")
+	require.NoError(t, err)
+	assert.Nil(t, descriptor)
+	require.Len(t, failures, 3)
+	assert.Equal(t, 8, failures[0].Line)
+	assert.Equal(t, 12, failures[1].Line)
+	assert.Equal(t, 18, failures[2].Line)
+}
+
+func TestParseUnrecoverable(t *testing.T) {
+	descriptor, failures, err := Parse(strings.NewReader(`syntax = "proto3";
+
+message Foo {
+  string bar = 1;
+`), "foo.proto")
+	require.NoError(t, err)
+	assert.Nil(t, descriptor)
+	require.Len(t, failures, 1)
+}
+
+func TestParseMaxFailures(t *testing.T) {
+	data := `syntax = "proto3";` + strings.Repeat("\nmessage 1Foo {}", MaxFailures+5)
+	_, failures, err := Parse(strings.NewReader(data), "foo.proto")
+	require.NoError(t, err)
+	assert.Len(t, failures, MaxFailures)
+}
+
+func TestNewFailure(t *testing.T) {
+	assert.Equal(
+		t,
+		&text.Failure{
+			Filename: "foo.proto",
+			Line:     3,
+			Column:   1,
+			Message:  `Found "}" but expected [message field].`,
+		},
+		NewFailure("foo.proto", errors.New(`foo.proto:3:1: found "}" but expected [message field]`)),
+	)
+}
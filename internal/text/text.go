@@ -23,10 +23,13 @@ package text
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"sort"
 	"strconv"
 	"strings"
 	"text/scanner"
+	"text/template"
 )
 
 const (
@@ -40,6 +43,8 @@ const (
 	FailureFieldID
 	// FailureFieldMessage references the Message field of a Failure.
 	FailureFieldMessage
+	// FailureFieldOwner references the Owner field of a Failure.
+	FailureFieldOwner
 )
 
 var (
@@ -57,6 +62,7 @@ var (
 		FailureFieldColumn:   "column",
 		FailureFieldID:       "id",
 		FailureFieldMessage:  "message",
+		FailureFieldOwner:    "owner",
 	}
 	_stringToFailureField = map[string]FailureField{
 		"filename": FailureFieldFilename,
@@ -64,6 +70,7 @@ var (
 		"column":   FailureFieldColumn,
 		"id":       FailureFieldID,
 		"message":  FailureFieldMessage,
+		"owner":    FailureFieldOwner,
 	}
 )
 
@@ -106,6 +113,15 @@ func ParseColonSeparatedFailureFields(s string) ([]FailureField, error) {
 	return failureFields, nil
 }
 
+const (
+	// SeverityError is the severity of a Failure that is an error.
+	SeverityError = "error"
+	// SeverityWarning is the severity of a Failure that is a warning.
+	SeverityWarning = "warning"
+	// SeverityInfo is the severity of a Failure that is informational.
+	SeverityInfo = "info"
+)
+
 // Failure is a failure with a position in text.
 type Failure struct {
 	Filename string
@@ -113,6 +129,49 @@ type Failure struct {
 	Column   int
 	ID       string
 	Message  string
+	// Either SeverityError, SeverityWarning, or SeverityInfo.
+	// If empty, the Failure is an error.
+	Severity string
+	// The team that owns the file, if any.
+	Owner string
+}
+
+// JSONFailure is the structured representation of a Failure
+// that is meant to be encoded to JSON.
+type JSONFailure struct {
+	Filename string `json:"filename"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	ID       string `json:"id,omitempty"`
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+	Owner    string `json:"owner,omitempty"`
+}
+
+// JSONFailure returns the JSONFailure for the Failure.
+//
+// Unset lines and columns are 1, and an unset severity is SeverityError,
+// matching Fprintln.
+func (f *Failure) JSONFailure() *JSONFailure {
+	jsonFailure := &JSONFailure{
+		Filename: f.Filename,
+		Line:     f.Line,
+		Column:   f.Column,
+		ID:       f.ID,
+		Message:  f.Message,
+		Severity: f.Severity,
+		Owner:    f.Owner,
+	}
+	if jsonFailure.Line == 0 {
+		jsonFailure.Line = 1
+	}
+	if jsonFailure.Column == 0 {
+		jsonFailure.Column = 1
+	}
+	if jsonFailure.Severity == "" {
+		jsonFailure.Severity = SeverityError
+	}
+	return jsonFailure
 }
 
 // FailureWriter is a writer that Failure.Println can accept.
@@ -170,6 +229,11 @@ func (f *Failure) Fprintln(writer FailureWriter, fields ...FailureField) error {
 			}
 		case FailureFieldMessage:
 			if f.Message != "" {
+				if f.Severity == SeverityWarning || f.Severity == SeverityInfo {
+					if _, err := writer.WriteString(f.Severity + ": "); err != nil {
+						return err
+					}
+				}
 				if _, err := writer.WriteString(f.Message); err != nil {
 					return err
 				}
@@ -177,6 +241,15 @@ func (f *Failure) Fprintln(writer FailureWriter, fields ...FailureField) error {
 			} else {
 				printColon = false
 			}
+		case FailureFieldOwner:
+			if f.Owner != "" {
+				if _, err := writer.WriteString(f.Owner); err != nil {
+					return err
+				}
+				written = true
+			} else {
+				printColon = false
+			}
 		default:
 			return fmt.Errorf("unknown FailureField: %v", field)
 		}
@@ -194,6 +267,45 @@ func (f *Failure) Fprintln(writer FailureWriter, fields ...FailureField) error {
 	return nil
 }
 
+// ParseFailureTemplate parses a text/template to print Failures with,
+// such as {{.Filename}}:{{.Line}} {{.ID}} {{.Message}}.
+//
+// The fields of Failure can be referenced. Returns an error if the
+// template is malformed or references anything else.
+func ParseFailureTemplate(s string) (*template.Template, error) {
+	tmpl, err := template.New("failure").Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(ioutil.Discard, &Failure{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// FprintlnTemplate prints the Failure to the writer with the given
+// template followed by a newline.
+//
+// As with Fprintln, an empty filename is printed as <input>, and a
+// line or column of 0 is printed as 1.
+func (f *Failure) FprintlnTemplate(writer io.Writer, tmpl *template.Template) error {
+	failure := *f
+	if failure.Filename == "" {
+		failure.Filename = "<input>"
+	}
+	if failure.Line == 0 {
+		failure.Line = 1
+	}
+	if failure.Column == 0 {
+		failure.Column = 1
+	}
+	if err := tmpl.Execute(writer, &failure); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, "\n")
+	return err
+}
+
 // String implements fmt.Stringer.
 func (f *Failure) String() string {
 	filename := f.Filename
@@ -234,6 +346,27 @@ func NewFailuref(position scanner.Position, id string, format string, args ...in
 	}
 }
 
+// ContainsError returns true if any of the Failures is an error.
+func ContainsError(failures ...*Failure) bool {
+	for _, failure := range failures {
+		if failure.Severity == "" || failure.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// CountWarnings returns the number of Failures that are warnings.
+func CountWarnings(failures ...*Failure) int {
+	count := 0
+	for _, failure := range failures {
+		if failure.Severity == SeverityWarning {
+			count++
+		}
+	}
+	return count
+}
+
 // SortFailures sorts the Failures, by filename, line, column, id, message.
 func SortFailures(failures []*Failure) {
 	sort.Stable(sortFailures(failures))
@@ -43,6 +43,22 @@ func TestFailureFprintln(t *testing.T) {
 		FailureFieldFilename,
 		FailureFieldID,
 	)
+	ownedFailure := newTestFailure("foo", 2, 3, "BAR", "hello")
+	ownedFailure.Owner = "payments"
+	testFailureFprintln(t, "foo:2:3:payments:hello", ownedFailure,
+		FailureFieldFilename,
+		FailureFieldLine,
+		FailureFieldColumn,
+		FailureFieldOwner,
+		FailureFieldMessage,
+	)
+	testFailureFprintln(t, "foo:2:3:hello", newTestFailure("foo", 2, 3, "BAR", "hello"),
+		FailureFieldFilename,
+		FailureFieldLine,
+		FailureFieldColumn,
+		FailureFieldOwner,
+		FailureFieldMessage,
+	)
 }
 
 func testFailureFprintln(t *testing.T, expected string, failure *Failure, failureFields ...FailureField) {
@@ -51,10 +67,29 @@ func testFailureFprintln(t *testing.T, expected string, failure *Failure, failur
 	assert.Equal(t, expected+"\n", buffer.String())
 }
 
+func TestFailureFprintlnTemplate(t *testing.T) {
+	tmpl, err := ParseFailureTemplate("{{.Filename}}:{{.Line}} {{.ID}} {{.Severity}} {{.Message}}")
+	assert.NoError(t, err)
+	buffer := bytes.NewBuffer(nil)
+	failure := newTestFailure("", 0, 2, "BAR", "hello")
+	failure.Severity = SeverityWarning
+	assert.NoError(t, failure.FprintlnTemplate(buffer, tmpl))
+	assert.NoError(t, newTestFailure("foo", 2, 3, "BAR", "hello").FprintlnTemplate(buffer, tmpl))
+	assert.Equal(t, "<input>:1 BAR warning hello\nfoo:2 BAR  hello\n", buffer.String())
+	// the failure is not modified
+	assert.Equal(t, "", failure.Filename)
+
+	_, err = ParseFailureTemplate("{{.Filename")
+	assert.Error(t, err)
+	_, err = ParseFailureTemplate("{{.Foo}}")
+	assert.Error(t, err)
+}
+
 func TestParseColonSeparatedFailureFields(t *testing.T) {
 	testParseColonSeparatedFailureFields(t, "", false, DefaultFailureFields...)
 	testParseColonSeparatedFailureFields(t, "filename", false, FailureFieldFilename)
 	testParseColonSeparatedFailureFields(t, "filename:id", false, FailureFieldFilename, FailureFieldID)
+	testParseColonSeparatedFailureFields(t, "filename:owner", false, FailureFieldFilename, FailureFieldOwner)
 	testParseColonSeparatedFailureFields(t, ":", true)
 	testParseColonSeparatedFailureFields(t, ":filename:id", true)
 	testParseColonSeparatedFailureFields(t, "filename:id:", true)
@@ -133,6 +168,47 @@ func TestSortFailures(t *testing.T) {
 	)
 }
 
+func TestContainsErrorAndCountWarnings(t *testing.T) {
+	failures := []*Failure{
+		{Message: "foo", Severity: SeverityWarning},
+		{Message: "bar", Severity: SeverityInfo},
+		{Message: "baz", Severity: SeverityWarning},
+	}
+	assert.False(t, ContainsError(failures...))
+	assert.Equal(t, 2, CountWarnings(failures...))
+	failures = append(failures, &Failure{Message: "bat"})
+	assert.True(t, ContainsError(failures...))
+	assert.Equal(t, 2, CountWarnings(failures...))
+}
+
+func TestFailureJSONFailure(t *testing.T) {
+	assert.Equal(
+		t,
+		&JSONFailure{
+			Filename: "foo",
+			Line:     1,
+			Column:   1,
+			Message:  "hello",
+			Severity: SeverityError,
+		},
+		newTestFailure("foo", 0, 0, "", "hello").JSONFailure(),
+	)
+	failure := newTestFailure("foo", 2, 3, "BAR", "hello")
+	failure.Severity = SeverityWarning
+	assert.Equal(
+		t,
+		&JSONFailure{
+			Filename: "foo",
+			Line:     2,
+			Column:   3,
+			ID:       "BAR",
+			Message:  "hello",
+			Severity: SeverityWarning,
+		},
+		failure.JSONFailure(),
+	)
+}
+
 func newTestFailure(filename string, line int, column int, id string, message string) *Failure {
 	return NewFailuref(scanner.Position{Filename: filename, Line: line, Column: column}, id, message)
 }
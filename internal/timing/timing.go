@@ -0,0 +1,117 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package timing records the wall time spent in each phase of a command.
+package timing
+
+import (
+	"sync"
+	"time"
+)
+
+// Phase is the total wall time spent in a named phase.
+type Phase struct {
+	Name     string
+	Duration time.Duration
+	// The number of times the phase was started.
+	Count int
+}
+
+// Timer records the wall time of named phases.
+//
+// Timers are safe for concurrent use. Phases that run concurrently
+// each count their own wall time, so the sum of the phases can be
+// greater than the total wall time.
+type Timer interface {
+	// Start starts the phase with the given name and returns a function
+	// that stops it. If the phase was already recorded, the durations are
+	// added together.
+	Start(name string) func()
+	// Phases returns the recorded phases in the order they were first started.
+	Phases() []*Phase
+	// Total returns the wall time since the Timer was created.
+	Total() time.Duration
+}
+
+// NewTimer returns a new Timer.
+func NewTimer() Timer {
+	return newTimer()
+}
+
+// NewNopTimer returns a new Timer that does not record anything.
+func NewNopTimer() Timer {
+	return nopTimer{}
+}
+
+type timer struct {
+	start       time.Time
+	nameToPhase map[string]*Phase
+	phases      []*Phase
+	lock        sync.Mutex
+}
+
+func newTimer() *timer {
+	return &timer{
+		start:       time.Now(),
+		nameToPhase: make(map[string]*Phase),
+	}
+}
+
+func (t *timer) Start(name string) func() {
+	start := time.Now()
+	t.lock.Lock()
+	phase, ok := t.nameToPhase[name]
+	if !ok {
+		phase = &Phase{
+			Name: name,
+		}
+		t.nameToPhase[name] = phase
+		t.phases = append(t.phases, phase)
+	}
+	t.lock.Unlock()
+	return func() {
+		duration := time.Since(start)
+		t.lock.Lock()
+		phase.Duration += duration
+		phase.Count++
+		t.lock.Unlock()
+	}
+}
+
+func (t *timer) Phases() []*Phase {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	phases := make([]*Phase, 0, len(t.phases))
+	for _, phase := range t.phases {
+		c := *phase
+		phases = append(phases, &c)
+	}
+	return phases
+}
+
+func (t *timer) Total() time.Duration {
+	return time.Since(t.start)
+}
+
+type nopTimer struct{}
+
+func (nopTimer) Start(string) func()  { return func() {} }
+func (nopTimer) Phases() []*Phase     { return nil }
+func (nopTimer) Total() time.Duration { return 0 }
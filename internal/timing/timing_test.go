@@ -0,0 +1,57 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package timing
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimer(t *testing.T) {
+	timer := NewTimer()
+	stop := timer.Start("one")
+	stop()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			timer.Start("two")()
+		}()
+	}
+	wg.Wait()
+	timer.Start("one")()
+	phases := timer.Phases()
+	assert.Len(t, phases, 2)
+	assert.Equal(t, "one", phases[0].Name)
+	assert.Equal(t, 2, phases[0].Count)
+	assert.Equal(t, "two", phases[1].Name)
+	assert.Equal(t, 10, phases[1].Count)
+	assert.True(t, timer.Total() >= phases[0].Duration)
+}
+
+func TestNopTimer(t *testing.T) {
+	timer := NewNopTimer()
+	timer.Start("one")()
+	assert.Empty(t, timer.Phases())
+}
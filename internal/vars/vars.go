@@ -32,6 +32,22 @@ const (
 	//
 	// See https://github.com/google/protobuf/releases for the latest release.
 	DefaultProtocVersion = "3.5.1"
+
+	// DefaultGogoProtobufVersion is the default version of
+	// github.com/gogo/protobuf to take gogoproto/gogo.proto from
+	// when a gogo gen preset is used.
+	DefaultGogoProtobufVersion = "1.3.2"
+
+	// DefaultGRPCGatewayVersion is the default version of
+	// github.com/grpc-ecosystem/grpc-gateway to download
+	// protoc-gen-grpc-gateway and protoc-gen-openapiv2 for
+	// when the grpc-gateway or openapiv2 gen preset is used.
+	DefaultGRPCGatewayVersion = "2.16.0"
+
+	// DefaultProtobufJavascriptVersion is the default version of
+	// github.com/protocolbuffers/protobuf-javascript to download
+	// protoc-gen-js for when the js gen preset is used.
+	DefaultProtobufJavascriptVersion = "3.21.2"
 )
 
 var (
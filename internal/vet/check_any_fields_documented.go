@@ -0,0 +1,73 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vet
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
+)
+
+// checkAnyFieldsDocumented verifies that fields of type google.protobuf.Any
+// have a comment, as the types that are expected to be packed in the
+// field cannot be expressed otherwise.
+func checkAnyFieldsDocumented(add func(*text.Failure), config settings.VetConfig, descriptors []*proto.Proto) {
+	for _, descriptor := range descriptors {
+		walkMessages(descriptor, func(name string, message *proto.Message) {
+			for _, element := range message.Elements {
+				var field *proto.Field
+				switch t := element.(type) {
+				case *proto.NormalField:
+					field = t.Field
+				case *proto.MapField:
+					field = t.Field
+				case *proto.Oneof:
+					for _, oneofElement := range t.Elements {
+						if oneOfField, ok := oneofElement.(*proto.OneOfField); ok {
+							checkAnyFieldDocumented(add, name, oneOfField.Field)
+						}
+					}
+				}
+				if field != nil {
+					checkAnyFieldDocumented(add, name, field)
+				}
+			}
+		})
+	}
+}
+
+func checkAnyFieldDocumented(add func(*text.Failure), messageName string, field *proto.Field) {
+	if strings.TrimPrefix(field.Type, ".") != "google.protobuf.Any" {
+		return
+	}
+	if hasComment(field.Comment) || hasComment(field.InlineComment) {
+		return
+	}
+	add(text.NewFailuref(
+		field.Position,
+		"",
+		"Field %q of type google.protobuf.Any in message %q should have a comment documenting the types it is expected to contain.",
+		field.Name,
+		messageName,
+	))
+}
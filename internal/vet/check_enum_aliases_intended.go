@@ -0,0 +1,79 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vet
+
+import (
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
+)
+
+// checkEnumAliasesIntended verifies that the enums that set allow_alias
+// have aliases, and that each alias has a comment documenting why it
+// shares a number with another value.
+func checkEnumAliasesIntended(add func(*text.Failure), config settings.VetConfig, descriptors []*proto.Proto) {
+	for _, descriptor := range descriptors {
+		walkEnums(descriptor, func(enum *proto.Enum) {
+			var allowAliasOption *proto.Option
+			numberToName := make(map[int]string)
+			var aliases []*proto.EnumField
+			for _, element := range enum.Elements {
+				switch t := element.(type) {
+				case *proto.Option:
+					if t.Name == "allow_alias" && t.Constant.Source == "true" {
+						allowAliasOption = t
+					}
+				case *proto.EnumField:
+					if _, ok := numberToName[t.Integer]; ok {
+						aliases = append(aliases, t)
+					} else {
+						numberToName[t.Integer] = t.Name
+					}
+				}
+			}
+			if allowAliasOption == nil {
+				return
+			}
+			if len(aliases) == 0 {
+				add(text.NewFailuref(
+					allowAliasOption.Position,
+					"",
+					"Enum %q sets allow_alias but no values share a number.",
+					enum.Name,
+				))
+				return
+			}
+			for _, alias := range aliases {
+				if hasComment(alias.Comment) || hasComment(alias.InlineComment) {
+					continue
+				}
+				add(text.NewFailuref(
+					alias.Position,
+					"",
+					"Enum value %q shares the number %d with %q but has no comment documenting why it is an alias.",
+					alias.Name,
+					alias.Integer,
+					numberToName[alias.Integer],
+				))
+			}
+		})
+	}
+}
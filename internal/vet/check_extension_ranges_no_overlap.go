@@ -0,0 +1,147 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vet
+
+import (
+	"strings"
+	"text/scanner"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
+)
+
+// checkExtensionRangesNoOverlap verifies that the extension ranges of a
+// message do not overlap each other or the reserved ranges of the message,
+// and that extensions of the same message in different files do not use
+// the same number, which protoc only detects if the files are compiled together.
+func checkExtensionRangesNoOverlap(add func(*text.Failure), config settings.VetConfig, descriptors []*proto.Proto) {
+	type extension struct {
+		name     string
+		filename string
+	}
+	extendeeToNumberToExtension := make(map[string]map[int]*extension)
+	for _, descriptor := range descriptors {
+		walkMessages(descriptor, func(name string, message *proto.Message) {
+			checkMessageExtensionRangesNoOverlap(add, name, message)
+		})
+		pkg := getPackage(descriptor)
+		for _, element := range descriptor.Elements {
+			message, ok := element.(*proto.Message)
+			if !ok || !message.IsExtend {
+				continue
+			}
+			extendee := strings.TrimPrefix(message.Name, ".")
+			if !strings.Contains(extendee, ".") && pkg != "" {
+				extendee = pkg + "." + extendee
+			}
+			numberToExtension, ok := extendeeToNumberToExtension[extendee]
+			if !ok {
+				numberToExtension = make(map[int]*extension)
+				extendeeToNumberToExtension[extendee] = numberToExtension
+			}
+			for _, extendElement := range message.Elements {
+				field, ok := extendElement.(*proto.NormalField)
+				if !ok {
+					continue
+				}
+				if existing, ok := numberToExtension[field.Sequence]; ok && existing.filename != descriptor.Filename {
+					add(text.NewFailuref(
+						field.Position,
+						"",
+						"Extension %q of %q uses the number %d, which is also used by the extension %q in %s.",
+						field.Name,
+						extendee,
+						field.Sequence,
+						existing.name,
+						existing.filename,
+					))
+					continue
+				}
+				numberToExtension[field.Sequence] = &extension{
+					name:     field.Name,
+					filename: descriptor.Filename,
+				}
+			}
+		}
+	}
+}
+
+func checkMessageExtensionRangesNoOverlap(add func(*text.Failure), messageName string, message *proto.Message) {
+	type positionedRange struct {
+		from     int
+		to       int
+		source   string
+		position scanner.Position
+	}
+	var extensionRanges []*positionedRange
+	var reservedRanges []*positionedRange
+	for _, element := range message.Elements {
+		switch t := element.(type) {
+		case *proto.Extensions:
+			for _, r := range t.Ranges {
+				from, to := rangeBounds(r)
+				extensionRanges = append(extensionRanges, &positionedRange{
+					from:     from,
+					to:       to,
+					source:   r.SourceRepresentation(),
+					position: t.Position,
+				})
+			}
+		case *proto.Reserved:
+			for _, r := range t.Ranges {
+				from, to := rangeBounds(r)
+				reservedRanges = append(reservedRanges, &positionedRange{
+					from:     from,
+					to:       to,
+					source:   r.SourceRepresentation(),
+					position: t.Position,
+				})
+			}
+		}
+	}
+	for i, extensionRange := range extensionRanges {
+		for _, otherExtensionRange := range extensionRanges[:i] {
+			if extensionRange.from <= otherExtensionRange.to && otherExtensionRange.from <= extensionRange.to {
+				add(text.NewFailuref(
+					extensionRange.position,
+					"",
+					"Extension range %q of message %q overlaps the extension range %q.",
+					extensionRange.source,
+					messageName,
+					otherExtensionRange.source,
+				))
+			}
+		}
+		for _, reservedRange := range reservedRanges {
+			if extensionRange.from <= reservedRange.to && reservedRange.from <= extensionRange.to {
+				add(text.NewFailuref(
+					extensionRange.position,
+					"",
+					"Extension range %q of message %q overlaps the reserved range %q.",
+					extensionRange.source,
+					messageName,
+					reservedRange.source,
+				))
+			}
+		}
+	}
+}
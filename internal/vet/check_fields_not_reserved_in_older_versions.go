@@ -0,0 +1,217 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vet
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"text/scanner"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
+)
+
+// packageVersionRegexp matches packages with a version suffix such as
+// foo.v1, foo.v2beta1, or foo.v1alpha.
+var packageVersionRegexp = regexp.MustCompile(`^(.+)\.v([0-9]+)(?:(alpha|beta)([0-9]*))?$`)
+
+// checkFieldsNotReservedInOlderVersions verifies that fields of messages
+// in a versioned package do not use the numbers or names that are
+// reserved in the message of the same name in an older version of the
+// package, for example foo.v2.Bar and foo.v1.Bar.
+func checkFieldsNotReservedInOlderVersions(add func(*text.Failure), config settings.VetConfig, descriptors []*proto.Proto) {
+	basePackageToVersions := make(map[string][]*packageVersion)
+	for _, descriptor := range descriptors {
+		version, ok := parsePackageVersion(getPackage(descriptor))
+		if !ok {
+			continue
+		}
+		version = getOrAddPackageVersion(basePackageToVersions, version)
+		walkMessages(descriptor, func(name string, message *proto.Message) {
+			version.messages[name] = append(version.messages[name], message)
+		})
+	}
+	basePackages := make([]string, 0, len(basePackageToVersions))
+	for basePackage := range basePackageToVersions {
+		basePackages = append(basePackages, basePackage)
+	}
+	sort.Strings(basePackages)
+	for _, basePackage := range basePackages {
+		versions := basePackageToVersions[basePackage]
+		sort.Slice(versions, func(i int, j int) bool { return versions[i].less(versions[j]) })
+		for i, version := range versions {
+			for _, olderVersion := range versions[:i] {
+				checkVersionFieldsNotReserved(add, version, olderVersion)
+			}
+		}
+	}
+}
+
+func checkVersionFieldsNotReserved(add func(*text.Failure), version *packageVersion, olderVersion *packageVersion) {
+	for name, messages := range version.messages {
+		olderMessages, ok := olderVersion.messages[name]
+		if !ok {
+			continue
+		}
+		for _, message := range messages {
+			for _, field := range getMessageFields(message) {
+				for _, olderMessage := range olderMessages {
+					for _, element := range olderMessage.Elements {
+						reserved, ok := element.(*proto.Reserved)
+						if !ok {
+							continue
+						}
+						if reservedContainsNumber(reserved, field.number) {
+							add(text.NewFailuref(
+								field.position,
+								"",
+								"Field %q in message %q uses the number %d, which is reserved in the older version %s.%s.",
+								field.name,
+								name,
+								field.number,
+								olderVersion.pkg,
+								name,
+							))
+						}
+						if reservedContainsName(reserved, field.name) {
+							add(text.NewFailuref(
+								field.position,
+								"",
+								"Field %q in message %q uses a name that is reserved in the older version %s.%s.",
+								field.name,
+								name,
+								olderVersion.pkg,
+								name,
+							))
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+type packageVersion struct {
+	pkg       string
+	base      string
+	major     int
+	stability int
+	minor     int
+	// nested message name to messages, there may be more than one
+	// message for a name if the files do not compile
+	messages map[string][]*proto.Message
+}
+
+func parsePackageVersion(pkg string) (*packageVersion, bool) {
+	matches := packageVersionRegexp.FindStringSubmatch(pkg)
+	if matches == nil {
+		return nil, false
+	}
+	major, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return nil, false
+	}
+	version := &packageVersion{
+		pkg:       pkg,
+		base:      matches[1],
+		major:     major,
+		stability: 2,
+		messages:  make(map[string][]*proto.Message),
+	}
+	switch matches[3] {
+	case "alpha":
+		version.stability = 0
+	case "beta":
+		version.stability = 1
+	}
+	if matches[4] != "" {
+		version.minor, err = strconv.Atoi(matches[4])
+		if err != nil {
+			return nil, false
+		}
+	}
+	return version, true
+}
+
+func getOrAddPackageVersion(basePackageToVersions map[string][]*packageVersion, version *packageVersion) *packageVersion {
+	for _, existing := range basePackageToVersions[version.base] {
+		if existing.pkg == version.pkg {
+			return existing
+		}
+	}
+	basePackageToVersions[version.base] = append(basePackageToVersions[version.base], version)
+	return version
+}
+
+func (p *packageVersion) less(other *packageVersion) bool {
+	if p.major != other.major {
+		return p.major < other.major
+	}
+	if p.stability != other.stability {
+		return p.stability < other.stability
+	}
+	return p.minor < other.minor
+}
+
+type messageField struct {
+	name     string
+	number   int
+	position scanner.Position
+}
+
+func getMessageFields(message *proto.Message) []*messageField {
+	var fields []*messageField
+	for _, element := range message.Elements {
+		switch t := element.(type) {
+		case *proto.NormalField:
+			fields = append(fields, &messageField{name: t.Name, number: t.Sequence, position: t.Position})
+		case *proto.MapField:
+			fields = append(fields, &messageField{name: t.Name, number: t.Sequence, position: t.Position})
+		case *proto.Oneof:
+			for _, oneofElement := range t.Elements {
+				if oneOfField, ok := oneofElement.(*proto.OneOfField); ok {
+					fields = append(fields, &messageField{name: oneOfField.Name, number: oneOfField.Sequence, position: oneOfField.Position})
+				}
+			}
+		}
+	}
+	return fields
+}
+
+func reservedContainsNumber(reserved *proto.Reserved, number int) bool {
+	for _, r := range reserved.Ranges {
+		if from, to := rangeBounds(r); number >= from && number <= to {
+			return true
+		}
+	}
+	return false
+}
+
+func reservedContainsName(reserved *proto.Reserved, name string) bool {
+	for _, fieldName := range reserved.FieldNames {
+		if fieldName == name {
+			return true
+		}
+	}
+	return false
+}
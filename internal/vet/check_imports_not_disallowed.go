@@ -0,0 +1,71 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vet
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
+)
+
+// checkImportsNotDisallowed verifies that packages do not import, directly
+// or transitively, the packages that are disallowed for them in the config.
+//
+// The failure is reported at the import that starts the offending path.
+func checkImportsNotDisallowed(add func(*text.Failure), config settings.VetConfig, descriptors []*proto.Proto) {
+	if len(config.DisallowedImports) == 0 {
+		return
+	}
+	patterns := make([]string, 0, len(config.DisallowedImports))
+	for pattern := range config.DisallowedImports {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	graph := newImportGraph(descriptors)
+	for _, pkg := range graph.packages() {
+		for _, pattern := range patterns {
+			if !packageMatches(pattern, pkg) {
+				continue
+			}
+			for _, disallowedPattern := range config.DisallowedImports[pattern] {
+				path := graph.shortestPath(pkg, func(importPackage string) bool {
+					return packageMatches(disallowedPattern, importPackage)
+				})
+				if path == nil {
+					continue
+				}
+				edge := graph.edges[path[0]][path[1]]
+				add(text.NewFailuref(
+					edge.position,
+					"",
+					"Package %q may not import %q but imports %q through %s.",
+					pkg,
+					disallowedPattern,
+					path[len(path)-1],
+					strings.Join(path, " -> "),
+				))
+			}
+		}
+	}
+}
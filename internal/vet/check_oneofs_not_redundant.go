@@ -0,0 +1,63 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vet
+
+import (
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
+)
+
+// checkOneofsNotRedundant verifies that oneofs in proto2 files have more
+// than one field, as a single optional field already has presence.
+//
+// In proto3 files, a oneof with a single field is the way to give a
+// field presence, so these are not checked.
+func checkOneofsNotRedundant(add func(*text.Failure), config settings.VetConfig, descriptors []*proto.Proto) {
+	for _, descriptor := range descriptors {
+		if getSyntax(descriptor) != "proto2" {
+			continue
+		}
+		walkMessages(descriptor, func(name string, message *proto.Message) {
+			for _, element := range message.Elements {
+				oneof, ok := element.(*proto.Oneof)
+				if !ok {
+					continue
+				}
+				numFields := 0
+				for _, oneofElement := range oneof.Elements {
+					if _, ok := oneofElement.(*proto.OneOfField); ok {
+						numFields++
+					}
+				}
+				if numFields == 1 {
+					add(text.NewFailuref(
+						oneof.Position,
+						"",
+						"Oneof %q in message %q has a single field, use an optional field instead.",
+						oneof.Name,
+						name,
+					))
+				}
+			}
+		})
+	}
+}
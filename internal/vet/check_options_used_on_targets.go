@@ -0,0 +1,59 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vet
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
+)
+
+// checkOptionsUsedOnTargets verifies that the custom options used that are
+// defined with targets are only used on the kinds of elements in their
+// targets. Only newer versions of protoc enforce targets, so a file that
+// compiles now may fail to compile after upgrading protoc.
+func checkOptionsUsedOnTargets(add func(*text.Failure), config settings.VetConfig, descriptors []*proto.Proto) {
+	nameToDefinition := getOptionDefinitions(descriptors)
+	for _, descriptor := range descriptors {
+		pkg := getPackage(descriptor)
+		walkCustomOptions(descriptor, func(option *proto.Option, target string) {
+			definition, ok := resolveOptionDefinition(nameToDefinition, pkg, option.Name)
+			if !ok || len(definition.targets) == 0 {
+				return
+			}
+			for _, definitionTarget := range definition.targets {
+				if definitionTarget == target {
+					return
+				}
+			}
+			add(text.NewFailuref(
+				option.Position,
+				"",
+				"Option %q is used on %s, but its targets are %s, so newer versions of protoc reject it.",
+				definition.name,
+				targetToDescription[target],
+				strings.Join(definition.targets, ", "),
+			))
+		})
+	}
+}
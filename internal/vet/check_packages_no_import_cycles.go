@@ -0,0 +1,74 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vet
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
+)
+
+// checkPackagesNoImportCycles verifies that there are no cycles in the
+// imports between packages. protoc only detects cycles between files.
+//
+// A failure is reported once for each cycle, at the import that starts
+// the cycle from the package that sorts first.
+func checkPackagesNoImportCycles(add func(*text.Failure), config settings.VetConfig, descriptors []*proto.Proto) {
+	graph := newImportGraph(descriptors)
+	seen := make(map[string]struct{})
+	for _, pkg := range graph.packages() {
+		path := graph.shortestPath(pkg, func(importPackage string) bool {
+			return importPackage == pkg
+		})
+		if path == nil {
+			continue
+		}
+		key := cycleKey(path)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		edge := graph.edges[path[0]][path[1]]
+		add(text.NewFailuref(
+			edge.position,
+			"",
+			"Package %q has an import cycle: %s.",
+			pkg,
+			strings.Join(path, " -> "),
+		))
+	}
+}
+
+// cycleKey returns a key for the cycle that is the same regardless of
+// the package that the cycle starts at.
+func cycleKey(path []string) string {
+	// the last package is the same as the first
+	cycle := path[:len(path)-1]
+	minIndex := 0
+	for i, pkg := range cycle {
+		if pkg < cycle[minIndex] {
+			minIndex = i
+		}
+	}
+	return strings.Join(append(append([]string{}, cycle[minIndex:]...), cycle[:minIndex]...), " ")
+}
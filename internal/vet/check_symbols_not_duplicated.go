@@ -0,0 +1,75 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vet
+
+import (
+	"text/scanner"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
+)
+
+// checkSymbolsNotDuplicated verifies that a fully-qualified message or
+// service is not defined in more than one file, including the files that
+// the files being vetted import. This usually means that a file was
+// vendored under a different path, and protoc only reports it, with a
+// confusing error, when both files are compiled together.
+//
+// A failure is reported at each definition after the first, and at the
+// first definition for each later definition.
+func checkSymbolsNotDuplicated(add func(*text.Failure), config settings.VetConfig, descriptors []*proto.Proto) {
+	type symbol struct {
+		kind     string
+		position scanner.Position
+	}
+	nameToSymbol := make(map[string]*symbol)
+	define := func(name string, kind string, position scanner.Position) {
+		existing, ok := nameToSymbol[name]
+		if !ok {
+			nameToSymbol[name] = &symbol{
+				kind:     kind,
+				position: position,
+			}
+			return
+		}
+		// duplicates within a file are reported by protoc
+		if existing.position.Filename == position.Filename {
+			return
+		}
+		add(text.NewFailuref(position, "", "%s %q is also defined in %s.", kind, name, existing.position))
+		add(text.NewFailuref(existing.position, "", "%s %q is also defined in %s.", existing.kind, name, position))
+	}
+	for _, descriptor := range descriptors {
+		prefix := ""
+		if pkg := getPackage(descriptor); pkg != "" {
+			prefix = pkg + "."
+		}
+		walkMessages(descriptor, func(name string, message *proto.Message) {
+			define(prefix+name, "Message", message.Position)
+		})
+		for _, element := range descriptor.Elements {
+			if service, ok := element.(*proto.Service); ok {
+				define(prefix+service.Name, "Service", service.Position)
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vet
+
+import (
+	"sort"
+	"strings"
+	"text/scanner"
+
+	"github.com/emicklei/proto"
+)
+
+// importGraph is the graph of imports between packages.
+type importGraph struct {
+	// package to imported package to the first import that
+	// results in the edge
+	edges map[string]map[string]*importEdge
+}
+
+// importEdge is an import of a file in another package.
+type importEdge struct {
+	filename       string
+	importFilename string
+	position       scanner.Position
+}
+
+// newImportGraph returns the import graph for the descriptors.
+//
+// Imported files are resolved to the descriptors by their path, so only
+// imports of the files being vetted are in the graph. Imports of files
+// within the same package are ignored.
+func newImportGraph(descriptors []*proto.Proto) *importGraph {
+	filenameToPackage := make(map[string]string, len(descriptors))
+	for _, descriptor := range descriptors {
+		filenameToPackage[descriptor.Filename] = getPackage(descriptor)
+	}
+	graph := &importGraph{
+		edges: make(map[string]map[string]*importEdge),
+	}
+	for _, descriptor := range descriptors {
+		pkg := getPackage(descriptor)
+		if pkg == "" {
+			continue
+		}
+		for _, element := range descriptor.Elements {
+			protoImport, ok := element.(*proto.Import)
+			if !ok {
+				continue
+			}
+			importPackage, ok := resolveImportPackage(filenameToPackage, protoImport.Filename)
+			if !ok || importPackage == "" || importPackage == pkg {
+				continue
+			}
+			if graph.edges[pkg] == nil {
+				graph.edges[pkg] = make(map[string]*importEdge)
+			}
+			if _, ok := graph.edges[pkg][importPackage]; !ok {
+				graph.edges[pkg][importPackage] = &importEdge{
+					filename:       descriptor.Filename,
+					importFilename: protoImport.Filename,
+					position:       protoImport.Position,
+				}
+			}
+		}
+	}
+	return graph
+}
+
+// packages returns the sorted packages that import other packages.
+func (g *importGraph) packages() []string {
+	packages := make([]string, 0, len(g.edges))
+	for pkg := range g.edges {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+	return packages
+}
+
+// imports returns the sorted packages imported by the package.
+func (g *importGraph) imports(pkg string) []string {
+	imports := make([]string, 0, len(g.edges[pkg]))
+	for importPackage := range g.edges[pkg] {
+		imports = append(imports, importPackage)
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// shortestPath returns the shortest path of packages from the package
+// to a package for which match returns true, or nil if there is none.
+//
+// The path starts with from and has at least two packages.
+func (g *importGraph) shortestPath(from string, match func(string) bool) []string {
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		for _, importPackage := range g.imports(pkg) {
+			if match(importPackage) {
+				path := []string{importPackage}
+				for p := pkg; p != ""; p = previous[p] {
+					path = append([]string{p}, path...)
+				}
+				return path
+			}
+			if _, ok := previous[importPackage]; !ok {
+				previous[importPackage] = pkg
+				queue = append(queue, importPackage)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveImportPackage returns the package of the imported file, if the
+// file is one of the files being vetted.
+func resolveImportPackage(filenameToPackage map[string]string, importFilename string) (string, bool) {
+	if pkg, ok := filenameToPackage[importFilename]; ok {
+		return pkg, true
+	}
+	for filename, pkg := range filenameToPackage {
+		if strings.HasSuffix(filename, "/"+importFilename) {
+			return pkg, true
+		}
+	}
+	return "", false
+}
+
+// packageMatches returns true if the package matches the pattern, which
+// is either a package, or a package followed by .* which matches the
+// package and all packages within it.
+func packageMatches(pattern string, pkg string) bool {
+	if prefix := strings.TrimSuffix(pattern, ".*"); prefix != pattern {
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+".")
+	}
+	return pkg == pattern
+}
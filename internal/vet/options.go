@@ -0,0 +1,196 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vet
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+)
+
+// targetToDescription maps the TARGET_TYPE_* values to the kinds of
+// elements that walkCustomOptions passes them for.
+var targetToDescription = map[string]string{
+	"TARGET_TYPE_FILE":       "a file",
+	"TARGET_TYPE_MESSAGE":    "a message",
+	"TARGET_TYPE_FIELD":      "a field",
+	"TARGET_TYPE_ONEOF":      "a oneof",
+	"TARGET_TYPE_ENUM":       "an enum",
+	"TARGET_TYPE_ENUM_ENTRY": "an enum value",
+	"TARGET_TYPE_SERVICE":    "a service",
+	"TARGET_TYPE_METHOD":     "an RPC",
+}
+
+// optionDefinition is a custom option, that is an extension of one of the
+// google.protobuf.*Options messages, with the retention and targets set on it.
+type optionDefinition struct {
+	name      string
+	retention string
+	targets   []string
+}
+
+// getOptionDefinitions returns the extensions defined in the descriptors
+// keyed by their fully-qualified names.
+//
+// Every extension is returned, as the extendee of an option is checked by protoc.
+func getOptionDefinitions(descriptors []*proto.Proto) map[string]*optionDefinition {
+	nameToDefinition := make(map[string]*optionDefinition)
+	addExtend := func(prefix string, extend *proto.Message) {
+		for _, element := range extend.Elements {
+			field, ok := element.(*proto.NormalField)
+			if !ok {
+				continue
+			}
+			definition := &optionDefinition{
+				name: prefix + field.Name,
+			}
+			for _, option := range field.Options {
+				switch option.Name {
+				case "retention":
+					definition.retention = option.Constant.Source
+				case "targets":
+					definition.targets = append(definition.targets, option.Constant.Source)
+				}
+			}
+			nameToDefinition[definition.name] = definition
+		}
+	}
+	for _, descriptor := range descriptors {
+		prefix := ""
+		if pkg := getPackage(descriptor); pkg != "" {
+			prefix = pkg + "."
+		}
+		for _, element := range descriptor.Elements {
+			if message, ok := element.(*proto.Message); ok && message.IsExtend {
+				addExtend(prefix, message)
+			}
+		}
+		walkMessages(descriptor, func(name string, message *proto.Message) {
+			for _, element := range message.Elements {
+				if nestedMessage, ok := element.(*proto.Message); ok && nestedMessage.IsExtend {
+					addExtend(prefix+name+".", nestedMessage)
+				}
+			}
+		})
+	}
+	return nameToDefinition
+}
+
+// resolveOptionDefinition returns the definition of the custom option used
+// with the given name, such as (bar) or (foo.v1.bar).baz, in a file of the
+// given package, resolving the name relative to each enclosing package.
+func resolveOptionDefinition(nameToDefinition map[string]*optionDefinition, pkg string, optionName string) (*optionDefinition, bool) {
+	end := strings.Index(optionName, ")")
+	if !strings.HasPrefix(optionName, "(") || end < 0 {
+		return nil, false
+	}
+	name := optionName[1:end]
+	if strings.HasPrefix(name, ".") {
+		definition, ok := nameToDefinition[name[1:]]
+		return definition, ok
+	}
+	scope := pkg
+	for {
+		candidate := name
+		if scope != "" {
+			candidate = scope + "." + name
+		}
+		if definition, ok := nameToDefinition[candidate]; ok {
+			return definition, true
+		}
+		if scope == "" {
+			return nil, false
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+// walkCustomOptions calls f for every custom option used in the descriptor
+// with the TARGET_TYPE_* value of the kind of element it is used on.
+func walkCustomOptions(descriptor *proto.Proto, f func(option *proto.Option, target string)) {
+	visitOptions := func(elements []proto.Visitee, target string) {
+		for _, element := range elements {
+			if option, ok := element.(*proto.Option); ok && isCustomOption(option) {
+				f(option, target)
+			}
+		}
+	}
+	visitField := func(field *proto.Field) {
+		for _, option := range field.Options {
+			if isCustomOption(option) {
+				f(option, "TARGET_TYPE_FIELD")
+			}
+		}
+	}
+	visitEnum := func(enum *proto.Enum) {
+		visitOptions(enum.Elements, "TARGET_TYPE_ENUM")
+		for _, element := range enum.Elements {
+			if enumField, ok := element.(*proto.EnumField); ok {
+				visitOptions(enumField.Elements, "TARGET_TYPE_ENUM_ENTRY")
+			}
+		}
+	}
+	var visitMessage func(message *proto.Message)
+	visitMessage = func(message *proto.Message) {
+		if !message.IsExtend {
+			visitOptions(message.Elements, "TARGET_TYPE_MESSAGE")
+		}
+		for _, element := range message.Elements {
+			switch t := element.(type) {
+			case *proto.NormalField:
+				visitField(t.Field)
+			case *proto.MapField:
+				visitField(t.Field)
+			case *proto.Oneof:
+				visitOptions(t.Elements, "TARGET_TYPE_ONEOF")
+				for _, oneofElement := range t.Elements {
+					if oneofField, ok := oneofElement.(*proto.OneOfField); ok {
+						visitField(oneofField.Field)
+					}
+				}
+			case *proto.Enum:
+				visitEnum(t)
+			case *proto.Message:
+				visitMessage(t)
+			}
+		}
+	}
+	visitOptions(descriptor.Elements, "TARGET_TYPE_FILE")
+	for _, element := range descriptor.Elements {
+		switch t := element.(type) {
+		case *proto.Message:
+			visitMessage(t)
+		case *proto.Enum:
+			visitEnum(t)
+		case *proto.Service:
+			visitOptions(t.Elements, "TARGET_TYPE_SERVICE")
+			for _, serviceElement := range t.Elements {
+				if rpc, ok := serviceElement.(*proto.RPC); ok {
+					visitOptions(rpc.Elements, "TARGET_TYPE_METHOD")
+				}
+			}
+		}
+	}
+}
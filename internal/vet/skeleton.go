@@ -0,0 +1,189 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vet
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+)
+
+// newSkeleton returns a copy of the descriptor with only the elements
+// that the cross-file checks need, that is the syntax, package, imports,
+// messages with their fields, reserved ranges, and extension ranges,
+// enums with their values, and services with their RPCs, and only the
+// custom options of each, plus the retention and targets of fields.
+//
+// Comments, built-in options, and parent pointers are dropped so
+// that the parsed file can be released once the per-directory checks
+// have run over it.
+func newSkeleton(descriptor *proto.Proto) *proto.Proto {
+	skeleton := &proto.Proto{
+		Filename: descriptor.Filename,
+	}
+	for _, element := range descriptor.Elements {
+		switch t := element.(type) {
+		case *proto.Syntax:
+			skeleton.Elements = append(skeleton.Elements, &proto.Syntax{Position: t.Position, Value: t.Value})
+		case *proto.Package:
+			skeleton.Elements = append(skeleton.Elements, &proto.Package{Position: t.Position, Name: t.Name})
+		case *proto.Import:
+			skeleton.Elements = append(skeleton.Elements, &proto.Import{Position: t.Position, Filename: t.Filename, Kind: t.Kind})
+		case *proto.Message:
+			skeleton.Elements = append(skeleton.Elements, newMessageSkeleton(t))
+		case *proto.Enum:
+			skeleton.Elements = append(skeleton.Elements, newEnumSkeleton(t))
+		case *proto.Service:
+			skeleton.Elements = append(skeleton.Elements, newServiceSkeleton(t))
+		case *proto.Option:
+			if isCustomOption(t) {
+				skeleton.Elements = append(skeleton.Elements, newOptionSkeleton(t))
+			}
+		}
+	}
+	return skeleton
+}
+
+func newMessageSkeleton(message *proto.Message) *proto.Message {
+	skeleton := &proto.Message{
+		Position: message.Position,
+		Name:     message.Name,
+		IsExtend: message.IsExtend,
+	}
+	for _, element := range message.Elements {
+		switch t := element.(type) {
+		case *proto.NormalField:
+			skeleton.Elements = append(skeleton.Elements, &proto.NormalField{
+				Field:    newFieldSkeleton(t.Field),
+				Repeated: t.Repeated,
+				Optional: t.Optional,
+				Required: t.Required,
+			})
+		case *proto.MapField:
+			skeleton.Elements = append(skeleton.Elements, &proto.MapField{Field: newFieldSkeleton(t.Field), KeyType: t.KeyType})
+		case *proto.Oneof:
+			oneof := &proto.Oneof{Position: t.Position, Name: t.Name}
+			for _, oneofElement := range t.Elements {
+				switch oneofElement := oneofElement.(type) {
+				case *proto.OneOfField:
+					oneof.Elements = append(oneof.Elements, &proto.OneOfField{Field: newFieldSkeleton(oneofElement.Field)})
+				case *proto.Option:
+					if isCustomOption(oneofElement) {
+						oneof.Elements = append(oneof.Elements, newOptionSkeleton(oneofElement))
+					}
+				}
+			}
+			skeleton.Elements = append(skeleton.Elements, oneof)
+		case *proto.Reserved:
+			skeleton.Elements = append(skeleton.Elements, &proto.Reserved{Position: t.Position, Ranges: t.Ranges, FieldNames: t.FieldNames})
+		case *proto.Extensions:
+			skeleton.Elements = append(skeleton.Elements, &proto.Extensions{Position: t.Position, Ranges: t.Ranges})
+		case *proto.Message:
+			skeleton.Elements = append(skeleton.Elements, newMessageSkeleton(t))
+		case *proto.Enum:
+			skeleton.Elements = append(skeleton.Elements, newEnumSkeleton(t))
+		case *proto.Option:
+			if isCustomOption(t) {
+				skeleton.Elements = append(skeleton.Elements, newOptionSkeleton(t))
+			}
+		}
+	}
+	return skeleton
+}
+
+func newFieldSkeleton(field *proto.Field) *proto.Field {
+	skeleton := &proto.Field{
+		Position: field.Position,
+		Name:     field.Name,
+		Type:     field.Type,
+		Sequence: field.Sequence,
+	}
+	for _, option := range field.Options {
+		if isCustomOption(option) || option.Name == "retention" || option.Name == "targets" {
+			skeleton.Options = append(skeleton.Options, newOptionSkeleton(option))
+		}
+	}
+	return skeleton
+}
+
+func newEnumSkeleton(enum *proto.Enum) *proto.Enum {
+	skeleton := &proto.Enum{
+		Position: enum.Position,
+		Name:     enum.Name,
+	}
+	for _, element := range enum.Elements {
+		switch t := element.(type) {
+		case *proto.EnumField:
+			enumField := &proto.EnumField{Position: t.Position, Name: t.Name, Integer: t.Integer}
+			for _, enumFieldElement := range t.Elements {
+				if option, ok := enumFieldElement.(*proto.Option); ok && isCustomOption(option) {
+					enumField.Elements = append(enumField.Elements, newOptionSkeleton(option))
+				}
+			}
+			skeleton.Elements = append(skeleton.Elements, enumField)
+		case *proto.Option:
+			if isCustomOption(t) {
+				skeleton.Elements = append(skeleton.Elements, newOptionSkeleton(t))
+			}
+		}
+	}
+	return skeleton
+}
+
+func newServiceSkeleton(service *proto.Service) *proto.Service {
+	skeleton := &proto.Service{
+		Position: service.Position,
+		Name:     service.Name,
+	}
+	for _, element := range service.Elements {
+		switch t := element.(type) {
+		case *proto.RPC:
+			rpc := &proto.RPC{Position: t.Position, Name: t.Name}
+			for _, rpcElement := range t.Elements {
+				if option, ok := rpcElement.(*proto.Option); ok && isCustomOption(option) {
+					rpc.Elements = append(rpc.Elements, newOptionSkeleton(option))
+				}
+			}
+			skeleton.Elements = append(skeleton.Elements, rpc)
+		case *proto.Option:
+			if isCustomOption(t) {
+				skeleton.Elements = append(skeleton.Elements, newOptionSkeleton(t))
+			}
+		}
+	}
+	return skeleton
+}
+
+// newOptionSkeleton returns a copy of the option without its value,
+// except for a constant value such as RETENTION_SOURCE.
+func newOptionSkeleton(option *proto.Option) *proto.Option {
+	return &proto.Option{
+		Position: option.Position,
+		Name:     option.Name,
+		Constant: proto.Literal{Source: option.Constant.Source},
+	}
+}
+
+// isCustomOption returns true if the option is an extension, such as
+// (foo.v1.bar) or (foo.v1.bar).baz.
+func isCustomOption(option *proto.Option) bool {
+	return strings.HasPrefix(option.Name, "(")
+}
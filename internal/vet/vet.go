@@ -0,0 +1,54 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package vet performs semantic checks on Protobuf files that protoc
+// does not perform.
+package vet
+
+import (
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/text"
+	"go.uber.org/zap"
+)
+
+// Vetter vets Protobuf files.
+type Vetter interface {
+	// Vet vets the files in the ProtoSet.
+	//
+	// The files are expected to compile.
+	Vet(protoSet *file.ProtoSet) ([]*text.Failure, error)
+}
+
+// VetterOption is an option for a new Vetter.
+type VetterOption func(*vetter)
+
+// VetterWithLogger returns a VetterOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func VetterWithLogger(logger *zap.Logger) VetterOption {
+	return func(vetter *vetter) {
+		vetter.logger = logger
+	}
+}
+
+// NewVetter returns a new Vetter.
+func NewVetter(options ...VetterOption) Vetter {
+	return newVetter(options...)
+}
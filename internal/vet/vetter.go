@@ -0,0 +1,380 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vet
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/emicklei/proto"
+	"github.com/uber/prototool/internal/editions"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/lint"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
+	"go.uber.org/zap"
+)
+
+// maxFieldNumber is the maximum field number, which is used for ranges
+// that end in max.
+const maxFieldNumber = 536870911
+
+// checkFunc adds failures for the descriptors sorted by filename, which
+// are the files of one directory, or the skeletons of all the files being
+// vetted for cross-file checks.
+type checkFunc func(add func(*text.Failure), config settings.VetConfig, descriptors []*proto.Proto)
+
+type check struct {
+	ID string
+	f  checkFunc
+	// crossFile is true if the check needs the files of more than one
+	// directory, in which case it runs once over the skeletons of all
+	// files instead of once per directory.
+	crossFile bool
+	// imports is true if the cross-file check also needs the skeletons of
+	// the files that the files being vetted transitively import from the
+	// include paths. Failures in the imported files are dropped.
+	imports bool
+}
+
+var allChecks = []*check{
+	{
+		ID: "ANY_FIELDS_DOCUMENTED",
+		f:  checkAnyFieldsDocumented,
+	},
+	{
+		ID: "ENUM_ALIASES_INTENDED",
+		f:  checkEnumAliasesIntended,
+	},
+	{
+		ID:        "EXTENSION_RANGES_NO_OVERLAP",
+		f:         checkExtensionRangesNoOverlap,
+		crossFile: true,
+	},
+	{
+		ID:        "FIELDS_NOT_RESERVED_IN_OLDER_VERSIONS",
+		f:         checkFieldsNotReservedInOlderVersions,
+		crossFile: true,
+	},
+	{
+		ID:        "IMPORTS_NOT_DISALLOWED",
+		f:         checkImportsNotDisallowed,
+		crossFile: true,
+	},
+	{
+		ID: "ONEOFS_NOT_REDUNDANT",
+		f:  checkOneofsNotRedundant,
+	},
+	{
+		ID:        "OPTIONS_RETAINED_AT_RUNTIME",
+		f:         checkOptionsRetainedAtRuntime,
+		crossFile: true,
+		imports:   true,
+	},
+	{
+		ID:        "OPTIONS_USED_ON_TARGETS",
+		f:         checkOptionsUsedOnTargets,
+		crossFile: true,
+		imports:   true,
+	},
+	{
+		ID:        "PACKAGES_NO_IMPORT_CYCLES",
+		f:         checkPackagesNoImportCycles,
+		crossFile: true,
+	},
+	{
+		ID:        "SYMBOLS_NOT_DUPLICATED",
+		f:         checkSymbolsNotDuplicated,
+		crossFile: true,
+		imports:   true,
+	},
+}
+
+type vetter struct {
+	logger *zap.Logger
+}
+
+func newVetter(options ...VetterOption) *vetter {
+	vetter := &vetter{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(vetter)
+	}
+	return vetter
+}
+
+func (v *vetter) Vet(protoSet *file.ProtoSet) ([]*text.Failure, error) {
+	dirPaths := make([]string, 0, len(protoSet.DirPathToFiles))
+	for dirPath := range protoSet.DirPathToFiles {
+		dirPaths = append(dirPaths, dirPath)
+	}
+	sort.Strings(dirPaths)
+	// parse one directory at a time and only keep the skeletons of the
+	// files for the cross-file checks so that the whole corpus is never
+	// held in memory at once
+	var failures []*text.Failure
+	var skeletons []*proto.Proto
+	for _, dirPath := range dirPaths {
+		dirPathToDescriptors, err := lint.GetDirPathToDescriptors(&file.ProtoSet{
+			WorkDirPath:    protoSet.WorkDirPath,
+			DirPath:        protoSet.DirPath,
+			DirPathToFiles: map[string][]*file.ProtoFile{dirPath: protoSet.DirPathToFiles[dirPath]},
+			Config:         protoSet.Config,
+		})
+		if err != nil {
+			return nil, err
+		}
+		descriptors := dirPathToDescriptors[dirPath]
+		failures = append(failures, v.runChecks(protoSet.Config.Vet, descriptors, false, nil)...)
+		for _, descriptor := range descriptors {
+			skeletons = append(skeletons, newSkeleton(descriptor))
+		}
+	}
+	imports, err := v.getImportSkeletons(protoSet, skeletons)
+	if err != nil {
+		return nil, err
+	}
+	failures = append(failures, v.runChecks(protoSet.Config.Vet, skeletons, true, imports)...)
+	text.SortFailures(failures)
+	return failures, nil
+}
+
+func (v *vetter) vetDescriptors(config settings.VetConfig, descriptors []*proto.Proto) []*text.Failure {
+	failures := v.runChecks(config, descriptors, false, nil)
+	failures = append(failures, v.runChecks(config, descriptors, true, nil)...)
+	text.SortFailures(failures)
+	return failures
+}
+
+// runChecks runs either the per-directory or the cross-file checks.
+//
+// The imports are only passed to the cross-file checks that need them.
+func (v *vetter) runChecks(config settings.VetConfig, descriptors []*proto.Proto, crossFile bool, imports []*proto.Proto) []*text.Failure {
+	sort.Slice(descriptors, func(i int, j int) bool { return descriptors[i].Filename < descriptors[j].Filename })
+	filenames := make(map[string]struct{}, len(descriptors))
+	for _, descriptor := range descriptors {
+		filenames[descriptor.Filename] = struct{}{}
+	}
+	var failures []*text.Failure
+	for _, check := range allChecks {
+		if check.crossFile != crossFile {
+			continue
+		}
+		checkDescriptors := descriptors
+		if check.imports && len(imports) > 0 {
+			checkDescriptors = append(append([]*proto.Proto{}, descriptors...), imports...)
+			sort.Slice(checkDescriptors, func(i int, j int) bool { return checkDescriptors[i].Filename < checkDescriptors[j].Filename })
+		}
+		v.logger.Debug("running check", zap.String("id", check.ID))
+		check.f(
+			func(failure *text.Failure) {
+				if _, ok := filenames[failure.Filename]; check.imports && !ok {
+					return
+				}
+				failure.ID = check.ID
+				failures = append(failures, failure)
+			},
+			config,
+			checkDescriptors,
+		)
+	}
+	return failures
+}
+
+// getImportSkeletons returns the skeletons of the files that the files
+// being vetted transitively import from the include paths of the config,
+// not including the files being vetted.
+//
+// Imports that are not on the include paths, such as the Well-Known Types,
+// and files that do not parse are skipped, as protoc reports these.
+func (v *vetter) getImportSkeletons(protoSet *file.ProtoSet, skeletons []*proto.Proto) ([]*proto.Proto, error) {
+	needsImports := false
+	for _, check := range allChecks {
+		needsImports = needsImports || check.imports
+	}
+	if !needsImports {
+		return nil, nil
+	}
+	includePaths := file.GetIncludePaths(protoSet)
+	seenPaths := make(map[string]struct{})
+	for _, protoFiles := range protoSet.DirPathToFiles {
+		for _, protoFile := range protoFiles {
+			seenPaths[protoFile.Path] = struct{}{}
+		}
+	}
+	var imports []*proto.Proto
+	queue := skeletons
+	for len(queue) > 0 {
+		descriptor := queue[0]
+		queue = queue[1:]
+		for _, element := range descriptor.Elements {
+			protoImport, ok := element.(*proto.Import)
+			if !ok {
+				continue
+			}
+			path, ok := findImport(includePaths, protoImport.Filename)
+			if !ok {
+				continue
+			}
+			if _, ok := seenPaths[path]; ok {
+				continue
+			}
+			seenPaths[path] = struct{}{}
+			importDescriptor, err := parseImport(protoSet.WorkDirPath, path)
+			if err != nil {
+				return nil, err
+			}
+			if importDescriptor == nil {
+				continue
+			}
+			v.logger.Debug("parsed import", zap.String("path", path))
+			skeleton := newSkeleton(importDescriptor)
+			imports = append(imports, skeleton)
+			queue = append(queue, skeleton)
+		}
+	}
+	return imports, nil
+}
+
+// findImport returns the path of the imported file in the first include
+// path that contains it.
+func findImport(includePaths []string, importFilename string) (string, bool) {
+	for _, includePath := range includePaths {
+		path := filepath.Join(includePath, filepath.FromSlash(importFilename))
+		if fileInfo, err := os.Stat(path); err == nil && !fileInfo.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// parseImport parses the imported file with its path relative to the
+// working directory as the filename if possible, returning nil if the
+// file does not parse.
+func parseImport(workDirPath string, path string) (*proto.Proto, error) {
+	osFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	parser, err := editions.NewParser(osFile)
+	_ = osFile.Close()
+	if err != nil {
+		return nil, err
+	}
+	displayPath := path
+	if relPath, err := filepath.Rel(workDirPath, path); err == nil {
+		displayPath = relPath
+	}
+	parser.Filename(displayPath)
+	descriptor, err := parser.Parse()
+	if err != nil {
+		return nil, nil
+	}
+	return descriptor, nil
+}
+
+// walkMessages calls f for every message in the descriptor, including
+// nested messages, with the nested name of the message, for example Foo.Bar.
+func walkMessages(descriptor *proto.Proto, f func(name string, message *proto.Message)) {
+	for _, element := range descriptor.Elements {
+		if message, ok := element.(*proto.Message); ok {
+			walkMessagesRec("", message, f)
+		}
+	}
+}
+
+func walkMessagesRec(prefix string, message *proto.Message, f func(string, *proto.Message)) {
+	// extend declarations are parsed as messages
+	if message.IsExtend {
+		return
+	}
+	name := prefix + message.Name
+	f(name, message)
+	for _, element := range message.Elements {
+		if nestedMessage, ok := element.(*proto.Message); ok {
+			walkMessagesRec(name+".", nestedMessage, f)
+		}
+	}
+}
+
+// walkEnums calls f for every enum in the descriptor, including enums
+// nested in messages.
+func walkEnums(descriptor *proto.Proto, f func(enum *proto.Enum)) {
+	for _, element := range descriptor.Elements {
+		if enum, ok := element.(*proto.Enum); ok {
+			f(enum)
+		}
+	}
+	walkMessages(descriptor, func(_ string, message *proto.Message) {
+		for _, element := range message.Elements {
+			if enum, ok := element.(*proto.Enum); ok {
+				f(enum)
+			}
+		}
+	})
+}
+
+// getPackage returns the package of the descriptor, or empty if there is none.
+func getPackage(descriptor *proto.Proto) string {
+	for _, element := range descriptor.Elements {
+		if pkg, ok := element.(*proto.Package); ok {
+			return pkg.Name
+		}
+	}
+	return ""
+}
+
+// getSyntax returns the syntax of the descriptor, which is proto2 if
+// there is no syntax declaration.
+func getSyntax(descriptor *proto.Proto) string {
+	for _, element := range descriptor.Elements {
+		if syntax, ok := element.(*proto.Syntax); ok {
+			return syntax.Value
+		}
+	}
+	return "proto2"
+}
+
+// rangeBounds returns the inclusive bounds of the range.
+func rangeBounds(r proto.Range) (int, int) {
+	if r.Max {
+		return r.From, maxFieldNumber
+	}
+	if r.To < r.From {
+		return r.From, r.From
+	}
+	return r.From, r.To
+}
+
+// hasComment returns true if the comment has any non-empty lines.
+func hasComment(comment *proto.Comment) bool {
+	if comment == nil {
+		return false
+	}
+	for _, line := range comment.Lines {
+		if len(line) > 0 && line != " " {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,453 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vet
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
+)
+
+func TestVet(t *testing.T) {
+	failures := newVetter().vetDescriptors(
+		settings.VetConfig{},
+		parseDescriptors(
+			t,
+			"foo/v1/foo.proto",
+			`syntax = "proto3";
+
+package foo.v1;
+
+import "google/protobuf/any.proto";
+
+message Foo {
+  reserved 2, 4 to 6;
+  reserved "two";
+  int64 one = 1;
+  google.protobuf.Any any = 3;
+}
+
+enum Hello {
+  option allow_alias = true;
+  HELLO_INVALID = 0;
+  HELLO_ONE = 1;
+}
+`,
+			"foo/v2/foo.proto",
+			`syntax = "proto3";
+
+package foo.v2;
+
+import "google/protobuf/any.proto";
+
+message Foo {
+  int64 one = 1;
+  int64 two = 2;
+  int64 five = 5;
+  // Contains a foo.v2.Foo.
+  google.protobuf.Any any = 3;
+}
+
+enum Hello {
+  option allow_alias = true;
+  HELLO_INVALID = 0;
+  HELLO_ONE = 1;
+  HELLO_UNO = 1;
+  // Kept for compatibility.
+  HELLO_EINS = 1;
+}
+`,
+			"bar/bar.proto",
+			`syntax = "proto2";
+
+package bar;
+
+message Bar {
+  extensions 100 to 200, 150 to 300;
+  extensions 1000 to max;
+  reserved 1500;
+  oneof single {
+    int64 one = 1;
+  }
+}
+
+extend Bar {
+  optional int64 baz = 100;
+}
+`,
+			"bar/other.proto",
+			`syntax = "proto2";
+
+package bar;
+
+extend bar.Bar {
+  optional int64 bat = 100;
+}
+`,
+		),
+	)
+	var lines []string
+	for _, failure := range failures {
+		lines = append(lines, fmt.Sprintf("%s:%d:%s", failure.Filename, failure.Line, failure.ID))
+	}
+	assert.Equal(
+		t,
+		[]string{
+			"bar/bar.proto:6:EXTENSION_RANGES_NO_OVERLAP",
+			"bar/bar.proto:7:EXTENSION_RANGES_NO_OVERLAP",
+			"bar/bar.proto:9:ONEOFS_NOT_REDUNDANT",
+			"bar/other.proto:6:EXTENSION_RANGES_NO_OVERLAP",
+			"foo/v1/foo.proto:11:ANY_FIELDS_DOCUMENTED",
+			"foo/v1/foo.proto:15:ENUM_ALIASES_INTENDED",
+			"foo/v2/foo.proto:9:FIELDS_NOT_RESERVED_IN_OLDER_VERSIONS",
+			"foo/v2/foo.proto:9:FIELDS_NOT_RESERVED_IN_OLDER_VERSIONS",
+			"foo/v2/foo.proto:10:FIELDS_NOT_RESERVED_IN_OLDER_VERSIONS",
+			"foo/v2/foo.proto:19:ENUM_ALIASES_INTENDED",
+		},
+		lines,
+	)
+}
+
+func TestVetSkeletons(t *testing.T) {
+	descriptors := parseDescriptors(
+		t,
+		"foo/v1/foo.proto",
+		`syntax = "proto2";
+
+package foo.v1;
+
+import "foo/v2/foo.proto";
+
+// Foo is a foo.
+message Foo {
+  reserved 2;
+  extensions 100 to 200;
+  extensions 150 to 300;
+  optional int64 one = 1 [deprecated = true];
+  message Bar {
+    reserved "two";
+  }
+}
+
+extend Foo {
+  optional int64 ext = 150;
+}
+`,
+		"foo/v2/foo.proto",
+		`syntax = "proto2";
+
+package foo.v2;
+
+import "foo/v1/foo.proto";
+
+message Foo {
+  optional int64 two = 2;
+  message Bar {
+    map<string, int64> two = 1;
+  }
+}
+
+extend foo.v1.Foo {
+  optional int64 other_ext = 150;
+}
+`,
+	)
+	var skeletons []*proto.Proto
+	for _, descriptor := range descriptors {
+		skeletons = append(skeletons, newSkeleton(descriptor))
+	}
+	vetter := newVetter()
+	// failures are sorted by the caller of runChecks
+	failures := vetter.runChecks(settings.VetConfig{}, descriptors, true, nil)
+	text.SortFailures(failures)
+	skeletonFailures := vetter.runChecks(settings.VetConfig{}, skeletons, true, nil)
+	text.SortFailures(skeletonFailures)
+	assert.Equal(t, failures, skeletonFailures)
+	assert.Len(t, skeletonFailures, 5)
+}
+
+func TestVetImports(t *testing.T) {
+	failures := newVetter().vetDescriptors(
+		settings.VetConfig{
+			DisallowedImports: map[string][]string{
+				"foo.*": {"internal.*"},
+			},
+		},
+		parseDescriptors(
+			t,
+			"foo/v1/foo.proto",
+			`syntax = "proto3";
+
+package foo.v1;
+
+import "bar/v1/bar.proto";
+`,
+			"bar/v1/bar.proto",
+			`syntax = "proto3";
+
+package bar.v1;
+
+import "internal/baz/baz.proto";
+`,
+			"internal/baz/baz.proto",
+			`syntax = "proto3";
+
+package internal.baz;
+
+import "bat/bat.proto";
+`,
+			"bat/bat.proto",
+			`syntax = "proto3";
+
+package bat;
+
+import "internal/baz/baz.proto";
+`,
+		),
+	)
+	var lines []string
+	for _, failure := range failures {
+		lines = append(lines, fmt.Sprintf("%s:%d:%s:%s", failure.Filename, failure.Line, failure.ID, failure.Message))
+	}
+	assert.Equal(
+		t,
+		[]string{
+			`bat/bat.proto:5:PACKAGES_NO_IMPORT_CYCLES:Package "bat" has an import cycle: bat -> internal.baz -> bat.`,
+			`foo/v1/foo.proto:5:IMPORTS_NOT_DISALLOWED:Package "foo.v1" may not import "internal.*" but imports "internal.baz" through foo.v1 -> bar.v1 -> internal.baz.`,
+		},
+		lines,
+	)
+}
+
+func TestVetSymbolsNotDuplicated(t *testing.T) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	vendorPath := filepath.Join(tmpDirPath, "vendor")
+	writeFile(t, filepath.Join(tmpDirPath, "foo", "v1", "foo.proto"), `syntax = "proto3";
+
+package foo.v1;
+
+import "bar/v1/bar.proto";
+
+message Foo {
+  message Nested {}
+}
+
+service FooService {}
+`)
+	writeFile(t, filepath.Join(tmpDirPath, "foo", "v1", "other.proto"), `syntax = "proto3";
+
+package foo.v1;
+
+message Other {}
+`)
+	writeFile(t, filepath.Join(vendorPath, "bar", "v1", "bar.proto"), `syntax = "proto3";
+
+package bar.v1;
+
+import "foo/v1/foo_vendored.proto";
+`)
+	writeFile(t, filepath.Join(vendorPath, "foo", "v1", "foo_vendored.proto"), `syntax = "proto3";
+
+package foo.v1;
+
+message Foo {
+  message Nested {}
+}
+
+message Other {}
+`)
+	dirPath := filepath.Join(tmpDirPath, "foo", "v1")
+	failures, err := newVetter().Vet(&file.ProtoSet{
+		WorkDirPath: tmpDirPath,
+		DirPath:     tmpDirPath,
+		DirPathToFiles: map[string][]*file.ProtoFile{
+			dirPath: {
+				{
+					Path:        filepath.Join(dirPath, "foo.proto"),
+					DisplayPath: filepath.Join("foo", "v1", "foo.proto"),
+				},
+				{
+					Path:        filepath.Join(dirPath, "other.proto"),
+					DisplayPath: filepath.Join("foo", "v1", "other.proto"),
+				},
+			},
+		},
+		Config: settings.Config{
+			DirPath: tmpDirPath,
+			Compile: settings.CompileConfig{
+				IncludePaths: []string{vendorPath},
+			},
+		},
+	})
+	require.NoError(t, err)
+	var lines []string
+	for _, failure := range failures {
+		lines = append(lines, fmt.Sprintf("%s:%d:%s:%s", filepath.ToSlash(failure.Filename), failure.Line, failure.ID, filepath.ToSlash(failure.Message)))
+	}
+	assert.Equal(
+		t,
+		[]string{
+			`foo/v1/foo.proto:7:SYMBOLS_NOT_DUPLICATED:Message "foo.v1.Foo" is also defined in vendor/foo/v1/foo_vendored.proto:5:1.`,
+			`foo/v1/foo.proto:8:SYMBOLS_NOT_DUPLICATED:Message "foo.v1.Foo.Nested" is also defined in vendor/foo/v1/foo_vendored.proto:6:3.`,
+			`foo/v1/other.proto:5:SYMBOLS_NOT_DUPLICATED:Message "foo.v1.Other" is also defined in vendor/foo/v1/foo_vendored.proto:9:1.`,
+		},
+		lines,
+	)
+}
+
+func TestVetOptions(t *testing.T) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	vendorPath := filepath.Join(tmpDirPath, "vendor")
+	writeFile(t, filepath.Join(tmpDirPath, "foo", "v1", "foo.proto"), `syntax = "proto3";
+
+package foo.v1;
+
+import "options/v1/options.proto";
+
+option (options.v1.file_note) = "hello";
+
+message Foo {
+  option (options.v1.field_only) = true;
+  string one = 1 [(options.v1.field_only) = true, (options.v1.source_note) = "x"];
+  oneof two {
+    option (options.v1.field_only) = true;
+    int64 three = 3;
+  }
+}
+
+enum Hello {
+  HELLO_INVALID = 0 [(options.v1.field_only) = true];
+}
+
+service HelloService {
+  rpc Hello(Foo) returns (Foo) {
+    option (options.v1.field_only) = true;
+  }
+}
+`)
+	writeFile(t, filepath.Join(vendorPath, "options", "v1", "options.proto"), `syntax = "proto2";
+
+package options.v1;
+
+import "google/protobuf/descriptor.proto";
+
+extend google.protobuf.FileOptions {
+  optional string file_note = 50000;
+}
+
+extend google.protobuf.FieldOptions {
+  optional string source_note = 50001 [retention = RETENTION_SOURCE];
+}
+
+message Extensions {
+  extend google.protobuf.MessageOptions {
+    optional bool nested = 50002 [retention = RETENTION_SOURCE, targets = TARGET_TYPE_MESSAGE];
+  }
+}
+
+extend google.protobuf.FieldOptions {
+  optional bool field_only = 50003 [targets = TARGET_TYPE_FIELD];
+}
+`)
+	dirPath := filepath.Join(tmpDirPath, "foo", "v1")
+	failures, err := newVetter().Vet(&file.ProtoSet{
+		WorkDirPath: tmpDirPath,
+		DirPath:     tmpDirPath,
+		DirPathToFiles: map[string][]*file.ProtoFile{
+			dirPath: {
+				{
+					Path:        filepath.Join(dirPath, "foo.proto"),
+					DisplayPath: filepath.Join("foo", "v1", "foo.proto"),
+				},
+			},
+		},
+		Config: settings.Config{
+			DirPath: tmpDirPath,
+			Compile: settings.CompileConfig{
+				IncludePaths: []string{vendorPath},
+			},
+		},
+	})
+	require.NoError(t, err)
+	var lines []string
+	for _, failure := range failures {
+		lines = append(lines, fmt.Sprintf("%s:%d:%s:%s", filepath.ToSlash(failure.Filename), failure.Line, failure.ID, failure.Message))
+	}
+	assert.Equal(
+		t,
+		[]string{
+			`foo/v1/foo.proto:10:OPTIONS_USED_ON_TARGETS:Option "options.v1.field_only" is used on a message, but its targets are TARGET_TYPE_FIELD, so newer versions of protoc reject it.`,
+			`foo/v1/foo.proto:11:OPTIONS_RETAINED_AT_RUNTIME:Option "options.v1.source_note" has retention RETENTION_SOURCE, so it is stripped from runtime descriptors and cannot be read with reflection.`,
+			`foo/v1/foo.proto:13:OPTIONS_USED_ON_TARGETS:Option "options.v1.field_only" is used on a oneof, but its targets are TARGET_TYPE_FIELD, so newer versions of protoc reject it.`,
+			`foo/v1/foo.proto:19:OPTIONS_USED_ON_TARGETS:Option "options.v1.field_only" is used on an enum value, but its targets are TARGET_TYPE_FIELD, so newer versions of protoc reject it.`,
+			`foo/v1/foo.proto:24:OPTIONS_USED_ON_TARGETS:Option "options.v1.field_only" is used on an RPC, but its targets are TARGET_TYPE_FIELD, so newer versions of protoc reject it.`,
+		},
+		lines,
+	)
+
+	nameToDefinition := getOptionDefinitions(parseDescriptors(t, "options.proto", `syntax = "proto2";
+
+package options.v1;
+
+message Extensions {
+  extend google.protobuf.MessageOptions {
+    optional bool nested = 50002 [retention = RETENTION_SOURCE, targets = TARGET_TYPE_MESSAGE, targets = TARGET_TYPE_FIELD];
+  }
+}
+`))
+	definition, ok := resolveOptionDefinition(nameToDefinition, "options.v2", "(v1.Extensions.nested).foo")
+	require.True(t, ok)
+	assert.Equal(t, "RETENTION_SOURCE", definition.retention)
+	assert.Equal(t, []string{"TARGET_TYPE_MESSAGE", "TARGET_TYPE_FIELD"}, definition.targets)
+	_, ok = resolveOptionDefinition(nameToDefinition, "foo.v1", "(nested)")
+	assert.False(t, ok)
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func parseDescriptors(t *testing.T, filenameToData ...string) []*proto.Proto {
+	var descriptors []*proto.Proto
+	for i := 0; i < len(filenameToData); i += 2 {
+		parser := proto.NewParser(strings.NewReader(filenameToData[i+1]))
+		parser.Filename(filenameToData[i])
+		descriptor, err := parser.Parse()
+		require.NoError(t, err)
+		descriptors = append(descriptors, descriptor)
+	}
+	return descriptors
+}
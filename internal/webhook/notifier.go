@@ -0,0 +1,131 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/uber/prototool/internal/text"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+var templateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	},
+}
+
+type notifier struct {
+	logger  *zap.Logger
+	tmpl    *template.Template
+	headers map[string]string
+	timeout time.Duration
+
+	url        string
+	httpClient *http.Client
+}
+
+func newNotifier(url string, options ...NotifierOption) *notifier {
+	notifier := &notifier{
+		logger:  zap.NewNop(),
+		timeout: DefaultTimeout,
+		url:     url,
+	}
+	for _, option := range options {
+		option(notifier)
+	}
+	notifier.httpClient = &http.Client{
+		Timeout: notifier.timeout,
+	}
+	return notifier
+}
+
+func (n *notifier) Notify(payload *Payload) (retErr error) {
+	var body []byte
+	if n.tmpl != nil {
+		buffer := bytes.NewBuffer(nil)
+		if err := n.tmpl.Execute(buffer, payload); err != nil {
+			return err
+		}
+		body = buffer.Bytes()
+	} else {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = data
+	}
+	httpRequest, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	for key, value := range n.headers {
+		httpRequest.Header.Set(key, value)
+	}
+	n.logger.Debug("webhook request", zap.String("command", payload.Command), zap.Int("failures", len(payload.Failures)))
+	httpResponse, err := n.httpClient.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, httpResponse.Body.Close())
+	}()
+	// read the body so that the connection can be reused
+	if _, err := io.Copy(ioutil.Discard, httpResponse.Body); err != nil {
+		return err
+	}
+	if httpResponse.StatusCode < 200 || httpResponse.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", httpResponse.Status)
+	}
+	return nil
+}
+
+func newPayload(command string, failures ...*text.Failure) *Payload {
+	sortedFailures := make([]*text.Failure, len(failures))
+	copy(sortedFailures, failures)
+	text.SortFailures(sortedFailures)
+	payload := &Payload{
+		Command:      command,
+		WarningCount: text.CountWarnings(failures...),
+		Failures:     make([]*text.JSONFailure, len(sortedFailures)),
+	}
+	for i, failure := range sortedFailures {
+		jsonFailure := failure.JSONFailure()
+		if jsonFailure.Severity == text.SeverityError {
+			payload.ErrorCount++
+		}
+		payload.Failures[i] = jsonFailure
+	}
+	return payload
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/text"
+)
+
+func TestNotifier(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		data, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		bodies = append(bodies, string(data))
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	headers := map[string]string{"Authorization": "Bearer token"}
+	payload := NewPayload(
+		"lint",
+		&text.Failure{Filename: "b.proto", Line: 2, Column: 3, ID: "FOO", Message: "Bad \"foo\"."},
+		&text.Failure{Filename: "a.proto", ID: "BAR", Message: "Bad bar.", Severity: text.SeverityWarning},
+	)
+
+	require.NoError(t, NewNotifier(server.URL, NotifierWithHeaders(headers)).Notify(payload))
+	tmpl, err := ParseTemplate(`{"text": {{json (printf "%s found %d errors, first %s" .Command .ErrorCount (index .Failures 0).Message)}}}`)
+	require.NoError(t, err)
+	require.NoError(t, NewNotifier(server.URL, NotifierWithHeaders(headers), NotifierWithTemplate(tmpl)).Notify(payload))
+	assert.Error(t, NewNotifier(server.URL+"/fail", NotifierWithHeaders(headers)).Notify(payload))
+	require.Len(t, bodies, 3)
+	assert.Equal(
+		t,
+		`{"command":"lint","error_count":1,"warning_count":1,"failures":[`+
+			`{"filename":"a.proto","line":1,"column":1,"id":"BAR","message":"Bad bar.","severity":"warning"},`+
+			`{"filename":"b.proto","line":2,"column":3,"id":"FOO","message":"Bad \"foo\".","severity":"error"}]}`,
+		bodies[0],
+	)
+	assert.Equal(t, `{"text": "lint found 1 errors, first Bad bar."}`, bodies[1])
+}
+
+func TestParseTemplate(t *testing.T) {
+	_, err := ParseTemplate(`{{.Command`)
+	assert.Error(t, err)
+}
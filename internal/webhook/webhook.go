@@ -0,0 +1,114 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package webhook posts failures as JSON to a webhook URL, so that chat bots
+// and dashboards can be notified of failures found in CI.
+package webhook
+
+import (
+	"text/template"
+	"time"
+
+	"github.com/uber/prototool/internal/text"
+	"go.uber.org/zap"
+)
+
+// DefaultTimeout is the default timeout for requests.
+const DefaultTimeout = 10 * time.Second
+
+// Payload is the payload that is posted as JSON, or that the template
+// is executed with if a template is set.
+type Payload struct {
+	// The command that found the failures, either compile, lint, or break.
+	Command string `json:"command"`
+	// The number of failures that are errors.
+	ErrorCount int `json:"error_count"`
+	// The number of failures that are warnings.
+	WarningCount int `json:"warning_count"`
+	// The failures, sorted.
+	Failures []*text.JSONFailure `json:"failures"`
+}
+
+// NewPayload returns a new Payload for the failures found by the command.
+func NewPayload(command string, failures ...*text.Failure) *Payload {
+	return newPayload(command, failures...)
+}
+
+// Notifier posts payloads to a webhook.
+type Notifier interface {
+	// Notify posts the payload to the webhook.
+	//
+	// Any status code other than 2xx is an error.
+	Notify(payload *Payload) error
+}
+
+// NotifierOption is an option for a new Notifier.
+type NotifierOption func(*notifier)
+
+// NotifierWithLogger returns a NotifierOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func NotifierWithLogger(logger *zap.Logger) NotifierOption {
+	return func(notifier *notifier) {
+		notifier.logger = logger
+	}
+}
+
+// NotifierWithTemplate returns a NotifierOption that posts the output of
+// the template executed with the Payload instead of the Payload as JSON.
+//
+// The template is expected to be parsed with ParseTemplate.
+func NotifierWithTemplate(tmpl *template.Template) NotifierOption {
+	return func(notifier *notifier) {
+		notifier.tmpl = tmpl
+	}
+}
+
+// NotifierWithHeaders returns a NotifierOption that sets the given
+// headers on requests, for example Authorization.
+func NotifierWithHeaders(headers map[string]string) NotifierOption {
+	return func(notifier *notifier) {
+		notifier.headers = headers
+	}
+}
+
+// NotifierWithTimeout returns a NotifierOption that uses the given timeout
+// for requests.
+//
+// The default is to use DefaultTimeout.
+func NotifierWithTimeout(timeout time.Duration) NotifierOption {
+	return func(notifier *notifier) {
+		notifier.timeout = timeout
+	}
+}
+
+// NewNotifier returns a new Notifier for the webhook at the given URL.
+func NewNotifier(url string, options ...NotifierOption) Notifier {
+	return newNotifier(url, options...)
+}
+
+// ParseTemplate parses a payload template.
+//
+// In addition to the builtin functions, the template can use json to
+// encode a value as JSON, for example {{json .Command}}, so that values
+// are escaped when the template is a JSON document.
+func ParseTemplate(s string) (*template.Template, error) {
+	return template.New("webhook").Funcs(templateFuncs).Parse(s)
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package lint exposes the Prototool lint engine as a library, so that other Go
+// tools and services can run the Prototool linters in-process without shelling
+// out to the prototool binary.
+//
+// This package is meant to be stable. The linters themselves may be added to
+// between releases, but the API in this package will only change in a
+// backwards-compatible manner.
+package lint
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/emicklei/proto"
+	intlint "github.com/uber/prototool/internal/lint"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/strs"
+	"github.com/uber/prototool/internal/syntax"
+	"github.com/uber/prototool/internal/text"
+)
+
+// Failure is a lint failure.
+type Failure struct {
+	// The filename given for the source that failed.
+	Filename string
+	// The line of the failure, starting at 1.
+	// Will be 0 if the failure is not associated with a specific line.
+	Line int
+	// The column of the failure, starting at 1.
+	// Will be 0 if the failure is not associated with a specific column.
+	Column int
+	// The ID of the linter that produced this failure.
+	ID string
+	// The human-readable message.
+	Message string
+}
+
+// String implements fmt.Stringer.
+func (f *Failure) String() string {
+	return fmt.Sprintf("%s:%d:%d:%s %s", f.Filename, f.Line, f.Column, f.ID, f.Message)
+}
+
+// Source is a Protobuf source file to lint.
+type Source struct {
+	// The filename of the source.
+	//
+	// Files in the same directory are linted together, which matters for
+	// linters that check consistency between files in a directory, such as
+	// PACKAGES_SAME_IN_DIR.
+	Filename string
+	// The data of the source.
+	Data []byte
+}
+
+// Linter describes a linter.
+type Linter struct {
+	// The ID of the linter, in UPPER_SNAKE_CASE.
+	ID string
+	// The human-readable purpose of the linter.
+	Purpose string
+}
+
+// Runner runs linters on Protobuf sources.
+type Runner interface {
+	// Linters returns the linters this Runner will run, sorted by ID.
+	Linters() []Linter
+	// Lint lints the given sources.
+	//
+	// Sources are expected to be compilable. If a source cannot be parsed,
+	// an error with all of the syntax errors of the sources is returned.
+	// Lint failures are returned sorted by filename,
+	// line, column, ID, and message.
+	Lint(sources ...Source) ([]*Failure, error)
+}
+
+// RunnerOption is an option for a new Runner.
+type RunnerOption func(*runner)
+
+// RunnerWithIDs returns a RunnerOption that only uses the linters with the given IDs.
+//
+// This cannot be used with RunnerWithGroup, RunnerWithIncludeIDs, or RunnerWithExcludeIDs.
+func RunnerWithIDs(ids ...string) RunnerOption {
+	return func(runner *runner) {
+		runner.config.IDs = append(runner.config.IDs, ids...)
+	}
+}
+
+// RunnerWithGroup returns a RunnerOption that uses the linters in the given group.
+//
+// The default is to use the default group.
+func RunnerWithGroup(group string) RunnerOption {
+	return func(runner *runner) {
+		runner.config.Group = group
+	}
+}
+
+// RunnerWithIncludeIDs returns a RunnerOption that adds the linters with the
+// given IDs to the linters from the group.
+func RunnerWithIncludeIDs(ids ...string) RunnerOption {
+	return func(runner *runner) {
+		runner.config.IncludeIDs = append(runner.config.IncludeIDs, ids...)
+	}
+}
+
+// RunnerWithExcludeIDs returns a RunnerOption that removes the linters with the
+// given IDs from the linters from the group.
+func RunnerWithExcludeIDs(ids ...string) RunnerOption {
+	return func(runner *runner) {
+		runner.config.ExcludeIDs = append(runner.config.ExcludeIDs, ids...)
+	}
+}
+
+// NewRunner returns a new Runner.
+//
+// An error is returned if the options result in an invalid configuration,
+// for example if an unknown group is given.
+func NewRunner(options ...RunnerOption) (Runner, error) {
+	return newRunner(options...)
+}
+
+// AllLinters returns all known linters, sorted by ID.
+func AllLinters() []Linter {
+	return toLinters(intlint.AllLinters)
+}
+
+// Groups returns the names of all known lint groups, sorted.
+func Groups() []string {
+	groups := make([]string, 0, len(intlint.GroupToLinters))
+	for group := range intlint.GroupToLinters {
+		groups = append(groups, group)
+	}
+	return strs.DedupeSort(groups, nil)
+}
+
+type runner struct {
+	config  settings.LintConfig
+	linters []intlint.Linter
+}
+
+func newRunner(options ...RunnerOption) (*runner, error) {
+	runner := &runner{}
+	for _, option := range options {
+		option(runner)
+	}
+	// normalize the same way the settings package does for config files
+	runner.config.IDs = strs.DedupeSort(runner.config.IDs, strings.ToUpper)
+	runner.config.Group = strings.ToLower(runner.config.Group)
+	runner.config.IncludeIDs = strs.DedupeSort(runner.config.IncludeIDs, strings.ToUpper)
+	runner.config.ExcludeIDs = strs.DedupeSort(runner.config.ExcludeIDs, strings.ToUpper)
+	if len(runner.config.IDs) > 0 && (runner.config.Group != "" || len(runner.config.IncludeIDs) > 0 || len(runner.config.ExcludeIDs) > 0) {
+		return nil, fmt.Errorf("can only specify either ids, or group/include ids/exclude ids")
+	}
+	if intersection := strs.Intersection(runner.config.IncludeIDs, runner.config.ExcludeIDs); len(intersection) > 0 {
+		return nil, fmt.Errorf("intersection of %v between include ids and exclude ids", intersection)
+	}
+	linters, err := intlint.GetLinters(runner.config)
+	if err != nil {
+		return nil, err
+	}
+	runner.linters = linters
+	return runner, nil
+}
+
+func (r *runner) Linters() []Linter {
+	return toLinters(r.linters)
+}
+
+func (r *runner) Lint(sources ...Source) ([]*Failure, error) {
+	dirPathToDescriptors := make(map[string][]*proto.Proto)
+	var syntaxFailures []string
+	for _, source := range sources {
+		descriptor, failures, err := syntax.Parse(bytes.NewReader(source.Data), source.Filename)
+		if err != nil {
+			return nil, err
+		}
+		for _, failure := range failures {
+			syntaxFailures = append(syntaxFailures, failure.String())
+		}
+		if len(failures) > 0 {
+			continue
+		}
+		descriptor.Filename = source.Filename
+		dirPath := filepath.Dir(source.Filename)
+		dirPathToDescriptors[dirPath] = append(dirPathToDescriptors[dirPath], descriptor)
+	}
+	if len(syntaxFailures) > 0 {
+		return nil, errors.New(strings.Join(syntaxFailures, "\n"))
+	}
+	textFailures, err := intlint.CheckMultiple(r.linters, dirPathToDescriptors, nil)
+	if err != nil {
+		return nil, err
+	}
+	failures := make([]*Failure, 0, len(textFailures))
+	for _, textFailure := range textFailures {
+		failures = append(failures, toFailure(textFailure))
+	}
+	return failures, nil
+}
+
+func toFailure(failure *text.Failure) *Failure {
+	return &Failure{
+		Filename: failure.Filename,
+		Line:     failure.Line,
+		Column:   failure.Column,
+		ID:       failure.ID,
+		Message:  failure.Message,
+	}
+}
+
+func toLinters(intLinters []intlint.Linter) []Linter {
+	linters := make([]Linter, 0, len(intLinters))
+	for _, intLinter := range intLinters {
+		linters = append(linters, Linter{
+			ID:      intLinter.ID(),
+			Purpose: intLinter.Purpose(),
+		})
+	}
+	sort.Slice(linters, func(i int, j int) bool { return linters[i].ID < linters[j].ID })
+	return linters
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	runner, err := NewRunner(RunnerWithIDs("syntax_proto3", "message_names_camel_case"))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]Linter{
+			{ID: "MESSAGE_NAMES_CAMEL_CASE", Purpose: "Verifies that all non-extended message names are CamelCase."},
+			{ID: "SYNTAX_PROTO3", Purpose: "Verifies that the syntax is proto3, or that the file uses an edition."},
+		},
+		runner.Linters(),
+	)
+	failures, err := runner.Lint(
+		Source{
+			Filename: "foo/foo.proto",
+			Data: []byte(`syntax = "proto2";
+
+package foo;
+
+message foo_bar {}
+`),
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, failures, 2)
+	assert.Equal(t, "foo/foo.proto", failures[0].Filename)
+	assert.Equal(t, "SYNTAX_PROTO3", failures[0].ID)
+	assert.Equal(t, 1, failures[0].Line)
+	assert.Equal(t, "MESSAGE_NAMES_CAMEL_CASE", failures[1].ID)
+	assert.Equal(t, 5, failures[1].Line)
+}
+
+func TestNewRunnerErrors(t *testing.T) {
+	_, err := NewRunner(RunnerWithIDs("SYNTAX_PROTO3"), RunnerWithGroup("all"))
+	assert.Error(t, err)
+	_, err = NewRunner(RunnerWithIncludeIDs("SYNTAX_PROTO3"), RunnerWithExcludeIDs("syntax_proto3"))
+	assert.Error(t, err)
+	_, err = NewRunner(RunnerWithGroup("unknown"))
+	assert.Error(t, err)
+}